@@ -9,6 +9,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -139,6 +140,11 @@ func main() {
 				Usage:   "Tolerate eventlog gaps when archiving",
 				Sources: cli.EnvVars("TOLERATE_EVENTLOG_GAPS"),
 			},
+			&cli.BoolFlag{
+				Name:    "archive-read-audit",
+				Usage:   "Archive the read audit log to the archive bucket",
+				Sources: cli.EnvVars("ARCHIVE_READ_AUDIT"),
+			},
 			&cli.BoolFlag{
 				Name:    "no-archiver",
 				Usage:   "Disable the archiver",
@@ -160,11 +166,48 @@ func main() {
 				Usage:   "Disable scheduled publishing",
 				Sources: cli.EnvVars("NO_SCHEDULER"),
 			},
+			&cli.BoolFlag{
+				Name:    "no-upload-janitor",
+				Usage:   "Disable expiry of old uploads and reconciliation of attached objects",
+				Sources: cli.EnvVars("NO_UPLOAD_JANITOR"),
+			},
+			&cli.DurationFlag{
+				Name:    "max-upload-age",
+				Usage:   "Age after which uploads are expired by the upload janitor",
+				Value:   repository.DefaultMaxUploadAge,
+				Sources: cli.EnvVars("MAX_UPLOAD_AGE"),
+			},
+			&cli.BoolFlag{
+				Name:    "upload-janitor-dry-run",
+				Usage:   "Only report what the upload janitor would delete",
+				Sources: cli.EnvVars("UPLOAD_JANITOR_DRY_RUN"),
+			},
+			&cli.BoolFlag{
+				Name:    "upload-proxy",
+				Usage:   "Serve upload and download proxy endpoints for clients that can't reach the asset bucket",
+				Sources: cli.EnvVars("UPLOAD_PROXY"),
+			},
+			&cli.Int64Flag{
+				Name:    "upload-proxy-max-size",
+				Usage:   "Largest upload in bytes that is accepted by the upload proxy",
+				Value:   repository.DefaultMaxProxiedUploadSize,
+				Sources: cli.EnvVars("UPLOAD_PROXY_MAX_SIZE"),
+			},
 			&cli.BoolFlag{
 				Name:    "no-charcounter",
 				Usage:   "Disable built in character counter",
 				Sources: cli.EnvVars("NO_CHARCOUNTER"),
 			},
+			&cli.BoolFlag{
+				Name:    "transform-on-read",
+				Usage:   "Apply the transforms of the active schema generation to documents when they are read",
+				Sources: cli.EnvVars("TRANSFORM_ON_READ"),
+			},
+			&cli.IntFlag{
+				Name:    "trusted-proxies",
+				Usage:   "Number of proxies in front of the repository that append the client IP to X-Forwarded-For",
+				Sources: cli.EnvVars("TRUSTED_PROXIES"),
+			},
 			&cli.BoolFlag{
 				Name:    "no-websocket",
 				Usage:   "Disable websocket API",
@@ -198,6 +241,17 @@ func main() {
 				Usage:   "Token-bucket rate (events/sec) for an eventlog subscription stream",
 				Sources: cli.EnvVars("EVENTLOG_STREAM_RATE"),
 			},
+			&cli.IntFlag{
+				Name:    "document-set-buffer-size",
+				Value:   repository.DefaultDocumentSetBufferSize,
+				Usage:   "Number of document events that can be queued for a websocket document set before it has to be resynchronised",
+				Sources: cli.EnvVars("DOCUMENT_SET_BUFFER_SIZE"),
+			},
+			&cli.StringSliceFlag{
+				Name:    "document-set-type-buffer-size",
+				Usage:   "Document set buffer size for a document type, in the format type=size",
+				Sources: cli.EnvVars("DOCUMENT_SET_TYPE_BUFFER_SIZES"),
+			},
 			&cli.BoolFlag{
 				Name: "migrate-db",
 				Usage: `Perform database migrations.
@@ -221,6 +275,17 @@ always present; this flag only adds the extra event back. Transition aid for
 external consumers; will be removed in a future release.`,
 				Sources: cli.EnvVars("EMIT_ACL_EVENT"),
 			},
+			&cli.BoolFlag{
+				Name:    "no-attachment-processing",
+				Usage:   "Disable metadata extraction and thumbnails for attached objects",
+				Sources: cli.EnvVars("NO_ATTACHMENT_PROCESSING"),
+			},
+			&cli.Int64Flag{
+				Name:    "max-processed-attachment-size",
+				Usage:   "Attached objects larger than this (in bytes) are not processed",
+				Value:   repository.DefaultMaxProcessedAttachmentSize,
+				Sources: cli.EnvVars("MAX_PROCESSED_ATTACHMENT_SIZE"),
+			},
 		}, elephantine.AuthenticationCLIFlags()...),
 	}
 
@@ -229,6 +294,7 @@ external consumers; will be removed in a future release.`,
 		Usage: "The Elephant repository",
 		Commands: []*cli.Command{
 			&runCmd,
+			seedCommand(),
 		},
 	}
 
@@ -250,17 +316,27 @@ func runServer(ctx context.Context, c *cli.Command) error {
 		defaultLanguage   = c.String("default-language")
 		defaultTimezone   = c.String("default-timezone")
 		noCharCounter     = c.Bool("no-charcounter")
+		transformOnRead   = c.Bool("transform-on-read")
 		noWebsocket       = c.Bool("no-websocket")
 		noSSE             = c.Bool("no-sse")
 		corsHosts         = c.StringSlice("cors-host")
 		eventlogBufSize   = c.Int("eventlog-buffer-size")
 		eventlogBurst     = c.Int("eventlog-stream-burst")
 		eventlogRate      = c.Float("eventlog-stream-rate")
+		docSetBufSize     = c.Int("document-set-buffer-size")
+		docSetTypeBufSize = c.StringSlice("document-set-type-buffer-size")
 		migrateDB         = c.Bool("migrate-db")
 		emitWorkflowEvent = c.Bool("emit-workflow-event")
 		emitACLEvent      = c.Bool("emit-acl-event")
+		maxProcessedSize  = c.Int64("max-processed-attachment-size")
 	)
 
+	var attachmentProcessors []repository.AttachmentProcessor
+
+	if !c.Bool("no-attachment-processing") {
+		attachmentProcessors = repository.DefaultAttachmentProcessors()
+	}
+
 	logger := elephantine.SetUpLogger(logLevel, os.Stdout)
 	grace := elephantine.NewGracefulShutdown(logger, 20*time.Second)
 
@@ -278,6 +354,11 @@ func runServer(ctx context.Context, c *cli.Command) error {
 		return fmt.Errorf("invalid default timezone: %w", err)
 	}
 
+	docSetTypeBufSizes, err := parseTypeBufferSizes(docSetTypeBufSize)
+	if err != nil {
+		return fmt.Errorf("invalid document set type buffer sizes: %w", err)
+	}
+
 	conf, err := cmd.BackendConfigFromContext(c)
 	if err != nil {
 		return fmt.Errorf("failed to read configuration: %w", err)
@@ -392,6 +473,9 @@ func runServer(ctx context.Context, c *cli.Command) error {
 			DefaultTZ:          defaultTZ,
 			EmitWorkflowEvent:  emitWorkflowEvent,
 			EmitACLEvent:       emitACLEvent,
+
+			AttachmentProcessors:       attachmentProcessors,
+			MaxProcessedAttachmentSize: maxProcessedSize,
 		})
 	if err != nil {
 		return fmt.Errorf("failed to create doc store: %w", err)
@@ -399,6 +483,29 @@ func runServer(ctx context.Context, c *cli.Command) error {
 
 	go store.RunListener(stopCtx, pubsubPool)
 	go store.RunCleaner(stopCtx, 5*time.Minute)
+	go store.RunACLExpiry(stopCtx, 1*time.Minute)
+	go store.RunACLInheritance(stopCtx, 10*time.Second)
+	go store.RunRevalidation(stopCtx, 10*time.Second)
+	go store.RunDeprecationUsageScan(stopCtx, 10*time.Second)
+	go store.RunExemplarCollection(stopCtx, 1*time.Hour)
+
+	if !c.Bool("no-upload-janitor") {
+		janitor, err := repository.NewUploadJanitor(
+			repository.UploadJanitorOptions{
+				Logger: logger.With(
+					elephantine.LogKeyComponent, "upload-janitor"),
+				DB:                dbpool,
+				Assets:            assets,
+				MetricsRegisterer: prometheus.DefaultRegisterer,
+				MaxUploadAge:      c.Duration("max-upload-age"),
+				DryRun:            c.Bool("upload-janitor-dry-run"),
+			})
+		if err != nil {
+			return fmt.Errorf("failed to create upload janitor: %w", err)
+		}
+
+		go janitor.Run(stopCtx, 1*time.Hour)
+	}
 
 	bootstrapLock, err := pg.NewJobLock(
 		dbpool, logger, "bootstrap-generation",
@@ -421,6 +528,8 @@ func runServer(ctx context.Context, c *cli.Command) error {
 		return fmt.Errorf("failed to create validator: %w", err)
 	}
 
+	go store.RunSchemaUpgrades(stopCtx, 10*time.Second, validator)
+
 	workflows, err := repository.NewWorkflows(ctx, logger, store)
 	if err != nil {
 		return fmt.Errorf("failed to create workflows: %w", err)
@@ -442,7 +551,9 @@ func runServer(ctx context.Context, c *cli.Command) error {
 		defaultLanguage,
 		typeConfs,
 		docCache,
+		store,
 		socketKey,
+		transformOnRead,
 	)
 	if err != nil {
 		return fmt.Errorf("create documents service: %w", err)
@@ -571,9 +682,11 @@ func runServer(ctx context.Context, c *cli.Command) error {
 		}()
 	}
 
-	schemaService := repository.NewSchemasService(logger, store)
+	schemaService := repository.NewSchemasService(logger, store,
+		repository.NewGenerationArchive(dbpool, s3Client, conf.ArchiveBucket))
 	workflowService := repository.NewWorkflowsService(store)
 	metricsService := repository.NewMetricsService(store)
+	templatesService := repository.NewTemplatesService(store, validator)
 
 	router := httprouter.New()
 
@@ -581,6 +694,8 @@ func runServer(ctx context.Context, c *cli.Command) error {
 
 	opts.SetJWTValidation(auth.AuthParser)
 
+	opts.TrustedProxies = c.Int("trusted-proxies")
+
 	metrics, err := elephantine.NewTwirpMetricsHooks()
 	if err != nil {
 		return fmt.Errorf("failed to create twirp metrics hook: %w", err)
@@ -594,11 +709,24 @@ func runServer(ctx context.Context, c *cli.Command) error {
 	routerOpts := []repository.RouterOption{
 		repository.WithDocumentsAPI(docService, opts),
 		repository.WithSchemasAPI(schemaService, opts),
+		repository.WithTemplatesAPI(templatesService, opts),
 		repository.WithWorkflowsAPI(workflowService, opts),
 		repository.WithMetricsAPI(metricsService, opts),
 		repository.WithSigningKeys(dbpool),
 	}
 
+	if c.Bool("upload-proxy") {
+		proxy := repository.NewAssetProxy(repository.AssetProxyOptions{
+			Logger:        logger,
+			Documents:     docService,
+			Assets:        assets,
+			MaxUploadSize: c.Int64("upload-proxy-max-size"),
+		})
+
+		routerOpts = append(routerOpts,
+			repository.WithAssetProxy(proxy, opts))
+	}
+
 	var sseSubsystem *repository.SSE
 
 	if !noSSE {
@@ -625,13 +753,17 @@ func runServer(ctx context.Context, c *cli.Command) error {
 				Rate:       rate.Limit(eventlogRate),
 				Burst:      eventlogBurst,
 			},
+			repository.DocumentSetConfig{
+				BufferSize:      docSetBufSize,
+				TypeBufferSizes: docSetTypeBufSizes,
+			},
 		)
 		if err != nil {
 			return fmt.Errorf("set up socket handler: %w", err)
 		}
 
 		routerOpts = append(routerOpts,
-			repository.WithWebsocket(socket))
+			repository.WithWebsocket(socket, opts))
 	}
 
 	err = repository.SetUpRouter(router, routerOpts...)
@@ -799,6 +931,7 @@ func startArchiver(
 		Store:              store,
 		TolerateGaps:       conf.TolerateEventlogGaps,
 		TypeConfigurations: typeConf,
+		ArchiveReadAudit:   conf.ArchiveReadAudit,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create archiver: %w", err)
@@ -811,3 +944,29 @@ func startArchiver(
 
 	return nil
 }
+
+// parseTypeBufferSizes parses a list of type=size buffer size overrides.
+func parseTypeBufferSizes(values []string) (map[string]int, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	sizes := make(map[string]int, len(values))
+
+	for _, v := range values {
+		docType, sizeStr, ok := strings.Cut(v, "=")
+		if !ok || docType == "" {
+			return nil, fmt.Errorf("expected type=size, got %q", v)
+		}
+
+		size, err := strconv.Atoi(sizeStr)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid buffer size for %q: %q",
+				docType, sizeStr)
+		}
+
+		sizes[docType] = size
+	}
+
+	return sizes, nil
+}
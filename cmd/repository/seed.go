@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/ttab/eleconf"
+	rpc "github.com/ttab/elephant-api/repository"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/oauth2"
+)
+
+func seedCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "seed",
+		Description: "Loads documents into a running repository",
+		Action:      runSeed,
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:    "repository",
+				Usage:   "Base URL of the repository",
+				Value:   "http://localhost:1080",
+				Sources: cli.EnvVars("REPOSITORY_ENDPOINT"),
+			},
+			&cli.StringFlag{
+				Name:  "bundle",
+				Usage: "Export bundle to load documents from",
+			},
+			&cli.StringFlag{
+				Name:  "dir",
+				Usage: "Directory of newsdoc JSON files to load documents from",
+			},
+			&cli.StringFlag{
+				Name:  "creator",
+				Usage: "Creator of documents loaded from a directory",
+				Value: "core://application/repository-seed",
+			},
+			&cli.BoolFlag{
+				Name:  "remap-uuids",
+				Usage: "Give the documents new UUIDs, and rewrite links to them",
+			},
+			&cli.StringFlag{
+				Name: "remap-namespace",
+				Usage: `UUID namespace that new UUIDs are derived from, defaults to a
+random namespace. Use the same namespace to get the same UUIDs when re-seeding.`,
+			},
+			&cli.StringSliceFlag{
+				Name:  "acl-unit",
+				Usage: "Replace document ACLs with read and write access for the unit",
+			},
+			&cli.StringFlag{
+				Name: "config",
+				Usage: `Repository configuration directory to apply, together with the
+schemas, before loading documents`,
+			},
+			&cli.StringSliceFlag{
+				Name:  "schema",
+				Usage: "Embedded schema to activate when a configuration is applied",
+				Value: []string{
+					"se.ecms", "se.ecms.metadoc", "se.ecms.planning",
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "validate-only",
+				Usage: "Only report what would be loaded",
+			},
+			&cli.StringFlag{
+				Name:    "log-level",
+				Sources: cli.EnvVars("LOG_LEVEL"),
+				Value:   "info",
+			},
+		}, elephantine.AuthenticationCLIFlags()...),
+	}
+}
+
+func runSeed(ctx context.Context, c *cli.Command) error {
+	var (
+		endpoint     = c.String("repository")
+		bundlePath   = c.String("bundle")
+		dir          = c.String("dir")
+		configDir    = c.String("config")
+		validateOnly = c.Bool("validate-only")
+	)
+
+	logger := elephantine.SetUpLogger(c.String("log-level"), os.Stdout)
+
+	if (bundlePath == "") == (dir == "") {
+		return errors.New("either a bundle or a directory is required")
+	}
+
+	opts := repository.SeedOptions{
+		RemapUUIDs: c.Bool("remap-uuids"),
+		ACLUnits:   c.StringSlice("acl-unit"),
+	}
+
+	if opts.RemapUUIDs {
+		opts.RemapNamespace = uuid.New()
+
+		if ns := c.String("remap-namespace"); ns != "" {
+			u, err := uuid.Parse(ns)
+			if err != nil {
+				return fmt.Errorf("invalid remap namespace: %w", err)
+			}
+
+			opts.RemapNamespace = u
+		}
+	}
+
+	var (
+		docs []repository.ImportDocument
+		err  error
+	)
+
+	if bundlePath != "" {
+		docs, err = repository.LoadSeedBundle(bundlePath)
+	} else {
+		docs, err = repository.LoadSeedDirectory(dir, c.String("creator"))
+	}
+
+	if err != nil {
+		return fmt.Errorf("load documents: %w", err)
+	}
+
+	docs, err = repository.PrepareSeed(docs, opts)
+	if err != nil {
+		return fmt.Errorf("prepare documents: %w", err)
+	}
+
+	scopes := []string{
+		repository.ScopeDocumentRead,
+		repository.ScopeDocumentWrite,
+		repository.ScopeDocumentImport,
+	}
+
+	if configDir != "" {
+		scopes = append(scopes,
+			repository.ScopeSchemaAdmin,
+			repository.ScopeWorkflowAdmin,
+			repository.ScopeMetricsAdmin,
+		)
+	}
+
+	auth, err := elephantine.AuthenticationConfigFromCLI(ctx, c, scopes)
+	if err != nil {
+		return fmt.Errorf("set up authentication: %w", err)
+	}
+
+	client := oauth2.NewClient(ctx, auth.TokenSource)
+
+	if configDir != "" && !validateOnly {
+		err := applySeedConfiguration(ctx, client, endpoint,
+			configDir, c.StringSlice("schema"))
+		if err != nil {
+			return err
+		}
+
+		logger.InfoContext(ctx, "applied repository configuration",
+			"config", configDir)
+	}
+
+	results, err := repository.Seed(ctx,
+		repository.NewExtensionClient(
+			client, endpoint, rpc.DocumentsPathPrefix, nil),
+		docs, validateOnly)
+	if err != nil {
+		return err
+	}
+
+	outcomes := make(map[repository.ImportOutcome]int)
+
+	var rejected int
+
+	for _, r := range results {
+		outcomes[r.Outcome]++
+
+		switch r.Outcome {
+		case repository.ImportOutcomeImported,
+			repository.ImportOutcomeValid,
+			repository.ImportOutcomeUnchanged:
+			continue
+		case repository.ImportOutcomeInvalid,
+			repository.ImportOutcomeConflict,
+			repository.ImportOutcomeFailed:
+		}
+
+		rejected++
+
+		logger.WarnContext(ctx, "document was not loaded",
+			elephantine.LogKeyDocumentUUID, r.UUID,
+			"outcome", r.Outcome,
+			elephantine.LogKeyError, r.Error,
+			"problems", r.Problems)
+	}
+
+	logger.InfoContext(ctx, "seeded documents",
+		"documents", len(results),
+		"imported", outcomes[repository.ImportOutcomeImported],
+		"valid", outcomes[repository.ImportOutcomeValid],
+		"unchanged", outcomes[repository.ImportOutcomeUnchanged],
+		"rejected", rejected)
+
+	if rejected > 0 {
+		return fmt.Errorf("%d documents were not loaded", rejected)
+	}
+
+	return nil
+}
+
+func applySeedConfiguration(
+	ctx context.Context, client *http.Client, endpoint string,
+	configDir string, schemaNames []string,
+) error {
+	conf, err := eleconf.ReadConfigFromDirectory(configDir)
+	if err != nil {
+		return fmt.Errorf("read repository configuration: %w", err)
+	}
+
+	schemas, err := repository.LoadEmbeddedSchemaSet(schemaNames...)
+	if err != nil {
+		return fmt.Errorf("load schemas: %w", err)
+	}
+
+	clients := eleconf.StaticClients{
+		Workflows: rpc.NewWorkflowsProtobufClient(endpoint, client),
+		Schemas:   rpc.NewSchemasProtobufClient(endpoint, client),
+		Metrics:   rpc.NewMetricsProtobufClient(endpoint, client),
+	}
+
+	err = repository.ApplySeedConfiguration(ctx, &clients, conf, schemas)
+	if err != nil {
+		return fmt.Errorf("apply repository configuration: %w", err)
+	}
+
+	return nil
+}
@@ -27,6 +27,8 @@ type BackendConfig struct {
 
 	// TolerateEventlogGaps to deal with old inconsistent data.
 	TolerateEventlogGaps bool
+	// ArchiveReadAudit enables archiving of the read audit log.
+	ArchiveReadAudit bool
 }
 
 func BackendConfigFromContext(c *cli.Command) (BackendConfig, error) {
@@ -53,6 +55,7 @@ func BackendConfigFromContext(c *cli.Command) (BackendConfig, error) {
 			AccessKeySecret: c.String("s3-key-secret"),
 		},
 		TolerateEventlogGaps: c.Bool("tolerate-eventlog-gaps"),
+		ArchiveReadAudit:     c.Bool("archive-read-audit"),
 	}
 
 	return cfg, nil
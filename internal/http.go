@@ -21,6 +21,19 @@ func RHandleFunc(
 	}
 }
 
+// HandleFunc creates a http.Handler from a function that returns an error,
+// errors are written to the response like in RHandleFunc.
+func HandleFunc(
+	fn func(http.ResponseWriter, *http.Request) error,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := fn(w, r)
+		if err != nil {
+			writeHTTPError(w, err)
+		}
+	})
+}
+
 func writeHTTPError(w http.ResponseWriter, err error) {
 	var httpErr *elephantine.HTTPError
 
@@ -1,7 +1,21 @@
 package postgres
 
 type AssetMetadata struct {
-	Filename string            `json:"filename"`
-	Mimetype string            `json:"mimetype"`
-	Props    map[string]string `json:"props"`
+	Filename string `json:"filename"`
+	Mimetype string `json:"mimetype"`
+	// SniffedMimetype is the content type detected when the upload was
+	// verified.
+	SniffedMimetype string            `json:"sniffed_mimetype,omitempty"`
+	Props           map[string]string `json:"props"`
+	SHA256          string            `json:"sha256,omitempty"`
+	Size            int64             `json:"size,omitempty"`
+	Derived         map[string]string `json:"derived,omitempty"`
+	Renditions      []AssetRendition  `json:"renditions,omitempty"`
+}
+
+type AssetRendition struct {
+	Name     string `json:"name"`
+	Mimetype string `json:"mimetype"`
+	Size     int64  `json:"size"`
+	Key      string `json:"key,omitempty"`
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 var (
@@ -18,10 +19,14 @@ var (
 )
 
 const aCLUpdate = `-- name: ACLUpdate :batchexec
-INSERT INTO acl(uuid, uri, permissions)
-VALUES ($1, $2, $3::text[])
+INSERT INTO acl(uuid, uri, permissions, not_before, expires)
+VALUES ($1, $2, $3::text[], $4, $5)
        ON CONFLICT(uuid, uri) DO UPDATE SET
-          permissions = $3::text[]
+          permissions = $3::text[],
+          not_before = CASE WHEN $6::bool
+                       THEN $4 ELSE acl.not_before END,
+          expires = CASE WHEN $6::bool
+                    THEN $5 ELSE acl.expires END
 `
 
 type ACLUpdateBatchResults struct {
@@ -31,9 +36,12 @@ type ACLUpdateBatchResults struct {
 }
 
 type ACLUpdateParams struct {
-	UUID        uuid.UUID
-	URI         string
-	Permissions []string
+	UUID          uuid.UUID
+	URI           string
+	Permissions   []string
+	NotBefore     pgtype.Timestamptz
+	Expires       pgtype.Timestamptz
+	SetTimeBounds bool
 }
 
 func (q *Queries) ACLUpdate(ctx context.Context, arg []ACLUpdateParams) *ACLUpdateBatchResults {
@@ -43,6 +51,9 @@ func (q *Queries) ACLUpdate(ctx context.Context, arg []ACLUpdateParams) *ACLUpda
 			a.UUID,
 			a.URI,
 			a.Permissions,
+			a.NotBefore,
+			a.Expires,
+			a.SetTimeBounds,
 		}
 		batch.Queue(aCLUpdate, vals...)
 	}
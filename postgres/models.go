@@ -59,6 +59,27 @@ type Acl struct {
 	UUID        uuid.UUID
 	URI         string
 	Permissions []string
+	NotBefore   pgtype.Timestamptz
+	Expires     pgtype.Timestamptz
+}
+
+type AclInheritance struct {
+	UUID        uuid.UUID
+	Parent      uuid.UUID
+	Permissions []string
+}
+
+type AclInheritanceReapply struct {
+	Type     string
+	Position uuid.UUID
+	Created  pgtype.Timestamptz
+}
+
+type AclInheritanceRule struct {
+	Type        string
+	Rel         string
+	LinkType    string
+	Permissions []string
 }
 
 type ActiveSchema struct {
@@ -110,6 +131,18 @@ type Deprecation struct {
 	Enforced bool
 }
 
+type DeprecationUsage struct {
+	Label   string
+	UUID    uuid.UUID
+	Version int64
+}
+
+type DeprecationUsageScan struct {
+	GenerationID int64
+	Position     uuid.UUID
+	Created      pgtype.Timestamptz
+}
+
 type Document struct {
 	UUID           uuid.UUID
 	URI            string
@@ -133,6 +166,32 @@ type DocumentArchiveCounter struct {
 	Unarchived int32
 }
 
+type DocumentExport struct {
+	ID        int64
+	Uuids     []uuid.UUID
+	Type      pgtype.Text
+	TimeRange pgtype.Range[pgtype.Timestamptz]
+	Labels    []string
+	Status    string
+	Created   pgtype.Timestamptz
+	CreatedBy string
+	Started   pgtype.Timestamptz
+	Finished  pgtype.Timestamptz
+	Total     int64
+	Processed int64
+	ObjectKey pgtype.Text
+	Size      pgtype.Int8
+	Signature pgtype.Text
+	Error     pgtype.Text
+}
+
+type DocumentLink struct {
+	FromDocument uuid.UUID
+	ToDocument   uuid.UUID
+	Rel          string
+	Type         string
+}
+
 type DocumentLock struct {
 	UUID        uuid.UUID
 	Token       string
@@ -163,6 +222,18 @@ type DocumentStatus struct {
 	MetaDocVersion pgtype.Int8
 }
 
+type DocumentTemplate struct {
+	Name        string
+	Version     int64
+	Type        string
+	Description string
+	Document    []byte
+	Variables   []byte
+	Acl         []byte
+	Created     pgtype.Timestamptz
+	CreatedBy   string
+}
+
 type DocumentType struct {
 	Type              string
 	BoundedCollection bool
@@ -307,6 +378,25 @@ type PurgeRequest struct {
 	Finished       pgtype.Timestamptz
 }
 
+type ReadAudit struct {
+	ID         int64
+	UUID       uuid.UUID
+	Type       string
+	Version    int64
+	Operation  string
+	Attachment pgtype.Text
+	Subject    string
+	App        pgtype.Text
+	ClientIp   pgtype.Text
+	Created    pgtype.Timestamptz
+}
+
+type ReadAuditArchiver struct {
+	ID            bool
+	Position      int64
+	LastSignature string
+}
+
 type RestoreRequest struct {
 	ID             int64
 	UUID           uuid.UUID
@@ -351,6 +441,7 @@ type SchemaGenerationExemplar struct {
 	GenerationID int64
 	Name         string
 	Version      string
+	Collected    bool
 }
 
 type SchemaGenerationSchema struct {
@@ -359,6 +450,49 @@ type SchemaGenerationSchema struct {
 	Version      string
 }
 
+type SchemaGenerationTransform struct {
+	GenerationID int64
+	Transforms   []byte
+	Updated      pgtype.Timestamptz
+	UpdatedBy    string
+}
+
+type SchemaRevalidation struct {
+	GenerationID int64
+	Types        []string
+	Status       string
+	Created      pgtype.Timestamptz
+	CreatedBy    string
+	Finished     pgtype.Timestamptz
+	Position     uuid.UUID
+	Total        int64
+	Processed    int64
+	Failed       int64
+	Error        pgtype.Text
+}
+
+type SchemaRevalidationResult struct {
+	GenerationID int64
+	UUID         uuid.UUID
+	Type         string
+	Version      int64
+	Errors       []byte
+}
+
+type SchemaUpgrade struct {
+	GenerationID int64
+	Types        []string
+	Status       string
+	Created      pgtype.Timestamptz
+	CreatedBy    string
+	Finished     pgtype.Timestamptz
+	Position     uuid.UUID
+	Processed    int64
+	Upgraded     int64
+	Failed       int64
+	Error        pgtype.Text
+}
+
 type SchemaVersion struct {
 	Version int32
 }
@@ -402,10 +536,12 @@ type SystemConfig struct {
 }
 
 type Upload struct {
-	ID        uuid.UUID
-	CreatedBy string
-	CreatedAt pgtype.Timestamptz
-	Meta      AssetMetadata
+	ID              uuid.UUID
+	CreatedBy       string
+	CreatedAt       pgtype.Timestamptz
+	Meta            AssetMetadata
+	MultipartID     pgtype.Text
+	MultipartStatus pgtype.Text
 }
 
 type Workflow struct {
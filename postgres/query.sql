@@ -43,7 +43,7 @@ DELETE FROM document_archive_counter
 WHERE uuid = @uuid;
 
 -- name: GetDocumentACL :many
-SELECT uuid, uri, permissions FROM acl WHERE uuid = $1;
+SELECT uuid, uri, permissions, not_before, expires FROM acl WHERE uuid = $1;
 
 -- name: GetCurrentDocumentVersions :many
 SELECT d.uuid, d.current_version, d.updated,
@@ -142,7 +142,7 @@ WHERE document = ANY(@documents::uuid[])
       AND deleted = false;
 
 -- name: BulkGetDocumentACL :many
-SELECT uuid, uri, permissions
+SELECT uuid, uri, permissions, not_before, expires
 FROM acl
 WHERE uuid = ANY(@uuids::uuid[]);
 
@@ -435,6 +435,11 @@ FROM document AS d
      LEFT JOIN meta_type_use AS m ON m.main_type = d.type
 WHERE d.uuid = @uuid;
 
+-- name: GetMetaTypeForType :one
+SELECT meta_type
+FROM meta_type_use
+WHERE main_type = @main_type;
+
 -- name: GetMetaDocVersion :one
 SELECT current_version FROM document
 WHERE main_doc = @uuid;
@@ -560,31 +565,175 @@ SELECT kid, spec FROM signing_keys WHERE NOT archived;
 UPDATE signing_keys SET archived = true WHERE kid = @kid;
 
 -- name: ACLUpdate :batchexec
-INSERT INTO acl(uuid, uri, permissions)
-VALUES (@uuid, @uri, @permissions::text[])
+INSERT INTO acl(uuid, uri, permissions, not_before, expires)
+VALUES (@uuid, @uri, @permissions::text[], @not_before, @expires)
        ON CONFLICT(uuid, uri) DO UPDATE SET
-          permissions = @permissions::text[];
+          permissions = @permissions::text[],
+          not_before = CASE WHEN @set_time_bounds::bool
+                       THEN @not_before ELSE acl.not_before END,
+          expires = CASE WHEN @set_time_bounds::bool
+                    THEN @expires ELSE acl.expires END;
 
 -- name: DropACL :exec
 DELETE FROM acl WHERE uuid = @uuid AND uri = @uri;
 
 -- name: CheckPermissions :one
-SELECT (acl.uri IS NOT NULL) = true AS has_access, d.system_state
+SELECT (
+         EXISTS (
+           SELECT 1 FROM acl
+           WHERE (acl.uuid = d.uuid OR acl.uuid = d.main_doc)
+                 AND acl.uri = ANY(@uri::text[])
+                 AND @permissions::text[] && acl.permissions
+                 AND (acl.not_before IS NULL OR acl.not_before <= now())
+                 AND (acl.expires IS NULL OR acl.expires > now())
+         )
+         OR EXISTS (
+           SELECT 1
+           FROM acl_inheritance AS ai
+                INNER JOIN acl AS pa
+                      ON pa.uuid = ai.parent
+                      AND pa.uri = ANY(@uri::text[])
+                      AND (pa.not_before IS NULL OR pa.not_before <= now())
+                      AND (pa.expires IS NULL OR pa.expires > now())
+           WHERE (ai.uuid = d.uuid OR ai.uuid = d.main_doc)
+                 AND EXISTS (
+                   SELECT 1 FROM unnest(@permissions::text[]) AS p(name)
+                   WHERE p.name = ANY(ai.permissions)
+                         AND p.name = ANY(pa.permissions)
+                 )
+         )
+       )::bool AS has_access, d.system_state
 FROM document AS d
-     LEFT JOIN acl
-          ON (acl.uuid = d.uuid OR acl.uuid = d.main_doc)
-          AND acl.uri = ANY(@uri::text[])
-          AND @permissions::text[] && permissions
 WHERE d.uuid = @uuid;
 
 -- name: BulkCheckPermissions :many
 SELECT d.uuid
 FROM document AS d
-     INNER JOIN acl
-          ON (acl.uuid = d.uuid OR acl.uuid = d.main_doc)
-          AND acl.uri = ANY(@uri::text[])
-          AND @permissions::text[] && permissions
-WHERE d.uuid = ANY(@uuids::uuid[]);
+WHERE d.uuid = ANY(@uuids::uuid[])
+      AND (
+        EXISTS (
+          SELECT 1 FROM acl
+          WHERE (acl.uuid = d.uuid OR acl.uuid = d.main_doc)
+                AND acl.uri = ANY(@uri::text[])
+                AND @permissions::text[] && acl.permissions
+                AND (acl.not_before IS NULL OR acl.not_before <= now())
+                AND (acl.expires IS NULL OR acl.expires > now())
+        )
+        OR EXISTS (
+          SELECT 1
+          FROM acl_inheritance AS ai
+               INNER JOIN acl AS pa
+                     ON pa.uuid = ai.parent
+                     AND pa.uri = ANY(@uri::text[])
+                     AND (pa.not_before IS NULL OR pa.not_before <= now())
+                     AND (pa.expires IS NULL OR pa.expires > now())
+          WHERE (ai.uuid = d.uuid OR ai.uuid = d.main_doc)
+                AND EXISTS (
+                  SELECT 1 FROM unnest(@permissions::text[]) AS p(name)
+                  WHERE p.name = ANY(ai.permissions)
+                        AND p.name = ANY(pa.permissions)
+                )
+        )
+      );
+
+-- name: GetACLInheritanceRules :many
+SELECT type, rel, link_type, permissions
+FROM acl_inheritance_rule
+WHERE type = @type
+ORDER BY rel, link_type;
+
+-- name: DeleteACLInheritanceRules :exec
+DELETE FROM acl_inheritance_rule WHERE type = @type;
+
+-- name: InsertACLInheritanceRule :exec
+INSERT INTO acl_inheritance_rule(type, rel, link_type, permissions)
+VALUES (@type, @rel, @link_type, @permissions);
+
+-- name: DropDocumentACLInheritance :exec
+DELETE FROM acl_inheritance WHERE uuid = @uuid;
+
+-- name: InsertDocumentACLInheritance :exec
+INSERT INTO acl_inheritance(uuid, parent, permissions)
+VALUES (@uuid, @parent, @permissions);
+
+-- name: GetInheritedACL :many
+SELECT ai.uuid, pa.uri, ARRAY(
+         SELECT p FROM unnest(pa.permissions) AS p
+         WHERE p = ANY(ai.permissions)
+       )::text[] AS permissions
+FROM acl_inheritance AS ai
+     INNER JOIN acl AS pa ON pa.uuid = ai.parent
+WHERE ai.uuid = ANY(@uuids::uuid[])
+      AND pa.permissions && ai.permissions
+      AND (pa.not_before IS NULL OR pa.not_before <= now())
+      AND (pa.expires IS NULL OR pa.expires > now());
+
+-- name: GetInheritedACLSources :many
+SELECT ai.uuid, ai.parent, ai.permissions AS inheritable,
+       pa.uri, pa.permissions, pa.not_before, pa.expires
+FROM acl_inheritance AS ai
+     INNER JOIN acl AS pa ON pa.uuid = ai.parent
+WHERE ai.uuid = ANY(@uuids::uuid[])
+      AND pa.uri = ANY(@uris::text[]);
+
+-- name: GetACLInheritors :many
+SELECT d.uuid, d.type, d.language, d.current_version, d.nonce, d.main_doc,
+       d.main_doc_type, d.system_state, d.labels, d.time
+FROM acl_inheritance AS ai
+     INNER JOIN document AS d ON d.uuid = ai.uuid
+WHERE ai.parent = @parent;
+
+-- name: GetDocumentACLInheritance :many
+SELECT parent, permissions
+FROM acl_inheritance
+WHERE uuid = @uuid;
+
+-- name: StartACLInheritanceReapply :exec
+INSERT INTO acl_inheritance_reapply(type, position, created)
+VALUES (@type, '00000000-0000-0000-0000-000000000000', @created)
+ON CONFLICT (type) DO UPDATE SET
+   position = excluded.position,
+   created = excluded.created;
+
+-- name: GetACLInheritanceReapply :one
+SELECT type, position, created
+FROM acl_inheritance_reapply
+ORDER BY created
+LIMIT 1;
+
+-- name: SetACLInheritanceReapplyPosition :execrows
+UPDATE acl_inheritance_reapply
+SET position = @position
+WHERE type = @type AND created = @created;
+
+-- name: FinishACLInheritanceReapply :execrows
+DELETE FROM acl_inheritance_reapply
+WHERE type = @type AND created = @created;
+
+-- name: GetDocumentsForACLInheritance :many
+SELECT d.uuid, d.type, d.language, d.current_version, d.nonce, d.main_doc,
+       d.main_doc_type, d.system_state, d.labels, d.time, v.document_data
+FROM document AS d
+     INNER JOIN document_version AS v
+           ON v.uuid = d.uuid AND v.version = d.current_version
+WHERE d.type = @type
+      AND d.uuid > @after
+      AND d.main_doc IS NULL
+ORDER BY d.uuid
+LIMIT @row_limit
+FOR UPDATE OF d;
+
+-- name: GetACLTransitions :many
+SELECT a.uuid, a.uri, a.permissions, a.not_before, a.expires,
+       d.type, d.language, d.current_version, d.nonce, d.main_doc,
+       d.main_doc_type, d.labels, d.time
+FROM acl AS a
+     INNER JOIN document AS d ON d.uuid = a.uuid
+WHERE (a.expires <= @now OR a.not_before <= @now)
+      AND d.system_state IS NULL
+ORDER BY a.uuid
+LIMIT sqlc.arg(count)::bigint
+FOR UPDATE OF d SKIP LOCKED;
 
 -- name: SelectDocumentsInTimeRange :many
 SELECT d.uuid, d.current_version, d.language
@@ -700,6 +849,72 @@ VALUES(@label, @enforced)
 ON CONFLICT(label) DO UPDATE SET
    enforced = @enforced;
 
+-- name: DropDeprecationUsage :exec
+DELETE FROM deprecation_usage WHERE uuid = @uuid;
+
+-- name: InsertDeprecationUsage :exec
+INSERT INTO deprecation_usage(label, uuid, version)
+SELECT l.label, @uuid::uuid, @version::bigint
+FROM unnest(@labels::text[]) AS l(label)
+ON CONFLICT DO NOTHING;
+
+-- name: GetDeprecationUsage :many
+SELECT COALESCE(d.label, u.label)::text AS label,
+       COALESCE(d.enforced, false)::bool AS enforced,
+       COALESCE(u.documents, 0)::bigint AS documents
+FROM deprecation AS d
+     FULL OUTER JOIN (
+          SELECT du.label, count(*) AS documents
+          FROM deprecation_usage AS du
+               INNER JOIN document AS doc ON doc.uuid = du.uuid
+          WHERE doc.system_state IS NULL
+          GROUP BY du.label
+     ) AS u ON u.label = d.label
+ORDER BY 1;
+
+-- name: GetDeprecatedDocuments :many
+SELECT u.uuid, d.type, u.version
+FROM deprecation_usage AS u
+     INNER JOIN document AS d ON d.uuid = u.uuid
+WHERE u.label = @label
+      AND u.uuid > @after
+      AND d.system_state IS NULL
+ORDER BY u.uuid
+LIMIT @row_limit;
+
+-- name: ClearDeprecationUsageScans :exec
+DELETE FROM deprecation_usage_scan;
+
+-- name: StartDeprecationUsageScan :exec
+INSERT INTO deprecation_usage_scan(generation_id, position, created)
+VALUES (@generation_id, '00000000-0000-0000-0000-000000000000', @created);
+
+-- name: GetDeprecationUsageScan :one
+SELECT generation_id, position, created
+FROM deprecation_usage_scan
+ORDER BY created
+LIMIT 1;
+
+-- name: SetDeprecationUsageScanPosition :execrows
+UPDATE deprecation_usage_scan
+SET position = @position
+WHERE generation_id = @generation_id AND created = @created;
+
+-- name: FinishDeprecationUsageScan :execrows
+DELETE FROM deprecation_usage_scan
+WHERE generation_id = @generation_id AND created = @created;
+
+-- name: GetDocumentsForDeprecationScan :many
+SELECT d.uuid, d.current_version, v.document_data
+FROM document AS d
+     INNER JOIN document_version AS v
+           ON v.uuid = d.uuid AND v.version = d.current_version
+WHERE d.uuid > @after
+      AND d.system_state IS NULL
+ORDER BY d.uuid
+LIMIT @row_limit
+FOR UPDATE OF d;
+
 -- name: GetActiveStatuses :many
 SELECT type, name
 FROM status
@@ -1056,13 +1271,33 @@ WHERE pd.document = ANY(@uuids::uuid[])
 ORDER BY pd.document, d.updated DESC;
 
 -- name: CreateUpload :exec
-INSERT INTO upload(id, created_at, created_by, meta)
-VALUES (@id, @created_at, @created_by, @meta);
+INSERT INTO upload(
+       id, created_at, created_by, meta, multipart_id, multipart_status
+)
+VALUES (
+       @id, @created_at, @created_by, @meta,
+       sqlc.narg(multipart_id), sqlc.narg(multipart_status)
+);
 
 -- name: GetUpload :one
-SELECT id, created_at, created_by, meta
+SELECT id, created_at, created_by, meta, multipart_id, multipart_status
 FROM upload WHERE id = @id;
 
+-- name: SetUploadMultipartStatus :execrows
+UPDATE upload SET multipart_status = @multipart_status
+WHERE id = @id AND multipart_status = @current_status;
+
+-- name: GetExpiredUploads :many
+SELECT id, created_at, multipart_id, multipart_status
+FROM upload
+WHERE created_at < @cutoff
+      AND (created_at, id) > (@after_created::timestamptz, @after_id::uuid)
+ORDER BY created_at, id
+LIMIT sqlc.arg(count)::bigint;
+
+-- name: DeleteUpload :exec
+DELETE FROM upload WHERE id = @id;
+
 -- name: GetAttachedObject :one
 SELECT
         o.document,
@@ -1082,6 +1317,48 @@ FROM attached_object_current AS c
 WHERE c.document = @document
       AND c.name = @name;
 
+-- name: GetAttachedObjectHistory :many
+SELECT document, name, version, object_version, attached_at,
+       created_by, created_at, meta
+FROM attached_object
+WHERE document = @document
+      AND name = @name
+ORDER BY version DESC;
+
+-- name: GetAttachedObjectVersion :one
+SELECT document, name, version, object_version, attached_at,
+       created_by, created_at, meta
+FROM attached_object
+WHERE document = @document
+      AND name = @name
+      AND version = @version;
+
+-- name: GetAttachedObjectsAttachedAt :many
+SELECT document, name, version, object_version, attached_at,
+       created_by, created_at, meta
+FROM attached_object
+WHERE document = @document
+      AND attached_at = @attached_at
+ORDER BY name;
+
+-- name: GetAttachedObjectsAtVersion :many
+SELECT o.document, o.name, o.version, o.object_version, o.attached_at,
+       o.created_by, o.created_at, o.meta
+FROM attached_object AS o
+     INNER JOIN attached_object_current AS c ON
+           c.document = o.document
+           AND c.name = o.name
+WHERE o.document = @document
+      AND o.version = (
+          SELECT max(h.version)
+          FROM attached_object AS h
+          WHERE h.document = o.document
+                AND h.name = o.name
+                AND h.attached_at <= @doc_version
+      )
+      AND NOT (c.deleted AND c.version = o.version)
+ORDER BY o.name;
+
 -- name: AddAttachedObject :exec
 INSERT INTO attached_object(
        document, name, version, object_version, attached_at,
@@ -1129,6 +1406,30 @@ FROM attached_object_current AS c
 WHERE c.document = @document
       AND c.deleted = false;
 
+-- name: ListCurrentAttachedObjects :many
+SELECT
+        o.document,
+        o.name,
+        o.object_version,
+        o.created_at,
+        o.meta
+FROM attached_object_current AS c
+     INNER JOIN attached_object AS o ON
+           o.document = c.document
+           AND o.name = c.name
+           AND o.version = c.version
+WHERE c.deleted = false
+      AND c.name = @name::text
+      AND c.document > @after_document::uuid
+ORDER BY c.document
+LIMIT sqlc.arg(count)::bigint;
+
+-- name: ListAttachedObjectNames :many
+SELECT DISTINCT name
+FROM attached_object_current
+WHERE deleted = false
+ORDER BY name;
+
 -- name: GetAttachmentsForDocuments :many
 SELECT
         o.document,
@@ -1406,3 +1707,392 @@ INSERT INTO active_schemas(name, version)
        FROM schema_generation_schema sgs
        WHERE sgs.generation_id = @generation_id
 ON CONFLICT (name) DO UPDATE SET version = excluded.version;
+
+-- name: StartSchemaRevalidation :exec
+INSERT INTO schema_revalidation(
+       generation_id, types, status, created, created_by, finished,
+       position, total, processed, failed, error
+) VALUES (
+       @generation_id, @types, 'running', @created, @created_by, NULL,
+       '00000000-0000-0000-0000-000000000000', @total, 0, 0, NULL
+)
+ON CONFLICT (generation_id) DO UPDATE SET
+   types = excluded.types,
+   status = excluded.status,
+   created = excluded.created,
+   created_by = excluded.created_by,
+   finished = NULL,
+   position = excluded.position,
+   total = excluded.total,
+   processed = 0,
+   failed = 0,
+   error = NULL;
+
+-- name: DeleteSchemaRevalidationResults :exec
+DELETE FROM schema_revalidation_result
+WHERE generation_id = @generation_id;
+
+-- name: CountDocumentsOfTypes :one
+SELECT COUNT(*)
+FROM document
+WHERE type = ANY(@types::text[])
+      AND system_state IS NULL;
+
+-- name: GetSchemaRevalidation :one
+SELECT generation_id, types, status, created, created_by, finished,
+       position, total, processed, failed, error
+FROM schema_revalidation
+WHERE generation_id = @generation_id;
+
+-- name: GetRunningSchemaRevalidation :one
+SELECT generation_id, types, status, created, created_by, finished,
+       position, total, processed, failed, error
+FROM schema_revalidation
+WHERE status = 'running'
+ORDER BY created
+LIMIT 1;
+
+-- name: GetCurrentDocumentsOfTypes :many
+SELECT d.uuid, d.type, d.current_version, v.document_data
+FROM document AS d
+     INNER JOIN document_version AS v
+           ON v.uuid = d.uuid AND v.version = d.current_version
+WHERE d.type = ANY(@types::text[])
+      AND d.uuid > @after
+      AND d.system_state IS NULL
+ORDER BY d.uuid
+LIMIT @row_limit;
+
+-- name: InsertSchemaRevalidationResult :exec
+INSERT INTO schema_revalidation_result(
+       generation_id, uuid, type, version, errors
+) VALUES (
+       @generation_id, @uuid, @type, @version, @errors
+)
+ON CONFLICT (generation_id, uuid) DO UPDATE SET
+   type = excluded.type,
+   version = excluded.version,
+   errors = excluded.errors;
+
+-- name: UpdateSchemaRevalidationProgress :execrows
+UPDATE schema_revalidation
+SET position = @position,
+    processed = processed + @processed::bigint,
+    failed = failed + @failed::bigint
+WHERE generation_id = @generation_id
+      AND created = @created
+      AND status = 'running';
+
+-- name: FinishSchemaRevalidation :exec
+UPDATE schema_revalidation
+SET status = @status, finished = @finished, error = sqlc.narg('error')
+WHERE generation_id = @generation_id
+      AND created = @created
+      AND status = 'running';
+
+-- name: GetSchemaRevalidationFailures :many
+SELECT generation_id, uuid, type, version, errors
+FROM schema_revalidation_result
+WHERE generation_id = @generation_id
+      AND uuid > @after
+ORDER BY uuid
+LIMIT @row_limit;
+
+-- name: SetSchemaGenerationTransforms :exec
+INSERT INTO schema_generation_transform(
+       generation_id, transforms, updated, updated_by
+) VALUES (
+       @generation_id, @transforms, @updated, @updated_by
+)
+ON CONFLICT (generation_id) DO UPDATE SET
+   transforms = excluded.transforms,
+   updated = excluded.updated,
+   updated_by = excluded.updated_by;
+
+-- name: GetSchemaGenerationTransforms :one
+SELECT transforms
+FROM schema_generation_transform
+WHERE generation_id = @generation_id;
+
+-- name: GetActiveGenerationTransforms :one
+SELECT t.transforms
+FROM schema_generation AS sg
+     INNER JOIN schema_generation_transform AS t
+           ON t.generation_id = sg.id
+WHERE sg.status = 'active';
+
+-- name: StartSchemaUpgrade :exec
+INSERT INTO schema_upgrade(
+       generation_id, types, status, created, created_by, finished,
+       position, processed, upgraded, failed, error
+) VALUES (
+       @generation_id, @types, 'running', @created, @created_by, NULL,
+       '00000000-0000-0000-0000-000000000000', 0, 0, 0, NULL
+)
+ON CONFLICT (generation_id) DO UPDATE SET
+   types = excluded.types,
+   status = excluded.status,
+   created = excluded.created,
+   created_by = excluded.created_by,
+   finished = NULL,
+   position = excluded.position,
+   processed = 0,
+   upgraded = 0,
+   failed = 0,
+   error = NULL;
+
+-- name: GetSchemaUpgrade :one
+SELECT generation_id, types, status, created, created_by, finished,
+       position, processed, upgraded, failed, error
+FROM schema_upgrade
+WHERE generation_id = @generation_id;
+
+-- name: GetRunningSchemaUpgrade :one
+SELECT generation_id, types, status, created, created_by, finished,
+       position, processed, upgraded, failed, error
+FROM schema_upgrade
+WHERE status = 'running'
+ORDER BY created
+LIMIT 1;
+
+-- name: UpdateSchemaUpgradeProgress :execrows
+UPDATE schema_upgrade
+SET position = @position,
+    processed = processed + @processed::bigint,
+    upgraded = upgraded + @upgraded::bigint,
+    failed = failed + @failed::bigint
+WHERE generation_id = @generation_id
+      AND created = @created
+      AND status = 'running';
+
+-- name: FinishSchemaUpgrade :exec
+UPDATE schema_upgrade
+SET status = @status, finished = @finished, error = sqlc.narg('error')
+WHERE generation_id = @generation_id
+      AND created = @created
+      AND status = 'running';
+
+-- name: InsertReadAudit :exec
+WITH reads AS (
+     SELECT unnest(@uuids::uuid[]) AS uuid,
+            unnest(@versions::bigint[]) AS version,
+            unnest(@attachments::text[]) AS attachment
+)
+INSERT INTO read_audit(
+       uuid, type, version, operation, attachment,
+       subject, app, client_ip, created
+)
+SELECT r.uuid, d.type, r.version, @operation::text,
+       NULLIF(r.attachment, ''), @subject::text,
+       NULLIF(@app::text, ''), NULLIF(@client_ip::text, ''),
+       @created::timestamptz
+FROM reads AS r
+     INNER JOIN document AS d ON d.uuid = r.uuid
+WHERE d.type = ANY(@types::text[]);
+
+-- name: GetReadAudit :many
+SELECT id, uuid, type, version, operation, attachment, subject, app,
+       client_ip, created
+FROM read_audit
+WHERE (sqlc.narg('uuid')::uuid IS NULL OR uuid = @uuid)
+      AND (sqlc.narg('subject')::text IS NULL OR subject = @subject)
+      AND id > @after
+ORDER BY id
+LIMIT @row_limit;
+
+-- name: GetReadAuditArchiver :one
+SELECT position, last_signature
+FROM read_audit_archiver
+LIMIT 1;
+
+-- name: SetReadAuditArchiver :exec
+INSERT INTO read_audit_archiver(position, last_signature)
+       VALUES (@position, @last_signature)
+ON CONFLICT (id) DO UPDATE
+   SET position = @position, last_signature = @last_signature;
+
+-- name: DropDocumentLinks :exec
+DELETE FROM document_link WHERE from_document = @uuid;
+
+-- name: InsertDocumentLinks :exec
+INSERT INTO document_link(from_document, to_document, rel, type)
+SELECT @uuid::uuid, l.to_document, l.rel, l.type
+FROM unnest(
+     @to_documents::uuid[], @rels::text[], @types::text[]
+) AS l(to_document, rel, type)
+ON CONFLICT DO NOTHING;
+
+-- name: GetBacklinks :many
+SELECT l.from_document AS uuid, d.type, d.current_version,
+       array_agg(DISTINCT l.rel ORDER BY l.rel)::text[] AS rels
+FROM document_link AS l
+     INNER JOIN document AS d ON d.uuid = l.from_document
+WHERE l.to_document = @uuid
+      AND l.from_document > @after
+      AND (sqlc.narg('rel')::text IS NULL OR l.rel = @rel)
+      AND (sqlc.narg('type')::text IS NULL OR d.type = @type)
+      AND d.system_state IS NULL
+GROUP BY l.from_document, d.type, d.current_version
+ORDER BY l.from_document
+LIMIT @row_limit;
+
+-- name: GetRecentDocumentsOfType :many
+SELECT d.uuid, v.document_data
+FROM document AS d
+     INNER JOIN document_version AS v
+           ON v.uuid = d.uuid AND v.version = d.current_version
+WHERE d.type = @type
+      AND d.system_state IS NULL
+      AND v.document_data IS NOT NULL
+ORDER BY d.updated DESC
+LIMIT @row_limit;
+
+-- name: DropCollectedExemplars :exec
+DELETE FROM schema_generation_exemplar
+WHERE generation_id = @generation_id AND collected;
+
+-- name: InsertCollectedExemplar :exec
+INSERT INTO schema_generation_exemplar(generation_id, name, version, collected)
+       VALUES (@generation_id, @name, @version, true)
+ON CONFLICT (generation_id, name) DO NOTHING;
+
+-- name: CopyCollectedExemplars :exec
+INSERT INTO schema_generation_exemplar(generation_id, name, version, collected)
+SELECT @to_generation::bigint, name, version, true
+FROM schema_generation_exemplar
+WHERE generation_id = @from_generation AND collected
+ON CONFLICT (generation_id, name) DO NOTHING;
+
+-- name: GetCollectedExemplars :many
+SELECT se.name, se.version, se.doc_type, se.document
+FROM schema_generation_exemplar sge
+     INNER JOIN schema_exemplar se
+           ON se.name = sge.name AND se.version = sge.version
+WHERE sge.generation_id = @generation_id
+      AND sge.collected
+ORDER BY se.name;
+
+-- name: DeleteUnreferencedCollectedExemplars :exec
+DELETE FROM schema_exemplar AS se
+WHERE se.name LIKE 'collected://%'
+      AND NOT EXISTS (
+          SELECT 1 FROM schema_generation_exemplar AS sge
+          WHERE sge.name = se.name AND sge.version = se.version
+      );
+
+-- name: InsertDocumentTemplate :one
+INSERT INTO document_template(
+       name, version, type, description, document, variables, acl,
+       created, created_by
+) VALUES (
+       @name,
+       (SELECT COALESCE(MAX(t.version), 0) + 1
+        FROM document_template AS t WHERE t.name = @name),
+       @type, @description, @document, @variables, @acl,
+       @created, @created_by
+)
+RETURNING version;
+
+-- name: GetDocumentTemplate :one
+SELECT name, version, type, description, document, variables, acl,
+       created, created_by
+FROM document_template
+WHERE name = @name
+      AND (sqlc.arg(version)::bigint = 0 OR version = sqlc.arg(version)::bigint)
+ORDER BY version DESC
+LIMIT 1;
+
+-- name: ListDocumentTemplates :many
+SELECT DISTINCT ON (name)
+       name, version, type, description, document, variables, acl,
+       created, created_by
+FROM document_template
+WHERE sqlc.narg('type')::text IS NULL OR type = @type
+ORDER BY name, version DESC;
+
+-- name: DeleteDocumentTemplate :execrows
+DELETE FROM document_template WHERE name = @name;
+
+-- name: CreateDocumentExport :one
+INSERT INTO document_export(
+       uuids, type, time_range, labels, status, created, created_by
+) VALUES (
+       @uuids, sqlc.narg('type'), @time_range, @labels, 'pending',
+       @created, @created_by
+)
+RETURNING id;
+
+-- name: GetDocumentExport :one
+SELECT id, uuids, type, time_range, labels, status, created, created_by,
+       started, finished, total, processed, object_key, size, signature,
+       error
+FROM document_export
+WHERE id = @id;
+
+-- name: GetNextDocumentExport :one
+SELECT id, uuids, type, time_range, labels, status, created, created_by,
+       started, finished, total, processed, object_key, size, signature,
+       error
+FROM document_export
+WHERE finished IS NULL
+ORDER BY id
+LIMIT 1;
+
+-- name: StartDocumentExport :exec
+UPDATE document_export
+SET status = 'running', started = @started, total = @total, processed = 0
+WHERE id = @id;
+
+-- name: UpdateDocumentExportProgress :exec
+UPDATE document_export
+SET processed = @processed
+WHERE id = @id;
+
+-- name: FinishDocumentExport :exec
+UPDATE document_export
+SET status = @status, finished = @finished,
+    object_key = sqlc.narg('object_key'), size = sqlc.narg('size'),
+    signature = sqlc.narg('signature'), error = sqlc.narg('error')
+WHERE id = @id;
+
+-- name: CountExportDocuments :one
+SELECT COUNT(*)
+FROM document AS d
+WHERE d.system_state IS NULL
+      AND (COALESCE(cardinality(@uuids::uuid[]), 0) = 0 OR d.uuid = ANY(@uuids))
+      AND (sqlc.narg('type')::text IS NULL OR d.type = @type)
+      AND (sqlc.narg('time_range')::tstzrange IS NULL OR d.time && @time_range)
+      AND (COALESCE(cardinality(@labels::text[]), 0) = 0 OR d.labels @> @labels);
+
+-- name: GetExportDocuments :many
+SELECT d.uuid
+FROM document AS d
+WHERE d.uuid > @after
+      AND d.system_state IS NULL
+      AND (COALESCE(cardinality(@uuids::uuid[]), 0) = 0 OR d.uuid = ANY(@uuids))
+      AND (sqlc.narg('type')::text IS NULL OR d.type = @type)
+      AND (sqlc.narg('time_range')::tstzrange IS NULL OR d.time && @time_range)
+      AND (COALESCE(cardinality(@labels::text[]), 0) = 0 OR d.labels @> @labels)
+ORDER BY d.uuid
+LIMIT @row_limit;
+
+-- name: GetArchivedVersionSignatures :many
+SELECT version, signature
+FROM document_version
+WHERE uuid = @uuid
+      AND archived = true
+ORDER BY version;
+
+-- name: GetArchivedStatusSignatures :many
+SELECT name, id, signature
+FROM document_status
+WHERE uuid = @uuid
+      AND archived = true
+ORDER BY name, id;
+
+-- name: GetAllAttachedObjects :many
+SELECT document, name, version, object_version, attached_at,
+       created_by, created_at, meta
+FROM attached_object
+WHERE document = @document
+ORDER BY name, version;
@@ -105,21 +105,42 @@ func (q *Queries) AddEventToOutbox(ctx context.Context, event OutboxEvent) (int6
 const bulkCheckPermissions = `-- name: BulkCheckPermissions :many
 SELECT d.uuid
 FROM document AS d
-     INNER JOIN acl
-          ON (acl.uuid = d.uuid OR acl.uuid = d.main_doc)
-          AND acl.uri = ANY($1::text[])
-          AND $2::text[] && permissions
-WHERE d.uuid = ANY($3::uuid[])
+WHERE d.uuid = ANY($1::uuid[])
+      AND (
+        EXISTS (
+          SELECT 1 FROM acl
+          WHERE (acl.uuid = d.uuid OR acl.uuid = d.main_doc)
+                AND acl.uri = ANY($2::text[])
+                AND $3::text[] && acl.permissions
+                AND (acl.not_before IS NULL OR acl.not_before <= now())
+                AND (acl.expires IS NULL OR acl.expires > now())
+        )
+        OR EXISTS (
+          SELECT 1
+          FROM acl_inheritance AS ai
+               INNER JOIN acl AS pa
+                     ON pa.uuid = ai.parent
+                     AND pa.uri = ANY($2::text[])
+                     AND (pa.not_before IS NULL OR pa.not_before <= now())
+                     AND (pa.expires IS NULL OR pa.expires > now())
+          WHERE (ai.uuid = d.uuid OR ai.uuid = d.main_doc)
+                AND EXISTS (
+                  SELECT 1 FROM unnest($3::text[]) AS p(name)
+                  WHERE p.name = ANY(ai.permissions)
+                        AND p.name = ANY(pa.permissions)
+                )
+        )
+      )
 `
 
 type BulkCheckPermissionsParams struct {
+	Uuids       []uuid.UUID
 	URI         []string
 	Permissions []string
-	Uuids       []uuid.UUID
 }
 
 func (q *Queries) BulkCheckPermissions(ctx context.Context, arg BulkCheckPermissionsParams) ([]uuid.UUID, error) {
-	rows, err := q.db.Query(ctx, bulkCheckPermissions, arg.URI, arg.Permissions, arg.Uuids)
+	rows, err := q.db.Query(ctx, bulkCheckPermissions, arg.Uuids, arg.URI, arg.Permissions)
 	if err != nil {
 		return nil, err
 	}
@@ -220,7 +241,7 @@ func (q *Queries) BulkGetDeliverableInfo(ctx context.Context, uuids []uuid.UUID)
 }
 
 const bulkGetDocumentACL = `-- name: BulkGetDocumentACL :many
-SELECT uuid, uri, permissions
+SELECT uuid, uri, permissions, not_before, expires
 FROM acl
 WHERE uuid = ANY($1::uuid[])
 `
@@ -234,7 +255,13 @@ func (q *Queries) BulkGetDocumentACL(ctx context.Context, uuids []uuid.UUID) ([]
 	var items []Acl
 	for rows.Next() {
 		var i Acl
-		if err := rows.Scan(&i.UUID, &i.URI, &i.Permissions); err != nil {
+		if err := rows.Scan(
+			&i.UUID,
+			&i.URI,
+			&i.Permissions,
+			&i.NotBefore,
+			&i.Expires,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -538,12 +565,32 @@ func (q *Queries) CheckMetaDocumentType(ctx context.Context, argUuid uuid.UUID)
 }
 
 const checkPermissions = `-- name: CheckPermissions :one
-SELECT (acl.uri IS NOT NULL) = true AS has_access, d.system_state
+SELECT (
+         EXISTS (
+           SELECT 1 FROM acl
+           WHERE (acl.uuid = d.uuid OR acl.uuid = d.main_doc)
+                 AND acl.uri = ANY($1::text[])
+                 AND $2::text[] && acl.permissions
+                 AND (acl.not_before IS NULL OR acl.not_before <= now())
+                 AND (acl.expires IS NULL OR acl.expires > now())
+         )
+         OR EXISTS (
+           SELECT 1
+           FROM acl_inheritance AS ai
+                INNER JOIN acl AS pa
+                      ON pa.uuid = ai.parent
+                      AND pa.uri = ANY($1::text[])
+                      AND (pa.not_before IS NULL OR pa.not_before <= now())
+                      AND (pa.expires IS NULL OR pa.expires > now())
+           WHERE (ai.uuid = d.uuid OR ai.uuid = d.main_doc)
+                 AND EXISTS (
+                   SELECT 1 FROM unnest($2::text[]) AS p(name)
+                   WHERE p.name = ANY(ai.permissions)
+                         AND p.name = ANY(pa.permissions)
+                 )
+         )
+       )::bool AS has_access, d.system_state
 FROM document AS d
-     LEFT JOIN acl
-          ON (acl.uuid = d.uuid OR acl.uuid = d.main_doc)
-          AND acl.uri = ANY($1::text[])
-          AND $2::text[] && permissions
 WHERE d.uuid = $3
 `
 
@@ -619,6 +666,15 @@ func (q *Queries) ClearActiveSchemas(ctx context.Context) error {
 	return err
 }
 
+const clearDeprecationUsageScans = `-- name: ClearDeprecationUsageScans :exec
+DELETE FROM deprecation_usage_scan
+`
+
+func (q *Queries) ClearDeprecationUsageScans(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, clearDeprecationUsageScans)
+	return err
+}
+
 const clearSystemState = `-- name: ClearSystemState :exec
 UPDATE document SET system_state = NULL
 WHERE uuid = $1 AND NOT system_state IS NULL
@@ -645,6 +701,100 @@ func (q *Queries) ConfigureEventsink(ctx context.Context, arg ConfigureEventsink
 	return err
 }
 
+const copyCollectedExemplars = `-- name: CopyCollectedExemplars :exec
+INSERT INTO schema_generation_exemplar(generation_id, name, version, collected)
+SELECT $1::bigint, name, version, true
+FROM schema_generation_exemplar
+WHERE generation_id = $2 AND collected
+ON CONFLICT (generation_id, name) DO NOTHING
+`
+
+type CopyCollectedExemplarsParams struct {
+	ToGeneration   int64
+	FromGeneration int64
+}
+
+func (q *Queries) CopyCollectedExemplars(ctx context.Context, arg CopyCollectedExemplarsParams) error {
+	_, err := q.db.Exec(ctx, copyCollectedExemplars, arg.ToGeneration, arg.FromGeneration)
+	return err
+}
+
+const countDocumentsOfTypes = `-- name: CountDocumentsOfTypes :one
+SELECT COUNT(*)
+FROM document
+WHERE type = ANY($1::text[])
+      AND system_state IS NULL
+`
+
+func (q *Queries) CountDocumentsOfTypes(ctx context.Context, types []string) (int64, error) {
+	row := q.db.QueryRow(ctx, countDocumentsOfTypes, types)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countExportDocuments = `-- name: CountExportDocuments :one
+SELECT COUNT(*)
+FROM document AS d
+WHERE d.system_state IS NULL
+      AND (COALESCE(cardinality($1::uuid[]), 0) = 0 OR d.uuid = ANY($1))
+      AND ($2::text IS NULL OR d.type = $2)
+      AND ($3::tstzrange IS NULL OR d.time && $3)
+      AND (COALESCE(cardinality($4::text[]), 0) = 0 OR d.labels @> $4)
+`
+
+type CountExportDocumentsParams struct {
+	Uuids     []uuid.UUID
+	Type      pgtype.Text
+	TimeRange pgtype.Range[pgtype.Timestamptz]
+	Labels    []string
+}
+
+func (q *Queries) CountExportDocuments(ctx context.Context, arg CountExportDocumentsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countExportDocuments,
+		arg.Uuids,
+		arg.Type,
+		arg.TimeRange,
+		arg.Labels,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createDocumentExport = `-- name: CreateDocumentExport :one
+INSERT INTO document_export(
+       uuids, type, time_range, labels, status, created, created_by
+) VALUES (
+       $1, $2, $3, $4, 'pending',
+       $5, $6
+)
+RETURNING id
+`
+
+type CreateDocumentExportParams struct {
+	Uuids     []uuid.UUID
+	Type      pgtype.Text
+	TimeRange pgtype.Range[pgtype.Timestamptz]
+	Labels    []string
+	Created   pgtype.Timestamptz
+	CreatedBy string
+}
+
+func (q *Queries) CreateDocumentExport(ctx context.Context, arg CreateDocumentExportParams) (int64, error) {
+	row := q.db.QueryRow(ctx, createDocumentExport,
+		arg.Uuids,
+		arg.Type,
+		arg.TimeRange,
+		arg.Labels,
+		arg.Created,
+		arg.CreatedBy,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
 const createDocumentVersion = `-- name: CreateDocumentVersion :exec
 INSERT INTO document_version(
        uuid, version,
@@ -730,15 +880,22 @@ func (q *Queries) CreateStatusHead(ctx context.Context, arg CreateStatusHeadPara
 }
 
 const createUpload = `-- name: CreateUpload :exec
-INSERT INTO upload(id, created_at, created_by, meta)
-VALUES ($1, $2, $3, $4)
+INSERT INTO upload(
+       id, created_at, created_by, meta, multipart_id, multipart_status
+)
+VALUES (
+       $1, $2, $3, $4,
+       $5, $6
+)
 `
 
 type CreateUploadParams struct {
-	ID        uuid.UUID
-	CreatedAt pgtype.Timestamptz
-	CreatedBy string
-	Meta      AssetMetadata
+	ID              uuid.UUID
+	CreatedAt       pgtype.Timestamptz
+	CreatedBy       string
+	Meta            AssetMetadata
+	MultipartID     pgtype.Text
+	MultipartStatus pgtype.Text
 }
 
 func (q *Queries) CreateUpload(ctx context.Context, arg CreateUploadParams) error {
@@ -747,6 +904,8 @@ func (q *Queries) CreateUpload(ctx context.Context, arg CreateUploadParams) erro
 		arg.CreatedAt,
 		arg.CreatedBy,
 		arg.Meta,
+		arg.MultipartID,
+		arg.MultipartStatus,
 	)
 	return err
 }
@@ -809,6 +968,15 @@ func (q *Queries) DeactivateSchemaGeneration(ctx context.Context, arg Deactivate
 	return err
 }
 
+const deleteACLInheritanceRules = `-- name: DeleteACLInheritanceRules :exec
+DELETE FROM acl_inheritance_rule WHERE type = $1
+`
+
+func (q *Queries) DeleteACLInheritanceRules(ctx context.Context, type_ string) error {
+	_, err := q.db.Exec(ctx, deleteACLInheritanceRules, type_)
+	return err
+}
+
 const deleteDocumentEntry = `-- name: DeleteDocumentEntry :exec
 DELETE FROM document WHERE uuid = $1
 `
@@ -837,6 +1005,18 @@ func (q *Queries) DeleteDocumentLock(ctx context.Context, arg DeleteDocumentLock
 	return result.RowsAffected(), nil
 }
 
+const deleteDocumentTemplate = `-- name: DeleteDocumentTemplate :execrows
+DELETE FROM document_template WHERE name = $1
+`
+
+func (q *Queries) DeleteDocumentTemplate(ctx context.Context, name string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteDocumentTemplate, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteDocumentUnarchivedCounter = `-- name: DeleteDocumentUnarchivedCounter :exec
 DELETE FROM document_archive_counter
 WHERE uuid = $1
@@ -903,6 +1083,16 @@ func (q *Queries) DeletePlanningItem(ctx context.Context, argUuid uuid.UUID) err
 	return err
 }
 
+const deleteSchemaRevalidationResults = `-- name: DeleteSchemaRevalidationResults :exec
+DELETE FROM schema_revalidation_result
+WHERE generation_id = $1
+`
+
+func (q *Queries) DeleteSchemaRevalidationResults(ctx context.Context, generationID int64) error {
+	_, err := q.db.Exec(ctx, deleteSchemaRevalidationResults, generationID)
+	return err
+}
+
 const deleteStatusRule = `-- name: DeleteStatusRule :exec
 DELETE FROM status_rule WHERE type = $1 AND name = $2
 `
@@ -917,6 +1107,29 @@ func (q *Queries) DeleteStatusRule(ctx context.Context, arg DeleteStatusRulePara
 	return err
 }
 
+const deleteUnreferencedCollectedExemplars = `-- name: DeleteUnreferencedCollectedExemplars :exec
+DELETE FROM schema_exemplar AS se
+WHERE se.name LIKE 'collected://%'
+      AND NOT EXISTS (
+          SELECT 1 FROM schema_generation_exemplar AS sge
+          WHERE sge.name = se.name AND sge.version = se.version
+      )
+`
+
+func (q *Queries) DeleteUnreferencedCollectedExemplars(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteUnreferencedCollectedExemplars)
+	return err
+}
+
+const deleteUpload = `-- name: DeleteUpload :exec
+DELETE FROM upload WHERE id = $1
+`
+
+func (q *Queries) DeleteUpload(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUpload, id)
+	return err
+}
+
 const dropACL = `-- name: DropACL :exec
 DELETE FROM acl WHERE uuid = $1 AND uri = $2
 `
@@ -931,6 +1144,43 @@ func (q *Queries) DropACL(ctx context.Context, arg DropACLParams) error {
 	return err
 }
 
+const dropCollectedExemplars = `-- name: DropCollectedExemplars :exec
+DELETE FROM schema_generation_exemplar
+WHERE generation_id = $1 AND collected
+`
+
+func (q *Queries) DropCollectedExemplars(ctx context.Context, generationID int64) error {
+	_, err := q.db.Exec(ctx, dropCollectedExemplars, generationID)
+	return err
+}
+
+const dropDeprecationUsage = `-- name: DropDeprecationUsage :exec
+DELETE FROM deprecation_usage WHERE uuid = $1
+`
+
+func (q *Queries) DropDeprecationUsage(ctx context.Context, argUuid uuid.UUID) error {
+	_, err := q.db.Exec(ctx, dropDeprecationUsage, argUuid)
+	return err
+}
+
+const dropDocumentACLInheritance = `-- name: DropDocumentACLInheritance :exec
+DELETE FROM acl_inheritance WHERE uuid = $1
+`
+
+func (q *Queries) DropDocumentACLInheritance(ctx context.Context, argUuid uuid.UUID) error {
+	_, err := q.db.Exec(ctx, dropDocumentACLInheritance, argUuid)
+	return err
+}
+
+const dropDocumentLinks = `-- name: DropDocumentLinks :exec
+DELETE FROM document_link WHERE from_document = $1
+`
+
+func (q *Queries) DropDocumentLinks(ctx context.Context, uuid uuid.UUID) error {
+	_, err := q.db.Exec(ctx, dropDocumentLinks, uuid)
+	return err
+}
+
 const dropInvalidRestoreRequests = `-- name: DropInvalidRestoreRequests :exec
 DELETE FROM restore_request AS rr
 WHERE rr.finished IS NULL
@@ -1017,6 +1267,73 @@ func (q *Queries) FinaliseDocumentDelete(ctx context.Context, argUuid uuid.UUID)
 	return result.RowsAffected(), nil
 }
 
+const finishACLInheritanceReapply = `-- name: FinishACLInheritanceReapply :execrows
+DELETE FROM acl_inheritance_reapply
+WHERE type = $1 AND created = $2
+`
+
+type FinishACLInheritanceReapplyParams struct {
+	Type    string
+	Created pgtype.Timestamptz
+}
+
+func (q *Queries) FinishACLInheritanceReapply(ctx context.Context, arg FinishACLInheritanceReapplyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, finishACLInheritanceReapply, arg.Type, arg.Created)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const finishDeprecationUsageScan = `-- name: FinishDeprecationUsageScan :execrows
+DELETE FROM deprecation_usage_scan
+WHERE generation_id = $1 AND created = $2
+`
+
+type FinishDeprecationUsageScanParams struct {
+	GenerationID int64
+	Created      pgtype.Timestamptz
+}
+
+func (q *Queries) FinishDeprecationUsageScan(ctx context.Context, arg FinishDeprecationUsageScanParams) (int64, error) {
+	result, err := q.db.Exec(ctx, finishDeprecationUsageScan, arg.GenerationID, arg.Created)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const finishDocumentExport = `-- name: FinishDocumentExport :exec
+UPDATE document_export
+SET status = $1, finished = $2,
+    object_key = $3, size = $4,
+    signature = $5, error = $6
+WHERE id = $7
+`
+
+type FinishDocumentExportParams struct {
+	Status    string
+	Finished  pgtype.Timestamptz
+	ObjectKey pgtype.Text
+	Size      pgtype.Int8
+	Signature pgtype.Text
+	Error     pgtype.Text
+	ID        int64
+}
+
+func (q *Queries) FinishDocumentExport(ctx context.Context, arg FinishDocumentExportParams) error {
+	_, err := q.db.Exec(ctx, finishDocumentExport,
+		arg.Status,
+		arg.Finished,
+		arg.ObjectKey,
+		arg.Size,
+		arg.Signature,
+		arg.Error,
+		arg.ID,
+	)
+	return err
+}
+
 const finishPurgeRequest = `-- name: FinishPurgeRequest :exec
 UPDATE purge_request
 SET finished = $1
@@ -1049,95 +1366,96 @@ func (q *Queries) FinishRestoreRequest(ctx context.Context, arg FinishRestoreReq
 	return err
 }
 
-const getActiveGenerationSchemas = `-- name: GetActiveGenerationSchemas :many
-SELECT sgs.name, sgs.version, ds.spec
-FROM schema_generation sg
-     INNER JOIN schema_generation_schema sgs
-           ON sgs.generation_id = sg.id
-     INNER JOIN document_schema ds
-           ON ds.name = sgs.name AND ds.version = sgs.version
-WHERE sg.status = 'active'
-ORDER BY sgs.name
+const finishSchemaRevalidation = `-- name: FinishSchemaRevalidation :exec
+UPDATE schema_revalidation
+SET status = $1, finished = $2, error = $3
+WHERE generation_id = $4
+      AND created = $5
+      AND status = 'running'
 `
 
-type GetActiveGenerationSchemasRow struct {
-	Name    string
-	Version string
-	Spec    []byte
+type FinishSchemaRevalidationParams struct {
+	Status       string
+	Finished     pgtype.Timestamptz
+	Error        pgtype.Text
+	GenerationID int64
+	Created      pgtype.Timestamptz
 }
 
-func (q *Queries) GetActiveGenerationSchemas(ctx context.Context) ([]GetActiveGenerationSchemasRow, error) {
-	rows, err := q.db.Query(ctx, getActiveGenerationSchemas)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []GetActiveGenerationSchemasRow
-	for rows.Next() {
-		var i GetActiveGenerationSchemasRow
-		if err := rows.Scan(&i.Name, &i.Version, &i.Spec); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) FinishSchemaRevalidation(ctx context.Context, arg FinishSchemaRevalidationParams) error {
+	_, err := q.db.Exec(ctx, finishSchemaRevalidation,
+		arg.Status,
+		arg.Finished,
+		arg.Error,
+		arg.GenerationID,
+		arg.Created,
+	)
+	return err
 }
 
-const getActiveSchema = `-- name: GetActiveSchema :one
-SELECT s.name, s.version, s.spec
-FROM active_schemas AS a
-     INNER JOIN document_schema AS s
-           ON s.name = a.name AND s.version = a.version
-WHERE a.name = $1
+const finishSchemaUpgrade = `-- name: FinishSchemaUpgrade :exec
+UPDATE schema_upgrade
+SET status = $1, finished = $2, error = $3
+WHERE generation_id = $4
+      AND created = $5
+      AND status = 'running'
 `
 
-func (q *Queries) GetActiveSchema(ctx context.Context, name string) (DocumentSchema, error) {
-	row := q.db.QueryRow(ctx, getActiveSchema, name)
-	var i DocumentSchema
-	err := row.Scan(&i.Name, &i.Version, &i.Spec)
-	return i, err
+type FinishSchemaUpgradeParams struct {
+	Status       string
+	Finished     pgtype.Timestamptz
+	Error        pgtype.Text
+	GenerationID int64
+	Created      pgtype.Timestamptz
 }
 
-const getActiveSchemaGeneration = `-- name: GetActiveSchemaGeneration :one
-SELECT id, identity_hash, status, created, activated, deactivated
-FROM schema_generation
-WHERE status = 'active'
+func (q *Queries) FinishSchemaUpgrade(ctx context.Context, arg FinishSchemaUpgradeParams) error {
+	_, err := q.db.Exec(ctx, finishSchemaUpgrade,
+		arg.Status,
+		arg.Finished,
+		arg.Error,
+		arg.GenerationID,
+		arg.Created,
+	)
+	return err
+}
+
+const getACLInheritanceReapply = `-- name: GetACLInheritanceReapply :one
+SELECT type, position, created
+FROM acl_inheritance_reapply
+ORDER BY created
+LIMIT 1
 `
 
-func (q *Queries) GetActiveSchemaGeneration(ctx context.Context) (SchemaGeneration, error) {
-	row := q.db.QueryRow(ctx, getActiveSchemaGeneration)
-	var i SchemaGeneration
-	err := row.Scan(
-		&i.ID,
-		&i.IdentityHash,
-		&i.Status,
-		&i.Created,
-		&i.Activated,
-		&i.Deactivated,
-	)
+func (q *Queries) GetACLInheritanceReapply(ctx context.Context) (AclInheritanceReapply, error) {
+	row := q.db.QueryRow(ctx, getACLInheritanceReapply)
+	var i AclInheritanceReapply
+	err := row.Scan(&i.Type, &i.Position, &i.Created)
 	return i, err
 }
 
-const getActiveSchemas = `-- name: GetActiveSchemas :many
-SELECT s.name, s.version, s.spec
-FROM active_schemas AS a
-     INNER JOIN document_schema AS s
-           ON s.name = a.name AND s.version = a.version
+const getACLInheritanceRules = `-- name: GetACLInheritanceRules :many
+SELECT type, rel, link_type, permissions
+FROM acl_inheritance_rule
+WHERE type = $1
+ORDER BY rel, link_type
 `
 
-func (q *Queries) GetActiveSchemas(ctx context.Context) ([]DocumentSchema, error) {
-	rows, err := q.db.Query(ctx, getActiveSchemas)
+func (q *Queries) GetACLInheritanceRules(ctx context.Context, type_ string) ([]AclInheritanceRule, error) {
+	rows, err := q.db.Query(ctx, getACLInheritanceRules, type_)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []DocumentSchema
+	var items []AclInheritanceRule
 	for rows.Next() {
-		var i DocumentSchema
-		if err := rows.Scan(&i.Name, &i.Version, &i.Spec); err != nil {
+		var i AclInheritanceRule
+		if err := rows.Scan(
+			&i.Type,
+			&i.Rel,
+			&i.LinkType,
+			&i.Permissions,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -1148,24 +1466,258 @@ func (q *Queries) GetActiveSchemas(ctx context.Context) ([]DocumentSchema, error
 	return items, nil
 }
 
-const getActiveStatuses = `-- name: GetActiveStatuses :many
-SELECT type, name
-FROM status
-WHERE disabled = false
-      AND ($1::text IS NULL OR type = $1)
+const getACLInheritors = `-- name: GetACLInheritors :many
+SELECT d.uuid, d.type, d.language, d.current_version, d.nonce, d.main_doc,
+       d.main_doc_type, d.system_state, d.labels, d.time
+FROM acl_inheritance AS ai
+     INNER JOIN document AS d ON d.uuid = ai.uuid
+WHERE ai.parent = $1
 `
 
-type GetActiveStatusesRow struct {
-	Type string
-	Name string
-}
-
-func (q *Queries) GetActiveStatuses(ctx context.Context, type_ pgtype.Text) ([]GetActiveStatusesRow, error) {
-	rows, err := q.db.Query(ctx, getActiveStatuses, type_)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+type GetACLInheritorsRow struct {
+	UUID           uuid.UUID
+	Type           string
+	Language       pgtype.Text
+	CurrentVersion int64
+	Nonce          uuid.UUID
+	MainDoc        pgtype.UUID
+	MainDocType    pgtype.Text
+	SystemState    pgtype.Text
+	Labels         []string
+	Time           pgtype.Multirange[pgtype.Range[pgtype.Timestamptz]]
+}
+
+func (q *Queries) GetACLInheritors(ctx context.Context, parent uuid.UUID) ([]GetACLInheritorsRow, error) {
+	rows, err := q.db.Query(ctx, getACLInheritors, parent)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetACLInheritorsRow
+	for rows.Next() {
+		var i GetACLInheritorsRow
+		if err := rows.Scan(
+			&i.UUID,
+			&i.Type,
+			&i.Language,
+			&i.CurrentVersion,
+			&i.Nonce,
+			&i.MainDoc,
+			&i.MainDocType,
+			&i.SystemState,
+			&i.Labels,
+			&i.Time,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getACLTransitions = `-- name: GetACLTransitions :many
+SELECT a.uuid, a.uri, a.permissions, a.not_before, a.expires,
+       d.type, d.language, d.current_version, d.nonce, d.main_doc,
+       d.main_doc_type, d.labels, d.time
+FROM acl AS a
+     INNER JOIN document AS d ON d.uuid = a.uuid
+WHERE (a.expires <= $1 OR a.not_before <= $1)
+      AND d.system_state IS NULL
+ORDER BY a.uuid
+LIMIT $2::bigint
+FOR UPDATE OF d SKIP LOCKED
+`
+
+type GetACLTransitionsParams struct {
+	Now   pgtype.Timestamptz
+	Count int64
+}
+
+type GetACLTransitionsRow struct {
+	UUID           uuid.UUID
+	URI            string
+	Permissions    []string
+	NotBefore      pgtype.Timestamptz
+	Expires        pgtype.Timestamptz
+	Type           string
+	Language       pgtype.Text
+	CurrentVersion int64
+	Nonce          uuid.UUID
+	MainDoc        pgtype.UUID
+	MainDocType    pgtype.Text
+	Labels         []string
+	Time           pgtype.Multirange[pgtype.Range[pgtype.Timestamptz]]
+}
+
+func (q *Queries) GetACLTransitions(ctx context.Context, arg GetACLTransitionsParams) ([]GetACLTransitionsRow, error) {
+	rows, err := q.db.Query(ctx, getACLTransitions, arg.Now, arg.Count)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetACLTransitionsRow
+	for rows.Next() {
+		var i GetACLTransitionsRow
+		if err := rows.Scan(
+			&i.UUID,
+			&i.URI,
+			&i.Permissions,
+			&i.NotBefore,
+			&i.Expires,
+			&i.Type,
+			&i.Language,
+			&i.CurrentVersion,
+			&i.Nonce,
+			&i.MainDoc,
+			&i.MainDocType,
+			&i.Labels,
+			&i.Time,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getActiveGenerationSchemas = `-- name: GetActiveGenerationSchemas :many
+SELECT sgs.name, sgs.version, ds.spec
+FROM schema_generation sg
+     INNER JOIN schema_generation_schema sgs
+           ON sgs.generation_id = sg.id
+     INNER JOIN document_schema ds
+           ON ds.name = sgs.name AND ds.version = sgs.version
+WHERE sg.status = 'active'
+ORDER BY sgs.name
+`
+
+type GetActiveGenerationSchemasRow struct {
+	Name    string
+	Version string
+	Spec    []byte
+}
+
+func (q *Queries) GetActiveGenerationSchemas(ctx context.Context) ([]GetActiveGenerationSchemasRow, error) {
+	rows, err := q.db.Query(ctx, getActiveGenerationSchemas)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetActiveGenerationSchemasRow
+	for rows.Next() {
+		var i GetActiveGenerationSchemasRow
+		if err := rows.Scan(&i.Name, &i.Version, &i.Spec); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getActiveGenerationTransforms = `-- name: GetActiveGenerationTransforms :one
+SELECT t.transforms
+FROM schema_generation AS sg
+     INNER JOIN schema_generation_transform AS t
+           ON t.generation_id = sg.id
+WHERE sg.status = 'active'
+`
+
+func (q *Queries) GetActiveGenerationTransforms(ctx context.Context) ([]byte, error) {
+	row := q.db.QueryRow(ctx, getActiveGenerationTransforms)
+	var transforms []byte
+	err := row.Scan(&transforms)
+	return transforms, err
+}
+
+const getActiveSchema = `-- name: GetActiveSchema :one
+SELECT s.name, s.version, s.spec
+FROM active_schemas AS a
+     INNER JOIN document_schema AS s
+           ON s.name = a.name AND s.version = a.version
+WHERE a.name = $1
+`
+
+func (q *Queries) GetActiveSchema(ctx context.Context, name string) (DocumentSchema, error) {
+	row := q.db.QueryRow(ctx, getActiveSchema, name)
+	var i DocumentSchema
+	err := row.Scan(&i.Name, &i.Version, &i.Spec)
+	return i, err
+}
+
+const getActiveSchemaGeneration = `-- name: GetActiveSchemaGeneration :one
+SELECT id, identity_hash, status, created, activated, deactivated
+FROM schema_generation
+WHERE status = 'active'
+`
+
+func (q *Queries) GetActiveSchemaGeneration(ctx context.Context) (SchemaGeneration, error) {
+	row := q.db.QueryRow(ctx, getActiveSchemaGeneration)
+	var i SchemaGeneration
+	err := row.Scan(
+		&i.ID,
+		&i.IdentityHash,
+		&i.Status,
+		&i.Created,
+		&i.Activated,
+		&i.Deactivated,
+	)
+	return i, err
+}
+
+const getActiveSchemas = `-- name: GetActiveSchemas :many
+SELECT s.name, s.version, s.spec
+FROM active_schemas AS a
+     INNER JOIN document_schema AS s
+           ON s.name = a.name AND s.version = a.version
+`
+
+func (q *Queries) GetActiveSchemas(ctx context.Context) ([]DocumentSchema, error) {
+	rows, err := q.db.Query(ctx, getActiveSchemas)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DocumentSchema
+	for rows.Next() {
+		var i DocumentSchema
+		if err := rows.Scan(&i.Name, &i.Version, &i.Spec); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getActiveStatuses = `-- name: GetActiveStatuses :many
+SELECT type, name
+FROM status
+WHERE disabled = false
+      AND ($1::text IS NULL OR type = $1)
+`
+
+type GetActiveStatusesRow struct {
+	Type string
+	Name string
+}
+
+func (q *Queries) GetActiveStatuses(ctx context.Context, type_ pgtype.Text) ([]GetActiveStatusesRow, error) {
+	rows, err := q.db.Query(ctx, getActiveStatuses, type_)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 	var items []GetActiveStatusesRow
 	for rows.Next() {
 		var i GetActiveStatusesRow
@@ -1180,46 +1732,225 @@ func (q *Queries) GetActiveStatuses(ctx context.Context, type_ pgtype.Text) ([]G
 	return items, nil
 }
 
-const getAttachedObject = `-- name: GetAttachedObject :one
-SELECT
-        o.document,
-        o.name,
-        o.version,
-        o.object_version,
-        o.attached_at,
-        o.created_by,
-        o.created_at,
-        o.meta,
-        c.deleted
-FROM attached_object_current AS c
-     INNER JOIN attached_object AS o ON
-           o.document = c.document
-           AND o.name = c.name
-           AND o.version = c.version
-WHERE c.document = $1
-      AND c.name = $2
+const getAllAttachedObjects = `-- name: GetAllAttachedObjects :many
+SELECT document, name, version, object_version, attached_at,
+       created_by, created_at, meta
+FROM attached_object
+WHERE document = $1
+ORDER BY name, version
+`
+
+func (q *Queries) GetAllAttachedObjects(ctx context.Context, document uuid.UUID) ([]AttachedObject, error) {
+	rows, err := q.db.Query(ctx, getAllAttachedObjects, document)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AttachedObject
+	for rows.Next() {
+		var i AttachedObject
+		if err := rows.Scan(
+			&i.Document,
+			&i.Name,
+			&i.Version,
+			&i.ObjectVersion,
+			&i.AttachedAt,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.Meta,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getArchivedStatusSignatures = `-- name: GetArchivedStatusSignatures :many
+SELECT name, id, signature
+FROM document_status
+WHERE uuid = $1
+      AND archived = true
+ORDER BY name, id
+`
+
+type GetArchivedStatusSignaturesRow struct {
+	Name      string
+	ID        int64
+	Signature pgtype.Text
+}
+
+func (q *Queries) GetArchivedStatusSignatures(ctx context.Context, argUuid uuid.UUID) ([]GetArchivedStatusSignaturesRow, error) {
+	rows, err := q.db.Query(ctx, getArchivedStatusSignatures, argUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetArchivedStatusSignaturesRow
+	for rows.Next() {
+		var i GetArchivedStatusSignaturesRow
+		if err := rows.Scan(&i.Name, &i.ID, &i.Signature); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getArchivedVersionSignatures = `-- name: GetArchivedVersionSignatures :many
+SELECT version, signature
+FROM document_version
+WHERE uuid = $1
+      AND archived = true
+ORDER BY version
+`
+
+type GetArchivedVersionSignaturesRow struct {
+	Version   int64
+	Signature pgtype.Text
+}
+
+func (q *Queries) GetArchivedVersionSignatures(ctx context.Context, argUuid uuid.UUID) ([]GetArchivedVersionSignaturesRow, error) {
+	rows, err := q.db.Query(ctx, getArchivedVersionSignatures, argUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetArchivedVersionSignaturesRow
+	for rows.Next() {
+		var i GetArchivedVersionSignaturesRow
+		if err := rows.Scan(&i.Version, &i.Signature); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAttachedObject = `-- name: GetAttachedObject :one
+SELECT
+        o.document,
+        o.name,
+        o.version,
+        o.object_version,
+        o.attached_at,
+        o.created_by,
+        o.created_at,
+        o.meta,
+        c.deleted
+FROM attached_object_current AS c
+     INNER JOIN attached_object AS o ON
+           o.document = c.document
+           AND o.name = c.name
+           AND o.version = c.version
+WHERE c.document = $1
+      AND c.name = $2
+`
+
+type GetAttachedObjectParams struct {
+	Document uuid.UUID
+	Name     string
+}
+
+type GetAttachedObjectRow struct {
+	Document      uuid.UUID
+	Name          string
+	Version       int64
+	ObjectVersion string
+	AttachedAt    int64
+	CreatedBy     string
+	CreatedAt     pgtype.Timestamptz
+	Meta          AssetMetadata
+	Deleted       bool
+}
+
+func (q *Queries) GetAttachedObject(ctx context.Context, arg GetAttachedObjectParams) (GetAttachedObjectRow, error) {
+	row := q.db.QueryRow(ctx, getAttachedObject, arg.Document, arg.Name)
+	var i GetAttachedObjectRow
+	err := row.Scan(
+		&i.Document,
+		&i.Name,
+		&i.Version,
+		&i.ObjectVersion,
+		&i.AttachedAt,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.Meta,
+		&i.Deleted,
+	)
+	return i, err
+}
+
+const getAttachedObjectHistory = `-- name: GetAttachedObjectHistory :many
+SELECT document, name, version, object_version, attached_at,
+       created_by, created_at, meta
+FROM attached_object
+WHERE document = $1
+      AND name = $2
+ORDER BY version DESC
+`
+
+type GetAttachedObjectHistoryParams struct {
+	Document uuid.UUID
+	Name     string
+}
+
+func (q *Queries) GetAttachedObjectHistory(ctx context.Context, arg GetAttachedObjectHistoryParams) ([]AttachedObject, error) {
+	rows, err := q.db.Query(ctx, getAttachedObjectHistory, arg.Document, arg.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AttachedObject
+	for rows.Next() {
+		var i AttachedObject
+		if err := rows.Scan(
+			&i.Document,
+			&i.Name,
+			&i.Version,
+			&i.ObjectVersion,
+			&i.AttachedAt,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.Meta,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAttachedObjectVersion = `-- name: GetAttachedObjectVersion :one
+SELECT document, name, version, object_version, attached_at,
+       created_by, created_at, meta
+FROM attached_object
+WHERE document = $1
+      AND name = $2
+      AND version = $3
 `
 
-type GetAttachedObjectParams struct {
+type GetAttachedObjectVersionParams struct {
 	Document uuid.UUID
 	Name     string
+	Version  int64
 }
 
-type GetAttachedObjectRow struct {
-	Document      uuid.UUID
-	Name          string
-	Version       int64
-	ObjectVersion string
-	AttachedAt    int64
-	CreatedBy     string
-	CreatedAt     pgtype.Timestamptz
-	Meta          AssetMetadata
-	Deleted       bool
-}
-
-func (q *Queries) GetAttachedObject(ctx context.Context, arg GetAttachedObjectParams) (GetAttachedObjectRow, error) {
-	row := q.db.QueryRow(ctx, getAttachedObject, arg.Document, arg.Name)
-	var i GetAttachedObjectRow
+func (q *Queries) GetAttachedObjectVersion(ctx context.Context, arg GetAttachedObjectVersionParams) (AttachedObject, error) {
+	row := q.db.QueryRow(ctx, getAttachedObjectVersion, arg.Document, arg.Name, arg.Version)
+	var i AttachedObject
 	err := row.Scan(
 		&i.Document,
 		&i.Name,
@@ -1229,11 +1960,106 @@ func (q *Queries) GetAttachedObject(ctx context.Context, arg GetAttachedObjectPa
 		&i.CreatedBy,
 		&i.CreatedAt,
 		&i.Meta,
-		&i.Deleted,
 	)
 	return i, err
 }
 
+const getAttachedObjectsAtVersion = `-- name: GetAttachedObjectsAtVersion :many
+SELECT o.document, o.name, o.version, o.object_version, o.attached_at,
+       o.created_by, o.created_at, o.meta
+FROM attached_object AS o
+     INNER JOIN attached_object_current AS c ON
+           c.document = o.document
+           AND c.name = o.name
+WHERE o.document = $1
+      AND o.version = (
+          SELECT max(h.version)
+          FROM attached_object AS h
+          WHERE h.document = o.document
+                AND h.name = o.name
+                AND h.attached_at <= $2
+      )
+      AND NOT (c.deleted AND c.version = o.version)
+ORDER BY o.name
+`
+
+type GetAttachedObjectsAtVersionParams struct {
+	Document   uuid.UUID
+	DocVersion int64
+}
+
+func (q *Queries) GetAttachedObjectsAtVersion(ctx context.Context, arg GetAttachedObjectsAtVersionParams) ([]AttachedObject, error) {
+	rows, err := q.db.Query(ctx, getAttachedObjectsAtVersion, arg.Document, arg.DocVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AttachedObject
+	for rows.Next() {
+		var i AttachedObject
+		if err := rows.Scan(
+			&i.Document,
+			&i.Name,
+			&i.Version,
+			&i.ObjectVersion,
+			&i.AttachedAt,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.Meta,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAttachedObjectsAttachedAt = `-- name: GetAttachedObjectsAttachedAt :many
+SELECT document, name, version, object_version, attached_at,
+       created_by, created_at, meta
+FROM attached_object
+WHERE document = $1
+      AND attached_at = $2
+ORDER BY name
+`
+
+type GetAttachedObjectsAttachedAtParams struct {
+	Document   uuid.UUID
+	AttachedAt int64
+}
+
+func (q *Queries) GetAttachedObjectsAttachedAt(ctx context.Context, arg GetAttachedObjectsAttachedAtParams) ([]AttachedObject, error) {
+	rows, err := q.db.Query(ctx, getAttachedObjectsAttachedAt, arg.Document, arg.AttachedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AttachedObject
+	for rows.Next() {
+		var i AttachedObject
+		if err := rows.Scan(
+			&i.Document,
+			&i.Name,
+			&i.Version,
+			&i.ObjectVersion,
+			&i.AttachedAt,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.Meta,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAttachments = `-- name: GetAttachments :many
 SELECT name, version FROM attached_object_current
 WHERE document = $1
@@ -1318,6 +2144,102 @@ func (q *Queries) GetAttachmentsForDocuments(ctx context.Context, arg GetAttachm
 	return items, nil
 }
 
+const getBacklinks = `-- name: GetBacklinks :many
+SELECT l.from_document AS uuid, d.type, d.current_version,
+       array_agg(DISTINCT l.rel ORDER BY l.rel)::text[] AS rels
+FROM document_link AS l
+     INNER JOIN document AS d ON d.uuid = l.from_document
+WHERE l.to_document = $1
+      AND l.from_document > $2
+      AND ($3::text IS NULL OR l.rel = $3)
+      AND ($4::text IS NULL OR d.type = $4)
+      AND d.system_state IS NULL
+GROUP BY l.from_document, d.type, d.current_version
+ORDER BY l.from_document
+LIMIT $5
+`
+
+type GetBacklinksParams struct {
+	UUID     uuid.UUID
+	After    uuid.UUID
+	Rel      pgtype.Text
+	Type     pgtype.Text
+	RowLimit int64
+}
+
+type GetBacklinksRow struct {
+	UUID           uuid.UUID
+	Type           string
+	CurrentVersion int64
+	Rels           []string
+}
+
+func (q *Queries) GetBacklinks(ctx context.Context, arg GetBacklinksParams) ([]GetBacklinksRow, error) {
+	rows, err := q.db.Query(ctx, getBacklinks,
+		arg.UUID,
+		arg.After,
+		arg.Rel,
+		arg.Type,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBacklinksRow
+	for rows.Next() {
+		var i GetBacklinksRow
+		if err := rows.Scan(
+			&i.UUID,
+			&i.Type,
+			&i.CurrentVersion,
+			&i.Rels,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCollectedExemplars = `-- name: GetCollectedExemplars :many
+SELECT se.name, se.version, se.doc_type, se.document
+FROM schema_generation_exemplar sge
+     INNER JOIN schema_exemplar se
+           ON se.name = sge.name AND se.version = sge.version
+WHERE sge.generation_id = $1
+      AND sge.collected
+ORDER BY se.name
+`
+
+func (q *Queries) GetCollectedExemplars(ctx context.Context, generationID int64) ([]SchemaExemplar, error) {
+	rows, err := q.db.Query(ctx, getCollectedExemplars, generationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SchemaExemplar
+	for rows.Next() {
+		var i SchemaExemplar
+		if err := rows.Scan(
+			&i.Name,
+			&i.Version,
+			&i.DocType,
+			&i.Document,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getCompactedEventlog = `-- name: GetCompactedEventlog :many
 SELECT
         w.id, w.event, w.uuid, w.timestamp, w.type, w.version, w.status,
@@ -1467,6 +2389,56 @@ func (q *Queries) GetCurrentDocumentVersions(ctx context.Context, uuids []uuid.U
 	return items, nil
 }
 
+const getCurrentDocumentsOfTypes = `-- name: GetCurrentDocumentsOfTypes :many
+SELECT d.uuid, d.type, d.current_version, v.document_data
+FROM document AS d
+     INNER JOIN document_version AS v
+           ON v.uuid = d.uuid AND v.version = d.current_version
+WHERE d.type = ANY($1::text[])
+      AND d.uuid > $2
+      AND d.system_state IS NULL
+ORDER BY d.uuid
+LIMIT $3
+`
+
+type GetCurrentDocumentsOfTypesParams struct {
+	Types    []string
+	After    uuid.UUID
+	RowLimit int64
+}
+
+type GetCurrentDocumentsOfTypesRow struct {
+	UUID           uuid.UUID
+	Type           string
+	CurrentVersion int64
+	DocumentData   []byte
+}
+
+func (q *Queries) GetCurrentDocumentsOfTypes(ctx context.Context, arg GetCurrentDocumentsOfTypesParams) ([]GetCurrentDocumentsOfTypesRow, error) {
+	rows, err := q.db.Query(ctx, getCurrentDocumentsOfTypes, arg.Types, arg.After, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCurrentDocumentsOfTypesRow
+	for rows.Next() {
+		var i GetCurrentDocumentsOfTypesRow
+		if err := rows.Scan(
+			&i.UUID,
+			&i.Type,
+			&i.CurrentVersion,
+			&i.DocumentData,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getDelayedScheduled = `-- name: GetDelayedScheduled :many
 SELECT
         ws.uuid,
@@ -1747,6 +2719,104 @@ func (q *Queries) GetDeliverableTimes(ctx context.Context, argUuid uuid.UUID) ([
 	return items, nil
 }
 
+const getDeprecatedDocuments = `-- name: GetDeprecatedDocuments :many
+SELECT u.uuid, d.type, u.version
+FROM deprecation_usage AS u
+     INNER JOIN document AS d ON d.uuid = u.uuid
+WHERE u.label = $1
+      AND u.uuid > $2
+      AND d.system_state IS NULL
+ORDER BY u.uuid
+LIMIT $3
+`
+
+type GetDeprecatedDocumentsParams struct {
+	Label    string
+	After    uuid.UUID
+	RowLimit int64
+}
+
+type GetDeprecatedDocumentsRow struct {
+	UUID    uuid.UUID
+	Type    string
+	Version int64
+}
+
+func (q *Queries) GetDeprecatedDocuments(ctx context.Context, arg GetDeprecatedDocumentsParams) ([]GetDeprecatedDocumentsRow, error) {
+	rows, err := q.db.Query(ctx, getDeprecatedDocuments, arg.Label, arg.After, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDeprecatedDocumentsRow
+	for rows.Next() {
+		var i GetDeprecatedDocumentsRow
+		if err := rows.Scan(&i.UUID, &i.Type, &i.Version); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeprecationUsage = `-- name: GetDeprecationUsage :many
+SELECT COALESCE(d.label, u.label)::text AS label,
+       COALESCE(d.enforced, false)::bool AS enforced,
+       COALESCE(u.documents, 0)::bigint AS documents
+FROM deprecation AS d
+     FULL OUTER JOIN (
+          SELECT du.label, count(*) AS documents
+          FROM deprecation_usage AS du
+               INNER JOIN document AS doc ON doc.uuid = du.uuid
+          WHERE doc.system_state IS NULL
+          GROUP BY du.label
+     ) AS u ON u.label = d.label
+ORDER BY 1
+`
+
+type GetDeprecationUsageRow struct {
+	Label     string
+	Enforced  bool
+	Documents int64
+}
+
+func (q *Queries) GetDeprecationUsage(ctx context.Context) ([]GetDeprecationUsageRow, error) {
+	rows, err := q.db.Query(ctx, getDeprecationUsage)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDeprecationUsageRow
+	for rows.Next() {
+		var i GetDeprecationUsageRow
+		if err := rows.Scan(&i.Label, &i.Enforced, &i.Documents); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeprecationUsageScan = `-- name: GetDeprecationUsageScan :one
+SELECT generation_id, position, created
+FROM deprecation_usage_scan
+ORDER BY created
+LIMIT 1
+`
+
+func (q *Queries) GetDeprecationUsageScan(ctx context.Context) (DeprecationUsageScan, error) {
+	row := q.db.QueryRow(ctx, getDeprecationUsageScan)
+	var i DeprecationUsageScan
+	err := row.Scan(&i.GenerationID, &i.Position, &i.Created)
+	return i, err
+}
+
 const getDeprecations = `-- name: GetDeprecations :many
 SELECT label, enforced
 FROM deprecation
@@ -1774,7 +2844,7 @@ func (q *Queries) GetDeprecations(ctx context.Context) ([]Deprecation, error) {
 }
 
 const getDocumentACL = `-- name: GetDocumentACL :many
-SELECT uuid, uri, permissions FROM acl WHERE uuid = $1
+SELECT uuid, uri, permissions, not_before, expires FROM acl WHERE uuid = $1
 `
 
 func (q *Queries) GetDocumentACL(ctx context.Context, argUuid uuid.UUID) ([]Acl, error) {
@@ -1786,7 +2856,44 @@ func (q *Queries) GetDocumentACL(ctx context.Context, argUuid uuid.UUID) ([]Acl,
 	var items []Acl
 	for rows.Next() {
 		var i Acl
-		if err := rows.Scan(&i.UUID, &i.URI, &i.Permissions); err != nil {
+		if err := rows.Scan(
+			&i.UUID,
+			&i.URI,
+			&i.Permissions,
+			&i.NotBefore,
+			&i.Expires,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDocumentACLInheritance = `-- name: GetDocumentACLInheritance :many
+SELECT parent, permissions
+FROM acl_inheritance
+WHERE uuid = $1
+`
+
+type GetDocumentACLInheritanceRow struct {
+	Parent      uuid.UUID
+	Permissions []string
+}
+
+func (q *Queries) GetDocumentACLInheritance(ctx context.Context, argUuid uuid.UUID) ([]GetDocumentACLInheritanceRow, error) {
+	rows, err := q.db.Query(ctx, getDocumentACLInheritance, argUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDocumentACLInheritanceRow
+	for rows.Next() {
+		var i GetDocumentACLInheritanceRow
+		if err := rows.Scan(&i.Parent, &i.Permissions); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -1865,6 +2972,38 @@ func (q *Queries) GetDocumentData(ctx context.Context, argUuid uuid.UUID) (GetDo
 	return i, err
 }
 
+const getDocumentExport = `-- name: GetDocumentExport :one
+SELECT id, uuids, type, time_range, labels, status, created, created_by,
+       started, finished, total, processed, object_key, size, signature,
+       error
+FROM document_export
+WHERE id = $1
+`
+
+func (q *Queries) GetDocumentExport(ctx context.Context, id int64) (DocumentExport, error) {
+	row := q.db.QueryRow(ctx, getDocumentExport, id)
+	var i DocumentExport
+	err := row.Scan(
+		&i.ID,
+		&i.Uuids,
+		&i.Type,
+		&i.TimeRange,
+		&i.Labels,
+		&i.Status,
+		&i.Created,
+		&i.CreatedBy,
+		&i.Started,
+		&i.Finished,
+		&i.Total,
+		&i.Processed,
+		&i.ObjectKey,
+		&i.Size,
+		&i.Signature,
+		&i.Error,
+	)
+	return i, err
+}
+
 const getDocumentForDeletion = `-- name: GetDocumentForDeletion :one
 SELECT dr.id, dr.uuid, dr.nonce, dr.heads, dr.acl, dr.version, dr.attachments
 FROM delete_record AS dr
@@ -2258,6 +3397,38 @@ func (q *Queries) GetDocumentStatusForArchiving(ctx context.Context, arg GetDocu
 	return i, err
 }
 
+const getDocumentTemplate = `-- name: GetDocumentTemplate :one
+SELECT name, version, type, description, document, variables, acl,
+       created, created_by
+FROM document_template
+WHERE name = $1
+      AND ($2::bigint = 0 OR version = $2::bigint)
+ORDER BY version DESC
+LIMIT 1
+`
+
+type GetDocumentTemplateParams struct {
+	Name    string
+	Version int64
+}
+
+func (q *Queries) GetDocumentTemplate(ctx context.Context, arg GetDocumentTemplateParams) (DocumentTemplate, error) {
+	row := q.db.QueryRow(ctx, getDocumentTemplate, arg.Name, arg.Version)
+	var i DocumentTemplate
+	err := row.Scan(
+		&i.Name,
+		&i.Version,
+		&i.Type,
+		&i.Description,
+		&i.Document,
+		&i.Variables,
+		&i.Acl,
+		&i.Created,
+		&i.CreatedBy,
+	)
+	return i, err
+}
+
 const getDocumentUnarchivedCount = `-- name: GetDocumentUnarchivedCount :one
 SELECT unarchived FROM document_archive_counter
 WHERE uuid = $1
@@ -2412,6 +3583,115 @@ func (q *Queries) GetDocumentWorkflows(ctx context.Context) ([]Workflow, error)
 	return items, nil
 }
 
+const getDocumentsForACLInheritance = `-- name: GetDocumentsForACLInheritance :many
+SELECT d.uuid, d.type, d.language, d.current_version, d.nonce, d.main_doc,
+       d.main_doc_type, d.system_state, d.labels, d.time, v.document_data
+FROM document AS d
+     INNER JOIN document_version AS v
+           ON v.uuid = d.uuid AND v.version = d.current_version
+WHERE d.type = $1
+      AND d.uuid > $2
+      AND d.main_doc IS NULL
+ORDER BY d.uuid
+LIMIT $3
+FOR UPDATE OF d
+`
+
+type GetDocumentsForACLInheritanceParams struct {
+	Type     string
+	After    uuid.UUID
+	RowLimit int64
+}
+
+type GetDocumentsForACLInheritanceRow struct {
+	UUID           uuid.UUID
+	Type           string
+	Language       pgtype.Text
+	CurrentVersion int64
+	Nonce          uuid.UUID
+	MainDoc        pgtype.UUID
+	MainDocType    pgtype.Text
+	SystemState    pgtype.Text
+	Labels         []string
+	Time           pgtype.Multirange[pgtype.Range[pgtype.Timestamptz]]
+	DocumentData   []byte
+}
+
+func (q *Queries) GetDocumentsForACLInheritance(ctx context.Context, arg GetDocumentsForACLInheritanceParams) ([]GetDocumentsForACLInheritanceRow, error) {
+	rows, err := q.db.Query(ctx, getDocumentsForACLInheritance, arg.Type, arg.After, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDocumentsForACLInheritanceRow
+	for rows.Next() {
+		var i GetDocumentsForACLInheritanceRow
+		if err := rows.Scan(
+			&i.UUID,
+			&i.Type,
+			&i.Language,
+			&i.CurrentVersion,
+			&i.Nonce,
+			&i.MainDoc,
+			&i.MainDocType,
+			&i.SystemState,
+			&i.Labels,
+			&i.Time,
+			&i.DocumentData,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDocumentsForDeprecationScan = `-- name: GetDocumentsForDeprecationScan :many
+SELECT d.uuid, d.current_version, v.document_data
+FROM document AS d
+     INNER JOIN document_version AS v
+           ON v.uuid = d.uuid AND v.version = d.current_version
+WHERE d.uuid > $1
+      AND d.system_state IS NULL
+ORDER BY d.uuid
+LIMIT $2
+FOR UPDATE OF d
+`
+
+type GetDocumentsForDeprecationScanParams struct {
+	After    uuid.UUID
+	RowLimit int64
+}
+
+type GetDocumentsForDeprecationScanRow struct {
+	UUID           uuid.UUID
+	CurrentVersion int64
+	DocumentData   []byte
+}
+
+func (q *Queries) GetDocumentsForDeprecationScan(ctx context.Context, arg GetDocumentsForDeprecationScanParams) ([]GetDocumentsForDeprecationScanRow, error) {
+	rows, err := q.db.Query(ctx, getDocumentsForDeprecationScan, arg.After, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDocumentsForDeprecationScanRow
+	for rows.Next() {
+		var i GetDocumentsForDeprecationScanRow
+		if err := rows.Scan(&i.UUID, &i.CurrentVersion, &i.DocumentData); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getEnforcedDeprecations = `-- name: GetEnforcedDeprecations :many
 SELECT label
 FROM deprecation
@@ -2579,6 +3859,108 @@ func (q *Queries) GetExpiredDocumentLocks(ctx context.Context, cutoff pgtype.Tim
 	return items, nil
 }
 
+const getExpiredUploads = `-- name: GetExpiredUploads :many
+SELECT id, created_at, multipart_id, multipart_status
+FROM upload
+WHERE created_at < $1
+      AND (created_at, id) > ($2::timestamptz, $3::uuid)
+ORDER BY created_at, id
+LIMIT $4::bigint
+`
+
+type GetExpiredUploadsParams struct {
+	Cutoff       pgtype.Timestamptz
+	AfterCreated pgtype.Timestamptz
+	AfterID      uuid.UUID
+	Count        int64
+}
+
+type GetExpiredUploadsRow struct {
+	ID              uuid.UUID
+	CreatedAt       pgtype.Timestamptz
+	MultipartID     pgtype.Text
+	MultipartStatus pgtype.Text
+}
+
+func (q *Queries) GetExpiredUploads(ctx context.Context, arg GetExpiredUploadsParams) ([]GetExpiredUploadsRow, error) {
+	rows, err := q.db.Query(ctx, getExpiredUploads,
+		arg.Cutoff,
+		arg.AfterCreated,
+		arg.AfterID,
+		arg.Count,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExpiredUploadsRow
+	for rows.Next() {
+		var i GetExpiredUploadsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.MultipartID,
+			&i.MultipartStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExportDocuments = `-- name: GetExportDocuments :many
+SELECT d.uuid
+FROM document AS d
+WHERE d.uuid > $1
+      AND d.system_state IS NULL
+      AND (COALESCE(cardinality($2::uuid[]), 0) = 0 OR d.uuid = ANY($2))
+      AND ($3::text IS NULL OR d.type = $3)
+      AND ($4::tstzrange IS NULL OR d.time && $4)
+      AND (COALESCE(cardinality($5::text[]), 0) = 0 OR d.labels @> $5)
+ORDER BY d.uuid
+LIMIT $6
+`
+
+type GetExportDocumentsParams struct {
+	After     uuid.UUID
+	Uuids     []uuid.UUID
+	Type      pgtype.Text
+	TimeRange pgtype.Range[pgtype.Timestamptz]
+	Labels    []string
+	RowLimit  int64
+}
+
+func (q *Queries) GetExportDocuments(ctx context.Context, arg GetExportDocumentsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, getExportDocuments,
+		arg.After,
+		arg.Uuids,
+		arg.Type,
+		arg.TimeRange,
+		arg.Labels,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var uuid uuid.UUID
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, err
+		}
+		items = append(items, uuid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFullDocumentHeads = `-- name: GetFullDocumentHeads :many
 SELECT s.uuid, s.name, s.id, s.version, s.created, s.creator_uri, s.meta,
        s.archived, s.signature, s.meta_doc_version, h.language
@@ -2668,6 +4050,97 @@ func (q *Queries) GetFullVersion(ctx context.Context, arg GetFullVersionParams)
 	return i, err
 }
 
+const getInheritedACL = `-- name: GetInheritedACL :many
+SELECT ai.uuid, pa.uri, ARRAY(
+         SELECT p FROM unnest(pa.permissions) AS p
+         WHERE p = ANY(ai.permissions)
+       )::text[] AS permissions
+FROM acl_inheritance AS ai
+     INNER JOIN acl AS pa ON pa.uuid = ai.parent
+WHERE ai.uuid = ANY($1::uuid[])
+      AND pa.permissions && ai.permissions
+      AND (pa.not_before IS NULL OR pa.not_before <= now())
+      AND (pa.expires IS NULL OR pa.expires > now())
+`
+
+type GetInheritedACLRow struct {
+	UUID        uuid.UUID
+	URI         string
+	Permissions []string
+}
+
+func (q *Queries) GetInheritedACL(ctx context.Context, uuids []uuid.UUID) ([]GetInheritedACLRow, error) {
+	rows, err := q.db.Query(ctx, getInheritedACL, uuids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetInheritedACLRow
+	for rows.Next() {
+		var i GetInheritedACLRow
+		if err := rows.Scan(&i.UUID, &i.URI, &i.Permissions); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getInheritedACLSources = `-- name: GetInheritedACLSources :many
+SELECT ai.uuid, ai.parent, ai.permissions AS inheritable,
+       pa.uri, pa.permissions, pa.not_before, pa.expires
+FROM acl_inheritance AS ai
+     INNER JOIN acl AS pa ON pa.uuid = ai.parent
+WHERE ai.uuid = ANY($1::uuid[])
+      AND pa.uri = ANY($2::text[])
+`
+
+type GetInheritedACLSourcesParams struct {
+	Uuids []uuid.UUID
+	Uris  []string
+}
+
+type GetInheritedACLSourcesRow struct {
+	UUID        uuid.UUID
+	Parent      uuid.UUID
+	Inheritable []string
+	URI         string
+	Permissions []string
+	NotBefore   pgtype.Timestamptz
+	Expires     pgtype.Timestamptz
+}
+
+func (q *Queries) GetInheritedACLSources(ctx context.Context, arg GetInheritedACLSourcesParams) ([]GetInheritedACLSourcesRow, error) {
+	rows, err := q.db.Query(ctx, getInheritedACLSources, arg.Uuids, arg.Uris)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetInheritedACLSourcesRow
+	for rows.Next() {
+		var i GetInheritedACLSourcesRow
+		if err := rows.Scan(
+			&i.UUID,
+			&i.Parent,
+			&i.Inheritable,
+			&i.URI,
+			&i.Permissions,
+			&i.NotBefore,
+			&i.Expires,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getInvalidRestoreRequests = `-- name: GetInvalidRestoreRequests :many
 SELECT r.id
 FROM restore_request AS r
@@ -2798,6 +4271,19 @@ func (q *Queries) GetMetaDocVersion(ctx context.Context, argUuid pgtype.UUID) (i
 	return current_version, err
 }
 
+const getMetaTypeForType = `-- name: GetMetaTypeForType :one
+SELECT meta_type
+FROM meta_type_use
+WHERE main_type = $1
+`
+
+func (q *Queries) GetMetaTypeForType(ctx context.Context, mainType string) (string, error) {
+	row := q.db.QueryRow(ctx, getMetaTypeForType, mainType)
+	var meta_type string
+	err := row.Scan(&meta_type)
+	return meta_type, err
+}
+
 const getMetaTypeUse = `-- name: GetMetaTypeUse :many
 SELECT main_type, meta_type
 FROM meta_type_use
@@ -2987,6 +4473,40 @@ func (q *Queries) GetMultipleStatusHeads(ctx context.Context, arg GetMultipleSta
 	return items, nil
 }
 
+const getNextDocumentExport = `-- name: GetNextDocumentExport :one
+SELECT id, uuids, type, time_range, labels, status, created, created_by,
+       started, finished, total, processed, object_key, size, signature,
+       error
+FROM document_export
+WHERE finished IS NULL
+ORDER BY id
+LIMIT 1
+`
+
+func (q *Queries) GetNextDocumentExport(ctx context.Context) (DocumentExport, error) {
+	row := q.db.QueryRow(ctx, getNextDocumentExport)
+	var i DocumentExport
+	err := row.Scan(
+		&i.ID,
+		&i.Uuids,
+		&i.Type,
+		&i.TimeRange,
+		&i.Labels,
+		&i.Status,
+		&i.Created,
+		&i.CreatedBy,
+		&i.Started,
+		&i.Finished,
+		&i.Total,
+		&i.Processed,
+		&i.ObjectKey,
+		&i.Size,
+		&i.Signature,
+		&i.Error,
+	)
+	return i, err
+}
+
 const getNextPurgeRequest = `-- name: GetNextPurgeRequest :one
 SELECT p.id, p.uuid, p.delete_record_id, p.created
 FROM purge_request AS p
@@ -3285,6 +4805,176 @@ func (q *Queries) GetPlanningItem(ctx context.Context, argUuid uuid.UUID) (Plann
 	return i, err
 }
 
+const getReadAudit = `-- name: GetReadAudit :many
+SELECT id, uuid, type, version, operation, attachment, subject, app,
+       client_ip, created
+FROM read_audit
+WHERE ($1::uuid IS NULL OR uuid = $1)
+      AND ($2::text IS NULL OR subject = $2)
+      AND id > $3
+ORDER BY id
+LIMIT $4
+`
+
+type GetReadAuditParams struct {
+	UUID     pgtype.UUID
+	Subject  pgtype.Text
+	After    int64
+	RowLimit int64
+}
+
+func (q *Queries) GetReadAudit(ctx context.Context, arg GetReadAuditParams) ([]ReadAudit, error) {
+	rows, err := q.db.Query(ctx, getReadAudit,
+		arg.UUID,
+		arg.Subject,
+		arg.After,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReadAudit
+	for rows.Next() {
+		var i ReadAudit
+		if err := rows.Scan(
+			&i.ID,
+			&i.UUID,
+			&i.Type,
+			&i.Version,
+			&i.Operation,
+			&i.Attachment,
+			&i.Subject,
+			&i.App,
+			&i.ClientIp,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReadAuditArchiver = `-- name: GetReadAuditArchiver :one
+SELECT position, last_signature
+FROM read_audit_archiver
+LIMIT 1
+`
+
+type GetReadAuditArchiverRow struct {
+	Position      int64
+	LastSignature string
+}
+
+func (q *Queries) GetReadAuditArchiver(ctx context.Context) (GetReadAuditArchiverRow, error) {
+	row := q.db.QueryRow(ctx, getReadAuditArchiver)
+	var i GetReadAuditArchiverRow
+	err := row.Scan(&i.Position, &i.LastSignature)
+	return i, err
+}
+
+const getRecentDocumentsOfType = `-- name: GetRecentDocumentsOfType :many
+SELECT d.uuid, v.document_data
+FROM document AS d
+     INNER JOIN document_version AS v
+           ON v.uuid = d.uuid AND v.version = d.current_version
+WHERE d.type = $1
+      AND d.system_state IS NULL
+      AND v.document_data IS NOT NULL
+ORDER BY d.updated DESC
+LIMIT $2
+`
+
+type GetRecentDocumentsOfTypeParams struct {
+	Type     string
+	RowLimit int64
+}
+
+type GetRecentDocumentsOfTypeRow struct {
+	UUID         uuid.UUID
+	DocumentData []byte
+}
+
+func (q *Queries) GetRecentDocumentsOfType(ctx context.Context, arg GetRecentDocumentsOfTypeParams) ([]GetRecentDocumentsOfTypeRow, error) {
+	rows, err := q.db.Query(ctx, getRecentDocumentsOfType, arg.Type, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentDocumentsOfTypeRow
+	for rows.Next() {
+		var i GetRecentDocumentsOfTypeRow
+		if err := rows.Scan(&i.UUID, &i.DocumentData); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRunningSchemaRevalidation = `-- name: GetRunningSchemaRevalidation :one
+SELECT generation_id, types, status, created, created_by, finished,
+       position, total, processed, failed, error
+FROM schema_revalidation
+WHERE status = 'running'
+ORDER BY created
+LIMIT 1
+`
+
+func (q *Queries) GetRunningSchemaRevalidation(ctx context.Context) (SchemaRevalidation, error) {
+	row := q.db.QueryRow(ctx, getRunningSchemaRevalidation)
+	var i SchemaRevalidation
+	err := row.Scan(
+		&i.GenerationID,
+		&i.Types,
+		&i.Status,
+		&i.Created,
+		&i.CreatedBy,
+		&i.Finished,
+		&i.Position,
+		&i.Total,
+		&i.Processed,
+		&i.Failed,
+		&i.Error,
+	)
+	return i, err
+}
+
+const getRunningSchemaUpgrade = `-- name: GetRunningSchemaUpgrade :one
+SELECT generation_id, types, status, created, created_by, finished,
+       position, processed, upgraded, failed, error
+FROM schema_upgrade
+WHERE status = 'running'
+ORDER BY created
+LIMIT 1
+`
+
+func (q *Queries) GetRunningSchemaUpgrade(ctx context.Context) (SchemaUpgrade, error) {
+	row := q.db.QueryRow(ctx, getRunningSchemaUpgrade)
+	var i SchemaUpgrade
+	err := row.Scan(
+		&i.GenerationID,
+		&i.Types,
+		&i.Status,
+		&i.Created,
+		&i.CreatedBy,
+		&i.Finished,
+		&i.Position,
+		&i.Processed,
+		&i.Upgraded,
+		&i.Failed,
+		&i.Error,
+	)
+	return i, err
+}
+
 const getScheduled = `-- name: GetScheduled :many
 SELECT
         ws.uuid,
@@ -3579,6 +5269,112 @@ func (q *Queries) GetSchemaGenerationSchemasWithSpec(ctx context.Context, genera
 	return items, nil
 }
 
+const getSchemaGenerationTransforms = `-- name: GetSchemaGenerationTransforms :one
+SELECT transforms
+FROM schema_generation_transform
+WHERE generation_id = $1
+`
+
+func (q *Queries) GetSchemaGenerationTransforms(ctx context.Context, generationID int64) ([]byte, error) {
+	row := q.db.QueryRow(ctx, getSchemaGenerationTransforms, generationID)
+	var transforms []byte
+	err := row.Scan(&transforms)
+	return transforms, err
+}
+
+const getSchemaRevalidation = `-- name: GetSchemaRevalidation :one
+SELECT generation_id, types, status, created, created_by, finished,
+       position, total, processed, failed, error
+FROM schema_revalidation
+WHERE generation_id = $1
+`
+
+func (q *Queries) GetSchemaRevalidation(ctx context.Context, generationID int64) (SchemaRevalidation, error) {
+	row := q.db.QueryRow(ctx, getSchemaRevalidation, generationID)
+	var i SchemaRevalidation
+	err := row.Scan(
+		&i.GenerationID,
+		&i.Types,
+		&i.Status,
+		&i.Created,
+		&i.CreatedBy,
+		&i.Finished,
+		&i.Position,
+		&i.Total,
+		&i.Processed,
+		&i.Failed,
+		&i.Error,
+	)
+	return i, err
+}
+
+const getSchemaRevalidationFailures = `-- name: GetSchemaRevalidationFailures :many
+SELECT generation_id, uuid, type, version, errors
+FROM schema_revalidation_result
+WHERE generation_id = $1
+      AND uuid > $2
+ORDER BY uuid
+LIMIT $3
+`
+
+type GetSchemaRevalidationFailuresParams struct {
+	GenerationID int64
+	After        uuid.UUID
+	RowLimit     int64
+}
+
+func (q *Queries) GetSchemaRevalidationFailures(ctx context.Context, arg GetSchemaRevalidationFailuresParams) ([]SchemaRevalidationResult, error) {
+	rows, err := q.db.Query(ctx, getSchemaRevalidationFailures, arg.GenerationID, arg.After, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SchemaRevalidationResult
+	for rows.Next() {
+		var i SchemaRevalidationResult
+		if err := rows.Scan(
+			&i.GenerationID,
+			&i.UUID,
+			&i.Type,
+			&i.Version,
+			&i.Errors,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSchemaUpgrade = `-- name: GetSchemaUpgrade :one
+SELECT generation_id, types, status, created, created_by, finished,
+       position, processed, upgraded, failed, error
+FROM schema_upgrade
+WHERE generation_id = $1
+`
+
+func (q *Queries) GetSchemaUpgrade(ctx context.Context, generationID int64) (SchemaUpgrade, error) {
+	row := q.db.QueryRow(ctx, getSchemaUpgrade, generationID)
+	var i SchemaUpgrade
+	err := row.Scan(
+		&i.GenerationID,
+		&i.Types,
+		&i.Status,
+		&i.Created,
+		&i.CreatedBy,
+		&i.Finished,
+		&i.Position,
+		&i.Processed,
+		&i.Upgraded,
+		&i.Failed,
+		&i.Error,
+	)
+	return i, err
+}
+
 const getSchemaVersions = `-- name: GetSchemaVersions :many
 SELECT a.name, a.version
 FROM active_schemas AS a
@@ -3904,15 +5700,17 @@ func (q *Queries) GetUnarchivedSigningKeys(ctx context.Context) ([]GetUnarchived
 }
 
 const getUpload = `-- name: GetUpload :one
-SELECT id, created_at, created_by, meta
+SELECT id, created_at, created_by, meta, multipart_id, multipart_status
 FROM upload WHERE id = $1
 `
 
 type GetUploadRow struct {
-	ID        uuid.UUID
-	CreatedAt pgtype.Timestamptz
-	CreatedBy string
-	Meta      AssetMetadata
+	ID              uuid.UUID
+	CreatedAt       pgtype.Timestamptz
+	CreatedBy       string
+	Meta            AssetMetadata
+	MultipartID     pgtype.Text
+	MultipartStatus pgtype.Text
 }
 
 func (q *Queries) GetUpload(ctx context.Context, id uuid.UUID) (GetUploadRow, error) {
@@ -3923,6 +5721,8 @@ func (q *Queries) GetUpload(ctx context.Context, id uuid.UUID) (GetUploadRow, er
 		&i.CreatedAt,
 		&i.CreatedBy,
 		&i.Meta,
+		&i.MultipartID,
+		&i.MultipartStatus,
 	)
 	return i, err
 }
@@ -4092,6 +5892,45 @@ func (q *Queries) GranteesWithPermission(ctx context.Context, arg GranteesWithPe
 	return items, nil
 }
 
+const insertACLInheritanceRule = `-- name: InsertACLInheritanceRule :exec
+INSERT INTO acl_inheritance_rule(type, rel, link_type, permissions)
+VALUES ($1, $2, $3, $4)
+`
+
+type InsertACLInheritanceRuleParams struct {
+	Type        string
+	Rel         string
+	LinkType    string
+	Permissions []string
+}
+
+func (q *Queries) InsertACLInheritanceRule(ctx context.Context, arg InsertACLInheritanceRuleParams) error {
+	_, err := q.db.Exec(ctx, insertACLInheritanceRule,
+		arg.Type,
+		arg.Rel,
+		arg.LinkType,
+		arg.Permissions,
+	)
+	return err
+}
+
+const insertCollectedExemplar = `-- name: InsertCollectedExemplar :exec
+INSERT INTO schema_generation_exemplar(generation_id, name, version, collected)
+       VALUES ($1, $2, $3, true)
+ON CONFLICT (generation_id, name) DO NOTHING
+`
+
+type InsertCollectedExemplarParams struct {
+	GenerationID int64
+	Name         string
+	Version      string
+}
+
+func (q *Queries) InsertCollectedExemplar(ctx context.Context, arg InsertCollectedExemplarParams) error {
+	_, err := q.db.Exec(ctx, insertCollectedExemplar, arg.GenerationID, arg.Name, arg.Version)
+	return err
+}
+
 const insertDeleteRecord = `-- name: InsertDeleteRecord :one
 INSERT INTO delete_record(
        uuid, uri, type, version, created, creator_uri, meta,
@@ -4171,6 +6010,24 @@ func (q *Queries) InsertDeletionPlaceholder(ctx context.Context, arg InsertDelet
 	return err
 }
 
+const insertDeprecationUsage = `-- name: InsertDeprecationUsage :exec
+INSERT INTO deprecation_usage(label, uuid, version)
+SELECT l.label, $1::uuid, $2::bigint
+FROM unnest($3::text[]) AS l(label)
+ON CONFLICT DO NOTHING
+`
+
+type InsertDeprecationUsageParams struct {
+	UUID    uuid.UUID
+	Version int64
+	Labels  []string
+}
+
+func (q *Queries) InsertDeprecationUsage(ctx context.Context, arg InsertDeprecationUsageParams) error {
+	_, err := q.db.Exec(ctx, insertDeprecationUsage, arg.UUID, arg.Version, arg.Labels)
+	return err
+}
+
 const insertDocument = `-- name: InsertDocument :exec
 INSERT INTO document(
        uuid, uri, type,
@@ -4212,6 +6069,48 @@ func (q *Queries) InsertDocument(ctx context.Context, arg InsertDocumentParams)
 	return err
 }
 
+const insertDocumentACLInheritance = `-- name: InsertDocumentACLInheritance :exec
+INSERT INTO acl_inheritance(uuid, parent, permissions)
+VALUES ($1, $2, $3)
+`
+
+type InsertDocumentACLInheritanceParams struct {
+	UUID        uuid.UUID
+	Parent      uuid.UUID
+	Permissions []string
+}
+
+func (q *Queries) InsertDocumentACLInheritance(ctx context.Context, arg InsertDocumentACLInheritanceParams) error {
+	_, err := q.db.Exec(ctx, insertDocumentACLInheritance, arg.UUID, arg.Parent, arg.Permissions)
+	return err
+}
+
+const insertDocumentLinks = `-- name: InsertDocumentLinks :exec
+INSERT INTO document_link(from_document, to_document, rel, type)
+SELECT $1::uuid, l.to_document, l.rel, l.type
+FROM unnest(
+     $2::uuid[], $3::text[], $4::text[]
+) AS l(to_document, rel, type)
+ON CONFLICT DO NOTHING
+`
+
+type InsertDocumentLinksParams struct {
+	UUID        uuid.UUID
+	ToDocuments []uuid.UUID
+	Rels        []string
+	Types       []string
+}
+
+func (q *Queries) InsertDocumentLinks(ctx context.Context, arg InsertDocumentLinksParams) error {
+	_, err := q.db.Exec(ctx, insertDocumentLinks,
+		arg.UUID,
+		arg.ToDocuments,
+		arg.Rels,
+		arg.Types,
+	)
+	return err
+}
+
 const insertDocumentLock = `-- name: InsertDocumentLock :exec
 INSERT INTO document_lock(
   uuid, token, created, expires, uri, app, comment, exclusivity
@@ -4280,6 +6179,47 @@ func (q *Queries) InsertDocumentStatus(ctx context.Context, arg InsertDocumentSt
 	return err
 }
 
+const insertDocumentTemplate = `-- name: InsertDocumentTemplate :one
+INSERT INTO document_template(
+       name, version, type, description, document, variables, acl,
+       created, created_by
+) VALUES (
+       $1,
+       (SELECT COALESCE(MAX(t.version), 0) + 1
+        FROM document_template AS t WHERE t.name = $1),
+       $2, $3, $4, $5, $6,
+       $7, $8
+)
+RETURNING version
+`
+
+type InsertDocumentTemplateParams struct {
+	Name        string
+	Type        string
+	Description string
+	Document    []byte
+	Variables   []byte
+	Acl         []byte
+	Created     pgtype.Timestamptz
+	CreatedBy   string
+}
+
+func (q *Queries) InsertDocumentTemplate(ctx context.Context, arg InsertDocumentTemplateParams) (int64, error) {
+	row := q.db.QueryRow(ctx, insertDocumentTemplate,
+		arg.Name,
+		arg.Type,
+		arg.Description,
+		arg.Document,
+		arg.Variables,
+		arg.Acl,
+		arg.Created,
+		arg.CreatedBy,
+	)
+	var version int64
+	err := row.Scan(&version)
+	return version, err
+}
+
 const insertIntoEventLog = `-- name: InsertIntoEventLog :exec
 INSERT INTO eventlog(
        id, event, uuid, nonce, type, timestamp, updater, version, status, status_id, acl,
@@ -4382,6 +6322,52 @@ func (q *Queries) InsertPurgeRequest(ctx context.Context, arg InsertPurgeRequest
 	return err
 }
 
+const insertReadAudit = `-- name: InsertReadAudit :exec
+WITH reads AS (
+     SELECT unnest($1::uuid[]) AS uuid,
+            unnest($2::bigint[]) AS version,
+            unnest($3::text[]) AS attachment
+)
+INSERT INTO read_audit(
+       uuid, type, version, operation, attachment,
+       subject, app, client_ip, created
+)
+SELECT r.uuid, d.type, r.version, $4::text,
+       NULLIF(r.attachment, ''), $5::text,
+       NULLIF($6::text, ''), NULLIF($7::text, ''),
+       $8::timestamptz
+FROM reads AS r
+     INNER JOIN document AS d ON d.uuid = r.uuid
+WHERE d.type = ANY($9::text[])
+`
+
+type InsertReadAuditParams struct {
+	Uuids       []uuid.UUID
+	Versions    []int64
+	Attachments []string
+	Operation   string
+	Subject     string
+	App         string
+	ClientIp    string
+	Created     pgtype.Timestamptz
+	Types       []string
+}
+
+func (q *Queries) InsertReadAudit(ctx context.Context, arg InsertReadAuditParams) error {
+	_, err := q.db.Exec(ctx, insertReadAudit,
+		arg.Uuids,
+		arg.Versions,
+		arg.Attachments,
+		arg.Operation,
+		arg.Subject,
+		arg.App,
+		arg.ClientIp,
+		arg.Created,
+		arg.Types,
+	)
+	return err
+}
+
 const insertRestoreRequest = `-- name: InsertRestoreRequest :exec
 INSERT INTO restore_request(
        uuid, delete_record_id, created, creator, spec
@@ -4476,14 +6462,45 @@ INSERT INTO schema_generation_schema(generation_id, name, version)
        VALUES ($1, $2, $3)
 `
 
-type InsertSchemaGenerationSchemaParams struct {
+type InsertSchemaGenerationSchemaParams struct {
+	GenerationID int64
+	Name         string
+	Version      string
+}
+
+func (q *Queries) InsertSchemaGenerationSchema(ctx context.Context, arg InsertSchemaGenerationSchemaParams) error {
+	_, err := q.db.Exec(ctx, insertSchemaGenerationSchema, arg.GenerationID, arg.Name, arg.Version)
+	return err
+}
+
+const insertSchemaRevalidationResult = `-- name: InsertSchemaRevalidationResult :exec
+INSERT INTO schema_revalidation_result(
+       generation_id, uuid, type, version, errors
+) VALUES (
+       $1, $2, $3, $4, $5
+)
+ON CONFLICT (generation_id, uuid) DO UPDATE SET
+   type = excluded.type,
+   version = excluded.version,
+   errors = excluded.errors
+`
+
+type InsertSchemaRevalidationResultParams struct {
 	GenerationID int64
-	Name         string
-	Version      string
+	UUID         uuid.UUID
+	Type         string
+	Version      int64
+	Errors       []byte
 }
 
-func (q *Queries) InsertSchemaGenerationSchema(ctx context.Context, arg InsertSchemaGenerationSchemaParams) error {
-	_, err := q.db.Exec(ctx, insertSchemaGenerationSchema, arg.GenerationID, arg.Name, arg.Version)
+func (q *Queries) InsertSchemaRevalidationResult(ctx context.Context, arg InsertSchemaRevalidationResultParams) error {
+	_, err := q.db.Exec(ctx, insertSchemaRevalidationResult,
+		arg.GenerationID,
+		arg.UUID,
+		arg.Type,
+		arg.Version,
+		arg.Errors,
+	)
 	return err
 }
 
@@ -4526,6 +6543,92 @@ func (q *Queries) ListActiveSchemas(ctx context.Context) ([]ActiveSchema, error)
 	return items, nil
 }
 
+const listAttachedObjectNames = `-- name: ListAttachedObjectNames :many
+SELECT DISTINCT name
+FROM attached_object_current
+WHERE deleted = false
+ORDER BY name
+`
+
+func (q *Queries) ListAttachedObjectNames(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, listAttachedObjectNames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCurrentAttachedObjects = `-- name: ListCurrentAttachedObjects :many
+SELECT
+        o.document,
+        o.name,
+        o.object_version,
+        o.created_at,
+        o.meta
+FROM attached_object_current AS c
+     INNER JOIN attached_object AS o ON
+           o.document = c.document
+           AND o.name = c.name
+           AND o.version = c.version
+WHERE c.deleted = false
+      AND c.name = $1::text
+      AND c.document > $2::uuid
+ORDER BY c.document
+LIMIT $3::bigint
+`
+
+type ListCurrentAttachedObjectsParams struct {
+	Name          string
+	AfterDocument uuid.UUID
+	Count         int64
+}
+
+type ListCurrentAttachedObjectsRow struct {
+	Document      uuid.UUID
+	Name          string
+	ObjectVersion string
+	CreatedAt     pgtype.Timestamptz
+	Meta          AssetMetadata
+}
+
+func (q *Queries) ListCurrentAttachedObjects(ctx context.Context, arg ListCurrentAttachedObjectsParams) ([]ListCurrentAttachedObjectsRow, error) {
+	rows, err := q.db.Query(ctx, listCurrentAttachedObjects, arg.Name, arg.AfterDocument, arg.Count)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCurrentAttachedObjectsRow
+	for rows.Next() {
+		var i ListCurrentAttachedObjectsRow
+		if err := rows.Scan(
+			&i.Document,
+			&i.Name,
+			&i.ObjectVersion,
+			&i.CreatedAt,
+			&i.Meta,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listDeleteRecords = `-- name: ListDeleteRecords :many
 SELECT id, uuid, uri, type, version, created, creator_uri, meta,
        main_doc, language, meta_doc_record, finalised, purged, attachments
@@ -4594,6 +6697,45 @@ func (q *Queries) ListDeleteRecords(ctx context.Context, arg ListDeleteRecordsPa
 	return items, nil
 }
 
+const listDocumentTemplates = `-- name: ListDocumentTemplates :many
+SELECT DISTINCT ON (name)
+       name, version, type, description, document, variables, acl,
+       created, created_by
+FROM document_template
+WHERE $1::text IS NULL OR type = $1
+ORDER BY name, version DESC
+`
+
+func (q *Queries) ListDocumentTemplates(ctx context.Context, type_ pgtype.Text) ([]DocumentTemplate, error) {
+	rows, err := q.db.Query(ctx, listDocumentTemplates, type_)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DocumentTemplate
+	for rows.Next() {
+		var i DocumentTemplate
+		if err := rows.Scan(
+			&i.Name,
+			&i.Version,
+			&i.Type,
+			&i.Description,
+			&i.Document,
+			&i.Variables,
+			&i.Acl,
+			&i.Created,
+			&i.CreatedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listSchemaGenerations = `-- name: ListSchemaGenerations :many
 SELECT id, identity_hash, status, created, activated, deactivated
 FROM schema_generation
@@ -4951,6 +7093,26 @@ func (q *Queries) SelectDocumentsInTimeRange(ctx context.Context, arg SelectDocu
 	return items, nil
 }
 
+const setACLInheritanceReapplyPosition = `-- name: SetACLInheritanceReapplyPosition :execrows
+UPDATE acl_inheritance_reapply
+SET position = $1
+WHERE type = $2 AND created = $3
+`
+
+type SetACLInheritanceReapplyPositionParams struct {
+	Position uuid.UUID
+	Type     string
+	Created  pgtype.Timestamptz
+}
+
+func (q *Queries) SetACLInheritanceReapplyPosition(ctx context.Context, arg SetACLInheritanceReapplyPositionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setACLInheritanceReapplyPosition, arg.Position, arg.Type, arg.Created)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const setCurrentAttachedObject = `-- name: SetCurrentAttachedObject :exec
 INSERT INTO attached_object_current(
        document, name, version, deleted
@@ -4978,6 +7140,26 @@ func (q *Queries) SetCurrentAttachedObject(ctx context.Context, arg SetCurrentAt
 	return err
 }
 
+const setDeprecationUsageScanPosition = `-- name: SetDeprecationUsageScanPosition :execrows
+UPDATE deprecation_usage_scan
+SET position = $1
+WHERE generation_id = $2 AND created = $3
+`
+
+type SetDeprecationUsageScanPositionParams struct {
+	Position     uuid.UUID
+	GenerationID int64
+	Created      pgtype.Timestamptz
+}
+
+func (q *Queries) SetDeprecationUsageScanPosition(ctx context.Context, arg SetDeprecationUsageScanPositionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setDeprecationUsageScanPosition, arg.Position, arg.GenerationID, arg.Created)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const setDocumentStatusAsArchived = `-- name: SetDocumentStatusAsArchived :exec
 UPDATE document_status
 SET archived = true, signature = $1::text
@@ -5244,6 +7426,23 @@ func (q *Queries) SetPlanningItemDeliverable(ctx context.Context, arg SetPlannin
 	return err
 }
 
+const setReadAuditArchiver = `-- name: SetReadAuditArchiver :exec
+INSERT INTO read_audit_archiver(position, last_signature)
+       VALUES ($1, $2)
+ON CONFLICT (id) DO UPDATE
+   SET position = $1, last_signature = $2
+`
+
+type SetReadAuditArchiverParams struct {
+	Position      int64
+	LastSignature string
+}
+
+func (q *Queries) SetReadAuditArchiver(ctx context.Context, arg SetReadAuditArchiverParams) error {
+	_, err := q.db.Exec(ctx, setReadAuditArchiver, arg.Position, arg.LastSignature)
+	return err
+}
+
 const setSchemaGenerationArchiver = `-- name: SetSchemaGenerationArchiver :exec
 INSERT INTO schema_generation_archiver(position, last_signature)
        VALUES ($1, $2)
@@ -5288,6 +7487,35 @@ func (q *Queries) SetSchemaGenerationPending(ctx context.Context, id int64) erro
 	return err
 }
 
+const setSchemaGenerationTransforms = `-- name: SetSchemaGenerationTransforms :exec
+INSERT INTO schema_generation_transform(
+       generation_id, transforms, updated, updated_by
+) VALUES (
+       $1, $2, $3, $4
+)
+ON CONFLICT (generation_id) DO UPDATE SET
+   transforms = excluded.transforms,
+   updated = excluded.updated,
+   updated_by = excluded.updated_by
+`
+
+type SetSchemaGenerationTransformsParams struct {
+	GenerationID int64
+	Transforms   []byte
+	Updated      pgtype.Timestamptz
+	UpdatedBy    string
+}
+
+func (q *Queries) SetSchemaGenerationTransforms(ctx context.Context, arg SetSchemaGenerationTransformsParams) error {
+	_, err := q.db.Exec(ctx, setSchemaGenerationTransforms,
+		arg.GenerationID,
+		arg.Transforms,
+		arg.Updated,
+		arg.UpdatedBy,
+	)
+	return err
+}
+
 const setSigningKeyArchived = `-- name: SetSigningKeyArchived :exec
 UPDATE signing_keys SET archived = true WHERE kid = $1
 `
@@ -5333,6 +7561,153 @@ func (q *Queries) SetTypeConfiguration(ctx context.Context, arg SetTypeConfigura
 	return err
 }
 
+const setUploadMultipartStatus = `-- name: SetUploadMultipartStatus :execrows
+UPDATE upload SET multipart_status = $1
+WHERE id = $2 AND multipart_status = $3
+`
+
+type SetUploadMultipartStatusParams struct {
+	MultipartStatus string
+	ID              uuid.UUID
+	CurrentStatus   string
+}
+
+func (q *Queries) SetUploadMultipartStatus(ctx context.Context, arg SetUploadMultipartStatusParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setUploadMultipartStatus, arg.MultipartStatus, arg.ID, arg.CurrentStatus)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const startACLInheritanceReapply = `-- name: StartACLInheritanceReapply :exec
+INSERT INTO acl_inheritance_reapply(type, position, created)
+VALUES ($1, '00000000-0000-0000-0000-000000000000', $2)
+ON CONFLICT (type) DO UPDATE SET
+   position = excluded.position,
+   created = excluded.created
+`
+
+type StartACLInheritanceReapplyParams struct {
+	Type    string
+	Created pgtype.Timestamptz
+}
+
+func (q *Queries) StartACLInheritanceReapply(ctx context.Context, arg StartACLInheritanceReapplyParams) error {
+	_, err := q.db.Exec(ctx, startACLInheritanceReapply, arg.Type, arg.Created)
+	return err
+}
+
+const startDeprecationUsageScan = `-- name: StartDeprecationUsageScan :exec
+INSERT INTO deprecation_usage_scan(generation_id, position, created)
+VALUES ($1, '00000000-0000-0000-0000-000000000000', $2)
+`
+
+type StartDeprecationUsageScanParams struct {
+	GenerationID int64
+	Created      pgtype.Timestamptz
+}
+
+func (q *Queries) StartDeprecationUsageScan(ctx context.Context, arg StartDeprecationUsageScanParams) error {
+	_, err := q.db.Exec(ctx, startDeprecationUsageScan, arg.GenerationID, arg.Created)
+	return err
+}
+
+const startDocumentExport = `-- name: StartDocumentExport :exec
+UPDATE document_export
+SET status = 'running', started = $1, total = $2, processed = 0
+WHERE id = $3
+`
+
+type StartDocumentExportParams struct {
+	Started pgtype.Timestamptz
+	Total   int64
+	ID      int64
+}
+
+func (q *Queries) StartDocumentExport(ctx context.Context, arg StartDocumentExportParams) error {
+	_, err := q.db.Exec(ctx, startDocumentExport, arg.Started, arg.Total, arg.ID)
+	return err
+}
+
+const startSchemaRevalidation = `-- name: StartSchemaRevalidation :exec
+INSERT INTO schema_revalidation(
+       generation_id, types, status, created, created_by, finished,
+       position, total, processed, failed, error
+) VALUES (
+       $1, $2, 'running', $3, $4, NULL,
+       '00000000-0000-0000-0000-000000000000', $5, 0, 0, NULL
+)
+ON CONFLICT (generation_id) DO UPDATE SET
+   types = excluded.types,
+   status = excluded.status,
+   created = excluded.created,
+   created_by = excluded.created_by,
+   finished = NULL,
+   position = excluded.position,
+   total = excluded.total,
+   processed = 0,
+   failed = 0,
+   error = NULL
+`
+
+type StartSchemaRevalidationParams struct {
+	GenerationID int64
+	Types        []string
+	Created      pgtype.Timestamptz
+	CreatedBy    string
+	Total        int64
+}
+
+func (q *Queries) StartSchemaRevalidation(ctx context.Context, arg StartSchemaRevalidationParams) error {
+	_, err := q.db.Exec(ctx, startSchemaRevalidation,
+		arg.GenerationID,
+		arg.Types,
+		arg.Created,
+		arg.CreatedBy,
+		arg.Total,
+	)
+	return err
+}
+
+const startSchemaUpgrade = `-- name: StartSchemaUpgrade :exec
+INSERT INTO schema_upgrade(
+       generation_id, types, status, created, created_by, finished,
+       position, processed, upgraded, failed, error
+) VALUES (
+       $1, $2, 'running', $3, $4, NULL,
+       '00000000-0000-0000-0000-000000000000', 0, 0, 0, NULL
+)
+ON CONFLICT (generation_id) DO UPDATE SET
+   types = excluded.types,
+   status = excluded.status,
+   created = excluded.created,
+   created_by = excluded.created_by,
+   finished = NULL,
+   position = excluded.position,
+   processed = 0,
+   upgraded = 0,
+   failed = 0,
+   error = NULL
+`
+
+type StartSchemaUpgradeParams struct {
+	GenerationID int64
+	Types        []string
+	Created      pgtype.Timestamptz
+	CreatedBy    string
+}
+
+func (q *Queries) StartSchemaUpgrade(ctx context.Context, arg StartSchemaUpgradeParams) error {
+	_, err := q.db.Exec(ctx, startSchemaUpgrade,
+		arg.GenerationID,
+		arg.Types,
+		arg.Created,
+		arg.CreatedBy,
+	)
+	return err
+}
+
 const stealJobLock = `-- name: StealJobLock :execrows
 UPDATE job_lock
 SET holder = $1,
@@ -5393,6 +7768,22 @@ func (q *Queries) UpdateDeprecation(ctx context.Context, arg UpdateDeprecationPa
 	return err
 }
 
+const updateDocumentExportProgress = `-- name: UpdateDocumentExportProgress :exec
+UPDATE document_export
+SET processed = $1
+WHERE id = $2
+`
+
+type UpdateDocumentExportProgressParams struct {
+	Processed int64
+	ID        int64
+}
+
+func (q *Queries) UpdateDocumentExportProgress(ctx context.Context, arg UpdateDocumentExportProgressParams) error {
+	_, err := q.db.Exec(ctx, updateDocumentExportProgress, arg.Processed, arg.ID)
+	return err
+}
+
 const updateDocumentLock = `-- name: UpdateDocumentLock :exec
 UPDATE document_lock
 SET expires = $1
@@ -5459,6 +7850,73 @@ func (q *Queries) UpdateEventsinkPosition(ctx context.Context, arg UpdateEventsi
 	return err
 }
 
+const updateSchemaRevalidationProgress = `-- name: UpdateSchemaRevalidationProgress :execrows
+UPDATE schema_revalidation
+SET position = $1,
+    processed = processed + $2::bigint,
+    failed = failed + $3::bigint
+WHERE generation_id = $4
+      AND created = $5
+      AND status = 'running'
+`
+
+type UpdateSchemaRevalidationProgressParams struct {
+	Position     uuid.UUID
+	Processed    int64
+	Failed       int64
+	GenerationID int64
+	Created      pgtype.Timestamptz
+}
+
+func (q *Queries) UpdateSchemaRevalidationProgress(ctx context.Context, arg UpdateSchemaRevalidationProgressParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateSchemaRevalidationProgress,
+		arg.Position,
+		arg.Processed,
+		arg.Failed,
+		arg.GenerationID,
+		arg.Created,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateSchemaUpgradeProgress = `-- name: UpdateSchemaUpgradeProgress :execrows
+UPDATE schema_upgrade
+SET position = $1,
+    processed = processed + $2::bigint,
+    upgraded = upgraded + $3::bigint,
+    failed = failed + $4::bigint
+WHERE generation_id = $5
+      AND created = $6
+      AND status = 'running'
+`
+
+type UpdateSchemaUpgradeProgressParams struct {
+	Position     uuid.UUID
+	Processed    int64
+	Upgraded     int64
+	Failed       int64
+	GenerationID int64
+	Created      pgtype.Timestamptz
+}
+
+func (q *Queries) UpdateSchemaUpgradeProgress(ctx context.Context, arg UpdateSchemaUpgradeProgressParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateSchemaUpgradeProgress,
+		arg.Position,
+		arg.Processed,
+		arg.Upgraded,
+		arg.Failed,
+		arg.GenerationID,
+		arg.Created,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateStatus = `-- name: UpdateStatus :exec
 INSERT INTO status(type, name, disabled)
 VALUES($1, $2, $3)
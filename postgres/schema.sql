@@ -29,6 +29,19 @@ CREATE TYPE public.schema_generation_status AS ENUM (
 );
 
 
+--
+-- Name: read_audit_append_only(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.read_audit_append_only() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    raise exception 'the read audit log is append only';
+END;
+$$;
+
+
 --
 -- Name: sequential_eventlog(); Type: FUNCTION; Schema: public; Owner: -
 --
@@ -61,6 +74,42 @@ SET default_table_access_method = heap;
 CREATE TABLE public.acl (
     uuid uuid NOT NULL,
     uri text NOT NULL,
+    permissions text[] NOT NULL,
+    not_before timestamp with time zone,
+    expires timestamp with time zone
+);
+
+
+--
+-- Name: acl_inheritance; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.acl_inheritance (
+    uuid uuid NOT NULL,
+    parent uuid NOT NULL,
+    permissions text[] NOT NULL
+);
+
+
+--
+-- Name: acl_inheritance_reapply; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.acl_inheritance_reapply (
+    type text NOT NULL,
+    "position" uuid NOT NULL,
+    created timestamp with time zone NOT NULL
+);
+
+
+--
+-- Name: acl_inheritance_rule; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.acl_inheritance_rule (
+    type text NOT NULL,
+    rel text NOT NULL,
+    link_type text DEFAULT ''::text NOT NULL,
     permissions text[] NOT NULL
 );
 
@@ -153,6 +202,28 @@ CREATE TABLE public.deprecation (
 );
 
 
+--
+-- Name: deprecation_usage; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.deprecation_usage (
+    label text NOT NULL,
+    uuid uuid NOT NULL,
+    version bigint NOT NULL
+);
+
+
+--
+-- Name: deprecation_usage_scan; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.deprecation_usage_scan (
+    generation_id bigint NOT NULL,
+    "position" uuid NOT NULL,
+    created timestamp with time zone NOT NULL
+);
+
+
 --
 -- Name: document; Type: TABLE; Schema: public; Owner: -
 --
@@ -186,6 +257,56 @@ CREATE TABLE public.document_archive_counter (
 );
 
 
+--
+-- Name: document_export; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.document_export (
+    id bigint NOT NULL,
+    uuids uuid[],
+    type text,
+    time_range tstzrange,
+    labels text[],
+    status text NOT NULL,
+    created timestamp with time zone NOT NULL,
+    created_by text NOT NULL,
+    started timestamp with time zone,
+    finished timestamp with time zone,
+    total bigint DEFAULT 0 NOT NULL,
+    processed bigint DEFAULT 0 NOT NULL,
+    object_key text,
+    size bigint,
+    signature text,
+    error text
+);
+
+
+--
+-- Name: document_export_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.document_export ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public.document_export_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
+--
+-- Name: document_link; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.document_link (
+    from_document uuid NOT NULL,
+    to_document uuid NOT NULL,
+    rel text NOT NULL,
+    type text NOT NULL
+);
+
+
 --
 -- Name: document_lock; Type: TABLE; Schema: public; Owner: -
 --
@@ -231,6 +352,23 @@ CREATE TABLE public.document_status (
 );
 
 
+--
+-- Name: document_template; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.document_template (
+    name text NOT NULL,
+    version bigint NOT NULL,
+    type text NOT NULL,
+    description text NOT NULL,
+    document jsonb NOT NULL,
+    variables jsonb NOT NULL,
+    acl jsonb NOT NULL,
+    created timestamp with time zone NOT NULL,
+    created_by text NOT NULL
+);
+
+
 --
 -- Name: document_type; Type: TABLE; Schema: public; Owner: -
 --
@@ -497,6 +635,50 @@ ALTER TABLE public.purge_request ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTIT
 );
 
 
+--
+-- Name: read_audit; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.read_audit (
+    id bigint NOT NULL,
+    uuid uuid NOT NULL,
+    type text NOT NULL,
+    version bigint NOT NULL,
+    operation text NOT NULL,
+    attachment text,
+    subject text NOT NULL,
+    app text,
+    client_ip text,
+    created timestamp with time zone NOT NULL
+);
+
+
+--
+-- Name: read_audit_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.read_audit ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public.read_audit_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
+--
+-- Name: read_audit_archiver; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.read_audit_archiver (
+    id boolean DEFAULT true NOT NULL,
+    "position" bigint DEFAULT 0 NOT NULL,
+    last_signature text DEFAULT ''::text NOT NULL,
+    CONSTRAINT single_row CHECK (id)
+);
+
+
 --
 -- Name: restore_request; Type: TABLE; Schema: public; Owner: -
 --
@@ -598,7 +780,8 @@ ALTER TABLE public.schema_generation_event ALTER COLUMN id ADD GENERATED ALWAYS
 CREATE TABLE public.schema_generation_exemplar (
     generation_id bigint NOT NULL,
     name text NOT NULL,
-    version text NOT NULL
+    version text NOT NULL,
+    collected boolean DEFAULT false NOT NULL
 );
 
 
@@ -627,6 +810,69 @@ CREATE TABLE public.schema_generation_schema (
 );
 
 
+--
+-- Name: schema_generation_transform; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.schema_generation_transform (
+    generation_id bigint NOT NULL,
+    transforms jsonb NOT NULL,
+    updated timestamp with time zone NOT NULL,
+    updated_by text NOT NULL
+);
+
+
+--
+-- Name: schema_revalidation; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.schema_revalidation (
+    generation_id bigint NOT NULL,
+    types text[] NOT NULL,
+    status text NOT NULL,
+    created timestamp with time zone NOT NULL,
+    created_by text NOT NULL,
+    finished timestamp with time zone,
+    "position" uuid DEFAULT '00000000-0000-0000-0000-000000000000'::uuid NOT NULL,
+    total bigint DEFAULT 0 NOT NULL,
+    processed bigint DEFAULT 0 NOT NULL,
+    failed bigint DEFAULT 0 NOT NULL,
+    error text
+);
+
+
+--
+-- Name: schema_revalidation_result; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.schema_revalidation_result (
+    generation_id bigint NOT NULL,
+    uuid uuid NOT NULL,
+    type text NOT NULL,
+    version bigint NOT NULL,
+    errors jsonb NOT NULL
+);
+
+
+--
+-- Name: schema_upgrade; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.schema_upgrade (
+    generation_id bigint NOT NULL,
+    types text[] NOT NULL,
+    status text NOT NULL,
+    created timestamp with time zone NOT NULL,
+    created_by text NOT NULL,
+    finished timestamp with time zone,
+    "position" uuid DEFAULT '00000000-0000-0000-0000-000000000000'::uuid NOT NULL,
+    processed bigint DEFAULT 0 NOT NULL,
+    upgraded bigint DEFAULT 0 NOT NULL,
+    failed bigint DEFAULT 0 NOT NULL,
+    error text
+);
+
+
 --
 -- Name: schema_version; Type: TABLE; Schema: public; Owner: -
 --
@@ -707,7 +953,9 @@ CREATE TABLE public.upload (
     id uuid NOT NULL,
     created_by text NOT NULL,
     created_at timestamp with time zone NOT NULL,
-    meta jsonb NOT NULL
+    meta jsonb NOT NULL,
+    multipart_id text,
+    multipart_status text
 );
 
 
@@ -749,6 +997,30 @@ ALTER TABLE ONLY public.acl
     ADD CONSTRAINT acl_pkey PRIMARY KEY (uuid, uri);
 
 
+--
+-- Name: acl_inheritance acl_inheritance_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.acl_inheritance
+    ADD CONSTRAINT acl_inheritance_pkey PRIMARY KEY (uuid, parent);
+
+
+--
+-- Name: acl_inheritance_reapply acl_inheritance_reapply_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.acl_inheritance_reapply
+    ADD CONSTRAINT acl_inheritance_reapply_pkey PRIMARY KEY (type);
+
+
+--
+-- Name: acl_inheritance_rule acl_inheritance_rule_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.acl_inheritance_rule
+    ADD CONSTRAINT acl_inheritance_rule_pkey PRIMARY KEY (type, rel, link_type);
+
+
 --
 -- Name: active_schemas active_schemas_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -789,6 +1061,22 @@ ALTER TABLE ONLY public.deprecation
     ADD CONSTRAINT deprecation_pkey PRIMARY KEY (label);
 
 
+--
+-- Name: deprecation_usage deprecation_usage_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.deprecation_usage
+    ADD CONSTRAINT deprecation_usage_pkey PRIMARY KEY (label, uuid);
+
+
+--
+-- Name: deprecation_usage_scan deprecation_usage_scan_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.deprecation_usage_scan
+    ADD CONSTRAINT deprecation_usage_scan_pkey PRIMARY KEY (generation_id);
+
+
 --
 -- Name: document_archive_counter document_archive_counter_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -797,6 +1085,22 @@ ALTER TABLE ONLY public.document_archive_counter
     ADD CONSTRAINT document_archive_counter_pkey PRIMARY KEY (uuid);
 
 
+--
+-- Name: document_export document_export_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.document_export
+    ADD CONSTRAINT document_export_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: document_link document_link_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.document_link
+    ADD CONSTRAINT document_link_pkey PRIMARY KEY (from_document, to_document, rel, type);
+
+
 --
 -- Name: document_lock document_lock_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -829,6 +1133,14 @@ ALTER TABLE ONLY public.document_status
     ADD CONSTRAINT document_status_pkey PRIMARY KEY (uuid, name, id);
 
 
+--
+-- Name: document_template document_template_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.document_template
+    ADD CONSTRAINT document_template_pkey PRIMARY KEY (name, version);
+
+
 --
 -- Name: document_type document_type_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -965,6 +1277,22 @@ ALTER TABLE ONLY public.purge_request
     ADD CONSTRAINT purge_request_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: read_audit read_audit_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.read_audit
+    ADD CONSTRAINT read_audit_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: read_audit_archiver read_audit_archiver_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.read_audit_archiver
+    ADD CONSTRAINT read_audit_archiver_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: restore_request restore_request_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -1029,6 +1357,38 @@ ALTER TABLE ONLY public.schema_generation_schema
     ADD CONSTRAINT schema_generation_schema_pkey PRIMARY KEY (generation_id, name);
 
 
+--
+-- Name: schema_generation_transform schema_generation_transform_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.schema_generation_transform
+    ADD CONSTRAINT schema_generation_transform_pkey PRIMARY KEY (generation_id);
+
+
+--
+-- Name: schema_revalidation schema_revalidation_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.schema_revalidation
+    ADD CONSTRAINT schema_revalidation_pkey PRIMARY KEY (generation_id);
+
+
+--
+-- Name: schema_revalidation_result schema_revalidation_result_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.schema_revalidation_result
+    ADD CONSTRAINT schema_revalidation_result_pkey PRIMARY KEY (generation_id, uuid);
+
+
+--
+-- Name: schema_upgrade schema_upgrade_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.schema_upgrade
+    ADD CONSTRAINT schema_upgrade_pkey PRIMARY KEY (generation_id);
+
+
 --
 -- Name: signing_keys signing_keys_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -1093,6 +1453,34 @@ ALTER TABLE ONLY public.workflow_state
     ADD CONSTRAINT workflow_state_pkey PRIMARY KEY (uuid);
 
 
+--
+-- Name: acl_expires_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX acl_expires_idx ON public.acl USING btree (expires) WHERE (expires IS NOT NULL);
+
+
+--
+-- Name: acl_inheritance_parent_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX acl_inheritance_parent_idx ON public.acl_inheritance USING btree (parent);
+
+
+--
+-- Name: acl_not_before_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX acl_not_before_idx ON public.acl USING btree (not_before) WHERE (not_before IS NOT NULL);
+
+
+--
+-- Name: attached_object_current_name_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX attached_object_current_name_idx ON public.attached_object_current USING btree (name, document);
+
+
 --
 -- Name: delete_record_uuid_idx; Type: INDEX; Schema: public; Owner: -
 --
@@ -1107,6 +1495,27 @@ CREATE INDEX delete_record_uuid_idx ON public.delete_record USING btree (uuid);
 CREATE INDEX deletes_to_finalise ON public.delete_record USING btree (created) WHERE (finalised IS NULL);
 
 
+--
+-- Name: deprecation_usage_uuid_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX deprecation_usage_uuid_idx ON public.deprecation_usage USING btree (uuid);
+
+
+--
+-- Name: document_export_unfinished_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX document_export_unfinished_idx ON public.document_export USING btree (id) WHERE (finished IS NULL);
+
+
+--
+-- Name: document_link_to_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX document_link_to_idx ON public.document_link USING btree (to_document, from_document);
+
+
 --
 -- Name: document_status_archived; Type: INDEX; Schema: public; Owner: -
 --
@@ -1114,6 +1523,13 @@ CREATE INDEX deletes_to_finalise ON public.delete_record USING btree (created) W
 CREATE INDEX document_status_archived ON public.document_status USING btree (created) WHERE (archived = false);
 
 
+--
+-- Name: document_template_type_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX document_template_type_idx ON public.document_template USING btree (type);
+
+
 --
 -- Name: document_version_archived; Type: INDEX; Schema: public; Owner: -
 --
@@ -1212,6 +1628,20 @@ CREATE INDEX planning_item_event_idx ON public.planning_item USING btree (event)
 CREATE INDEX purges_to_perform ON public.purge_request USING btree (id) WHERE (finished IS NULL);
 
 
+--
+-- Name: read_audit_subject_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX read_audit_subject_idx ON public.read_audit USING btree (subject, id);
+
+
+--
+-- Name: read_audit_uuid_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX read_audit_uuid_idx ON public.read_audit USING btree (uuid, id);
+
+
 --
 -- Name: restores_to_perform; Type: INDEX; Schema: public; Owner: -
 --
@@ -1219,6 +1649,13 @@ CREATE INDEX purges_to_perform ON public.purge_request USING btree (id) WHERE (f
 CREATE INDEX restores_to_perform ON public.restore_request USING btree (id) WHERE (finished IS NULL);
 
 
+--
+-- Name: upload_created_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX upload_created_at_idx ON public.upload USING btree (created_at);
+
+
 --
 -- Name: eventlog sequential_eventlog; Type: TRIGGER; Schema: public; Owner: -
 --
@@ -1226,6 +1663,13 @@ CREATE INDEX restores_to_perform ON public.restore_request USING btree (id) WHER
 CREATE TRIGGER sequential_eventlog BEFORE INSERT ON public.eventlog FOR EACH ROW EXECUTE FUNCTION public.sequential_eventlog();
 
 
+--
+-- Name: read_audit read_audit_append_only; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER read_audit_append_only BEFORE DELETE OR UPDATE ON public.read_audit FOR EACH ROW EXECUTE FUNCTION public.read_audit_append_only();
+
+
 --
 -- Name: acl acl_uuid_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1234,6 +1678,14 @@ ALTER TABLE ONLY public.acl
     ADD CONSTRAINT acl_uuid_fkey FOREIGN KEY (uuid) REFERENCES public.document(uuid) ON DELETE CASCADE;
 
 
+--
+-- Name: acl_inheritance acl_inheritance_uuid_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.acl_inheritance
+    ADD CONSTRAINT acl_inheritance_uuid_fkey FOREIGN KEY (uuid) REFERENCES public.document(uuid) ON DELETE CASCADE;
+
+
 --
 -- Name: active_schemas active_schemas_name_version_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1266,6 +1718,22 @@ ALTER TABLE ONLY public.attached_object
     ADD CONSTRAINT attached_object_document_fkey FOREIGN KEY (document) REFERENCES public.document(uuid) ON DELETE CASCADE;
 
 
+--
+-- Name: deprecation_usage deprecation_usage_uuid_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.deprecation_usage
+    ADD CONSTRAINT deprecation_usage_uuid_fkey FOREIGN KEY (uuid) REFERENCES public.document(uuid) ON DELETE CASCADE;
+
+
+--
+-- Name: deprecation_usage_scan deprecation_usage_scan_generation_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.deprecation_usage_scan
+    ADD CONSTRAINT deprecation_usage_scan_generation_id_fkey FOREIGN KEY (generation_id) REFERENCES public.schema_generation(id) ON DELETE CASCADE;
+
+
 --
 -- Name: document_archive_counter document_archive_counter_uuid_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1274,6 +1742,14 @@ ALTER TABLE ONLY public.document_archive_counter
     ADD CONSTRAINT document_archive_counter_uuid_fkey FOREIGN KEY (uuid) REFERENCES public.document(uuid) ON DELETE CASCADE;
 
 
+--
+-- Name: document_link document_link_from_document_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.document_link
+    ADD CONSTRAINT document_link_from_document_fkey FOREIGN KEY (from_document) REFERENCES public.document(uuid) ON DELETE CASCADE;
+
+
 --
 -- Name: document_lock document_lock_uuid_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1418,6 +1894,38 @@ ALTER TABLE ONLY public.schema_generation_schema
     ADD CONSTRAINT schema_generation_schema_name_version_fkey FOREIGN KEY (name, version) REFERENCES public.document_schema(name, version);
 
 
+--
+-- Name: schema_generation_transform schema_generation_transform_generation_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.schema_generation_transform
+    ADD CONSTRAINT schema_generation_transform_generation_id_fkey FOREIGN KEY (generation_id) REFERENCES public.schema_generation(id);
+
+
+--
+-- Name: schema_revalidation schema_revalidation_generation_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.schema_revalidation
+    ADD CONSTRAINT schema_revalidation_generation_id_fkey FOREIGN KEY (generation_id) REFERENCES public.schema_generation(id);
+
+
+--
+-- Name: schema_revalidation_result schema_revalidation_result_generation_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.schema_revalidation_result
+    ADD CONSTRAINT schema_revalidation_result_generation_id_fkey FOREIGN KEY (generation_id) REFERENCES public.schema_revalidation(generation_id) ON DELETE CASCADE;
+
+
+--
+-- Name: schema_upgrade schema_upgrade_generation_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.schema_upgrade
+    ADD CONSTRAINT schema_upgrade_generation_id_fkey FOREIGN KEY (generation_id) REFERENCES public.schema_generation(id);
+
+
 --
 -- Name: status_heads status_heads_uuid_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
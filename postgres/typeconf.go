@@ -1,10 +1,13 @@
 package postgres
 
 type TypeConfiguration struct {
-	BoundedCollection bool                  `json:"bounded_collection"`
-	TimeExpressions   []TypeTimeExpression  `json:"time_expressions,omitempty"`
-	LabelExpressions  []TypeLabelExpression `json:"label_expressions,omitempty"`
-	Variants          []string              `json:"variants,omitempty"`
+	BoundedCollection  bool                  `json:"bounded_collection"`
+	TimeExpressions    []TypeTimeExpression  `json:"time_expressions,omitempty"`
+	LabelExpressions   []TypeLabelExpression `json:"label_expressions,omitempty"`
+	Variants           []string              `json:"variants,omitempty"`
+	ReadAudit          bool                  `json:"read_audit,omitempty"`
+	ExemplarSampling   *TypeExemplarSampling `json:"exemplar_sampling,omitempty"`
+	ArchiveAttachments bool                  `json:"archive_attachments,omitempty"`
 }
 
 type TypeTimeExpression struct {
@@ -17,3 +20,16 @@ type TypeLabelExpression struct {
 	Expression string `json:"expression"`
 	Template   string `json:"template"`
 }
+
+type TypeExemplarSampling struct {
+	SampleSize int                   `json:"sample_size"`
+	Anonymise  []TypeAnonymisedField `json:"anonymise,omitempty"`
+}
+
+type TypeAnonymisedField struct {
+	Kind string `json:"kind,omitempty"`
+	Type string `json:"type,omitempty"`
+	Rel  string `json:"rel,omitempty"`
+	Role string `json:"role,omitempty"`
+	Key  string `json:"key"`
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/pg"
+)
+
+const (
+	// aclExpiryUpdater is used as the updater of ACL events created
+	// when time-bound grants take or lose effect.
+	aclExpiryUpdater = "internal://acl-expiry"
+
+	// aclInheritanceUpdater is used as the updater of ACL events
+	// created when changed inheritance rules are re-applied.
+	aclInheritanceUpdater = "internal://acl-inheritance"
+
+	aclTransitionBatchSize = 200
+)
+
+// RunACLExpiry periodically removes expired ACL entries and emits ACL events
+// for time-bound grants that have taken effect.
+func (s *PGDocStore) RunACLExpiry(ctx context.Context, period time.Duration) {
+	for {
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return
+		}
+
+		jobLock, err := pg.NewJobLock(s.pool, s.logger, "acl-expiry", pg.JobLockOptions{
+			PingInterval:  10 * time.Second,
+			StaleAfter:    1 * time.Minute,
+			CheckInterval: 20 * time.Second,
+			Timeout:       5 * time.Second,
+		})
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to create job lock",
+				elephantine.LogKeyError, err)
+
+			continue
+		}
+
+		err = jobLock.RunWithContext(ctx, s.processACLTransitions)
+		if err != nil {
+			s.logger.ErrorContext(
+				ctx, "ACL expiry error",
+				elephantine.LogKeyError, err,
+			)
+		}
+	}
+}
+
+func (s *PGDocStore) processACLTransitions(ctx context.Context) error {
+	for {
+		n, err := s.processACLTransitionBatch(ctx)
+		if err != nil {
+			return err
+		}
+
+		if n < aclTransitionBatchSize {
+			return nil
+		}
+	}
+}
+
+func (s *PGDocStore) processACLTransitionBatch(ctx context.Context) (int, error) {
+	var count int
+
+	err := pg.WithTX(ctx, s.pool, func(tx pgx.Tx) error {
+		q := postgres.New(tx)
+		now := time.Now()
+
+		rows, err := q.GetACLTransitions(ctx, postgres.GetACLTransitionsParams{
+			Now:   pg.Time(now),
+			Count: aclTransitionBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("get ACL transitions: %w", err)
+		}
+
+		count = len(rows)
+
+		if count == 0 {
+			return nil
+		}
+
+		var (
+			uuids   []uuid.UUID
+			docs    = make(map[uuid.UUID]postgres.GetACLTransitionsRow)
+			updates = make(map[uuid.UUID][]ACLEntry)
+		)
+
+		for _, row := range rows {
+			if _, seen := docs[row.UUID]; !seen {
+				uuids = append(uuids, row.UUID)
+				docs[row.UUID] = row
+			}
+
+			entry := ACLEntry{
+				URI:     row.URI,
+				Expires: timestamptzPointer(row.Expires),
+			}
+
+			// Expired entries are dropped by giving them empty
+			// permissions, grants that have taken effect are
+			// kept without a not before time.
+			if entry.Expires == nil || now.Before(*entry.Expires) {
+				entry.Permissions = row.Permissions
+			}
+
+			updates[row.UUID] = append(updates[row.UUID], entry)
+		}
+
+		for _, docUUID := range uuids {
+			err := updateACL(ctx, q, docUUID, updates[docUUID], true)
+			if err != nil {
+				return fmt.Errorf("update ACL of %s: %w", docUUID, err)
+			}
+		}
+
+		effective, err := bulkGetEffectiveACL(ctx, q, uuids)
+		if err != nil {
+			return err
+		}
+
+		var evts []postgres.OutboxEvent
+
+		for _, docUUID := range uuids {
+			doc := docs[docUUID]
+
+			evts = append(evts, postgres.OutboxEvent{
+				Event:            string(TypeACLUpdate),
+				UUID:             docUUID,
+				Version:          doc.CurrentVersion,
+				Nonce:            doc.Nonce,
+				Timestamp:        now,
+				Updater:          aclExpiryUpdater,
+				Type:             doc.Type,
+				ACL:              aclTransitionEntries(updates[docUUID], effective[docUUID]),
+				Language:         doc.Language.String,
+				MainDocument:     pg.ToUUIDPointer(doc.MainDoc),
+				MainDocumentType: doc.MainDocType.String,
+				Timespans: TimespansAsTuples(
+					TimestampRangesToTimespans(doc.Time)),
+				Labels: doc.Labels,
+			})
+
+			inheritorEvts, err := aclInheritorEvents(ctx, q,
+				docUUID, updates[docUUID], now, aclExpiryUpdater)
+			if err != nil {
+				return fmt.Errorf(
+					"create ACL events for inheriting documents: %w", err)
+			}
+
+			evts = append(evts, inheritorEvts...)
+		}
+
+		for _, evt := range evts {
+			err := addEventToOutbox(ctx, tx, evt)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("process ACL transitions: %w", err)
+	}
+
+	if count > 0 {
+		s.logger.InfoContext(ctx, "processed time-bound ACL entries",
+			"count", count)
+	}
+
+	return count, nil
+}
+
+// aclTransitionEntries creates the ACL event entries for the URIs that were
+// updated. The permissions are taken from the effective ACL so that grants
+// inherited from other documents are reflected in the event, and URIs that no
+// longer grant anything get empty permissions so that consumers drop them.
+func aclTransitionEntries(
+	updated []ACLEntry, effective []ACLEntry,
+) []postgres.ACLEntry {
+	entries := make([]postgres.ACLEntry, len(updated))
+
+	for i, u := range updated {
+		// Entries that haven't taken effect yet are sent with their
+		// time bounds, the ACL expiry job will emit a new event when
+		// they take effect.
+		entries[i] = postgres.ACLEntry{
+			URI:         u.URI,
+			Permissions: []string{},
+			NotBefore:   u.NotBefore,
+			Expires:     u.Expires,
+		}
+
+		for _, e := range effective {
+			if e.URI != u.URI {
+				continue
+			}
+
+			entries[i].Permissions = e.Permissions
+			entries[i].NotBefore = nil
+			entries[i].Expires = e.Expires
+		}
+	}
+
+	return entries
+}
+
+// aclEventEntries converts ACL entries to event entries. Entries that aren't
+// in effect at the given time are given empty permissions, the ACL expiry job
+// will emit a new event when they take effect.
+func aclEventEntries(acl []ACLEntry, t time.Time) []postgres.ACLEntry {
+	entries := make([]postgres.ACLEntry, len(acl))
+
+	for i, e := range acl {
+		entries[i] = postgres.ACLEntry{
+			URI:         e.URI,
+			Permissions: e.Permissions,
+			NotBefore:   e.NotBefore,
+			Expires:     e.Expires,
+		}
+
+		if !e.InEffect(t) {
+			entries[i].Permissions = []string{}
+		}
+	}
+
+	return entries
+}
+
+func aclEntryFromRow(row postgres.Acl) ACLEntry {
+	return ACLEntry{
+		URI:         row.URI,
+		Permissions: row.Permissions,
+		NotBefore:   timestamptzPointer(row.NotBefore),
+		Expires:     timestamptzPointer(row.Expires),
+	}
+}
+
+func timestamptzPointer(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
@@ -0,0 +1,182 @@
+package repository_test
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationTimeBoundACL(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+	ctx := t.Context()
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		RunEventlogBuilder: true,
+		RunACLExpiry:       true,
+	})
+
+	const (
+		expiringUnit = "unit://test/expiring"
+		upcomingUnit = "unit://test/upcoming"
+	)
+
+	editorClaims := itest.Claims(t, "editor", "doc_read doc_write eventlog_read")
+
+	editor := tc.DocumentsClient(t, editorClaims)
+	editorExt := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, editorClaims)
+	expiring := tc.DocumentsClient(t,
+		itest.Claims(t, "expiring", "doc_read", expiringUnit))
+	upcoming := tc.DocumentsClient(t,
+		itest.Claims(t, "upcoming", "doc_read", upcomingUnit))
+
+	docUUID := uuid.NewString()
+
+	_, err := editor.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: baseDocument(docUUID, "article://test/time-bound"),
+	})
+	test.Must(t, err, "create document")
+
+	now := time.Now()
+	expires := now.Add(2 * time.Second)
+	notBefore := now.Add(2 * time.Second)
+
+	err = editorExt.Call(ctx, "UpdateACL", repository.UpdateACLRequest{
+		UUID: docUUID,
+		ACL: []repository.ACLEntry{
+			{
+				URI:         expiringUnit,
+				Permissions: []string{"r"},
+				Expires:     &expires,
+			},
+			{
+				URI:         upcomingUnit,
+				Permissions: []string{"r"},
+				NotBefore:   &notBefore,
+			},
+		},
+	}, &repository.UpdateACLResponse{})
+	test.Must(t, err, "set time-bound grants")
+
+	// An ordinary update can't express time bounds, and must not make
+	// the temporary grant permanent when it resends the entry.
+	_, err = editor.Update(ctx, &rpc.UpdateRequest{
+		Uuid: docUUID,
+		Acl: []*rpc.ACLEntry{
+			{
+				Uri:         expiringUnit,
+				Permissions: []string{"r"},
+			},
+		},
+	})
+	test.Must(t, err, "resend the time-bound grant without bounds")
+
+	var bounded repository.GetACLResponse
+
+	err = editorExt.Call(ctx, "GetACL", repository.GetACLRequest{
+		UUID: docUUID,
+	}, &bounded)
+	test.Must(t, err, "get document ACL")
+
+	for _, e := range bounded.ACL {
+		if e.URI == expiringUnit && e.Expires == nil {
+			t.Fatal("expected the grant to keep its expiry time")
+		}
+	}
+
+	err = editorExt.Call(ctx, "UpdateACL", repository.UpdateACLRequest{
+		UUID: docUUID,
+		ACL: []repository.ACLEntry{
+			{
+				URI:         expiringUnit,
+				Permissions: []string{"r"},
+				Expires:     &now,
+			},
+		},
+	}, &repository.UpdateACLResponse{})
+	itest.IsTwirpError(t, err, twirp.InvalidArgument)
+
+	_, err = expiring.Get(ctx, &rpc.GetDocumentRequest{Uuid: docUUID})
+	test.Must(t, err, "read document before the grant expires")
+
+	_, err = upcoming.Get(ctx, &rpc.GetDocumentRequest{Uuid: docUUID})
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	time.Sleep(time.Until(expires))
+
+	_, err = expiring.Get(ctx, &rpc.GetDocumentRequest{Uuid: docUUID})
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	_, err = upcoming.Get(ctx, &rpc.GetDocumentRequest{Uuid: docUUID})
+	test.Must(t, err, "read document after the grant has taken effect")
+
+	deadline := time.After(5 * time.Second)
+
+	var (
+		after            int64
+		expiredEvent     bool
+		takenEffectEvent bool
+	)
+
+	for !expiredEvent || !takenEffectEvent {
+		log, err := editor.Eventlog(ctx, &rpc.GetEventlogRequest{
+			After:       after,
+			BatchWaitMs: 200,
+		})
+		test.Must(t, err, "read eventlog")
+
+		for _, item := range log.Items {
+			after = item.Id
+
+			if item.Event != "acl" || item.Uuid != docUUID ||
+				item.UpdaterUri != "internal://acl-expiry" {
+				continue
+			}
+
+			for _, e := range item.Acl {
+				switch {
+				case e.Uri == expiringUnit && len(e.Permissions) == 0:
+					expiredEvent = true
+				case e.Uri == upcomingUnit && len(e.Permissions) == 1:
+					takenEffectEvent = true
+				}
+			}
+		}
+
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for ACL expiry events, got expired: %v, taken effect: %v",
+				expiredEvent, takenEffectEvent)
+		default:
+		}
+	}
+
+	var acl repository.GetACLResponse
+
+	err = editorExt.Call(ctx, "GetACL", repository.GetACLRequest{
+		UUID: docUUID,
+	}, &acl)
+	test.Must(t, err, "get document ACL")
+
+	for _, e := range acl.ACL {
+		switch e.URI {
+		case expiringUnit:
+			t.Fatal("expected the expired grant to have been removed")
+		case upcomingUnit:
+			if e.NotBefore != nil {
+				t.Fatal("expected the not before time to have been cleared")
+			}
+		}
+	}
+}
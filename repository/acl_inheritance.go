@@ -0,0 +1,603 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/pg"
+	"github.com/ttab/newsdoc"
+)
+
+// SetACLInheritance implements SchemaStore.
+func (s *PGDocStore) SetACLInheritance(
+	ctx context.Context,
+	docType string,
+	rules []ACLInheritanceRule,
+) error {
+	err := pg.WithTX(ctx, s.pool, func(tx pgx.Tx) error {
+		q := postgres.New(tx)
+
+		err := q.DeleteACLInheritanceRules(ctx, docType)
+		if err != nil {
+			return fmt.Errorf("clear current rules: %w", err)
+		}
+
+		for _, r := range rules {
+			err := q.InsertACLInheritanceRule(ctx,
+				postgres.InsertACLInheritanceRuleParams{
+					Type:        docType,
+					Rel:         r.Rel,
+					LinkType:    r.LinkType,
+					Permissions: r.Permissions,
+				})
+			if pg.IsConstraintError(err, "acl_inheritance_rule_pkey") {
+				return DocStoreErrorf(ErrCodeBadRequest,
+					"duplicate rule for the rel %q and link type %q",
+					r.Rel, r.LinkType)
+			} else if err != nil {
+				return fmt.Errorf("insert rule: %w", err)
+			}
+		}
+
+		// Existing documents of the type get their inheritance
+		// re-evaluated by the ACL inheritance job.
+		err = q.StartACLInheritanceReapply(ctx,
+			postgres.StartACLInheritanceReapplyParams{
+				Type:    docType,
+				Created: pg.Time(time.Now()),
+			})
+		if err != nil {
+			return fmt.Errorf("start re-applying rules: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+// GetACLInheritance implements SchemaStore.
+func (s *PGDocStore) GetACLInheritance(
+	ctx context.Context,
+	docType string,
+) ([]ACLInheritanceRule, error) {
+	return getACLInheritanceRules(ctx, s.reader, docType)
+}
+
+func getACLInheritanceRules(
+	ctx context.Context, q *postgres.Queries, docType string,
+) ([]ACLInheritanceRule, error) {
+	rows, err := q.GetACLInheritanceRules(ctx, docType)
+	if err != nil {
+		return nil, fmt.Errorf("read rules from database: %w", err)
+	}
+
+	rules := make([]ACLInheritanceRule, len(rows))
+
+	for i := range rows {
+		rules[i] = ACLInheritanceRule{
+			Rel:         rows[i].Rel,
+			LinkType:    rows[i].LinkType,
+			Permissions: rows[i].Permissions,
+		}
+	}
+
+	return rules, nil
+}
+
+// GetEffectiveDocumentACL implements DocStore.
+func (s *PGDocStore) GetEffectiveDocumentACL(
+	ctx context.Context, docUUID uuid.UUID,
+) ([]ACLEntry, error) {
+	acls, err := bulkGetEffectiveACL(ctx, s.reader, []uuid.UUID{docUUID})
+	if err != nil {
+		return nil, err
+	}
+
+	return acls[docUUID], nil
+}
+
+func bulkGetEffectiveACL(
+	ctx context.Context, q *postgres.Queries, uuids []uuid.UUID,
+) (map[uuid.UUID][]ACLEntry, error) {
+	own, err := q.BulkGetDocumentACL(ctx, uuids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch document ACLs: %w", err)
+	}
+
+	now := time.Now()
+	result := make(map[uuid.UUID][]ACLEntry, len(uuids))
+
+	for _, a := range own {
+		entry := aclEntryFromRow(a)
+
+		// Entries that aren't in effect don't grant any access.
+		if !entry.InEffect(now) {
+			continue
+		}
+
+		result[a.UUID] = append(result[a.UUID], entry)
+	}
+
+	err = addInheritedACL(ctx, q, uuids, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// addInheritedACL merges the grants that the documents inherit from linked
+// documents into their ACLs.
+func addInheritedACL(
+	ctx context.Context, q *postgres.Queries,
+	uuids []uuid.UUID, acls map[uuid.UUID][]ACLEntry,
+) error {
+	inherited, err := q.GetInheritedACL(ctx, uuids)
+	if err != nil {
+		return fmt.Errorf("failed to fetch inherited ACLs: %w", err)
+	}
+
+	for _, a := range inherited {
+		acls[a.UUID] = mergeACLEntry(acls[a.UUID], ACLEntry{
+			URI:         a.URI,
+			Permissions: a.Permissions,
+		})
+	}
+
+	return nil
+}
+
+func mergeACLEntry(acl []ACLEntry, entry ACLEntry) []ACLEntry {
+	idx := slices.IndexFunc(acl, func(e ACLEntry) bool {
+		return e.URI == entry.URI
+	})
+	if idx == -1 {
+		return append(acl, entry)
+	}
+
+	// Don't modify the permissions slice in place, it might be shared.
+	perms := slices.Clone(acl[idx].Permissions)
+
+	for _, p := range entry.Permissions {
+		if !slices.Contains(perms, p) {
+			perms = append(perms, p)
+		}
+	}
+
+	acl[idx].Permissions = perms
+
+	// The merged entry combines grants with different time bounds, the
+	// bounds of the own entry no longer describe it.
+	acl[idx].NotBefore = nil
+	acl[idx].Expires = nil
+
+	return acl
+}
+
+// aclInheritanceParents returns the documents that doc should inherit
+// permissions from according to the rules, and the permissions inherited from
+// each of them.
+func aclInheritanceParents(
+	docUUID uuid.UUID, doc newsdoc.Document, rules []ACLInheritanceRule,
+) map[uuid.UUID][]string {
+	parents := make(map[uuid.UUID][]string)
+
+	for _, link := range doc.Links {
+		if link.UUID == "" {
+			continue
+		}
+
+		parent, err := uuid.Parse(link.UUID)
+		if err != nil || parent == docUUID {
+			continue
+		}
+
+		for _, r := range rules {
+			if r.Rel != link.Rel {
+				continue
+			}
+
+			if r.LinkType != "" && r.LinkType != link.Type {
+				continue
+			}
+
+			perms := parents[parent]
+
+			for _, p := range r.Permissions {
+				if !slices.Contains(perms, p) {
+					perms = append(perms, p)
+				}
+			}
+
+			parents[parent] = perms
+		}
+	}
+
+	for _, perms := range parents {
+		slices.Sort(perms)
+	}
+
+	return parents
+}
+
+// updateACLInheritance replaces the recorded ACL inheritance for a document
+// based on its current links. Returns the effective ACL of the document from
+// before the change, and whether the inheritance changed.
+func updateACLInheritance(
+	ctx context.Context, q *postgres.Queries,
+	docUUID uuid.UUID, doc newsdoc.Document,
+) ([]ACLEntry, bool, error) {
+	rules, err := getACLInheritanceRules(ctx, q, doc.Type)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var parents map[uuid.UUID][]string
+
+	if len(rules) > 0 {
+		parents = aclInheritanceParents(docUUID, doc, rules)
+	}
+
+	current, err := q.GetDocumentACLInheritance(ctx, docUUID)
+	if err != nil {
+		return nil, false, fmt.Errorf("get current inheritance: %w", err)
+	}
+
+	changed := len(current) != len(parents)
+
+	for _, c := range current {
+		perms, ok := parents[c.Parent]
+		if !ok || !sameACLPermissions(perms, c.Permissions) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil, false, nil
+	}
+
+	previous, err := bulkGetEffectiveACL(ctx, q, []uuid.UUID{docUUID})
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = q.DropDocumentACLInheritance(ctx, docUUID)
+	if err != nil {
+		return nil, false, fmt.Errorf("clear current inheritance: %w", err)
+	}
+
+	for parent, perms := range parents {
+		err := q.InsertDocumentACLInheritance(ctx,
+			postgres.InsertDocumentACLInheritanceParams{
+				UUID:        docUUID,
+				Parent:      parent,
+				Permissions: perms,
+			})
+		if err != nil {
+			return nil, false, fmt.Errorf(
+				"record inheritance from %s: %w", parent, err)
+		}
+	}
+
+	return previous[docUUID], true, nil
+}
+
+// addChangedACLURIs adds entries without permissions to the update for the
+// URIs that have different permissions in the before and after ACLs, so that
+// aclTransitionEntries includes them in ACL events.
+func addChangedACLURIs(update []ACLEntry, before, after []ACLEntry) []ACLEntry {
+	permissionsOf := func(acl []ACLEntry, uri string) []string {
+		for _, e := range acl {
+			if e.URI == uri {
+				return e.Permissions
+			}
+		}
+
+		return nil
+	}
+
+	add := func(uri string) {
+		if slices.ContainsFunc(update, func(e ACLEntry) bool {
+			return e.URI == uri
+		}) {
+			return
+		}
+
+		if sameACLPermissions(
+			permissionsOf(before, uri), permissionsOf(after, uri),
+		) {
+			return
+		}
+
+		update = append(update, ACLEntry{URI: uri})
+	}
+
+	for _, e := range before {
+		add(e.URI)
+	}
+
+	for _, e := range after {
+		add(e.URI)
+	}
+
+	return update
+}
+
+func sameACLPermissions(a, b []string) bool {
+	a = slices.Clone(a)
+	b = slices.Clone(b)
+
+	slices.Sort(a)
+	slices.Sort(b)
+
+	return slices.Equal(a, b)
+}
+
+// aclInheritorEvents creates "acl" events for the documents that inherit
+// permissions from the parent document. Should be called after the ACL of the
+// parent has been updated so that the events carry the new effective
+// permissions for the updated URIs.
+func aclInheritorEvents(
+	ctx context.Context, q *postgres.Queries, parent uuid.UUID,
+	updated []ACLEntry, timestamp time.Time, updater string,
+) ([]postgres.OutboxEvent, error) {
+	inheritors, err := q.GetACLInheritors(ctx, parent)
+	if err != nil {
+		return nil, fmt.Errorf("get inheriting documents: %w", err)
+	}
+
+	if len(inheritors) == 0 {
+		return nil, nil
+	}
+
+	uuids := make([]uuid.UUID, len(inheritors))
+
+	for i := range inheritors {
+		uuids[i] = inheritors[i].UUID
+	}
+
+	acls, err := bulkGetEffectiveACL(ctx, q, uuids)
+	if err != nil {
+		return nil, err
+	}
+
+	// The time bounds of the parent entries don't apply to the
+	// inheriting documents, only send the URIs.
+	uris := make([]ACLEntry, len(updated))
+
+	for i := range updated {
+		uris[i] = ACLEntry{URI: updated[i].URI}
+	}
+
+	evts := make([]postgres.OutboxEvent, 0, len(inheritors))
+
+	for _, doc := range inheritors {
+		// Documents that are being deleted or restored will get
+		// their ACL events when the process finishes.
+		if doc.SystemState.Valid {
+			continue
+		}
+
+		evts = append(evts, inheritorACLEvent(doc,
+			aclTransitionEntries(uris, acls[doc.UUID]),
+			timestamp, updater))
+	}
+
+	return evts, nil
+}
+
+func inheritorACLEvent(
+	doc postgres.GetACLInheritorsRow, entries []postgres.ACLEntry,
+	timestamp time.Time, updater string,
+) postgres.OutboxEvent {
+	return postgres.OutboxEvent{
+		Event:            string(TypeACLUpdate),
+		UUID:             doc.UUID,
+		Version:          doc.CurrentVersion,
+		Nonce:            doc.Nonce,
+		Timestamp:        timestamp,
+		Updater:          updater,
+		Type:             doc.Type,
+		ACL:              entries,
+		Language:         doc.Language.String,
+		MainDocument:     pg.ToUUIDPointer(doc.MainDoc),
+		MainDocumentType: doc.MainDocType.String,
+		Timespans: TimespansAsTuples(
+			TimestampRangesToTimespans(doc.Time)),
+		Labels: doc.Labels,
+	}
+}
+
+// RunACLInheritance periodically re-applies ACL inheritance rules that have
+// changed to the existing documents of the affected types.
+func (s *PGDocStore) RunACLInheritance(ctx context.Context, period time.Duration) {
+	for {
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return
+		}
+
+		jobLock, err := pg.NewJobLock(s.pool, s.logger, "acl-inheritance", pg.JobLockOptions{
+			PingInterval:  10 * time.Second,
+			StaleAfter:    1 * time.Minute,
+			CheckInterval: 20 * time.Second,
+			Timeout:       5 * time.Second,
+		})
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to create job lock",
+				elephantine.LogKeyError, err)
+
+			continue
+		}
+
+		err = jobLock.RunWithContext(ctx, s.processACLInheritanceReapply)
+		if err != nil {
+			s.logger.ErrorContext(
+				ctx, "ACL inheritance error",
+				elephantine.LogKeyError, err,
+			)
+		}
+	}
+}
+
+func (s *PGDocStore) processACLInheritanceReapply(ctx context.Context) error {
+	for {
+		job, err := s.reader.GetACLInheritanceReapply(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("get pending ACL inheritance job: %w", err)
+		}
+
+		for {
+			done, err := s.reapplyACLInheritanceBatch(ctx, &job)
+			if errors.Is(err, errACLInheritanceReplaced) {
+				break
+			} else if err != nil {
+				return fmt.Errorf("re-apply rules for %q: %w",
+					job.Type, err)
+			}
+
+			if done {
+				s.logger.InfoContext(ctx,
+					"re-applied ACL inheritance rules",
+					elephantine.LogKeyDocumentType, job.Type)
+
+				break
+			}
+		}
+	}
+}
+
+// errACLInheritanceReplaced is returned when the rules of a type have been
+// changed while a batch was being processed.
+var errACLInheritanceReplaced = errors.New("ACL inheritance job has been replaced")
+
+func (s *PGDocStore) reapplyACLInheritanceBatch(
+	ctx context.Context, job *postgres.AclInheritanceReapply,
+) (bool, error) {
+	var done bool
+
+	err := pg.WithTX(ctx, s.pool, func(tx pgx.Tx) error {
+		q := postgres.New(tx)
+		now := time.Now()
+
+		docs, err := q.GetDocumentsForACLInheritance(ctx,
+			postgres.GetDocumentsForACLInheritanceParams{
+				Type:     job.Type,
+				After:    job.Position,
+				RowLimit: aclTransitionBatchSize,
+			})
+		if err != nil {
+			return fmt.Errorf("get documents: %w", err)
+		}
+
+		var evts []postgres.OutboxEvent
+
+		for _, d := range docs {
+			var doc newsdoc.Document
+
+			err := json.Unmarshal(d.DocumentData, &doc)
+			if err != nil {
+				return fmt.Errorf(
+					"unmarshal document %s: %w", d.UUID, err)
+			}
+
+			before, changed, err := updateACLInheritance(
+				ctx, q, d.UUID, doc)
+			if err != nil {
+				return fmt.Errorf(
+					"update inheritance of %s: %w", d.UUID, err)
+			}
+
+			// Documents that are being deleted or restored will
+			// get their ACL events when the process finishes.
+			if !changed || d.SystemState.Valid {
+				continue
+			}
+
+			after, err := bulkGetEffectiveACL(ctx, q,
+				[]uuid.UUID{d.UUID})
+			if err != nil {
+				return err
+			}
+
+			entries := aclTransitionEntries(
+				addChangedACLURIs(nil, before, after[d.UUID]),
+				after[d.UUID])
+			if len(entries) == 0 {
+				continue
+			}
+
+			evts = append(evts, inheritorACLEvent(
+				postgres.GetACLInheritorsRow{
+					UUID:           d.UUID,
+					Type:           d.Type,
+					Language:       d.Language,
+					CurrentVersion: d.CurrentVersion,
+					Nonce:          d.Nonce,
+					MainDoc:        d.MainDoc,
+					MainDocType:    d.MainDocType,
+					SystemState:    d.SystemState,
+					Labels:         d.Labels,
+					Time:           d.Time,
+				}, entries, now, aclInheritanceUpdater))
+		}
+
+		for _, evt := range evts {
+			err := addEventToOutbox(ctx, tx, evt)
+			if err != nil {
+				return err
+			}
+		}
+
+		var n int64
+
+		if len(docs) < aclTransitionBatchSize {
+			done = true
+
+			n, err = q.FinishACLInheritanceReapply(ctx,
+				postgres.FinishACLInheritanceReapplyParams{
+					Type:    job.Type,
+					Created: job.Created,
+				})
+		} else {
+			job.Position = docs[len(docs)-1].UUID
+
+			n, err = q.SetACLInheritanceReapplyPosition(ctx,
+				postgres.SetACLInheritanceReapplyPositionParams{
+					Position: job.Position,
+					Type:     job.Type,
+					Created:  job.Created,
+				})
+		}
+
+		if err != nil {
+			return fmt.Errorf("update job position: %w", err)
+		}
+
+		if n == 0 {
+			return errACLInheritanceReplaced
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return done, nil
+}
@@ -0,0 +1,197 @@
+package repository_test
+
+import (
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	rpc_newsdoc "github.com/ttab/elephant-api/newsdoc"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/test"
+	"github.com/ttab/newsdoc"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationACLInheritance(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+	dataDir := filepath.Join("..", "testdata", t.Name())
+	ctx := t.Context()
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		RunEventlogBuilder: true,
+		RunACLInheritance:  true,
+	})
+
+	const deskUnit = "unit://test/desk"
+
+	editor := tc.DocumentsClient(t,
+		itest.Claims(t, "editor", "doc_read doc_write eventlog_read"))
+	planner := tc.DocumentsClient(t,
+		itest.Claims(t, "planner", "doc_read doc_write"))
+	reader := tc.DocumentsClient(t,
+		itest.Claims(t, "reader", "doc_read doc_write", deskUnit))
+
+	var event newsdoc.Document
+
+	err := elephantine.UnmarshalFile(
+		filepath.Join(dataDir, "event.json"), &event)
+	test.Must(t, err, "unmarshal event document")
+
+	_, err = editor.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     event.UUID,
+		Document: rpc_newsdoc.DocumentToRPC(event),
+		Acl: []*rpc.ACLEntry{
+			{
+				Uri:         deskUnit,
+				Permissions: []string{"r", "w"},
+			},
+		},
+	})
+	test.Must(t, err, "create event")
+
+	// Created before the rules are set, gets the inherited grant when
+	// the rules are re-applied to existing documents.
+	existingUUID := uuid.NewString()
+
+	_, err = planner.Update(ctx, &rpc.UpdateRequest{
+		Uuid: existingUUID,
+		Document: basePlanningDocument(
+			existingUUID, "", "", event.UUID),
+	})
+	test.Must(t, err, "create planning item before setting rules")
+
+	_, err = reader.Get(ctx, &rpc.GetDocumentRequest{
+		Uuid: existingUUID,
+	})
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	schemas := tc.ExtensionClient(t, rpc.SchemasPathPrefix,
+		itest.StandardClaims(t, "schema_admin"))
+
+	err = schemas.Call(ctx, "SetACLInheritance",
+		repository.SetACLInheritanceRequest{
+			Type: "core/planning-item",
+			Rules: []repository.ACLInheritanceRule{
+				{
+					Rel:         "event",
+					LinkType:    "core/event",
+					Permissions: []string{"r"},
+				},
+			},
+		}, &repository.SetACLInheritanceResponse{})
+	test.Must(t, err, "set ACL inheritance rules")
+
+	reapplyDeadline := time.Now().Add(5 * time.Second)
+
+	for {
+		_, err = reader.Get(ctx, &rpc.GetDocumentRequest{
+			Uuid: existingUUID,
+		})
+		if err == nil {
+			break
+		}
+
+		if time.Now().After(reapplyDeadline) {
+			t.Fatalf("expected the rules to be re-applied to the existing planning item: %v",
+				err)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	planningUUID := uuid.NewString()
+
+	_, err = planner.Update(ctx, &rpc.UpdateRequest{
+		Uuid: planningUUID,
+		Document: basePlanningDocument(
+			planningUUID, "", "", event.UUID),
+	})
+	test.Must(t, err, "create planning item")
+
+	_, err = reader.Get(ctx, &rpc.GetDocumentRequest{
+		Uuid: planningUUID,
+	})
+	test.Must(t, err, "read planning item through inherited grant")
+
+	_, err = reader.Update(ctx, &rpc.UpdateRequest{
+		Uuid: planningUUID,
+		Document: basePlanningDocument(
+			planningUUID, "", "", event.UUID),
+	})
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	meta, err := planner.GetMeta(ctx, &rpc.GetMetaRequest{
+		Uuid: planningUUID,
+	})
+	test.Must(t, err, "get planning item meta")
+
+	hasInherited := slices.ContainsFunc(meta.Meta.Acl, func(e *rpc.ACLEntry) bool {
+		return e.Uri == deskUnit && slices.Equal(e.Permissions, []string{"r"})
+	})
+	if !hasInherited {
+		t.Fatalf("expected the meta ACL to contain the inherited grant, got: %v",
+			meta.Meta.Acl)
+	}
+
+	_, err = editor.Update(ctx, &rpc.UpdateRequest{
+		Uuid: event.UUID,
+		Acl: []*rpc.ACLEntry{
+			{
+				Uri:         deskUnit,
+				Permissions: []string{},
+			},
+		},
+	})
+	test.Must(t, err, "revoke desk access to the event")
+
+	_, err = reader.Get(ctx, &rpc.GetDocumentRequest{
+		Uuid: planningUUID,
+	})
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	deadline := time.After(5 * time.Second)
+
+	var after int64
+
+	for {
+		log, err := editor.Eventlog(ctx, &rpc.GetEventlogRequest{
+			After:       after,
+			BatchWaitMs: 200,
+		})
+		test.Must(t, err, "read eventlog")
+
+		for _, item := range log.Items {
+			after = item.Id
+
+			if item.Event != "acl" || item.Uuid != planningUUID {
+				continue
+			}
+
+			revoked := slices.ContainsFunc(item.Acl, func(e *rpc.ACLEntry) bool {
+				return e.Uri == deskUnit && len(e.Permissions) == 0
+			})
+			if !revoked {
+				t.Fatalf("expected the acl event to drop the revoked grant, got: %v",
+					item.Acl)
+			}
+
+			return
+		}
+
+		select {
+		case <-deadline:
+			t.Fatal("timed out waiting for an acl event for the planning item")
+		default:
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/twitchtv/twirp"
+	"github.com/twitchtv/twirp/ctxsetters"
+)
+
+// ExtensionMethod is an API method that isn't part of the published service
+// definitions. The returned value is marshalled as the JSON response body.
+type ExtensionMethod func(ctx context.Context, r *http.Request) (any, error)
+
+// ExtensionMethods maps method names to extension methods.
+type ExtensionMethods map[string]ExtensionMethod
+
+// ExtensionProvider is implemented by API services that serve extension
+// methods. Extension methods are served under the same path prefix as the
+// service they belong to, f.ex. the "GetBacklinks" extension for the
+// documents service is called through
+// "/twirp/elephant.repository.Documents/GetBacklinks". Only JSON requests are
+// supported, and errors are returned using the twirp error format.
+type ExtensionProvider interface {
+	ExtensionMethods() ExtensionMethods
+}
+
+// JSONMethod creates an extension method that reads its request from a JSON
+// body.
+func JSONMethod[Req any, Res any](
+	fn func(ctx context.Context, req *Req) (*Res, error),
+) ExtensionMethod {
+	return func(ctx context.Context, r *http.Request) (any, error) {
+		ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if ct != "application/json" {
+			return nil, twirp.NewError(twirp.Malformed,
+				"extension methods only support JSON requests")
+		}
+
+		var req Req
+
+		dec := json.NewDecoder(r.Body)
+
+		err := dec.Decode(&req)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, twirp.WrapError(twirp.NewError(twirp.Malformed,
+				"the json request could not be decoded"), err)
+		}
+
+		res, err := fn(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+
+		if res == nil {
+			return nil, twirp.InternalError(
+				"received a nil response and nil error")
+		}
+
+		return res, nil
+	}
+}
+
+type extensionHandler struct {
+	hooks   *twirp.ServerHooks
+	pkg     string
+	service string
+	method  string
+	fn      ExtensionMethod
+}
+
+func newExtensionHandler(
+	hooks *twirp.ServerHooks, pathPrefix string, method string,
+	fn ExtensionMethod,
+) *extensionHandler {
+	qualified := strings.Trim(strings.TrimPrefix(pathPrefix, "/twirp/"), "/")
+
+	h := extensionHandler{
+		hooks:   hooks,
+		service: qualified,
+		method:  method,
+		fn:      fn,
+	}
+
+	idx := strings.LastIndex(qualified, ".")
+	if idx != -1 {
+		h.pkg = qualified[:idx]
+		h.service = qualified[idx+1:]
+	}
+
+	return &h
+}
+
+// ServeHTTP mimics how a generated twirp server handles requests, so that the
+// server hooks used for logging and metrics work for extension methods as
+// well.
+func (h *extensionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx = ctxsetters.WithPackageName(ctx, h.pkg)
+	ctx = ctxsetters.WithServiceName(ctx, h.service)
+	ctx = ctxsetters.WithResponseWriter(ctx, w)
+
+	ctx, err := h.requestReceived(ctx)
+	if err != nil {
+		h.writeError(ctx, w, err)
+
+		return
+	}
+
+	ctx = ctxsetters.WithMethodName(ctx, h.method)
+
+	ctx, err = h.requestRouted(ctx)
+	if err != nil {
+		h.writeError(ctx, w, err)
+
+		return
+	}
+
+	res, err := h.fn(ctx, r.WithContext(ctx))
+	if err != nil {
+		h.writeError(ctx, w, err)
+
+		return
+	}
+
+	if h.hooks != nil && h.hooks.ResponsePrepared != nil {
+		ctx = h.hooks.ResponsePrepared(ctx)
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		h.writeError(ctx, w, twirp.InternalErrorWith(
+			fmt.Errorf("marshal response: %w", err)))
+
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(data)
+	if err != nil && h.hooks != nil && h.hooks.Error != nil {
+		ctx = h.hooks.Error(ctx, twirp.NewError(twirp.Unknown,
+			"failed to write response: "+err.Error()))
+	}
+
+	if h.hooks != nil && h.hooks.ResponseSent != nil {
+		h.hooks.ResponseSent(ctx)
+	}
+}
+
+func (h *extensionHandler) requestReceived(
+	ctx context.Context,
+) (context.Context, error) {
+	if h.hooks == nil || h.hooks.RequestReceived == nil {
+		return ctx, nil
+	}
+
+	return h.hooks.RequestReceived(ctx)
+}
+
+func (h *extensionHandler) requestRouted(
+	ctx context.Context,
+) (context.Context, error) {
+	if h.hooks == nil || h.hooks.RequestRouted == nil {
+		return ctx, nil
+	}
+
+	return h.hooks.RequestRouted(ctx)
+}
+
+func (h *extensionHandler) writeError(
+	ctx context.Context, w http.ResponseWriter, err error,
+) {
+	var twerr twirp.Error
+
+	if !errors.As(err, &twerr) {
+		twerr = twirp.InternalErrorWith(err)
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, twirp.ServerHTTPStatusFromErrorCode(twerr.Code()))
+
+	if h.hooks != nil && h.hooks.Error != nil {
+		ctx = h.hooks.Error(ctx, twerr)
+	}
+
+	_ = twirp.WriteError(w, twerr)
+
+	if h.hooks != nil && h.hooks.ResponseSent != nil {
+		h.hooks.ResponseSent(ctx)
+	}
+}
+
+// NewExtensionClient creates a client for calling the extension methods of a
+// service. The path prefix is the twirp path prefix of the service, f.ex.
+// repository.DocumentsPathPrefix. Header is added to all requests, and would
+// typically be used for the authorization header.
+func NewExtensionClient(
+	client *http.Client, baseURL string, pathPrefix string,
+	header http.Header,
+) *ExtensionClient {
+	return &ExtensionClient{
+		client: client,
+		base:   strings.TrimSuffix(baseURL, "/") + pathPrefix,
+		header: header,
+	}
+}
+
+// ExtensionClient calls extension methods using JSON requests.
+type ExtensionClient struct {
+	client *http.Client
+	base   string
+	header http.Header
+}
+
+// Call an extension method, the response will be unmarshalled into res.
+// Errors returned by the server are returned as twirp errors.
+func (c *ExtensionClient) Call(
+	ctx context.Context, method string, req any, res any,
+) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	hReq, err := http.NewRequestWithContext(ctx,
+		http.MethodPost, c.base+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	for k, v := range c.header {
+		hReq.Header[k] = v
+	}
+
+	hReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(hReq)
+	if err != nil {
+		return fmt.Errorf("perform request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Code string            `json:"code"`
+			Msg  string            `json:"msg"`
+			Meta map[string]string `json:"meta"`
+		}
+
+		err := dec.Decode(&e)
+		if err != nil || !twirp.IsValidErrorCode(twirp.ErrorCode(e.Code)) {
+			return twirp.NewErrorf(twirp.Internal,
+				"unexpected response status %q", resp.Status)
+		}
+
+		twerr := twirp.NewError(twirp.ErrorCode(e.Code), e.Msg)
+
+		for k, v := range e.Meta {
+			twerr = twerr.WithMeta(k, v)
+		}
+
+		return twerr
+	}
+
+	err = dec.Decode(res)
+	if err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return nil
+}
@@ -48,6 +48,8 @@ type ArchiverOptions struct {
 	Store              DocStore
 	TypeConfigurations *TypeConfigurations
 	TolerateGaps       bool
+	// ArchiveReadAudit enables archiving of the read audit log.
+	ArchiveReadAudit bool
 }
 
 // Archiver reads unarchived document versions, and statuses and writes a copy
@@ -64,6 +66,7 @@ type Archiver struct {
 	store              DocStore
 	types              *TypeConfigurations
 	tolerateGaps       bool
+	archiveReadAudit   bool
 
 	eventArchiverPos    prometheus.Gauge
 	eventsArchived      *prometheus.CounterVec
@@ -73,6 +76,7 @@ type Archiver struct {
 	purgesProcessed     *prometheus.CounterVec
 	purgeDeletes        *prometheus.CounterVec
 	batchesCreated      *prometheus.CounterVec
+	exportsProcessed    *prometheus.CounterVec
 	batchArchiverPos1k  prometheus.Gauge
 	batchArchiverPos10k prometheus.Gauge
 
@@ -94,14 +98,15 @@ func NewArchiver(opts ArchiverOptions) (*Archiver, error) {
 	}
 
 	a := Archiver{
-		logger:       opts.Logger,
-		s3:           opts.S3,
-		pool:         opts.DB,
-		store:        opts.Store,
-		types:        opts.TypeConfigurations,
-		bucket:       opts.Bucket,
-		assetBucket:  opts.AssetBucket,
-		tolerateGaps: opts.TolerateGaps,
+		logger:           opts.Logger,
+		s3:               opts.S3,
+		pool:             opts.DB,
+		store:            opts.Store,
+		types:            opts.TypeConfigurations,
+		bucket:           opts.Bucket,
+		assetBucket:      opts.AssetBucket,
+		tolerateGaps:     opts.TolerateGaps,
+		archiveReadAudit: opts.ArchiveReadAudit,
 	}
 
 	m := elephantine.NewMetricsHelper(opts.MetricsRegisterer)
@@ -146,6 +151,11 @@ func NewArchiver(opts ArchiverOptions) (*Archiver, error) {
 		Help: "Number of event batches created.",
 	}, []string{"size", "status"})
 
+	m.CounterVec(&a.exportsProcessed, prometheus.CounterOpts{
+		Name: "elephant_archiver_exports_total",
+		Help: "Number of document exports processed.",
+	}, []string{"status"})
+
 	m.Gauge(&a.batchArchiverPos1k, prometheus.GaugeOpts{
 		Name: "elephant_archiver_batch_1k_position",
 		Help: "Eventlog batch archiver position for 1k batches.",
@@ -208,6 +218,18 @@ func (a *Archiver) Run(ctx context.Context) error {
 		1*time.Hour,
 		a.runGenerationArchiver)
 
+	grp.GoWithRetries("run document exporter",
+		30, elephantine.StaticBackoff(10*time.Second),
+		1*time.Hour,
+		a.runExporter)
+
+	if a.archiveReadAudit {
+		grp.GoWithRetries("run read audit archiver",
+			30, elephantine.StaticBackoff(10*time.Second),
+			1*time.Hour,
+			a.runReadAuditArchiver)
+	}
+
 	return grp.Wait() //nolint: wrapcheck
 }
 
@@ -584,16 +606,22 @@ type DeleteManifest struct {
 }
 
 type AttachedObject struct {
-	Document      uuid.UUID         `json:"document"`
-	Name          string            `json:"name"`
-	Version       int64             `json:"version"`
-	ObjectVersion string            `json:"object_version"`
-	AttachedAt    int64             `json:"attached_at"`
-	CreatedBy     string            `json:"created_by"`
-	CreatedAt     time.Time         `json:"created_at"`
-	Filename      string            `json:"filename"`
-	Mimetype      string            `json:"mimetype"`
-	Props         map[string]string `json:"props"`
+	Document      uuid.UUID `json:"document"`
+	Name          string    `json:"name"`
+	Version       int64     `json:"version"`
+	ObjectVersion string    `json:"object_version"`
+	AttachedAt    int64     `json:"attached_at"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	Filename      string    `json:"filename"`
+	Mimetype      string    `json:"mimetype"`
+	// SniffedMimetype is the content type that was detected when the
+	// upload was verified.
+	SniffedMimetype string            `json:"sniffed_mimetype,omitempty"`
+	Props           map[string]string `json:"props"`
+	SHA256          string            `json:"sha256,omitempty"`
+	Size            int64             `json:"size,omitempty"`
+	Derived         map[string]string `json:"derived,omitempty"`
 }
 
 func (m *DeleteManifest) GetArchivedTime() time.Time {
@@ -679,6 +707,20 @@ func (a *Archiver) processDeletes(
 			if err != nil {
 				return fmt.Errorf("copy attachment: %w", err)
 			}
+
+			// Renditions are derived from the attached object, so
+			// they're not archived.
+			for _, r := range o.Meta.Renditions {
+				_, err := a.s3.DeleteObject(gCtx, &s3.DeleteObjectInput{
+					Bucket: aws.String(a.assetBucket),
+					Key: aws.String(
+						renditionObjectKey(
+							o.Document, o.Name, r.Name, r.Key)),
+				})
+				if err != nil {
+					return fmt.Errorf("delete rendition: %w", err)
+				}
+			}
 		}
 
 		return nil
@@ -702,21 +744,27 @@ func (a *Archiver) processDeletes(
 		manifest.ACL[i] = ACLEntry{
 			URI:         deleteOrder.Acl[i].URI,
 			Permissions: deleteOrder.Acl[i].Permissions,
+			NotBefore:   deleteOrder.Acl[i].NotBefore,
+			Expires:     deleteOrder.Acl[i].Expires,
 		}
 	}
 
 	for i, a := range deleteOrder.Attachments {
 		manifest.Attached[i] = AttachedObject{
-			Document:      a.Document,
-			Name:          a.Name,
-			Version:       a.Version,
-			ObjectVersion: a.ObjectVersion,
-			AttachedAt:    a.AttachedAt,
-			CreatedBy:     a.CreatedBy,
-			CreatedAt:     a.CreatedAt.Time,
-			Filename:      a.Meta.Filename,
-			Mimetype:      a.Meta.Mimetype,
-			Props:         a.Meta.Props,
+			Document:        a.Document,
+			Name:            a.Name,
+			Version:         a.Version,
+			ObjectVersion:   a.ObjectVersion,
+			AttachedAt:      a.AttachedAt,
+			CreatedBy:       a.CreatedBy,
+			CreatedAt:       a.CreatedAt.Time,
+			Filename:        a.Meta.Filename,
+			Mimetype:        a.Meta.Mimetype,
+			SniffedMimetype: a.Meta.SniffedMimetype,
+			Props:           a.Meta.Props,
+			SHA256:          a.Meta.SHA256,
+			Size:            a.Meta.Size,
+			Derived:         a.Meta.Derived,
 		}
 	}
 
@@ -879,9 +927,16 @@ func (a *Archiver) processRestores(
 		spec.ACL = manifest.ACL
 	}
 
+	archivedKeys, err := a.listArchivedAttachments(ctx, requestPrefix)
+	if err != nil {
+		return false, fmt.Errorf(
+			"list archived attached objects: %w", err)
+	}
+
 	var (
-		parentSig    string
-		lastAttached = map[string]int64{}
+		parentSig        string
+		lastAttached     = map[string]int64{}
+		archivedAttached []*ArchivedAttachedObject
 	)
 
 	// Iterate through the versions we want to restore.
@@ -897,6 +952,29 @@ func (a *Archiver) processRestores(
 
 		for _, name := range attached {
 			lastAttached[name] = version
+
+			manifestKey, _ := archivedAttachmentKeys(
+				requestPrefix, name, version)
+
+			if !archivedKeys[manifestKey] {
+				continue
+			}
+
+			obj, _, err := a.reader.ReadAttachedObject(
+				ctx, manifestKey, &sig)
+			if err != nil {
+				return false, fmt.Errorf(
+					"read archived attached object %q for version %d: %w",
+					name, version, err)
+			}
+
+			if obj.Name != name || obj.AttachedAt != version {
+				return false, fmt.Errorf(
+					"archived attached object %q for version %d doesn't match the document version",
+					name, version)
+			}
+
+			archivedAttached = append(archivedAttached, obj)
 		}
 
 		parentSig = sig
@@ -934,25 +1012,64 @@ func (a *Archiver) processRestores(
 		}
 	}
 
+	// Restore the history of the attachments that were archived, the
+	// current versions are set below.
+	archivedVersions, err := a.restoreArchivedAttachments(
+		ctx, q, requestPrefix, req.UUID, archivedAttached)
+	if err != nil {
+		return false, err
+	}
+
 	attachedNames := make([]string, len(manifest.Attached))
 
 	// Adding the attached object at the end of the restore, but that's fine
 	// as it will become visible at the same time of commit.
 	for i, o := range manifest.Attached {
+		attachedNames[i] = o.Name
+
+		// Objects without an archived history are restored as the
+		// first version of the attachment.
+		objVersion := int64(1)
+
+		if v, ok := archivedVersions[o.Name]; ok {
+			delete(archivedVersions, o.Name)
+
+			if v == o.Version {
+				err = q.SetCurrentAttachedObject(ctx,
+					postgres.SetCurrentAttachedObjectParams{
+						Document: o.Document,
+						Name:     o.Name,
+						Version:  v,
+						Deleted:  false,
+					})
+				if err != nil {
+					return false, fmt.Errorf(
+						"set current attachment: %w", err)
+				}
+
+				continue
+			}
+
+			// The current version wasn't archived, f.ex.
+			// because archiving was disabled for the type.
+			objVersion = o.Version
+		}
+
 		srcKey := fmt.Sprintf("%s/attached/%s", requestPrefix, o.Name)
 		key := fmt.Sprintf("objects/%s/%s", o.Name, o.Document)
 
-		res, err := a.s3.CopyObject(ctx, &s3.CopyObjectInput{
-			Bucket:     aws.String(a.assetBucket),
-			Key:        aws.String(key),
-			CopySource: aws.String(a.bucket + "/" + srcKey),
+		res, err := copyS3Object(ctx, a.s3, s3CopyInput{
+			SourceBucket: a.bucket,
+			SourceKey:    srcKey,
+			Bucket:       a.assetBucket,
+			Key:          key,
 		})
 		if err != nil {
 			return false, fmt.Errorf("restore attachment %q: %w",
 				o.Name, err)
 		}
 
-		if res.VersionId == nil {
+		if res.VersionID == "" {
 			return false, errors.New("attachment bucket is not versioned")
 		}
 
@@ -964,15 +1081,19 @@ func (a *Archiver) processRestores(
 		err = q.AddAttachedObject(ctx, postgres.AddAttachedObjectParams{
 			Document:      o.Document,
 			Name:          o.Name,
-			Version:       1,
-			ObjectVersion: *res.VersionId,
+			Version:       objVersion,
+			ObjectVersion: res.VersionID,
 			AttachedAt:    attachedAt,
 			CreatedBy:     o.CreatedBy,
 			CreatedAt:     pg.Time(o.CreatedAt),
 			Meta: postgres.AssetMetadata{
-				Filename: o.Filename,
-				Mimetype: o.Mimetype,
-				Props:    o.Props,
+				Filename:        o.Filename,
+				Mimetype:        o.Mimetype,
+				SniffedMimetype: o.SniffedMimetype,
+				Props:           o.Props,
+				SHA256:          o.SHA256,
+				Size:            o.Size,
+				Derived:         o.Derived,
 			},
 		})
 		if err != nil {
@@ -983,34 +1104,54 @@ func (a *Archiver) processRestores(
 			postgres.SetCurrentAttachedObjectParams{
 				Document: o.Document,
 				Name:     o.Name,
-				Version:  1,
+				Version:  objVersion,
 				Deleted:  false,
 			})
 		if err != nil {
 			return false, fmt.Errorf("set current attachment: %w", err)
 		}
+	}
 
-		attachedNames[i] = o.Name
+	// Attachments that have an archived history but weren't attached when
+	// the document was deleted had been detached.
+	for name, version := range archivedVersions {
+		_, err := a.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(a.assetBucket),
+			Key:    aws.String(fmt.Sprintf("objects/%s/%s", name, req.UUID)),
+		})
+		if err != nil {
+			return false, fmt.Errorf(
+				"remove detached attachment %q: %w", name, err)
+		}
+
+		err = q.SetCurrentAttachedObject(ctx,
+			postgres.SetCurrentAttachedObjectParams{
+				Document: req.UUID,
+				Name:     name,
+				Version:  version,
+				Deleted:  true,
+			})
+		if err != nil {
+			return false, fmt.Errorf(
+				"set detached attachment %q: %w", name, err)
+		}
 	}
 
-	var (
-		acls      = make([]postgres.ACLUpdateParams, len(spec.ACL))
-		eventACLs = make([]postgres.ACLEntry, len(spec.ACL))
-	)
+	acls := make([]postgres.ACLUpdateParams, len(spec.ACL))
 
 	for i, acl := range spec.ACL {
 		acls[i] = postgres.ACLUpdateParams{
-			UUID:        req.UUID,
-			URI:         acl.URI,
-			Permissions: acl.Permissions,
-		}
-
-		eventACLs[i] = postgres.ACLEntry{
-			URI:         acl.URI,
-			Permissions: acl.Permissions,
+			UUID:          req.UUID,
+			URI:           acl.URI,
+			Permissions:   acl.Permissions,
+			NotBefore:     pg.PTime(acl.NotBefore),
+			Expires:       pg.PTime(acl.Expires),
+			SetTimeBounds: true,
 		}
 	}
 
+	eventACLs := aclEventEntries(spec.ACL, time.Now())
+
 	docInfo, err := q.GetDocumentRow(ctx, req.UUID)
 	if err != nil {
 		return false, fmt.Errorf(
@@ -1191,6 +1332,11 @@ func (a *Archiver) restoreDocumentVersion(
 		return nil, "", fmt.Errorf("update document row: %w", err)
 	}
 
+	err = updateDocumentLinks(ctx, q, dv.UUID, doc)
+	if err != nil {
+		return nil, "", fmt.Errorf("update document links: %w", err)
+	}
+
 	err = addEventToOutbox(ctx, tx, postgres.OutboxEvent{
 		Event:           string(TypeDocumentVersion),
 		UUID:            req.UUID,
@@ -1419,12 +1565,11 @@ func (a *Archiver) moveDeletedObject(
 		a.deleteMoves.WithLabelValues(status).Inc()
 	}()
 
-	_, err := a.s3.CopyObject(ctx, &s3.CopyObjectInput{
-		Bucket: aws.String(a.bucket),
-		Key:    aws.String(dstKey),
-		CopySource: aws.String(
-			sourceBucket + "/" + key,
-		),
+	_, err := copyS3Object(ctx, a.s3, s3CopyInput{
+		SourceBucket: sourceBucket,
+		SourceKey:    key,
+		Bucket:       a.bucket,
+		Key:          dstKey,
 	})
 	if err != nil {
 		return fmt.Errorf(
@@ -1535,6 +1680,24 @@ func (a *Archiver) archiveDocumentVersion(
 		ref.Remove(cleanupCtx)
 	}()
 
+	attachedRefs, err := a.archiveAttachedObjects(
+		ctx, cleanupCtx, tx, &dv, ref.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("archive attached objects: %w", err)
+	}
+
+	// Make sure that the archived attached objects are cleaned up together
+	// with the document version.
+	removeVersion := ref.Remove
+
+	ref.Remove = func(ctx context.Context) {
+		removeVersion(ctx)
+
+		for _, r := range attachedRefs {
+			r.Remove(ctx)
+		}
+	}
+
 	err = q.SetDocumentVersionAsArchived(ctx,
 		postgres.SetDocumentVersionAsArchivedParams{
 			UUID:      dv.UUID,
@@ -1675,6 +1838,38 @@ func (a *Archiver) archiveDocumentStatus(
 	return ref, nil
 }
 
+const signingKeyValidity = 180 * 24 * time.Hour
+
+// loadSigningKeys loads the archive signing keys from the database.
+func loadSigningKeys(
+	ctx context.Context, q *postgres.Queries,
+) ([]SigningKey, error) {
+	rows, err := q.GetSigningKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make([]SigningKey, 0, len(rows))
+
+	for i := range rows {
+		var spec SigningKey
+
+		err := json.Unmarshal(rows[i].Spec, &spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal key %q: %w",
+				rows[i].Kid, err)
+		}
+
+		if spec.NotAfter.IsZero() {
+			spec.NotAfter = spec.NotBefore.Add(signingKeyValidity)
+		}
+
+		keys = append(keys, spec)
+	}
+
+	return keys, nil
+}
+
 func (a *Archiver) ensureSigningKeys(ctx context.Context) (outErr error) {
 	tx, err := a.pool.Begin(ctx)
 	if err != nil {
@@ -1694,31 +1889,17 @@ func (a *Archiver) ensureSigningKeys(ctx context.Context) (outErr error) {
 
 	const (
 		days              = 24 * time.Hour
-		validFor          = 180 * days
+		validFor          = signingKeyValidity
 		headsUpPeriod     = 2 * days
 		generateNewMargin = 7 * days
 	)
 
-	keys, err := q.GetSigningKeys(ctx)
+	keys, err := loadSigningKeys(ctx, q)
 	if err != nil {
-		return fmt.Errorf("failed to fetch signing keys: %w", err)
+		return err
 	}
 
-	for i := range keys {
-		var spec SigningKey
-
-		err := json.Unmarshal(keys[i].Spec, &spec)
-		if err != nil {
-			return fmt.Errorf("failed to unmarshal key %q: %w",
-				keys[i].Kid, err)
-		}
-
-		if spec.NotAfter.IsZero() {
-			spec.NotAfter = spec.NotBefore.Add(validFor)
-		}
-
-		set.Keys = append(set.Keys, spec)
-	}
+	set.Keys = keys
 
 	var keyID int64
 
@@ -0,0 +1,342 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/uuid"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/pg"
+)
+
+// ArchivedAttachedObject is the manifest of an archived version of an attached
+// object. The parent signature is the signature of the archived document
+// version that the object was attached in, which links the object into the
+// signature chain of the document.
+type ArchivedAttachedObject struct {
+	AttachedObject
+
+	// ContentSHA256 is the hex encoded SHA-256 checksum of the archived
+	// object contents.
+	ContentSHA256   string    `json:"content_sha256"`
+	ParentSignature string    `json:"parent_signature"`
+	Archived        time.Time `json:"archived"`
+}
+
+func (ao *ArchivedAttachedObject) GetArchivedTime() time.Time {
+	return ao.Archived
+}
+
+func (ao *ArchivedAttachedObject) GetParentSignature() string {
+	return ao.ParentSignature
+}
+
+// archivedAttachmentKeys returns the keys of the manifest and contents of an
+// archived attached object. The prefix is "documents/{uuid}" for archived
+// documents, and "deleted/{uuid}/{delete record}" for deleted documents.
+func archivedAttachmentKeys(
+	prefix string, name string, docVersion int64,
+) (string, string) {
+	base := fmt.Sprintf("%s/attachments/%s/%019d", prefix, name, docVersion)
+
+	return base + ".json", base + ".object"
+}
+
+// archiveAttachedObjects copies the objects that were attached in a document
+// version to the archive bucket, if attachment archiving has been enabled for
+// the document type. Returns references to the stored archive objects so that
+// they can be removed if archiving fails.
+func (a *Archiver) archiveAttachedObjects(
+	ctx context.Context,
+	cleanupCtx context.Context,
+	tx postgres.DBTX,
+	dv *ArchivedDocumentVersion,
+	versionSignature string,
+) (_ []*archiveObjectRef, outErr error) {
+	if len(dv.Attached) == 0 {
+		return nil, nil
+	}
+
+	conf, ok, err := a.types.GetConfiguration(ctx, dv.Type)
+	if err != nil {
+		return nil, fmt.Errorf("get type configuration: %w", err)
+	}
+
+	if !ok || !conf.ArchiveAttachments {
+		return nil, nil
+	}
+
+	q := postgres.New(tx)
+
+	objects, err := q.GetAttachedObjectsAttachedAt(ctx,
+		postgres.GetAttachedObjectsAttachedAtParams{
+			Document:   dv.UUID,
+			AttachedAt: dv.Version,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("read attached objects: %w", err)
+	}
+
+	var refs []*archiveObjectRef
+
+	// We try to clean up the S3 objects if the operation fails.
+	defer func() {
+		if outErr == nil {
+			return
+		}
+
+		for _, ref := range refs {
+			ref.Remove(cleanupCtx)
+		}
+	}()
+
+	prefix := fmt.Sprintf("documents/%s", dv.UUID)
+
+	for _, o := range objects {
+		if !slices.Contains(dv.Attached, o.Name) {
+			continue
+		}
+
+		manifestKey, contentKey := archivedAttachmentKeys(
+			prefix, o.Name, dv.Version)
+
+		content, err := a.copyArchiveContent(ctx,
+			a.assetBucket,
+			fmt.Sprintf("objects/%s/%s", o.Name, o.Document),
+			o.ObjectVersion,
+			a.bucket, contentKey)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"copy attached object %q: %w", o.Name, err)
+		}
+
+		refs = append(refs, content.Ref)
+
+		manifest := ArchivedAttachedObject{
+			AttachedObject:  attachedObjectFromRow(o),
+			ContentSHA256:   content.SHA256,
+			ParentSignature: versionSignature,
+			Archived:        time.Now(),
+		}
+
+		ref, err := a.storeArchiveObject(ctx, manifestKey, &manifest)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"store attached object manifest %q: %w",
+				o.Name, err)
+		}
+
+		refs = append(refs, ref)
+	}
+
+	return refs, nil
+}
+
+// copiedObject is an object that has been copied by copyArchiveContent.
+type copiedObject struct {
+	Ref       *archiveObjectRef
+	VersionID string
+	// SHA256 is the hex encoded SHA-256 checksum of the object.
+	SHA256 string
+}
+
+// copyArchiveContent copies an object between buckets.
+func (a *Archiver) copyArchiveContent(
+	ctx context.Context,
+	srcBucket string, srcKey string, srcVersion string,
+	dstBucket string, dstKey string,
+) (*copiedObject, error) {
+	res, err := copyS3Object(ctx, a.s3, s3CopyInput{
+		SourceBucket:      srcBucket,
+		SourceKey:         srcKey,
+		SourceVersion:     srcVersion,
+		Bucket:            dstBucket,
+		Key:               dstKey,
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("copy object: %w", err)
+	}
+
+	ref := archiveObjectRef{
+		Remove: func(ctx context.Context) {
+			_, cErr := a.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
+				Bucket:    aws.String(dstBucket),
+				Key:       aws.String(dstKey),
+				VersionId: aws.String(res.VersionID),
+			})
+			if cErr != nil {
+				a.logger.ErrorContext(ctx,
+					"failed to clean up copied object after failure",
+					elephantine.LogKeyError, cErr,
+					elephantine.LogKeyBucket, dstBucket,
+					elephantine.LogKeyObjectKey, dstKey)
+			}
+		},
+	}
+
+	copied := copiedObject{
+		Ref:       &ref,
+		VersionID: res.VersionID,
+	}
+
+	// Fall back to reading the object if the object store didn't give us
+	// a full object checksum, which is the case for objects that were
+	// copied in parts.
+	sum, err := base64.StdEncoding.DecodeString(res.ChecksumSHA256)
+	if err == nil && len(sum) == sha256.Size {
+		copied.SHA256 = hex.EncodeToString(sum)
+
+		return &copied, nil
+	}
+
+	copied.SHA256, err = a.objectChecksum(ctx,
+		dstBucket, dstKey, copied.VersionID)
+	if err != nil {
+		ref.Remove(ctx)
+
+		return nil, err
+	}
+
+	return &copied, nil
+}
+
+// objectChecksum reads an object and calculates its hex encoded SHA-256
+// checksum.
+func (a *Archiver) objectChecksum(
+	ctx context.Context, bucket string, key string, version string,
+) (string, error) {
+	input := s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+
+	if version != "" {
+		input.VersionId = aws.String(version)
+	}
+
+	res, err := a.s3.GetObject(ctx, &input)
+	if err != nil {
+		return "", fmt.Errorf("read object: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	hash := sha256.New()
+
+	_, err = io.Copy(hash, res.Body)
+	if err != nil {
+		return "", fmt.Errorf("read object data: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// listArchivedAttachments lists the keys of the archived attached object
+// manifests under a prefix.
+func (a *Archiver) listArchivedAttachments(
+	ctx context.Context, prefix string,
+) (map[string]bool, error) {
+	keys := make(map[string]bool)
+
+	paginator := s3.NewListObjectsV2Paginator(a.s3, &s3.ListObjectsV2Input{
+		Bucket: aws.String(a.bucket),
+		Prefix: aws.String(prefix + "/attachments/"),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list objects: %w", err)
+		}
+
+		for _, o := range page.Contents {
+			key := aws.ToString(o.Key)
+
+			if strings.HasSuffix(key, ".json") {
+				keys[key] = true
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+// restoreArchivedAttachments copies archived attached object versions back to
+// the asset bucket in the order that they were attached, and records them as
+// attachment versions. Returns the last restored version per attachment name.
+func (a *Archiver) restoreArchivedAttachments(
+	ctx context.Context, q *postgres.Queries,
+	requestPrefix string, document uuid.UUID,
+	archived []*ArchivedAttachedObject,
+) (map[string]int64, error) {
+	latest := make(map[string]int64)
+
+	for _, o := range archived {
+		_, contentKey := archivedAttachmentKeys(
+			requestPrefix, o.Name, o.AttachedAt)
+
+		key := fmt.Sprintf("objects/%s/%s", o.Name, document)
+
+		content, err := a.copyArchiveContent(ctx,
+			a.bucket, contentKey, "",
+			a.assetBucket, key)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"restore attached object %q version %d: %w",
+				o.Name, o.Version, err)
+		}
+
+		if content.SHA256 != o.ContentSHA256 {
+			content.Ref.Remove(ctx)
+
+			return nil, fmt.Errorf(
+				"attached object %q version %d doesn't match the archived checksum",
+				o.Name, o.Version)
+		}
+
+		if content.VersionID == "" {
+			return nil, errors.New("attachment bucket is not versioned")
+		}
+
+		err = q.AddAttachedObject(ctx, postgres.AddAttachedObjectParams{
+			Document:      document,
+			Name:          o.Name,
+			Version:       o.Version,
+			ObjectVersion: content.VersionID,
+			AttachedAt:    o.AttachedAt,
+			CreatedBy:     o.CreatedBy,
+			CreatedAt:     pg.Time(o.CreatedAt),
+			Meta: postgres.AssetMetadata{
+				Filename:        o.Filename,
+				Mimetype:        o.Mimetype,
+				SniffedMimetype: o.SniffedMimetype,
+				Props:           o.Props,
+				SHA256:          o.SHA256,
+				Size:            o.Size,
+				Derived:         o.Derived,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf(
+				"store attachment data for %q version %d: %w",
+				o.Name, o.Version, err)
+		}
+
+		latest[o.Name] = o.Version
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,222 @@
+package repository_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/test"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationAttachmentArchiving(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelError))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		RunArchiver: true,
+	})
+
+	claims := itest.Claims(t, "archivist",
+		"doc_read doc_write doc_delete doc_restore asset_upload")
+
+	client := tc.DocumentsClient(t, claims)
+	ext := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, claims)
+	schemas := tc.ExtensionClient(t, rpc.SchemasPathPrefix,
+		itest.StandardClaims(t, "schema_admin"))
+
+	err := schemas.Call(ctx, "SetTypeAttachmentArchiving",
+		repository.SetTypeAttachmentArchivingRequest{
+			Type:    "core/article",
+			Enabled: true,
+		}, &repository.SetTypeAttachmentArchivingResponse{})
+	test.Must(t, err, "enable attachment archiving for articles")
+
+	// The type configuration is propagated asynchronously, wait for the
+	// archiver to see it before we start attaching objects.
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		conf, _, err := tc.Types.GetConfiguration(ctx, "core/article")
+		test.Must(t, err, "get type configuration")
+
+		if conf.ArchiveAttachments {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the type configuration")
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	docUUID := uuid.NewString()
+	doc := baseDocument(docUUID, "article://test/"+docUUID)
+
+	upload := func(data string) string {
+		t.Helper()
+
+		up, err := client.CreateUpload(ctx, &rpc.CreateUploadRequest{
+			Name:        "my.txt",
+			ContentType: "text/plain",
+		})
+		test.Must(t, err, "create upload")
+
+		req, err := http.NewRequestWithContext(ctx,
+			http.MethodPut, up.Url, strings.NewReader(data))
+		test.Must(t, err, "create upload request")
+
+		req.ContentLength = int64(len(data))
+
+		res, err := http.DefaultClient.Do(req)
+		test.Must(t, err, "make upload request")
+
+		_ = res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("error response from upload recipient: %s",
+				res.Status)
+		}
+
+		return up.Id
+	}
+
+	download := func(url string) string {
+		t.Helper()
+
+		res, err := http.Get(url)
+		test.Must(t, err, "download the attachment")
+
+		defer res.Body.Close()
+
+		data, err := io.ReadAll(res.Body)
+		test.Must(t, err, "read the attachment")
+
+		return string(data)
+	}
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: doc,
+		AttachObjects: map[string]string{
+			"plaintext": upload("first version"),
+			"notes":     upload("some notes"),
+		},
+	})
+	test.Must(t, err, "create the document with attachments")
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: doc,
+		AttachObjects: map[string]string{
+			"plaintext": upload("second version"),
+		},
+		DetachObjects: []string{"notes"},
+	})
+	test.Must(t, err, "replace and detach attachments")
+
+	_, err = client.Delete(ctx, &rpc.DeleteDocumentRequest{
+		Uuid: docUUID,
+	})
+	test.Must(t, err, "delete the document")
+
+	deletes, err := client.ListDeleted(ctx, &rpc.ListDeletedRequest{
+		Uuid: docUUID,
+	})
+	test.Must(t, err, "list deletes")
+
+	test.Equal(t, 1, len(deletes.Deletes), "expect one delete record")
+
+	deadline = time.Now().Add(5 * time.Second)
+
+	for {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the delete to finish")
+		}
+
+		_, err = client.Restore(ctx, &rpc.RestoreRequest{
+			Uuid:           docUUID,
+			DeleteRecordId: deletes.Deletes[0].Id,
+		})
+		if elephantine.IsTwirpErrorCode(err, twirp.FailedPrecondition) {
+			time.Sleep(100 * time.Millisecond)
+
+			continue
+		}
+
+		test.Must(t, err, "start restore")
+
+		break
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+
+	for {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the restore to finish")
+		}
+
+		_, err = client.Get(ctx, &rpc.GetDocumentRequest{
+			Uuid: docUUID,
+		})
+		if elephantine.IsTwirpErrorCode(err, twirp.FailedPrecondition) {
+			time.Sleep(100 * time.Millisecond)
+
+			continue
+		}
+
+		test.Must(t, err, "read the restored document")
+
+		break
+	}
+
+	var history repository.GetAttachmentHistoryResponse
+
+	err = ext.Call(ctx, "GetAttachmentHistory",
+		repository.GetAttachmentHistoryRequest{
+			UUID:           docUUID,
+			AttachmentName: "plaintext",
+			DownloadLinks:  true,
+		}, &history)
+	test.Must(t, err, "get attachment history")
+
+	test.Equal(t, 2, len(history.Versions), "restore both versions")
+	test.Equal(t, int64(2), history.CurrentVersion,
+		"restore the current version")
+	test.Equal(t, "second version",
+		download(history.Versions[0].DownloadLink),
+		"download the restored current version")
+	test.Equal(t, "first version",
+		download(history.Versions[1].DownloadLink),
+		"download the restored historical version")
+
+	var notes repository.GetAttachmentHistoryResponse
+
+	err = ext.Call(ctx, "GetAttachmentHistory",
+		repository.GetAttachmentHistoryRequest{
+			UUID:           docUUID,
+			AttachmentName: "notes",
+			DownloadLinks:  true,
+		}, &notes)
+	test.Must(t, err, "get detached attachment history")
+
+	test.Equal(t, 1, len(notes.Versions), "restore the detached object")
+	test.Equal(t, int64(0), notes.CurrentVersion,
+		"keep the object detached")
+	test.Equal(t, "some notes",
+		download(notes.Versions[0].DownloadLink),
+		"download the restored detached object")
+}
@@ -0,0 +1,670 @@
+package repository
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/pg"
+)
+
+const (
+	exportBatchSize    = 100
+	exportPollInterval = 1 * time.Second
+	exportPrefix       = "exports"
+
+	// exportArchiveWait is how long an export waits for the archiver to
+	// catch up with a document before it fails.
+	exportArchiveWait = 5 * time.Minute
+
+	// ExportManifestName is the name of the manifest entry in an export
+	// bundle.
+	ExportManifestName = "manifest.json"
+	// ExportManifestSignatureName is the name of the entry that holds the
+	// archive signature of the manifest.
+	ExportManifestSignatureName = "manifest.sig"
+	// ExportSignaturesName is the name of the entry that lists the archive
+	// signatures of the archive objects in the bundle.
+	ExportSignaturesName = "signatures.txt"
+)
+
+// ExportManifest describes the contents of an export bundle. Files has the
+// hex encoded SHA-256 checksums of all entries in the bundle except the
+// manifest and its signature, which makes the signed manifest cover the
+// whole bundle.
+type ExportManifest struct {
+	ID        int64             `json:"id"`
+	Query     ExportQuery       `json:"query"`
+	CreatedBy string            `json:"created_by"`
+	Exported  time.Time         `json:"exported"`
+	Documents []uuid.UUID       `json:"documents"`
+	Files     map[string]string `json:"files"`
+}
+
+// ExportedDocument is the snapshot of a document that is stored as
+// "documents/{uuid}/document.json" in an export bundle. The archived versions
+// and statuses of the document are stored as they are in the archive, and
+// the attached object versions are stored as
+// "documents/{uuid}/attachments/{name}/{version}.object".
+type ExportedDocument struct {
+	UUID           uuid.UUID        `json:"uuid"`
+	URI            string           `json:"uri"`
+	Type           string           `json:"type"`
+	Language       string           `json:"language"`
+	CurrentVersion int64            `json:"current_version"`
+	Created        time.Time        `json:"created"`
+	CreatorURI     string           `json:"creator_uri"`
+	Updated        time.Time        `json:"updated"`
+	UpdaterURI     string           `json:"updater_uri"`
+	MainDocument   *uuid.UUID       `json:"main_document,omitempty"`
+	Heads          map[string]int64 `json:"heads"`
+	ACL            []ACLEntry       `json:"acl"`
+	Attachments    []AttachedObject `json:"attachments,omitempty"`
+}
+
+// errNotArchived is returned by exportDocument when the current version or a
+// current status of the document hasn't been archived yet.
+var errNotArchived = errors.New("not archived yet")
+
+// ExportObjectKey returns the archive bucket key of an export bundle.
+func ExportObjectKey(id int64) string {
+	return fmt.Sprintf("%s/%019d.zip", exportPrefix, id)
+}
+
+// StartExport implements DocStore.
+func (s *PGDocStore) StartExport(
+	ctx context.Context, query ExportQuery, createdBy string,
+) (*DocumentExport, error) {
+	var timeRange pgtype.Range[pgtype.Timestamptz]
+
+	if query.From != nil && query.To != nil {
+		timeRange = TimespanToRange(Timespan{
+			From: *query.From,
+			To:   *query.To,
+		})
+	}
+
+	id, err := s.reader.CreateDocumentExport(ctx,
+		postgres.CreateDocumentExportParams{
+			Uuids:     query.UUIDs,
+			Type:      pg.TextOrNull(query.Type),
+			TimeRange: timeRange,
+			Labels:    query.Labels,
+			Created:   pg.Time(time.Now()),
+			CreatedBy: createdBy,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("store export: %w", err)
+	}
+
+	return s.GetExport(ctx, id)
+}
+
+// GetExport implements DocStore.
+func (s *PGDocStore) GetExport(
+	ctx context.Context, id int64,
+) (*DocumentExport, error) {
+	row, err := s.reader.GetDocumentExport(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, DocStoreErrorf(ErrCodeNotFound,
+			"export %d not found", id)
+	} else if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	exp := documentExportFromRow(row)
+
+	return &exp, nil
+}
+
+func documentExportFromRow(row postgres.DocumentExport) DocumentExport {
+	exp := DocumentExport{
+		ID: row.ID,
+		Query: ExportQuery{
+			UUIDs:  row.Uuids,
+			Type:   row.Type.String,
+			Labels: row.Labels,
+		},
+		Status:    ExportStatus(row.Status),
+		Created:   row.Created.Time,
+		CreatedBy: row.CreatedBy,
+		Total:     row.Total,
+		Processed: row.Processed,
+		ObjectKey: row.ObjectKey.String,
+		Size:      row.Size.Int64,
+		Signature: row.Signature.String,
+		Error:     row.Error.String,
+	}
+
+	if row.TimeRange.Valid {
+		from := row.TimeRange.Lower.Time
+		to := row.TimeRange.Upper.Time
+
+		exp.Query.From = &from
+		exp.Query.To = &to
+	}
+
+	if row.Started.Valid {
+		t := row.Started.Time
+		exp.Started = &t
+	}
+
+	if row.Finished.Valid {
+		t := row.Finished.Time
+		exp.Finished = &t
+	}
+
+	return exp
+}
+
+func (a *Archiver) runExporter(ctx context.Context) error {
+	lock, err := pg.NewJobLock(a.pool, a.logger, "document-exporter",
+		pg.JobLockOptions{})
+	if err != nil {
+		return fmt.Errorf("acquire job lock: %w", err)
+	}
+
+	return lock.RunWithContext(ctx, a.processExports)
+}
+
+// processExports runs the unfinished exports in the order they were created.
+// Exports that were interrupted are restarted from the beginning, as we hold
+// the job lock nobody else can be working on them.
+func (a *Archiver) processExports(ctx context.Context) error {
+	q := postgres.New(a.pool)
+
+	for {
+		job, err := q.GetNextDocumentExport(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			select {
+			case <-time.After(exportPollInterval):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		} else if err != nil {
+			return fmt.Errorf("get next export: %w", err)
+		}
+
+		result, err := a.runExport(ctx, q, job)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		finish := postgres.FinishDocumentExportParams{
+			ID:       job.ID,
+			Status:   string(ExportDone),
+			Finished: pg.Time(time.Now()),
+		}
+
+		if err != nil {
+			a.exportsProcessed.WithLabelValues("error").Inc()
+
+			a.logger.ErrorContext(ctx, "document export failed",
+				elephantine.LogKeyError, err,
+				"export_id", job.ID)
+
+			finish.Status = string(ExportFailed)
+			finish.Error = pg.Text(err.Error())
+		} else {
+			a.exportsProcessed.WithLabelValues("ok").Inc()
+
+			finish.ObjectKey = pg.Text(result.Key)
+			finish.Size = pgtype.Int8{Int64: result.Size, Valid: true}
+			finish.Signature = pg.Text(result.Signature)
+		}
+
+		err = q.FinishDocumentExport(ctx, finish)
+		if err != nil {
+			return fmt.Errorf("finish export %d: %w", job.ID, err)
+		}
+	}
+}
+
+type exportResult struct {
+	Key       string
+	Size      int64
+	Signature string
+}
+
+func (a *Archiver) runExport(
+	ctx context.Context, q *postgres.Queries, job postgres.DocumentExport,
+) (*exportResult, error) {
+	total, err := q.CountExportDocuments(ctx,
+		postgres.CountExportDocumentsParams{
+			Uuids:     job.Uuids,
+			Type:      job.Type,
+			TimeRange: job.TimeRange,
+			Labels:    job.Labels,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("count documents: %w", err)
+	}
+
+	err = q.StartDocumentExport(ctx, postgres.StartDocumentExportParams{
+		ID:      job.ID,
+		Started: pg.Time(time.Now()),
+		Total:   total,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start export: %w", err)
+	}
+
+	// Bundles can contain large attachments, so we spool them to disk
+	// instead of building them in memory.
+	file, err := os.CreateTemp("", "elephant-export-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary file: %w", err)
+	}
+
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	fileHash := sha256.New()
+
+	bundle := exportBundle{
+		zw:    zip.NewWriter(io.MultiWriter(file, fileHash)),
+		files: make(map[string]string),
+	}
+
+	manifest := ExportManifest{
+		ID:        job.ID,
+		Query:     documentExportFromRow(job).Query,
+		CreatedBy: job.CreatedBy,
+		Documents: []uuid.UUID{},
+	}
+
+	var (
+		after     uuid.UUID
+		processed int64
+	)
+
+	for {
+		docs, err := q.GetExportDocuments(ctx,
+			postgres.GetExportDocumentsParams{
+				After:     after,
+				Uuids:     job.Uuids,
+				Type:      job.Type,
+				TimeRange: job.TimeRange,
+				Labels:    job.Labels,
+				RowLimit:  exportBatchSize,
+			})
+		if err != nil {
+			return nil, fmt.Errorf("get documents: %w", err)
+		}
+
+		if len(docs) == 0 {
+			break
+		}
+
+		for _, docUUID := range docs {
+			ok, err := a.exportArchivedDocument(
+				ctx, q, &bundle, docUUID)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"export document %s: %w", docUUID, err)
+			}
+
+			if ok {
+				manifest.Documents = append(
+					manifest.Documents, docUUID)
+			}
+		}
+
+		processed += int64(len(docs))
+		after = docs[len(docs)-1]
+
+		err = q.UpdateDocumentExportProgress(ctx,
+			postgres.UpdateDocumentExportProgressParams{
+				ID:        job.ID,
+				Processed: processed,
+			})
+		if err != nil {
+			return nil, fmt.Errorf("update progress: %w", err)
+		}
+	}
+
+	err = bundle.add(ExportSignaturesName,
+		bytes.NewReader(bundle.sigs.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	manifest.Exported = time.Now()
+	manifest.Files = bundle.files
+
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	manifestSig, err := a.signExport(manifest.Exported,
+		sha256.Sum256(manifestData))
+	if err != nil {
+		return nil, err
+	}
+
+	err = bundle.writeEntry(ExportManifestName, manifestData)
+	if err != nil {
+		return nil, err
+	}
+
+	err = bundle.writeEntry(ExportManifestSignatureName,
+		[]byte(manifestSig.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	err = bundle.zw.Close()
+	if err != nil {
+		return nil, fmt.Errorf("close zip writer: %w", err)
+	}
+
+	var checksum [sha256.Size]byte
+
+	copy(checksum[:], fileHash.Sum(nil))
+
+	signature, err := a.signExport(time.Now(), checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("get bundle size: %w", err)
+	}
+
+	key := ExportObjectKey(job.ID)
+
+	// Bundles can be larger than what a single PutObject request
+	// accepts, so they're uploaded in parts.
+	err = uploadS3Object(ctx, a.s3, s3UploadInput{
+		Bucket:      a.bucket,
+		Key:         key,
+		ContentType: "application/zip",
+		Metadata: map[string]string{
+			"elephant-signature": signature.String(),
+		},
+		Body: file,
+		Size: size,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("upload bundle: %w", err)
+	}
+
+	return &exportResult{
+		Key:       key,
+		Size:      size,
+		Signature: signature.String(),
+	}, nil
+}
+
+func (a *Archiver) signExport(
+	t time.Time, checksum [sha256.Size]byte,
+) (*ArchiveSignature, error) {
+	signingKey := a.signingKeys.CurrentKey(t)
+	if signingKey == nil {
+		return nil, errors.New("no signing keys have been configured")
+	}
+
+	signature, err := NewArchiveSignature(signingKey, checksum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign export data: %w", err)
+	}
+
+	return signature, nil
+}
+
+// exportArchivedDocument adds a document to the export bundle once its current
+// version and statuses have been archived. Fails if the archiver hasn't caught
+// up with the document within exportArchiveWait, as the bundle would be
+// incomplete otherwise.
+func (a *Archiver) exportArchivedDocument(
+	ctx context.Context, q *postgres.Queries,
+	bundle *exportBundle, docUUID uuid.UUID,
+) (bool, error) {
+	deadline := time.Now().Add(exportArchiveWait)
+
+	for {
+		ok, err := a.exportDocument(ctx, q, bundle, docUUID)
+		if !errors.Is(err, errNotArchived) || time.Now().After(deadline) {
+			return ok, err
+		}
+
+		select {
+		case <-time.After(exportPollInterval):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+// exportDocument adds a document to the export bundle. The versions and
+// statuses are exported from the archive, and errNotArchived is returned
+// without adding anything to the bundle if the current version or a current
+// status hasn't been archived yet. Returns false if the document has been
+// deleted.
+func (a *Archiver) exportDocument(
+	ctx context.Context, q *postgres.Queries,
+	bundle *exportBundle, docUUID uuid.UUID,
+) (bool, error) {
+	info, err := q.GetDocumentRow(ctx, docUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("read document: %w", err)
+	}
+
+	if info.SystemState.Valid {
+		return false, nil
+	}
+
+	doc := ExportedDocument{
+		UUID:           info.UUID,
+		URI:            info.URI,
+		Type:           info.Type,
+		Language:       info.Language.String,
+		CurrentVersion: info.CurrentVersion,
+		Created:        info.Created.Time,
+		CreatorURI:     info.CreatorUri,
+		Updated:        info.Updated.Time,
+		UpdaterURI:     info.UpdaterUri,
+		Heads:          make(map[string]int64),
+		ACL:            []ACLEntry{},
+	}
+
+	if info.MainDoc.Valid {
+		mainDoc := uuid.UUID(info.MainDoc.Bytes)
+		doc.MainDocument = &mainDoc
+	}
+
+	acl, err := q.GetDocumentACL(ctx, docUUID)
+	if err != nil {
+		return false, fmt.Errorf("read ACL: %w", err)
+	}
+
+	for _, entry := range acl {
+		doc.ACL = append(doc.ACL, aclEntryFromRow(entry))
+	}
+
+	heads, err := q.GetDocumentHeads(ctx, docUUID)
+	if err != nil {
+		return false, fmt.Errorf("read status heads: %w", err)
+	}
+
+	for _, h := range heads {
+		doc.Heads[h.Name] = h.CurrentID
+	}
+
+	versions, err := q.GetArchivedVersionSignatures(ctx, docUUID)
+	if err != nil {
+		return false, fmt.Errorf("list archived versions: %w", err)
+	}
+
+	statuses, err := q.GetArchivedStatusSignatures(ctx, docUUID)
+	if err != nil {
+		return false, fmt.Errorf("list archived statuses: %w", err)
+	}
+
+	versionArchived := slices.ContainsFunc(versions,
+		func(v postgres.GetArchivedVersionSignaturesRow) bool {
+			return v.Version == info.CurrentVersion
+		})
+	if !versionArchived {
+		return false, fmt.Errorf("version %d: %w",
+			info.CurrentVersion, errNotArchived)
+	}
+
+	for name, id := range doc.Heads {
+		statusArchived := slices.ContainsFunc(statuses,
+			func(s postgres.GetArchivedStatusSignaturesRow) bool {
+				return s.Name == name && s.ID == id
+			})
+		if !statusArchived {
+			return false, fmt.Errorf("status %s %d: %w",
+				name, id, errNotArchived)
+		}
+	}
+
+	for _, v := range versions {
+		key := fmt.Sprintf("documents/%s/versions/%019d.json",
+			docUUID, v.Version)
+
+		err := a.exportArchiveObject(ctx, bundle, key)
+		if err != nil {
+			return false, fmt.Errorf("export version %d: %w",
+				v.Version, err)
+		}
+	}
+
+	for _, s := range statuses {
+		key := fmt.Sprintf("documents/%s/statuses/%s/%019d.json",
+			docUUID, s.Name, s.ID)
+
+		err := a.exportArchiveObject(ctx, bundle, key)
+		if err != nil {
+			return false, fmt.Errorf("export status %s %d: %w",
+				s.Name, s.ID, err)
+		}
+	}
+
+	attached, err := q.GetAllAttachedObjects(ctx, docUUID)
+	if err != nil {
+		return false, fmt.Errorf("list attached objects: %w", err)
+	}
+
+	for _, o := range attached {
+		err := a.exportAttachedObject(ctx, bundle, o)
+		if err != nil {
+			return false, fmt.Errorf(
+				"export attached object %q version %d: %w",
+				o.Name, o.Version, err)
+		}
+
+		doc.Attachments = append(doc.Attachments,
+			attachedObjectFromRow(o))
+	}
+
+	docData, err := json.Marshal(doc)
+	if err != nil {
+		return false, fmt.Errorf("marshal document snapshot: %w", err)
+	}
+
+	err = bundle.writeEntry(
+		fmt.Sprintf("documents/%s/document.json", docUUID), docData)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// exportArchiveObject verifies an archive object and adds it to the bundle
+// together with its signature.
+func (a *Archiver) exportArchiveObject(
+	ctx context.Context, bundle *exportBundle, key string,
+) error {
+	data, signature, err := a.reader.ReadVerifiedRaw(ctx, key)
+	if err != nil {
+		return fmt.Errorf("read archive object: %w", err)
+	}
+
+	err = bundle.writeEntry(key, data)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(&bundle.sigs, "%s\t%s\n", key, signature)
+
+	return nil
+}
+
+func (a *Archiver) exportAttachedObject(
+	ctx context.Context, bundle *exportBundle, o postgres.AttachedObject,
+) error {
+	res, err := a.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(a.assetBucket),
+		Key:       aws.String(fmt.Sprintf("objects/%s/%s", o.Name, o.Document)),
+		VersionId: aws.String(o.ObjectVersion),
+	})
+	if err != nil {
+		return fmt.Errorf("get object: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	name := fmt.Sprintf("documents/%s/attachments/%s/%019d.object",
+		o.Document, o.Name, o.Version)
+
+	return bundle.add(name, res.Body)
+}
+
+// exportBundle writes entries to an export zip and keeps track of their
+// checksums and archive signatures.
+type exportBundle struct {
+	zw    *zip.Writer
+	files map[string]string
+	sigs  bytes.Buffer
+}
+
+func (b *exportBundle) writeEntry(name string, data []byte) error {
+	return b.add(name, bytes.NewReader(data))
+}
+
+func (b *exportBundle) add(name string, r io.Reader) error {
+	w, err := b.zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("create zip entry %s: %w", name, err)
+	}
+
+	hash := sha256.New()
+
+	_, err = io.Copy(io.MultiWriter(w, hash), r)
+	if err != nil {
+		return fmt.Errorf("write zip entry %s: %w", name, err)
+	}
+
+	if name != ExportManifestName && name != ExportManifestSignatureName {
+		b.files[name] = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package repository_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationDocumentExport(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelError))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		RunArchiver: true,
+	})
+
+	claims := itest.Claims(t, "exporter",
+		"doc_read doc_write asset_upload doc_export")
+
+	client := tc.DocumentsClient(t, claims)
+	ext := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, claims)
+
+	docUUID := uuid.NewString()
+	otherUUID := uuid.NewString()
+
+	up, err := client.CreateUpload(ctx, &rpc.CreateUploadRequest{
+		Name:        "my.txt",
+		ContentType: "text/plain",
+	})
+	test.Must(t, err, "create upload")
+
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodPut, up.Url, strings.NewReader("attached text"))
+	test.Must(t, err, "create upload request")
+
+	res, err := http.DefaultClient.Do(req)
+	test.Must(t, err, "make upload request")
+
+	_ = res.Body.Close()
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: baseDocument(docUUID, "article://test/"+docUUID),
+		AttachObjects: map[string]string{
+			"plaintext": up.Id,
+		},
+	})
+	test.Must(t, err, "create document")
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: baseDocument(docUUID, "article://test/"+docUUID),
+		Status: []*rpc.StatusUpdate{
+			{Name: "usable"},
+		},
+	})
+	test.Must(t, err, "update document")
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     otherUUID,
+		Document: baseDocument(otherUUID, "article://test/"+otherUUID),
+	})
+	test.Must(t, err, "create a document that shouldn't be exported")
+
+	// Only archived versions and statuses are exported, so wait for the
+	// archiver to catch up.
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		var unarchived int64
+
+		err := tc.DB.QueryRow(ctx, `
+SELECT (SELECT COUNT(*) FROM document_version
+        WHERE uuid = $1 AND archived = false)
+     + (SELECT COUNT(*) FROM document_status
+        WHERE uuid = $1 AND archived = false)`,
+			docUUID).Scan(&unarchived)
+		test.Must(t, err, "count unarchived versions and statuses")
+
+		if unarchived == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the document to be archived")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	err = ext.Call(ctx, "StartExport", repository.StartExportRequest{},
+		&repository.StartExportResponse{})
+	test.IsTwirpError(t, err, twirp.InvalidArgument)
+
+	err = tc.ExtensionClient(t, rpc.DocumentsPathPrefix,
+		itest.Claims(t, "reader", "doc_read"),
+	).Call(ctx, "StartExport", repository.StartExportRequest{
+		UUIDs: []string{docUUID},
+	}, &repository.StartExportResponse{})
+	test.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	var started repository.StartExportResponse
+
+	err = ext.Call(ctx, "StartExport", repository.StartExportRequest{
+		UUIDs: []string{docUUID},
+	}, &started)
+	test.Must(t, err, "start export")
+
+	var exp repository.GetExportResponse
+
+	deadline = time.Now().Add(10 * time.Second)
+
+	for exp.Export == nil || exp.Export.Finished == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the export to finish")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		err := ext.Call(ctx, "GetExport", repository.GetExportRequest{
+			ID: started.Export.ID,
+		}, &exp)
+		test.Must(t, err, "get export")
+	}
+
+	test.Equal(t, repository.ExportDone, exp.Export.Status,
+		"complete the export")
+	test.Equal(t, int64(1), exp.Export.Total, "match one document")
+	test.Equal(t, int64(1), exp.Export.Processed, "process the document")
+
+	obj, err := tc.Env.S3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(tc.Env.Bucket),
+		Key:    aws.String(exp.Export.ObjectKey),
+	})
+	test.Must(t, err, "get export bundle")
+
+	defer obj.Body.Close()
+
+	data, err := io.ReadAll(obj.Body)
+	test.Must(t, err, "read export bundle")
+
+	test.Equal(t, exp.Export.Signature,
+		obj.Metadata["elephant-signature"],
+		"store the signature with the bundle")
+
+	bundleSig, err := repository.ParseArchiveSignature(exp.Export.Signature)
+	test.Must(t, err, "parse bundle signature")
+
+	test.Equal(t, sha256.Sum256(data), bundleSig.Hash,
+		"sign the bundle checksum")
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	test.Must(t, err, "open export bundle")
+
+	entries := make(map[string][]byte)
+
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		test.Must(t, err, "open entry %s", f.Name)
+
+		entries[f.Name], err = io.ReadAll(rc)
+		test.Must(t, err, "read entry %s", f.Name)
+
+		_ = rc.Close()
+	}
+
+	manifestSig, err := repository.ParseArchiveSignature(
+		string(entries[repository.ExportManifestSignatureName]))
+	test.Must(t, err, "parse manifest signature")
+
+	test.Equal(t, sha256.Sum256(entries[repository.ExportManifestName]),
+		manifestSig.Hash, "sign the manifest checksum")
+
+	var manifest repository.ExportManifest
+
+	err = json.Unmarshal(entries[repository.ExportManifestName], &manifest)
+	test.Must(t, err, "decode manifest")
+
+	test.EqualDiff(t, []uuid.UUID{uuid.MustParse(docUUID)}, manifest.Documents,
+		"list the exported document")
+
+	for name, checksum := range manifest.Files {
+		sum := sha256.Sum256(entries[name])
+
+		test.Equal(t, checksum, hex.EncodeToString(sum[:]),
+			"match the checksum of %s", name)
+	}
+
+	prefix := "documents/" + docUUID + "/"
+
+	for _, name := range []string{
+		prefix + "versions/0000000000000000001.json",
+		prefix + "versions/0000000000000000002.json",
+		prefix + "statuses/usable/0000000000000000001.json",
+	} {
+		_, ok := manifest.Files[name]
+		test.Equal(t, true, ok, "export %s", name)
+
+		test.Equal(t, true,
+			strings.Contains(
+				string(entries[repository.ExportSignaturesName]),
+				name+"\t"),
+			"list the signature of %s", name)
+	}
+
+	test.Equal(t, "attached text",
+		string(entries[prefix+"attachments/plaintext/0000000000000000001.object"]),
+		"export the attached object")
+
+	var doc repository.ExportedDocument
+
+	err = json.Unmarshal(entries[prefix+"document.json"], &doc)
+	test.Must(t, err, "decode document snapshot")
+
+	test.Equal(t, int64(2), doc.CurrentVersion, "snapshot the document")
+	test.Equal(t, int64(1), doc.Heads["usable"], "snapshot the heads")
+	test.Equal(t, 1, len(doc.Attachments), "snapshot the attachments")
+}
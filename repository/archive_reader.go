@@ -108,6 +108,25 @@ func (a *ArchiveReader) ReadDocumentStatus(
 	return &obj, sigStr, nil
 }
 
+// ReadAttachedObject reads and verifies an archived attached object manifest
+// from the archive. If parentSignature is provided the manifest will be
+// verified against it.
+func (a *ArchiveReader) ReadAttachedObject(
+	ctx context.Context,
+	key string, parentSignature *string,
+) (*ArchivedAttachedObject, string, error) {
+	var obj ArchivedAttachedObject
+
+	sigStr, err := a.fetchAndVerify(
+		ctx, key, parentSignature, &obj,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &obj, sigStr, nil
+}
+
 // RawEvent holds the raw bytes and signature of an archived event.
 type RawEvent struct {
 	Data      []byte
@@ -172,6 +191,58 @@ func (a *ArchiveReader) ReadRaw(
 	return data, nil
 }
 
+// ReadVerifiedRaw reads the raw bytes of an archive object and verifies them
+// against the object signature. Parent signatures are not checked.
+func (a *ArchiveReader) ReadVerifiedRaw(
+	ctx context.Context, key string,
+) (_ []byte, _ string, outErr error) {
+	res, err := a.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("get archive object from S3: %w", err)
+	}
+
+	defer func() {
+		err := res.Body.Close()
+		if err != nil {
+			outErr = errors.Join(outErr, fmt.Errorf(
+				"close S3 response body: %w", err))
+		}
+	}()
+
+	sigStr := res.Metadata["elephant-signature"]
+
+	signature, err := ParseArchiveSignature(sigStr)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid object signature: %w", err)
+	}
+
+	signingKey := a.signingKeys.GetKeyByID(signature.KeyID)
+	if signingKey == nil {
+		return nil, "", errors.New("unknown signing key")
+	}
+
+	err = signature.Verify(signingKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("verify signature: %w", err)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("read S3 response body: %w", err)
+	}
+
+	hash := sha256.Sum256(data)
+
+	if !bytes.Equal(hash[:], signature.Hash[:]) {
+		return nil, "", errors.New("object does not match the signature")
+	}
+
+	return data, sigStr, nil
+}
+
 // ReadGenerationEvents reads archived generation events from S3 starting after
 // afterPos, up to limit events.
 func (a *ArchiveReader) ReadGenerationEvents(
@@ -211,6 +282,67 @@ func (a *ArchiveReader) ReadGenerationEvents(
 	return events, nil
 }
 
+// ReadGeneration reads and verifies the archived metadata of a schema
+// generation.
+func (a *ArchiveReader) ReadGeneration(
+	ctx context.Context, id int64,
+) (*ArchivedGeneration, error) {
+	var obj ArchivedGeneration
+
+	key := fmt.Sprintf("generations/%d/generation.json", id)
+
+	_, err := a.fetchAndVerify(ctx, key, nil, &obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return &obj, nil
+}
+
+// ReadGenerationSchema reads and verifies an archived schema specification
+// of a generation.
+func (a *ArchiveReader) ReadGenerationSchema(
+	ctx context.Context, id int64, ref ArchivedSchemaRef,
+) (*ArchivedSchemaSpec, error) {
+	var obj ArchivedSchemaSpec
+
+	key := fmt.Sprintf("generations/%d/schemas/%s@%s.json",
+		id, ref.Name, ref.Version)
+
+	sig, err := a.fetchAndVerify(ctx, key, nil, &obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if sig != ref.Signature {
+		return nil, errors.New("signature doesn't match the generation")
+	}
+
+	return &obj, nil
+}
+
+// ReadGenerationExemplar reads and verifies an archived exemplar document of
+// a generation. The index is the position of the exemplar in the archived
+// generation.
+func (a *ArchiveReader) ReadGenerationExemplar(
+	ctx context.Context, id int64, index int, ref ArchivedExemplarRef,
+) (*ArchivedExemplarDoc, error) {
+	var obj ArchivedExemplarDoc
+
+	key := fmt.Sprintf("generations/%d/exemplars/%d.json", id, index)
+
+	sig, err := a.fetchAndVerify(ctx, key, nil, &obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if sig != ref.Signature {
+		return nil, errors.New("signature doesn't match the generation")
+	}
+
+	return &obj, nil
+}
+
 func (a *ArchiveReader) fetchAndVerify(
 	ctx context.Context,
 	key string, parentSignature *string, obj ArchivedObject,
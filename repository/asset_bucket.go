@@ -1,14 +1,23 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
 	"github.com/google/uuid"
 	"github.com/ttab/elephantine"
@@ -35,6 +44,18 @@ type AssetBucket struct {
 	name    string
 }
 
+// errUploadReplaced is returned when an upload object has been replaced after
+// it was inspected.
+var errUploadReplaced = errors.New("the upload object has been replaced")
+
+// UploadPart is a part of a multipart upload.
+type UploadPart struct {
+	PartNumber   int32     `json:"part_number"`
+	ETag         string    `json:"etag"`
+	Size         int64     `json:"size,omitempty"`
+	LastModified time.Time `json:"last_modified,omitzero"`
+}
+
 // CreateUploadURL creates a presigned upload URL that clients can use to upload
 // an asset to the object store.
 func (ab *AssetBucket) CreateUploadURL(
@@ -51,6 +72,126 @@ func (ab *AssetBucket) CreateUploadURL(
 	return req.URL, nil
 }
 
+// CreateMultipartUpload starts a multipart upload to the object store and
+// returns its multipart upload ID.
+func (ab *AssetBucket) CreateMultipartUpload(
+	ctx context.Context, id uuid.UUID, contentType string,
+) (string, error) {
+	res, err := ab.client.CreateMultipartUpload(ctx,
+		&s3.CreateMultipartUploadInput{
+			Bucket:      aws.String(ab.name),
+			Key:         aws.String(fmt.Sprintf("uploads/%s", id)),
+			ContentType: aws.String(contentType),
+		})
+	if err != nil {
+		return "", fmt.Errorf("create multipart upload: %w", err)
+	}
+
+	if res.UploadId == nil {
+		return "", errors.New("no upload ID in multipart upload response")
+	}
+
+	return *res.UploadId, nil
+}
+
+// CreateUploadPartURL creates a presigned upload URL for a part of a multipart
+// upload.
+func (ab *AssetBucket) CreateUploadPartURL(
+	ctx context.Context, id uuid.UUID, multipartID string, partNumber int32,
+) (string, error) {
+	req, err := ab.presign.PresignUploadPart(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(ab.name),
+		Key:        aws.String(fmt.Sprintf("uploads/%s", id)),
+		UploadId:   aws.String(multipartID),
+		PartNumber: aws.Int32(partNumber),
+	}, s3.WithPresignExpires(15*time.Minute))
+	if err != nil {
+		return "", fmt.Errorf("sign upload part URL: %w", err)
+	}
+
+	return req.URL, nil
+}
+
+// ListUploadParts lists the parts that have been uploaded for a multipart
+// upload.
+func (ab *AssetBucket) ListUploadParts(
+	ctx context.Context, id uuid.UUID, multipartID string,
+) ([]UploadPart, error) {
+	var parts []UploadPart
+
+	paginator := s3.NewListPartsPaginator(ab.client, &s3.ListPartsInput{
+		Bucket:   aws.String(ab.name),
+		Key:      aws.String(fmt.Sprintf("uploads/%s", id)),
+		UploadId: aws.String(multipartID),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list parts: %w", err)
+		}
+
+		for _, p := range page.Parts {
+			parts = append(parts, UploadPart{
+				PartNumber:   aws.ToInt32(p.PartNumber),
+				ETag:         aws.ToString(p.ETag),
+				Size:         aws.ToInt64(p.Size),
+				LastModified: aws.ToTime(p.LastModified),
+			})
+		}
+	}
+
+	return parts, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into the upload object.
+func (ab *AssetBucket) CompleteMultipartUpload(
+	ctx context.Context, id uuid.UUID, multipartID string,
+	parts []UploadPart,
+) error {
+	completed := make([]types.CompletedPart, len(parts))
+
+	for i, p := range parts {
+		completed[i] = types.CompletedPart{
+			PartNumber: aws.Int32(p.PartNumber),
+			ETag:       aws.String(p.ETag),
+		}
+	}
+
+	_, err := ab.client.CompleteMultipartUpload(ctx,
+		&s3.CompleteMultipartUploadInput{
+			Bucket:   aws.String(ab.name),
+			Key:      aws.String(fmt.Sprintf("uploads/%s", id)),
+			UploadId: aws.String(multipartID),
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: completed,
+			},
+		})
+	if err != nil {
+		return fmt.Errorf("complete multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+// AbortMultipartUpload aborts a multipart upload and frees the storage used by
+// the uploaded parts.
+func (ab *AssetBucket) AbortMultipartUpload(
+	ctx context.Context, id uuid.UUID, multipartID string,
+) error {
+	_, err := ab.client.AbortMultipartUpload(ctx,
+		&s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(ab.name),
+			Key:      aws.String(fmt.Sprintf("uploads/%s", id)),
+			UploadId: aws.String(multipartID),
+		})
+	if err != nil {
+		return fmt.Errorf("abort multipart upload: %w", err)
+	}
+
+	return nil
+}
+
 // CreateDownloadURL creates a presigned download URL that clients can use to
 // download an asset from the object store.
 func (ab *AssetBucket) CreateDownloadURL(
@@ -67,49 +208,229 @@ func (ab *AssetBucket) CreateDownloadURL(
 	return req.URL, nil
 }
 
-func (ab *AssetBucket) UploadExists(
-	ctx context.Context, id uuid.UUID,
-) (bool, error) {
+// CreateVersionDownloadURL creates a presigned download URL for a specific
+// version of an attached object.
+func (ab *AssetBucket) CreateVersionDownloadURL(
+	ctx context.Context, document uuid.UUID, name string, objectVersion string,
+) (string, error) {
+	req, err := ab.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket:    &ab.name,
+		Key:       aws.String(ab.objKey(document, name)),
+		VersionId: aws.String(objectVersion),
+	}, s3.WithPresignExpires(15*time.Minute))
+	if err != nil {
+		return "", fmt.Errorf("sign download URL: %w", err)
+	}
+
+	return req.URL, nil
+}
+
+// PutUpload streams the contents of an upload to the object store. The body
+// must be exactly size bytes long.
+func (ab *AssetBucket) PutUpload(
+	ctx context.Context, id uuid.UUID, body io.Reader, size int64,
+	contentType string,
+) error {
+	_, err := ab.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(ab.name),
+		Key:           aws.String(fmt.Sprintf("uploads/%s", id)),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String(contentType),
+	},
+		// The body can't be rewound, so we can neither sign the payload
+		// nor let the client calculate a checksum over plain HTTP. The
+		// caller is responsible for verifying the contents.
+		s3.WithAPIOptions(
+			v4.SwapComputePayloadSHA256ForUnsignedPayloadMiddleware,
+		),
+		func(o *s3.Options) {
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("put upload object: %w", err)
+	}
+
+	return nil
+}
+
+// GetObjectVersion opens a specific version of an attached object for
+// reading. The caller is responsible for closing the returned body.
+func (ab *AssetBucket) GetObjectVersion(
+	ctx context.Context, document uuid.UUID, name string, objectVersion string,
+) (io.ReadCloser, int64, error) {
+	obj, err := ab.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(ab.name),
+		Key:       aws.String(ab.objKey(document, name)),
+		VersionId: aws.String(objectVersion),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("read object: %w", err)
+	}
+
+	return obj.Body, aws.ToInt64(obj.ContentLength), nil
+}
+
+// InspectUpload reads the size of an upload and sniffs its MIME type. The
+// SHA-256 checksum is only calculated if withChecksum is true, as that requires
+// reading the whole object. Returns nil if nothing has been uploaded.
+func (ab *AssetBucket) InspectUpload(
+	ctx context.Context, id uuid.UUID, withChecksum bool,
+) (*UploadInspection, error) {
 	var ae smithy.APIError
 
-	_, err := ab.client.HeadObject(ctx, &s3.HeadObjectInput{
+	key := fmt.Sprintf("uploads/%s", id)
+
+	head, err := ab.client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(ab.name),
-		Key:    aws.String(fmt.Sprintf("uploads/%s", id)),
+		Key:    aws.String(key),
 	})
 
 	switch {
 	// See https://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html#ErrorCodeList
-	case errors.As(err, &ae) && ae.ErrorCode() == "NoSuchKey":
-		return false, nil
+	case errors.As(err, &ae) && (ae.ErrorCode() == "NoSuchKey" ||
+		ae.ErrorCode() == "NotFound"):
+		return nil, nil
 	case err != nil:
-		return false, fmt.Errorf("check if object exists: %w", err)
+		return nil, fmt.Errorf("check if object exists: %w", err)
+	}
+
+	inspection := UploadInspection{
+		Size: aws.ToInt64(head.ContentLength),
+		ETag: aws.ToString(head.ETag),
+	}
+
+	if inspection.Size == 0 {
+		inspection.Mimetype = http.DetectContentType(nil)
+
+		if withChecksum {
+			sum := sha256.Sum256(nil)
+
+			inspection.SHA256 = hex.EncodeToString(sum[:])
+		}
+
+		return &inspection, nil
+	}
+
+	input := s3.GetObjectInput{
+		Bucket:  aws.String(ab.name),
+		Key:     aws.String(key),
+		IfMatch: head.ETag,
+	}
+
+	// Only read as much as is needed for content sniffing if we're not
+	// going to calculate a checksum.
+	if !withChecksum {
+		input.Range = aws.String(fmt.Sprintf(
+			"bytes=0-%d", sniffLen-1))
+	}
+
+	obj, err := ab.client.GetObject(ctx, &input)
+	if err != nil {
+		return nil, fmt.Errorf("read object: %w", err)
+	}
+
+	defer obj.Body.Close()
+
+	hash := sha256.New()
+	sniffBuf := make([]byte, sniffLen)
+
+	body := io.TeeReader(obj.Body, hash)
+
+	n, err := io.ReadFull(body, sniffBuf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("read object start: %w", err)
+	}
+
+	inspection.Mimetype = http.DetectContentType(sniffBuf[:n])
+
+	if !withChecksum {
+		return &inspection, nil
+	}
+
+	_, err = io.Copy(io.Discard, body)
+	if err != nil {
+		return nil, fmt.Errorf("read object: %w", err)
+	}
+
+	inspection.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	return &inspection, nil
+}
+
+// ReadUpload reads the contents of an upload. Returns nil if the upload is
+// larger than maxSize. If etag is set the upload is only read if the upload
+// object still has that ETag, errUploadReplaced is returned otherwise.
+func (ab *AssetBucket) ReadUpload(
+	ctx context.Context, id uuid.UUID, etag string, maxSize int64,
+) ([]byte, error) {
+	input := s3.GetObjectInput{
+		Bucket: aws.String(ab.name),
+		Key:    aws.String(fmt.Sprintf("uploads/%s", id)),
+	}
+
+	if etag != "" {
+		input.IfMatch = aws.String(etag)
+	}
+
+	obj, err := ab.client.GetObject(ctx, &input)
+	if isS3PreconditionFailed(err) {
+		return nil, errUploadReplaced
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read object: %w", err)
+	}
+
+	defer obj.Body.Close()
+
+	if aws.ToInt64(obj.ContentLength) > maxSize {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(obj.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read object data: %w", err)
 	}
 
-	return true, nil
+	if int64(len(data)) > maxSize {
+		return nil, nil
+	}
+
+	return data, nil
 }
 
 // AttachUpload to a document and returns the object version. Name here is the
-// object name for the attachment.
+// object name for the attachment. If etag is set the upload is only attached
+// if the upload object still has that ETag, errUploadReplaced is returned
+// otherwise.
 func (ab *AssetBucket) AttachUpload(
 	ctx context.Context,
 	upload uuid.UUID,
 	document uuid.UUID,
 	name string,
+	etag string,
 ) (string, error) {
 	key := ab.objKey(document, name)
 	sourceKey := fmt.Sprintf("uploads/%s", upload)
-	source := fmt.Sprintf("%s/%s", ab.name, sourceKey)
 
-	res, err := ab.client.CopyObject(ctx, &s3.CopyObjectInput{
-		Bucket:     aws.String(ab.name),
-		Key:        aws.String(key),
-		CopySource: &source,
+	res, err := copyS3Object(ctx, ab.client, s3CopyInput{
+		SourceBucket: ab.name,
+		SourceKey:    sourceKey,
+		SourceETag:   etag,
+		Bucket:       ab.name,
+		Key:          key,
 	})
+	if isS3PreconditionFailed(err) {
+		return "", errUploadReplaced
+	}
+
 	if err != nil {
 		return "", fmt.Errorf("copy upload to document: %w", err)
 	}
 
-	if res.VersionId == nil {
+	if res.VersionID == "" {
 		return "", errors.New("unversioned asset bucket")
 	}
 
@@ -125,7 +446,30 @@ func (ab *AssetBucket) AttachUpload(
 		)
 	}
 
-	return *res.VersionId, nil
+	return res.VersionID, nil
+}
+
+// CopyToUpload copies a version of an attached object to an upload so that it
+// can be attached to another document.
+func (ab *AssetBucket) CopyToUpload(
+	ctx context.Context,
+	document uuid.UUID,
+	name string,
+	objectVersion string,
+	upload uuid.UUID,
+) error {
+	_, err := copyS3Object(ctx, ab.client, s3CopyInput{
+		SourceBucket:  ab.name,
+		SourceKey:     ab.objKey(document, name),
+		SourceVersion: objectVersion,
+		Bucket:        ab.name,
+		Key:           fmt.Sprintf("uploads/%s", upload),
+	})
+	if err != nil {
+		return fmt.Errorf("copy object to upload: %w", err)
+	}
+
+	return nil
 }
 
 // RevertObject to an earlier version. This will create a new version of the
@@ -137,18 +481,19 @@ func (ab *AssetBucket) RevertObject(
 	version string,
 ) (string, error) {
 	key := ab.objKey(document, name)
-	source := fmt.Sprintf("%s/%s?versionId=%s", ab.name, key, version)
 
-	res, err := ab.client.CopyObject(ctx, &s3.CopyObjectInput{
-		Bucket:     aws.String(ab.name),
-		Key:        aws.String(key),
-		CopySource: &source,
+	res, err := copyS3Object(ctx, ab.client, s3CopyInput{
+		SourceBucket:  ab.name,
+		SourceKey:     key,
+		SourceVersion: version,
+		Bucket:        ab.name,
+		Key:           key,
 	})
 	if err != nil {
 		return "", fmt.Errorf("copy old version: %w", err)
 	}
 
-	return *res.VersionId, nil
+	return res.VersionID, nil
 }
 
 func (ab *AssetBucket) DeleteObject(
@@ -169,9 +514,212 @@ func (ab *AssetBucket) DeleteObject(
 	return nil
 }
 
+// PutRendition stores a rendition of an attached object under its key.
+// Renditions are derived from the attached object, and every attached object
+// version gets its own rendition keys.
+func (ab *AssetBucket) PutRendition(
+	ctx context.Context,
+	rendition Rendition,
+) error {
+	_, err := ab.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(ab.name),
+		Key:           aws.String(rendition.Key),
+		Body:          bytes.NewReader(rendition.Data),
+		ContentLength: aws.Int64(int64(len(rendition.Data))),
+		ContentType:   aws.String(rendition.ContentType),
+	})
+	if err != nil {
+		return fmt.Errorf("put rendition object: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRendition deletes a rendition of an attached object.
+func (ab *AssetBucket) DeleteRendition(
+	ctx context.Context, key string,
+) error {
+	_, err := ab.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(ab.name),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("delete rendition object: %w", err)
+	}
+
+	return nil
+}
+
+// CreateRenditionDownloadURL creates a presigned download URL for a rendition
+// of an attached object.
+func (ab *AssetBucket) CreateRenditionDownloadURL(
+	ctx context.Context, key string,
+) (string, error) {
+	req, err := ab.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: &ab.name,
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(15*time.Minute))
+	if err != nil {
+		return "", fmt.Errorf("sign download URL: %w", err)
+	}
+
+	return req.URL, nil
+}
+
 func (ab *AssetBucket) objKey(
 	document uuid.UUID,
 	name string,
 ) string {
 	return fmt.Sprintf("objects/%s/%s", name, document)
 }
+
+// DeleteUpload deletes the object of an upload, deleting an upload that
+// doesn't have an object is a no-op.
+func (ab *AssetBucket) DeleteUpload(ctx context.Context, id uuid.UUID) error {
+	_, err := ab.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(ab.name),
+		Key:    aws.String(fmt.Sprintf("uploads/%s", id)),
+	})
+	if err != nil {
+		return fmt.Errorf("delete upload object: %w", err)
+	}
+
+	return nil
+}
+
+// StoredObject is the latest version of an object in the asset bucket.
+type StoredObject struct {
+	Key          string
+	VersionID    string
+	LastModified time.Time
+	// Deleted is true if the latest version is a delete marker.
+	Deleted bool
+}
+
+// ListObjectNames lists the names that objects are stored under in the asset
+// bucket.
+func (ab *AssetBucket) ListObjectNames(ctx context.Context) ([]string, error) {
+	var names []string
+
+	paginator := s3.NewListObjectsV2Paginator(ab.client,
+		&s3.ListObjectsV2Input{
+			Bucket:    aws.String(ab.name),
+			Prefix:    aws.String("objects/"),
+			Delimiter: aws.String("/"),
+		})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list object prefixes: %w", err)
+		}
+
+		for _, p := range page.CommonPrefixes {
+			name := strings.TrimSuffix(
+				strings.TrimPrefix(aws.ToString(p.Prefix), "objects/"),
+				"/")
+
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// ListStoredObjects calls fn with the latest versions of the objects,
+// including renditions, that are stored under an object name. The objects are
+// listed in key order one page at a time.
+func (ab *AssetBucket) ListStoredObjects(
+	ctx context.Context, name string, fn func(page []StoredObject) error,
+) error {
+	paginator := s3.NewListObjectVersionsPaginator(ab.client,
+		&s3.ListObjectVersionsInput{
+			Bucket: aws.String(ab.name),
+			Prefix: aws.String("objects/" + name + "/"),
+		})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list object versions: %w", err)
+		}
+
+		var objects []StoredObject
+
+		for _, v := range page.Versions {
+			if !aws.ToBool(v.IsLatest) {
+				continue
+			}
+
+			objects = append(objects, StoredObject{
+				Key:          aws.ToString(v.Key),
+				VersionID:    aws.ToString(v.VersionId),
+				LastModified: aws.ToTime(v.LastModified),
+			})
+		}
+
+		for _, m := range page.DeleteMarkers {
+			if !aws.ToBool(m.IsLatest) {
+				continue
+			}
+
+			objects = append(objects, StoredObject{
+				Key:          aws.ToString(m.Key),
+				VersionID:    aws.ToString(m.VersionId),
+				LastModified: aws.ToTime(m.LastModified),
+				Deleted:      true,
+			})
+		}
+
+		// Versions and delete markers are listed separately, so
+		// restore the key order.
+		slices.SortFunc(objects, func(a, b StoredObject) int {
+			return strings.Compare(a.Key, b.Key)
+		})
+
+		err = fn(objects)
+		if err != nil {
+			return fmt.Errorf("process page: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteKey deletes an object in the asset bucket by key. As the bucket is
+// versioned this only adds a delete marker, the object data is removed by the
+// bucket lifecycle rules.
+func (ab *AssetBucket) DeleteKey(ctx context.Context, key string) error {
+	_, err := ab.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(ab.name),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("delete object: %w", err)
+	}
+
+	return nil
+}
+
+// renditionKey is the object key of a rendition of an attached upload.
+// Renditions are stored next to the attached object as
+// "objects/{name}/{document}.{rendition}.{upload}", so that the renditions of
+// every attached version have their own keys.
+func renditionKey(
+	document uuid.UUID, name string, rendition string, upload uuid.UUID,
+) string {
+	return fmt.Sprintf("objects/%s/%s.%s.%s", name, document, rendition, upload)
+}
+
+// renditionObjectKey returns the object key of a stored rendition. Renditions
+// that were stored before their keys were recorded were stored as
+// "objects/{name}/{document}.{rendition}".
+func renditionObjectKey(
+	document uuid.UUID, name string, rendition string, key string,
+) string {
+	if key != "" {
+		return key
+	}
+
+	return fmt.Sprintf("objects/%s/%s.%s", name, document, rendition)
+}
@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/ttab/elephantine"
+	"github.com/twitchtv/twirp"
+)
+
+// DefaultMaxProxiedUploadSize is the default size limit for uploads through
+// the asset proxy.
+const DefaultMaxProxiedUploadSize = 1 << 30
+
+type AssetProxyOptions struct {
+	Logger    *slog.Logger
+	Documents *DocumentsService
+	Assets    *AssetBucket
+	// MaxUploadSize is the largest upload in bytes that is accepted by the
+	// proxy, defaults to DefaultMaxProxiedUploadSize.
+	MaxUploadSize int64
+}
+
+// AssetProxy streams uploads and attached objects through the repository for
+// clients that can't reach the object store through presigned URLs.
+type AssetProxy struct {
+	logger        *slog.Logger
+	docs          *DocumentsService
+	assets        *AssetBucket
+	maxUploadSize int64
+}
+
+func NewAssetProxy(opts AssetProxyOptions) *AssetProxy {
+	if opts.MaxUploadSize == 0 {
+		opts.MaxUploadSize = DefaultMaxProxiedUploadSize
+	}
+
+	return &AssetProxy{
+		logger:        opts.Logger,
+		docs:          opts.Documents,
+		assets:        opts.Assets,
+		maxUploadSize: opts.MaxUploadSize,
+	}
+}
+
+// ProxyUploadResponse is the response to a successful proxied upload.
+type ProxyUploadResponse struct {
+	ID     string `json:"id"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Upload streams the request body to the object store as the contents of an
+// upload. Only the creator of the upload, or a document admin, can write to
+// it. The size and checksum declared for the upload are verified, and the
+// upload is discarded if they don't match.
+func (p *AssetProxy) Upload(
+	w http.ResponseWriter, r *http.Request, uploadID string,
+) error {
+	ctx := r.Context()
+
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentAdmin, ScopeAssetUpload,
+	)
+	if err != nil {
+		return httpErrorFromTwirp(err)
+	}
+
+	id, err := uuid.Parse(uploadID)
+	if err != nil {
+		return elephantine.HTTPErrorf(http.StatusBadRequest,
+			"invalid upload ID: %v", err)
+	}
+
+	upload, err := p.docs.store.GetUpload(ctx, id)
+	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
+		return elephantine.HTTPErrorf(http.StatusNotFound,
+			"no such upload")
+	} else if err != nil {
+		return fmt.Errorf("read upload: %w", err)
+	}
+
+	if upload.CreatedBy != auth.Claims.Subject &&
+		!auth.Claims.HasScope(ScopeDocumentAdmin) {
+		return elephantine.HTTPErrorf(http.StatusForbidden,
+			"the upload was created by another client")
+	}
+
+	if upload.MultipartID != "" {
+		return elephantine.HTTPErrorf(http.StatusConflict,
+			"multipart uploads can't be proxied")
+	}
+
+	size := r.ContentLength
+
+	switch {
+	case size < 0:
+		return elephantine.HTTPErrorf(http.StatusLengthRequired,
+			"a content length is required")
+	case size > p.maxUploadSize:
+		return elephantine.HTTPErrorf(http.StatusRequestEntityTooLarge,
+			"uploads through the proxy are limited to %d bytes",
+			p.maxUploadSize)
+	case upload.Meta.Size != 0 && size != upload.Meta.Size:
+		return elephantine.HTTPErrorf(http.StatusBadRequest,
+			"expected a size of %d bytes, got %d bytes",
+			upload.Meta.Size, size)
+	}
+
+	// The HTTP server doesn't let us read more than the content length, so
+	// the limit is enforced by the checks above.
+	hash := sha256.New()
+	sniff := sniffWriter{limit: sniffLen}
+
+	body := io.TeeReader(r.Body, io.MultiWriter(hash, &sniff))
+
+	err = p.assets.PutUpload(ctx, id, body, size, upload.Meta.Mimetype)
+	if err != nil {
+		return fmt.Errorf("store upload: %w", err)
+	}
+
+	inspection := UploadInspection{
+		Size:     size,
+		SHA256:   hex.EncodeToString(hash.Sum(nil)),
+		Mimetype: http.DetectContentType(sniff.data),
+	}
+
+	err = VerifyUpload(upload.Meta, inspection)
+	if err != nil {
+		delErr := p.assets.DeleteUpload(ctx, id)
+		if delErr != nil {
+			p.logger.ErrorContext(ctx,
+				"failed to delete rejected proxied upload",
+				elephantine.LogKeyError, delErr,
+				"upload_id", id,
+			)
+		}
+
+		return elephantine.HTTPErrorf(http.StatusBadRequest,
+			"upload verification failed: %v", err)
+	}
+
+	return writeProxyJSON(w, ProxyUploadResponse{
+		ID:     id.String(),
+		Size:   inspection.Size,
+		SHA256: inspection.SHA256,
+	})
+}
+
+// Download streams an attached object to the client. The current version is
+// returned unless a version is given. Requires the same permissions as
+// GetAttachments, and downloads are recorded in the read audit log.
+func (p *AssetProxy) Download(
+	w http.ResponseWriter, r *http.Request,
+	document string, name string,
+) error {
+	ctx := r.Context()
+
+	auth, docUUID, err := p.docs.attachmentVersionAccess(ctx, document, name)
+	if err != nil {
+		return httpErrorFromTwirp(err)
+	}
+
+	var version int64
+
+	if v := r.URL.Query().Get("version"); v != "" {
+		version, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || version <= 0 {
+			return elephantine.HTTPErrorf(http.StatusBadRequest,
+				"version must be a positive attachment version")
+		}
+	}
+
+	if version == 0 {
+		history, err := p.docs.store.GetAttachmentHistory(
+			ctx, docUUID, name)
+		if err != nil && !IsDocStoreErrorCode(err, ErrCodeNotFound) {
+			return fmt.Errorf("read attachment history: %w", err)
+		}
+
+		if history == nil || history.CurrentVersion == 0 {
+			return elephantine.HTTPErrorf(http.StatusNotFound,
+				"no such attachment")
+		}
+
+		version = history.CurrentVersion
+	}
+
+	obj, err := p.docs.store.GetAttachmentVersion(ctx, docUUID, name, version)
+	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
+		return elephantine.HTTPErrorf(http.StatusNotFound,
+			"no such attachment version")
+	} else if err != nil {
+		return fmt.Errorf("read attachment version: %w", err)
+	}
+
+	err = p.docs.recordReads(ctx, auth, ReadAuditAttachment, []DocumentRead{
+		{
+			UUID:       docUUID,
+			Version:    obj.Version,
+			Attachment: obj.Name,
+		},
+	})
+	if err != nil {
+		return httpErrorFromTwirp(err)
+	}
+
+	body, size, err := p.assets.GetObjectVersion(
+		ctx, docUUID, name, obj.ObjectVersion)
+	if err != nil {
+		return fmt.Errorf("open attached object: %w", err)
+	}
+
+	defer body.Close()
+
+	h := w.Header()
+
+	h.Set("Content-Type", obj.Mimetype)
+	h.Set("Content-Length", strconv.FormatInt(size, 10))
+	h.Set("Content-Disposition", mime.FormatMediaType(
+		"attachment", map[string]string{"filename": obj.Filename}))
+	h.Set("Cache-Control", "private, no-store")
+
+	if sum, err := hex.DecodeString(obj.SHA256); err == nil && len(sum) > 0 {
+		h.Set("Repr-Digest", "sha-256=:"+
+			base64.StdEncoding.EncodeToString(sum)+":")
+	}
+
+	_, err = io.Copy(w, body)
+	if err != nil {
+		// The response has already been started, so all we can do is
+		// log the error.
+		p.logger.WarnContext(ctx, "failed to stream attached object",
+			elephantine.LogKeyError, err,
+			elephantine.LogKeyDocumentUUID, docUUID,
+		)
+	}
+
+	return nil
+}
+
+// sniffWriter keeps the first limit bytes that are written to it.
+type sniffWriter struct {
+	limit int
+	data  []byte
+}
+
+func (sw *sniffWriter) Write(p []byte) (int, error) {
+	if rem := sw.limit - len(sw.data); rem > 0 {
+		sw.data = append(sw.data, p[:min(rem, len(p))]...)
+	}
+
+	return len(p), nil
+}
+
+func writeProxyJSON(w http.ResponseWriter, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal response: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("write response: %w", err)
+	}
+
+	return nil
+}
+
+// httpErrorFromTwirp converts twirp errors to HTTP errors with the
+// corresponding status code.
+func httpErrorFromTwirp(err error) error {
+	var te twirp.Error
+
+	if !errors.As(err, &te) {
+		return err
+	}
+
+	return elephantine.NewHTTPError(
+		twirp.ServerHTTPStatusFromErrorCode(te.Code()), te.Msg())
+}
@@ -0,0 +1,149 @@
+package repository_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/test"
+)
+
+func TestIntegrationAssetProxy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		MaxProxiedUploadSize: 64,
+	})
+
+	claims := itest.Claims(t, "uploader",
+		"doc_read doc_write asset_upload")
+
+	client := tc.DocumentsClient(t, claims)
+
+	proxyRequest := func(
+		method string, path string, claims elephantine.JWTClaims,
+		body string,
+	) (int, string) {
+		t.Helper()
+
+		token, err := itest.AccessToken(tc.SigningKey, claims)
+		test.Must(t, err, "create access token")
+
+		req, err := http.NewRequestWithContext(ctx,
+			method, tc.Server.URL+path, strings.NewReader(body))
+		test.Must(t, err, "create proxy request")
+
+		req.Header.Set("Authorization", bearerPrefix+token)
+
+		res, err := http.DefaultClient.Do(req)
+		test.Must(t, err, "make proxy request")
+
+		defer res.Body.Close()
+
+		data, err := io.ReadAll(res.Body)
+		test.Must(t, err, "read proxy response")
+
+		return res.StatusCode, string(data)
+	}
+
+	createUpload := func(meta map[string]string) string {
+		t.Helper()
+
+		up, err := client.CreateUpload(ctx, &rpc.CreateUploadRequest{
+			Name:        "my.txt",
+			ContentType: "text/plain",
+			Meta:        meta,
+		})
+		test.Must(t, err, "create upload")
+
+		return up.Id
+	}
+
+	content := "proxied content"
+	sum := sha256.Sum256([]byte(content))
+	checksum := hex.EncodeToString(sum[:])
+
+	uploadID := createUpload(map[string]string{
+		repository.UploadMetaSHA256: checksum,
+		repository.UploadMetaSize:   strconv.Itoa(len(content)),
+	})
+
+	status, body := proxyRequest(http.MethodPut,
+		"/uploads/"+uploadID, claims, content)
+	test.Equal(t, http.StatusOK, status, "upload through the proxy")
+
+	var upRes repository.ProxyUploadResponse
+
+	err := json.Unmarshal([]byte(body), &upRes)
+	test.Must(t, err, "decode upload response")
+
+	test.Equal(t, checksum, upRes.SHA256, "calculate the checksum")
+
+	status, _ = proxyRequest(http.MethodPut,
+		"/uploads/"+uploadID,
+		itest.Claims(t, "other", "asset_upload"), content)
+	test.Equal(t, http.StatusForbidden, status,
+		"reject uploads to another client's upload")
+
+	status, _ = proxyRequest(http.MethodPut,
+		"/uploads/"+createUpload(nil), claims, strings.Repeat("x", 65))
+	test.Equal(t, http.StatusRequestEntityTooLarge, status,
+		"reject uploads over the size limit")
+
+	mismatched := createUpload(map[string]string{
+		repository.UploadMetaSHA256: checksum,
+	})
+
+	status, _ = proxyRequest(http.MethodPut,
+		"/uploads/"+mismatched, claims, "tampered content")
+	test.Equal(t, http.StatusBadRequest, status,
+		"reject uploads with the wrong checksum")
+
+	docUUID := uuid.NewString()
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: baseDocument(docUUID, "article://test/"+docUUID),
+		AttachObjects: map[string]string{
+			"plaintext": uploadID,
+		},
+	})
+	test.Must(t, err, "attach the proxied upload")
+
+	status, body = proxyRequest(http.MethodGet,
+		"/attachments/"+docUUID+"/plaintext", claims, "")
+	test.Equal(t, http.StatusOK, status, "download through the proxy")
+	test.Equal(t, content, body, "get the attached object")
+
+	status, body = proxyRequest(http.MethodGet,
+		"/attachments/"+docUUID+"/plaintext?version=1", claims, "")
+	test.Equal(t, http.StatusOK, status, "download a specific version")
+	test.Equal(t, content, body, "get the attached object version")
+
+	status, _ = proxyRequest(http.MethodGet,
+		"/attachments/"+docUUID+"/plaintext?version=2", claims, "")
+	test.Equal(t, http.StatusNotFound, status,
+		"fail to download a missing version")
+
+	status, _ = proxyRequest(http.MethodGet,
+		"/attachments/"+docUUID+"/plaintext",
+		itest.Claims(t, "outsider", "doc_read"), "")
+	test.Equal(t, http.StatusForbidden, status,
+		"enforce read permissions")
+}
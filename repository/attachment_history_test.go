@@ -0,0 +1,170 @@
+package repository_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationAttachmentHistory(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{})
+
+	claims := itest.Claims(t, "uploader",
+		"doc_read doc_write asset_upload")
+
+	client := tc.DocumentsClient(t, claims)
+	ext := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, claims)
+	outsider := tc.ExtensionClient(t, rpc.DocumentsPathPrefix,
+		itest.Claims(t, "outsider", "doc_read"))
+
+	docUUID := uuid.NewString()
+	doc := baseDocument(docUUID, "article://test/"+docUUID)
+
+	attach := func(data string) {
+		t.Helper()
+
+		up, err := client.CreateUpload(ctx, &rpc.CreateUploadRequest{
+			Name:        "my.txt",
+			ContentType: "text/plain",
+		})
+		test.Must(t, err, "create upload")
+
+		req, err := http.NewRequestWithContext(ctx,
+			http.MethodPut, up.Url, strings.NewReader(data))
+		test.Must(t, err, "create upload request")
+
+		req.ContentLength = int64(len(data))
+
+		res, err := http.DefaultClient.Do(req)
+		test.Must(t, err, "make upload request")
+
+		_ = res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("error response from upload recipient: %s",
+				res.Status)
+		}
+
+		_, err = client.Update(ctx, &rpc.UpdateRequest{
+			Uuid:     docUUID,
+			Document: doc,
+			AttachObjects: map[string]string{
+				"plaintext": up.Id,
+			},
+		})
+		test.Must(t, err, "attach the object")
+	}
+
+	download := func(url string) string {
+		t.Helper()
+
+		res, err := http.Get(url)
+		test.Must(t, err, "download the attachment")
+
+		defer res.Body.Close()
+
+		data, err := io.ReadAll(res.Body)
+		test.Must(t, err, "read the attachment")
+
+		return string(data)
+	}
+
+	attach("first version")
+	attach("second version")
+
+	var history repository.GetAttachmentHistoryResponse
+
+	err := ext.Call(ctx, "GetAttachmentHistory",
+		repository.GetAttachmentHistoryRequest{
+			UUID:           docUUID,
+			AttachmentName: "plaintext",
+			DownloadLinks:  true,
+		}, &history)
+	test.Must(t, err, "get attachment history")
+
+	test.Equal(t, 2, len(history.Versions), "get both versions")
+	test.Equal(t, int64(2), history.CurrentVersion,
+		"get the current version")
+	test.Equal(t, int64(2), history.Versions[0].Version,
+		"list the newest version first")
+	test.Equal(t, int64(1), history.Versions[1].DocumentVersion,
+		"get the document version the object was attached at")
+	test.Equal(t, "user://test/uploader", history.Versions[1].Creator,
+		"get the creator of the version")
+
+	test.Equal(t, "second version",
+		download(history.Versions[0].DownloadLink),
+		"download the current version")
+	test.Equal(t, "first version",
+		download(history.Versions[1].DownloadLink),
+		"download the historical version")
+
+	var version repository.GetAttachmentVersionResponse
+
+	err = ext.Call(ctx, "GetAttachmentVersion",
+		repository.GetAttachmentVersionRequest{
+			UUID:           docUUID,
+			AttachmentName: "plaintext",
+			Version:        1,
+			DownloadLink:   true,
+		}, &version)
+	test.Must(t, err, "get attachment version")
+
+	test.Equal(t, history.Versions[1].ObjectVersion,
+		version.Attachment.ObjectVersion,
+		"get the object version of the attachment version")
+	test.Equal(t, "first version",
+		download(version.Attachment.DownloadLink),
+		"download the requested version")
+
+	err = ext.Call(ctx, "GetAttachmentVersion",
+		repository.GetAttachmentVersionRequest{
+			UUID:           docUUID,
+			AttachmentName: "plaintext",
+			Version:        3,
+		}, &repository.GetAttachmentVersionResponse{})
+	itest.IsTwirpError(t, err, twirp.NotFound)
+
+	err = outsider.Call(ctx, "GetAttachmentHistory",
+		repository.GetAttachmentHistoryRequest{
+			UUID:           docUUID,
+			AttachmentName: "plaintext",
+		}, &repository.GetAttachmentHistoryResponse{})
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:          docUUID,
+		Document:      doc,
+		DetachObjects: []string{"plaintext"},
+	})
+	test.Must(t, err, "detach the object")
+
+	var detached repository.GetAttachmentHistoryResponse
+
+	err = ext.Call(ctx, "GetAttachmentHistory",
+		repository.GetAttachmentHistoryRequest{
+			UUID:           docUUID,
+			AttachmentName: "plaintext",
+		}, &detached)
+	test.Must(t, err, "get attachment history after detach")
+
+	test.Equal(t, int64(0), detached.CurrentVersion,
+		"have no current version after detach")
+	test.Equal(t, 2, len(detached.Versions), "keep the history")
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine"
+)
+
+// DefaultMaxProcessedAttachmentSize is the default size limit for attachments
+// that are passed to the attachment processors.
+const DefaultMaxProcessedAttachmentSize = 32 * 1024 * 1024
+
+// AttachmentProcessor extracts metadata from, and creates renditions of,
+// objects that are attached to documents.
+type AttachmentProcessor interface {
+	// Name of the processor, used as a prefix for the derived metadata
+	// keys and to identify the processor in logs.
+	Name() string
+	// Accepts returns true if the processor can handle objects of the
+	// given content type.
+	Accepts(contentType string) bool
+	// Process an attachment. Returning an error will not stop the object
+	// from being attached, the error will be logged and the processor
+	// results will be missing from the attachment.
+	Process(
+		ctx context.Context, data AttachmentData,
+	) (*AttachmentProcessingResult, error)
+}
+
+// AttachmentData is the object that's about to be attached to a document.
+type AttachmentData struct {
+	Document    uuid.UUID
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// AttachmentProcessingResult is the output of an attachment processor.
+type AttachmentProcessingResult struct {
+	// Props are derived metadata values, they will be recorded on the
+	// attachment as "<processor>.<key>".
+	Props map[string]string
+	// Renditions are derived objects that will be stored next to the
+	// attached object.
+	Renditions []Rendition
+}
+
+// Rendition is a derived object, f.ex. a thumbnail.
+type Rendition struct {
+	Name        string
+	ContentType string
+	Data        []byte
+	// Key is the object key that the rendition is stored under, it's set
+	// when an upload is processed.
+	Key string
+}
+
+// AssetRendition is a stored rendition of an attached object.
+type AssetRendition struct {
+	Name     string `json:"name"`
+	Mimetype string `json:"mimetype"`
+	Size     int64  `json:"size"`
+	// Key is the object key of the rendition. Renditions that were stored
+	// before keys were recorded don't have one, see renditionObjectKey.
+	Key string `json:"key,omitempty"`
+}
+
+// DefaultAttachmentProcessors returns the built in attachment processors.
+func DefaultAttachmentProcessors() []AttachmentProcessor {
+	return []AttachmentProcessor{
+		ImageProcessor{},
+		ExifProcessor{},
+		PDFProcessor{},
+		ThumbnailProcessor{MaxSize: DefaultThumbnailSize},
+	}
+}
+
+var renditionNameExp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// processAttachment runs the attachment processors that accept the content
+// type of the attachment and merges their results. Processor failures are
+// logged and otherwise ignored, as processing is secondary to storing the
+// attachment.
+func processAttachment(
+	ctx context.Context,
+	logger *slog.Logger,
+	processors []AttachmentProcessor,
+	data AttachmentData,
+) (map[string]string, []Rendition) {
+	var (
+		derived    map[string]string
+		renditions []Rendition
+		seen       = make(map[string]bool)
+	)
+
+	for _, p := range processors {
+		if !p.Accepts(data.ContentType) {
+			continue
+		}
+
+		res, err := p.Process(ctx, data)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to process attachment",
+				elephantine.LogKeyError, err,
+				elephantine.LogKeyDocumentUUID, data.Document,
+				"object_name", data.Name,
+				"processor", p.Name(),
+			)
+
+			continue
+		}
+
+		if res == nil {
+			continue
+		}
+
+		for k, v := range res.Props {
+			if derived == nil {
+				derived = make(map[string]string)
+			}
+
+			derived[p.Name()+"."+k] = v
+		}
+
+		for _, r := range res.Renditions {
+			err := validateRendition(r, seen)
+			if err != nil {
+				logger.WarnContext(ctx, "discarding invalid rendition",
+					elephantine.LogKeyError, err,
+					elephantine.LogKeyDocumentUUID, data.Document,
+					"object_name", data.Name,
+					"processor", p.Name(),
+				)
+
+				continue
+			}
+
+			seen[r.Name] = true
+
+			renditions = append(renditions, r)
+		}
+	}
+
+	return derived, renditions
+}
+
+func validateRendition(r Rendition, seen map[string]bool) error {
+	if !renditionNameExp.MatchString(r.Name) {
+		return fmt.Errorf("invalid rendition name %q", r.Name)
+	}
+
+	if seen[r.Name] {
+		return fmt.Errorf("duplicate rendition %q", r.Name)
+	}
+
+	if r.ContentType == "" {
+		return fmt.Errorf("missing content type for rendition %q", r.Name)
+	}
+
+	return nil
+}
+
+// assetRenditions describes the renditions as they will be stored.
+func assetRenditions(renditions []Rendition) []AssetRendition {
+	if len(renditions) == 0 {
+		return nil
+	}
+
+	res := make([]AssetRendition, len(renditions))
+
+	for i, r := range renditions {
+		res[i] = AssetRendition{
+			Name:     r.Name,
+			Mimetype: r.ContentType,
+			Size:     int64(len(r.Data)),
+			Key:      r.Key,
+		}
+	}
+
+	return res
+}
+
+func renditionsToPG(renditions []AssetRendition) []postgres.AssetRendition {
+	if len(renditions) == 0 {
+		return nil
+	}
+
+	res := make([]postgres.AssetRendition, len(renditions))
+
+	for i, r := range renditions {
+		res[i] = postgres.AssetRendition(r)
+	}
+
+	return res
+}
+
+func renditionsFromPG(renditions []postgres.AssetRendition) []AssetRendition {
+	if len(renditions) == 0 {
+		return nil
+	}
+
+	res := make([]AssetRendition, len(renditions))
+
+	for i, r := range renditions {
+		res[i] = AssetRendition(r)
+	}
+
+	return res
+}
+
+func assetMetadataMapFromPG(
+	meta map[string]postgres.AssetMetadata,
+) map[string]AssetMetadata {
+	if len(meta) == 0 {
+		return nil
+	}
+
+	res := make(map[string]AssetMetadata, len(meta))
+
+	for name, m := range meta {
+		res[name] = AssetMetadata{
+			Filename:        m.Filename,
+			Mimetype:        m.Mimetype,
+			SniffedMimetype: m.SniffedMimetype,
+			Props:           m.Props,
+			SHA256:          m.SHA256,
+			Size:            m.Size,
+			Derived:         m.Derived,
+			Renditions:      renditionsFromPG(m.Renditions),
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,313 @@
+package repository_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+)
+
+func testImage(t *testing.T, width int, height int) image.Image {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.RGBA{
+				R: uint8(x), G: uint8(y), B: 0x80, A: 0xFF,
+			})
+		}
+	}
+
+	return img
+}
+
+func testPNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	err := png.Encode(&buf, testImage(t, width, height))
+	test.Must(t, err, "encode test PNG")
+
+	return buf.Bytes()
+}
+
+// testExifJPEG creates a JPEG with an EXIF segment that has a make and
+// orientation in IFD0, and an original timestamp in the EXIF IFD.
+func testExifJPEG(t *testing.T) []byte {
+	t.Helper()
+
+	var img bytes.Buffer
+
+	err := jpeg.Encode(&img, testImage(t, 16, 8), nil)
+	test.Must(t, err, "encode test JPEG")
+
+	order := binary.BigEndian
+
+	var tiff []byte
+
+	tiff = append(tiff, 'M', 'M')
+	tiff = order.AppendUint16(tiff, 42)
+	tiff = order.AppendUint32(tiff, 8)
+
+	const (
+		ifd0Size    = 2 + 3*12 + 4
+		makeOffset  = 8 + ifd0Size
+		makeValue   = "Elephant\x00"
+		exifOffset  = makeOffset + len(makeValue)
+		exifIFDSize = 2 + 12 + 4
+		dateOffset  = exifOffset + exifIFDSize
+		dateValue   = "2024:01:02 03:04:05\x00"
+	)
+
+	entry := func(tag uint16, typ uint16, count uint32, value uint32) {
+		tiff = order.AppendUint16(tiff, tag)
+		tiff = order.AppendUint16(tiff, typ)
+		tiff = order.AppendUint32(tiff, count)
+
+		if typ == 3 {
+			tiff = order.AppendUint16(tiff, uint16(value))
+			tiff = order.AppendUint16(tiff, 0)
+
+			return
+		}
+
+		tiff = order.AppendUint32(tiff, value)
+	}
+
+	tiff = order.AppendUint16(tiff, 3)
+	entry(0x010F, 2, uint32(len(makeValue)), uint32(makeOffset))
+	entry(0x0112, 3, 1, 6)
+	entry(0x8769, 4, 1, uint32(exifOffset))
+	tiff = order.AppendUint32(tiff, 0)
+	tiff = append(tiff, makeValue...)
+
+	tiff = order.AppendUint16(tiff, 1)
+	entry(0x9003, 2, uint32(len(dateValue)), uint32(dateOffset))
+	tiff = order.AppendUint32(tiff, 0)
+	tiff = append(tiff, dateValue...)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	var out []byte
+
+	out = append(out, 0xFF, 0xD8, 0xFF, 0xE1)
+	out = order.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	out = append(out, img.Bytes()[2:]...)
+
+	return out
+}
+
+func TestAttachmentProcessors(t *testing.T) {
+	ctx := t.Context()
+
+	pngData := testPNG(t, 800, 400)
+
+	imgRes, err := repository.ImageProcessor{}.Process(ctx,
+		repository.AttachmentData{
+			ContentType: "image/png",
+			Data:        pngData,
+		})
+	test.Must(t, err, "process image")
+
+	test.EqualDiff(t, map[string]string{
+		"width":  "800",
+		"height": "400",
+		"format": "png",
+	}, imgRes.Props, "get image dimensions and format")
+
+	thumbRes, err := repository.ThumbnailProcessor{MaxSize: 320}.Process(
+		ctx, repository.AttachmentData{
+			ContentType: "image/png",
+			Data:        pngData,
+		})
+	test.Must(t, err, "create thumbnail")
+
+	test.Equal(t, 1, len(thumbRes.Renditions), "create one rendition")
+	test.Equal(t, "image/jpeg", thumbRes.Renditions[0].ContentType,
+		"create a JPEG thumbnail")
+
+	thumbConf, err := jpeg.DecodeConfig(
+		bytes.NewReader(thumbRes.Renditions[0].Data))
+	test.Must(t, err, "decode thumbnail")
+
+	test.Equal(t, 320, thumbConf.Width, "scale the width to fit")
+	test.Equal(t, 160, thumbConf.Height, "keep the aspect ratio")
+
+	exifRes, err := repository.ExifProcessor{}.Process(ctx,
+		repository.AttachmentData{
+			ContentType: "image/jpeg",
+			Data:        testExifJPEG(t),
+		})
+	test.Must(t, err, "extract EXIF data")
+
+	test.EqualDiff(t, map[string]string{
+		"make":              "Elephant",
+		"orientation":       "6",
+		"datetime_original": "2024:01:02 03:04:05",
+	}, exifRes.Props, "get EXIF tags")
+
+	pdf := []byte(`%PDF-1.4
+1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj
+2 0 obj << /Kids [3 0 R 4 0 R] /Count 2 /Type /Pages >> endobj
+3 0 obj << /Type /Page /Parent 2 0 R >> endobj
+4 0 obj << /Type /Page /Parent 2 0 R >> endobj
+trailer << /Root 1 0 R >>
+%%EOF`)
+
+	pdfRes, err := repository.PDFProcessor{}.Process(ctx,
+		repository.AttachmentData{
+			ContentType: "application/pdf",
+			Data:        pdf,
+		})
+	test.Must(t, err, "process PDF")
+
+	test.Equal(t, "2", pdfRes.Props["pages"], "get the page count")
+
+	_, err = repository.PDFProcessor{}.Process(ctx,
+		repository.AttachmentData{
+			ContentType: "application/pdf",
+			Data:        []byte("not a pdf"),
+		})
+	test.MustNot(t, err, "reject content that isn't a PDF")
+}
+
+func TestIntegrationAttachmentProcessing(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		RunEventlogBuilder: true,
+	})
+
+	claims := itest.Claims(t, "uploader",
+		"doc_read doc_write asset_upload eventlog_read")
+
+	client := tc.DocumentsClient(t, claims)
+	ext := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, claims)
+
+	data := testPNG(t, 640, 480)
+
+	up, err := client.CreateUpload(ctx, &rpc.CreateUploadRequest{
+		Name:        "image.png",
+		ContentType: "image/png",
+	})
+	test.Must(t, err, "create upload")
+
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodPut, up.Url, bytes.NewReader(data))
+	test.Must(t, err, "create upload request")
+
+	req.ContentLength = int64(len(data))
+
+	res, err := http.DefaultClient.Do(req)
+	test.Must(t, err, "make upload request")
+
+	_ = res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("error response from upload recipient: %s",
+			res.Status)
+	}
+
+	docUUID := uuid.NewString()
+	doc := baseDocument(docUUID, "article://test/"+docUUID)
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: doc,
+		AttachObjects: map[string]string{
+			"image": up.Id,
+		},
+	})
+	test.Must(t, err, "attach the image")
+
+	var details repository.GetAttachmentDetailsResponse
+
+	err = ext.Call(ctx, "GetAttachmentDetails",
+		repository.GetAttachmentDetailsRequest{
+			Documents:      []string{docUUID},
+			AttachmentName: "image",
+			DownloadLink:   true,
+		}, &details)
+	test.Must(t, err, "get attachment details")
+
+	test.Equal(t, 1, len(details.Attachments), "get the attachment")
+
+	attachment := details.Attachments[0]
+
+	test.Equal(t, "640", attachment.Derived["image.width"],
+		"record the image width")
+	test.Equal(t, "480", attachment.Derived["image.height"],
+		"record the image height")
+	test.Equal(t, 1, len(attachment.Renditions), "get the thumbnail")
+	test.Equal(t, "thumbnail", attachment.Renditions[0].Name,
+		"name the thumbnail rendition")
+
+	thumbRes, err := http.Get(attachment.Renditions[0].DownloadLink)
+	test.Must(t, err, "download the thumbnail")
+
+	defer thumbRes.Body.Close()
+
+	thumbConf, err := jpeg.DecodeConfig(thumbRes.Body)
+	test.Must(t, err, "decode the thumbnail")
+
+	test.Equal(t, 320, thumbConf.Width, "scale the thumbnail width")
+	test.Equal(t, 240, thumbConf.Height, "scale the thumbnail height")
+
+	deadline := time.After(5 * time.Second)
+
+	var (
+		after int64
+		meta  *repository.EventlogObjectMeta
+	)
+
+	for meta == nil {
+		var log repository.GetEventlogDetailsResponse
+
+		err := ext.Call(ctx, "GetEventlogDetails",
+			repository.GetEventlogDetailsRequest{
+				After: after,
+			}, &log)
+		test.Must(t, err, "read eventlog details")
+
+		for _, item := range log.Items {
+			after = item.Id
+
+			m, ok := item.AttachedObjectMeta["image"]
+			if item.Uuid == docUUID && ok {
+				meta = &m
+			}
+		}
+
+		select {
+		case <-deadline:
+			t.Fatal("timed out waiting for the attach event")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	test.Equal(t, "640", meta.Derived["image.width"],
+		"include the image width in the event")
+	test.Equal(t, 1, len(meta.Renditions),
+		"include the thumbnail in the event")
+}
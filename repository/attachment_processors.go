@@ -0,0 +1,484 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+
+	// Register the image formats that the processors can decode.
+	_ "image/gif"
+	_ "image/png"
+)
+
+// DefaultThumbnailSize is the default maximum width and height of thumbnails.
+const DefaultThumbnailSize = 320
+
+// maxDecodedPixels is the largest image that we will decode to create a
+// thumbnail, this protects against decompression bombs.
+const maxDecodedPixels = 50_000_000
+
+// decodableImage checks if the content type is an image format that we can
+// decode.
+func decodableImage(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch mediaType {
+	case "image/png", "image/jpeg", "image/gif":
+		return true
+	}
+
+	return false
+}
+
+// ImageProcessor records the dimensions and format of images.
+type ImageProcessor struct{}
+
+// Name implements AttachmentProcessor.
+func (ImageProcessor) Name() string {
+	return "image"
+}
+
+// Accepts implements AttachmentProcessor.
+func (ImageProcessor) Accepts(contentType string) bool {
+	return decodableImage(contentType)
+}
+
+// Process implements AttachmentProcessor.
+func (ImageProcessor) Process(
+	_ context.Context, data AttachmentData,
+) (*AttachmentProcessingResult, error) {
+	conf, format, err := image.DecodeConfig(bytes.NewReader(data.Data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image config: %w", err)
+	}
+
+	return &AttachmentProcessingResult{
+		Props: map[string]string{
+			"width":  strconv.Itoa(conf.Width),
+			"height": strconv.Itoa(conf.Height),
+			"format": format,
+		},
+	}, nil
+}
+
+// ExifProcessor extracts a subset of the EXIF metadata from JPEG images.
+type ExifProcessor struct{}
+
+// Name implements AttachmentProcessor.
+func (ExifProcessor) Name() string {
+	return "exif"
+}
+
+// Accepts implements AttachmentProcessor.
+func (ExifProcessor) Accepts(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	return err == nil && mediaType == "image/jpeg"
+}
+
+// Process implements AttachmentProcessor.
+func (ExifProcessor) Process(
+	_ context.Context, data AttachmentData,
+) (*AttachmentProcessingResult, error) {
+	tiff, err := jpegExifSegment(data.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	if tiff == nil {
+		return nil, nil
+	}
+
+	props, err := parseExif(tiff)
+	if err != nil {
+		return nil, fmt.Errorf("parse EXIF data: %w", err)
+	}
+
+	return &AttachmentProcessingResult{
+		Props: props,
+	}, nil
+}
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// jpegExifSegment returns the TIFF structure from the EXIF APP1 segment of a
+// JPEG image, or nil if the image has no EXIF data.
+func jpegExifSegment(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errors.New("not a JPEG image")
+	}
+
+	pos := 2
+
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil, fmt.Errorf("invalid JPEG marker at offset %d", pos)
+		}
+
+		marker := data[pos+1]
+
+		// Start of scan or end of image, there are no more metadata
+		// segments.
+		if marker == 0xDA || marker == 0xD9 {
+			return nil, nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return nil, fmt.Errorf("invalid JPEG segment length at offset %d", pos)
+		}
+
+		segment := data[pos+4 : pos+2+length]
+
+		if marker == 0xE1 && bytes.HasPrefix(segment, exifHeader) {
+			return segment[len(exifHeader):], nil
+		}
+
+		pos += 2 + length
+	}
+
+	return nil, nil
+}
+
+// EXIF tags that we extract.
+var exifTags = map[uint16]string{
+	0x010F: "make",
+	0x0110: "model",
+	0x0112: "orientation",
+	0x0132: "datetime",
+	0x013B: "artist",
+	0x8298: "copyright",
+	0x9003: "datetime_original",
+}
+
+const (
+	exifIFDPointer = 0x8769
+
+	tiffTypeASCII = 2
+	tiffTypeShort = 3
+	tiffTypeLong  = 4
+)
+
+// parseExif extracts the tags listed in exifTags from IFD0 and the EXIF IFD of
+// a TIFF structure.
+func parseExif(tiff []byte) (map[string]string, error) {
+	if len(tiff) < 8 {
+		return nil, errors.New("truncated TIFF header")
+	}
+
+	var order binary.ByteOrder
+
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid TIFF byte order")
+	}
+
+	if order.Uint16(tiff[2:]) != 42 {
+		return nil, errors.New("invalid TIFF magic number")
+	}
+
+	props := make(map[string]string)
+
+	exifOffset, err := parseIFD(tiff, order, order.Uint32(tiff[4:]), props)
+	if err != nil {
+		return nil, fmt.Errorf("read IFD0: %w", err)
+	}
+
+	if exifOffset != 0 {
+		_, err := parseIFD(tiff, order, exifOffset, props)
+		if err != nil {
+			return nil, fmt.Errorf("read EXIF IFD: %w", err)
+		}
+	}
+
+	return props, nil
+}
+
+// parseIFD reads the known tags of an IFD into props and returns the offset of
+// the EXIF IFD if the IFD has a pointer to it.
+func parseIFD(
+	tiff []byte, order binary.ByteOrder, offset uint32,
+	props map[string]string,
+) (uint32, error) {
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return 0, errors.New("IFD offset out of bounds")
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	start := int(offset) + 2
+
+	if start+count*12 > len(tiff) {
+		return 0, errors.New("truncated IFD")
+	}
+
+	var exifOffset uint32
+
+	for i := range count {
+		entry := tiff[start+i*12 : start+(i+1)*12]
+
+		tag := order.Uint16(entry[0:])
+		typ := order.Uint16(entry[2:])
+		n := order.Uint32(entry[4:])
+		value := entry[8:12]
+
+		if tag == exifIFDPointer && typ == tiffTypeLong {
+			exifOffset = order.Uint32(value)
+
+			continue
+		}
+
+		name, ok := exifTags[tag]
+		if !ok {
+			continue
+		}
+
+		switch typ {
+		case tiffTypeShort:
+			props[name] = strconv.Itoa(int(order.Uint16(value)))
+		case tiffTypeLong:
+			props[name] = strconv.FormatUint(
+				uint64(order.Uint32(value)), 10)
+		case tiffTypeASCII:
+			raw := value
+
+			if n > 4 {
+				off := uint64(order.Uint32(value))
+				if off+uint64(n) > uint64(len(tiff)) {
+					return 0, fmt.Errorf(
+						"value of tag %#x out of bounds", tag)
+				}
+
+				raw = tiff[off : off+uint64(n)]
+			} else {
+				raw = raw[:n]
+			}
+
+			s := strings.TrimSpace(strings.TrimRight(string(raw), "\x00"))
+			if s != "" {
+				props[name] = s
+			}
+		}
+	}
+
+	return exifOffset, nil
+}
+
+// PDFProcessor records the page count of PDF documents.
+type PDFProcessor struct{}
+
+// Name implements AttachmentProcessor.
+func (PDFProcessor) Name() string {
+	return "pdf"
+}
+
+// Accepts implements AttachmentProcessor.
+func (PDFProcessor) Accepts(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	return err == nil && mediaType == "application/pdf"
+}
+
+var (
+	pdfObjectExp = regexp.MustCompile(`(?s)\bobj\b(.*?)\bendobj\b`)
+	pdfPagesExp  = regexp.MustCompile(`/Type\s*/Pages\b`)
+	pdfCountExp  = regexp.MustCompile(`/Count\s+(\d+)`)
+	pdfPageExp   = regexp.MustCompile(`/Type\s*/Page\b`)
+)
+
+// Process implements AttachmentProcessor.
+func (PDFProcessor) Process(
+	_ context.Context, data AttachmentData,
+) (*AttachmentProcessingResult, error) {
+	if !bytes.HasPrefix(data.Data, []byte("%PDF-")) {
+		return nil, errors.New("not a PDF document")
+	}
+
+	pages, ok := pdfPageCount(data.Data)
+	if !ok {
+		return nil, errors.New("could not determine the page count")
+	}
+
+	return &AttachmentProcessingResult{
+		Props: map[string]string{
+			"pages": strconv.Itoa(pages),
+		},
+	}, nil
+}
+
+// pdfPageCount reads the page count from the root page tree node, that's the
+// pages node with the highest count. Falls back to counting page objects. This
+// will not work for documents that keep their page tree in compressed object
+// streams.
+func pdfPageCount(data []byte) (int, bool) {
+	var count int
+
+	for _, m := range pdfObjectExp.FindAllSubmatch(data, -1) {
+		if !pdfPagesExp.Match(m[1]) {
+			continue
+		}
+
+		c := pdfCountExp.FindSubmatch(m[1])
+		if c == nil {
+			continue
+		}
+
+		n, err := strconv.Atoi(string(c[1]))
+		if err == nil && n > count {
+			count = n
+		}
+	}
+
+	if count > 0 {
+		return count, true
+	}
+
+	count = len(pdfPageExp.FindAll(data, -1))
+
+	return count, count > 0
+}
+
+// ThumbnailProcessor creates a JPEG thumbnail rendition of images.
+type ThumbnailProcessor struct {
+	// MaxSize is the maximum width and height of the thumbnail.
+	MaxSize int
+}
+
+// Name implements AttachmentProcessor.
+func (ThumbnailProcessor) Name() string {
+	return "thumbnail"
+}
+
+// Accepts implements AttachmentProcessor.
+func (ThumbnailProcessor) Accepts(contentType string) bool {
+	return decodableImage(contentType)
+}
+
+// Process implements AttachmentProcessor.
+func (p ThumbnailProcessor) Process(
+	_ context.Context, data AttachmentData,
+) (*AttachmentProcessingResult, error) {
+	maxSize := p.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultThumbnailSize
+	}
+
+	conf, _, err := image.DecodeConfig(bytes.NewReader(data.Data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image config: %w", err)
+	}
+
+	if conf.Width*conf.Height > maxDecodedPixels {
+		return nil, fmt.Errorf("image is too large to decode: %dx%d",
+			conf.Width, conf.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data.Data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+
+	thumb := scaleToFit(img, maxSize)
+
+	var buf bytes.Buffer
+
+	err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80})
+	if err != nil {
+		return nil, fmt.Errorf("encode thumbnail: %w", err)
+	}
+
+	bounds := thumb.Bounds()
+
+	return &AttachmentProcessingResult{
+		Props: map[string]string{
+			"width":  strconv.Itoa(bounds.Dx()),
+			"height": strconv.Itoa(bounds.Dy()),
+		},
+		Renditions: []Rendition{
+			{
+				Name:        "thumbnail",
+				ContentType: "image/jpeg",
+				Data:        buf.Bytes(),
+			},
+		},
+	}, nil
+}
+
+// scaleToFit flattens the image onto a white background and downscales it,
+// using box averaging, so that it fits within maxSize x maxSize. Images are
+// never upscaled.
+func scaleToFit(img image.Image, maxSize int) *image.RGBA {
+	sb := img.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+
+	src := image.NewRGBA(image.Rect(0, 0, sw, sh))
+
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, sb.Min, draw.Over)
+
+	dw, dh := sw, sh
+
+	if sw > maxSize || sh > maxSize {
+		if sw >= sh {
+			dw = maxSize
+			dh = max(1, sh*maxSize/sw)
+		} else {
+			dh = maxSize
+			dw = max(1, sw*maxSize/sh)
+		}
+	}
+
+	if dw == sw && dh == sh {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := range dh {
+		y0 := y * sh / dh
+		y1 := max(y0+1, (y+1)*sh/dh)
+
+		for x := range dw {
+			x0 := x * sw / dw
+			x1 := max(x0+1, (x+1)*sw/dw)
+
+			var r, g, b, n uint64
+
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(row[sx*4])
+					g += uint64(row[sx*4+1])
+					b += uint64(row[sx*4+2])
+					n++
+				}
+			}
+
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n),
+				G: uint8(g / n),
+				B: uint8(b / n),
+				A: 0xFF,
+			})
+		}
+	}
+
+	return dst
+}
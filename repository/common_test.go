@@ -46,6 +46,9 @@ type TestContext struct {
 	Schemas          rpc.Schemas
 	Workflows        rpc.Workflows
 	Env              itest.Environment
+	DB               *pgxpool.Pool
+	Assets           *repository.AssetBucket
+	Types            *repository.TypeConfigurations
 }
 
 func (tc *TestContext) SSEConnect(
@@ -169,6 +172,21 @@ func (tc *TestContext) MetricsClient(
 	return metricsClient
 }
 
+func (tc *TestContext) ExtensionClient(
+	t *testing.T, pathPrefix string, claims elephantine.JWTClaims,
+) *repository.ExtensionClient {
+	t.Helper()
+
+	token, err := itest.AccessToken(tc.SigningKey, claims)
+	test.Must(t, err, "create access token")
+
+	return repository.NewExtensionClient(
+		tc.client, tc.Server.URL, pathPrefix,
+		http.Header{
+			"Authorization": []string{bearerPrefix + token},
+		})
+}
+
 type testingServerOptions struct {
 	RunArchiver        bool
 	RunEventlogBuilder bool
@@ -179,9 +197,21 @@ type testingServerOptions struct {
 	NoCoreSchemas      bool
 	EmitWorkflowEvent  bool
 	EmitACLEvent       bool
+	RunACLExpiry       bool
+	RunACLInheritance  bool
+	RunRevalidation    bool
+	RunDeprecationScan bool
+	RunSchemaUpgrade   bool
+	RunExemplarCollect bool
+	TransformOnRead    bool
 	// EventlogStream overrides the eventlog stream config for the socket
 	// handler. A zero BufferSize defaults to 500.
 	EventlogStream repository.EventlogStreamConfig
+	// DocumentSets overrides the document set config for the socket
+	// handler. A zero BufferSize defaults to 128.
+	DocumentSets repository.DocumentSetConfig
+	// MaxProxiedUploadSize overrides the size limit of the upload proxy.
+	MaxProxiedUploadSize int64
 }
 
 func testingAPIServer(
@@ -230,11 +260,33 @@ func testingAPIServer(
 			TypeConfigurations: typeConf,
 			EmitWorkflowEvent:  opts.EmitWorkflowEvent,
 			EmitACLEvent:       opts.EmitACLEvent,
+
+			AttachmentProcessors: repository.DefaultAttachmentProcessors(),
 		})
 	test.Must(t, err, "create doc store")
 
 	go store.RunListener(ctx, dbpool)
 
+	if opts.RunACLExpiry {
+		go store.RunACLExpiry(ctx, 200*time.Millisecond)
+	}
+
+	if opts.RunACLInheritance {
+		go store.RunACLInheritance(ctx, 200*time.Millisecond)
+	}
+
+	if opts.RunRevalidation {
+		go store.RunRevalidation(ctx, 200*time.Millisecond)
+	}
+
+	if opts.RunDeprecationScan {
+		go store.RunDeprecationUsageScan(ctx, 200*time.Millisecond)
+	}
+
+	if opts.RunExemplarCollect {
+		go store.RunExemplarCollection(ctx, 200*time.Millisecond)
+	}
+
 	go func() {
 		err := typeConf.Run(ctx, store)
 		test.Must(t, err, "run type configurations")
@@ -309,6 +361,10 @@ func testingAPIServer(
 
 	t.Cleanup(validator.Stop)
 
+	if opts.RunSchemaUpgrade {
+		go store.RunSchemaUpgrades(ctx, 200*time.Millisecond, validator)
+	}
+
 	workflows, err := repository.NewWorkflows(ctx, logger, store)
 	test.Must(t, err, "create workflows")
 
@@ -326,13 +382,16 @@ func testingAPIServer(
 		"sv-se",
 		typeConf,
 		docCache,
+		store,
 		socketKey,
+		opts.TransformOnRead,
 	)
 	test.Must(t, err, "create documents service")
 
-	schemaService := repository.NewSchemasService(logger, store)
+	schemaService := repository.NewSchemasService(logger, store, nil)
 	workflowService := repository.NewWorkflowsService(store)
 	metricsService := repository.NewMetricsService(store)
+	templatesService := repository.NewTemplatesService(store, validator)
 
 	router := httprouter.New()
 
@@ -356,16 +415,25 @@ func testingAPIServer(
 		ctx, logger, reg,
 		store, docCache, authParser, &socketKey.PublicKey,
 		[]string{"localhost", "example.ecms.se"},
-		opts.EventlogStream)
+		opts.EventlogStream, opts.DocumentSets)
 	test.Must(t, err, "set up socket handler")
 
+	assetProxy := repository.NewAssetProxy(repository.AssetProxyOptions{
+		Logger:        logger,
+		Documents:     docService,
+		Assets:        assetBucket,
+		MaxUploadSize: opts.MaxProxiedUploadSize,
+	})
+
 	err = repository.SetUpRouter(router,
 		repository.WithDocumentsAPI(docService, srvOpts),
 		repository.WithSchemasAPI(schemaService, srvOpts),
+		repository.WithTemplatesAPI(templatesService, srvOpts),
 		repository.WithWorkflowsAPI(workflowService, srvOpts),
 		repository.WithMetricsAPI(metricsService, srvOpts),
 		repository.WithSSE(sse.HTTPHandler(), srvOpts),
-		repository.WithWebsocket(socket),
+		repository.WithWebsocket(socket, srvOpts),
+		repository.WithAssetProxy(assetProxy, srvOpts),
 	)
 	test.Must(t, err, "set up router")
 
@@ -387,6 +455,9 @@ func testingAPIServer(
 		Schemas:          schemaService,
 		WorkflowProvider: workflows,
 		Env:              env,
+		DB:               dbpool,
+		Assets:           assetBucket,
+		Types:            typeConf,
 	}
 
 	wf := tc.WorkflowsClient(t,
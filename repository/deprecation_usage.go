@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/pg"
+	"github.com/ttab/newsdoc"
+	"github.com/ttab/revisor"
+)
+
+const deprecationScanBatchSize = 200
+
+// errDeprecationScanReplaced is returned when a new scan has been started
+// while a batch was being processed.
+var errDeprecationScanReplaced = errors.New("deprecation usage scan has been replaced")
+
+// updateDeprecationUsage replaces the deprecation labels recorded for a
+// document with the labels encountered when validating its new version.
+func updateDeprecationUsage(
+	ctx context.Context, q *postgres.Queries,
+	docUUID uuid.UUID, version int64, labels []string,
+) error {
+	err := q.DropDeprecationUsage(ctx, docUUID)
+	if err != nil {
+		return fmt.Errorf("clear current usage: %w", err)
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+
+	err = q.InsertDeprecationUsage(ctx, postgres.InsertDeprecationUsageParams{
+		UUID:    docUUID,
+		Version: version,
+		Labels:  labels,
+	})
+	if err != nil {
+		return fmt.Errorf("insert usage: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeprecationUsage implements SchemaStore.
+func (s *PGDocStore) GetDeprecationUsage(
+	ctx context.Context,
+) ([]DeprecationUsage, error) {
+	rows, err := s.reader.GetDeprecationUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	res := make([]DeprecationUsage, len(rows))
+
+	for i, row := range rows {
+		res[i] = DeprecationUsage{
+			Label:     row.Label,
+			Enforced:  row.Enforced,
+			Documents: row.Documents,
+		}
+	}
+
+	return res, nil
+}
+
+// GetDeprecatedDocuments implements SchemaStore.
+func (s *PGDocStore) GetDeprecatedDocuments(
+	ctx context.Context, query DeprecatedDocumentQuery,
+) ([]DeprecatedDocument, error) {
+	rows, err := s.reader.GetDeprecatedDocuments(ctx,
+		postgres.GetDeprecatedDocumentsParams{
+			Label:    query.Label,
+			After:    query.After,
+			RowLimit: query.Limit,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	res := make([]DeprecatedDocument, len(rows))
+
+	for i, row := range rows {
+		res[i] = DeprecatedDocument{
+			UUID:    row.UUID,
+			Type:    row.Type,
+			Version: row.Version,
+		}
+	}
+
+	return res, nil
+}
+
+// startDeprecationUsageScan replaces any running scan with a scan of the
+// current versions of all documents against the schemas of a generation.
+func startDeprecationUsageScan(
+	ctx context.Context, q *postgres.Queries, generationID int64, now time.Time,
+) error {
+	err := q.ClearDeprecationUsageScans(ctx)
+	if err != nil {
+		return fmt.Errorf("clear previous scans: %w", err)
+	}
+
+	err = q.StartDeprecationUsageScan(ctx, postgres.StartDeprecationUsageScanParams{
+		GenerationID: generationID,
+		Created:      pg.Time(now),
+	})
+	if err != nil {
+		return fmt.Errorf("start scan: %w", err)
+	}
+
+	return nil
+}
+
+// RunDeprecationUsageScan records the deprecation usage of documents that
+// haven't been written since their schemas changed. A scan is started when a
+// generation is activated.
+func (s *PGDocStore) RunDeprecationUsageScan(
+	ctx context.Context, period time.Duration,
+) {
+	for {
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return
+		}
+
+		jobLock, err := pg.NewJobLock(s.pool, s.logger, "deprecation-usage-scan", pg.JobLockOptions{
+			PingInterval:  10 * time.Second,
+			StaleAfter:    1 * time.Minute,
+			CheckInterval: 20 * time.Second,
+			Timeout:       5 * time.Second,
+		})
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to create job lock",
+				elephantine.LogKeyError, err)
+
+			continue
+		}
+
+		err = jobLock.RunWithContext(ctx, s.processDeprecationUsageScan)
+		if err != nil {
+			s.logger.ErrorContext(
+				ctx, "deprecation usage scan error",
+				elephantine.LogKeyError, err,
+			)
+		}
+	}
+}
+
+func (s *PGDocStore) processDeprecationUsageScan(ctx context.Context) error {
+	for {
+		scan, err := s.reader.GetDeprecationUsageScan(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("get pending scan: %w", err)
+		}
+
+		val, err := s.generationValidator(ctx, scan.GenerationID)
+		if err != nil {
+			return fmt.Errorf("create validator for generation %d: %w",
+				scan.GenerationID, err)
+		}
+
+		// Documents are transformed on write, so record the usage of
+		// their transformed shape.
+		transforms, err := s.GetGenerationTransforms(ctx, scan.GenerationID)
+		if err != nil {
+			return fmt.Errorf("get generation transforms: %w", err)
+		}
+
+		for {
+			done, err := s.scanDeprecationUsageBatch(
+				ctx, &scan, val, transforms)
+			if errors.Is(err, errDeprecationScanReplaced) {
+				break
+			} else if err != nil {
+				return fmt.Errorf("scan documents for generation %d: %w",
+					scan.GenerationID, err)
+			}
+
+			if done {
+				s.logger.InfoContext(ctx,
+					"scanned documents for deprecation usage",
+					LogKeyGenerationID, scan.GenerationID)
+
+				break
+			}
+		}
+	}
+}
+
+func (s *PGDocStore) scanDeprecationUsageBatch(
+	ctx context.Context, scan *postgres.DeprecationUsageScan,
+	val *revisor.Validator, transforms []DocumentTransform,
+) (bool, error) {
+	var done bool
+
+	err := pg.WithTX(ctx, s.pool, func(tx pgx.Tx) error {
+		q := postgres.New(tx)
+
+		docs, err := q.GetDocumentsForDeprecationScan(ctx,
+			postgres.GetDocumentsForDeprecationScanParams{
+				After:    scan.Position,
+				RowLimit: deprecationScanBatchSize,
+			})
+		if err != nil {
+			return fmt.Errorf("get documents: %w", err)
+		}
+
+		for _, d := range docs {
+			if d.DocumentData == nil {
+				continue
+			}
+
+			var doc newsdoc.Document
+
+			err := json.Unmarshal(d.DocumentData, &doc)
+			if err != nil {
+				return fmt.Errorf(
+					"unmarshal document %s: %w", d.UUID, err)
+			}
+
+			doc, _ = ApplyTransforms(doc, transforms)
+
+			valCtx, deprecations := WithDeprecationCollector(ctx)
+
+			_, err = val.ValidateDocument(valCtx, &doc,
+				revisor.WithDeprecationHandler(collectDeprecation))
+			if err != nil {
+				return fmt.Errorf(
+					"validate document %s: %w", d.UUID, err)
+			}
+
+			err = updateDeprecationUsage(ctx, q, d.UUID,
+				d.CurrentVersion, deprecations.Labels())
+			if err != nil {
+				return fmt.Errorf(
+					"update usage for %s: %w", d.UUID, err)
+			}
+		}
+
+		var n int64
+
+		if len(docs) < deprecationScanBatchSize {
+			done = true
+
+			n, err = q.FinishDeprecationUsageScan(ctx,
+				postgres.FinishDeprecationUsageScanParams{
+					GenerationID: scan.GenerationID,
+					Created:      scan.Created,
+				})
+		} else {
+			scan.Position = docs[len(docs)-1].UUID
+
+			n, err = q.SetDeprecationUsageScanPosition(ctx,
+				postgres.SetDeprecationUsageScanPositionParams{
+					Position:     scan.Position,
+					GenerationID: scan.GenerationID,
+					Created:      scan.Created,
+				})
+		}
+
+		if err != nil {
+			return fmt.Errorf("update scan position: %w", err)
+		}
+
+		if n == 0 {
+			return errDeprecationScanReplaced
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return done, nil
+}
+
+// collectDeprecation is a deprecation handler that only records the
+// deprecation in the collector of the context, without enforcing it.
+func collectDeprecation(
+	ctx context.Context, _ *newsdoc.Document,
+	deprecation revisor.Deprecation, _ revisor.DeprecationContext,
+) (revisor.DeprecationDecision, error) {
+	if c, ok := ctx.Value(deprecationCollectorKey{}).(*DeprecationCollector); ok {
+		c.add(deprecation.Label)
+	}
+
+	return revisor.DeprecationDecision{}, nil
+}
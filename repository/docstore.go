@@ -71,6 +71,11 @@ type DocStore interface {
 	GetMetaTypeForDocument(
 		ctx context.Context, uuid uuid.UUID,
 	) (DocumentMetaType, error)
+	// GetMetaTypeForType returns the meta type for documents of a type
+	// that doesn't have to exist yet.
+	GetMetaTypeForType(
+		ctx context.Context, docType string,
+	) (DocumentMetaType, error)
 	RegisterMetaType(
 		ctx context.Context, metaType string, exclusive bool,
 	) error
@@ -117,6 +122,16 @@ type DocStore interface {
 	GetDocumentACL(
 		ctx context.Context, uuid uuid.UUID,
 	) ([]ACLEntry, error)
+	// GetEffectiveDocumentACL returns the document ACL merged with the
+	// grants that the document inherits from linked documents.
+	GetEffectiveDocumentACL(
+		ctx context.Context, uuid uuid.UUID,
+	) ([]ACLEntry, error)
+	// GetPermissionSources returns the information that permission
+	// checks for the grantees are based on.
+	GetPermissionSources(
+		ctx context.Context, uuid uuid.UUID, granteeURIs []string,
+	) (*PermissionSources, error)
 	Lock(
 		ctx context.Context, req LockRequest,
 	) (LockResult, error)
@@ -133,12 +148,42 @@ type DocStore interface {
 		ctx context.Context, uuids []uuid.UUID,
 	) ([]DeliverableInfo, error)
 	CreateUpload(ctx context.Context, upload Upload) error
+	GetUpload(ctx context.Context, id uuid.UUID) (*Upload, error)
+	// DeleteUpload removes an upload that won't be used.
+	DeleteUpload(ctx context.Context, id uuid.UUID) error
+	// SetUploadMultipartStatus changes the status of a multipart upload,
+	// fails with ErrCodeFailedPrecondition if the upload doesn't have the
+	// expected current status.
+	SetUploadMultipartStatus(
+		ctx context.Context, id uuid.UUID,
+		current MultipartStatus, status MultipartStatus,
+	) error
 	GetAttachments(
 		ctx context.Context,
 		documents []uuid.UUID,
 		attachment string,
 		getDownloadLink bool,
 	) ([]AttachmentDetails, error)
+	GetAttachedObjects(
+		ctx context.Context, document uuid.UUID,
+	) ([]AttachedObject, error)
+	// GetAttachedObjectsAtVersion returns the objects that were attached
+	// to a version of a document. Objects that currently are detached are
+	// left out, as the document version they were detached at isn't
+	// recorded.
+	GetAttachedObjectsAtVersion(
+		ctx context.Context, document uuid.UUID, version int64,
+	) ([]AttachedObject, error)
+	// GetAttachmentHistory returns all versions of an attached object,
+	// newest first.
+	GetAttachmentHistory(
+		ctx context.Context, document uuid.UUID, name string,
+	) (*AttachmentHistory, error)
+	// GetAttachmentVersion returns a specific version of an attached
+	// object.
+	GetAttachmentVersion(
+		ctx context.Context, document uuid.UUID, name string, version int64,
+	) (*AttachedObject, error)
 	ListDocumentsInTimeRange(
 		ctx context.Context,
 		docType string,
@@ -152,6 +197,22 @@ type DocStore interface {
 		labels []string,
 	) ([]DocumentItem, error)
 	EnsureSocketKey(ctx context.Context) (*ecdsa.PrivateKey, error)
+	// RecordReads adds the reads to the read audit log. Reads of
+	// documents with types that don't have read auditing enabled are
+	// ignored.
+	RecordReads(ctx context.Context, record ReadAuditRecord) error
+	GetReadAudit(
+		ctx context.Context, query ReadAuditQuery,
+	) ([]ReadAuditEntry, error)
+	// GetBacklinks returns the documents that link to a document.
+	GetBacklinks(
+		ctx context.Context, query BacklinkQuery,
+	) ([]Backlink, error)
+	// StartExport creates an export job that is processed by the archiver.
+	StartExport(
+		ctx context.Context, query ExportQuery, createdBy string,
+	) (*DocumentExport, error)
+	GetExport(ctx context.Context, id int64) (*DocumentExport, error)
 }
 
 type DocumentItem struct {
@@ -165,6 +226,15 @@ type TypeConfiguration struct {
 	TimeExpressions   []TimespanConfiguration
 	LabelExpressions  []LabelConfiguration
 	Variants          []string
+	// ReadAudit enables auditing of read access to documents of the
+	// type.
+	ReadAudit bool
+	// ExemplarSampling enables collection of exemplars from the stored
+	// documents of the type.
+	ExemplarSampling *ExemplarSampling
+	// ArchiveAttachments enables archiving of every version of the
+	// objects attached to documents of the type.
+	ArchiveAttachments bool
 }
 
 type DeliverableInfo struct {
@@ -180,8 +250,29 @@ type Upload struct {
 	CreatedBy string
 	CreatedAt time.Time
 	Meta      AssetMetadata
+	// MultipartID is the object store ID of a multipart upload, empty for
+	// single request uploads.
+	MultipartID string
+	// MultipartStatus is the status of a multipart upload, empty for
+	// single request uploads.
+	MultipartStatus MultipartStatus
+	// Renditions created by the attachment processors that should be
+	// stored together with the attached object.
+	Renditions []Rendition
+	// ETag of the upload object when it was verified. The upload isn't
+	// attached if the object has been replaced since.
+	ETag string
 }
 
+// MultipartStatus is the status of a multipart upload.
+type MultipartStatus string
+
+const (
+	MultipartStatusUploading MultipartStatus = "uploading"
+	MultipartStatusCompleted MultipartStatus = "completed"
+	MultipartStatusAborted   MultipartStatus = "aborted"
+)
+
 type AttachmentDetails struct {
 	Document     uuid.UUID
 	Name         string
@@ -189,12 +280,51 @@ type AttachmentDetails struct {
 	DownloadLink string
 	Filename     string
 	ContentType  string
+	// SniffedContentType is the content type that was detected when the
+	// upload was verified.
+	SniffedContentType string
+	SHA256             string
+	Size               int64
+	Derived            map[string]string
+	Renditions         []AttachmentRendition
+}
+
+// AttachmentHistory is the version history of an attached object.
+type AttachmentHistory struct {
+	// CurrentVersion is the currently attached version, zero if the
+	// object has been detached.
+	CurrentVersion int64
+	// Versions of the attached object, newest first.
+	Versions []AttachedObject
+}
+
+// AttachmentRendition is a rendition of an attached object.
+type AttachmentRendition struct {
+	Name         string
+	ContentType  string
+	Size         int64
+	DownloadLink string
 }
 
 type AssetMetadata struct {
 	Filename string            `json:"filename"`
 	Mimetype string            `json:"mimetype"`
 	Props    map[string]string `json:"props"`
+	// SniffedMimetype is the content type that was detected when the
+	// upload was verified.
+	SniffedMimetype string `json:"sniffed_mimetype,omitempty"`
+	// SHA256 is the hex encoded SHA-256 checksum of the object. For uploads
+	// it's the checksum declared by the client, for attached objects it's
+	// the verified checksum.
+	SHA256 string `json:"sha256,omitempty"`
+	// Size of the object in bytes. For uploads it's the size declared by
+	// the client, for attached objects it's the verified size.
+	Size int64 `json:"size,omitempty"`
+	// Derived metadata from the attachment processors, keyed as
+	// "<processor>.<key>".
+	Derived map[string]string `json:"derived,omitempty"`
+	// Renditions that have been created by the attachment processors.
+	Renditions []AssetRendition `json:"renditions,omitempty"`
 }
 
 type SchemaStore interface {
@@ -230,6 +360,12 @@ type SchemaStore interface {
 	UpdateDeprecation(
 		ctx context.Context, deprecation Deprecation,
 	) error
+	GetDeprecationUsage(
+		ctx context.Context,
+	) ([]DeprecationUsage, error)
+	GetDeprecatedDocuments(
+		ctx context.Context, query DeprecatedDocumentQuery,
+	) ([]DeprecatedDocument, error)
 	ConfigureType(
 		ctx context.Context,
 		docType string,
@@ -242,6 +378,15 @@ type SchemaStore interface {
 	GetTypeConfigurations(
 		ctx context.Context,
 	) (map[string]TypeConfiguration, error)
+	SetACLInheritance(
+		ctx context.Context,
+		docType string,
+		rules []ACLInheritanceRule,
+	) error
+	GetACLInheritance(
+		ctx context.Context,
+		docType string,
+	) ([]ACLInheritanceRule, error)
 
 	// Generation management.
 	RegisterGeneration(
@@ -249,6 +394,7 @@ type SchemaStore interface {
 	) (int64, error)
 	SetGenerationStatus(
 		ctx context.Context, id int64, activation SchemaGenerationStatus,
+		force bool,
 	) error
 	ListGenerations(
 		ctx context.Context, before int64,
@@ -257,10 +403,35 @@ type SchemaStore interface {
 		ctx context.Context, generationID int64, known map[string]string,
 	) ([]ExemplarRecord, error)
 	GetActiveGeneration(ctx context.Context) (*SchemaGeneration, error)
+	GetGenerationSnapshot(
+		ctx context.Context, generationID int64,
+	) (*GenerationSnapshot, error)
 	GetActiveGenerationID(ctx context.Context) (int64, error)
 	GetPendingGeneration(ctx context.Context) (*SchemaGeneration, error)
 	GetActiveGenerationSchemas(ctx context.Context) ([]*Schema, error)
 	GetPendingGenerationSchemas(ctx context.Context) ([]*Schema, error)
+	StartRevalidation(
+		ctx context.Context, req StartRevalidationStoreRequest,
+	) (*SchemaRevalidation, error)
+	GetRevalidation(
+		ctx context.Context, generationID int64,
+	) (*SchemaRevalidation, error)
+	GetRevalidationFailures(
+		ctx context.Context, query RevalidationFailureQuery,
+	) ([]RevalidationFailure, error)
+	SetGenerationTransforms(
+		ctx context.Context, generationID int64,
+		transforms []DocumentTransform, updatedBy string,
+	) error
+	GetGenerationTransforms(
+		ctx context.Context, generationID int64,
+	) ([]DocumentTransform, error)
+	StartUpgrade(
+		ctx context.Context, req StartUpgradeStoreRequest,
+	) (*SchemaUpgrade, error)
+	GetUpgrade(
+		ctx context.Context, generationID int64,
+	) (*SchemaUpgrade, error)
 }
 
 type WorkflowStore interface {
@@ -281,6 +452,19 @@ type WorkflowStore interface {
 	DeleteDocumentWorkflow(ctx context.Context, docType string) error
 }
 
+type TemplateStore interface {
+	SaveTemplate(
+		ctx context.Context, template DocumentTemplate,
+	) (int64, error)
+	GetTemplate(
+		ctx context.Context, name string, version int64,
+	) (*DocumentTemplate, error)
+	ListTemplates(
+		ctx context.Context, docType string,
+	) ([]DocumentTemplate, error)
+	DeleteTemplate(ctx context.Context, name string) error
+}
+
 type MetricStore interface {
 	RegisterMetricKind(
 		ctx context.Context, name string, aggregation Aggregation,
@@ -426,6 +610,91 @@ type SchemaGeneration struct {
 	Schemas     []SchemaReference
 }
 
+// SchemaJobStatus is the state of a background job that processes stored
+// documents for a schema generation.
+type SchemaJobStatus string
+
+const (
+	SchemaJobRunning   SchemaJobStatus = "running"
+	SchemaJobDone      SchemaJobStatus = "done"
+	SchemaJobFailed    SchemaJobStatus = "failed"
+	SchemaJobCancelled SchemaJobStatus = "cancelled"
+)
+
+// StartRevalidationStoreRequest starts a revalidation of the current
+// versions of documents against a pending schema generation.
+type StartRevalidationStoreRequest struct {
+	GenerationID int64
+	// Types to revalidate, defaults to the document types declared by
+	// the schemas of the generation.
+	Types     []string
+	CreatedBy string
+}
+
+// SchemaRevalidation describes the progress of a revalidation. Total is the
+// number of documents that matched when the revalidation was started, and
+// can drift from the number of processed documents as documents are
+// created and deleted.
+type SchemaRevalidation struct {
+	GenerationID int64           `json:"generation_id"`
+	Types        []string        `json:"types"`
+	Status       SchemaJobStatus `json:"status"`
+	Created      time.Time       `json:"created"`
+	CreatedBy    string          `json:"created_by"`
+	Finished     *time.Time      `json:"finished,omitempty"`
+	Total        int64           `json:"total"`
+	Processed    int64           `json:"processed"`
+	Failed       int64           `json:"failed"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// StartUpgradeStoreRequest starts an upgrade of the current versions of
+// documents using the transforms of the active schema generation.
+type StartUpgradeStoreRequest struct {
+	// Types to upgrade, defaults to the document types that the
+	// transforms of the generation apply to.
+	Types     []string
+	CreatedBy string
+}
+
+// SchemaUpgrade describes the progress of an upgrade of stored documents.
+type SchemaUpgrade struct {
+	GenerationID int64           `json:"generation_id"`
+	Types        []string        `json:"types"`
+	Status       SchemaJobStatus `json:"status"`
+	Created      time.Time       `json:"created"`
+	CreatedBy    string          `json:"created_by"`
+	Finished     *time.Time      `json:"finished,omitempty"`
+	Processed    int64           `json:"processed"`
+	Upgraded     int64           `json:"upgraded"`
+	Failed       int64           `json:"failed"`
+	Error        string          `json:"error,omitempty"`
+}
+
+type RevalidationFailureQuery struct {
+	GenerationID int64
+	After        uuid.UUID
+	Limit        int64
+}
+
+// RevalidationFailure is a document that failed validation against the
+// schema generation that it was revalidated against.
+type RevalidationFailure struct {
+	UUID    uuid.UUID `json:"uuid"`
+	Type    string    `json:"type"`
+	Version int64     `json:"version"`
+	Errors  []string  `json:"errors"`
+}
+
+// GenerationSnapshot is the schemas and exemplars of a schema generation.
+type GenerationSnapshot struct {
+	ID int64
+	// Archived is true if the generation was read from the archive.
+	Archived  bool
+	Schemas   []*Schema
+	Exemplars []ExemplarRecord
+}
+
 // SchemaReference identifies a schema by name and version.
 type SchemaReference struct {
 	Name    string
@@ -461,6 +730,9 @@ type RegisterGenerationStoreRequest struct {
 	Schemas    []RegisterGenerationSchema
 	Activation SchemaGenerationStatus
 	Exemplars  []ExemplarInput
+	// Force activates the generation without checking that the exemplars
+	// are valid.
+	Force bool
 }
 
 type MetaTypeInfo struct {
@@ -473,6 +745,24 @@ type Deprecation struct {
 	Enforced bool
 }
 
+type DeprecationUsage struct {
+	Label     string `json:"label"`
+	Enforced  bool   `json:"enforced"`
+	Documents int64  `json:"documents"`
+}
+
+type DeprecatedDocumentQuery struct {
+	Label string
+	After uuid.UUID
+	Limit int64
+}
+
+type DeprecatedDocument struct {
+	UUID    uuid.UUID `json:"uuid"`
+	Type    string    `json:"type"`
+	Version int64     `json:"version"`
+}
+
 type CheckPermissionRequest struct {
 	UUID        uuid.UUID
 	GranteeURIs []string
@@ -494,12 +784,189 @@ const (
 	PermissionCheckSystemLock
 )
 
+// PermissionSources is the information that a permission check for a
+// document is based on.
+type PermissionSources struct {
+	Type         string
+	MainDocument *uuid.UUID
+	SystemState  SystemState
+	Lock         Lock
+	Grants       []ACLGrant
+}
+
+// ACLGrantSource describes how an ACL entry applies to a document.
+type ACLGrantSource string
+
+const (
+	// ACLGrantDocument is an entry in the document ACL.
+	ACLGrantDocument ACLGrantSource = "document"
+	// ACLGrantMainDocument is an entry in the ACL of the main document of
+	// a meta document.
+	ACLGrantMainDocument ACLGrantSource = "main_document"
+	// ACLGrantInherited is an entry in the ACL of a linked document that
+	// the document inherits permissions from.
+	ACLGrantInherited ACLGrantSource = "inherited"
+)
+
+// ACLGrant is an ACL entry that applies to a document.
+type ACLGrant struct {
+	Source ACLGrantSource
+	// Document is the document that the entry belongs to.
+	Document uuid.UUID
+	// Inheritor is the document that inherits from Document, either the
+	// document itself or its main document. Only set for inherited
+	// grants.
+	Inheritor uuid.UUID
+	// Inheritable is the set of permissions that can be inherited from
+	// Document. Only set for inherited grants.
+	Inheritable []string
+	Entry       ACLEntry
+}
+
+// Permissions returns the permissions that the grant gives at the given time.
+func (g ACLGrant) Permissions(t time.Time) []string {
+	if !g.Entry.InEffect(t) {
+		return nil
+	}
+
+	if g.Source != ACLGrantInherited {
+		return g.Entry.Permissions
+	}
+
+	var perms []string
+
+	for _, p := range g.Entry.Permissions {
+		if slices.Contains(g.Inheritable, p) {
+			perms = append(perms, p)
+		}
+	}
+
+	return perms
+}
+
+// ReadAuditOperation is the kind of read that was audited.
+type ReadAuditOperation string
+
+const (
+	ReadAuditGet        ReadAuditOperation = "get"
+	ReadAuditBulkGet    ReadAuditOperation = "bulk_get"
+	ReadAuditAttachment ReadAuditOperation = "attachment"
+	ReadAuditSocket     ReadAuditOperation = "socket"
+)
+
+// ReadAuditRecord is a set of document reads performed by a client in a
+// single operation.
+type ReadAuditRecord struct {
+	Operation ReadAuditOperation
+	Subject   string
+	App       string
+	ClientIP  string
+	Created   time.Time
+	Reads     []DocumentRead
+}
+
+// DocumentRead is a read of a document version, or of a document
+// attachment.
+type DocumentRead struct {
+	UUID       uuid.UUID
+	Version    int64
+	Attachment string
+}
+
+type ReadAuditQuery struct {
+	UUID    *uuid.UUID
+	Subject string
+	After   int64
+	Limit   int64
+}
+
+type ReadAuditEntry struct {
+	ID         int64              `json:"id"`
+	UUID       uuid.UUID          `json:"uuid"`
+	Type       string             `json:"type"`
+	Version    int64              `json:"version"`
+	Operation  ReadAuditOperation `json:"operation"`
+	Attachment string             `json:"attachment,omitempty"`
+	Subject    string             `json:"subject"`
+	App        string             `json:"app,omitempty"`
+	ClientIP   string             `json:"client_ip,omitempty"`
+	Created    time.Time          `json:"created"`
+}
+
+// ExportQuery selects the documents to export. The criteria are combined, and
+// a timespan can only be used together with a type.
+type ExportQuery struct {
+	UUIDs  []uuid.UUID `json:"uuids,omitempty"`
+	Type   string      `json:"type,omitempty"`
+	From   *time.Time  `json:"from,omitempty"`
+	To     *time.Time  `json:"to,omitempty"`
+	Labels []string    `json:"labels,omitempty"`
+}
+
+// ExportStatus is the state of a document export.
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportRunning ExportStatus = "running"
+	ExportDone    ExportStatus = "done"
+	ExportFailed  ExportStatus = "failed"
+)
+
+// DocumentExport describes the progress of an export. Total is the number of
+// documents that matched when the export was started. The finished bundle is
+// stored under ObjectKey in the archive bucket, and Signature is the archive
+// signature of the bundle.
+type DocumentExport struct {
+	ID        int64        `json:"id"`
+	Query     ExportQuery  `json:"query"`
+	Status    ExportStatus `json:"status"`
+	Created   time.Time    `json:"created"`
+	CreatedBy string       `json:"created_by"`
+	Started   *time.Time   `json:"started,omitempty"`
+	Finished  *time.Time   `json:"finished,omitempty"`
+	Total     int64        `json:"total"`
+	Processed int64        `json:"processed"`
+	ObjectKey string       `json:"object_key,omitempty"`
+	Size      int64        `json:"size,omitempty"`
+	Signature string       `json:"signature,omitempty"`
+	Error     string       `json:"error,omitempty"`
+}
+
+type BacklinkQuery struct {
+	// UUID of the document that is linked to.
+	UUID uuid.UUID
+	// Rel optionally restricts the query to links with the given rel.
+	Rel string
+	// Type optionally restricts the query to linking documents of the
+	// given type.
+	Type string
+	// After is the UUID of the last linking document of the previous
+	// page.
+	After uuid.UUID
+	Limit int64
+}
+
+// Backlink is a document that links to another document.
+type Backlink struct {
+	UUID    uuid.UUID `json:"uuid"`
+	Type    string    `json:"type"`
+	Version int64     `json:"version"`
+	// Rels are the rels of the links to the document.
+	Rels []string `json:"rels"`
+}
+
 type UpdateRequest struct {
-	UUID             uuid.UUID
-	Updated          time.Time
-	Updater          string
-	Meta             newsdoc.DataMap
-	ACL              []ACLEntry
+	UUID    uuid.UUID
+	Updated time.Time
+	Updater string
+	Meta    newsdoc.DataMap
+	ACL     []ACLEntry
+	// ACLTimeBounds is set when the time bounds of the ACL entries
+	// should replace the time bounds of existing entries. Otherwise
+	// existing entries keep their time bounds, as callers that can't
+	// express time bounds would make temporary grants permanent.
+	ACLTimeBounds    bool
 	DefaultACL       []ACLEntry
 	Status           []StatusUpdate
 	Document         *newsdoc.Document
@@ -511,6 +978,12 @@ type UpdateRequest struct {
 	AttachObjects    map[string]Upload
 	DetachObjects    []string
 	SchemaGeneration int64
+	// Deprecations are the labels of the deprecations that were
+	// encountered when the document was validated.
+	Deprecations []string
+	// NoWorkflow stores the update without progressing the workflow state
+	// of the document.
+	NoWorkflow bool
 }
 
 type DeleteRequest struct {
@@ -570,6 +1043,33 @@ type AttachmentRef struct {
 type ACLEntry struct {
 	URI         string   `json:"uri"`
 	Permissions []string `json:"permissions"`
+	// NotBefore is the time from which the entry grants access.
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	// Expires is the time at which the entry stops granting access and
+	// is removed from the ACL.
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// InEffect returns true if the entry grants access at the given time.
+func (e ACLEntry) InEffect(t time.Time) bool {
+	if e.NotBefore != nil && t.Before(*e.NotBefore) {
+		return false
+	}
+
+	if e.Expires != nil && !t.Before(*e.Expires) {
+		return false
+	}
+
+	return true
+}
+
+// ACLInheritanceRule declares that documents of a type inherit the given
+// permissions from the ACL of the documents they link to with Rel. LinkType
+// optionally restricts the rule to links of a specific type.
+type ACLInheritanceRule struct {
+	Rel         string   `json:"rel"`
+	LinkType    string   `json:"link_type,omitempty"`
+	Permissions []string `json:"permissions"`
 }
 
 type Lock struct {
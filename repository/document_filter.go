@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ttab/elephant-api/repository"
+	"github.com/ttab/newsdoc"
+)
+
+// Filter expressions that extract values from the document state instead of
+// from the document itself.
+const (
+	// FilterExpressionHeads extracts one item per status head with the
+	// values "name" and "current", where current is "true" if the head
+	// refers to the current version of the document.
+	FilterExpressionHeads = "heads"
+	// FilterExpressionWorkflow extracts the values "state" and
+	// "checkpoint" from the workflow state of the document.
+	FilterExpressionWorkflow = "workflow"
+)
+
+// maxFilterDepth is the maximum nesting depth of and/or filters.
+const maxFilterDepth = 8
+
+// documentFilter is a predicate on a document and its state.
+type documentFilter struct {
+	expression string
+	extractor  *newsdoc.ValueExtractor
+	operator   repository.FilterOperator
+	values     []map[string]string
+	and        []*documentFilter
+	or         []*documentFilter
+}
+
+func documentFilterFromRPC(f *repository.DocumentFilter) (*documentFilter, error) {
+	return buildDocumentFilter(f, 0)
+}
+
+func buildDocumentFilter(
+	f *repository.DocumentFilter, depth int,
+) (*documentFilter, error) {
+	if f == nil {
+		return nil, errors.New("a filter cannot be nil")
+	}
+
+	if depth > maxFilterDepth {
+		return nil, fmt.Errorf(
+			"filters cannot be nested more than %d levels deep",
+			maxFilterDepth)
+	}
+
+	df := documentFilter{
+		expression: f.Expression,
+		operator:   f.Operator,
+	}
+
+	switch f.Expression {
+	case "":
+		if f.Operator != repository.FilterOperator_FILTER_OP_UNKNOWN ||
+			len(f.Values) > 0 {
+			return nil, errors.New(
+				"an operator and values require an expression")
+		}
+
+		if len(f.And) == 0 && len(f.Or) == 0 {
+			return nil, errors.New(
+				"a filter must have an expression or and/or filters")
+		}
+	case FilterExpressionHeads, FilterExpressionWorkflow:
+	default:
+		ve, err := newsdoc.ValueExtractorFromString(f.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression %q: %w",
+				f.Expression, err)
+		}
+
+		df.extractor = ve
+	}
+
+	if f.Expression != "" {
+		switch f.Operator {
+		case repository.FilterOperator_FILTER_OP_ANY,
+			repository.FilterOperator_FILTER_OP_ALL,
+			repository.FilterOperator_FILTER_OP_NONE:
+		default:
+			return nil, fmt.Errorf("invalid operator for %q: %v",
+				f.Expression, f.Operator)
+		}
+	}
+
+	for _, v := range f.Values {
+		df.values = append(df.values, v.GetValues())
+	}
+
+	for i, sub := range f.And {
+		sf, err := buildDocumentFilter(sub, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("and.%d: %w", i, err)
+		}
+
+		df.and = append(df.and, sf)
+	}
+
+	for i, sub := range f.Or {
+		sf, err := buildDocumentFilter(sub, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("or.%d: %w", i, err)
+		}
+
+		df.or = append(df.or, sf)
+	}
+
+	return &df, nil
+}
+
+// Matches returns true if the document and its state matches the filter. A nil
+// filter matches all documents.
+func (df *documentFilter) Matches(doc newsdoc.Document, meta *DocumentMeta) bool {
+	if df == nil {
+		return true
+	}
+
+	if df.expression != "" && !df.matchValues(df.collect(doc, meta)) {
+		return false
+	}
+
+	for _, sub := range df.and {
+		if !sub.Matches(doc, meta) {
+			return false
+		}
+	}
+
+	if len(df.or) == 0 {
+		return true
+	}
+
+	for _, sub := range df.or {
+		if sub.Matches(doc, meta) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (df *documentFilter) collect(
+	doc newsdoc.Document, meta *DocumentMeta,
+) []map[string]string {
+	var items []map[string]string
+
+	switch df.expression {
+	case FilterExpressionHeads:
+		if meta == nil {
+			return nil
+		}
+
+		for name, head := range meta.Statuses {
+			items = append(items, map[string]string{
+				"name": name,
+				"current": strconv.FormatBool(
+					head.Version == meta.CurrentVersion),
+			})
+		}
+	case FilterExpressionWorkflow:
+		if meta == nil {
+			return nil
+		}
+
+		items = append(items, map[string]string{
+			"state":      meta.WorkflowState,
+			"checkpoint": meta.WorkflowCheckpoint,
+		})
+	default:
+		for _, extracted := range df.extractor.Collect(doc) {
+			item := make(map[string]string, len(extracted))
+
+			for k, v := range extracted {
+				item[k] = v.Value
+			}
+
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func (df *documentFilter) matchValues(items []map[string]string) bool {
+	// Without values the filter checks if the expression yielded anything
+	// at all.
+	if len(df.values) == 0 {
+		if df.operator == repository.FilterOperator_FILTER_OP_NONE {
+			return len(items) == 0
+		}
+
+		return len(items) > 0
+	}
+
+	for _, want := range df.values {
+		found := false
+
+		for _, item := range items {
+			if itemHasValues(item, want) {
+				found = true
+
+				break
+			}
+		}
+
+		switch {
+		case found && df.operator == repository.FilterOperator_FILTER_OP_ANY:
+			return true
+		case found && df.operator == repository.FilterOperator_FILTER_OP_NONE:
+			return false
+		case !found && df.operator == repository.FilterOperator_FILTER_OP_ALL:
+			return false
+		}
+	}
+
+	return df.operator != repository.FilterOperator_FILTER_OP_ANY
+}
+
+func itemHasValues(item map[string]string, want map[string]string) bool {
+	for k, v := range want {
+		got, ok := item[k]
+		if !ok || got != v {
+			return false
+		}
+	}
+
+	return true
+}
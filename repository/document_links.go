@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine/pg"
+	"github.com/ttab/newsdoc"
+)
+
+// updateDocumentLinks replaces the indexed links of a document with the links
+// of the given document version.
+func updateDocumentLinks(
+	ctx context.Context, q *postgres.Queries,
+	docUUID uuid.UUID, doc newsdoc.Document,
+) error {
+	err := q.DropDocumentLinks(ctx, docUUID)
+	if err != nil {
+		return fmt.Errorf("clear current links: %w", err)
+	}
+
+	params := postgres.InsertDocumentLinksParams{
+		UUID: docUUID,
+	}
+
+	for _, link := range doc.Links {
+		if link.UUID == "" {
+			continue
+		}
+
+		target, err := uuid.Parse(link.UUID)
+		if err != nil || target == docUUID {
+			continue
+		}
+
+		params.ToDocuments = append(params.ToDocuments, target)
+		params.Rels = append(params.Rels, link.Rel)
+		params.Types = append(params.Types, link.Type)
+	}
+
+	if len(params.ToDocuments) == 0 {
+		return nil
+	}
+
+	err = q.InsertDocumentLinks(ctx, params)
+	if err != nil {
+		return fmt.Errorf("insert links: %w", err)
+	}
+
+	return nil
+}
+
+// GetBacklinks implements DocStore.
+func (s *PGDocStore) GetBacklinks(
+	ctx context.Context, query BacklinkQuery,
+) ([]Backlink, error) {
+	rows, err := s.reader.GetBacklinks(ctx, postgres.GetBacklinksParams{
+		UUID:     query.UUID,
+		After:    query.After,
+		Rel:      pg.TextOrNull(query.Rel),
+		Type:     pg.TextOrNull(query.Type),
+		RowLimit: query.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	links := make([]Backlink, len(rows))
+
+	for i, row := range rows {
+		links[i] = Backlink{
+			UUID:    row.UUID,
+			Type:    row.Type,
+			Version: row.CurrentVersion,
+			Rels:    row.Rels,
+		}
+	}
+
+	return links, nil
+}
@@ -0,0 +1,158 @@
+package repository_test
+
+import (
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationBacklinks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+	ctx := t.Context()
+
+	tc := testingAPIServer(t, logger, testingServerOptions{})
+
+	plannerAClaims := itest.Claims(t, "planner-a", "doc_read doc_write")
+	plannerBClaims := itest.Claims(t, "planner-b", "doc_read doc_write")
+	adminClaims := itest.Claims(t, "admin", "doc_read_all")
+
+	plannerA := tc.DocumentsClient(t, plannerAClaims)
+	plannerB := tc.DocumentsClient(t, plannerBClaims)
+
+	plannerAExt := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, plannerAClaims)
+	adminExt := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, adminClaims)
+
+	eventUUID := uuid.NewString()
+
+	_, err := plannerA.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     eventUUID,
+		Document: baseDocument(eventUUID, "article://test/"+eventUUID),
+	})
+	test.Must(t, err, "create the linked document")
+
+	var (
+		planningA []string
+		planningB []string
+	)
+
+	for range 2 {
+		id := uuid.NewString()
+
+		_, err := plannerA.Update(ctx, &rpc.UpdateRequest{
+			Uuid:     id,
+			Document: basePlanningDocument(id, "", "", eventUUID),
+		})
+		test.Must(t, err, "create planning item for planner A")
+
+		planningA = append(planningA, id)
+	}
+
+	for range 2 {
+		id := uuid.NewString()
+
+		_, err := plannerB.Update(ctx, &rpc.UpdateRequest{
+			Uuid:     id,
+			Document: basePlanningDocument(id, "", "", eventUUID),
+		})
+		test.Must(t, err, "create planning item for planner B")
+
+		planningB = append(planningB, id)
+	}
+
+	err = tc.ExtensionClient(t, rpc.DocumentsPathPrefix, plannerBClaims).Call(
+		ctx, "GetBacklinks", repository.GetBacklinksRequest{
+			UUID: eventUUID,
+		}, &repository.GetBacklinksResponse{})
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	var res repository.GetBacklinksResponse
+
+	err = plannerAExt.Call(ctx, "GetBacklinks", repository.GetBacklinksRequest{
+		UUID: eventUUID,
+	}, &res)
+	test.Must(t, err, "get backlinks for planner A")
+
+	test.Equal(t, len(planningA), len(res.Backlinks),
+		"only get the backlinks planner A has access to")
+
+	for _, l := range res.Backlinks {
+		if !slices.Contains(planningA, l.UUID.String()) {
+			t.Fatalf("got backlink from %s that planner A doesn't have access to",
+				l.UUID)
+		}
+
+		test.Equal(t, "core/planning-item", l.Type, "get the linking document type")
+		test.EqualDiff(t, []string{"event"}, l.Rels, "get the link rels")
+	}
+
+	// Page through all backlinks one at a time.
+	var (
+		all    []string
+		cursor string
+	)
+
+	for {
+		var page repository.GetBacklinksResponse
+
+		err := adminExt.Call(ctx, "GetBacklinks", repository.GetBacklinksRequest{
+			UUID:  eventUUID,
+			Rel:   "event",
+			Type:  "core/planning-item",
+			After: cursor,
+			Limit: 1,
+		}, &page)
+		test.Must(t, err, "get page of backlinks")
+
+		for _, l := range page.Backlinks {
+			all = append(all, l.UUID.String())
+		}
+
+		if page.Next == "" {
+			break
+		}
+
+		cursor = page.Next
+	}
+
+	expected := append(slices.Clone(planningA), planningB...)
+
+	slices.Sort(expected)
+	slices.Sort(all)
+
+	test.EqualDiff(t, expected, all, "get all backlinks when paging")
+
+	err = adminExt.Call(ctx, "GetBacklinks", repository.GetBacklinksRequest{
+		UUID: eventUUID,
+		Rel:  "deliverable",
+	}, &res)
+	test.Must(t, err, "get backlinks with another rel")
+
+	test.Equal(t, 0, len(res.Backlinks), "get no backlinks with another rel")
+
+	// Removing the link should remove the backlink.
+	_, err = plannerA.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     planningA[0],
+		Document: basePlanningDocument(planningA[0], "", "", ""),
+	})
+	test.Must(t, err, "remove event link")
+
+	err = plannerAExt.Call(ctx, "GetBacklinks", repository.GetBacklinksRequest{
+		UUID: eventUUID,
+	}, &res)
+	test.Must(t, err, "get backlinks after removing link")
+
+	test.Equal(t, 1, len(res.Backlinks), "get the remaining backlink")
+	test.Equal(t, planningA[1], res.Backlinks[0].UUID.String(),
+		"get the remaining planning item")
+}
@@ -2,16 +2,26 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"iter"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	rpc_newsdoc "github.com/ttab/elephant-api/newsdoc"
+	"github.com/ttab/elephant-api/repository"
 	rsock "github.com/ttab/elephant-api/repositorysocket"
 	"github.com/ttab/newsdoc"
+	"github.com/twitchtv/twirp"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/proto"
 )
 
 type DocumentSetEmitter interface {
@@ -20,6 +30,10 @@ type DocumentSetEmitter interface {
 	InclusionBatch(ctx context.Context, msg *rsock.InclusionBatch)
 	Remove(ctx context.Context, msg *rsock.DocumentRemoved)
 	Error(ctx context.Context, msg *rsock.Error)
+	// Checkpoint is called when everything that has been emitted for the
+	// set reflects the state at the cursor. The cursor is empty if the
+	// position of the set is unknown.
+	Checkpoint(ctx context.Context, cursor string)
 }
 
 func newDocumentSet(
@@ -28,13 +42,17 @@ func newDocumentSet(
 	name string,
 	timespan *Timespan,
 	labels []string,
+	filter *documentFilter,
 	includeExtractors []*newsdoc.ValueExtractor,
 	subsetExtractors []*newsdoc.ValueExtractor,
 	inclusionSubsets map[string][]*newsdoc.ValueExtractor,
+	definition []byte,
 	identity []string,
 	cache *DocCache,
 	store DocStore,
 	emitter DocumentSetEmitter,
+	bufferSize int,
+	resyncs *prometheus.CounterVec,
 ) *documentSet {
 	slices.Sort(labels)
 
@@ -44,19 +62,24 @@ func newDocumentSet(
 		name:              name,
 		timespan:          timespan,
 		labels:            slices.Compact(labels),
+		filter:            filter,
 		set:               make(map[uuid.UUID]*setDocument),
 		included:          make(map[uuid.UUID]*incDocument),
 		includeExtractors: includeExtractors,
 		subsetExtractors:  subsetExtractors,
 		inclusionSubsets:  inclusionSubsets,
-		process:           make(chan DocumentStreamItem, 128),
-		oosErr:            make(chan struct{}),
-		identity:          identity,
+		process:           make(chan DocumentStreamItem, bufferSize),
+		resync:            make(chan struct{}, 1),
+		resyncs:           resyncs,
+		definition:        definition,
+		position:          -1,
 		cache:             cache,
 		store:             store,
 		emitter:           emitter,
 	}
 
+	ds.IdentityUpdated(identity)
+
 	return &ds
 }
 
@@ -67,9 +90,25 @@ type documentSet struct {
 	name       string
 	timespan   *Timespan
 	labels     []string
-
-	identMutex sync.RWMutex
-	identity   []string
+	filter     *documentFilter
+
+	// definition is a hash of the request that defined the set, it's
+	// combined with the identity into the fingerprint of set cursors.
+	definition []byte
+
+	identMutex  sync.RWMutex
+	identity    []string
+	fingerprint string
+
+	// position is the ID of the last event that is reflected in what has
+	// been emitted for the set, -1 if it's unknown.
+	position int64
+	// received is the ID of the last event that was received from the
+	// document stream.
+	received atomic.Int64
+	// unchecked is the number of processed events since the last
+	// checkpoint.
+	unchecked int
 
 	cache   *DocCache
 	store   DocStore
@@ -84,13 +123,19 @@ type documentSet struct {
 
 	process chan DocumentStreamItem
 
-	oosErr  chan struct{}
-	oosOnce sync.Once
+	// overflow is set when the processing buffer has overflowed, items
+	// are dropped until the set has been resynchronised.
+	overflow atomic.Bool
+	resync   chan struct{}
+	resyncs  *prometheus.CounterVec
 }
 
 func (ds *documentSet) IdentityUpdated(uris []string) {
+	fp := setFingerprint(ds.definition, uris)
+
 	ds.identMutex.Lock()
 	ds.identity = uris
+	ds.fingerprint = fp
 	ds.identMutex.Unlock()
 }
 
@@ -102,36 +147,122 @@ func (ds *documentSet) getIdentity() []string {
 	return identity
 }
 
+func (ds *documentSet) getFingerprint() string {
+	ds.identMutex.RLock()
+	fp := ds.fingerprint
+	ds.identMutex.RUnlock()
+
+	return fp
+}
+
+// checkpointBatchSize is the maximum number of events that are processed
+// between checkpoints while the processing buffer has queued events.
+const checkpointBatchSize = 100
+
+// advance moves the position of the set to a processed event. Checkpoints are
+// made after a batch of events, when the processing buffer has been drained
+// or checkpointBatchSize events have been processed, so that the emitter
+// doesn't have to flush a cursor for every event.
+func (ds *documentSet) advance(ctx context.Context, eventID int64) {
+	ds.position = eventID
+	ds.unchecked++
+
+	if len(ds.process) > 0 && ds.unchecked < checkpointBatchSize {
+		return
+	}
+
+	ds.checkpoint(ctx)
+}
+
+// checkpoint lets the emitter know that everything that has been emitted for
+// the set reflects the state at the current position.
+func (ds *documentSet) checkpoint(ctx context.Context) {
+	ds.unchecked = 0
+
+	var cursor string
+
+	if ds.position >= 0 {
+		cursor = setCursor{
+			Position:    ds.position,
+			Fingerprint: ds.getFingerprint(),
+		}.String()
+	}
+
+	ds.emitter.Checkpoint(ctx, cursor)
+}
+
 func (ds *documentSet) handleDocStreamItem(items []DocumentStreamItem) {
 	for _, item := range items {
+		ds.received.Store(item.Event.ID)
+
 		if item.Event.Event == TypeACLUpdate && !ds.includeACL {
 			continue
 		}
 
-		select {
-		case <-ds.oosErr:
+		if ds.overflow.Load() {
 			return
+		}
+
+		select {
 		case ds.process <- item:
 		default:
 			// If the processing buffer is full we will lose
 			// events and get out of sync with the actual state of
-			// things. This will cause the processing loop to emit
-			// an "oos" error and exit on the next iteration.
-			ds.oosOnce.Do(func() {
-				close(ds.oosErr)
-			})
+			// things. This will cause the processing loop to
+			// resynchronise the set against the current state of
+			// the documents.
+			if ds.overflow.CompareAndSwap(false, true) {
+				ds.resync <- struct{}{}
+			}
 
 			return
 		}
 	}
 }
 
+// Initialise subscribes to the document stream and emits the documents in the
+// set. If the client resumes the set from a cursor with a matching fingerprint
+// and the stream buffer covers the events after the cursor position only the
+// changes since the cursor are emitted, otherwise all documents in the set are
+// emitted.
 func (ds *documentSet) Initialise(
 	ctx context.Context,
 	stream *DocumentStream,
+	resume *setCursor,
 ) error {
-	stream.Subscribe(ctx, ds.handleDocStreamItem)
+	if resume != nil && resume.Fingerprint == ds.getFingerprint() {
+		var replay []DocumentStreamItem
+
+		ok := stream.SubscribeAfter(ctx, resume.Position,
+			func(items []DocumentStreamItem) {
+				replay = append(replay, items...)
+			}, ds.handleDocStreamItem)
+		if ok {
+			return ds.emitDelta(ctx, resume.Position, replay)
+		}
+	}
+
+	ds.position = stream.Subscribe(ctx, ds.handleDocStreamItem)
+
+	matches, err := ds.loadMatches(ctx)
+	if err != nil {
+		return err
+	}
 
+	err = ds.emitMatches(ctx, matches, nil)
+	if err != nil {
+		return err
+	}
+
+	ds.checkpoint(ctx)
+
+	return nil
+}
+
+// loadMatches loads the current state of the documents that match the set.
+func (ds *documentSet) loadMatches(
+	ctx context.Context,
+) (map[uuid.UUID]*DocumentStreamData, error) {
 	method := matchByType
 
 	if ds.timespan != nil {
@@ -145,7 +276,7 @@ func (ds *documentSet) Initialise(
 		hits, err := ds.store.ListDocumentsInTimeRange(
 			ctx, ds.docType, *ds.timespan, ds.labels)
 		if err != nil {
-			return fmt.Errorf(
+			return nil, fmt.Errorf(
 				"get documents for type and time range: %w", err)
 		}
 
@@ -154,13 +285,13 @@ func (ds *documentSet) Initialise(
 		hits, err := ds.store.ListDocumentsOfType(
 			ctx, ds.docType, nil, ds.labels)
 		if err != nil {
-			return fmt.Errorf(
+			return nil, fmt.Errorf(
 				"get documents by type and labels: %w", err)
 		}
 
 		items = hits
 	default:
-		return fmt.Errorf("unexpected match method: %#v", method)
+		return nil, fmt.Errorf("unexpected match method: %#v", method)
 	}
 
 	permReq := BulkCheckPermissionRequest{
@@ -178,11 +309,11 @@ func (ds *documentSet) Initialise(
 
 	allowedDocs, err := ds.store.BulkCheckPermissions(ctx, permReq)
 	if err != nil {
-		return fmt.Errorf("perform pernissions check: %w", err)
+		return nil, fmt.Errorf("perform pernissions check: %w", err)
 	}
 
 	if len(allowedDocs) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	getRefs := make([]BulkGetReference, len(allowedDocs))
@@ -229,11 +360,9 @@ func (ds *documentSet) Initialise(
 
 	err = grp.Wait()
 	if err != nil {
-		return fmt.Errorf("get match data: %w", err)
+		return nil, fmt.Errorf("get match data: %w", err)
 	}
 
-	// TODO: This is probably where we would apply filters.
-
 	for docID, m := range meta {
 		match := matches[docID]
 		if match == nil {
@@ -246,7 +375,7 @@ func (ds *documentSet) Initialise(
 	for doc := range documents {
 		docID, err := uuid.Parse(doc.Document.UUID)
 		if err != nil {
-			return fmt.Errorf(
+			return nil, fmt.Errorf(
 				"invalid document UUID: %w", err)
 		}
 
@@ -259,29 +388,41 @@ func (ds *documentSet) Initialise(
 		match.DocumentVersion = doc.Version
 	}
 
-	var (
-		batch       *rsock.DocumentBatch
-		inclChanges []inclusionChange
-	)
+	for docID, m := range matches {
+		if !ds.filter.Matches(m.Document, &m.Meta) {
+			delete(matches, docID)
+		}
+	}
 
-	batch = &rsock.DocumentBatch{}
+	return matches, nil
+}
+
+// emitMatches adds the matching documents to the set and emits them in
+// document batches. Documents that already are in the set with the same meta
+// information are skipped. Any previous inclusion changes will be handled
+// together with the first batch.
+func (ds *documentSet) emitMatches(
+	ctx context.Context,
+	matches map[uuid.UUID]*DocumentStreamData,
+	inclChanges []inclusionChange,
+) error {
+	batch := &rsock.DocumentBatch{}
 
 	for docUUID, m := range matches {
-		state := &rsock.DocumentState{
-			Uuid: docUUID.String(),
-			Meta: DocumentMetaToRPC(&m.Meta),
-		}
+		meta := DocumentMetaToRPC(&m.Meta)
 
-		if len(ds.subsetExtractors) > 0 {
-			state.Subset = collectSubset(m.Document, ds.subsetExtractors)
-		} else {
-			state.Document = rpc_newsdoc.DocumentToRPC(m.Document)
+		current := ds.set[docUUID]
+		if current != nil && proto.Equal(current.Meta, meta) {
+			continue
 		}
 
-		batch.Documents = append(batch.Documents, state)
+		batch.Documents = append(batch.Documents,
+			ds.documentState(docUUID, m.Document, meta))
 
 		incCh := ds.AddDocument(docUUID, m.Document)
 
+		ds.set[docUUID].Meta = meta
+
 		inclChanges = append(inclChanges, incCh...)
 
 		if len(batch.Documents) == 20 {
@@ -299,14 +440,339 @@ func (ds *documentSet) Initialise(
 	// so not checking len(Documents) before emit.
 	batch.FinalBatch = true
 
+	err := ds.emitBatch(ctx, batch, inclChanges)
+	if err != nil {
+		return fmt.Errorf("emit final document batch: %w", err)
+	}
+
+	return nil
+}
+
+// documentState creates the state of a set document for a document batch.
+func (ds *documentSet) documentState(
+	docUUID uuid.UUID,
+	doc newsdoc.Document,
+	meta *repository.DocumentMeta,
+) *rsock.DocumentState {
+	state := &rsock.DocumentState{
+		Uuid: docUUID.String(),
+		Meta: meta,
+	}
+
+	if len(ds.subsetExtractors) > 0 {
+		state.Subset = collectSubset(doc, ds.subsetExtractors)
+	} else {
+		state.Document = rpc_newsdoc.DocumentToRPC(doc)
+	}
+
+	return state
+}
+
+// resynchronise brings the set back in sync with the current state of the
+// documents after the processing buffer has overflowed. Documents that have
+// left the set are removed, and new or changed documents are emitted in
+// document batches. The state of all included documents is re-emitted, as
+// we can't know which of them changed.
+func (ds *documentSet) resynchronise(ctx context.Context) error {
+	// The current state of the documents will be loaded, so queued items
+	// can be discarded.
+	for len(ds.process) > 0 {
+		<-ds.process
+	}
+
+	ds.overflow.Store(false)
+
+	// All events that have been received so far will be reflected in the
+	// loaded state.
+	ds.position = ds.received.Load()
+
+	matches, err := ds.loadMatches(ctx)
+	if err != nil {
+		return err
+	}
+
+	var inclChanges []inclusionChange
+
+	for docUUID := range ds.set {
+		if matches[docUUID] != nil {
+			continue
+		}
+
+		inclChanges = append(inclChanges, ds.RemoveDocument(docUUID)...)
+
+		ds.emitRemove(ctx, docUUID)
+	}
+
+	removed := make(map[uuid.UUID]bool, len(inclChanges))
+
+	for _, change := range inclChanges {
+		removed[change.UUID] = true
+	}
+
+	for incUUID, inc := range ds.included {
+		if removed[incUUID] {
+			continue
+		}
+
+		inclChanges = append(inclChanges, inclusionChange{
+			UUID:    incUUID,
+			Change:  changeAdd,
+			LoadDoc: inc.GetDocCount > 0,
+			Recheck: true,
+		})
+	}
+
+	err = ds.emitMatches(ctx, matches, inclChanges)
+	if err != nil {
+		return err
+	}
+
+	ds.checkpoint(ctx)
+
+	return nil
+}
+
+// emitDelta brings a client that resumes the set from a cursor up to date
+// using the events that were replayed after the cursor position. Set documents
+// touched by the events are emitted in document batches if they still are in
+// the set, and removed if they aren't. Inclusions of the touched documents and
+// touched included documents are re-emitted, and inclusions that have been
+// dropped are removed. If the inclusions that the client had can't be
+// determined all documents in the set are emitted instead.
+func (ds *documentSet) emitDelta(
+	ctx context.Context,
+	position int64,
+	replay []DocumentStreamItem,
+) error {
+	ds.position = position
+
+	if len(replay) > 0 {
+		ds.position = replay[len(replay)-1].Event.ID
+	}
+
+	matches, err := ds.loadMatches(ctx)
+	if err != nil {
+		return err
+	}
+
+	previousRefs, ok, err := ds.previousIncludeRefs(ctx, replay, matches)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		err := ds.emitMatches(ctx, matches, nil)
+		if err != nil {
+			return err
+		}
+
+		ds.checkpoint(ctx)
+
+		return nil
+	}
+
+	var (
+		touched    = make(map[uuid.UUID]bool)
+		touchedSet = make(map[uuid.UUID]bool)
+	)
+
+	for _, item := range replay {
+		touched[item.Event.UUID] = true
+
+		if item.Event.Type == ds.docType {
+			touchedSet[item.Event.UUID] = true
+		}
+	}
+
+	// The client already has the documents that haven't been touched, so
+	// all matches are added to the set without being emitted.
+	changed := make(map[uuid.UUID]*DocumentStreamData)
+
+	for docUUID, m := range matches {
+		ds.AddDocument(docUUID, m.Document)
+
+		ds.set[docUUID].Meta = DocumentMetaToRPC(&m.Meta)
+
+		if touched[docUUID] {
+			changed[docUUID] = m
+		}
+	}
+
+	for docUUID := range touchedSet {
+		if matches[docUUID] != nil {
+			continue
+		}
+
+		ds.emitRemove(ctx, docUUID)
+	}
+
+	var (
+		inclChanges []inclusionChange
+		queued      = make(map[uuid.UUID]bool)
+	)
+
+	queueInclusion := func(incUUID uuid.UUID) {
+		inc := ds.included[incUUID]
+		if inc == nil || queued[incUUID] {
+			return
+		}
+
+		queued[incUUID] = true
+
+		inclChanges = append(inclChanges, inclusionChange{
+			UUID:    incUUID,
+			Change:  changeAdd,
+			LoadDoc: inc.GetDocCount > 0,
+			Recheck: true,
+		})
+	}
+
+	for docUUID := range changed {
+		for incUUID := range ds.set[docUUID].IncludeRefs {
+			queueInclusion(incUUID)
+		}
+	}
+
+	for docUUID := range touched {
+		queueInclusion(docUUID)
+	}
+
+	for incUUID := range previousRefs {
+		if ds.included[incUUID] != nil {
+			continue
+		}
+
+		inclChanges = append(inclChanges, inclusionChange{
+			UUID:   incUUID,
+			Change: changeRemoved,
+		})
+	}
+
+	batch := &rsock.DocumentBatch{}
+
+	for docUUID, m := range changed {
+		batch.Documents = append(batch.Documents,
+			ds.documentState(docUUID, m.Document, ds.set[docUUID].Meta))
+
+		if len(batch.Documents) == 20 {
+			err := ds.emitBatch(ctx, batch, nil)
+			if err != nil {
+				return fmt.Errorf("emit document batch: %w", err)
+			}
+
+			batch = &rsock.DocumentBatch{}
+		}
+	}
+
+	batch.FinalBatch = true
+
 	err = ds.emitBatch(ctx, batch, inclChanges)
 	if err != nil {
 		return fmt.Errorf("emit final document batch: %w", err)
 	}
 
+	ds.checkpoint(ctx)
+
 	return nil
 }
 
+// previousIncludeRefs collects the documents that set documents touched by the
+// replayed events included at the cursor position. The content of a document
+// at the cursor position is the version before its first document version
+// event, or the current content if it hasn't been updated. Returns false if the
+// content can't be determined, f.ex. when the document has been deleted.
+func (ds *documentSet) previousIncludeRefs(
+	ctx context.Context,
+	replay []DocumentStreamItem,
+	matches map[uuid.UUID]*DocumentStreamData,
+) (map[uuid.UUID]bool, bool, error) {
+	refs := make(map[uuid.UUID]bool)
+
+	if len(ds.includeExtractors) == 0 {
+		return refs, true, nil
+	}
+
+	var (
+		docs    = make(map[uuid.UUID]newsdoc.Document)
+		decided = make(map[uuid.UUID]bool)
+		load    []BulkGetReference
+	)
+
+	for _, item := range replay {
+		docUUID := item.Event.UUID
+
+		if item.Event.Type != ds.docType || decided[docUUID] {
+			continue
+		}
+
+		switch item.Event.Event {
+		case TypeDeleteDocument:
+			return nil, false, nil
+		case TypeDocumentVersion:
+			decided[docUUID] = true
+
+			delete(docs, docUUID)
+
+			if item.Event.Version > 1 {
+				load = append(load, BulkGetReference{
+					UUID:    docUUID,
+					Version: item.Event.Version - 1,
+				})
+			}
+		default:
+			if _, ok := docs[docUUID]; ok {
+				continue
+			}
+
+			switch {
+			case matches[docUUID] != nil:
+				docs[docUUID] = matches[docUUID].Document
+			case item.Data != nil:
+				docs[docUUID] = item.Data.Document
+			}
+		}
+	}
+
+	if len(load) > 0 {
+		loaded, err := ds.cache.GetDocuments(ctx, load)
+		if err != nil {
+			return nil, false, fmt.Errorf(
+				"get previous document versions: %w", err)
+		}
+
+		var found int
+
+		for item := range loaded {
+			docs[item.UUID] = item.Document
+			found++
+		}
+
+		if found != len(load) {
+			return nil, false, nil
+		}
+	}
+
+	for _, doc := range docs {
+		for _, ex := range ds.includeExtractors {
+			for _, item := range ex.Collect(doc) {
+				value, ok := item["uuid"]
+				if !ok {
+					continue
+				}
+
+				incUUID, err := uuid.Parse(value.Value)
+				if err != nil {
+					continue
+				}
+
+				refs[incUUID] = true
+			}
+		}
+	}
+
+	return refs, true, nil
+}
+
 func (ds *documentSet) emitBatch(
 	ctx context.Context,
 	batch *rsock.DocumentBatch,
@@ -329,10 +795,19 @@ func (ds *documentSet) ProcessingLoop(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ds.oosErr:
-			ds.emitErrorf(ctx, "oos", "processing buffer overflow")
+		case <-ds.resync:
+			err := ds.resynchronise(ctx)
+			if err != nil {
+				ds.resyncs.WithLabelValues(ds.docType, "error").Inc()
 
-			return
+				ds.emitErrorf(ctx, "oos",
+					"failed to recover from processing buffer overflow: %v",
+					err)
+
+				return
+			}
+
+			ds.resyncs.WithLabelValues(ds.docType, "ok").Inc()
 		case item := <-ds.process:
 			// Included documents are not part of the core set (and
 			// are therefore not Added), but they are part of an
@@ -343,6 +818,8 @@ func (ds *documentSet) ProcessingLoop(ctx context.Context) {
 			// Only process events for documents that have been
 			// included or have the core set type.
 			if item.Event.Type != ds.docType && !isIncluded {
+				ds.advance(ctx, item.Event.ID)
+
 				continue
 			}
 
@@ -379,7 +856,9 @@ func (ds *documentSet) ProcessingLoop(ctx context.Context) {
 				ds.EvaluateDocument(
 					item.Event.Type,
 					item.Timespans,
-					item.Event.Labels)
+					item.Event.Labels,
+					item.Data.Document,
+					&item.Data.Meta)
 			isInSet := ds.set[item.Event.UUID] != nil
 			// The update caused the document to exit the set.
 			isRemove := !shouldBeInSet && isInSet
@@ -401,6 +880,12 @@ func (ds *documentSet) ProcessingLoop(ctx context.Context) {
 				inclusionChanges = ds.RemoveDocument(item.Event.UUID)
 			}
 
+			// Keep track of the last known meta information so that
+			// a resync can skip documents that haven't changed.
+			if sd := ds.set[item.Event.UUID]; sd != nil {
+				sd.Meta = DocumentMetaToRPC(&item.Data.Meta)
+			}
+
 			switch {
 			case isUpdate:
 				ds.emitItem(ctx, item, withState, shouldBeInSet)
@@ -421,6 +906,8 @@ func (ds *documentSet) ProcessingLoop(ctx context.Context) {
 
 				return
 			}
+
+			ds.advance(ctx, item.Event.ID)
 		}
 	}
 }
@@ -527,7 +1014,15 @@ func (ds *documentSet) handleInclusionChanges(
 	}
 
 	for _, change := range changes {
+		if change.Change == changeRemoved {
+			continue
+		}
+
 		if !canRead[change.UUID] {
+			if change.Recheck {
+				ds.emitRemove(ctx, change.UUID)
+			}
+
 			continue
 		}
 
@@ -585,11 +1080,19 @@ func (ds *documentSet) handleInclusionChanges(
 	// Build the inclusion batch from the meta information and loaded
 	// documents.
 	for _, change := range changes {
-		if change.Change == changeRemoved {
+		if change.Change == changeRemoved || !canRead[change.UUID] {
 			continue
 		}
 
 		docMeta := meta[change.UUID]
+		if docMeta == nil {
+			// The document doesn't exist (anymore).
+			if change.Recheck {
+				ds.emitRemove(ctx, change.UUID)
+			}
+
+			continue
+		}
 
 		state := &rsock.DocumentState{
 			Uuid: change.UUID.String(),
@@ -613,17 +1116,25 @@ func (ds *documentSet) handleInclusionChanges(
 		})
 	}
 
+	if len(batch.Documents) == 0 {
+		return nil
+	}
+
 	ds.emitter.InclusionBatch(ctx, batch)
 
 	return nil
 }
 
-// EvaluateDocument checks if the document matches the timespan and labels
-// defined for the set.
+// EvaluateDocument checks if the document matches the timespan, labels and
+// filter defined for the set. As the filter can refer to the status heads and
+// workflow state of the document the membership of a document must be
+// re-evaluated on every change, not just for new document versions.
 func (ds *documentSet) EvaluateDocument(
 	docType string,
 	timespans []Timespan,
 	labels []string,
+	doc newsdoc.Document,
+	meta *DocumentMeta,
 ) bool {
 	if docType != ds.docType {
 		return false
@@ -662,12 +1173,14 @@ func (ds *documentSet) EvaluateDocument(
 		}
 	}
 
-	return true
+	return ds.filter.Matches(doc, meta)
 }
 
 // setDocument is the state information for a primary document in the set.
 type setDocument struct {
 	IncludeRefs map[uuid.UUID]bool
+	// Meta is the last meta information that was emitted for the document.
+	Meta *repository.DocumentMeta
 }
 
 // IncludedDocument tells us whether a set document included the document with
@@ -705,6 +1218,9 @@ type inclusionChange struct {
 	UUID    uuid.UUID
 	Change  int
 	LoadDoc bool
+	// Recheck is set for inclusions that the client might already have,
+	// they are removed if they no longer can be read or no longer exist.
+	Recheck bool
 }
 
 // AddDocument that already has been evaluated as a match. Returns a list of
@@ -852,6 +1368,67 @@ func (ds *documentSet) RemoveDocument(docUUID uuid.UUID) []inclusionChange {
 	return changed
 }
 
+// setCursor is a position that a client can resume a document set from. It's
+// made up of the ID of the last event that was reflected in what the client
+// has received, and a fingerprint of the set definition and client identity.
+type setCursor struct {
+	Position    int64
+	Fingerprint string
+}
+
+func (c setCursor) String() string {
+	return strconv.FormatInt(c.Position, 10) + "." + c.Fingerprint
+}
+
+func parseSetCursor(v string) (setCursor, error) {
+	pos, fp, ok := strings.Cut(v, ".")
+	if !ok || fp == "" {
+		return setCursor{}, errors.New("expected a position and a fingerprint")
+	}
+
+	position, err := strconv.ParseInt(pos, 10, 64)
+	if err != nil || position < 0 {
+		return setCursor{}, fmt.Errorf("invalid position %q", pos)
+	}
+
+	return setCursor{
+		Position:    position,
+		Fingerprint: fp,
+	}, nil
+}
+
+// documentSetDefinition hashes the parts of a GetDocuments request that define
+// the contents of the set.
+func documentSetDefinition(req *rsock.GetDocuments) ([]byte, error) {
+	def := proto.CloneOf(req)
+
+	def.SetName = ""
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(def)
+	if err != nil {
+		return nil, fmt.Errorf("marshal set definition: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+
+	return sum[:], nil
+}
+
+// setFingerprint combines a set definition with the identity of the client, a
+// change in either invalidates cursors for the set.
+func setFingerprint(definition []byte, identity []string) string {
+	h := sha256.New()
+
+	h.Write(definition)
+
+	for _, uri := range slices.Sorted(slices.Values(identity)) {
+		h.Write([]byte{0})
+		h.Write([]byte(uri))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:12])
+}
+
 type SocketResponder interface {
 	Respond(
 		ctx context.Context,
@@ -859,12 +1436,26 @@ type SocketResponder interface {
 		resp *rsock.Response,
 		final bool,
 	)
+	// RespondWithCursor sends a document set response together with a
+	// set cursor.
+	RespondWithCursor(
+		ctx context.Context,
+		handle *CallHandle,
+		resp *rsock.Response,
+		cursor string,
+	)
+}
+
+// ReadRecorder records document deliveries in the read audit log.
+type ReadRecorder interface {
+	RecordReads(ctx context.Context, reads []DocumentRead) error
 }
 
 func newDocumentSetHandle(
 	ctx context.Context,
 	call *CallHandle,
 	responder SocketResponder,
+	recorder ReadRecorder,
 ) *documentSetHandle {
 	ctx, cancel := context.WithCancel(ctx)
 
@@ -873,6 +1464,7 @@ func newDocumentSetHandle(
 		ctx:       ctx,
 		cancel:    cancel,
 		responder: responder,
+		recorder:  recorder,
 	}
 }
 
@@ -883,6 +1475,14 @@ type documentSetHandle struct {
 	ctx       context.Context
 	cancel    func()
 	responder SocketResponder
+	recorder  ReadRecorder
+
+	// pending is the last response for the set, it's held back until the
+	// next response or checkpoint so that it can carry the cursor that
+	// was reached with it.
+	pending *rsock.Response
+	// cursor is the last cursor that the client can resume the set from.
+	cursor string
 
 	Set *documentSet
 }
@@ -893,13 +1493,25 @@ func (d *documentSetHandle) Close() {
 
 // DocumentBatch implements DocumentSetEmitter.
 func (d *documentSetHandle) DocumentBatch(ctx context.Context, msg *rsock.DocumentBatch) {
-	d.responder.Respond(ctx, d.call, &rsock.Response{
+	var reads []DocumentRead
+
+	for _, state := range msg.Documents {
+		reads = appendStateRead(reads, state)
+	}
+
+	if !d.recordReads(ctx, reads) {
+		return
+	}
+
+	d.respond(ctx, &rsock.Response{
 		DocumentBatch: msg,
-	}, false)
+	})
 }
 
 // Error implements DocumentSetEmitter.
 func (d *documentSetHandle) Error(ctx context.Context, msg *rsock.Error) {
+	d.flush(ctx)
+
 	d.responder.Respond(ctx, d.call, &rsock.Response{
 		Error: msg,
 	}, false)
@@ -907,23 +1519,126 @@ func (d *documentSetHandle) Error(ctx context.Context, msg *rsock.Error) {
 	d.Close()
 }
 
+// Checkpoint implements DocumentSetEmitter.
+func (d *documentSetHandle) Checkpoint(ctx context.Context, cursor string) {
+	d.cursor = cursor
+
+	d.flush(ctx)
+}
+
+// respond sends the pending response and holds back resp in its place.
+func (d *documentSetHandle) respond(ctx context.Context, resp *rsock.Response) {
+	d.flush(ctx)
+
+	d.pending = resp
+}
+
+// flush sends the pending response, with the current cursor if the client
+// has asked for set cursors.
+func (d *documentSetHandle) flush(ctx context.Context) {
+	if d.pending == nil {
+		return
+	}
+
+	resp := d.pending
+
+	d.pending = nil
+
+	if !d.call.SetCursors || d.cursor == "" {
+		d.responder.Respond(ctx, d.call, resp, false)
+
+		return
+	}
+
+	d.responder.RespondWithCursor(ctx, d.call, resp, d.cursor)
+}
+
 // InclusionBatch implements DocumentSetEmitter.
 func (d *documentSetHandle) InclusionBatch(ctx context.Context, msg *rsock.InclusionBatch) {
-	d.responder.Respond(ctx, d.call, &rsock.Response{
+	var reads []DocumentRead
+
+	for _, doc := range msg.Documents {
+		reads = appendStateRead(reads, doc.State)
+	}
+
+	if !d.recordReads(ctx, reads) {
+		return
+	}
+
+	d.respond(ctx, &rsock.Response{
 		InclusionBatch: msg,
-	}, false)
+	})
 }
 
 // Remove implements DocumentSetEmitter.
 func (d *documentSetHandle) Remove(ctx context.Context, msg *rsock.DocumentRemoved) {
-	d.responder.Respond(ctx, d.call, &rsock.Response{
+	d.respond(ctx, &rsock.Response{
 		Removed: msg,
-	}, false)
+	})
 }
 
 // Update implements DocumentSetEmitter.
 func (d *documentSetHandle) Update(ctx context.Context, msg *rsock.DocumentUpdate) {
-	d.responder.Respond(ctx, d.call, &rsock.Response{
+	var reads []DocumentRead
+
+	docUUID, err := uuid.Parse(msg.Event.Uuid)
+
+	delivered := msg.Document != nil || len(msg.Subset) > 0
+	if err == nil && delivered {
+		reads = append(reads, DocumentRead{
+			UUID:    docUUID,
+			Version: msg.Event.Version,
+		})
+	}
+
+	if !d.recordReads(ctx, reads) {
+		return
+	}
+
+	d.respond(ctx, &rsock.Response{
 		DocumentUpdate: msg,
-	}, false)
+	})
+}
+
+// recordReads records the document deliveries in the read audit log. If the
+// reads couldn't be recorded an error is emitted, which closes the set, and
+// false is returned.
+func (d *documentSetHandle) recordReads(
+	ctx context.Context, reads []DocumentRead,
+) bool {
+	if len(reads) == 0 {
+		return true
+	}
+
+	err := d.recorder.RecordReads(ctx, reads)
+	if err != nil {
+		d.Error(ctx, &rsock.Error{
+			ErrorCode:    string(twirp.Internal),
+			ErrorMessage: fmt.Sprintf("record read audit: %v", err),
+		})
+
+		return false
+	}
+
+	return true
+}
+
+// appendStateRead appends a read of the document to reads if the state
+// carries document data.
+func appendStateRead(
+	reads []DocumentRead, state *rsock.DocumentState,
+) []DocumentRead {
+	if state == nil || (state.Document == nil && len(state.Subset) == 0) {
+		return reads
+	}
+
+	docUUID, err := uuid.Parse(state.Uuid)
+	if err != nil {
+		return reads
+	}
+
+	return append(reads, DocumentRead{
+		UUID:    docUUID,
+		Version: state.Meta.GetCurrentVersion(),
+	})
 }
@@ -96,6 +96,18 @@ func (b *docStreamBuf) itemsFrom(from int64) ([]DocumentStreamItem, bool) {
 	return result, true
 }
 
+// oldestID returns the event ID of the oldest item in the buffer, false is
+// returned if the buffer is empty.
+func (b *docStreamBuf) oldestID() (int64, bool) {
+	if b.len == 0 {
+		return 0, false
+	}
+
+	size := len(b.items)
+
+	return b.items[(b.head-b.len+size)%size].Event.ID, true
+}
+
 func NewDocumentStream(
 	ctx context.Context,
 	log *slog.Logger,
@@ -169,7 +181,7 @@ type DocumentStream struct {
 	mSubscribers prometheus.Gauge
 
 	// No mutex needed for these as they're only touched from the event
-	// handling loop.
+	// handling loop, lastID is also read by subscribers holding emitMu.
 	lastID    int64
 	eventChan chan int64
 	store     DocStore
@@ -334,10 +346,11 @@ func (s *DocumentStream) emitEvents(
 
 // Subscribe registers a handler for new document stream items. The handler
 // will be automatically unregistered when ctx or the stream context is
-// cancelled.
+// cancelled. Returns the ID of the last event that was emitted before the
+// handler was registered, or -1 if the stream hasn't emitted any events yet.
 func (s *DocumentStream) Subscribe(
 	ctx context.Context, handler DocumentStreamHandlerFunc,
-) {
+) int64 {
 	s.emitMu.Lock()
 	defer s.emitMu.Unlock()
 
@@ -353,6 +366,8 @@ func (s *DocumentStream) Subscribe(
 	s.m.Unlock()
 
 	go s.unsubscribeOnCancel(ctx, id)
+
+	return s.lastID
 }
 
 // SubscribeFrom replays buffered items with Event.ID >= from to replayHandler,
@@ -397,6 +412,49 @@ func (s *DocumentStream) SubscribeFrom(
 	return true
 }
 
+// SubscribeAfter works like SubscribeFrom, but replays the items after the
+// given event ID, and only subscribes if the buffer is known to cover every
+// event after it. That is the case when after is the last emitted event, or
+// when the buffer still holds an item at or before after. An empty buffer or
+// a position that the stream hasn't reached yet isn't considered covered.
+func (s *DocumentStream) SubscribeAfter(
+	ctx context.Context, after int64,
+	replayHandler, liveHandler DocumentStreamHandlerFunc,
+) bool {
+	s.emitMu.Lock()
+	defer s.emitMu.Unlock()
+
+	s.m.Lock()
+
+	oldest, ok := s.buf.oldestID()
+
+	covered := s.lastID != -1 &&
+		(after == s.lastID || (ok && oldest <= after && after < s.lastID))
+	if !covered {
+		s.m.Unlock()
+
+		return false
+	}
+
+	replay, _ := s.buf.itemsFrom(after + 1)
+	if len(replay) > 0 {
+		replayHandler(replay)
+	}
+
+	s.serial++
+
+	id := s.serial
+
+	s.consumers[id] = liveHandler
+	s.mSubscribers.Set(float64(len(s.consumers)))
+
+	s.m.Unlock()
+
+	go s.unsubscribeOnCancel(ctx, id)
+
+	return true
+}
+
 func (s *DocumentStream) unsubscribeOnCancel(ctx context.Context, id int64) {
 	select {
 	case <-ctx.Done():
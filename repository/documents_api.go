@@ -33,18 +33,58 @@ type DocumentValidator interface {
 		ctx context.Context, document *newsdoc.Document,
 	) ([]revisor.ValidationResult, error)
 	ActiveGenerationID() int64
+	TransformDocument(doc newsdoc.Document) (newsdoc.Document, []string)
 }
 
 type WorkflowProvider interface {
 	HasStatus(docType string, name string) bool
 	EvaluateRules(input StatusRuleInput) []StatusRuleViolation
 	GetDocumentWorkflow(docType string) (DocumentWorkflow, bool)
+	GetAccessRules(docType string) []StatusRule
 }
 
 type UploadURLCreator interface {
 	CreateUploadURL(ctx context.Context, id uuid.UUID) (string, error)
 }
 
+// MultipartUploader manages multipart uploads to the object store.
+type MultipartUploader interface {
+	CreateMultipartUpload(
+		ctx context.Context, id uuid.UUID, contentType string,
+	) (string, error)
+	CreateUploadPartURL(
+		ctx context.Context, id uuid.UUID, multipartID string,
+		partNumber int32,
+	) (string, error)
+	ListUploadParts(
+		ctx context.Context, id uuid.UUID, multipartID string,
+	) ([]UploadPart, error)
+	CompleteMultipartUpload(
+		ctx context.Context, id uuid.UUID, multipartID string,
+		parts []UploadPart,
+	) error
+	AbortMultipartUpload(
+		ctx context.Context, id uuid.UUID, multipartID string,
+	) error
+}
+
+// DocumentAssets gives the documents service access to the objects attached
+// to documents.
+type DocumentAssets interface {
+	UploadURLCreator
+	MultipartUploader
+
+	CopyToUpload(
+		ctx context.Context, document uuid.UUID, name string,
+		objectVersion string, upload uuid.UUID,
+	) error
+	DeleteUpload(ctx context.Context, id uuid.UUID) error
+	CreateVersionDownloadURL(
+		ctx context.Context, document uuid.UUID, name string,
+		objectVersion string,
+	) (string, error)
+}
+
 type BulkDocCache interface {
 	GetDocuments(
 		ctx context.Context,
@@ -57,11 +97,13 @@ func NewDocumentsService(
 	sched ScheduleStore,
 	validator DocumentValidator,
 	workflows WorkflowProvider,
-	assets UploadURLCreator,
+	assets DocumentAssets,
 	defaultLanguage string,
 	docTypes *TypeConfigurations,
 	docCache BulkDocCache,
+	templates TemplateStore,
 	socketKey *ecdsa.PrivateKey,
+	transformOnRead bool,
 ) (*DocumentsService, error) {
 	return &DocumentsService{
 		socketKey:       socketKey,
@@ -73,6 +115,8 @@ func NewDocumentsService(
 		defaultLanguage: defaultLanguage,
 		docTypes:        docTypes,
 		docCache:        docCache,
+		templates:       templates,
+		transformOnRead: transformOnRead,
 	}, nil
 }
 
@@ -85,10 +129,12 @@ type DocumentsService struct {
 	sched           ScheduleStore
 	validator       DocumentValidator
 	workflows       WorkflowProvider
-	assets          UploadURLCreator
+	assets          DocumentAssets
 	defaultLanguage string
 	docTypes        *TypeConfigurations
 	docCache        BulkDocCache
+	templates       TemplateStore
+	transformOnRead bool
 }
 
 // GetSocketToken implements repository.Documents.
@@ -783,7 +829,7 @@ func (a *DocumentsService) GetPermissions(
 		return &resp, nil
 	}
 
-	acl, err := a.store.GetDocumentACL(ctx, docUUID)
+	acl, err := a.store.GetEffectiveDocumentACL(ctx, docUUID)
 	if err != nil {
 		return nil, twirp.InternalErrorf("failed to read document ACL: %w", err)
 	}
@@ -831,2181 +877,4272 @@ func aclPermissions(sub string, acl []ACLEntry) []Permission {
 	return perms
 }
 
-// CompactedEventlog implements repository.Documents.
-func (a *DocumentsService) CompactedEventlog(
-	ctx context.Context,
-	req *repository.GetCompactedEventlogRequest,
-) (*repository.GetCompactedEventlogResponse, error) {
-	_, err := RequireAnyScope(ctx, ScopeEventlogRead, ScopeDocumentAdmin)
+// ExtensionMethods implements ExtensionProvider.
+func (a *DocumentsService) ExtensionMethods() ExtensionMethods {
+	return ExtensionMethods{
+		"AbortMultipartUpload":    JSONMethod(a.AbortMultipartUpload),
+		"CompleteMultipartUpload": JSONMethod(a.CompleteMultipartUpload),
+		"CreateFromTemplate":      JSONMethod(a.CreateFromTemplate),
+		"CreateMultipartUpload":   JSONMethod(a.CreateMultipartUpload),
+		"Duplicate":               JSONMethod(a.Duplicate),
+		"ExplainPermission":       JSONMethod(a.ExplainPermission),
+		"GetACL":                  JSONMethod(a.GetACL),
+		"GetAttachmentDetails":    JSONMethod(a.GetAttachmentDetails),
+		"GetAttachmentHistory":    JSONMethod(a.GetAttachmentHistory),
+		"GetAttachmentVersion":    JSONMethod(a.GetAttachmentVersion),
+		"GetBacklinks":            JSONMethod(a.GetBacklinks),
+		"GetEventlogDetails":      JSONMethod(a.GetEventlogDetails),
+		"GetExport":               JSONMethod(a.GetExport),
+		"GetReadAudit":            JSONMethod(a.GetReadAudit),
+		"GetUploadPartURLs":       JSONMethod(a.GetUploadPartURLs),
+		"Import":                  JSONMethod(a.Import),
+		"ListUploadParts":         JSONMethod(a.ListUploadParts),
+		"StartExport":             JSONMethod(a.StartExport),
+		"UpdateACL":               JSONMethod(a.UpdateACL),
+	}
+}
+
+type GetACLRequest struct {
+	UUID string `json:"uuid"`
+}
+
+type GetACLResponse struct {
+	ACL []ACLEntry `json:"acl"`
+}
+
+// GetACL returns the ACL entries of a document, including the time bounds of
+// time-bound grants. Grants inherited from other documents are not included.
+func (a *DocumentsService) GetACL(
+	ctx context.Context, req *GetACLRequest,
+) (*GetACLResponse, error) {
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentRead, ScopeDocumentReadAll,
+		ScopeDocumentAdmin,
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	lastID, err := a.store.GetLastEventID(ctx)
-	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
-		return &repository.GetCompactedEventlogResponse{}, nil
-	} else if err != nil {
-		return nil, fmt.Errorf(
-			"failed to get the latest event ID: %w", err)
+	docUUID, err := validateRequiredUUIDParam(req.UUID)
+	if err != nil {
+		return nil, err
 	}
 
-	if req.After >= lastID {
-		return &repository.GetCompactedEventlogResponse{}, nil
+	err = a.accessCheck(ctx, auth, docUUID, ReadPermission)
+	if err != nil {
+		return nil, err
 	}
 
-	cr := GetCompactedEventlogRequest{
-		After:  req.After,
-		Until:  req.Until,
-		Offset: req.Offset,
-		Type:   req.Type,
+	acl, err := a.store.GetDocumentACL(ctx, docUUID)
+	if err != nil {
+		return nil, twirp.InternalErrorf(
+			"failed to read document ACL: %v", err)
 	}
 
-	if req.Limit != 0 {
-		cr.Limit = &req.Limit
-	}
+	return &GetACLResponse{
+		ACL: acl,
+	}, nil
+}
 
-	if cr.Until == 0 {
-		increment := max(req.Limit, req.Limit*5, 500)
-		cr.Until = min(cr.After+int64(increment), lastID)
-	}
+type UpdateACLRequest struct {
+	UUID      string     `json:"uuid"`
+	ACL       []ACLEntry `json:"acl"`
+	IfMatch   int64      `json:"if_match,omitempty"`
+	LockToken string     `json:"lock_token,omitempty"`
+}
 
-	switch {
-	case cr.Until <= cr.After:
-		return nil, twirp.InvalidArgumentError("until",
-			"until must be greater than 'after'")
-	case cr.Until > lastID:
-		return nil, twirp.InvalidArgumentError("until",
-			"cannot be greater than the latest event ID")
-	case cr.Until-cr.After > 10000:
-		return nil, twirp.InvalidArgumentError("until",
-			"`until` cannot be greater than `after`+10000")
-	}
+type UpdateACLResponse struct {
+	Version int64 `json:"version"`
+}
 
-	evts, err := a.store.GetCompactedEventlog(ctx, cr)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"failed to read eventlog from database: %w", err)
+// UpdateACL updates the ACL of a document like an update with only ACL
+// entries would, but also accepts time bounds for the entries. Entries are
+// enforced from their "not_before" time, and are removed from the ACL once
+// they expire.
+func (a *DocumentsService) UpdateACL(
+	ctx context.Context, req *UpdateACLRequest,
+) (*UpdateACLResponse, error) {
+	elephantine.SetLogMetadata(ctx,
+		elephantine.LogKeyDocumentUUID, req.UUID)
+
+	if len(req.ACL) == 0 {
+		return nil, twirp.RequiredArgumentError("acl")
 	}
 
-	var res repository.GetCompactedEventlogResponse
+	now := time.Now()
 
-	for i := range evts {
-		res.Items = append(res.Items, EventToRPC(evts[i]))
+	update := repository.UpdateRequest{
+		Uuid:      req.UUID,
+		IfMatch:   req.IfMatch,
+		LockToken: req.LockToken,
+		Acl:       make([]*repository.ACLEntry, len(req.ACL)),
 	}
 
-	return &res, nil
-}
+	for i, e := range req.ACL {
+		if e.Expires != nil && !e.Expires.After(now) {
+			return nil, twirp.InvalidArgumentError(
+				fmt.Sprintf("acl.%d.expires", i),
+				"must be in the future")
+		}
 
-const (
-	defaultEventlogBatchSize = 10
-	maxEventlogBatchSize     = 500
-)
+		if e.Expires != nil && e.NotBefore != nil &&
+			!e.Expires.After(*e.NotBefore) {
+			return nil, twirp.InvalidArgumentError(
+				fmt.Sprintf("acl.%d.expires", i),
+				"must be after not_before")
+		}
 
-// Eventlog returns document update events, optionally waiting for new events.
-func (a *DocumentsService) Eventlog(
-	ctx context.Context, req *repository.GetEventlogRequest,
-) (*repository.GetEventlogResponse, error) {
-	_, err := RequireAnyScope(ctx, ScopeEventlogRead, ScopeDocumentAdmin)
+		update.Acl[i] = &repository.ACLEntry{
+			Uri:         e.URI,
+			Permissions: e.Permissions,
+		}
+	}
+
+	auth, err := a.verifyUpdateRequests(ctx,
+		[]*repository.UpdateRequest{&update})
 	if err != nil {
 		return nil, err
 	}
 
-	var (
-		wait      time.Duration
-		waitBatch time.Duration
-	)
-
-	if req.WaitMs == 0 {
-		wait = 2 * time.Second
-	} else {
-		wait = time.Duration(req.WaitMs) * time.Millisecond
+	up, err := a.buildUpdateRequest(ctx, auth, &update)
+	if err != nil {
+		return nil, err
 	}
 
-	if req.BatchWaitMs == 0 {
-		waitBatch = 200 * time.Millisecond
-	} else {
-		waitBatch = time.Duration(req.BatchWaitMs) * time.Millisecond
+	if len(up.ACL) != len(req.ACL) {
+		return nil, twirp.InternalError(
+			"the ACL entries were not carried over to the update")
 	}
 
-	after := req.After
-	limit := min(req.BatchSize, maxEventlogBatchSize)
-
-	if limit == 0 && after < 0 {
-		// Default limit to abs(after) when after is negative. Don't
-		// exceed the normal default though (so, no overflow gosec...)
-		limit = internal.MustInt32(min(defaultEventlogBatchSize, -after))
-	} else if limit == 0 {
-		limit = defaultEventlogBatchSize
-	}
+	up.ACLTimeBounds = true
 
-	if after < 0 {
-		evt, err := a.store.GetLastEvent(ctx)
+	// aclListFromRPC preserves the order of the entries.
+	for i, e := range req.ACL {
+		up.ACL[i].Expires = e.Expires
 
-		switch {
-		case IsDocStoreErrorCode(err, ErrCodeNotFound):
-			after = 0
-		case err != nil:
-			return nil, twirp.InternalErrorf(
-				"failed to get last event: %w", err)
-		default:
-			after = max(0, evt.ID+after)
+		// A grant that already is in effect doesn't need a not
+		// before time.
+		if e.NotBefore != nil && e.NotBefore.After(now) {
+			up.ACL[i].NotBefore = e.NotBefore
 		}
 	}
 
-	evts, err := a.store.GetEventlog(ctx, after, limit)
+	res, err := a.store.Update(ctx, a.workflows, []*UpdateRequest{up})
 	if err != nil {
-		return nil, twirp.InternalErrorf(
-			"failed to fetch events from store: %w", err)
+		return nil, twirpErrorFromDocumentUpdateError(err)
 	}
 
-	var res repository.GetEventlogResponse
+	return &UpdateACLResponse{
+		Version: res[0].Version,
+	}, nil
+}
 
-	maxID := req.After
+type CreateFromTemplateRequest struct {
+	Template string `json:"template"`
+	// TemplateVersion defaults to the latest version of the template.
+	TemplateVersion int64 `json:"template_version,omitempty"`
+	// UUID of the new document, a random UUID is used if it's omitted.
+	UUID   string            `json:"uuid,omitempty"`
+	Values map[string]string `json:"values,omitempty"`
+	// ACL replaces the default ACL of the template if set. The default
+	// ACL of the template is extended with read and write access for the
+	// creator, but a supplied ACL is used as is.
+	ACL    []TemplateACLEntry         `json:"acl,omitempty"`
+	Status []*repository.StatusUpdate `json:"status,omitempty"`
+}
 
-	for i := range evts {
-		if evts[i].ID > maxID {
-			maxID = evts[i].ID
-		}
+type CreateFromTemplateResponse struct {
+	UUID            string `json:"uuid"`
+	Version         int64  `json:"version"`
+	TemplateVersion int64  `json:"template_version"`
+}
 
-		res.Items = append(res.Items, EventToRPC(evts[i]))
+// CreateFromTemplate instantiates a template with the supplied values and
+// creates a new document from it using a regular update.
+func (a *DocumentsService) CreateFromTemplate(
+	ctx context.Context, req *CreateFromTemplateRequest,
+) (*CreateFromTemplateResponse, error) {
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentWrite, ScopeDocumentAdmin,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	if wait < 0 || len(res.Items) == int(limit) {
-		return &res, nil
+	if req.Template == "" {
+		return nil, twirp.RequiredArgumentError("template")
 	}
 
-	return a.eventlogWaitLoop(ctx, maxID, limit, wait, waitBatch, &res)
-}
+	docUUID := req.UUID
+	if docUUID == "" {
+		docUUID = uuid.NewString()
+	}
 
-func (a *DocumentsService) eventlogWaitLoop(
-	ctx context.Context, after int64, limit int32,
-	wait time.Duration, waitBatch time.Duration,
-	res *repository.GetEventlogResponse,
-) (*repository.GetEventlogResponse, error) {
-	var batchTimeout <-chan time.Time
+	elephantine.SetLogMetadata(ctx,
+		elephantine.LogKeyDocumentUUID, docUUID)
 
-	timeout := time.After(wait)
+	tmpl, err := a.templates.GetTemplate(ctx,
+		req.Template, req.TemplateVersion)
 
-	if len(res.Items) > 0 {
-		batchTimeout = time.After(waitBatch)
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.NotFound.Error(err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf("read template: %v", err)
 	}
 
-	newEvent := make(chan int64, 1)
+	doc, acl, err := tmpl.Instantiate(docUUID, req.Values, time.Now())
+	if err != nil {
+		return nil, twirp.InvalidArgumentError("values", err.Error())
+	}
 
-	a.store.OnEventlog(ctx, newEvent)
+	switch {
+	case req.ACL != nil:
+		acl = req.ACL
+	case len(acl) > 0:
+		// The template ACL replaces the default ACL for new documents,
+		// so keep the access that the creator would have gotten.
+		acl = withTemplateACLGrant(acl, auth.Claims.Subject, "r", "w")
+	}
 
-	for {
-		select {
-		case <-batchTimeout:
-			return res, nil
-		case <-timeout:
-			return res, nil
-		case <-ctx.Done():
-			return nil, twirp.Canceled.Error("context cancelled")
-		case <-newEvent:
-		}
+	update := repository.UpdateRequest{
+		Uuid:     docUUID,
+		Document: rpcdoc.DocumentToRPC(doc),
+		IfMatch:  -1,
+		Status:   req.Status,
+		Acl:      make([]*repository.ACLEntry, len(acl)),
+	}
 
-		evts, err := a.store.GetEventlog(
-			ctx, after,
-			// Limited in practice by maxEventlogBatchSize, so no
-			// integer overflow.
-			limit-internal.MustInt32(len(res.Items)),
-		)
-		if err != nil {
-			return nil, twirp.InternalErrorf(
-				"failed to fetch events from store: %w", err)
+	for i, e := range acl {
+		update.Acl[i] = &repository.ACLEntry{
+			Uri:         e.URI,
+			Permissions: e.Permissions,
 		}
+	}
 
-		for i := range evts {
-			if evts[i].ID > after {
-				after = evts[i].ID
-			}
+	res, err := a.Update(ctx, &update)
+	if err != nil {
+		return nil, err
+	}
 
-			res.Items = append(res.Items, EventToRPC(evts[i]))
-		}
+	return &CreateFromTemplateResponse{
+		UUID:            res.Uuid,
+		Version:         res.Version,
+		TemplateVersion: tmpl.Version,
+	}, nil
+}
 
-		if len(res.Items) == int(limit) {
-			return res, nil
-		}
+// withTemplateACLGrant adds permissions for a URI to a template ACL, merging
+// them with the permissions of an existing entry for the URI.
+func withTemplateACLGrant(
+	acl []TemplateACLEntry, uri string, permissions ...string,
+) []TemplateACLEntry {
+	merged := make([]TemplateACLEntry, 0, len(acl)+1)
+	found := false
 
-		if batchTimeout == nil && len(res.Items) > 0 {
-			batchTimeout = time.After(waitBatch)
-		}
-	}
-}
+	for _, e := range acl {
+		if e.URI == uri {
+			found = true
 
-func RPCToEvent(evt *repository.EventlogItem) (Event, error) {
-	acl := make([]ACLEntry, len(evt.Acl))
+			e.Permissions = slices.Clone(e.Permissions)
 
-	for i, a := range evt.Acl {
-		if a == nil {
-			continue
+			for _, p := range permissions {
+				if !slices.Contains(e.Permissions, p) {
+					e.Permissions = append(e.Permissions, p)
+				}
+			}
 		}
 
-		acl[i] = ACLEntry{
-			URI:         a.Uri,
-			Permissions: a.Permissions,
-		}
+		merged = append(merged, e)
 	}
 
-	docUUID, err := uuid.Parse(evt.Uuid)
-	if err != nil {
-		return Event{}, fmt.Errorf(
-			"invalid document UUID for event: %w", err)
+	if !found {
+		merged = append(merged, TemplateACLEntry{
+			URI:         uri,
+			Permissions: permissions,
+		})
 	}
 
-	docNonce, err := uuid.Parse(evt.DocumentNonce)
+	return merged
+}
+
+type DuplicateRequest struct {
+	UUID string `json:"uuid"`
+	// Version to duplicate, defaults to the current version.
+	Version int64 `json:"version,omitempty"`
+	// NewUUID of the duplicate, a random UUID is used if it's omitted.
+	NewUUID string `json:"new_uuid,omitempty"`
+	// Type rewrites the type of the duplicate.
+	Type string `json:"type,omitempty"`
+	// URI of the duplicate, defaults to the URI of the original with its
+	// UUID replaced by the UUID of the duplicate.
+	URI string `json:"uri,omitempty"`
+	// CopyACL copies the ACL of the original instead of using the default
+	// ACL for new documents.
+	CopyACL bool `json:"copy_acl,omitempty"`
+}
+
+type DuplicateResponse struct {
+	UUID    string `json:"uuid"`
+	Version int64  `json:"version"`
+	// MetaVersion is the version of the copied meta document, zero if the
+	// original didn't have a meta document.
+	MetaVersion int64 `json:"meta_version,omitempty"`
+}
+
+// Duplicate creates a copy of a version of a document under a new UUID. The
+// meta document and the attachments of the version are copied along with it. The
+// copy gets "duplicated_from" and "duplicated_from_version" version metadata,
+// the original is left untouched.
+func (a *DocumentsService) Duplicate(
+	ctx context.Context, req *DuplicateRequest,
+) (_ *DuplicateResponse, outErr error) {
+	elephantine.SetLogMetadata(ctx,
+		elephantine.LogKeyDocumentUUID, req.UUID)
+
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentWrite, ScopeDocumentAdmin,
+	)
 	if err != nil {
-		return Event{}, fmt.Errorf(
-			"invalid document nonce for event: %w", err)
+		return nil, err
 	}
 
-	timestamp, err := time.Parse(time.RFC3339, evt.Timestamp)
+	sourceUUID, err := validateRequiredUUIDParam(req.UUID)
 	if err != nil {
-		return Event{}, fmt.Errorf(
-			"invalid timestamp for event: %w", err)
+		return nil, err
 	}
 
-	timespans, err := TimespanTuplesFromRPC(evt.Timespans)
-	if err != nil {
-		return Event{}, fmt.Errorf(
-			"invalid timespans for event: %w", err)
+	if req.Version < 0 {
+		return nil, twirp.InvalidArgumentError("version",
+			"cannot be a negative number")
 	}
 
-	var mainDoc *uuid.UUID
+	newUUID := uuid.New()
 
-	if evt.MainDocument != "" {
-		u, err := uuid.Parse(evt.MainDocument)
+	if req.NewUUID != "" {
+		newUUID, err = uuid.Parse(req.NewUUID)
 		if err != nil {
-			return Event{}, fmt.Errorf(
-				"invalid main document UUID: %w", err)
+			return nil, twirp.InvalidArgumentError("new_uuid",
+				err.Error())
 		}
-
-		mainDoc = &u
 	}
 
-	return Event{
-		ID:               evt.Id,
-		Event:            EventType(evt.Event),
-		UUID:             docUUID,
-		Nonce:            docNonce,
-		Type:             evt.Type,
-		Timestamp:        timestamp,
-		Updater:          evt.UpdaterUri,
-		Version:          evt.Version,
-		Status:           evt.Status,
-		StatusID:         evt.StatusId,
-		ACL:              acl,
-		MainDocument:     mainDoc,
-		MainDocumentType: evt.MainDocumentType,
-		Language:         evt.Language,
-		OldLanguage:      evt.OldLanguage,
-		AttachedObjects:  evt.AttachedObjects,
-		DetachedObjects:  evt.DetachedObjects,
-		Timespans:        timespans,
-	}, nil
-}
+	if newUUID == sourceUUID {
+		return nil, twirp.InvalidArgumentError("new_uuid",
+			"must be different from the original UUID")
+	}
 
-func EventToRPC(evt Event) *repository.EventlogItem {
-	acl := make([]*repository.ACLEntry, len(evt.ACL))
+	err = a.accessCheck(ctx, auth, sourceUUID, ReadPermission)
+	if err != nil {
+		return nil, err
+	}
 
-	for i, a := range evt.ACL {
-		acl[i] = &repository.ACLEntry{
-			Uri:         a.URI,
-			Permissions: a.Permissions,
-		}
+	meta, err := a.store.GetDocumentMeta(ctx, sourceUUID)
+	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
+		return nil, twirp.NotFoundError("the document doesn't exist")
+	} else if err != nil {
+		return nil, twirp.InternalErrorf(
+			"failed to load document metadata: %v", err)
 	}
 
-	var mainDoc string
+	if meta.MainDocument != "" {
+		return nil, twirp.InvalidArgumentError("uuid",
+			"meta documents cannot be duplicated")
+	}
 
-	if evt.MainDocument != nil {
-		mainDoc = evt.MainDocument.String()
+	version := req.Version
+	if version == 0 {
+		version = meta.CurrentVersion
 	}
 
-	return &repository.EventlogItem{
-		Id:                 evt.ID,
-		Event:              string(evt.Event),
-		Uuid:               evt.UUID.String(),
-		DocumentNonce:      evt.Nonce.String(),
-		Type:               evt.Type,
-		Timestamp:          evt.Timestamp.Format(time.RFC3339),
-		UpdaterUri:         evt.Updater,
-		Version:            evt.Version,
-		Status:             evt.Status,
-		StatusId:           evt.StatusID,
-		Acl:                acl,
-		MainDocument:       mainDoc,
-		MainDocumentType:   evt.MainDocumentType,
-		Language:           evt.Language,
-		OldLanguage:        evt.OldLanguage,
-		SystemState:        evt.SystemState,
-		WorkflowState:      evt.WorkflowStep,
-		WorkflowCheckpoint: evt.WorkflowCheckpoint,
-		AttachedObjects:    evt.AttachedObjects,
-		DetachedObjects:    evt.DetachedObjects,
-		DeleteRecordId:     evt.DeleteRecordID,
-		Timespans:          TimespanTuplesToRPC(evt.Timespans),
-		Labels:             evt.Labels,
-		SchemaGeneration:   evt.SchemaGeneration,
+	doc, _, err := a.store.GetDocument(ctx, sourceUUID, version)
+	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
+		return nil, twirp.NotFoundError("no such version")
+	} else if err != nil {
+		return nil, twirp.InternalErrorf(
+			"failed to load document version: %v", err)
 	}
-}
 
-func TimespanFromRPC(ts *repository.Timespan) (Timespan, error) {
-	var z Timespan
+	dup := *doc
 
-	from, err := time.Parse(time.RFC3339, ts.From)
-	if err != nil {
-		return z, fmt.Errorf(
-			"invalid Timespan.from: %w", err)
-	}
+	dup.UUID = newUUID.String()
 
-	to, err := time.Parse(time.RFC3339, ts.To)
-	if err != nil {
-		return z, fmt.Errorf(
-			"invalid Timespan.to: %w", err)
+	if req.Type != "" {
+		dup.Type = req.Type
 	}
 
-	return Timespan{
-		From: from,
-		To:   to,
-	}, nil
-}
-
-func TimespanTuplesToRPC(tuples [][2]time.Time) []*repository.Timespan {
-	if tuples == nil {
-		return nil
+	switch {
+	case req.URI != "":
+		dup.URI = req.URI
+	case strings.Contains(doc.URI, sourceUUID.String()):
+		dup.URI = strings.ReplaceAll(doc.URI,
+			sourceUUID.String(), newUUID.String())
+	default:
+		return nil, twirp.InvalidArgumentError("uri",
+			"required when the URI of the original doesn't contain its UUID")
 	}
 
-	spans := make([]*repository.Timespan, len(tuples))
+	dupMeta := map[string]string{
+		"duplicated_from":         sourceUUID.String(),
+		"duplicated_from_version": strconv.FormatInt(version, 10),
+	}
 
-	for i := range tuples {
-		spans[i] = &repository.Timespan{
-			From: tuples[i][0].Format(time.RFC3339),
-			To:   tuples[i][1].Format(time.RFC3339),
-		}
+	attached, err := a.store.GetAttachedObjectsAtVersion(ctx,
+		sourceUUID, version)
+	if err != nil {
+		return nil, twirp.InternalErrorf(
+			"failed to load attachments: %v", err)
 	}
 
-	return spans
-}
+	attach := make(map[string]string, len(attached))
 
-func TimespanTuplesFromRPC(spans []*repository.Timespan) ([][2]time.Time, error) {
-	if spans == nil {
-		return nil, nil
-	}
+	var uploads []uuid.UUID
 
-	tuples := make([][2]time.Time, len(spans))
+	// The copied objects are only used if the duplicate is written, the
+	// upload janitor expires any uploads that we fail to discard.
+	defer func() {
+		if outErr != nil {
+			a.discardUploads(context.WithoutCancel(ctx), uploads)
+		}
+	}()
+
+	for _, obj := range attached {
+		upload := Upload{
+			ID:        uuid.New(),
+			CreatedBy: auth.Claims.Subject,
+			CreatedAt: time.Now(),
+			Meta: AssetMetadata{
+				Filename: obj.Filename,
+				Mimetype: obj.Mimetype,
+				Props:    obj.Props,
+				SHA256:   obj.SHA256,
+				Size:     obj.Size,
+			},
+		}
 
-	for i := range spans {
-		from, err := time.Parse(time.RFC3339, spans[i].From)
+		err := a.store.CreateUpload(ctx, upload)
 		if err != nil {
-			return nil, fmt.Errorf(
-				"invalid 'from' value %q for timespan %d: %w",
-				spans[i].From, i+1, err)
+			return nil, twirp.InternalErrorf(
+				"store upload record: %v", err)
 		}
 
-		to, err := time.Parse(time.RFC3339, spans[i].To)
+		uploads = append(uploads, upload.ID)
+
+		err = a.assets.CopyToUpload(ctx,
+			sourceUUID, obj.Name, obj.ObjectVersion, upload.ID)
 		if err != nil {
-			return nil, fmt.Errorf(
-				"invalid 'to' value %q for timespan %d: %w",
-				spans[i].To, i+1, err)
+			return nil, twirp.InternalErrorf(
+				"copy %q attachment: %v", obj.Name, err)
 		}
 
-		tuples[i] = [2]time.Time{from, to}
+		attach[obj.Name] = upload.ID.String()
 	}
 
-	return tuples, nil
-}
+	updates := []*repository.UpdateRequest{
+		{
+			Uuid:          dup.UUID,
+			Document:      rpcdoc.DocumentToRPC(dup),
+			Meta:          dupMeta,
+			IfMatch:       -1,
+			AttachObjects: attach,
+		},
+	}
 
-// Evict implements repository.Documents.
-func (a *DocumentsService) Evict(
-	_ context.Context, _ *repository.EvictRequest,
-) (*repository.EvictResponse, error) {
-	return nil, twirp.Unimplemented.Error("not implemented")
-}
+	sourceMetaUUID, _ := metaIdentity(sourceUUID)
 
-// Delete implements repository.Documents.
-func (a *DocumentsService) Delete(
-	ctx context.Context, req *repository.DeleteDocumentRequest,
-) (*repository.DeleteDocumentResponse, error) {
-	elephantine.SetLogMetadata(ctx,
-		elephantine.LogKeyDocumentUUID, req.Uuid,
-	)
+	metaDoc, _, err := a.store.GetDocument(ctx, sourceMetaUUID, 0)
 
-	auth, err := RequireAnyScope(ctx,
-		ScopeDocumentDelete, ScopeDocumentAdmin,
-	)
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"failed to load meta document: %v", err)
+	default:
+		// The meta document identity is derived from the main
+		// document by the update.
+		metaDoc.UUID = ""
+		metaDoc.URI = ""
+
+		updates = append(updates, &repository.UpdateRequest{
+			Uuid:               dup.UUID,
+			Document:           rpcdoc.DocumentToRPC(*metaDoc),
+			Meta:               dupMeta,
+			IfMatch:            -1,
+			UpdateMetaDocument: true,
+		})
+	}
+
+	_, err = a.verifyUpdateRequests(ctx, updates)
 	if err != nil {
 		return nil, err
 	}
 
-	if req.IfMatch < -1 {
-		return nil, twirp.InvalidArgumentError("if_match",
-			"cannot be less than -1")
+	ups := make([]*UpdateRequest, len(updates))
+
+	for i := range updates {
+		ups[i], err = a.buildUpdateRequest(ctx, auth, updates[i])
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	docUUID, err := validateRequiredUUIDParam(req.Uuid)
+	if req.CopyACL {
+		acl, err := a.store.GetDocumentACL(ctx, sourceUUID)
+		if err != nil {
+			return nil, twirp.InternalErrorf(
+				"failed to read document ACL: %v", err)
+		}
+
+		ups[0].ACL = acl
+	}
+
+	// The copy and the copy of the meta document are written in one
+	// transaction.
+	written, err := a.store.Update(ctx, a.workflows, ups)
 	if err != nil {
-		return nil, err
+		return nil, twirpErrorFromDocumentUpdateError(err)
 	}
 
-	err = a.accessCheck(ctx, auth, docUUID, WritePermission)
-	if elephantine.IsTwirpErrorCode(err, twirp.NotFound) {
-		// Treat a delete of a document that doesn't exist as ok.
-		return &repository.DeleteDocumentResponse{}, nil
-	} else if err != nil {
-		return nil, err
+	res := DuplicateResponse{
+		UUID:    dup.UUID,
+		Version: written[0].Version,
 	}
 
-	err = a.store.Delete(ctx, DeleteRequest{
-		UUID:      docUUID,
-		Updated:   time.Now(),
-		Updater:   auth.Claims.Subject,
-		Meta:      req.Meta,
-		IfMatch:   req.IfMatch,
-		LockToken: req.LockToken,
-	})
+	if len(written) > 1 {
+		res.MetaVersion = written[1].Version
+	}
 
-	switch {
-	case IsDocStoreErrorCode(err, ErrCodeFailedPrecondition):
-		return nil, twirp.FailedPrecondition.Error(err.Error())
-	case IsDocStoreErrorCode(err, ErrCodeDocumentLock):
-		return nil, twirp.FailedPrecondition.Error("the document is locked by someone else")
-	case IsDocStoreErrorCode(err, ErrCodeDeleteLock):
-		// Treating a delete call as a success if the delete already is
-		// in progress.
-		return &repository.DeleteDocumentResponse{}, nil
-	case err != nil:
-		return nil, twirp.InternalErrorf(
-			"failed to delete document from data store: %w", err)
+	return &res, nil
+}
+
+// discardUploads deletes uploads that were created for an operation that
+// failed. This is a best effort, the upload janitor expires what's left.
+func (a *DocumentsService) discardUploads(
+	ctx context.Context, uploads []uuid.UUID,
+) {
+	for _, id := range uploads {
+		err := a.assets.DeleteUpload(ctx, id)
+		if err != nil {
+			continue
+		}
+
+		_ = a.store.DeleteUpload(ctx, id)
 	}
+}
 
-	return &repository.DeleteDocumentResponse{}, nil
+type GetBacklinksRequest struct {
+	UUID string `json:"uuid"`
+	// Rel optionally restricts the lookup to links with the given rel.
+	Rel string `json:"rel,omitempty"`
+	// Type optionally restricts the lookup to linking documents of the
+	// given type.
+	Type string `json:"type,omitempty"`
+	// After is the cursor returned as "next" by the previous page.
+	After string `json:"after,omitempty"`
+	Limit int64  `json:"limit,omitempty"`
 }
 
-// ListDeleted implements repository.Documents.
-func (a *DocumentsService) ListDeleted(
-	ctx context.Context, req *repository.ListDeletedRequest,
-) (*repository.ListDeletedResponse, error) {
-	_, err := RequireAnyScope(ctx,
-		ScopeDocumentRestore,
+type GetBacklinksResponse struct {
+	Backlinks []Backlink `json:"backlinks"`
+	// Next is the cursor for the next page, empty when there are no
+	// more backlinks.
+	Next string `json:"next,omitempty"`
+}
+
+const (
+	maxBacklinksLimit = 500
+	maxBacklinksScans = 10
+)
+
+// GetBacklinks returns the documents that link to a document. Only documents
+// that the caller has read access to are returned.
+func (a *DocumentsService) GetBacklinks(
+	ctx context.Context, req *GetBacklinksRequest,
+) (*GetBacklinksResponse, error) {
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentRead, ScopeDocumentReadAll,
 		ScopeDocumentAdmin,
-		ScopeDocumentPurge,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	var (
-		docUUID    *uuid.UUID
-		beforeTime *time.Time
-	)
+	docUUID, err := validateRequiredUUIDParam(req.UUID)
+	if err != nil {
+		return nil, err
+	}
 
-	if req.Uuid != "" {
-		u, err := uuid.Parse(req.Uuid)
+	// The backlinks reveal what the document is used for, so the caller
+	// must be able to read it.
+	err = a.accessCheck(ctx, auth, docUUID, ReadPermission)
+	if err != nil {
+		return nil, err
+	}
+
+	query := BacklinkQuery{
+		UUID:  docUUID,
+		Rel:   req.Rel,
+		Type:  req.Type,
+		Limit: req.Limit,
+	}
+
+	if req.After != "" {
+		after, err := uuid.Parse(req.After)
 		if err != nil {
-			return nil, twirp.InvalidArgumentError(
-				"uuid", err.Error())
+			return nil, twirp.InvalidArgumentError("after",
+				"invalid cursor")
 		}
 
-		docUUID = &u
+		query.After = after
 	}
 
-	tz := time.UTC
+	if query.Limit == 0 {
+		query.Limit = 100
+	}
 
-	if req.Timezone != "" {
-		l, err := time.LoadLocation(req.Timezone)
-		if err != nil {
-			return nil, twirp.InvalidArgumentError(
-				"timezone", "unknown timezone")
-		}
+	if query.Limit < 0 || query.Limit > maxBacklinksLimit {
+		return nil, twirp.InvalidArgumentError("limit",
+			fmt.Sprintf("must be between 1 and %d", maxBacklinksLimit))
+	}
 
-		tz = l
+	aclBypass := auth.Claims.HasAnyScope(
+		ScopeDocumentReadAll, ScopeDocumentAdmin)
+
+	res := GetBacklinksResponse{
+		Backlinks: []Backlink{},
 	}
 
-	if req.BeforeDate != "" {
-		t, err := time.ParseInLocation("2006-01-02", req.BeforeDate, tz)
+	// Backlinks that the caller doesn't have access to are filtered out,
+	// so we keep on reading until the page is full or we run out of
+	// backlinks. The number of reads is capped so that callers with
+	// access to few of many backlinks can't make us scan the whole
+	// index, if we hit the cap we return what we have with a cursor to
+	// continue from.
+	for range maxBacklinksScans {
+		links, err := a.store.GetBacklinks(ctx, query)
 		if err != nil {
-			return nil, twirp.InvalidArgumentError(
-				"start_at_date", err.Error())
+			return nil, twirp.InternalErrorf(
+				"failed to read backlinks: %v", err)
 		}
 
-		beforeTime = &t
-	}
+		permitted, err := a.permittedBacklinks(ctx, auth, aclBypass, links)
+		if err != nil {
+			return nil, err
+		}
 
-	deleted, err := a.store.ListDeleteRecords(ctx, docUUID, req.BeforeId, beforeTime)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list delete records: %w", err)
-	}
+		for _, l := range links {
+			if !permitted[l.UUID] {
+				continue
+			}
 
-	res := repository.ListDeletedResponse{
-		Deletes: make([]*repository.DeleteRecord, len(deleted)),
-	}
+			res.Backlinks = append(res.Backlinks, l)
 
-	for i, d := range deleted {
-		r := repository.DeleteRecord{
-			Id:       d.ID,
-			Uuid:     d.UUID.String(),
-			Uri:      d.URI,
-			Type:     d.Type,
-			Language: d.Language,
-			Version:  d.Version,
-			Created:  d.Created.Format(time.RFC3339),
-			Creator:  d.Creator,
-			Meta:     d.Meta,
-		}
+			if int64(len(res.Backlinks)) == query.Limit {
+				res.Next = l.UUID.String()
 
-		if d.Finalised != nil {
-			r.Finalised = d.Finalised.Format(time.RFC3339)
+				return &res, nil
+			}
 		}
 
-		if d.Purged != nil {
-			r.Purged = d.Purged.Format(time.RFC3339)
+		if int64(len(links)) < query.Limit {
+			return &res, nil
 		}
 
-		res.Deletes[i] = &r
+		query.After = links[len(links)-1].UUID
 	}
 
+	res.Next = query.After.String()
+
 	return &res, nil
 }
 
-// Restore implements repository.Documents.
-func (a *DocumentsService) Restore(
-	ctx context.Context, req *repository.RestoreRequest,
-) (*repository.RestoreResponse, error) {
-	auth, err := RequireAnyScope(ctx,
-		ScopeDocumentRestore,
-		ScopeDocumentAdmin,
-	)
-	if err != nil {
-		return nil, err
-	}
+func (a *DocumentsService) permittedBacklinks(
+	ctx context.Context, auth *elephantine.AuthInfo,
+	aclBypass bool, links []Backlink,
+) (map[uuid.UUID]bool, error) {
+	permitted := make(map[uuid.UUID]bool, len(links))
 
-	if req.Uuid == "" {
-		return nil, twirp.RequiredArgumentError("uuid")
+	if aclBypass {
+		for _, l := range links {
+			permitted[l.UUID] = true
+		}
+
+		return permitted, nil
 	}
 
-	if req.DeleteRecordId == 0 {
-		return nil, twirp.RequiredArgumentError("delete_record_id")
+	if len(links) == 0 {
+		return permitted, nil
 	}
 
-	docUUID, err := uuid.Parse(req.Uuid)
-	if err != nil {
-		return nil, twirp.InvalidArgumentError(
-			"uuid", err.Error())
+	uuids := make([]uuid.UUID, len(links))
+
+	for i, l := range links {
+		uuids[i] = l.UUID
 	}
 
-	err = verifyACLParam(req.Acl)
+	allowed, err := a.store.BulkCheckPermissions(ctx,
+		BulkCheckPermissionRequest{
+			UUIDs: uuids,
+			GranteeURIs: append([]string{auth.Claims.Subject},
+				auth.Claims.Units...),
+			Permissions: []Permission{ReadPermission},
+		})
 	if err != nil {
-		return nil, err
+		return nil, twirp.InternalErrorf("check ACL access: %v", err)
 	}
 
-	acl := aclListFromRPC(req.Acl, auth.Claims.Subject)
+	for _, id := range allowed {
+		permitted[id] = true
+	}
 
-	err = a.store.RestoreDocument(ctx,
-		docUUID, req.DeleteRecordId,
-		auth.Claims.Subject, acl)
+	return permitted, nil
+}
 
-	switch {
-	case IsDocStoreErrorCode(err, ErrCodeExists):
-		return nil, twirp.AlreadyExists.Error(err.Error())
-	case IsDocStoreErrorCode(err, ErrCodeFailedPrecondition):
-		return nil, twirp.FailedPrecondition.Error(err.Error())
-	case IsDocStoreErrorCode(err, ErrCodeBadRequest):
-		return nil, twirp.InvalidArgument.Error(err.Error())
-	case IsDocStoreErrorCode(err, ErrCodeNotFound):
-		return nil, twirp.NotFoundError(err.Error())
-	case err != nil:
-		return nil, twirp.InternalErrorf(
-			"failed to start restore process: %v", err)
-	}
+type GetReadAuditRequest struct {
+	UUID    string `json:"uuid,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	// After is the ID of the last entry that has been read.
+	After int64 `json:"after,omitempty"`
+	Limit int64 `json:"limit,omitempty"`
+}
 
-	return &repository.RestoreResponse{}, nil
+type GetReadAuditResponse struct {
+	Entries []ReadAuditEntry `json:"entries"`
 }
 
-// Purge implements repository.Documents.
-func (a *DocumentsService) Purge(
-	ctx context.Context, req *repository.PurgeRequest,
-) (*repository.PurgeResponse, error) {
-	auth, err := RequireAnyScope(ctx,
-		ScopeDocumentPurge,
-		ScopeDocumentAdmin,
-	)
+// GetReadAudit returns read audit log entries for a document or a subject.
+func (a *DocumentsService) GetReadAudit(
+	ctx context.Context, req *GetReadAuditRequest,
+) (*GetReadAuditResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeReadAudit, ScopeDocumentAdmin)
 	if err != nil {
 		return nil, err
 	}
 
-	if req.Uuid == "" {
-		return nil, twirp.RequiredArgumentError("uuid")
+	if req.UUID == "" && req.Subject == "" {
+		return nil, twirp.InvalidArgumentError("uuid",
+			"a document UUID or a subject is required")
 	}
 
-	if req.DeleteRecordId == 0 {
-		return nil, twirp.RequiredArgumentError("delete_record_id")
+	query := ReadAuditQuery{
+		Subject: req.Subject,
+		After:   req.After,
+		Limit:   req.Limit,
 	}
 
-	docUUID, err := uuid.Parse(req.Uuid)
-	if err != nil {
-		return nil, twirp.InvalidArgumentError(
-			"uuid", err.Error())
+	if req.UUID != "" {
+		docUUID, err := validateRequiredUUIDParam(req.UUID)
+		if err != nil {
+			return nil, err
+		}
+
+		query.UUID = &docUUID
 	}
 
-	err = a.store.PurgeDocument(ctx,
-		docUUID, req.DeleteRecordId,
-		auth.Claims.Subject)
+	if query.Limit == 0 {
+		query.Limit = 100
+	}
 
-	switch {
-	case IsDocStoreErrorCode(err, ErrCodeFailedPrecondition):
-		return nil, twirp.FailedPrecondition.Error(err.Error())
-	case IsDocStoreErrorCode(err, ErrCodeNotFound):
-		return nil, twirp.NotFoundError(err.Error())
-	case err != nil:
+	if query.Limit < 0 || query.Limit > 1000 {
+		return nil, twirp.InvalidArgumentError("limit",
+			"must be between 1 and 1000")
+	}
+
+	entries, err := a.store.GetReadAudit(ctx, query)
+	if err != nil {
 		return nil, twirp.InternalErrorf(
-			"failed to start purge process: %v", err)
+			"failed to read audit log: %v", err)
 	}
 
-	return &repository.PurgeResponse{}, nil
+	return &GetReadAuditResponse{
+		Entries: entries,
+	}, nil
 }
 
-func parseSubsetExpressions(
-	expressions []string,
-) ([]*newsdoc.ValueExtractor, error) {
-	if len(expressions) == 0 {
-		return nil, nil
+type StartExportRequest struct {
+	UUIDs []string `json:"uuids,omitempty"`
+	Type  string   `json:"type,omitempty"`
+	// From and To select documents of the type with a timespan that
+	// overlaps the interval.
+	From   *time.Time `json:"from,omitempty"`
+	To     *time.Time `json:"to,omitempty"`
+	Labels []string   `json:"labels,omitempty"`
+}
+
+type StartExportResponse struct {
+	Export *DocumentExport `json:"export"`
+}
+
+// StartExport starts a background export of the selected documents, with
+// their full version and status history, ACL and attachments, to a signed
+// bundle in the archive bucket.
+func (a *DocumentsService) StartExport(
+	ctx context.Context, req *StartExportRequest,
+) (*StartExportResponse, error) {
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentExport, ScopeDocumentAdmin)
+	if err != nil {
+		return nil, err
 	}
 
-	extractors := make([]*newsdoc.ValueExtractor, len(expressions))
+	if len(req.UUIDs) == 0 && req.Type == "" && len(req.Labels) == 0 {
+		return nil, twirp.InvalidArgumentError("uuids",
+			"a list of documents, a type, or labels is required")
+	}
 
-	for i, expr := range expressions {
-		ve, err := newsdoc.ValueExtractorFromString(expr)
+	query := ExportQuery{
+		Type:   req.Type,
+		Labels: req.Labels,
+	}
+
+	for i, u := range req.UUIDs {
+		docUUID, err := uuid.Parse(u)
 		if err != nil {
 			return nil, twirp.InvalidArgumentError(
-				fmt.Sprintf("subset[%d]", i), err.Error())
+				fmt.Sprintf("uuids.%d", i), err.Error())
 		}
 
-		extractors[i] = ve
+		query.UUIDs = append(query.UUIDs, docUUID)
 	}
 
-	return extractors, nil
-}
+	switch {
+	case req.From == nil && req.To == nil:
+	case req.From == nil || req.To == nil:
+		return nil, twirp.InvalidArgumentError("from",
+			"both from and to must be set for a timespan")
+	case req.Type == "":
+		return nil, twirp.InvalidArgumentError("type",
+			"a timespan can only be used together with a type")
+	case req.To.Before(*req.From):
+		return nil, twirp.InvalidArgumentError("to",
+			"must not be before from")
+	default:
+		query.From = req.From
+		query.To = req.To
+	}
 
-func collectSubset(
-	doc newsdoc.Document, extractors []*newsdoc.ValueExtractor,
-) []*repository.ExtractedValues {
-	var result []*repository.ExtractedValues
+	exp, err := a.store.StartExport(ctx, query, auth.Claims.Subject)
+	if err != nil {
+		return nil, twirp.InternalErrorf("start export: %v", err)
+	}
 
-	for ie, ve := range extractors {
-		items := ve.Collect(doc)
+	return &StartExportResponse{
+		Export: exp,
+	}, nil
+}
 
-		for _, item := range items {
-			values := make(
-				map[string]*repository.ExtractedValue, len(item),
-			)
+type GetExportRequest struct {
+	ID int64 `json:"id"`
+}
 
-			for key, nev := range item {
-				ev := &repository.ExtractedValue{
-					Value:      nev.Value,
-					Annotation: nev.Annotation,
-					Role:       nev.Role,
-				}
+type GetExportResponse struct {
+	Export *DocumentExport `json:"export"`
+}
 
-				if nev.Block != nil {
-					ev.Block = rpcdoc.BlockToRPC(*nev.Block)
-				}
+// GetExport returns the progress of an export.
+func (a *DocumentsService) GetExport(
+	ctx context.Context, req *GetExportRequest,
+) (*GetExportResponse, error) {
+	_, err := RequireAnyScope(ctx,
+		ScopeDocumentExport, ScopeDocumentAdmin)
+	if err != nil {
+		return nil, err
+	}
 
-				values[key] = ev
-			}
+	if req.ID == 0 {
+		return nil, twirp.RequiredArgumentError("id")
+	}
 
-			result = append(result, &repository.ExtractedValues{
-				Extractor: int64(ie),
-				Values:    values,
-			})
-		}
+	exp, err := a.store.GetExport(ctx, req.ID)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.NotFound.Error(err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf("read export: %v", err)
 	}
 
-	return result
+	return &GetExportResponse{
+		Export: exp,
+	}, nil
 }
 
-// Get implements repository.Documents.
-func (a *DocumentsService) Get(
-	ctx context.Context, req *repository.GetDocumentRequest,
-) (*repository.GetDocumentResponse, error) {
-	elephantine.SetLogMetadata(ctx,
-		elephantine.LogKeyDocumentUUID, req.Uuid,
-	)
+type ExplainPermissionRequest struct {
+	UUID       string `json:"uuid"`
+	Permission string `json:"permission"`
+	// Subject to explain the permission for, defaults to the caller.
+	// Explaining the permissions of other subjects requires the doc_admin
+	// scope, and as their token isn't available their units and scopes
+	// must be provided explicitly.
+	Subject string   `json:"subject,omitempty"`
+	Units   []string `json:"units,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+}
 
-	requireMetaDoc := req.MetaDocument == repository.GetMetaDoc_META_ONLY
-	includeMetaDoc := requireMetaDoc ||
-		req.MetaDocument == repository.GetMetaDoc_META_INCLUDE
+// PermissionDecision is the outcome of a permission check.
+type PermissionDecision string
+
+const (
+	PermissionDecisionScope        PermissionDecision = "scope"
+	PermissionDecisionACL          PermissionDecision = "acl"
+	PermissionDecisionMissingScope PermissionDecision = "missing_scope"
+	PermissionDecisionNoGrant      PermissionDecision = "no_grant"
+	PermissionDecisionNoDocument   PermissionDecision = "no_such_document"
+	PermissionDecisionSystemLock   PermissionDecision = "system_lock"
+)
+
+type ExplainPermissionResponse struct {
+	Allowed     bool                    `json:"allowed"`
+	Decision    PermissionDecision      `json:"decision"`
+	Reason      string                  `json:"reason"`
+	Subject     string                  `json:"subject"`
+	Units       []string                `json:"units,omitempty"`
+	Scopes      []ScopeCheck            `json:"scopes"`
+	ACL         []ACLGrantCheck         `json:"acl"`
+	SystemState string                  `json:"system_state,omitempty"`
+	Lock        *LockCheck              `json:"lock,omitempty"`
+	StatusRules []StatusAccessRuleCheck `json:"status_rules,omitempty"`
+}
+
+// ScopeCheck describes a scope that was considered for the permission.
+type ScopeCheck struct {
+	Scope   string `json:"scope"`
+	Present bool   `json:"present"`
+	// Effect is "grants" for scopes that bypass the ACL, and "required"
+	// for scopes that are needed to get the permission through the ACL.
+	Effect string `json:"effect"`
+}
+
+// ACLGrantCheck describes an ACL entry that matched the subject or one of
+// its units.
+type ACLGrantCheck struct {
+	Source      ACLGrantSource `json:"source"`
+	Document    string         `json:"document"`
+	Inheritor   string         `json:"inheritor,omitempty"`
+	URI         string         `json:"uri"`
+	Permissions []string       `json:"permissions"`
+	Inheritable []string       `json:"inheritable,omitempty"`
+	NotBefore   *time.Time     `json:"not_before,omitempty"`
+	Expires     *time.Time     `json:"expires,omitempty"`
+	InEffect    bool           `json:"in_effect"`
+	Grants      bool           `json:"grants"`
+}
+
+// LockCheck describes the current lock of the document. A lock doesn't
+// affect permissions, but blocks updates from clients that don't hold it.
+type LockCheck struct {
+	URI           string    `json:"uri"`
+	App           string    `json:"app,omitempty"`
+	Comment       string    `json:"comment,omitempty"`
+	Expires       time.Time `json:"expires"`
+	Exclusivity   string    `json:"exclusivity"`
+	Blocks        []string  `json:"blocks"`
+	HeldBySubject bool      `json:"held_by_subject"`
+}
+
+// StatusAccessRuleCheck describes a status rule that is treated as an access
+// rule. Status rules are evaluated when statuses are set, so they're listed
+// without being evaluated.
+type StatusAccessRuleCheck struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	AppliesTo   []string `json:"applies_to"`
+	Expression  string   `json:"expression"`
+}
 
+// ExplainPermission explains how a permission check for a document is
+// decided for a subject.
+func (a *DocumentsService) ExplainPermission(
+	ctx context.Context, req *ExplainPermissionRequest,
+) (*ExplainPermissionResponse, error) {
 	auth, err := RequireAnyScope(ctx,
 		ScopeDocumentRead, ScopeDocumentReadAll,
+		ScopeDocumentWrite, ScopeDocumentDelete,
 		ScopeDocumentAdmin,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	if req.Version < 0 {
-		return nil, twirp.InvalidArgumentError("version",
-			"cannot be a negative number")
+	docUUID, err := validateRequiredUUIDParam(req.UUID)
+	if err != nil {
+		return nil, err
 	}
 
-	if req.Lock != nil && req.Lock.Ttl == 0 {
-		return nil, twirp.RequiredArgumentError("lock.ttl")
+	if req.Permission == "" {
+		return nil, twirp.RequiredArgumentError("permission")
 	}
 
-	if req.Version > 0 && req.Status != "" {
-		return nil, twirp.InvalidArgumentError("status",
-			"status cannot be specified together with a version")
+	permission := Permission(req.Permission)
+
+	if !IsValidPermission(permission) {
+		return nil, twirp.InvalidArgumentError("permission",
+			fmt.Sprintf("%q is not a valid permission", req.Permission))
 	}
 
-	if req.MetaDocumentVersion > 0 && req.Status != "" {
-		return nil, twirp.InvalidArgumentError("status",
-			"status cannot be specified together with a meta document version")
+	claims := auth.Claims
+
+	if req.Subject != "" && req.Subject != auth.Claims.Subject {
+		if !auth.Claims.HasScope(ScopeDocumentAdmin) {
+			return nil, twirp.PermissionDenied.Errorf(
+				"the %q scope is required to explain the permissions of other subjects",
+				ScopeDocumentAdmin)
+		}
+
+		claims = elephantine.JWTClaims{
+			Scope: strings.Join(req.Scopes, " "),
+			Units: req.Units,
+		}
+		claims.Subject = req.Subject
 	}
 
-	docUUID, err := validateRequiredUUIDParam(req.Uuid)
-	if err != nil {
-		return nil, err
+	resp := ExplainPermissionResponse{
+		Subject: claims.Subject,
+		Units:   claims.Units,
+		Scopes:  explainPermissionScopes(claims, permission),
+		ACL:     []ACLGrantCheck{},
 	}
 
-	extractors, err := parseSubsetExpressions(req.Subset)
-	if err != nil {
-		return nil, err
+	// Mirrors the order of the checks in accessCheck.
+	for _, s := range resp.Scopes {
+		if s.Effect == "grants" && s.Present {
+			resp.Allowed = true
+			resp.Decision = PermissionDecisionScope
+			resp.Reason = fmt.Sprintf("granted by the %q scope", s.Scope)
+
+			break
+		}
 	}
 
-	err = a.accessCheck(ctx, auth, docUUID, ReadPermission)
-	if err != nil {
-		return nil, err
+	if !resp.Allowed && permission == WritePermission &&
+		!claims.HasAnyScope(ScopeDocumentWrite, ScopeDocumentAdmin) {
+		resp.Decision = PermissionDecisionMissingScope
+		resp.Reason = fmt.Sprintf("one of the scopes %s is required",
+			strings.Join([]string{ScopeDocumentWrite, ScopeDocumentAdmin}, ", "))
 	}
 
-	if req.Lock != nil {
-		err = a.accessCheck(ctx, auth, docUUID, WritePermission)
-		if err != nil {
-			return nil, err
-		}
-	}
+	grantees := append([]string{claims.Subject}, claims.Units...)
 
-	// TODO: This is a bit wasteful to request for all document loads.
-	meta, err := a.store.GetDocumentMeta(ctx, docUUID)
-	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
-		return nil, twirp.NotFoundError("the document doesn't exist")
-	} else if err != nil {
-		return nil, twirp.Internal.Errorf(
-			"failed to load document metadata: %w", err)
-	}
+	sources, err := a.store.GetPermissionSources(ctx, docUUID, grantees)
 
-	if meta.SystemLock != "" {
-		return nil, twirp.FailedPrecondition.Errorf(
-			"document is locked for %q", meta.SystemLock)
-	}
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		if resp.Decision == "" {
+			resp.Decision = PermissionDecisionNoDocument
+			resp.Reason = "the document doesn't exist"
+		}
 
-	var lockGrant *repository.LockGrant
+		return &resp, nil
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"failed to read permission sources: %v", err)
+	}
+
+	now := time.Now()
+
+	// The explanation reveals the ACL, lock, and status rules of the
+	// document, so the caller must have read access to it through the
+	// collected grants or a scope. Explaining the permissions of other
+	// subjects already requires the admin scope.
+	if !auth.Claims.HasAnyScope(ScopeDocumentAdmin, ScopeDocumentReadAll) &&
+		!sourcesGrant(sources, ReadPermission, now) {
+		return nil, twirp.PermissionDenied.Error(
+			"no read permission for the document")
+	}
+
+	var grantedBy *ACLGrantCheck
+
+	for _, g := range sources.Grants {
+		check := ACLGrantCheck{
+			Source:      g.Source,
+			Document:    g.Document.String(),
+			URI:         g.Entry.URI,
+			Permissions: g.Entry.Permissions,
+			Inheritable: g.Inheritable,
+			NotBefore:   g.Entry.NotBefore,
+			Expires:     g.Entry.Expires,
+			InEffect:    g.Entry.InEffect(now),
+			Grants: slices.Contains(
+				g.Permissions(now), string(permission)),
+		}
 
-	if req.Lock != nil {
-		exclusivity, err := lockExclusivityFromRPC(req.Lock.Exclusivity)
-		if err != nil {
-			return nil, twirp.InvalidArgumentError("lock.exclusivity", err.Error())
+		if g.Source == ACLGrantInherited {
+			check.Inheritor = g.Inheritor.String()
 		}
 
-		lock, err := a.store.Lock(ctx, LockRequest{
-			UUID:        docUUID,
-			TTL:         req.Lock.Ttl,
-			URI:         auth.Claims.Subject,
-			App:         req.Lock.App,
-			Comment:     req.Lock.Comment,
-			Exclusivity: exclusivity,
-		})
+		resp.ACL = append(resp.ACL, check)
 
-		switch {
-		case IsDocStoreErrorCode(err, ErrCodeDeleteLock), IsDocStoreErrorCode(err, ErrCodeNotFound):
-			return nil, twirp.FailedPrecondition.Error("could not find the document")
-		case IsDocStoreErrorCode(err, ErrCodeBadRequest):
-			return nil, twirp.InvalidArgument.Error(err.Error())
-		case IsDocStoreErrorCode(err, ErrCodeDocumentLock):
-			return nil, lockConflictTwirp(err)
-		case err != nil:
-			return nil, fmt.Errorf("could not obtain lock: %w", err)
+		if check.Grants && grantedBy == nil {
+			grantedBy = &resp.ACL[len(resp.ACL)-1]
 		}
+	}
 
-		lockGrant = &repository.LockGrant{
-			Token:   lock.Token,
-			Expires: lock.Expires.Format(time.RFC3339),
-		}
+	resp.SystemState = string(sources.SystemState)
+
+	if sources.Lock.Expires != (time.Time{}) {
+		resp.Lock = explainLock(sources.Lock, claims.Subject)
 	}
 
-	var (
-		version     int64
-		metaVersion int64
-	)
+	for _, r := range a.workflows.GetAccessRules(sources.Type) {
+		resp.StatusRules = append(resp.StatusRules, StatusAccessRuleCheck{
+			Name:        r.Name,
+			Description: r.Description,
+			AppliesTo:   r.AppliesTo,
+			Expression:  r.Expression,
+		})
+	}
 
-	if req.MetaDocumentVersion > 0 {
-		metaVersion = req.MetaDocumentVersion
+	if resp.Decision != "" {
+		return &resp, nil
 	}
 
 	switch {
-	case req.Version > 0:
-		version = req.Version
-	case req.Status != "":
-		status, ok := meta.Statuses[req.Status]
-		if !ok {
-			return nil, twirp.NotFoundError(
-				"no such status set for the document")
-		}
+	case sources.SystemState != "":
+		resp.Decision = PermissionDecisionSystemLock
+		resp.Reason = fmt.Sprintf(
+			"the document is temporarily locked by the system (%s)",
+			sources.SystemState)
+	case grantedBy != nil:
+		resp.Allowed = true
+		resp.Decision = PermissionDecisionACL
+		resp.Reason = fmt.Sprintf("granted to %q by the %s ACL of %s",
+			grantedBy.URI, grantedBy.Source, grantedBy.Document)
+	default:
+		resp.Decision = PermissionDecisionNoGrant
+		resp.Reason = fmt.Sprintf(
+			"no ACL entry in effect grants %s permission",
+			permission.Name())
+	}
 
-		version = status.Version
-		if version == -1 {
-			return nil, twirp.NotFoundError(
-				"no such status set for the document")
-		}
+	return &resp, nil
+}
 
-		switch {
-		case requireMetaDoc && status.MetaDocVersion == 0:
-			return nil, twirp.NotFoundError(
-				"no meta document was set for that status")
-		case status.MetaDocVersion == 0:
-			// Signal that no meta document was present when the
-			// status was set.
-			metaVersion = -1
-		default:
-			metaVersion = status.MetaDocVersion
+// sourcesGrant reports whether any of the collected grants gives the
+// permission at the given time.
+func sourcesGrant(
+	sources *PermissionSources, permission Permission, now time.Time,
+) bool {
+	for _, g := range sources.Grants {
+		if slices.Contains(g.Permissions(now), string(permission)) {
+			return true
 		}
-	default:
-		version = meta.CurrentVersion
 	}
 
-	res := repository.GetDocumentResponse{
-		Version:        version,
-		IsMetaDocument: meta.MainDocument != "",
-		MainDocument:   meta.MainDocument,
-		Lock:           lockGrant,
+	return false
+}
+
+func explainPermissionScopes(
+	claims elephantine.JWTClaims, permission Permission,
+) []ScopeCheck {
+	scopes := []ScopeCheck{
+		{
+			Scope:   ScopeDocumentAdmin,
+			Present: claims.HasScope(ScopeDocumentAdmin),
+			Effect:  "grants",
+		},
 	}
 
-	if req.MetaDocument != repository.GetMetaDoc_META_ONLY {
-		doc, _, err := a.store.GetDocument(ctx, docUUID, version)
-		if IsDocStoreErrorCode(err, ErrCodeNotFound) {
-			return nil, twirp.NotFoundError("no such version")
-		} else if err != nil {
-			return nil, twirp.Internal.Errorf(
-				"failed to load document version: %w", err)
-		}
+	switch permission {
+	case ReadPermission:
+		scopes = append(scopes, ScopeCheck{
+			Scope:   ScopeDocumentReadAll,
+			Present: claims.HasScope(ScopeDocumentReadAll),
+			Effect:  "grants",
+		})
+	case MetaWritePermission:
+		scopes = append(scopes, ScopeCheck{
+			Scope:   ScopeMetaDocumentWriteAll,
+			Present: claims.HasScope(ScopeMetaDocumentWriteAll),
+			Effect:  "grants",
+		})
+	case WritePermission:
+		scopes = append(scopes, ScopeCheck{
+			Scope:   ScopeDocumentWrite,
+			Present: claims.HasScope(ScopeDocumentWrite),
+			Effect:  "required",
+		})
+	case SetStatusPermission:
+	}
 
-		if doc.Language == "" {
-			doc.Language = a.defaultLanguage
-		}
+	return scopes
+}
 
-		if len(extractors) > 0 {
-			res.Subset = collectSubset(*doc, extractors)
-		} else {
-			res.Document = rpcdoc.DocumentToRPC(*doc)
-		}
+func explainLock(lock Lock, subject string) *LockCheck {
+	check := LockCheck{
+		URI:           lock.URI,
+		App:           lock.App,
+		Comment:       lock.Comment,
+		Expires:       lock.Expires,
+		Exclusivity:   string(lock.Exclusivity),
+		Blocks:        []string{"document"},
+		HeldBySubject: lock.URI == subject,
 	}
 
-	if includeMetaDoc && metaVersion != -1 {
-		metaUUID, _ := metaIdentity(docUUID)
-
-		doc, v, err := a.store.GetDocument(ctx, metaUUID, metaVersion)
+	if checkLock(lock, "", lockOps{status: true}) == lockCheckDenied {
+		check.Blocks = append(check.Blocks, "status")
+	}
 
-		switch {
-		case IsDocStoreErrorCode(err, ErrCodeNotFound) && !requireMetaDoc:
-		case IsDocStoreErrorCode(err, ErrCodeNotFound) && requireMetaDoc:
-			return nil, twirp.NotFoundError("no meta document present")
-		case err != nil:
-			return nil, twirp.Internal.Errorf(
-				"failed to load meta document: %w", err)
-		default:
-			res.Meta = &repository.MetaDocument{
-				Document: rpcdoc.DocumentToRPC(*doc),
-				Version:  v,
-			}
-		}
+	if checkLock(lock, "", lockOps{acl: true}) == lockCheckDenied {
+		check.Blocks = append(check.Blocks, "acl")
 	}
 
-	return &res, nil
+	return &check
 }
 
-// BulkGet implements repository.Documents.
-func (a *DocumentsService) BulkGet(
-	ctx context.Context, req *repository.BulkGetRequest,
-) (*repository.BulkGetResponse, error) {
-	auth, err := RequireAnyScope(ctx,
-		ScopeDocumentRead, ScopeDocumentReadAll,
-		ScopeDocumentAdmin,
-	)
+// CompactedEventlog implements repository.Documents.
+func (a *DocumentsService) CompactedEventlog(
+	ctx context.Context,
+	req *repository.GetCompactedEventlogRequest,
+) (*repository.GetCompactedEventlogResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeEventlogRead, ScopeDocumentAdmin)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(req.Documents) == 0 {
-		return nil, twirp.RequiredArgumentError("documents")
+	lastID, err := a.store.GetLastEventID(ctx)
+	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
+		return &repository.GetCompactedEventlogResponse{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the latest event ID: %w", err)
 	}
 
-	if len(req.Documents) > 200 {
-		return nil, twirp.InvalidArgumentError("documents",
-			"bulk loading of more than 200 documents is not allowed")
+	if req.After >= lastID {
+		return &repository.GetCompactedEventlogResponse{}, nil
 	}
 
-	extractors, err := parseSubsetExpressions(req.Subset)
-	if err != nil {
-		return nil, err
+	cr := GetCompactedEventlogRequest{
+		After:  req.After,
+		Until:  req.Until,
+		Offset: req.Offset,
+		Type:   req.Type,
 	}
 
-	refs := make([]BulkGetReference, len(req.Documents))
-	uuids := make([]uuid.UUID, len(req.Documents))
-
-	for i, ref := range req.Documents {
-		if ref.Uuid == "" {
-			return nil, twirp.RequiredArgumentError(
-				fmt.Sprintf("documents.%d.uuid", i))
-		}
-
-		docUUID, err := uuid.Parse(ref.Uuid)
-		if err != nil {
-			return nil, twirp.InvalidArgumentError(
-				fmt.Sprintf("documents.%d.uuid", i), err.Error())
-		}
+	if req.Limit != 0 {
+		cr.Limit = &req.Limit
+	}
 
-		if ref.Version < 0 {
-			return nil, twirp.InvalidArgumentError(
-				fmt.Sprintf("documents.%d.version", i),
-				"cannot be a negative number")
-		}
+	if cr.Until == 0 {
+		increment := max(req.Limit, req.Limit*5, 500)
+		cr.Until = min(cr.After+int64(increment), lastID)
+	}
 
-		uuids[i] = docUUID
-		refs[i] = BulkGetReference{
-			UUID:    docUUID,
-			Version: ref.Version,
-		}
+	switch {
+	case cr.Until <= cr.After:
+		return nil, twirp.InvalidArgumentError("until",
+			"until must be greater than 'after'")
+	case cr.Until > lastID:
+		return nil, twirp.InvalidArgumentError("until",
+			"cannot be greater than the latest event ID")
+	case cr.Until-cr.After > 10000:
+		return nil, twirp.InvalidArgumentError("until",
+			"`until` cannot be greater than `after`+10000")
 	}
 
-	aclBypass := auth.Claims.HasAnyScope(
-		ScopeDocumentReadAll, ScopeDocumentAdmin)
+	evts, err := a.store.GetCompactedEventlog(ctx, cr)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to read eventlog from database: %w", err)
+	}
 
-	// If the caller doesn't have an ACL bypassing permission we filter down
-	// the given UUIDs to the ones they have permission to.
-	if !aclBypass {
-		permitted := make(map[uuid.UUID]bool, len(uuids))
+	var res repository.GetCompactedEventlogResponse
 
-		ident := append([]string{auth.Claims.Subject}, auth.Claims.Units...)
+	for i := range evts {
+		res.Items = append(res.Items, EventToRPC(evts[i]))
+	}
 
-		p, err := a.store.BulkCheckPermissions(ctx,
-			BulkCheckPermissionRequest{
-				UUIDs:       uuids,
-				GranteeURIs: ident,
-				Permissions: []Permission{ReadPermission},
-			})
-		if err != nil {
-			return nil, twirp.InternalErrorf("check ACL access: %v", err)
-		}
+	return &res, nil
+}
 
-		for _, id := range p {
-			permitted[id] = true
-		}
+const (
+	defaultEventlogBatchSize = 10
+	maxEventlogBatchSize     = 500
+)
 
-		accepted := make([]BulkGetReference, 0, len(req.Documents))
+// Eventlog returns document update events, optionally waiting for new events.
+func (a *DocumentsService) Eventlog(
+	ctx context.Context, req *repository.GetEventlogRequest,
+) (*repository.GetEventlogResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeEventlogRead, ScopeDocumentAdmin)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, ref := range refs {
-			if !permitted[ref.UUID] {
-				continue
-			}
-
-			accepted = append(accepted, ref)
-		}
+	var (
+		wait      time.Duration
+		waitBatch time.Duration
+	)
 
-		refs = accepted
+	if req.WaitMs == 0 {
+		wait = 2 * time.Second
+	} else {
+		wait = time.Duration(req.WaitMs) * time.Millisecond
 	}
 
-	docs, err := a.store.BulkGetDocuments(ctx, refs)
-	if err != nil {
-		return nil, twirp.InternalErrorf("load documents: %w", err)
+	if req.BatchWaitMs == 0 {
+		waitBatch = 200 * time.Millisecond
+	} else {
+		waitBatch = time.Duration(req.BatchWaitMs) * time.Millisecond
 	}
 
-	resp := repository.BulkGetResponse{
-		Items: make([]*repository.BulkGetItem, len(docs)),
-	}
+	after := req.After
+	limit := min(req.BatchSize, maxEventlogBatchSize)
 
-	// TODO: return a partial flag to inform the user when they didn't get
-	// all the requested documents. This could be caused by permission
-	// filtering or documents that have been deleted.
+	if limit == 0 && after < 0 {
+		// Default limit to abs(after) when after is negative. Don't
+		// exceed the normal default though (so, no overflow gosec...)
+		limit = internal.MustInt32(min(defaultEventlogBatchSize, -after))
+	} else if limit == 0 {
+		limit = defaultEventlogBatchSize
+	}
 
-	for i, d := range docs {
-		item := &repository.BulkGetItem{
-			Uuid:    d.UUID.String(),
-			Version: d.Version,
-		}
+	if after < 0 {
+		evt, err := a.store.GetLastEvent(ctx)
 
-		if len(extractors) > 0 {
-			item.Subset = collectSubset(d.Document, extractors)
-		} else {
-			item.Document = rpcdoc.DocumentToRPC(d.Document)
+		switch {
+		case IsDocStoreErrorCode(err, ErrCodeNotFound):
+			after = 0
+		case err != nil:
+			return nil, twirp.InternalErrorf(
+				"failed to get last event: %w", err)
+		default:
+			after = max(0, evt.ID+after)
 		}
+	}
 
-		resp.Items[i] = item
+	evts, err := a.store.GetEventlog(ctx, after, limit)
+	if err != nil {
+		return nil, twirp.InternalErrorf(
+			"failed to fetch events from store: %w", err)
 	}
 
-	return &resp, nil
-}
+	var res repository.GetEventlogResponse
 
-// GetHistory implements repository.Documents.
-func (a *DocumentsService) GetHistory(
-	ctx context.Context, req *repository.GetHistoryRequest,
-) (*repository.GetHistoryResponse, error) {
-	elephantine.SetLogMetadata(ctx,
-		elephantine.LogKeyDocumentUUID, req.Uuid,
-	)
+	maxID := req.After
 
-	auth, err := RequireAnyScope(ctx,
-		ScopeDocumentRead, ScopeDocumentReadAll,
-		ScopeDocumentAdmin,
-	)
-	if err != nil {
-		return nil, err
-	}
+	for i := range evts {
+		if evts[i].ID > maxID {
+			maxID = evts[i].ID
+		}
 
-	docUUID, err := validateRequiredUUIDParam(req.Uuid)
-	if err != nil {
-		return nil, err
+		res.Items = append(res.Items, EventToRPC(evts[i]))
 	}
 
-	err = a.accessCheck(ctx, auth, docUUID, ReadPermission)
-	if err != nil {
-		return nil, err
+	if wait < 0 || len(res.Items) == int(limit) {
+		return &res, nil
 	}
 
-	if req.Before != 0 && req.Before < 2 {
-		return nil, twirp.InvalidArgumentError("before",
-			"cannot be non-zero and less that 2")
-	}
+	return a.eventlogWaitLoop(ctx, maxID, limit, wait, waitBatch, &res)
+}
 
-	history, err := a.store.GetVersionHistory(
-		ctx, docUUID, req.Before, 10, req.LoadStatuses,
-	)
-	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
-		return nil, twirp.NotFoundError("no such version")
+func (a *DocumentsService) eventlogWaitLoop(
+	ctx context.Context, after int64, limit int32,
+	wait time.Duration, waitBatch time.Duration,
+	res *repository.GetEventlogResponse,
+) (*repository.GetEventlogResponse, error) {
+	var batchTimeout <-chan time.Time
+
+	timeout := time.After(wait)
+
+	if len(res.Items) > 0 {
+		batchTimeout = time.After(waitBatch)
 	}
 
-	var res repository.GetHistoryResponse
+	newEvent := make(chan int64, 1)
 
-	for _, up := range history {
-		v := repository.DocumentVersion{
-			Version:  up.Version,
-			Created:  up.Created.Format(time.RFC3339),
-			Creator:  up.Creator,
-			Meta:     up.Meta,
-			Statuses: make(map[string]*repository.DocumentStatuses),
+	a.store.OnEventlog(ctx, newEvent)
+
+	for {
+		select {
+		case <-batchTimeout:
+			return res, nil
+		case <-timeout:
+			return res, nil
+		case <-ctx.Done():
+			return nil, twirp.Canceled.Error("context cancelled")
+		case <-newEvent:
 		}
 
-		for name, items := range up.Statuses {
-			var r repository.DocumentStatuses
+		evts, err := a.store.GetEventlog(
+			ctx, after,
+			// Limited in practice by maxEventlogBatchSize, so no
+			// integer overflow.
+			limit-internal.MustInt32(len(res.Items)),
+		)
+		if err != nil {
+			return nil, twirp.InternalErrorf(
+				"failed to fetch events from store: %w", err)
+		}
 
-			for i := range items {
-				r.Items = append(r.Items, StatusToRPC(items[i]))
+		for i := range evts {
+			if evts[i].ID > after {
+				after = evts[i].ID
 			}
 
-			v.Statuses[name] = &r
+			res.Items = append(res.Items, EventToRPC(evts[i]))
 		}
 
-		res.Versions = append(res.Versions, &v)
-	}
-
-	return &res, nil
-}
+		if len(res.Items) == int(limit) {
+			return res, nil
+		}
 
-func StatusToRPC(status Status) *repository.Status {
-	return &repository.Status{
-		Id:             status.ID,
-		Version:        status.Version,
-		Creator:        status.Creator,
-		Created:        status.Created.Format(time.RFC3339),
-		Meta:           status.Meta,
-		MetaDocVersion: status.MetaDocVersion,
+		if batchTimeout == nil && len(res.Items) > 0 {
+			batchTimeout = time.After(waitBatch)
+		}
 	}
 }
 
-func StatusHeadToRPCStatus(status StatusHead) *repository.Status {
-	return &repository.Status{
-		Id:             status.ID,
-		Version:        status.Version,
-		Creator:        status.Creator,
-		Created:        status.Created.Format(time.RFC3339),
-		Meta:           status.Meta,
-		MetaDocVersion: status.MetaDocVersion,
-	}
-}
+// EventlogItemDetails is an eventlog item together with the event data that
+// repository.EventlogItem lacks fields for.
+type EventlogItemDetails struct {
+	*repository.EventlogItem
 
-func (a *DocumentsService) accessCheck(
-	ctx context.Context,
-	auth *elephantine.AuthInfo, docUUID uuid.UUID,
-	permissions ...Permission,
-) error {
-	if auth.Claims.HasAnyScope(ScopeDocumentAdmin) {
-		return nil
-	}
+	// AttachedObjectMeta is the metadata of the objects that were
+	// attached in the update, including the attachment processing results.
+	AttachedObjectMeta map[string]EventlogObjectMeta `json:"attached_object_meta,omitempty"`
+}
 
-	if slices.Contains(permissions, ReadPermission) &&
-		auth.Claims.HasScope(ScopeDocumentReadAll) {
-		return nil
-	}
+type EventlogObjectMeta struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	// SniffedContentType is the content type that was detected when the
+	// upload was verified.
+	SniffedContentType string            `json:"sniffed_content_type,omitempty"`
+	Props              map[string]string `json:"props,omitempty"`
+	SHA256             string            `json:"sha256,omitempty"`
+	Size               int64             `json:"size,omitempty"`
+	Derived            map[string]string `json:"derived,omitempty"`
+	// Renditions created by the attachment processors, download links are
+	// not included.
+	Renditions []AttachmentRenditionItem `json:"renditions,omitempty"`
+}
 
-	if slices.Contains(permissions, MetaWritePermission) &&
-		auth.Claims.HasScope(ScopeMetaDocumentWriteAll) {
-		return nil
+// EventToRPCDetails converts an event to an eventlog item with details.
+func EventToRPCDetails(evt Event) EventlogItemDetails {
+	item := EventlogItemDetails{
+		EventlogItem: EventToRPC(evt),
 	}
 
-	// Guard against ScopeMetaDocumentWriteAll giving the permission to
-	// create normal documents.
-	normalWrite := !slices.Contains(permissions, MetaWritePermission) &&
-		slices.Contains(permissions, WritePermission)
-	if normalWrite {
-		_, err := RequireAnyScope(ctx,
-			ScopeDocumentWrite, ScopeDocumentAdmin)
-		if err != nil {
-			return err
+	for name, m := range evt.AttachedObjectMeta {
+		if item.AttachedObjectMeta == nil {
+			item.AttachedObjectMeta = make(map[string]EventlogObjectMeta)
 		}
-	}
-
-	access, err := a.store.CheckPermissions(ctx, CheckPermissionRequest{
-		UUID: docUUID,
-		GranteeURIs: append([]string{auth.Claims.Subject},
-			auth.Claims.Units...),
-		Permissions: permissions,
-	})
-	if err != nil {
-		return twirp.InternalErrorf(
-			"failed to check document permissions: %w", err)
-	}
 
-	switch access {
-	case PermissionCheckNoSuchDocument:
-		return twirp.NotFoundError("no such document")
-	case PermissionCheckDenied:
-		names := make([]string, len(permissions))
+		meta := EventlogObjectMeta{
+			Filename:           m.Filename,
+			ContentType:        m.Mimetype,
+			SniffedContentType: m.SniffedMimetype,
+			Props:              m.Props,
+			SHA256:             m.SHA256,
+			Size:               m.Size,
+			Derived:            m.Derived,
+		}
 
-		for i := range permissions {
-			names[i] = permissions[i].Name()
+		for _, r := range m.Renditions {
+			meta.Renditions = append(meta.Renditions,
+				AttachmentRenditionItem{
+					Name:        r.Name,
+					ContentType: r.Mimetype,
+					Size:        r.Size,
+				})
 		}
 
-		return twirp.PermissionDenied.Errorf(
-			"no %s permission for the document",
-			strings.Join(names, " or "))
-	case PermissionCheckSystemLock:
-		return twirp.FailedPrecondition.Error(
-			"the document is temporarily locked by the system")
-	case PermissionCheckAllowed:
+		item.AttachedObjectMeta[name] = meta
 	}
 
-	return nil
+	return item
 }
 
-// GetMeta implements repository.Documents.
-func (a *DocumentsService) GetMeta(
-	ctx context.Context, req *repository.GetMetaRequest,
-) (*repository.GetMetaResponse, error) {
-	elephantine.SetLogMetadata(ctx,
-		elephantine.LogKeyDocumentUUID, req.Uuid,
-	)
+type GetEventlogDetailsRequest struct {
+	After     int64 `json:"after"`
+	BatchSize int32 `json:"batch_size,omitempty"`
+}
 
-	auth, err := RequireAnyScope(ctx,
-		ScopeDocumentRead, ScopeDocumentReadAll,
-		ScopeDocumentAdmin,
-	)
+type GetEventlogDetailsResponse struct {
+	Items []EventlogItemDetails `json:"items"`
+}
+
+// GetEventlogDetails works like Eventlog without waiting for new events, but
+// also returns the event data that the eventlog items lack fields for, like
+// the metadata of attached objects.
+func (a *DocumentsService) GetEventlogDetails(
+	ctx context.Context, req *GetEventlogDetailsRequest,
+) (*GetEventlogDetailsResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeEventlogRead, ScopeDocumentAdmin)
 	if err != nil {
 		return nil, err
 	}
 
-	docUUID, err := validateRequiredUUIDParam(req.Uuid)
-	if err != nil {
-		return nil, err
+	if req.After < 0 {
+		return nil, twirp.InvalidArgumentError("after",
+			"cannot be negative")
 	}
 
-	err = a.accessCheck(ctx, auth, docUUID, ReadPermission)
-	if err != nil {
-		return nil, err
+	limit := min(req.BatchSize, maxEventlogBatchSize)
+	if limit <= 0 {
+		limit = defaultEventlogBatchSize
 	}
 
-	meta, err := a.store.GetDocumentMeta(ctx, docUUID)
-	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
-		return nil, twirp.NotFoundError("the document doesn't exist")
-	} else if err != nil {
-		return nil, fmt.Errorf("failed to load basic metadata: %w", err)
+	evts, err := a.store.GetEventlog(ctx, req.After, limit)
+	if err != nil {
+		return nil, twirp.InternalErrorf(
+			"failed to fetch events from store: %w", err)
 	}
 
-	return &repository.GetMetaResponse{
-		Meta: DocumentMetaToRPC(meta),
-	}, nil
-}
-
-func validateRequiredUUIDParam(v string) (uuid.UUID, error) {
-	if v == "" {
-		return uuid.Nil, twirp.RequiredArgumentError("uuid")
+	res := GetEventlogDetailsResponse{
+		Items: make([]EventlogItemDetails, len(evts)),
 	}
 
-	u, err := uuid.Parse(v)
-	if err != nil {
-		return uuid.Nil, twirp.InvalidArgumentError("uuid", err.Error())
+	for i := range evts {
+		res.Items[i] = EventToRPCDetails(evts[i])
 	}
 
-	return u, nil
+	return &res, nil
 }
 
-// Update implements repository.Documents.
-func (a *DocumentsService) Update(
-	ctx context.Context, req *repository.UpdateRequest,
-) (*repository.UpdateResponse, error) {
-	elephantine.SetLogMetadata(ctx,
-		elephantine.LogKeyDocumentUUID, req.Uuid)
+func RPCToEvent(evt *repository.EventlogItem) (Event, error) {
+	acl := make([]ACLEntry, len(evt.Acl))
 
-	if req.Document != nil {
-		elephantine.SetLogMetadata(ctx,
-			elephantine.LogKeyDocumentType, req.Document.Type)
-		elephantine.SetLogMetadata(ctx,
-			elephantine.LogKeyDocumentTitle, req.Document.Title)
+	for i, a := range evt.Acl {
+		if a == nil {
+			continue
+		}
+
+		acl[i] = ACLEntry{
+			URI:         a.Uri,
+			Permissions: a.Permissions,
+		}
 	}
 
-	auth, err := a.verifyUpdateRequests(ctx,
-		[]*repository.UpdateRequest{req})
+	docUUID, err := uuid.Parse(evt.Uuid)
 	if err != nil {
-		return nil, err
+		return Event{}, fmt.Errorf(
+			"invalid document UUID for event: %w", err)
 	}
 
-	up, err := a.buildUpdateRequest(ctx, auth, req)
+	docNonce, err := uuid.Parse(evt.DocumentNonce)
 	if err != nil {
-		return nil, err
+		return Event{}, fmt.Errorf(
+			"invalid document nonce for event: %w", err)
 	}
 
-	res, err := a.store.Update(ctx, a.workflows, []*UpdateRequest{up})
+	timestamp, err := time.Parse(time.RFC3339, evt.Timestamp)
 	if err != nil {
-		return nil, twirpErrorFromDocumentUpdateError(err)
+		return Event{}, fmt.Errorf(
+			"invalid timestamp for event: %w", err)
 	}
 
-	return &repository.UpdateResponse{
-		Uuid:    res[0].UUID.String(),
-		Version: res[0].Version,
-	}, nil
-}
-
-// BulkUpdate implements repository.Documents.
-func (a *DocumentsService) BulkUpdate(
-	ctx context.Context,
-	req *repository.BulkUpdateRequest,
-) (*repository.BulkUpdateResponse, error) {
-	auth, err := a.verifyUpdateRequests(ctx, req.Updates)
+	timespans, err := TimespanTuplesFromRPC(evt.Timespans)
 	if err != nil {
-		return nil, err
+		return Event{}, fmt.Errorf(
+			"invalid timespans for event: %w", err)
 	}
 
-	var updates []*UpdateRequest
-
-	dedupe := make(map[string]bool)
-
-	for _, update := range req.Updates {
-		isDuplicate := dedupe[update.Uuid]
-		if isDuplicate {
-			return nil, twirp.InvalidArgumentError("updates",
-				"a document can only be updated once in a batch")
-		}
-
-		dedupe[update.Uuid] = true
+	var mainDoc *uuid.UUID
 
-		up, err := a.buildUpdateRequest(ctx, auth, update)
+	if evt.MainDocument != "" {
+		u, err := uuid.Parse(evt.MainDocument)
 		if err != nil {
-			return nil, err
+			return Event{}, fmt.Errorf(
+				"invalid main document UUID: %w", err)
 		}
 
-		updates = append(updates, up)
+		mainDoc = &u
 	}
 
-	res, err := a.store.Update(ctx, a.workflows, updates)
-	if err != nil {
-		return nil, twirpErrorFromDocumentUpdateError(err)
+	return Event{
+		ID:               evt.Id,
+		Event:            EventType(evt.Event),
+		UUID:             docUUID,
+		Nonce:            docNonce,
+		Type:             evt.Type,
+		Timestamp:        timestamp,
+		Updater:          evt.UpdaterUri,
+		Version:          evt.Version,
+		Status:           evt.Status,
+		StatusID:         evt.StatusId,
+		ACL:              acl,
+		MainDocument:     mainDoc,
+		MainDocumentType: evt.MainDocumentType,
+		Language:         evt.Language,
+		OldLanguage:      evt.OldLanguage,
+		AttachedObjects:  evt.AttachedObjects,
+		DetachedObjects:  evt.DetachedObjects,
+		Timespans:        timespans,
+	}, nil
+}
+
+func EventToRPC(evt Event) *repository.EventlogItem {
+	acl := make([]*repository.ACLEntry, len(evt.ACL))
+
+	for i, a := range evt.ACL {
+		acl[i] = &repository.ACLEntry{
+			Uri:         a.URI,
+			Permissions: a.Permissions,
+		}
 	}
 
-	var resp repository.BulkUpdateResponse
+	var mainDoc string
 
-	for i := range res {
-		resp.Updates = append(resp.Updates,
-			&repository.UpdateResponse{
-				Uuid:    res[i].UUID.String(),
-				Version: res[i].Version,
-			})
+	if evt.MainDocument != nil {
+		mainDoc = evt.MainDocument.String()
 	}
 
-	return &resp, nil
+	return &repository.EventlogItem{
+		Id:                 evt.ID,
+		Event:              string(evt.Event),
+		Uuid:               evt.UUID.String(),
+		DocumentNonce:      evt.Nonce.String(),
+		Type:               evt.Type,
+		Timestamp:          evt.Timestamp.Format(time.RFC3339),
+		UpdaterUri:         evt.Updater,
+		Version:            evt.Version,
+		Status:             evt.Status,
+		StatusId:           evt.StatusID,
+		Acl:                acl,
+		MainDocument:       mainDoc,
+		MainDocumentType:   evt.MainDocumentType,
+		Language:           evt.Language,
+		OldLanguage:        evt.OldLanguage,
+		SystemState:        evt.SystemState,
+		WorkflowState:      evt.WorkflowStep,
+		WorkflowCheckpoint: evt.WorkflowCheckpoint,
+		AttachedObjects:    evt.AttachedObjects,
+		DetachedObjects:    evt.DetachedObjects,
+		DeleteRecordId:     evt.DeleteRecordID,
+		Timespans:          TimespanTuplesToRPC(evt.Timespans),
+		Labels:             evt.Labels,
+		SchemaGeneration:   evt.SchemaGeneration,
+	}
 }
 
-func twirpErrorFromDocumentUpdateError(err error) error {
-	switch {
-	case IsDocStoreErrorCode(err, ErrCodeOptimisticLock):
-		return twirp.FailedPrecondition.Error(err.Error())
-	case IsDocStoreErrorCode(err, ErrCodeBadRequest):
-		return twirp.InvalidArgumentError("document", err.Error())
-	case IsDocStoreErrorCode(err, ErrCodeFailedPrecondition):
-		return twirp.FailedPrecondition.Error(err.Error())
-	case IsDocStoreErrorCode(err, ErrCodePermissionDenied):
-		return twirp.PermissionDenied.Error(err.Error())
-	case IsDocStoreErrorCode(err, ErrCodeDeleteLock):
-		return twirp.FailedPrecondition.Error(err.Error())
-	case IsDocStoreErrorCode(err, ErrCodeNotFound):
-		return twirp.NotFoundError(err.Error())
-	case IsDocStoreErrorCode(err, ErrCodeSystemLock):
-		return twirp.FailedPrecondition.Error(err.Error())
-	case IsDocStoreErrorCode(err, ErrCodeDuplicateURI):
-		return twirp.AlreadyExists.Error(err.Error())
-	case err != nil:
-		return twirp.InternalErrorf(
-			"failed to update document: %w", err)
+func TimespanFromRPC(ts *repository.Timespan) (Timespan, error) {
+	var z Timespan
+
+	from, err := time.Parse(time.RFC3339, ts.From)
+	if err != nil {
+		return z, fmt.Errorf(
+			"invalid Timespan.from: %w", err)
 	}
 
-	return nil
+	to, err := time.Parse(time.RFC3339, ts.To)
+	if err != nil {
+		return z, fmt.Errorf(
+			"invalid Timespan.to: %w", err)
+	}
+
+	return Timespan{
+		From: from,
+		To:   to,
+	}, nil
 }
 
-func (a *DocumentsService) buildUpdateRequest(
-	ctx context.Context,
-	auth *elephantine.AuthInfo,
-	req *repository.UpdateRequest,
-) (*UpdateRequest, error) {
-	docUUID := uuid.MustParse(req.Uuid)
-	updater := auth.Claims.Subject
-	updated := time.Now()
+func TimespanTuplesToRPC(tuples [][2]time.Time) []*repository.Timespan {
+	if tuples == nil {
+		return nil
+	}
 
-	if req.ImportDirective != nil {
-		id := req.ImportDirective
+	spans := make([]*repository.Timespan, len(tuples))
 
-		if id.OriginalCreator != "" {
-			updater = req.ImportDirective.OriginalCreator
+	for i := range tuples {
+		spans[i] = &repository.Timespan{
+			From: tuples[i][0].Format(time.RFC3339),
+			To:   tuples[i][1].Format(time.RFC3339),
 		}
+	}
 
-		if id.OriginallyCreated != "" {
-			t, err := time.Parse(time.RFC3339, id.OriginallyCreated)
-			if err != nil {
-				return nil, twirp.InvalidArgumentError(
-					"import_directive.originally_created",
-					fmt.Sprintf("invalid date: %v", err),
-				)
-			}
+	return spans
+}
 
-			updated = t
-		}
+func TimespanTuplesFromRPC(spans []*repository.Timespan) ([][2]time.Time, error) {
+	if spans == nil {
+		return nil, nil
 	}
 
-	up := UpdateRequest{
-		UUID:            docUUID,
-		Updated:         updated,
-		Updater:         updater,
-		Meta:            req.Meta,
-		Status:          RPCToStatusUpdate(req.Status),
-		IfMatch:         req.IfMatch,
-		LockToken:       req.LockToken,
-		IfWorkflowState: req.IfWorkflowState,
-		IfStatusHeads:   req.IfStatusHeads,
-		DetachObjects:   req.DetachObjects,
-	}
-
-	if len(req.AttachObjects) > 0 {
-		up.AttachObjects = make(map[string]Upload, len(req.AttachObjects))
+	tuples := make([][2]time.Time, len(spans))
 
-		for name, idStr := range req.AttachObjects {
-			id, err := uuid.Parse(idStr)
-			if err != nil {
-				return nil, elephantine.InvalidArgumentf(
-					"attach_objects",
-					"invalid UUID for object %q: %v",
-					name, err,
-				)
-			}
+	for i := range spans {
+		from, err := time.Parse(time.RFC3339, spans[i].From)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"invalid 'from' value %q for timespan %d: %w",
+				spans[i].From, i+1, err)
+		}
 
-			up.AttachObjects[name] = Upload{
-				ID:        id,
-				CreatedBy: updater,
-				CreatedAt: updated,
-			}
+		to, err := time.Parse(time.RFC3339, spans[i].To)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"invalid 'to' value %q for timespan %d: %w",
+				spans[i].To, i+1, err)
 		}
-	}
 
-	if req.Document != nil {
-		doc := rpcdoc.DocumentFromRPC(req.Document)
+		tuples[i] = [2]time.Time{from, to}
+	}
 
-		doc.UUID = docUUID.String()
+	return tuples, nil
+}
 
-		doc.Language = strings.ToLower(doc.Language)
+// Evict implements repository.Documents.
+func (a *DocumentsService) Evict(
+	_ context.Context, _ *repository.EvictRequest,
+) (*repository.EvictResponse, error) {
+	return nil, twirp.Unimplemented.Error("not implemented")
+}
 
-		validationResult, err := a.validator.ValidateDocument(ctx, &doc)
-		if err != nil {
-			return nil, fmt.Errorf("unable to validate document %w", err)
-		}
+// Delete implements repository.Documents.
+func (a *DocumentsService) Delete(
+	ctx context.Context, req *repository.DeleteDocumentRequest,
+) (*repository.DeleteDocumentResponse, error) {
+	elephantine.SetLogMetadata(ctx,
+		elephantine.LogKeyDocumentUUID, req.Uuid,
+	)
 
-		if len(validationResult) > 0 {
-			err := twirp.InvalidArgument.Errorf(
-				"the document had %d validation errors, the first one is: %v",
-				len(validationResult), validationResult[0].String())
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentDelete, ScopeDocumentAdmin,
+	)
+	if err != nil {
+		return nil, err
+	}
 
-			err = err.WithMeta("err_count",
-				strconv.Itoa(len(validationResult)))
+	if req.IfMatch < -1 {
+		return nil, twirp.InvalidArgumentError("if_match",
+			"cannot be less than -1")
+	}
 
-			for i := range validationResult {
-				err = err.WithMeta(strconv.Itoa(i),
-					validationResult[i].String())
-			}
+	docUUID, err := validateRequiredUUIDParam(req.Uuid)
+	if err != nil {
+		return nil, err
+	}
 
-			return nil, err
-		}
+	err = a.accessCheck(ctx, auth, docUUID, WritePermission)
+	if elephantine.IsTwirpErrorCode(err, twirp.NotFound) {
+		// Treat a delete of a document that doesn't exist as ok.
+		return &repository.DeleteDocumentResponse{}, nil
+	} else if err != nil {
+		return nil, err
+	}
 
-		up.SchemaGeneration = a.validator.ActiveGenerationID()
+	err = a.store.Delete(ctx, DeleteRequest{
+		UUID:      docUUID,
+		Updated:   time.Now(),
+		Updater:   auth.Claims.Subject,
+		Meta:      req.Meta,
+		IfMatch:   req.IfMatch,
+		LockToken: req.LockToken,
+	})
 
-		up.Document = &doc
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeFailedPrecondition):
+		return nil, twirp.FailedPrecondition.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeDocumentLock):
+		return nil, twirp.FailedPrecondition.Error("the document is locked by someone else")
+	case IsDocStoreErrorCode(err, ErrCodeDeleteLock):
+		// Treating a delete call as a success if the delete already is
+		// in progress.
+		return &repository.DeleteDocumentResponse{}, nil
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"failed to delete document from data store: %w", err)
+	}
 
-		if isMetaURI(up.Document.URI) {
-			mainDoc, err := parseMetaURI(up.Document.URI)
-			if err != nil {
-				return nil, twirp.InvalidArgumentError(
-					"document.uri", err.Error())
-			}
+	return &repository.DeleteDocumentResponse{}, nil
+}
 
-			up.MainDocument = &mainDoc
-		}
+// ListDeleted implements repository.Documents.
+func (a *DocumentsService) ListDeleted(
+	ctx context.Context, req *repository.ListDeletedRequest,
+) (*repository.ListDeletedResponse, error) {
+	_, err := RequireAnyScope(ctx,
+		ScopeDocumentRestore,
+		ScopeDocumentAdmin,
+		ScopeDocumentPurge,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	if req.UpdateMetaDocument {
-		id, uri := metaIdentity(docUUID)
+	var (
+		docUUID    *uuid.UUID
+		beforeTime *time.Time
+	)
 
-		up.UUID = id
-		up.Document.UUID = id.String()
-		up.Document.URI = uri
-		up.MainDocument = &docUUID
+	if req.Uuid != "" {
+		u, err := uuid.Parse(req.Uuid)
+		if err != nil {
+			return nil, twirp.InvalidArgumentError(
+				"uuid", err.Error())
+		}
+
+		docUUID = &u
 	}
 
-	isMeta := up.MainDocument != nil
+	tz := time.UTC
 
-	if !isMeta {
-		up.ACL = aclListFromRPC(req.Acl, auth.Claims.Subject)
+	if req.Timezone != "" {
+		l, err := time.LoadLocation(req.Timezone)
+		if err != nil {
+			return nil, twirp.InvalidArgumentError(
+				"timezone", "unknown timezone")
+		}
 
-		up.DefaultACL = append(up.DefaultACL, ACLEntry{
-			URI:         updater,
-			Permissions: []string{"r", "w"},
-		})
+		tz = l
+	}
 
-		if updater != auth.Claims.Subject {
-			up.DefaultACL = append(up.DefaultACL, ACLEntry{
-				URI:         auth.Claims.Subject,
-				Permissions: []string{"r", "w"},
-			})
+	if req.BeforeDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", req.BeforeDate, tz)
+		if err != nil {
+			return nil, twirp.InvalidArgumentError(
+				"start_at_date", err.Error())
 		}
+
+		beforeTime = &t
 	}
 
-	return &up, nil
-}
+	deleted, err := a.store.ListDeleteRecords(ctx, docUUID, req.BeforeId, beforeTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list delete records: %w", err)
+	}
 
-func aclListFromRPC(acl []*repository.ACLEntry, callerSubject string) []ACLEntry {
-	list := make([]ACLEntry, len(acl))
+	res := repository.ListDeletedResponse{
+		Deletes: make([]*repository.DeleteRecord, len(deleted)),
+	}
 
-	for i, e := range acl {
-		uri := e.Uri
-		if uri == "" {
-			uri = callerSubject
+	for i, d := range deleted {
+		r := repository.DeleteRecord{
+			Id:       d.ID,
+			Uuid:     d.UUID.String(),
+			Uri:      d.URI,
+			Type:     d.Type,
+			Language: d.Language,
+			Version:  d.Version,
+			Created:  d.Created.Format(time.RFC3339),
+			Creator:  d.Creator,
+			Meta:     d.Meta,
 		}
 
-		list[i] = ACLEntry{
-			URI:         uri,
-			Permissions: e.Permissions,
+		if d.Finalised != nil {
+			r.Finalised = d.Finalised.Format(time.RFC3339)
+		}
+
+		if d.Purged != nil {
+			r.Purged = d.Purged.Format(time.RFC3339)
 		}
+
+		res.Deletes[i] = &r
 	}
 
-	return list
+	return &res, nil
 }
 
-// verifyUpdateRequest verifies that a set of update request are correct, and
-// that the user has the necessary permissions for making the updates.
-func (a *DocumentsService) verifyUpdateRequests(
-	ctx context.Context,
-	updates []*repository.UpdateRequest,
-) (*elephantine.AuthInfo, error) {
+// Restore implements repository.Documents.
+func (a *DocumentsService) Restore(
+	ctx context.Context, req *repository.RestoreRequest,
+) (*repository.RestoreResponse, error) {
 	auth, err := RequireAnyScope(ctx,
-		ScopeDocumentWrite, ScopeDocumentAdmin,
-		ScopeMetaDocumentWriteAll,
+		ScopeDocumentRestore,
+		ScopeDocumentAdmin,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, req := range updates {
-		err := a.verifyUpdateRequest(ctx, auth, req)
-		if err != nil {
-			return nil, err
-		}
+	if req.Uuid == "" {
+		return nil, twirp.RequiredArgumentError("uuid")
 	}
 
-	return auth, nil
-}
-
-var objectNameDisallowed = regexp.MustCompile(`[^a-z-]`)
+	if req.DeleteRecordId == 0 {
+		return nil, twirp.RequiredArgumentError("delete_record_id")
+	}
 
-func (a *DocumentsService) verifyUpdateRequest(
-	ctx context.Context,
-	auth *elephantine.AuthInfo,
-	req *repository.UpdateRequest,
-) error {
-	if req.ImportDirective != nil && !auth.Claims.HasAnyScope(
-		ScopeDocumentImport, ScopeDocumentAdmin) {
-		return twirp.PermissionDenied.Error(
-			"no import directive permission")
+	docUUID, err := uuid.Parse(req.Uuid)
+	if err != nil {
+		return nil, twirp.InvalidArgumentError(
+			"uuid", err.Error())
 	}
 
-	docUUID, err := validateRequiredUUIDParam(req.Uuid)
+	err = verifyACLParam(req.Acl)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	isMeta := req.UpdateMetaDocument ||
-		(req.Document != nil && isMetaURI(req.Document.Uri))
+	acl := aclListFromRPC(req.Acl, auth.Claims.Subject)
 
-	if isMeta {
-		err := a.verifyMetaDocumentUpdate(ctx, req, docUUID)
-		if err != nil {
-			return err
-		}
-	} else {
-		// Load the meta type for the document as we want to check that
-		// this update doesn't change a document from a meta document to
-		// a normal document.
-		mt, err := a.store.GetMetaTypeForDocument(ctx, docUUID)
-		if err != nil {
-			return twirp.InternalErrorf(
-				"could not get meta type for document: %w", err)
-		}
+	err = a.store.RestoreDocument(ctx,
+		docUUID, req.DeleteRecordId,
+		auth.Claims.Subject, acl)
 
-		if mt.IsMetaDocument {
-			return twirp.InvalidArgument.Error(
-				"meta documents cannot be turned into normal documents")
-		}
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeExists):
+		return nil, twirp.AlreadyExists.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeFailedPrecondition):
+		return nil, twirp.FailedPrecondition.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeBadRequest):
+		return nil, twirp.InvalidArgument.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.NotFoundError(err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"failed to start restore process: %v", err)
 	}
 
-	if req.Document == nil {
-		switch {
-		case len(req.Status) == 0 && len(req.Acl) == 0:
-			return twirp.InvalidArgumentError(
-				"document",
-				"required when no status or ACL updates are included")
-		case len(req.AttachObjects) > 0:
-			return twirp.InvalidArgumentError("attach_objects",
-				"objects can only be attached when creating or updating the document")
-		case len(req.DetachObjects) > 0:
-			return twirp.InvalidArgumentError("detach_objects",
-				"objects can only be detached updating the document")
-		}
+	return &repository.RestoreResponse{}, nil
+}
+
+// Purge implements repository.Documents.
+func (a *DocumentsService) Purge(
+	ctx context.Context, req *repository.PurgeRequest,
+) (*repository.PurgeResponse, error) {
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentPurge,
+		ScopeDocumentAdmin,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	for name := range req.AttachObjects {
-		disallowed := objectNameDisallowed.FindString(name)
-		if disallowed != "" {
-			return elephantine.InvalidArgumentf("attach_objects",
-				"invalid character %q in object name", disallowed)
-		}
+	if req.Uuid == "" {
+		return nil, twirp.RequiredArgumentError("uuid")
 	}
 
-	if req.IfMatch < -1 {
-		return twirp.InvalidArgumentError("if_match",
-			"cannot be less than -1")
+	if req.DeleteRecordId == 0 {
+		return nil, twirp.RequiredArgumentError("delete_record_id")
 	}
 
-	var docType string
+	docUUID, err := uuid.Parse(req.Uuid)
+	if err != nil {
+		return nil, twirp.InvalidArgumentError(
+			"uuid", err.Error())
+	}
 
-	//nolint: nestif
-	if req.Document != nil {
-		req.Document.Uuid = strings.ToLower(req.Document.Uuid)
-		docType = req.Document.Type
+	err = a.store.PurgeDocument(ctx,
+		docUUID, req.DeleteRecordId,
+		auth.Claims.Subject)
 
-		if req.Document.Uuid == "" {
-			req.Document.Uuid = docUUID.String()
-		} else if req.Document.Uuid != docUUID.String() {
-			return twirp.InvalidArgumentError("document.uuid",
-				"the document must have the same UUID as the request uuid")
-		}
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeFailedPrecondition):
+		return nil, twirp.FailedPrecondition.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.NotFoundError(err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"failed to start purge process: %v", err)
+	}
 
-		if req.Document.Uri == "" {
-			return twirp.RequiredArgumentError("document.uri")
-		}
+	return &repository.PurgeResponse{}, nil
+}
 
-		if req.Document.Language == "" {
-			req.Document.Language = a.defaultLanguage
-		}
+func parseSubsetExpressions(
+	expressions []string,
+) ([]*newsdoc.ValueExtractor, error) {
+	if len(expressions) == 0 {
+		return nil, nil
+	}
 
-		_, err := langos.GetLanguage(req.Document.Language)
+	extractors := make([]*newsdoc.ValueExtractor, len(expressions))
+
+	for i, expr := range expressions {
+		ve, err := newsdoc.ValueExtractorFromString(expr)
 		if err != nil {
-			return twirp.InvalidArgumentError("document.language",
-				err.Error())
-		}
-	} else if len(req.Status) > 0 {
-		// We need to know the document type if we're to set statuses.
-		t, err := a.store.GetTypeOfDocument(ctx, docUUID)
-		if IsDocStoreErrorCode(err, ErrCodeNotFound) {
-			return twirp.NotFoundError("cannot set the status of a document that doesn't exist")
-		} else if err != nil {
-			return twirp.InternalErrorf("check type of document: %w", err)
+			return nil, twirp.InvalidArgumentError(
+				fmt.Sprintf("subset[%d]", i), err.Error())
 		}
 
-		docType = t
+		extractors[i] = ve
 	}
 
-	for i, s := range req.Status {
-		if s == nil {
-			return twirp.InvalidArgumentError(
-				fmt.Sprintf("status.%d", i),
-				"a status cannot be nil")
-		}
+	return extractors, nil
+}
 
-		if s.Name == "" {
-			return twirp.InvalidArgumentError(
-				fmt.Sprintf("status.%d.name", i),
-				"a status cannot have an empty name")
-		}
+func collectSubset(
+	doc newsdoc.Document, extractors []*newsdoc.ValueExtractor,
+) []*repository.ExtractedValues {
+	var result []*repository.ExtractedValues
 
-		if s.Version < -1 {
-			return twirp.InvalidArgumentError(
-				fmt.Sprintf("status.%d.version", i),
-				"must be -1 or greater",
+	for ie, ve := range extractors {
+		items := ve.Collect(doc)
+
+		for _, item := range items {
+			values := make(
+				map[string]*repository.ExtractedValue, len(item),
 			)
-		}
 
-		if req.Document == nil && s.Version == 0 {
-			return twirp.InvalidArgumentError(
-				fmt.Sprintf("status.%d.version", i),
-				"required when no document is included")
-		}
+			for key, nev := range item {
+				ev := &repository.ExtractedValue{
+					Value:      nev.Value,
+					Annotation: nev.Annotation,
+					Role:       nev.Role,
+				}
 
-		if !a.workflows.HasStatus(docType, s.Name) {
-			return twirp.InvalidArgumentError(
-				fmt.Sprintf("status.%d.name", i),
-				fmt.Sprintf("unknown status %q for %q",
-					s.Name, docType))
+				if nev.Block != nil {
+					ev.Block = rpcdoc.BlockToRPC(*nev.Block)
+				}
+
+				values[key] = ev
+			}
+
+			result = append(result, &repository.ExtractedValues{
+				Extractor: int64(ie),
+				Values:    values,
+			})
 		}
 	}
 
-	err = verifyACLParam(req.Acl)
+	return result
+}
+
+// Get implements repository.Documents.
+func (a *DocumentsService) Get(
+	ctx context.Context, req *repository.GetDocumentRequest,
+) (*repository.GetDocumentResponse, error) {
+	elephantine.SetLogMetadata(ctx,
+		elephantine.LogKeyDocumentUUID, req.Uuid,
+	)
+
+	requireMetaDoc := req.MetaDocument == repository.GetMetaDoc_META_ONLY
+	includeMetaDoc := requireMetaDoc ||
+		req.MetaDocument == repository.GetMetaDoc_META_INCLUDE
+
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentRead, ScopeDocumentReadAll,
+		ScopeDocumentAdmin,
+	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if isMeta {
-		// For meta documents the access check is made against the main
-		// document, as meta documents don't have ACLs of their own.
-		mainUUID, err := parseMetaURI(req.Document.Uri)
-		if err != nil {
-			return twirp.InvalidArgumentError(
-				"document.uri", err.Error())
-		}
+	if req.Version < 0 {
+		return nil, twirp.InvalidArgumentError("version",
+			"cannot be a negative number")
+	}
 
-		err = a.accessCheck(ctx, auth, mainUUID,
-			WritePermission,
-			MetaWritePermission,
-		)
-		if err != nil {
-			return err
-		}
-	} else {
-		perm := []Permission{
-			WritePermission,
-		}
+	if req.Lock != nil && req.Lock.Ttl == 0 {
+		return nil, twirp.RequiredArgumentError("lock.ttl")
+	}
 
-		onlyStatus := len(req.Status) > 0 &&
-			req.Document == nil && len(req.Acl) == 0
+	if req.Version > 0 && req.Status != "" {
+		return nil, twirp.InvalidArgumentError("status",
+			"status cannot be specified together with a version")
+	}
 
-		// Include the set status permission if the update request only
-		// sets statuses.
-		if onlyStatus {
-			perm = append(perm, SetStatusPermission)
-		}
+	if req.MetaDocumentVersion > 0 && req.Status != "" {
+		return nil, twirp.InvalidArgumentError("status",
+			"status cannot be specified together with a meta document version")
+	}
+
+	docUUID, err := validateRequiredUUIDParam(req.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	extractors, err := parseSubsetExpressions(req.Subset)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.accessCheck(ctx, auth, docUUID, ReadPermission)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Lock != nil {
+		err = a.accessCheck(ctx, auth, docUUID, WritePermission)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// TODO: This is a bit wasteful to request for all document loads.
+	meta, err := a.store.GetDocumentMeta(ctx, docUUID)
+	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
+		return nil, twirp.NotFoundError("the document doesn't exist")
+	} else if err != nil {
+		return nil, twirp.Internal.Errorf(
+			"failed to load document metadata: %w", err)
+	}
+
+	if meta.SystemLock != "" {
+		return nil, twirp.FailedPrecondition.Errorf(
+			"document is locked for %q", meta.SystemLock)
+	}
+
+	var lockGrant *repository.LockGrant
+
+	if req.Lock != nil {
+		exclusivity, err := lockExclusivityFromRPC(req.Lock.Exclusivity)
+		if err != nil {
+			return nil, twirp.InvalidArgumentError("lock.exclusivity", err.Error())
+		}
+
+		lock, err := a.store.Lock(ctx, LockRequest{
+			UUID:        docUUID,
+			TTL:         req.Lock.Ttl,
+			URI:         auth.Claims.Subject,
+			App:         req.Lock.App,
+			Comment:     req.Lock.Comment,
+			Exclusivity: exclusivity,
+		})
+
+		switch {
+		case IsDocStoreErrorCode(err, ErrCodeDeleteLock), IsDocStoreErrorCode(err, ErrCodeNotFound):
+			return nil, twirp.FailedPrecondition.Error("could not find the document")
+		case IsDocStoreErrorCode(err, ErrCodeBadRequest):
+			return nil, twirp.InvalidArgument.Error(err.Error())
+		case IsDocStoreErrorCode(err, ErrCodeDocumentLock):
+			return nil, lockConflictTwirp(err)
+		case err != nil:
+			return nil, fmt.Errorf("could not obtain lock: %w", err)
+		}
+
+		lockGrant = &repository.LockGrant{
+			Token:   lock.Token,
+			Expires: lock.Expires.Format(time.RFC3339),
+		}
+	}
+
+	var (
+		version     int64
+		metaVersion int64
+	)
+
+	if req.MetaDocumentVersion > 0 {
+		metaVersion = req.MetaDocumentVersion
+	}
+
+	switch {
+	case req.Version > 0:
+		version = req.Version
+	case req.Status != "":
+		status, ok := meta.Statuses[req.Status]
+		if !ok {
+			return nil, twirp.NotFoundError(
+				"no such status set for the document")
+		}
+
+		version = status.Version
+		if version == -1 {
+			return nil, twirp.NotFoundError(
+				"no such status set for the document")
+		}
+
+		switch {
+		case requireMetaDoc && status.MetaDocVersion == 0:
+			return nil, twirp.NotFoundError(
+				"no meta document was set for that status")
+		case status.MetaDocVersion == 0:
+			// Signal that no meta document was present when the
+			// status was set.
+			metaVersion = -1
+		default:
+			metaVersion = status.MetaDocVersion
+		}
+	default:
+		version = meta.CurrentVersion
+	}
+
+	res := repository.GetDocumentResponse{
+		Version:        version,
+		IsMetaDocument: meta.MainDocument != "",
+		MainDocument:   meta.MainDocument,
+		Lock:           lockGrant,
+	}
+
+	var reads []DocumentRead
+
+	if req.MetaDocument != repository.GetMetaDoc_META_ONLY {
+		doc, _, err := a.store.GetDocument(ctx, docUUID, version)
+		if IsDocStoreErrorCode(err, ErrCodeNotFound) {
+			return nil, twirp.NotFoundError("no such version")
+		} else if err != nil {
+			return nil, twirp.Internal.Errorf(
+				"failed to load document version: %w", err)
+		}
+
+		if doc.Language == "" {
+			doc.Language = a.defaultLanguage
+		}
+
+		*doc = a.transformOnReadPath(*doc)
+
+		if len(extractors) > 0 {
+			res.Subset = collectSubset(*doc, extractors)
+		} else {
+			res.Document = rpcdoc.DocumentToRPC(*doc)
+		}
+
+		reads = append(reads, DocumentRead{
+			UUID:    docUUID,
+			Version: version,
+		})
+	}
+
+	if includeMetaDoc && metaVersion != -1 {
+		metaUUID, _ := metaIdentity(docUUID)
+
+		doc, v, err := a.store.GetDocument(ctx, metaUUID, metaVersion)
+
+		switch {
+		case IsDocStoreErrorCode(err, ErrCodeNotFound) && !requireMetaDoc:
+		case IsDocStoreErrorCode(err, ErrCodeNotFound) && requireMetaDoc:
+			return nil, twirp.NotFoundError("no meta document present")
+		case err != nil:
+			return nil, twirp.Internal.Errorf(
+				"failed to load meta document: %w", err)
+		default:
+			res.Meta = &repository.MetaDocument{
+				Document: rpcdoc.DocumentToRPC(
+					a.transformOnReadPath(*doc)),
+				Version: v,
+			}
+
+			reads = append(reads, DocumentRead{
+				UUID:    metaUUID,
+				Version: v,
+			})
+		}
+	}
+
+	err = a.recordReads(ctx, auth, ReadAuditGet, reads)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// BulkGet implements repository.Documents.
+func (a *DocumentsService) BulkGet(
+	ctx context.Context, req *repository.BulkGetRequest,
+) (*repository.BulkGetResponse, error) {
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentRead, ScopeDocumentReadAll,
+		ScopeDocumentAdmin,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Documents) == 0 {
+		return nil, twirp.RequiredArgumentError("documents")
+	}
+
+	if len(req.Documents) > 200 {
+		return nil, twirp.InvalidArgumentError("documents",
+			"bulk loading of more than 200 documents is not allowed")
+	}
+
+	extractors, err := parseSubsetExpressions(req.Subset)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]BulkGetReference, len(req.Documents))
+	uuids := make([]uuid.UUID, len(req.Documents))
+
+	for i, ref := range req.Documents {
+		if ref.Uuid == "" {
+			return nil, twirp.RequiredArgumentError(
+				fmt.Sprintf("documents.%d.uuid", i))
+		}
+
+		docUUID, err := uuid.Parse(ref.Uuid)
+		if err != nil {
+			return nil, twirp.InvalidArgumentError(
+				fmt.Sprintf("documents.%d.uuid", i), err.Error())
+		}
+
+		if ref.Version < 0 {
+			return nil, twirp.InvalidArgumentError(
+				fmt.Sprintf("documents.%d.version", i),
+				"cannot be a negative number")
+		}
+
+		uuids[i] = docUUID
+		refs[i] = BulkGetReference{
+			UUID:    docUUID,
+			Version: ref.Version,
+		}
+	}
+
+	aclBypass := auth.Claims.HasAnyScope(
+		ScopeDocumentReadAll, ScopeDocumentAdmin)
+
+	// If the caller doesn't have an ACL bypassing permission we filter down
+	// the given UUIDs to the ones they have permission to.
+	if !aclBypass {
+		permitted := make(map[uuid.UUID]bool, len(uuids))
+
+		ident := append([]string{auth.Claims.Subject}, auth.Claims.Units...)
+
+		p, err := a.store.BulkCheckPermissions(ctx,
+			BulkCheckPermissionRequest{
+				UUIDs:       uuids,
+				GranteeURIs: ident,
+				Permissions: []Permission{ReadPermission},
+			})
+		if err != nil {
+			return nil, twirp.InternalErrorf("check ACL access: %v", err)
+		}
+
+		for _, id := range p {
+			permitted[id] = true
+		}
+
+		accepted := make([]BulkGetReference, 0, len(req.Documents))
+
+		for _, ref := range refs {
+			if !permitted[ref.UUID] {
+				continue
+			}
+
+			accepted = append(accepted, ref)
+		}
+
+		refs = accepted
+	}
+
+	docs, err := a.store.BulkGetDocuments(ctx, refs)
+	if err != nil {
+		return nil, twirp.InternalErrorf("load documents: %w", err)
+	}
+
+	resp := repository.BulkGetResponse{
+		Items: make([]*repository.BulkGetItem, len(docs)),
+	}
+
+	reads := make([]DocumentRead, len(docs))
+
+	// TODO: return a partial flag to inform the user when they didn't get
+	// all the requested documents. This could be caused by permission
+	// filtering or documents that have been deleted.
+
+	for i, d := range docs {
+		item := &repository.BulkGetItem{
+			Uuid:    d.UUID.String(),
+			Version: d.Version,
+		}
+
+		doc := a.transformOnReadPath(d.Document)
+
+		if len(extractors) > 0 {
+			item.Subset = collectSubset(doc, extractors)
+		} else {
+			item.Document = rpcdoc.DocumentToRPC(doc)
+		}
+
+		resp.Items[i] = item
+		reads[i] = DocumentRead{
+			UUID:    d.UUID,
+			Version: d.Version,
+		}
+	}
+
+	err = a.recordReads(ctx, auth, ReadAuditBulkGet, reads)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// GetHistory implements repository.Documents.
+func (a *DocumentsService) GetHistory(
+	ctx context.Context, req *repository.GetHistoryRequest,
+) (*repository.GetHistoryResponse, error) {
+	elephantine.SetLogMetadata(ctx,
+		elephantine.LogKeyDocumentUUID, req.Uuid,
+	)
+
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentRead, ScopeDocumentReadAll,
+		ScopeDocumentAdmin,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	docUUID, err := validateRequiredUUIDParam(req.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.accessCheck(ctx, auth, docUUID, ReadPermission)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Before != 0 && req.Before < 2 {
+		return nil, twirp.InvalidArgumentError("before",
+			"cannot be non-zero and less that 2")
+	}
+
+	history, err := a.store.GetVersionHistory(
+		ctx, docUUID, req.Before, 10, req.LoadStatuses,
+	)
+	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
+		return nil, twirp.NotFoundError("no such version")
+	}
+
+	var res repository.GetHistoryResponse
+
+	for _, up := range history {
+		v := repository.DocumentVersion{
+			Version:  up.Version,
+			Created:  up.Created.Format(time.RFC3339),
+			Creator:  up.Creator,
+			Meta:     up.Meta,
+			Statuses: make(map[string]*repository.DocumentStatuses),
+		}
+
+		for name, items := range up.Statuses {
+			var r repository.DocumentStatuses
+
+			for i := range items {
+				r.Items = append(r.Items, StatusToRPC(items[i]))
+			}
+
+			v.Statuses[name] = &r
+		}
+
+		res.Versions = append(res.Versions, &v)
+	}
+
+	return &res, nil
+}
+
+func StatusToRPC(status Status) *repository.Status {
+	return &repository.Status{
+		Id:             status.ID,
+		Version:        status.Version,
+		Creator:        status.Creator,
+		Created:        status.Created.Format(time.RFC3339),
+		Meta:           status.Meta,
+		MetaDocVersion: status.MetaDocVersion,
+	}
+}
+
+func StatusHeadToRPCStatus(status StatusHead) *repository.Status {
+	return &repository.Status{
+		Id:             status.ID,
+		Version:        status.Version,
+		Creator:        status.Creator,
+		Created:        status.Created.Format(time.RFC3339),
+		Meta:           status.Meta,
+		MetaDocVersion: status.MetaDocVersion,
+	}
+}
+
+func (a *DocumentsService) accessCheck(
+	ctx context.Context,
+	auth *elephantine.AuthInfo, docUUID uuid.UUID,
+	permissions ...Permission,
+) error {
+	if auth.Claims.HasAnyScope(ScopeDocumentAdmin) {
+		return nil
+	}
+
+	if slices.Contains(permissions, ReadPermission) &&
+		auth.Claims.HasScope(ScopeDocumentReadAll) {
+		return nil
+	}
+
+	if slices.Contains(permissions, MetaWritePermission) &&
+		auth.Claims.HasScope(ScopeMetaDocumentWriteAll) {
+		return nil
+	}
+
+	// Guard against ScopeMetaDocumentWriteAll giving the permission to
+	// create normal documents.
+	normalWrite := !slices.Contains(permissions, MetaWritePermission) &&
+		slices.Contains(permissions, WritePermission)
+	if normalWrite {
+		_, err := RequireAnyScope(ctx,
+			ScopeDocumentWrite, ScopeDocumentAdmin)
+		if err != nil {
+			return err
+		}
+	}
+
+	access, err := a.store.CheckPermissions(ctx, CheckPermissionRequest{
+		UUID: docUUID,
+		GranteeURIs: append([]string{auth.Claims.Subject},
+			auth.Claims.Units...),
+		Permissions: permissions,
+	})
+	if err != nil {
+		return twirp.InternalErrorf(
+			"failed to check document permissions: %w", err)
+	}
+
+	switch access {
+	case PermissionCheckNoSuchDocument:
+		return twirp.NotFoundError("no such document")
+	case PermissionCheckDenied:
+		names := make([]string, len(permissions))
+
+		for i := range permissions {
+			names[i] = permissions[i].Name()
+		}
+
+		return twirp.PermissionDenied.Errorf(
+			"no %s permission for the document",
+			strings.Join(names, " or "))
+	case PermissionCheckSystemLock:
+		return twirp.FailedPrecondition.Error(
+			"the document is temporarily locked by the system")
+	case PermissionCheckAllowed:
+	}
+
+	return nil
+}
+
+// recordReads adds the reads to the read audit log. Reads that cannot be
+// audited are treated as failures, we would rather fail the request than
+// hand out sensitive documents without a record of it.
+func (a *DocumentsService) recordReads(
+	ctx context.Context,
+	auth *elephantine.AuthInfo,
+	operation ReadAuditOperation,
+	reads []DocumentRead,
+) error {
+	err := a.store.RecordReads(ctx, newReadAuditRecord(
+		auth, clientIPFromContext(ctx), operation, reads))
+	if err != nil {
+		return twirp.InternalErrorf("record read audit: %v", err)
+	}
+
+	return nil
+}
+
+// GetMeta implements repository.Documents.
+func (a *DocumentsService) GetMeta(
+	ctx context.Context, req *repository.GetMetaRequest,
+) (*repository.GetMetaResponse, error) {
+	elephantine.SetLogMetadata(ctx,
+		elephantine.LogKeyDocumentUUID, req.Uuid,
+	)
+
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentRead, ScopeDocumentReadAll,
+		ScopeDocumentAdmin,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	docUUID, err := validateRequiredUUIDParam(req.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.accessCheck(ctx, auth, docUUID, ReadPermission)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := a.store.GetDocumentMeta(ctx, docUUID)
+	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
+		return nil, twirp.NotFoundError("the document doesn't exist")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load basic metadata: %w", err)
+	}
+
+	return &repository.GetMetaResponse{
+		Meta: DocumentMetaToRPC(meta),
+	}, nil
+}
+
+func validateRequiredUUIDParam(v string) (uuid.UUID, error) {
+	if v == "" {
+		return uuid.Nil, twirp.RequiredArgumentError("uuid")
+	}
+
+	u, err := uuid.Parse(v)
+	if err != nil {
+		return uuid.Nil, twirp.InvalidArgumentError("uuid", err.Error())
+	}
+
+	return u, nil
+}
+
+// Update implements repository.Documents.
+func (a *DocumentsService) Update(
+	ctx context.Context, req *repository.UpdateRequest,
+) (*repository.UpdateResponse, error) {
+	elephantine.SetLogMetadata(ctx,
+		elephantine.LogKeyDocumentUUID, req.Uuid)
+
+	if req.Document != nil {
+		elephantine.SetLogMetadata(ctx,
+			elephantine.LogKeyDocumentType, req.Document.Type)
+		elephantine.SetLogMetadata(ctx,
+			elephantine.LogKeyDocumentTitle, req.Document.Title)
+	}
+
+	auth, err := a.verifyUpdateRequests(ctx,
+		[]*repository.UpdateRequest{req})
+	if err != nil {
+		return nil, err
+	}
+
+	up, err := a.buildUpdateRequest(ctx, auth, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := a.store.Update(ctx, a.workflows, []*UpdateRequest{up})
+	if err != nil {
+		return nil, twirpErrorFromDocumentUpdateError(err)
+	}
+
+	return &repository.UpdateResponse{
+		Uuid:    res[0].UUID.String(),
+		Version: res[0].Version,
+	}, nil
+}
+
+// BulkUpdate implements repository.Documents.
+func (a *DocumentsService) BulkUpdate(
+	ctx context.Context,
+	req *repository.BulkUpdateRequest,
+) (*repository.BulkUpdateResponse, error) {
+	auth, err := a.verifyUpdateRequests(ctx, req.Updates)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []*UpdateRequest
+
+	dedupe := make(map[string]bool)
+
+	for _, update := range req.Updates {
+		isDuplicate := dedupe[update.Uuid]
+		if isDuplicate {
+			return nil, twirp.InvalidArgumentError("updates",
+				"a document can only be updated once in a batch")
+		}
+
+		dedupe[update.Uuid] = true
+
+		up, err := a.buildUpdateRequest(ctx, auth, update)
+		if err != nil {
+			return nil, err
+		}
+
+		updates = append(updates, up)
+	}
+
+	res, err := a.store.Update(ctx, a.workflows, updates)
+	if err != nil {
+		return nil, twirpErrorFromDocumentUpdateError(err)
+	}
+
+	var resp repository.BulkUpdateResponse
+
+	for i := range res {
+		resp.Updates = append(resp.Updates,
+			&repository.UpdateResponse{
+				Uuid:    res[i].UUID.String(),
+				Version: res[i].Version,
+			})
+	}
+
+	return &resp, nil
+}
+
+func twirpErrorFromDocumentUpdateError(err error) error {
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeOptimisticLock):
+		return twirp.FailedPrecondition.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeBadRequest):
+		return twirp.InvalidArgumentError("document", err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeFailedPrecondition):
+		return twirp.FailedPrecondition.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodePermissionDenied):
+		return twirp.PermissionDenied.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeDeleteLock):
+		return twirp.FailedPrecondition.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return twirp.NotFoundError(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeSystemLock):
+		return twirp.FailedPrecondition.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeDuplicateURI):
+		return twirp.AlreadyExists.Error(err.Error())
+	case err != nil:
+		return twirp.InternalErrorf(
+			"failed to update document: %w", err)
+	}
+
+	return nil
+}
+
+func (a *DocumentsService) buildUpdateRequest(
+	ctx context.Context,
+	auth *elephantine.AuthInfo,
+	req *repository.UpdateRequest,
+) (*UpdateRequest, error) {
+	docUUID := uuid.MustParse(req.Uuid)
+	updater := auth.Claims.Subject
+	updated := time.Now()
+
+	if req.ImportDirective != nil {
+		id := req.ImportDirective
+
+		if id.OriginalCreator != "" {
+			updater = req.ImportDirective.OriginalCreator
+		}
+
+		if id.OriginallyCreated != "" {
+			t, err := time.Parse(time.RFC3339, id.OriginallyCreated)
+			if err != nil {
+				return nil, twirp.InvalidArgumentError(
+					"import_directive.originally_created",
+					fmt.Sprintf("invalid date: %v", err),
+				)
+			}
+
+			updated = t
+		}
+	}
+
+	up := UpdateRequest{
+		UUID:            docUUID,
+		Updated:         updated,
+		Updater:         updater,
+		Meta:            req.Meta,
+		Status:          RPCToStatusUpdate(req.Status),
+		IfMatch:         req.IfMatch,
+		LockToken:       req.LockToken,
+		IfWorkflowState: req.IfWorkflowState,
+		IfStatusHeads:   req.IfStatusHeads,
+		DetachObjects:   req.DetachObjects,
+	}
+
+	if len(req.AttachObjects) > 0 {
+		up.AttachObjects = make(map[string]Upload, len(req.AttachObjects))
+
+		for name, idStr := range req.AttachObjects {
+			id, err := uuid.Parse(idStr)
+			if err != nil {
+				return nil, elephantine.InvalidArgumentf(
+					"attach_objects",
+					"invalid UUID for object %q: %v",
+					name, err,
+				)
+			}
+
+			up.AttachObjects[name] = Upload{
+				ID:        id,
+				CreatedBy: updater,
+				CreatedAt: updated,
+			}
+		}
+	}
+
+	if req.Document != nil {
+		doc := rpcdoc.DocumentFromRPC(req.Document)
+
+		doc.UUID = docUUID.String()
+
+		validationResult, err := a.setUpdateDocument(ctx, &up, doc)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(validationResult) > 0 {
+			err := twirp.InvalidArgument.Errorf(
+				"the document had %d validation errors, the first one is: %v",
+				len(validationResult), validationResult[0].String())
+
+			err = err.WithMeta("err_count",
+				strconv.Itoa(len(validationResult)))
+
+			for i := range validationResult {
+				err = err.WithMeta(strconv.Itoa(i),
+					validationResult[i].String())
+			}
+
+			return nil, err
+		}
+
+		if isMetaURI(up.Document.URI) {
+			mainDoc, err := parseMetaURI(up.Document.URI)
+			if err != nil {
+				return nil, twirp.InvalidArgumentError(
+					"document.uri", err.Error())
+			}
+
+			up.MainDocument = &mainDoc
+		}
+	}
+
+	if req.UpdateMetaDocument {
+		id, uri := metaIdentity(docUUID)
+
+		up.UUID = id
+		up.Document.UUID = id.String()
+		up.Document.URI = uri
+		up.MainDocument = &docUUID
+	}
+
+	isMeta := up.MainDocument != nil
+
+	if !isMeta {
+		up.ACL = aclListFromRPC(req.Acl, auth.Claims.Subject)
+		up.DefaultACL = updateDefaultACL(updater, auth.Claims.Subject)
+	}
+
+	return &up, nil
+}
+
+// setUpdateDocument brings a document written in the shape of an earlier
+// schema generation up to date, validates it, and sets it as the document of
+// the update. The validation problems are returned for the caller to report,
+// the update is invalid if there are any.
+func (a *DocumentsService) setUpdateDocument(
+	ctx context.Context, up *UpdateRequest, doc newsdoc.Document,
+) ([]revisor.ValidationResult, error) {
+	doc.Language = strings.ToLower(doc.Language)
+
+	doc, _ = a.validator.TransformDocument(doc)
+
+	valCtx, deprecations := WithDeprecationCollector(ctx)
+
+	validationResult, err := a.validator.ValidateDocument(valCtx, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to validate document %w", err)
+	}
+
+	up.Document = &doc
+	up.SchemaGeneration = a.validator.ActiveGenerationID()
+	up.Deprecations = deprecations.Labels()
+
+	return validationResult, nil
+}
+
+// updateDefaultACL is the ACL that a document gets if it's created without
+// one: the updater and the caller, when writing on behalf of someone else,
+// are given read and write access.
+func updateDefaultACL(updater string, caller string) []ACLEntry {
+	acl := []ACLEntry{{
+		URI:         updater,
+		Permissions: []string{"r", "w"},
+	}}
+
+	if updater != caller {
+		acl = append(acl, ACLEntry{
+			URI:         caller,
+			Permissions: []string{"r", "w"},
+		})
+	}
+
+	return acl
+}
+
+func aclListFromRPC(acl []*repository.ACLEntry, callerSubject string) []ACLEntry {
+	list := make([]ACLEntry, len(acl))
+
+	for i, e := range acl {
+		uri := e.Uri
+		if uri == "" {
+			uri = callerSubject
+		}
+
+		list[i] = ACLEntry{
+			URI:         uri,
+			Permissions: e.Permissions,
+		}
+	}
+
+	return list
+}
+
+// verifyUpdateRequest verifies that a set of update request are correct, and
+// that the user has the necessary permissions for making the updates.
+func (a *DocumentsService) verifyUpdateRequests(
+	ctx context.Context,
+	updates []*repository.UpdateRequest,
+) (*elephantine.AuthInfo, error) {
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentWrite, ScopeDocumentAdmin,
+		ScopeMetaDocumentWriteAll,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Types of the documents that are created by the batch, a meta
+	// document can be created together with its main document.
+	created := make(map[uuid.UUID]string)
+
+	for _, req := range updates {
+		err := a.verifyUpdateRequest(ctx, auth, req, created)
+		if err != nil {
+			return nil, err
+		}
+
+		if req.IfMatch == -1 && req.Document != nil && !req.UpdateMetaDocument {
+			// The UUID has been validated by verifyUpdateRequest.
+			docUUID, _ := uuid.Parse(req.Uuid)
+
+			created[docUUID] = req.Document.Type
+		}
+	}
+
+	return auth, nil
+}
+
+var objectNameDisallowed = regexp.MustCompile(`[^a-z-]`)
+
+func (a *DocumentsService) verifyUpdateRequest(
+	ctx context.Context,
+	auth *elephantine.AuthInfo,
+	req *repository.UpdateRequest,
+	created map[uuid.UUID]string,
+) error {
+	if req.ImportDirective != nil && !auth.Claims.HasAnyScope(
+		ScopeDocumentImport, ScopeDocumentAdmin) {
+		return twirp.PermissionDenied.Error(
+			"no import directive permission")
+	}
+
+	docUUID, err := validateRequiredUUIDParam(req.Uuid)
+	if err != nil {
+		return err
+	}
+
+	isMeta := req.UpdateMetaDocument ||
+		(req.Document != nil && isMetaURI(req.Document.Uri))
+
+	if isMeta {
+		err := a.verifyMetaDocumentUpdate(ctx, req, docUUID, created)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Load the meta type for the document as we want to check that
+		// this update doesn't change a document from a meta document to
+		// a normal document.
+		mt, err := a.store.GetMetaTypeForDocument(ctx, docUUID)
+		if err != nil {
+			return twirp.InternalErrorf(
+				"could not get meta type for document: %w", err)
+		}
+
+		if mt.IsMetaDocument {
+			return twirp.InvalidArgument.Error(
+				"meta documents cannot be turned into normal documents")
+		}
+	}
+
+	if req.Document == nil {
+		switch {
+		case len(req.Status) == 0 && len(req.Acl) == 0:
+			return twirp.InvalidArgumentError(
+				"document",
+				"required when no status or ACL updates are included")
+		case len(req.AttachObjects) > 0:
+			return twirp.InvalidArgumentError("attach_objects",
+				"objects can only be attached when creating or updating the document")
+		case len(req.DetachObjects) > 0:
+			return twirp.InvalidArgumentError("detach_objects",
+				"objects can only be detached updating the document")
+		}
+	}
+
+	for name := range req.AttachObjects {
+		disallowed := objectNameDisallowed.FindString(name)
+		if disallowed != "" {
+			return elephantine.InvalidArgumentf("attach_objects",
+				"invalid character %q in object name", disallowed)
+		}
+	}
+
+	if req.IfMatch < -1 {
+		return twirp.InvalidArgumentError("if_match",
+			"cannot be less than -1")
+	}
+
+	var docType string
+
+	//nolint: nestif
+	if req.Document != nil {
+		req.Document.Uuid = strings.ToLower(req.Document.Uuid)
+		docType = req.Document.Type
+
+		if req.Document.Uuid == "" {
+			req.Document.Uuid = docUUID.String()
+		} else if req.Document.Uuid != docUUID.String() {
+			return twirp.InvalidArgumentError("document.uuid",
+				"the document must have the same UUID as the request uuid")
+		}
+
+		if req.Document.Uri == "" {
+			return twirp.RequiredArgumentError("document.uri")
+		}
+
+		if req.Document.Language == "" {
+			req.Document.Language = a.defaultLanguage
+		}
+
+		_, err := langos.GetLanguage(req.Document.Language)
+		if err != nil {
+			return twirp.InvalidArgumentError("document.language",
+				err.Error())
+		}
+	} else if len(req.Status) > 0 {
+		// We need to know the document type if we're to set statuses.
+		t, err := a.store.GetTypeOfDocument(ctx, docUUID)
+		if IsDocStoreErrorCode(err, ErrCodeNotFound) {
+			return twirp.NotFoundError("cannot set the status of a document that doesn't exist")
+		} else if err != nil {
+			return twirp.InternalErrorf("check type of document: %w", err)
+		}
+
+		docType = t
+	}
+
+	for i, s := range req.Status {
+		if s == nil {
+			return twirp.InvalidArgumentError(
+				fmt.Sprintf("status.%d", i),
+				"a status cannot be nil")
+		}
+
+		if s.Name == "" {
+			return twirp.InvalidArgumentError(
+				fmt.Sprintf("status.%d.name", i),
+				"a status cannot have an empty name")
+		}
+
+		if s.Version < -1 {
+			return twirp.InvalidArgumentError(
+				fmt.Sprintf("status.%d.version", i),
+				"must be -1 or greater",
+			)
+		}
+
+		if req.Document == nil && s.Version == 0 {
+			return twirp.InvalidArgumentError(
+				fmt.Sprintf("status.%d.version", i),
+				"required when no document is included")
+		}
+
+		if !a.workflows.HasStatus(docType, s.Name) {
+			return twirp.InvalidArgumentError(
+				fmt.Sprintf("status.%d.name", i),
+				fmt.Sprintf("unknown status %q for %q",
+					s.Name, docType))
+		}
+	}
+
+	err = verifyACLParam(req.Acl)
+	if err != nil {
+		return err
+	}
+
+	if isMeta {
+		// For meta documents the access check is made against the main
+		// document, as meta documents don't have ACLs of their own.
+		mainUUID, err := parseMetaURI(req.Document.Uri)
+		if err != nil {
+			return twirp.InvalidArgumentError(
+				"document.uri", err.Error())
+		}
+
+		err = a.accessCheck(ctx, auth, mainUUID,
+			WritePermission,
+			MetaWritePermission,
+		)
+		if err != nil {
+			return err
+		}
+	} else {
+		perm := []Permission{
+			WritePermission,
+		}
+
+		onlyStatus := len(req.Status) > 0 &&
+			req.Document == nil && len(req.Acl) == 0
+
+		// Include the set status permission if the update request only
+		// sets statuses.
+		if onlyStatus {
+			perm = append(perm, SetStatusPermission)
+		}
+
+		// Check for ACL permissions, but allow the write if no
+		// document is found, as we want to allow the creation of new
+		// documents.
+		err = a.accessCheck(ctx, auth, docUUID, perm...)
+		if err != nil && !elephantine.IsTwirpErrorCode(err, twirp.NotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Verifies an ACL parameter, error message assumes that the parameter is named
+// "acl". If you want to use the function to validate a requets where the ACL
+// param has a differen name you have to add an argument for the parameter name.
+func verifyACLParam(acl []*repository.ACLEntry) error {
+	for i, e := range acl {
+		if e == nil {
+			return twirp.InvalidArgumentError(
+				fmt.Sprintf("acl.%d", i),
+				"an ACL entry cannot be nil")
+		}
+
+		for _, p := range e.Permissions {
+			if !IsValidPermission(Permission(p)) {
+				return twirp.InvalidArgumentError(
+					fmt.Sprintf("acl.%d.permissions", i),
+					fmt.Sprintf("%q is not a valid permission", p))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (a *DocumentsService) verifyMetaDocumentUpdate(
+	ctx context.Context,
+	req *repository.UpdateRequest,
+	docUUID uuid.UUID,
+	created map[uuid.UUID]string,
+) error {
+	directUpdate := !req.UpdateMetaDocument
+
+	if len(req.Acl) != 0 {
+		return twirp.InvalidArgumentError(
+			"acl", "cannot set ACLs on a meta document")
+	}
+
+	if len(req.Status) != 0 {
+		return twirp.InvalidArgumentError(
+			"status", "cannot set statuses on a meta document")
+	}
+
+	if req.IfMatch == 0 {
+		return twirp.InvalidArgumentError(
+			"if_match", "is required, updates of the meta document must use optimistic locks")
+	}
+
+	if req.Document == nil {
+		return twirp.RequiredArgumentError("document")
+	}
+
+	mainUUID := docUUID
+
+	if directUpdate {
+		main, err := parseMetaURI(req.Document.Uri)
+		if err != nil {
+			return twirp.InvalidArgumentError(
+				"document.uri", err.Error())
+		}
+
+		mainUUID = main
+
+		metaUUID, _ := metaIdentity(mainUUID)
+		if docUUID != metaUUID {
+			return twirp.InvalidArgumentError(
+				"uid",
+				fmt.Sprintf("uuid must be %s based on the meta URI", metaUUID))
+		}
+	} else {
+		metaURI := metaURI(docUUID)
+
+		if req.Document.Uri != "" && req.Document.Uri != metaURI {
+			return twirp.InvalidArgumentError(
+				"document.uri",
+				fmt.Sprintf("document URI must be %q or empty", metaURI))
+		}
+
+		req.Document.Uri = metaURI
+	}
+
+	mt, err := a.store.GetMetaTypeForDocument(ctx, mainUUID)
+	if err != nil {
+		return twirp.InternalErrorf(
+			"could not get meta type for document: %w", err)
+	}
+
+	mainType, mainCreated := created[mainUUID]
+	if !mt.Exists && mainCreated {
+		mt, err = a.store.GetMetaTypeForType(ctx, mainType)
+		if err != nil {
+			return twirp.InternalErrorf(
+				"could not get meta type for document type: %w", err)
+		}
+	}
+
+	if !mt.Exists {
+		return twirp.FailedPrecondition.Error(
+			"main document doesn't exist")
+	}
+
+	if mt.IsMetaDocument {
+		return twirp.InvalidArgumentError("update_meta_document",
+			"meta documents cannot have meta documents in turn")
+	}
+
+	if mt.MetaType == "" {
+		return twirp.InvalidArgument.Error(
+			"document type doesn't have a configured meta document type")
+	}
+
+	if req.Document.Type != mt.MetaType {
+		return twirp.InvalidArgumentError("document.type",
+			fmt.Sprintf("the meta document type has to be %q", mt.MetaType))
+	}
+
+	return nil
+}
+
+// Validate implements repository.Documents.
+func (a *DocumentsService) Validate(
+	ctx context.Context, req *repository.ValidateRequest,
+) (*repository.ValidateResponse, error) {
+	if req.Document == nil {
+		return nil, twirp.RequiredArgumentError("document")
+	}
+
+	doc := rpcdoc.DocumentFromRPC(req.Document)
+
+	doc, _ = a.validator.TransformDocument(doc)
+
+	validationResult, err := a.validator.ValidateDocument(ctx, &doc)
+	if err != nil {
+		//nolint: wrapcheck
+		return nil, err
+	}
+
+	var res repository.ValidateResponse
+
+	for _, r := range validationResult {
+		res.Errors = append(res.Errors, &repository.ValidationResult{
+			Entity: EntityRefToRPC(r.Entity),
+			Error:  r.Error,
+		})
+	}
+
+	return &res, nil
+}
+
+// transformOnReadPath applies the transforms of the active schema generation
+// to a document that is being returned to a client, if transform on read has
+// been enabled.
+func (a *DocumentsService) transformOnReadPath(
+	doc newsdoc.Document,
+) newsdoc.Document {
+	if !a.transformOnRead {
+		return doc
+	}
+
+	doc, _ = a.validator.TransformDocument(doc)
+
+	return doc
+}
+
+// Prune implements repository.Documents.
+func (a *DocumentsService) Prune(
+	ctx context.Context, req *repository.PruneRequest,
+) (*repository.PruneResponse, error) {
+	if req.Document == nil {
+		return nil, twirp.RequiredArgumentError("document")
+	}
+
+	doc := rpcdoc.DocumentFromRPC(req.Document)
+
+	pruneResult, err := a.validator.PruneDocument(ctx, &doc)
+	if err != nil {
+		//nolint: wrapcheck
+		return nil, err
+	}
+
+	var res repository.PruneResponse
+
+	for _, r := range pruneResult {
+		res.Errors = append(res.Errors, &repository.ValidationResult{
+			Entity: EntityRefToRPC(r.Entity),
+			Error:  r.Error,
+		})
+	}
+
+	if len(res.Errors) == 0 {
+		res.Document = rpcdoc.DocumentToRPC(doc)
+	}
+
+	return &res, nil
+}
+
+func (a *DocumentsService) Lock(
+	ctx context.Context, req *repository.LockRequest,
+) (*repository.LockResponse, error) {
+	elephantine.SetLogMetadata(ctx,
+		elephantine.LogKeyDocumentUUID, req.Uuid,
+	)
+
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentWrite, ScopeDocumentDelete, ScopeDocumentAdmin,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	docUUID, err := validateRequiredUUIDParam(req.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.accessCheck(ctx, auth, docUUID, WritePermission)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Ttl == 0 {
+		return nil, twirp.RequiredArgumentError("ttl")
+	}
+
+	exclusivity, err := lockExclusivityFromRPC(req.Exclusivity)
+	if err != nil {
+		return nil, twirp.InvalidArgumentError("exclusivity", err.Error())
+	}
+
+	lock, err := a.store.Lock(ctx, LockRequest{
+		UUID:        docUUID,
+		TTL:         req.Ttl,
+		URI:         auth.Claims.Subject,
+		App:         req.App,
+		Comment:     req.Comment,
+		Exclusivity: exclusivity,
+	})
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeDeleteLock), IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.FailedPrecondition.Error("could not find the document")
+	case IsDocStoreErrorCode(err, ErrCodeBadRequest):
+		return nil, twirp.InvalidArgument.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeDocumentLock):
+		return nil, lockConflictTwirp(err)
+	case err != nil:
+		return nil, fmt.Errorf("could not obtain lock: %w", err)
+	}
+
+	return &repository.LockResponse{
+		Token:   lock.Token,
+		Expires: lock.Expires.Format(time.RFC3339),
+	}, nil
+}
+
+// lockConflictTwirp wraps a LockConflictError as a
+// twirp.FailedPrecondition error with metadata describing the
+// existing lock's holder. Clients compare lock_holder_sub against
+// their own subject to distinguish "I already hold this" from "held
+// by someone else".
+func lockConflictTwirp(err error) twirp.Error {
+	var conflict *LockConflictError
+	if !errors.As(err, &conflict) {
+		return twirp.FailedPrecondition.Error("the document is locked")
+	}
+
+	twerr := twirp.FailedPrecondition.Error("the document is locked").
+		WithMeta("lock_holder_sub", conflict.Holder.URI)
+
+	if conflict.Holder.App != "" {
+		twerr = twerr.WithMeta("lock_app", conflict.Holder.App)
+	}
+
+	if conflict.Holder.Comment != "" {
+		twerr = twerr.WithMeta("lock_comment", conflict.Holder.Comment)
+	}
+
+	if !conflict.Holder.Expires.IsZero() {
+		twerr = twerr.WithMeta("lock_expires", conflict.Holder.Expires.Format(time.RFC3339))
+	}
+
+	if conflict.Holder.Exclusivity != "" {
+		twerr = twerr.WithMeta("lock_exclusivity", string(conflict.Holder.Exclusivity))
+	}
+
+	return twerr
+}
+
+func lockExclusivityFromRPC(
+	e repository.LockExclusivity,
+) (LockExclusivity, error) {
+	switch e {
+	case repository.LockExclusivity_LOCK_DOCUMENT:
+		return LockExclusivityDocument, nil
+	case repository.LockExclusivity_LOCK_STATUS:
+		return LockExclusivityStatus, nil
+	case repository.LockExclusivity_LOCK_ACL:
+		return LockExclusivityACL, nil
+	case repository.LockExclusivity_LOCK_EXCLUSIVE:
+		return LockExclusivityExclusive, nil
+	}
+
+	return "", fmt.Errorf("unknown lock exclusivity value %d", e)
+}
+
+func lockExclusivityToRPC(e LockExclusivity) repository.LockExclusivity {
+	switch e {
+	case LockExclusivityStatus:
+		return repository.LockExclusivity_LOCK_STATUS
+	case LockExclusivityACL:
+		return repository.LockExclusivity_LOCK_ACL
+	case LockExclusivityExclusive:
+		return repository.LockExclusivity_LOCK_EXCLUSIVE
+	case LockExclusivityDocument:
+	}
+
+	return repository.LockExclusivity_LOCK_DOCUMENT
+}
+
+// ExtendLock extends the expiration of an existing lock.
+func (a *DocumentsService) ExtendLock(
+	ctx context.Context, req *repository.ExtendLockRequest,
+) (*repository.LockResponse, error) {
+	elephantine.SetLogMetadata(ctx,
+		elephantine.LogKeyDocumentUUID, req.Uuid,
+	)
+
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentWrite, ScopeDocumentDelete, ScopeDocumentAdmin,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	docUUID, err := validateRequiredUUIDParam(req.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.accessCheck(ctx, auth, docUUID, WritePermission)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Ttl == 0 {
+		return nil, twirp.RequiredArgumentError("ttl")
+	}
+
+	if req.Token == "" {
+		return nil, twirp.RequiredArgumentError("token")
+	}
+
+	lock, err := a.store.UpdateLock(ctx, UpdateLockRequest{
+		UUID:  docUUID,
+		TTL:   req.Ttl,
+		Token: req.Token,
+	})
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeDeleteLock), IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.FailedPrecondition.Error("could not find the document")
+	case IsDocStoreErrorCode(err, ErrCodeNoSuchLock):
+		return nil, twirp.FailedPrecondition.Error("the document is not locked by anyone")
+	case IsDocStoreErrorCode(err, ErrCodeDocumentLock):
+		return nil, twirp.FailedPrecondition.Error("the doument is locked by someone else")
+	case err != nil:
+		return nil, fmt.Errorf("could not obtain lock: %w", err)
+	}
+
+	return &repository.LockResponse{
+		Token: lock.Token,
+	}, nil
+}
+
+func (a *DocumentsService) Unlock(
+	ctx context.Context, req *repository.UnlockRequest,
+) (*repository.UnlockResponse, error) {
+	elephantine.SetLogMetadata(ctx,
+		elephantine.LogKeyDocumentUUID, req.Uuid,
+	)
+
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentWrite, ScopeDocumentDelete, ScopeDocumentAdmin,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	docUUID, err := validateRequiredUUIDParam(req.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.accessCheck(ctx, auth, docUUID, WritePermission)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Token == "" {
+		return nil, twirp.RequiredArgumentError("token")
+	}
+
+	err = a.store.Unlock(ctx, docUUID, req.Token)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeDeleteLock):
+		return &repository.UnlockResponse{}, nil
+	case IsDocStoreErrorCode(err, ErrCodeDocumentLock):
+		return nil, twirp.FailedPrecondition.Errorf("the document is locked by someone else")
+	case err != nil:
+		return nil, fmt.Errorf("could not unlock document: %w", err)
+	}
+
+	return &repository.UnlockResponse{}, nil
+}
+
+// GetWithheld implements repository.Documents.
+func (a *DocumentsService) GetWithheld(
+	ctx context.Context, _ *repository.GetWithheldRequest,
+) (*repository.GetWithheldResponse, error) {
+	_, err := RequireAnyScope(ctx,
+		ScopeDocumentAdmin,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	after := time.Now().Add(-SchedulerRetryWindow)
+
+	scheduled, err := a.sched.GetScheduled(ctx, after, []string{"oc"})
+	if err != nil {
+		return nil, twirp.InternalErrorf(
+			"get scheduled documents: %w", err)
+	}
+
+	var res repository.GetWithheldResponse
+
+	for _, sch := range scheduled {
+		res.Items = append(res.Items, &repository.ScheduledDocument{
+			Uuid:            sch.UUID.String(),
+			Type:            sch.Type,
+			StatusId:        sch.StatusID,
+			DocumentVersion: sch.DocumentVersion,
+			PlanningItem:    sch.PlanningItem.String(),
+			Assignment:      sch.Assignment.String(),
+			Publish:         sch.Publish.Format(time.RFC3339),
+			ScheduledBy:     sch.ScheduledBy,
+		})
+	}
+
+	return &res, nil
+}
+
+// CreateUpload implements repository.Documents.
+func (a *DocumentsService) CreateUpload(
+	ctx context.Context, req *repository.CreateUploadRequest,
+) (*repository.CreateUploadResponse, error) {
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentAdmin, ScopeAssetUpload,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name == "" {
+		return nil, twirp.RequiredArgumentError("name")
+	}
+
+	if req.ContentType == "" {
+		return nil, twirp.RequiredArgumentError("content_type")
+	}
+
+	checksum, size, err := uploadDeclarationFromMeta(req.Meta)
+	if err != nil {
+		return nil, twirp.InvalidArgumentError("meta", err.Error())
+	}
+
+	upload := Upload{
+		ID:        uuid.New(),
+		CreatedBy: auth.Claims.Subject,
+		CreatedAt: time.Now(),
+		Meta: AssetMetadata{
+			Filename: req.Name,
+			Mimetype: req.ContentType,
+			Props:    make(map[string]string),
+			SHA256:   checksum,
+			Size:     size,
+		},
+	}
+
+	if req.Meta != nil {
+		maps.Copy(upload.Meta.Props, req.Meta)
+
+		delete(upload.Meta.Props, UploadMetaSHA256)
+		delete(upload.Meta.Props, UploadMetaSize)
+	}
+
+	err = a.store.CreateUpload(ctx, upload)
+	if err != nil {
+		return nil, twirp.InternalErrorf("store upload record: %v", err)
+	}
 
-		// Check for ACL permissions, but allow the write if no
-		// document is found, as we want to allow the creation of new
-		// documents.
-		err = a.accessCheck(ctx, auth, docUUID, perm...)
-		if err != nil && !elephantine.IsTwirpErrorCode(err, twirp.NotFound) {
-			return err
-		}
+	uploadURL, err := a.assets.CreateUploadURL(ctx, upload.ID)
+	if err != nil {
+		return nil, twirp.InternalErrorf("create upload URL: %v", err)
 	}
 
-	return nil
+	res := repository.CreateUploadResponse{
+		Id:  upload.ID.String(),
+		Url: uploadURL,
+	}
+
+	return &res, nil
 }
 
-// Verifies an ACL parameter, error message assumes that the parameter is named
-// "acl". If you want to use the function to validate a requets where the ACL
-// param has a differen name you have to add an argument for the parameter name.
-func verifyACLParam(acl []*repository.ACLEntry) error {
-	for i, e := range acl {
-		if e == nil {
-			return twirp.InvalidArgumentError(
-				fmt.Sprintf("acl.%d", i),
-				"an ACL entry cannot be nil")
-		}
+const (
+	// maxUploadPartNumber is the highest part number that the object store
+	// accepts for a multipart upload.
+	maxUploadPartNumber = 10000
+	// maxUploadPartURLs is the maximum number of part URLs that can be
+	// requested in one call.
+	maxUploadPartURLs = 100
+)
 
-		for _, p := range e.Permissions {
-			if !IsValidPermission(Permission(p)) {
-				return twirp.InvalidArgumentError(
-					fmt.Sprintf("acl.%d.permissions", i),
-					fmt.Sprintf("%q is not a valid permission", p))
-			}
-		}
-	}
+type CreateMultipartUploadRequest struct {
+	Name        string            `json:"name"`
+	ContentType string            `json:"content_type"`
+	Meta        map[string]string `json:"meta,omitempty"`
+	// SHA256 is the expected hex encoded SHA-256 checksum of the object.
+	SHA256 string `json:"sha256,omitempty"`
+	// Size is the expected size of the object in bytes.
+	Size int64 `json:"size,omitempty"`
+}
 
-	return nil
+type CreateMultipartUploadResponse struct {
+	ID string `json:"id"`
 }
 
-func (a *DocumentsService) verifyMetaDocumentUpdate(
-	ctx context.Context,
-	req *repository.UpdateRequest,
-	docUUID uuid.UUID,
-) error {
-	directUpdate := !req.UpdateMetaDocument
+// CreateMultipartUpload starts a multipart upload. Presigned URLs for the parts
+// are requested using GetUploadPartURLs, and the upload must be completed using
+// CompleteMultipartUpload before it can be attached to a document.
+func (a *DocumentsService) CreateMultipartUpload(
+	ctx context.Context, req *CreateMultipartUploadRequest,
+) (*CreateMultipartUploadResponse, error) {
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentAdmin, ScopeAssetUpload,
+	)
+	if err != nil {
+		return nil, err
+	}
 
-	if len(req.Acl) != 0 {
-		return twirp.InvalidArgumentError(
-			"acl", "cannot set ACLs on a meta document")
+	if req.Name == "" {
+		return nil, twirp.RequiredArgumentError("name")
 	}
 
-	if len(req.Status) != 0 {
-		return twirp.InvalidArgumentError(
-			"status", "cannot set statuses on a meta document")
+	if req.ContentType == "" {
+		return nil, twirp.RequiredArgumentError("content_type")
 	}
 
-	if req.IfMatch == 0 {
-		return twirp.InvalidArgumentError(
-			"if_match", "is required, updates of the meta document must use optimistic locks")
+	checksum, err := uploadDeclaration(req.SHA256, req.Size)
+	if err != nil {
+		return nil, twirp.InvalidArgument.Error(err.Error())
 	}
 
-	if req.Document == nil {
-		return twirp.RequiredArgumentError("document")
+	upload := Upload{
+		ID:        uuid.New(),
+		CreatedBy: auth.Claims.Subject,
+		CreatedAt: time.Now(),
+		Meta: AssetMetadata{
+			Filename: req.Name,
+			Mimetype: req.ContentType,
+			Props:    make(map[string]string),
+			SHA256:   checksum,
+			Size:     req.Size,
+		},
+		MultipartStatus: MultipartStatusUploading,
 	}
 
-	mainUUID := docUUID
+	maps.Copy(upload.Meta.Props, req.Meta)
 
-	if directUpdate {
-		main, err := parseMetaURI(req.Document.Uri)
-		if err != nil {
-			return twirp.InvalidArgumentError(
-				"document.uri", err.Error())
-		}
+	multipartID, err := a.assets.CreateMultipartUpload(
+		ctx, upload.ID, req.ContentType)
+	if err != nil {
+		return nil, twirp.InternalErrorf(
+			"create multipart upload: %v", err)
+	}
 
-		mainUUID = main
+	upload.MultipartID = multipartID
 
-		metaUUID, _ := metaIdentity(mainUUID)
-		if docUUID != metaUUID {
-			return twirp.InvalidArgumentError(
-				"uid",
-				fmt.Sprintf("uuid must be %s based on the meta URI", metaUUID))
-		}
-	} else {
-		metaURI := metaURI(docUUID)
+	err = a.store.CreateUpload(ctx, upload)
+	if err != nil {
+		return nil, twirp.InternalErrorf("store upload record: %v", err)
+	}
 
-		if req.Document.Uri != "" && req.Document.Uri != metaURI {
-			return twirp.InvalidArgumentError(
-				"document.uri",
-				fmt.Sprintf("document URI must be %q or empty", metaURI))
-		}
+	return &CreateMultipartUploadResponse{
+		ID: upload.ID.String(),
+	}, nil
+}
 
-		req.Document.Uri = metaURI
-	}
+type GetUploadPartURLsRequest struct {
+	ID string `json:"id"`
+	// Parts are the numbers of the parts to get upload URLs for, part
+	// numbers start at 1.
+	Parts []int32 `json:"parts"`
+}
 
-	mt, err := a.store.GetMetaTypeForDocument(ctx, mainUUID)
+type GetUploadPartURLsResponse struct {
+	Parts []UploadPartURL `json:"parts"`
+}
+
+type UploadPartURL struct {
+	PartNumber int32  `json:"part_number"`
+	URL        string `json:"url"`
+}
+
+// GetUploadPartURLs returns presigned upload URLs for parts of a multipart
+// upload. The URLs expire after 15 minutes, new URLs can be requested for as
+// long as the upload is in progress.
+func (a *DocumentsService) GetUploadPartURLs(
+	ctx context.Context, req *GetUploadPartURLsRequest,
+) (*GetUploadPartURLsResponse, error) {
+	upload, err := a.getMultipartUpload(ctx, req.ID)
 	if err != nil {
-		return twirp.InternalErrorf(
-			"could not get meta type for document: %w", err)
+		return nil, err
 	}
 
-	if !mt.Exists {
-		return twirp.FailedPrecondition.Error(
-			"main document doesn't exist")
+	if len(req.Parts) == 0 {
+		return nil, twirp.RequiredArgumentError("parts")
 	}
 
-	if mt.IsMetaDocument {
-		return twirp.InvalidArgumentError("update_meta_document",
-			"meta documents cannot have meta documents in turn")
+	if len(req.Parts) > maxUploadPartURLs {
+		return nil, twirp.InvalidArgumentError("parts", fmt.Sprintf(
+			"cannot request more than %d part URLs at a time",
+			maxUploadPartURLs))
 	}
 
-	if mt.MetaType == "" {
-		return twirp.InvalidArgument.Error(
-			"document type doesn't have a configured meta document type")
+	res := GetUploadPartURLsResponse{
+		Parts: make([]UploadPartURL, len(req.Parts)),
 	}
 
-	if req.Document.Type != mt.MetaType {
-		return twirp.InvalidArgumentError("document.type",
-			fmt.Sprintf("the meta document type has to be %q", mt.MetaType))
+	for i, n := range req.Parts {
+		if n < 1 || n > maxUploadPartNumber {
+			return nil, twirp.InvalidArgumentError(
+				fmt.Sprintf("parts.%d", i), fmt.Sprintf(
+					"part numbers must be between 1 and %d",
+					maxUploadPartNumber))
+		}
+
+		partURL, err := a.assets.CreateUploadPartURL(
+			ctx, upload.ID, upload.MultipartID, n)
+		if err != nil {
+			return nil, twirp.InternalErrorf(
+				"create upload URL for part %d: %v", n, err)
+		}
+
+		res.Parts[i] = UploadPartURL{
+			PartNumber: n,
+			URL:        partURL,
+		}
 	}
 
-	return nil
+	return &res, nil
 }
 
-// Validate implements repository.Documents.
-func (a *DocumentsService) Validate(
-	ctx context.Context, req *repository.ValidateRequest,
-) (*repository.ValidateResponse, error) {
-	if req.Document == nil {
-		return nil, twirp.RequiredArgumentError("document")
-	}
+type ListUploadPartsRequest struct {
+	ID string `json:"id"`
+}
 
-	doc := rpcdoc.DocumentFromRPC(req.Document)
+type ListUploadPartsResponse struct {
+	Parts []UploadPart `json:"parts"`
+}
 
-	validationResult, err := a.validator.ValidateDocument(ctx, &doc)
+// ListUploadParts lists the parts that have been uploaded for a multipart
+// upload, so that clients can resume an interrupted upload.
+func (a *DocumentsService) ListUploadParts(
+	ctx context.Context, req *ListUploadPartsRequest,
+) (*ListUploadPartsResponse, error) {
+	upload, err := a.getMultipartUpload(ctx, req.ID)
 	if err != nil {
-		//nolint: wrapcheck
 		return nil, err
 	}
 
-	var res repository.ValidateResponse
-
-	for _, r := range validationResult {
-		res.Errors = append(res.Errors, &repository.ValidationResult{
-			Entity: EntityRefToRPC(r.Entity),
-			Error:  r.Error,
-		})
+	parts, err := a.assets.ListUploadParts(
+		ctx, upload.ID, upload.MultipartID)
+	if err != nil {
+		return nil, twirp.InternalErrorf("list upload parts: %v", err)
 	}
 
-	return &res, nil
+	return &ListUploadPartsResponse{
+		Parts: parts,
+	}, nil
 }
 
-// Prune implements repository.Documents.
-func (a *DocumentsService) Prune(
-	ctx context.Context, req *repository.PruneRequest,
-) (*repository.PruneResponse, error) {
-	if req.Document == nil {
-		return nil, twirp.RequiredArgumentError("document")
-	}
+type CompleteMultipartUploadRequest struct {
+	ID string `json:"id"`
+	// Parts to assemble into the uploaded object, all uploaded parts are
+	// used if omitted.
+	Parts []UploadPart `json:"parts,omitempty"`
+}
 
-	doc := rpcdoc.DocumentFromRPC(req.Document)
+type CompleteMultipartUploadResponse struct{}
 
-	pruneResult, err := a.validator.PruneDocument(ctx, &doc)
+// CompleteMultipartUpload assembles the uploaded parts into the uploaded
+// object.
+func (a *DocumentsService) CompleteMultipartUpload(
+	ctx context.Context, req *CompleteMultipartUploadRequest,
+) (*CompleteMultipartUploadResponse, error) {
+	upload, err := a.getMultipartUpload(ctx, req.ID)
 	if err != nil {
-		//nolint: wrapcheck
 		return nil, err
 	}
 
-	var res repository.PruneResponse
+	parts := req.Parts
 
-	for _, r := range pruneResult {
-		res.Errors = append(res.Errors, &repository.ValidationResult{
-			Entity: EntityRefToRPC(r.Entity),
-			Error:  r.Error,
-		})
+	if len(parts) == 0 {
+		parts, err = a.assets.ListUploadParts(
+			ctx, upload.ID, upload.MultipartID)
+		if err != nil {
+			return nil, twirp.InternalErrorf(
+				"list upload parts: %v", err)
+		}
 	}
 
-	if len(res.Errors) == 0 {
-		res.Document = rpcdoc.DocumentToRPC(doc)
+	if len(parts) == 0 {
+		return nil, twirp.FailedPrecondition.Error(
+			"no parts have been uploaded")
 	}
 
-	return &res, nil
-}
+	slices.SortFunc(parts, func(a, b UploadPart) int {
+		return int(a.PartNumber - b.PartNumber)
+	})
 
-func (a *DocumentsService) Lock(
-	ctx context.Context, req *repository.LockRequest,
-) (*repository.LockResponse, error) {
-	elephantine.SetLogMetadata(ctx,
-		elephantine.LogKeyDocumentUUID, req.Uuid,
-	)
+	for i, p := range parts {
+		if p.ETag == "" {
+			return nil, twirp.RequiredArgumentError(
+				fmt.Sprintf("parts.%d.etag", i))
+		}
+	}
 
-	auth, err := RequireAnyScope(ctx,
-		ScopeDocumentWrite, ScopeDocumentDelete, ScopeDocumentAdmin,
-	)
+	err = a.assets.CompleteMultipartUpload(
+		ctx, upload.ID, upload.MultipartID, parts)
 	if err != nil {
-		return nil, err
+		return nil, twirp.InternalErrorf(
+			"complete multipart upload: %v", err)
 	}
 
-	docUUID, err := validateRequiredUUIDParam(req.Uuid)
+	err = a.store.SetUploadMultipartStatus(ctx, upload.ID,
+		MultipartStatusUploading, MultipartStatusCompleted)
 	if err != nil {
-		return nil, err
+		return nil, twirpErrorFromMultipartStatusError(err)
 	}
 
-	err = a.accessCheck(ctx, auth, docUUID, WritePermission)
+	return &CompleteMultipartUploadResponse{}, nil
+}
+
+type AbortMultipartUploadRequest struct {
+	ID string `json:"id"`
+}
+
+type AbortMultipartUploadResponse struct{}
+
+// AbortMultipartUpload aborts a multipart upload and discards the uploaded
+// parts.
+func (a *DocumentsService) AbortMultipartUpload(
+	ctx context.Context, req *AbortMultipartUploadRequest,
+) (*AbortMultipartUploadResponse, error) {
+	upload, err := a.getMultipartUpload(ctx, req.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	if req.Ttl == 0 {
-		return nil, twirp.RequiredArgumentError("ttl")
+	err = a.assets.AbortMultipartUpload(
+		ctx, upload.ID, upload.MultipartID)
+	if err != nil {
+		return nil, twirp.InternalErrorf(
+			"abort multipart upload: %v", err)
 	}
 
-	exclusivity, err := lockExclusivityFromRPC(req.Exclusivity)
+	err = a.store.SetUploadMultipartStatus(ctx, upload.ID,
+		MultipartStatusUploading, MultipartStatusAborted)
 	if err != nil {
-		return nil, twirp.InvalidArgumentError("exclusivity", err.Error())
+		return nil, twirpErrorFromMultipartStatusError(err)
 	}
 
-	lock, err := a.store.Lock(ctx, LockRequest{
-		UUID:        docUUID,
-		TTL:         req.Ttl,
-		URI:         auth.Claims.Subject,
-		App:         req.App,
-		Comment:     req.Comment,
-		Exclusivity: exclusivity,
-	})
+	return &AbortMultipartUploadResponse{}, nil
+}
 
-	switch {
-	case IsDocStoreErrorCode(err, ErrCodeDeleteLock), IsDocStoreErrorCode(err, ErrCodeNotFound):
-		return nil, twirp.FailedPrecondition.Error("could not find the document")
-	case IsDocStoreErrorCode(err, ErrCodeBadRequest):
-		return nil, twirp.InvalidArgument.Error(err.Error())
-	case IsDocStoreErrorCode(err, ErrCodeDocumentLock):
-		return nil, lockConflictTwirp(err)
-	case err != nil:
-		return nil, fmt.Errorf("could not obtain lock: %w", err)
+// getMultipartUpload loads a multipart upload that is in progress. Only the
+// creator of the upload, or a document admin, can work with an upload.
+func (a *DocumentsService) getMultipartUpload(
+	ctx context.Context, id string,
+) (*Upload, error) {
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentAdmin, ScopeAssetUpload,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	return &repository.LockResponse{
-		Token:   lock.Token,
-		Expires: lock.Expires.Format(time.RFC3339),
-	}, nil
-}
-
-// lockConflictTwirp wraps a LockConflictError as a
-// twirp.FailedPrecondition error with metadata describing the
-// existing lock's holder. Clients compare lock_holder_sub against
-// their own subject to distinguish "I already hold this" from "held
-// by someone else".
-func lockConflictTwirp(err error) twirp.Error {
-	var conflict *LockConflictError
-	if !errors.As(err, &conflict) {
-		return twirp.FailedPrecondition.Error("the document is locked")
+	uploadID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, twirp.InvalidArgumentError("id", err.Error())
 	}
 
-	twerr := twirp.FailedPrecondition.Error("the document is locked").
-		WithMeta("lock_holder_sub", conflict.Holder.URI)
+	upload, err := a.store.GetUpload(ctx, uploadID)
 
-	if conflict.Holder.App != "" {
-		twerr = twerr.WithMeta("lock_app", conflict.Holder.App)
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.NotFoundError(err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf("load upload: %v", err)
 	}
 
-	if conflict.Holder.Comment != "" {
-		twerr = twerr.WithMeta("lock_comment", conflict.Holder.Comment)
+	if upload.CreatedBy != auth.Claims.Subject &&
+		!auth.Claims.HasScope(ScopeDocumentAdmin) {
+		return nil, twirp.PermissionDenied.Error(
+			"the upload was created by someone else")
 	}
 
-	if !conflict.Holder.Expires.IsZero() {
-		twerr = twerr.WithMeta("lock_expires", conflict.Holder.Expires.Format(time.RFC3339))
+	if upload.MultipartID == "" {
+		return nil, twirp.FailedPrecondition.Error(
+			"not a multipart upload")
 	}
 
-	if conflict.Holder.Exclusivity != "" {
-		twerr = twerr.WithMeta("lock_exclusivity", string(conflict.Holder.Exclusivity))
+	if upload.MultipartStatus != MultipartStatusUploading {
+		return nil, twirp.FailedPrecondition.Errorf(
+			"the multipart upload is %s", upload.MultipartStatus)
 	}
 
-	return twerr
+	return upload, nil
 }
 
-func lockExclusivityFromRPC(
-	e repository.LockExclusivity,
-) (LockExclusivity, error) {
-	switch e {
-	case repository.LockExclusivity_LOCK_DOCUMENT:
-		return LockExclusivityDocument, nil
-	case repository.LockExclusivity_LOCK_STATUS:
-		return LockExclusivityStatus, nil
-	case repository.LockExclusivity_LOCK_ACL:
-		return LockExclusivityACL, nil
-	case repository.LockExclusivity_LOCK_EXCLUSIVE:
-		return LockExclusivityExclusive, nil
+func twirpErrorFromMultipartStatusError(err error) error {
+	if IsDocStoreErrorCode(err, ErrCodeFailedPrecondition) {
+		return twirp.FailedPrecondition.Error(err.Error())
 	}
 
-	return "", fmt.Errorf("unknown lock exclusivity value %d", e)
+	return twirp.InternalErrorf("update upload status: %v", err)
 }
 
-func lockExclusivityToRPC(e LockExclusivity) repository.LockExclusivity {
-	switch e {
-	case LockExclusivityStatus:
-		return repository.LockExclusivity_LOCK_STATUS
-	case LockExclusivityACL:
-		return repository.LockExclusivity_LOCK_ACL
-	case LockExclusivityExclusive:
-		return repository.LockExclusivity_LOCK_EXCLUSIVE
-	case LockExclusivityDocument:
+// GetAttachments implements repository.Documents.
+func (a *DocumentsService) GetAttachments(
+	ctx context.Context, req *repository.GetAttachmentsRequest,
+) (*repository.GetAttachmentsResponse, error) {
+	attachments, err := a.loadAttachments(ctx,
+		req.Documents, req.AttachmentName, req.DownloadLink)
+	if err != nil {
+		return nil, err
 	}
 
-	return repository.LockExclusivity_LOCK_DOCUMENT
+	res := repository.GetAttachmentsResponse{
+		Attachments: make([]*repository.AttachmentDetails, len(attachments)),
+	}
+
+	for i := range attachments {
+		res.Attachments[i] = &repository.AttachmentDetails{
+			Document:     attachments[i].Document.String(),
+			Name:         attachments[i].Name,
+			Version:      attachments[i].Version,
+			DownloadLink: attachments[i].DownloadLink,
+			Filename:     attachments[i].Filename,
+			ContentType:  attachments[i].ContentType,
+		}
+	}
+
+	return &res, nil
 }
 
-// ExtendLock extends the expiration of an existing lock.
-func (a *DocumentsService) ExtendLock(
-	ctx context.Context, req *repository.ExtendLockRequest,
-) (*repository.LockResponse, error) {
-	elephantine.SetLogMetadata(ctx,
-		elephantine.LogKeyDocumentUUID, req.Uuid,
-	)
+type GetAttachmentDetailsRequest struct {
+	Documents      []string `json:"documents"`
+	AttachmentName string   `json:"attachment_name"`
+	DownloadLink   bool     `json:"download_link,omitempty"`
+}
 
-	auth, err := RequireAnyScope(ctx,
-		ScopeDocumentWrite, ScopeDocumentDelete, ScopeDocumentAdmin,
-	)
-	if err != nil {
-		return nil, err
-	}
+type GetAttachmentDetailsResponse struct {
+	Attachments []AttachmentDetailsItem `json:"attachments"`
+}
 
-	docUUID, err := validateRequiredUUIDParam(req.Uuid)
-	if err != nil {
-		return nil, err
-	}
+type AttachmentDetailsItem struct {
+	Document     string `json:"document"`
+	Name         string `json:"name"`
+	Version      int64  `json:"version"`
+	DownloadLink string `json:"download_link,omitempty"`
+	Filename     string `json:"filename"`
+	ContentType  string `json:"content_type"`
+	// SniffedContentType is the content type that was detected when the
+	// upload was verified.
+	SniffedContentType string `json:"sniffed_content_type,omitempty"`
+	// SHA256 is the verified hex encoded SHA-256 checksum of the object,
+	// only set if a checksum was declared for the upload.
+	SHA256 string `json:"sha256,omitempty"`
+	// Size is the verified size of the object in bytes.
+	Size int64 `json:"size,omitempty"`
+	// Derived is the metadata extracted by the attachment processors,
+	// keyed as "<processor>.<key>".
+	Derived map[string]string `json:"derived,omitempty"`
+	// Renditions created by the attachment processors, f.ex. thumbnails.
+	Renditions []AttachmentRenditionItem `json:"renditions,omitempty"`
+}
 
-	err = a.accessCheck(ctx, auth, docUUID, WritePermission)
+type AttachmentRenditionItem struct {
+	Name         string `json:"name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	DownloadLink string `json:"download_link,omitempty"`
+}
+
+// GetAttachmentDetails works like GetAttachments, but also returns the
+// verified checksum and size of the attached objects, and the results of the
+// attachment processors.
+func (a *DocumentsService) GetAttachmentDetails(
+	ctx context.Context, req *GetAttachmentDetailsRequest,
+) (*GetAttachmentDetailsResponse, error) {
+	attachments, err := a.loadAttachments(ctx,
+		req.Documents, req.AttachmentName, req.DownloadLink)
 	if err != nil {
 		return nil, err
 	}
 
-	if req.Ttl == 0 {
-		return nil, twirp.RequiredArgumentError("ttl")
+	res := GetAttachmentDetailsResponse{
+		Attachments: make([]AttachmentDetailsItem, len(attachments)),
 	}
 
-	if req.Token == "" {
-		return nil, twirp.RequiredArgumentError("token")
+	for i := range attachments {
+		res.Attachments[i] = AttachmentDetailsItem{
+			Document:           attachments[i].Document.String(),
+			Name:               attachments[i].Name,
+			Version:            attachments[i].Version,
+			DownloadLink:       attachments[i].DownloadLink,
+			Filename:           attachments[i].Filename,
+			ContentType:        attachments[i].ContentType,
+			SniffedContentType: attachments[i].SniffedContentType,
+			SHA256:             attachments[i].SHA256,
+			Size:               attachments[i].Size,
+			Derived:            attachments[i].Derived,
+		}
+
+		for _, r := range attachments[i].Renditions {
+			res.Attachments[i].Renditions = append(
+				res.Attachments[i].Renditions,
+				AttachmentRenditionItem(r))
+		}
 	}
 
-	lock, err := a.store.UpdateLock(ctx, UpdateLockRequest{
-		UUID:  docUUID,
-		TTL:   req.Ttl,
-		Token: req.Token,
-	})
+	return &res, nil
+}
 
-	switch {
-	case IsDocStoreErrorCode(err, ErrCodeDeleteLock), IsDocStoreErrorCode(err, ErrCodeNotFound):
-		return nil, twirp.FailedPrecondition.Error("could not find the document")
-	case IsDocStoreErrorCode(err, ErrCodeNoSuchLock):
-		return nil, twirp.FailedPrecondition.Error("the document is not locked by anyone")
-	case IsDocStoreErrorCode(err, ErrCodeDocumentLock):
-		return nil, twirp.FailedPrecondition.Error("the doument is locked by someone else")
-	case err != nil:
-		return nil, fmt.Errorf("could not obtain lock: %w", err)
-	}
+type GetAttachmentHistoryRequest struct {
+	UUID           string `json:"uuid"`
+	AttachmentName string `json:"attachment_name"`
+	// DownloadLinks requests download links for all versions.
+	DownloadLinks bool `json:"download_links,omitempty"`
+}
 
-	return &repository.LockResponse{
-		Token: lock.Token,
-	}, nil
+type GetAttachmentHistoryResponse struct {
+	// CurrentVersion is the currently attached version, omitted if the
+	// object has been detached.
+	CurrentVersion int64                   `json:"current_version,omitempty"`
+	Versions       []AttachmentVersionItem `json:"versions"`
 }
 
-func (a *DocumentsService) Unlock(
-	ctx context.Context, req *repository.UnlockRequest,
-) (*repository.UnlockResponse, error) {
-	elephantine.SetLogMetadata(ctx,
-		elephantine.LogKeyDocumentUUID, req.Uuid,
-	)
+type AttachmentVersionItem struct {
+	Version int64 `json:"version"`
+	// ObjectVersion is the object store version ID of the object.
+	ObjectVersion string `json:"object_version"`
+	// DocumentVersion is the document version that the object was
+	// attached at.
+	DocumentVersion    int64             `json:"document_version"`
+	Creator            string            `json:"creator"`
+	Created            time.Time         `json:"created"`
+	Filename           string            `json:"filename"`
+	ContentType        string            `json:"content_type"`
+	SniffedContentType string            `json:"sniffed_content_type,omitempty"`
+	SHA256             string            `json:"sha256,omitempty"`
+	Size               int64             `json:"size,omitempty"`
+	Derived            map[string]string `json:"derived,omitempty"`
+	DownloadLink       string            `json:"download_link,omitempty"`
+}
 
-	auth, err := RequireAnyScope(ctx,
-		ScopeDocumentWrite, ScopeDocumentDelete, ScopeDocumentAdmin,
-	)
+// GetAttachmentHistory returns all versions of an attached object, newest
+// first.
+func (a *DocumentsService) GetAttachmentHistory(
+	ctx context.Context, req *GetAttachmentHistoryRequest,
+) (*GetAttachmentHistoryResponse, error) {
+	auth, docUUID, err := a.attachmentVersionAccess(
+		ctx, req.UUID, req.AttachmentName)
 	if err != nil {
 		return nil, err
 	}
 
-	docUUID, err := validateRequiredUUIDParam(req.Uuid)
-	if err != nil {
-		return nil, err
+	history, err := a.store.GetAttachmentHistory(
+		ctx, docUUID, req.AttachmentName)
+	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
+		return nil, twirp.NotFoundError("no such attachment")
+	} else if err != nil {
+		return nil, twirp.InternalErrorf(
+			"read attachment history: %v", err)
 	}
 
-	err = a.accessCheck(ctx, auth, docUUID, WritePermission)
-	if err != nil {
-		return nil, err
+	res := GetAttachmentHistoryResponse{
+		CurrentVersion: history.CurrentVersion,
+		Versions:       make([]AttachmentVersionItem, len(history.Versions)),
 	}
 
-	if req.Token == "" {
-		return nil, twirp.RequiredArgumentError("token")
+	for i, v := range history.Versions {
+		item, err := a.attachmentVersionItem(ctx, v, req.DownloadLinks)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Versions[i] = item
 	}
 
-	err = a.store.Unlock(ctx, docUUID, req.Token)
+	if !req.DownloadLinks {
+		return &res, nil
+	}
 
-	switch {
-	case IsDocStoreErrorCode(err, ErrCodeDeleteLock):
-		return &repository.UnlockResponse{}, nil
-	case IsDocStoreErrorCode(err, ErrCodeDocumentLock):
-		return nil, twirp.FailedPrecondition.Errorf("the document is locked by someone else")
-	case err != nil:
-		return nil, fmt.Errorf("could not unlock document: %w", err)
+	reads := make([]DocumentRead, len(history.Versions))
+
+	for i, v := range history.Versions {
+		reads[i] = DocumentRead{
+			UUID:       docUUID,
+			Version:    v.Version,
+			Attachment: v.Name,
+		}
 	}
 
-	return &repository.UnlockResponse{}, nil
+	err = a.recordReads(ctx, auth, ReadAuditAttachment, reads)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
 }
 
-// GetWithheld implements repository.Documents.
-func (a *DocumentsService) GetWithheld(
-	ctx context.Context, _ *repository.GetWithheldRequest,
-) (*repository.GetWithheldResponse, error) {
-	_, err := RequireAnyScope(ctx,
-		ScopeDocumentAdmin,
-	)
+type GetAttachmentVersionRequest struct {
+	UUID           string `json:"uuid"`
+	AttachmentName string `json:"attachment_name"`
+	Version        int64  `json:"version"`
+	DownloadLink   bool   `json:"download_link,omitempty"`
+}
+
+type GetAttachmentVersionResponse struct {
+	Attachment AttachmentVersionItem `json:"attachment"`
+}
+
+// GetAttachmentVersion returns a specific version of an attached object,
+// optionally with a download link for that version of the object.
+func (a *DocumentsService) GetAttachmentVersion(
+	ctx context.Context, req *GetAttachmentVersionRequest,
+) (*GetAttachmentVersionResponse, error) {
+	auth, docUUID, err := a.attachmentVersionAccess(
+		ctx, req.UUID, req.AttachmentName)
 	if err != nil {
 		return nil, err
 	}
 
-	after := time.Now().Add(-SchedulerRetryWindow)
+	if req.Version <= 0 {
+		return nil, twirp.InvalidArgumentError("version",
+			"must be a positive attachment version")
+	}
 
-	scheduled, err := a.sched.GetScheduled(ctx, after, []string{"oc"})
-	if err != nil {
+	obj, err := a.store.GetAttachmentVersion(
+		ctx, docUUID, req.AttachmentName, req.Version)
+	if IsDocStoreErrorCode(err, ErrCodeNotFound) {
+		return nil, twirp.NotFoundError("no such attachment version")
+	} else if err != nil {
 		return nil, twirp.InternalErrorf(
-			"get scheduled documents: %w", err)
+			"read attachment version: %v", err)
 	}
 
-	var res repository.GetWithheldResponse
+	item, err := a.attachmentVersionItem(ctx, *obj, req.DownloadLink)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, sch := range scheduled {
-		res.Items = append(res.Items, &repository.ScheduledDocument{
-			Uuid:            sch.UUID.String(),
-			Type:            sch.Type,
-			StatusId:        sch.StatusID,
-			DocumentVersion: sch.DocumentVersion,
-			PlanningItem:    sch.PlanningItem.String(),
-			Assignment:      sch.Assignment.String(),
-			Publish:         sch.Publish.Format(time.RFC3339),
-			ScheduledBy:     sch.ScheduledBy,
+	if req.DownloadLink {
+		err = a.recordReads(ctx, auth, ReadAuditAttachment, []DocumentRead{
+			{
+				UUID:       docUUID,
+				Version:    obj.Version,
+				Attachment: obj.Name,
+			},
 		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return &res, nil
+	return &GetAttachmentVersionResponse{
+		Attachment: item,
+	}, nil
 }
 
-// CreateUpload implements repository.Documents.
-func (a *DocumentsService) CreateUpload(
-	ctx context.Context, req *repository.CreateUploadRequest,
-) (*repository.CreateUploadResponse, error) {
+// attachmentVersionAccess validates the document and attachment name of an
+// attachment version request and checks that the caller has read access to the
+// document.
+func (a *DocumentsService) attachmentVersionAccess(
+	ctx context.Context, docID string, attachmentName string,
+) (*elephantine.AuthInfo, uuid.UUID, error) {
 	auth, err := RequireAnyScope(ctx,
-		ScopeDocumentAdmin, ScopeAssetUpload,
+		ScopeDocumentRead, ScopeDocumentAdmin, ScopeDocumentReadAll,
 	)
 	if err != nil {
-		return nil, err
-	}
-
-	if req.Name == "" {
-		return nil, twirp.RequiredArgumentError("name")
-	}
-
-	if req.ContentType == "" {
-		return nil, twirp.RequiredArgumentError("content_type")
+		return nil, uuid.Nil, err
 	}
 
-	upload := Upload{
-		ID:        uuid.New(),
-		CreatedBy: auth.Claims.Subject,
-		CreatedAt: time.Now(),
-		Meta: AssetMetadata{
-			Filename: req.Name,
-			Mimetype: req.ContentType,
-			Props:    make(map[string]string),
-		},
+	docUUID, err := validateRequiredUUIDParam(docID)
+	if err != nil {
+		return nil, uuid.Nil, err
 	}
 
-	if req.Meta != nil {
-		maps.Copy(upload.Meta.Props, req.Meta)
+	if attachmentName == "" {
+		return nil, uuid.Nil, twirp.RequiredArgumentError("attachment_name")
 	}
 
-	err = a.store.CreateUpload(ctx, upload)
+	err = a.accessCheck(ctx, auth, docUUID, ReadPermission)
 	if err != nil {
-		return nil, twirp.InternalErrorf("store upload record: %v", err)
+		return nil, uuid.Nil, err
 	}
 
-	uploadURL, err := a.assets.CreateUploadURL(ctx, upload.ID)
+	return auth, docUUID, nil
+}
+
+func (a *DocumentsService) attachmentVersionItem(
+	ctx context.Context, obj AttachedObject, downloadLink bool,
+) (AttachmentVersionItem, error) {
+	item := AttachmentVersionItem{
+		Version:            obj.Version,
+		ObjectVersion:      obj.ObjectVersion,
+		DocumentVersion:    obj.AttachedAt,
+		Creator:            obj.CreatedBy,
+		Created:            obj.CreatedAt,
+		Filename:           obj.Filename,
+		ContentType:        obj.Mimetype,
+		SniffedContentType: obj.SniffedMimetype,
+		SHA256:             obj.SHA256,
+		Size:               obj.Size,
+		Derived:            obj.Derived,
+	}
+
+	if !downloadLink {
+		return item, nil
+	}
+
+	link, err := a.assets.CreateVersionDownloadURL(ctx,
+		obj.Document, obj.Name, obj.ObjectVersion)
 	if err != nil {
-		return nil, twirp.InternalErrorf("create upload URL: %v", err)
+		return item, twirp.InternalErrorf(
+			"create download link: %v", err)
 	}
 
-	res := repository.CreateUploadResponse{
-		Id:  upload.ID.String(),
-		Url: uploadURL,
-	}
+	item.DownloadLink = link
 
-	return &res, nil
+	return item, nil
 }
 
-// GetAttachments implements repository.Documents.
-func (a *DocumentsService) GetAttachments(
-	ctx context.Context, req *repository.GetAttachmentsRequest,
-) (*repository.GetAttachmentsResponse, error) {
+// loadAttachments loads the current attachments with the given name for the
+// documents that the caller has read access to.
+func (a *DocumentsService) loadAttachments(
+	ctx context.Context,
+	documents []string, attachmentName string, downloadLink bool,
+) ([]AttachmentDetails, error) {
 	auth, err := RequireAnyScope(ctx,
 		ScopeDocumentRead, ScopeDocumentAdmin, ScopeDocumentReadAll,
 	)
@@ -3013,17 +5150,17 @@ func (a *DocumentsService) GetAttachments(
 		return nil, err
 	}
 
-	if len(req.Documents) == 0 {
+	if len(documents) == 0 {
 		return nil, twirp.RequiredArgumentError("documents")
 	}
 
-	if req.AttachmentName == "" {
+	if attachmentName == "" {
 		return nil, twirp.RequiredArgumentError("attachment_name")
 	}
 
-	docIDs := make([]uuid.UUID, len(req.Documents))
+	docIDs := make([]uuid.UUID, len(documents))
 
-	for i, id := range req.Documents {
+	for i, id := range documents {
 		docID, err := uuid.Parse(id)
 		if err != nil {
 			return nil, elephantine.InvalidArgumentf(
@@ -3061,28 +5198,34 @@ func (a *DocumentsService) GetAttachments(
 	attachments, err := a.store.GetAttachments(
 		ctx,
 		allowedDocs,
-		req.AttachmentName,
-		req.DownloadLink)
+		attachmentName,
+		downloadLink)
 	if err != nil {
 		return nil, twirp.InternalErrorf("get attachments: %v", err)
 	}
 
-	res := repository.GetAttachmentsResponse{
-		Attachments: make([]*repository.AttachmentDetails, len(attachments)),
+	// Attachment details without a download link don't give access to the
+	// attached object, so only downloads are audited.
+	if !downloadLink {
+		return attachments, nil
 	}
 
+	reads := make([]DocumentRead, len(attachments))
+
 	for i := range attachments {
-		res.Attachments[i] = &repository.AttachmentDetails{
-			Document:     attachments[i].Document.String(),
-			Name:         attachments[i].Name,
-			Version:      attachments[i].Version,
-			DownloadLink: attachments[i].DownloadLink,
-			Filename:     attachments[i].Filename,
-			ContentType:  attachments[i].ContentType,
+		reads[i] = DocumentRead{
+			UUID:       attachments[i].Document,
+			Version:    attachments[i].Version,
+			Attachment: attachments[i].Name,
 		}
 	}
 
-	return &res, nil
+	err = a.recordReads(ctx, auth, ReadAuditAttachment, reads)
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
 }
 
 func EntityRefToRPC(ref []revisor.EntityRef) []*repository.EntityRef {
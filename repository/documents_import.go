@@ -0,0 +1,595 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/langos"
+	"github.com/ttab/newsdoc"
+	"github.com/twitchtv/twirp"
+)
+
+// ImportMaxDocuments is the maximum number of documents that can be imported
+// in one request.
+const ImportMaxDocuments = 100
+
+type ImportRequest struct {
+	Documents []ImportDocument `json:"documents"`
+	// ValidateOnly validates the documents and reports what would be
+	// imported without writing anything.
+	ValidateOnly bool `json:"validate_only,omitempty"`
+}
+
+// ImportDocument is a document with its version and status history.
+type ImportDocument struct {
+	UUID string `json:"uuid"`
+	// Versions of the document, numbered from 1 without gaps.
+	Versions []ImportVersion `json:"versions"`
+	// Statuses of the document, numbered from 1 without gaps per status
+	// name.
+	Statuses []ImportStatus `json:"statuses,omitempty"`
+}
+
+type ImportVersion struct {
+	Version  int64            `json:"version"`
+	Created  time.Time        `json:"created"`
+	Creator  string           `json:"creator"`
+	Meta     newsdoc.DataMap  `json:"meta,omitempty"`
+	Document newsdoc.Document `json:"document"`
+	// ACL entries are set on the document when the version is written,
+	// entries without permissions are removed. The importing client is
+	// always granted access to the documents it creates, so that the
+	// import can be re-run.
+	ACL []ACLEntry `json:"acl,omitempty"`
+}
+
+type ImportStatus struct {
+	Name    string          `json:"name"`
+	ID      int64           `json:"id"`
+	Version int64           `json:"version"`
+	Created time.Time       `json:"created"`
+	Creator string          `json:"creator"`
+	Meta    newsdoc.DataMap `json:"meta,omitempty"`
+}
+
+type ImportResponse struct {
+	Documents []ImportResult `json:"documents"`
+}
+
+type ImportOutcome string
+
+const (
+	// ImportOutcomeImported is used when versions or statuses were
+	// written.
+	ImportOutcomeImported ImportOutcome = "imported"
+	// ImportOutcomeValid is used for documents that would have been
+	// imported if this wasn't a validate only request.
+	ImportOutcomeValid ImportOutcome = "valid"
+	// ImportOutcomeUnchanged is used when all versions and statuses
+	// already have been imported.
+	ImportOutcomeUnchanged ImportOutcome = "unchanged"
+	// ImportOutcomeInvalid is used when the document failed validation,
+	// nothing is written for invalid documents.
+	ImportOutcomeInvalid ImportOutcome = "invalid"
+	// ImportOutcomeConflict is used when the repository has versions or
+	// statuses that don't match the imported history.
+	ImportOutcomeConflict ImportOutcome = "conflict"
+	// ImportOutcomeFailed is used when the import was rejected by the
+	// document store. Versions and statuses written before the failure
+	// are kept, and will be skipped when the import is re-run.
+	ImportOutcomeFailed ImportOutcome = "failed"
+)
+
+type ImportResult struct {
+	UUID    string        `json:"uuid"`
+	Outcome ImportOutcome `json:"outcome"`
+	// Versions and Statuses are the number of versions and statuses that
+	// were written.
+	Versions int `json:"versions"`
+	Statuses int `json:"statuses"`
+	// SkippedVersions and SkippedStatuses are the number of versions and
+	// statuses that already were present in the repository.
+	SkippedVersions int             `json:"skipped_versions"`
+	SkippedStatuses int             `json:"skipped_statuses"`
+	Problems        []ImportProblem `json:"problems,omitempty"`
+	Error           string          `json:"error,omitempty"`
+}
+
+// ImportProblem is a validation problem with a version or status of an
+// imported document.
+type ImportProblem struct {
+	Version  int64  `json:"version,omitempty"`
+	Status   string `json:"status,omitempty"`
+	StatusID int64  `json:"status_id,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Import writes documents with their version and status history, keeping the
+// original creators and timestamps. Validation problems are reported per
+// document instead of failing the request, and versions and statuses that
+// already have been imported are skipped, so that an import can be re-run.
+func (a *DocumentsService) Import(
+	ctx context.Context, req *ImportRequest,
+) (*ImportResponse, error) {
+	auth, err := RequireAnyScope(ctx,
+		ScopeDocumentImport, ScopeDocumentAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Documents) == 0 {
+		return nil, twirp.RequiredArgumentError("documents")
+	}
+
+	if len(req.Documents) > ImportMaxDocuments {
+		return nil, twirp.InvalidArgumentError("documents",
+			fmt.Sprintf("cannot import more than %d documents at a time",
+				ImportMaxDocuments))
+	}
+
+	docUUIDs := make([]uuid.UUID, len(req.Documents))
+
+	for i := range req.Documents {
+		docUUID, err := verifyImportDocument(
+			fmt.Sprintf("documents.%d", i), &req.Documents[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if slices.Contains(docUUIDs[:i], docUUID) {
+			return nil, twirp.InvalidArgumentError(
+				fmt.Sprintf("documents.%d.uuid", i),
+				"a document can only be imported once in a request")
+		}
+
+		docUUIDs[i] = docUUID
+	}
+
+	res := ImportResponse{
+		Documents: make([]ImportResult, len(req.Documents)),
+	}
+
+	for i := range req.Documents {
+		result, err := a.importDocument(ctx, auth,
+			docUUIDs[i], &req.Documents[i], req.ValidateOnly)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Documents[i] = *result
+	}
+
+	return &res, nil
+}
+
+// verifyImportDocument checks that the version and status history of an
+// imported document is complete and in order.
+func verifyImportDocument(
+	field string, doc *ImportDocument,
+) (uuid.UUID, error) {
+	docUUID, err := uuid.Parse(doc.UUID)
+	if err != nil {
+		return uuid.Nil, twirp.InvalidArgumentError(
+			field+".uuid", err.Error())
+	}
+
+	if len(doc.Versions) == 0 {
+		return uuid.Nil, twirp.RequiredArgumentError(field + ".versions")
+	}
+
+	var lastCreated time.Time
+
+	for i, v := range doc.Versions {
+		vField := fmt.Sprintf("%s.versions.%d", field, i)
+
+		switch {
+		case v.Version != int64(i+1):
+			return uuid.Nil, twirp.InvalidArgumentError(
+				vField+".version",
+				fmt.Sprintf("expected version %d", i+1))
+		case v.Created.IsZero():
+			return uuid.Nil, twirp.RequiredArgumentError(
+				vField + ".created")
+		case v.Created.Before(lastCreated):
+			return uuid.Nil, twirp.InvalidArgumentError(
+				vField+".created",
+				"cannot be earlier than the previous version")
+		case v.Creator == "":
+			return uuid.Nil, twirp.RequiredArgumentError(
+				vField + ".creator")
+		}
+
+		lastCreated = v.Created
+	}
+
+	lastStatus := make(map[string]ImportStatus)
+
+	for i, s := range doc.Statuses {
+		sField := fmt.Sprintf("%s.statuses.%d", field, i)
+		prev := lastStatus[s.Name]
+
+		switch {
+		case s.Name == "":
+			return uuid.Nil, twirp.RequiredArgumentError(sField + ".name")
+		case s.ID != prev.ID+1:
+			return uuid.Nil, twirp.InvalidArgumentError(sField+".id",
+				fmt.Sprintf("expected %q status %d", s.Name, prev.ID+1))
+		case s.Version < -1 || s.Version == 0:
+			return uuid.Nil, twirp.InvalidArgumentError(
+				sField+".version",
+				"must be -1 or a document version")
+		case s.Version > int64(len(doc.Versions)):
+			return uuid.Nil, twirp.InvalidArgumentError(
+				sField+".version",
+				"must refer to an imported version")
+		case s.Created.IsZero():
+			return uuid.Nil, twirp.RequiredArgumentError(
+				sField + ".created")
+		case s.Created.Before(prev.Created):
+			return uuid.Nil, twirp.InvalidArgumentError(
+				sField+".created",
+				"cannot be earlier than the previous status")
+		case s.Creator == "":
+			return uuid.Nil, twirp.RequiredArgumentError(
+				sField + ".creator")
+		}
+
+		lastStatus[s.Name] = s
+	}
+
+	return docUUID, nil
+}
+
+func (a *DocumentsService) importDocument(
+	ctx context.Context,
+	auth *elephantine.AuthInfo,
+	docUUID uuid.UUID,
+	doc *ImportDocument,
+	validateOnly bool,
+) (*ImportResult, error) {
+	result := ImportResult{
+		UUID: docUUID.String(),
+	}
+
+	err := a.accessCheck(ctx, auth, docUUID, WritePermission)
+	if err != nil && !elephantine.IsTwirpErrorCode(err, twirp.NotFound) {
+		return nil, err
+	}
+
+	var (
+		currentVersion int64
+		heads          map[string]StatusHead
+	)
+
+	meta, err := a.store.GetDocumentMeta(ctx, docUUID)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"load metadata for %s: %v", docUUID, err)
+	case meta.SystemLock != "":
+		result.Outcome = ImportOutcomeFailed
+		result.Error = fmt.Sprintf(
+			"the document is locked for %q", meta.SystemLock)
+
+		return &result, nil
+	default:
+		currentVersion = meta.CurrentVersion
+		heads = meta.Statuses
+	}
+
+	conflict, err := a.checkImportedHistory(ctx,
+		docUUID, doc, currentVersion, heads)
+	if err != nil {
+		return nil, err
+	}
+
+	if conflict != "" {
+		result.Outcome = ImportOutcomeConflict
+		result.Error = conflict
+
+		return &result, nil
+	}
+
+	versions := doc.Versions[min(currentVersion, int64(len(doc.Versions))):]
+
+	var statuses []ImportStatus
+
+	for _, s := range doc.Statuses {
+		if s.ID <= heads[s.Name].ID {
+			continue
+		}
+
+		statuses = append(statuses, s)
+	}
+
+	result.SkippedVersions = len(doc.Versions) - len(versions)
+	result.SkippedStatuses = len(doc.Statuses) - len(statuses)
+
+	if len(versions) == 0 && len(statuses) == 0 {
+		result.Outcome = ImportOutcomeUnchanged
+
+		return &result, nil
+	}
+
+	// Statuses are validated against the type of the latest imported
+	// version.
+	docType := doc.Versions[len(doc.Versions)-1].Document.Type
+
+	var updates []*UpdateRequest
+
+	for _, v := range versions {
+		up, problems, err := a.buildImportVersion(ctx, auth, docUUID, v)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Problems = append(result.Problems, problems...)
+
+		updates = append(updates, up)
+	}
+
+	for _, s := range statuses {
+		if !a.workflows.HasStatus(docType, s.Name) {
+			result.Problems = append(result.Problems, ImportProblem{
+				Status:   s.Name,
+				StatusID: s.ID,
+				Message: fmt.Sprintf("unknown status %q for %q",
+					s.Name, docType),
+			})
+		}
+	}
+
+	if len(result.Problems) > 0 {
+		result.Outcome = ImportOutcomeInvalid
+
+		return &result, nil
+	}
+
+	if validateOnly {
+		result.Outcome = ImportOutcomeValid
+		result.Versions = len(versions)
+		result.Statuses = len(statuses)
+
+		return &result, nil
+	}
+
+	// Write versions and statuses in the order that they were created,
+	// statuses are written after versions created at the same time so
+	// that they never refer to a version that hasn't been written yet.
+	for len(updates) > 0 || len(statuses) > 0 {
+		writeStatus := len(updates) == 0 ||
+			(len(statuses) > 0 && currentVersion > 0 &&
+				statuses[0].Created.Before(updates[0].Updated) &&
+				statuses[0].Version <= currentVersion)
+
+		var up *UpdateRequest
+
+		if writeStatus {
+			s := statuses[0]
+			statuses = statuses[1:]
+
+			// Fail instead of writing the status with another ID
+			// if someone else has set the status.
+			ifHead := s.ID - 1
+			if ifHead == 0 {
+				ifHead = -1
+			}
+
+			up = &UpdateRequest{
+				UUID:    docUUID,
+				Updated: s.Created,
+				Updater: s.Creator,
+				Status: []StatusUpdate{{
+					Name:    s.Name,
+					Version: s.Version,
+					Meta:    s.Meta,
+				}},
+				IfStatusHeads: map[string]int64{
+					s.Name: ifHead,
+				},
+			}
+		} else {
+			up = updates[0]
+			updates = updates[1:]
+		}
+
+		// The import is based on the state of the document when it
+		// started, fail if it has been changed since then.
+		up.IfMatch = currentVersion
+		if currentVersion == 0 {
+			up.IfMatch = -1
+		}
+
+		_, err := a.store.Update(ctx, a.workflows, []*UpdateRequest{up})
+		if IsDocStoreErrorCode(err, ErrCodeOptimisticLock) {
+			result.Outcome = ImportOutcomeConflict
+			result.Error = err.Error()
+
+			return &result, nil
+		} else if GetDocStoreErrorCode(err) != NoErrCode {
+			result.Outcome = ImportOutcomeFailed
+			result.Error = err.Error()
+
+			return &result, nil
+		} else if err != nil {
+			return nil, twirp.InternalErrorf(
+				"import %s: %v", docUUID, err)
+		}
+
+		if writeStatus {
+			result.Statuses++
+		} else {
+			result.Versions++
+			currentVersion++
+		}
+	}
+
+	result.Outcome = ImportOutcomeImported
+
+	return &result, nil
+}
+
+// checkImportedHistory compares the versions and statuses that already exist
+// in the repository with the imported history. Returns a description of the
+// first mismatch, if any.
+func (a *DocumentsService) checkImportedHistory(
+	ctx context.Context,
+	docUUID uuid.UUID, doc *ImportDocument,
+	currentVersion int64, heads map[string]StatusHead,
+) (string, error) {
+	for _, v := range doc.Versions {
+		if v.Version > currentVersion {
+			break
+		}
+
+		existing, err := a.store.GetVersion(ctx, docUUID, v.Version)
+		if err != nil {
+			return "", twirp.InternalErrorf(
+				"load version %d of %s: %v",
+				v.Version, docUUID, err)
+		}
+
+		if !sameImportOrigin(existing.Creator, existing.Created,
+			v.Creator, v.Created) {
+			return fmt.Sprintf(
+				"version %d was created by %q at %s, not by %q at %s",
+				v.Version,
+				existing.Creator, existing.Created.Format(time.RFC3339Nano),
+				v.Creator, v.Created.Format(time.RFC3339Nano),
+			), nil
+		}
+	}
+
+	for _, s := range doc.Statuses {
+		if s.ID > heads[s.Name].ID {
+			continue
+		}
+
+		existing, err := a.store.GetStatus(ctx, docUUID, s.Name, s.ID)
+		if err != nil {
+			return "", twirp.InternalErrorf(
+				"load %q status %d of %s: %v",
+				s.Name, s.ID, docUUID, err)
+		}
+
+		if !sameImportOrigin(existing.Creator, existing.Created,
+			s.Creator, s.Created) || existing.Version != s.Version {
+			return fmt.Sprintf(
+				"%q status %d doesn't match the imported status",
+				s.Name, s.ID,
+			), nil
+		}
+	}
+
+	return "", nil
+}
+
+// sameImportOrigin compares creators and timestamps at the precision that
+// timestamps are stored with.
+func sameImportOrigin(
+	aCreator string, aCreated time.Time,
+	bCreator string, bCreated time.Time,
+) bool {
+	return aCreator == bCreator && aCreated.Truncate(time.Microsecond).Equal(
+		bCreated.Truncate(time.Microsecond))
+}
+
+// buildImportVersion creates the update request for an imported version and
+// collects its validation problems.
+func (a *DocumentsService) buildImportVersion(
+	ctx context.Context,
+	auth *elephantine.AuthInfo,
+	docUUID uuid.UUID,
+	v ImportVersion,
+) (*UpdateRequest, []ImportProblem, error) {
+	var problems []ImportProblem
+
+	addProblem := func(format string, args ...any) {
+		problems = append(problems, ImportProblem{
+			Version: v.Version,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	doc := v.Document
+
+	if doc.UUID != "" && !strings.EqualFold(doc.UUID, docUUID.String()) {
+		addProblem("the document must have the same UUID as the import")
+	}
+
+	doc.UUID = docUUID.String()
+	doc.Language = strings.ToLower(doc.Language)
+
+	if doc.Language == "" {
+		doc.Language = a.defaultLanguage
+	}
+
+	_, err := langos.GetLanguage(doc.Language)
+	if err != nil {
+		addProblem("invalid language: %v", err)
+	}
+
+	if doc.URI == "" {
+		addProblem("the document has no URI")
+	}
+
+	for _, e := range v.ACL {
+		for _, p := range e.Permissions {
+			if !IsValidPermission(Permission(p)) {
+				addProblem("%q is not a valid permission", p)
+			}
+		}
+	}
+
+	up := UpdateRequest{
+		UUID:    docUUID,
+		Updated: v.Created,
+		Updater: v.Creator,
+		Meta:    v.Meta,
+	}
+
+	validationResult, err := a.setUpdateDocument(ctx, &up, doc)
+	if err != nil {
+		return nil, nil, twirp.InternalErrorf("%v", err)
+	}
+
+	for i := range validationResult {
+		addProblem("%s", validationResult[i].String())
+	}
+
+	if isMetaURI(up.Document.URI) {
+		mainDoc, err := parseMetaURI(up.Document.URI)
+		if err != nil {
+			addProblem("invalid meta document URI: %v", err)
+		}
+
+		up.MainDocument = &mainDoc
+
+		return &up, problems, nil
+	}
+
+	up.ACL = v.ACL
+	up.ACLTimeBounds = true
+
+	callerGrant := slices.ContainsFunc(up.ACL, func(e ACLEntry) bool {
+		return e.URI == auth.Claims.Subject
+	})
+
+	if v.Version == 1 && len(up.ACL) > 0 && !callerGrant {
+		up.ACL = append(slices.Clone(up.ACL), ACLEntry{
+			URI:         auth.Claims.Subject,
+			Permissions: []string{"r", "w"},
+		})
+	}
+
+	up.DefaultACL = updateDefaultACL(v.Creator, auth.Claims.Subject)
+
+	return &up, problems, nil
+}
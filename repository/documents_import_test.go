@@ -0,0 +1,189 @@
+package repository_test
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	rpcdoc "github.com/ttab/elephant-api/newsdoc"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationImport(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelError))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{})
+
+	claims := itest.Claims(t, "importer",
+		"doc_read doc_write doc_import")
+
+	client := tc.DocumentsClient(t, claims)
+	ext := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, claims)
+
+	docUUID := uuid.NewString()
+	doc := rpcdoc.DocumentFromRPC(
+		baseDocument(docUUID, "article://test/"+docUUID))
+
+	created := time.Date(2019, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	importDoc := repository.ImportDocument{
+		UUID: docUUID,
+		Versions: []repository.ImportVersion{
+			{
+				Version:  1,
+				Created:  created,
+				Creator:  "core://user/legacy-writer",
+				Document: doc,
+				ACL: []repository.ACLEntry{
+					{
+						URI:         "core://unit/legacy",
+						Permissions: []string{"r", "w"},
+					},
+				},
+			},
+			{
+				Version:  2,
+				Created:  created.Add(time.Hour),
+				Creator:  "core://user/legacy-editor",
+				Document: doc,
+			},
+		},
+		Statuses: []repository.ImportStatus{
+			{
+				Name:    "usable",
+				ID:      1,
+				Version: 2,
+				Created: created.Add(2 * time.Hour),
+				Creator: "core://user/legacy-editor",
+			},
+		},
+	}
+
+	err := tc.ExtensionClient(t, rpc.DocumentsPathPrefix,
+		itest.Claims(t, "writer", "doc_read doc_write"),
+	).Call(ctx, "Import", repository.ImportRequest{
+		Documents: []repository.ImportDocument{importDoc},
+	}, &repository.ImportResponse{})
+	test.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	gapped := importDoc
+	gapped.Versions = importDoc.Versions[1:]
+
+	err = ext.Call(ctx, "Import", repository.ImportRequest{
+		Documents: []repository.ImportDocument{gapped},
+	}, &repository.ImportResponse{})
+	test.IsTwirpError(t, err, twirp.InvalidArgument)
+
+	var dryRun repository.ImportResponse
+
+	err = ext.Call(ctx, "Import", repository.ImportRequest{
+		Documents:    []repository.ImportDocument{importDoc},
+		ValidateOnly: true,
+	}, &dryRun)
+	test.Must(t, err, "validate the import")
+
+	test.Equal(t, repository.ImportOutcomeValid,
+		dryRun.Documents[0].Outcome, "report the document as valid")
+
+	_, err = client.GetMeta(ctx, &rpc.GetMetaRequest{Uuid: docUUID})
+	test.IsTwirpError(t, err, twirp.NotFound)
+
+	var res repository.ImportResponse
+
+	err = ext.Call(ctx, "Import", repository.ImportRequest{
+		Documents: []repository.ImportDocument{importDoc},
+	}, &res)
+	test.Must(t, err, "import the document")
+
+	test.Equal(t, repository.ImportOutcomeImported,
+		res.Documents[0].Outcome, "import the document")
+	test.Equal(t, 2, res.Documents[0].Versions, "import both versions")
+	test.Equal(t, 1, res.Documents[0].Statuses, "import the status")
+
+	meta, err := client.GetMeta(ctx, &rpc.GetMetaRequest{Uuid: docUUID})
+	test.Must(t, err, "get document meta")
+
+	metaCreated, err := time.Parse(time.RFC3339, meta.Meta.Created)
+	test.Must(t, err, "parse document creation time")
+
+	test.Equal(t, true, created.Equal(metaCreated),
+		"keep the original creation time")
+	test.Equal(t, "core://user/legacy-writer", meta.Meta.CreatorUri,
+		"keep the original creator")
+	test.Equal(t, int64(2), meta.Meta.CurrentVersion,
+		"write both versions")
+	test.Equal(t, "core://user/legacy-editor",
+		meta.Meta.Heads["usable"].Creator,
+		"keep the original status creator")
+
+	var hasUnitGrant bool
+
+	for _, e := range meta.Meta.Acl {
+		if e.Uri == "core://unit/legacy" {
+			hasUnitGrant = true
+		}
+	}
+
+	test.Equal(t, true, hasUnitGrant, "import the ACL")
+
+	var rerun repository.ImportResponse
+
+	err = ext.Call(ctx, "Import", repository.ImportRequest{
+		Documents: []repository.ImportDocument{importDoc},
+	}, &rerun)
+	test.Must(t, err, "re-run the import")
+
+	test.Equal(t, repository.ImportOutcomeUnchanged,
+		rerun.Documents[0].Outcome, "leave the document unchanged")
+	test.Equal(t, 2, rerun.Documents[0].SkippedVersions,
+		"skip the imported versions")
+	test.Equal(t, 1, rerun.Documents[0].SkippedStatuses,
+		"skip the imported status")
+
+	conflicting := importDoc
+	conflicting.Versions = append([]repository.ImportVersion{},
+		importDoc.Versions...)
+	conflicting.Versions[1].Creator = "core://user/someone-else"
+
+	invalidUUID := uuid.NewString()
+	invalidDoc := doc
+	invalidDoc.Type = "core/no-such-type"
+
+	var mixed repository.ImportResponse
+
+	err = ext.Call(ctx, "Import", repository.ImportRequest{
+		Documents: []repository.ImportDocument{
+			conflicting,
+			{
+				UUID: invalidUUID,
+				Versions: []repository.ImportVersion{{
+					Version:  1,
+					Created:  created,
+					Creator:  "core://user/legacy-writer",
+					Document: invalidDoc,
+				}},
+			},
+		},
+	}, &mixed)
+	test.Must(t, err, "import conflicting and invalid documents")
+
+	test.Equal(t, repository.ImportOutcomeConflict,
+		mixed.Documents[0].Outcome, "report the conflict")
+	test.Equal(t, repository.ImportOutcomeInvalid,
+		mixed.Documents[1].Outcome, "report the invalid document")
+	test.Equal(t, true, len(mixed.Documents[1].Problems) > 0,
+		"report the validation problems")
+
+	_, err = client.GetMeta(ctx, &rpc.GetMetaRequest{Uuid: invalidUUID})
+	test.IsTwirpError(t, err, twirp.NotFound)
+}
@@ -0,0 +1,210 @@
+package repository_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationDuplicate(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{})
+
+	claims := itest.Claims(t, "editor",
+		"doc_read doc_write asset_upload")
+
+	client := tc.DocumentsClient(t, claims)
+	ext := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, claims)
+
+	data := "Hello World\n"
+
+	upID := duplicateTestUpload(t, client, "my.txt", data)
+
+	docUUID := uuid.NewString()
+	doc := baseDocument(docUUID, "article://test/"+docUUID)
+
+	_, err := client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: doc,
+		AttachObjects: map[string]string{
+			"plaintext": upID,
+		},
+		Acl: []*rpc.ACLEntry{
+			{Permissions: []string{"r", "w"}},
+			{Uri: "core://unit/test", Permissions: []string{"r"}},
+		},
+	})
+	test.Must(t, err, "create document")
+
+	doc.Title = "A changed title"
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: doc,
+		AttachObjects: map[string]string{
+			"later": duplicateTestUpload(t, client, "later.txt", "Later\n"),
+		},
+		Status: []*rpc.StatusUpdate{
+			{Name: "usable"},
+		},
+	})
+	test.Must(t, err, "update document")
+
+	var res repository.DuplicateResponse
+
+	err = ext.Call(ctx, "Duplicate", repository.DuplicateRequest{
+		UUID:    docUUID,
+		NewUUID: docUUID,
+	}, &res)
+	itest.IsTwirpError(t, err, twirp.InvalidArgument)
+
+	err = ext.Call(ctx, "Duplicate", repository.DuplicateRequest{
+		UUID:    docUUID,
+		Version: 1,
+		CopyACL: true,
+	}, &res)
+	test.Must(t, err, "duplicate the first version")
+
+	test.Equal(t, 1, res.Version, "create a new document")
+
+	dup, err := client.Get(ctx, &rpc.GetDocumentRequest{
+		Uuid: res.UUID,
+	})
+	test.Must(t, err, "get the duplicate")
+
+	test.Equal(t, "A bare-bones article", dup.Document.Title,
+		"copy the requested version")
+	test.Equal(t, "article://test/"+res.UUID, dup.Document.Uri,
+		"rewrite the URI")
+
+	history, err := client.GetHistory(ctx, &rpc.GetHistoryRequest{
+		Uuid: res.UUID,
+	})
+	test.Must(t, err, "get history of the duplicate")
+
+	test.Equal(t, docUUID, history.Versions[0].Meta["duplicated_from"],
+		"record the original in the version meta")
+	test.Equal(t, "1", history.Versions[0].Meta["duplicated_from_version"],
+		"record the original version in the version meta")
+
+	original, err := client.GetMeta(ctx, &rpc.GetMetaRequest{
+		Uuid: docUUID,
+	})
+	test.Must(t, err, "get metadata of the original")
+
+	test.Equal(t, 2, original.Meta.CurrentVersion,
+		"don't write a new version of the original")
+	test.Equal(t, "usable", original.Meta.WorkflowState,
+		"keep the workflow state of the original")
+
+	var acl repository.GetACLResponse
+
+	err = ext.Call(ctx, "GetACL", repository.GetACLRequest{
+		UUID: res.UUID,
+	}, &acl)
+	test.Must(t, err, "get ACL of the duplicate")
+
+	test.Equal(t, 2, len(acl.ACL), "copy the ACL")
+
+	attachments, err := client.GetAttachments(ctx,
+		&rpc.GetAttachmentsRequest{
+			Documents:      []string{res.UUID},
+			AttachmentName: "plaintext",
+			DownloadLink:   true,
+		})
+	test.Must(t, err, "get attachments of the duplicate")
+
+	test.Equal(t, 1, len(attachments.Attachments), "copy the attachment")
+
+	later, err := client.GetAttachments(ctx,
+		&rpc.GetAttachmentsRequest{
+			Documents:      []string{res.UUID},
+			AttachmentName: "later",
+		})
+	test.Must(t, err, "get later attachments of the duplicate")
+
+	test.Equal(t, 0, len(later.Attachments),
+		"only copy the attachments of the duplicated version")
+	test.Equal(t, "my.txt", attachments.Attachments[0].Filename,
+		"copy the attachment metadata")
+
+	downloadRes, err := http.Get(attachments.Attachments[0].DownloadLink)
+	test.Must(t, err, "download the copied attachment")
+
+	defer downloadRes.Body.Close()
+
+	downloaded, err := io.ReadAll(downloadRes.Body)
+	test.Must(t, err, "read the copied attachment")
+
+	test.Equal(t, data, string(downloaded), "copy the attachment data")
+
+	countUploads := func() int64 {
+		var n int64
+
+		err := tc.DB.QueryRow(ctx, `SELECT COUNT(*) FROM upload`).Scan(&n)
+		test.Must(t, err, "count uploads")
+
+		return n
+	}
+
+	uploadsBefore := countUploads()
+
+	// Duplicating to a UUID that's taken fails when the duplicate is
+	// written, after the attachments have been copied.
+	err = ext.Call(ctx, "Duplicate", repository.DuplicateRequest{
+		UUID:    docUUID,
+		Version: 1,
+		NewUUID: res.UUID,
+	}, &repository.DuplicateResponse{})
+	itest.IsTwirpError(t, err, twirp.FailedPrecondition)
+
+	test.Equal(t, uploadsBefore, countUploads(),
+		"discard the copied attachments of a failed duplication")
+}
+
+func duplicateTestUpload(
+	t *testing.T, client rpc.Documents, name string, data string,
+) string {
+	t.Helper()
+
+	ctx := t.Context()
+
+	up, err := client.CreateUpload(ctx, &rpc.CreateUploadRequest{
+		Name:        name,
+		ContentType: "text/plain",
+	})
+	test.Must(t, err, "create upload")
+
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodPut, up.Url, strings.NewReader(data))
+	test.Must(t, err, "create upload request")
+
+	req.ContentLength = int64(len(data))
+
+	uploadRes, err := http.DefaultClient.Do(req)
+	test.Must(t, err, "make upload request")
+
+	_ = uploadRes.Body.Close()
+
+	if uploadRes.StatusCode != http.StatusOK {
+		t.Fatalf("error response from upload recipient: %s",
+			uploadRes.Status)
+	}
+
+	return up.Id
+}
@@ -0,0 +1,519 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/pg"
+	"github.com/ttab/newsdoc"
+	"github.com/ttab/revisor"
+)
+
+const (
+	// collectedExemplarPrefix is the name prefix of exemplars that have
+	// been collected from stored documents.
+	collectedExemplarPrefix = "collected://"
+
+	// MaxExemplarSampleSize is the maximum number of exemplars that are
+	// collected for a document type.
+	MaxExemplarSampleSize = 20
+)
+
+// collectedExemplarNamespace is used to derive the UUIDs of collected
+// exemplars from the UUIDs of the sampled documents.
+var collectedExemplarNamespace = uuid.MustParse(
+	"4ac6a8e4-8fb2-4a7b-9c73-5c3f0b55f2a1")
+
+// ExemplarSampling configures the collection of exemplars from the most
+// recently updated documents of a type.
+type ExemplarSampling struct {
+	SampleSize int               `json:"sample_size"`
+	Anonymise  []AnonymisedField `json:"anonymise,omitempty"`
+}
+
+// AnonymisedField is a document or block attribute that is scrambled in
+// collected exemplars. Without a block selector the field is a document
+// attribute, "title", "uri", or "url". Block data values are addressed as
+// "data.[key]".
+type AnonymisedField struct {
+	Block *BlockSelector `json:"block,omitempty"`
+	Key   string         `json:"key"`
+}
+
+// Validate checks that the sampling configuration is well-formed.
+func (es ExemplarSampling) Validate() error {
+	if es.SampleSize < 1 || es.SampleSize > MaxExemplarSampleSize {
+		return fmt.Errorf("sample size must be between 1 and %d",
+			MaxExemplarSampleSize)
+	}
+
+	for i, f := range es.Anonymise {
+		err := f.validate()
+		if err != nil {
+			return fmt.Errorf("anonymised field %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (f AnonymisedField) validate() error {
+	if f.Block == nil {
+		_, ok := documentAttribute(&newsdoc.Document{}, f.Key)
+		if !ok {
+			return fmt.Errorf("invalid document attribute %q", f.Key)
+		}
+
+		return nil
+	}
+
+	if !validBlockKind(f.Block.Kind) {
+		return fmt.Errorf("invalid block kind %q", f.Block.Kind)
+	}
+
+	if f.Block.Type == "" {
+		return errors.New("missing block type")
+	}
+
+	_, ok := blockAttribute(&newsdoc.Block{}, f.Key)
+	if !ok && !strings.HasPrefix(f.Key, "data.") {
+		return fmt.Errorf("invalid attribute %q", f.Key)
+	}
+
+	return nil
+}
+
+func documentAttribute(doc *newsdoc.Document, name string) (*string, bool) {
+	switch name {
+	case "title":
+		return &doc.Title, true
+	case "uri":
+		return &doc.URI, true
+	case "url":
+		return &doc.URL, true
+	}
+
+	return nil, false
+}
+
+func exemplarSamplingToDB(es ExemplarSampling) *postgres.TypeExemplarSampling {
+	c := postgres.TypeExemplarSampling{
+		SampleSize: es.SampleSize,
+		Anonymise:  make([]postgres.TypeAnonymisedField, len(es.Anonymise)),
+	}
+
+	for i, f := range es.Anonymise {
+		field := postgres.TypeAnonymisedField{
+			Key: f.Key,
+		}
+
+		if f.Block != nil {
+			field.Kind = string(f.Block.Kind)
+			field.Type = f.Block.Type
+			field.Rel = f.Block.Rel
+			field.Role = f.Block.Role
+		}
+
+		c.Anonymise[i] = field
+	}
+
+	return &c
+}
+
+func exemplarSamplingFromDB(es postgres.TypeExemplarSampling) *ExemplarSampling {
+	c := ExemplarSampling{
+		SampleSize: es.SampleSize,
+		Anonymise:  make([]AnonymisedField, len(es.Anonymise)),
+	}
+
+	for i, f := range es.Anonymise {
+		field := AnonymisedField{
+			Key: f.Key,
+		}
+
+		if f.Kind != "" {
+			field.Block = &BlockSelector{
+				Kind: revisor.BlockKind(f.Kind),
+				Type: f.Type,
+				Rel:  f.Rel,
+				Role: f.Role,
+			}
+		}
+
+		c.Anonymise[i] = field
+	}
+
+	return &c
+}
+
+// anonymiseDocument scrambles the configured fields of a document and gives
+// it a UUID derived from the original one. The blocks of the given document
+// are never modified.
+func anonymiseDocument(
+	doc newsdoc.Document, fields []AnonymisedField,
+) newsdoc.Document {
+	anonUUID := uuid.NewSHA1(collectedExemplarNamespace, []byte(doc.UUID)).String()
+
+	doc.URI = strings.ReplaceAll(doc.URI, doc.UUID, anonUUID)
+	doc.URL = strings.ReplaceAll(doc.URL, doc.UUID, anonUUID)
+	doc.UUID = anonUUID
+
+	for _, f := range fields {
+		if f.Block == nil {
+			attr, _ := documentAttribute(&doc, f.Key)
+			if attr != nil {
+				*attr = scrambleText(*attr)
+			}
+
+			continue
+		}
+
+		blocks := slices.Clone(documentBlocks(doc, f.Block.Kind))
+
+		for i := range blocks {
+			if !f.Block.matches(blocks[i]) {
+				continue
+			}
+
+			blocks[i] = anonymiseBlock(blocks[i], f.Key)
+		}
+
+		doc = withDocumentBlocks(doc, f.Block.Kind, blocks)
+	}
+
+	return doc
+}
+
+func anonymiseBlock(b newsdoc.Block, key string) newsdoc.Block {
+	if dataKey, ok := strings.CutPrefix(key, "data."); ok {
+		v, ok := b.Data[dataKey]
+		if !ok {
+			return b
+		}
+
+		data := maps.Clone(b.Data)
+
+		data[dataKey] = scrambleText(v)
+		b.Data = data
+
+		return b
+	}
+
+	attr, ok := blockAttribute(&b, key)
+	if ok {
+		*attr = scrambleText(*attr)
+	}
+
+	return b
+}
+
+// scrambleText replaces letters with "x" and digits with "0", preserving
+// case, length, punctuation, and any HTML tags, so that the scrambled value
+// keeps the shape of the original.
+func scrambleText(s string) string {
+	var (
+		b     strings.Builder
+		inTag bool
+	)
+
+	b.Grow(len(s))
+
+	for _, r := range s {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case inTag:
+		case unicode.IsUpper(r):
+			r = 'X'
+		case unicode.IsLetter(r):
+			r = 'x'
+		case unicode.IsDigit(r):
+			r = '0'
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// exemplarVersionHash returns the version hash of a canonicalised exemplar
+// document.
+func exemplarVersionHash(canonical []byte) string {
+	h := sha256.Sum256(canonical)
+
+	return "sha256:" + hex.EncodeToString(h[:])
+}
+
+// RunExemplarCollection periodically samples the most recently updated
+// documents of the types that have exemplar sampling configured, and
+// registers them as collected exemplars of the active schema generation.
+func (s *PGDocStore) RunExemplarCollection(
+	ctx context.Context, period time.Duration,
+) {
+	for {
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return
+		}
+
+		jobLock, err := pg.NewJobLock(s.pool, s.logger, "exemplar-collection", pg.JobLockOptions{
+			PingInterval:  10 * time.Second,
+			StaleAfter:    1 * time.Minute,
+			CheckInterval: 20 * time.Second,
+			Timeout:       5 * time.Second,
+		})
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to create job lock",
+				elephantine.LogKeyError, err)
+
+			continue
+		}
+
+		err = jobLock.RunWithContext(ctx, s.collectExemplars)
+		if err != nil {
+			s.logger.ErrorContext(
+				ctx, "exemplar collection error",
+				elephantine.LogKeyError, err,
+			)
+		}
+	}
+}
+
+func (s *PGDocStore) collectExemplars(ctx context.Context) error {
+	gen, err := s.reader.GetActiveSchemaGeneration(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get active generation: %w", err)
+	}
+
+	val, err := s.generationValidator(ctx, gen.ID)
+	if err != nil {
+		return err
+	}
+
+	confs, err := s.GetTypeConfigurations(ctx)
+	if err != nil {
+		return fmt.Errorf("get type configurations: %w", err)
+	}
+
+	var exemplars []ExemplarInput
+
+	for _, docType := range slices.Sorted(maps.Keys(confs)) {
+		sampling := confs[docType].ExemplarSampling
+		if sampling == nil || sampling.SampleSize == 0 {
+			continue
+		}
+
+		collected, err := s.sampleExemplars(ctx, val, docType, *sampling)
+		if err != nil {
+			return fmt.Errorf("sample %q documents: %w", docType, err)
+		}
+
+		exemplars = append(exemplars, collected...)
+	}
+
+	return pg.WithTX(ctx, s.pool, func(tx pgx.Tx) error {
+		q := postgres.New(tx)
+
+		err := q.DropCollectedExemplars(ctx, gen.ID)
+		if err != nil {
+			return fmt.Errorf("drop current exemplars: %w", err)
+		}
+
+		for _, ex := range exemplars {
+			err = q.UpsertSchemaExemplar(ctx, postgres.UpsertSchemaExemplarParams{
+				Name:     ex.Name,
+				Version:  ex.Version,
+				DocType:  ex.DocType,
+				Document: ex.Document,
+			})
+			if err != nil {
+				return fmt.Errorf("upsert exemplar %q: %w",
+					ex.Name, err)
+			}
+
+			err = q.InsertCollectedExemplar(ctx, postgres.InsertCollectedExemplarParams{
+				GenerationID: gen.ID,
+				Name:         ex.Name,
+				Version:      ex.Version,
+			})
+			if err != nil {
+				return fmt.Errorf("insert generation exemplar %q: %w",
+					ex.Name, err)
+			}
+		}
+
+		err = q.DeleteUnreferencedCollectedExemplars(ctx)
+		if err != nil {
+			return fmt.Errorf("delete unreferenced exemplars: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// sampleExemplars reads the most recently updated documents of a type and
+// returns the anonymised documents that are valid against the active
+// generation.
+func (s *PGDocStore) sampleExemplars(
+	ctx context.Context, val *revisor.Validator,
+	docType string, sampling ExemplarSampling,
+) ([]ExemplarInput, error) {
+	rows, err := s.reader.GetRecentDocumentsOfType(ctx,
+		postgres.GetRecentDocumentsOfTypeParams{
+			Type:     docType,
+			RowLimit: int64(sampling.SampleSize),
+		})
+	if err != nil {
+		return nil, fmt.Errorf("read documents: %w", err)
+	}
+
+	var exemplars []ExemplarInput
+
+	for _, row := range rows {
+		var doc newsdoc.Document
+
+		err := json.Unmarshal(row.DocumentData, &doc)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal document %s: %w",
+				row.UUID, err)
+		}
+
+		doc = anonymiseDocument(doc, sampling.Anonymise)
+
+		results, err := val.ValidateDocument(ctx, &doc)
+		if err != nil {
+			return nil, fmt.Errorf("validate document %s: %w",
+				row.UUID, err)
+		}
+
+		// Documents that already are invalid would make any new
+		// generation fail the activation check.
+		if len(results) > 0 {
+			s.logger.DebugContext(ctx,
+				"skipping invalid document as exemplar",
+				elephantine.LogKeyDocumentUUID, row.UUID,
+				elephantine.LogKeyError, results[0].String())
+
+			continue
+		}
+
+		canonical, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("canonicalise document %s: %w",
+				row.UUID, err)
+		}
+
+		exemplars = append(exemplars, ExemplarInput{
+			Name:     collectedExemplarPrefix + docType + "/" + doc.UUID,
+			DocType:  docType,
+			Document: canonical,
+			Version:  exemplarVersionHash(canonical),
+		})
+	}
+
+	return exemplars, nil
+}
+
+// checkGenerationExemplars validates the exemplars of a generation, and the
+// exemplars that have been collected for the active generation, against the
+// schemas of the generation before it's activated. Generations that are, or
+// have been, active are exempt so that rollbacks aren't blocked.
+func (s *PGDocStore) checkGenerationExemplars(
+	ctx context.Context, q *postgres.Queries, gen postgres.SchemaGeneration,
+) error {
+	if gen.Status == postgres.SchemaGenerationStatusActive || gen.Activated.Valid {
+		return nil
+	}
+
+	exemplars, err := q.GetSchemaGenerationExemplars(ctx, gen.ID)
+	if err != nil {
+		return fmt.Errorf("get exemplars: %w", err)
+	}
+
+	active, err := q.GetActiveSchemaGeneration(ctx)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return fmt.Errorf("get active generation: %w", err)
+	default:
+		collected, err := q.GetCollectedExemplars(ctx, active.ID)
+		if err != nil {
+			return fmt.Errorf("get collected exemplars: %w", err)
+		}
+
+		for _, ex := range collected {
+			known := slices.ContainsFunc(exemplars,
+				func(e postgres.SchemaExemplar) bool {
+					return e.Name == ex.Name
+				})
+			if !known {
+				exemplars = append(exemplars, ex)
+			}
+		}
+	}
+
+	if len(exemplars) == 0 {
+		return nil
+	}
+
+	// Read the schemas in the transaction, the generation might not have
+	// been committed yet.
+	schemas, err := generationSchemas(ctx, q, gen.ID)
+	if err != nil {
+		return err
+	}
+
+	val, err := s.schemaValidator(ctx, schemas)
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+
+	for _, ex := range exemplars {
+		var doc newsdoc.Document
+
+		err := json.Unmarshal(ex.Document, &doc)
+		if err != nil {
+			return fmt.Errorf("unmarshal exemplar %q: %w", ex.Name, err)
+		}
+
+		results, err := val.ValidateDocument(ctx, &doc)
+		if err != nil {
+			return fmt.Errorf("validate exemplar %q: %w", ex.Name, err)
+		}
+
+		if len(results) > 0 {
+			failures = append(failures, fmt.Sprintf("%s: %s",
+				ex.Name, results[0].String()))
+		}
+	}
+
+	if len(failures) > 0 {
+		return DocStoreErrorf(ErrCodeFailedPrecondition,
+			"%d exemplars fail validation against generation %d, activate with force to override: %s",
+			len(failures), gen.ID, strings.Join(failures, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package repository_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/ttab/revisor"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationExemplarCollection(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+	ctx := t.Context()
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		RunRevalidation:    true,
+		RunExemplarCollect: true,
+	})
+
+	adminClaims := itest.Claims(t, "admin", "schema_admin")
+
+	schemas := tc.SchemasClient(t, adminClaims)
+	schemaExt := tc.ExtensionClient(t, rpc.SchemasPathPrefix, adminClaims)
+
+	editor := tc.DocumentsClient(t,
+		itest.Claims(t, "editor", "doc_read doc_write"))
+
+	docUUID := uuid.NewString()
+
+	_, err := editor.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: baseDocument(docUUID, "article://test/"+docUUID),
+	})
+	test.Must(t, err, "create document")
+
+	err = schemaExt.Call(ctx, "SetTypeExemplarSampling",
+		repository.SetTypeExemplarSamplingRequest{
+			Type: "core/article",
+			Sampling: &repository.ExemplarSampling{
+				SampleSize: 100,
+			},
+		}, &repository.SetTypeExemplarSamplingResponse{})
+	itest.IsTwirpError(t, err, twirp.InvalidArgument)
+
+	err = schemaExt.Call(ctx, "SetTypeExemplarSampling",
+		repository.SetTypeExemplarSamplingRequest{
+			Type: "core/article",
+			Sampling: &repository.ExemplarSampling{
+				SampleSize: 5,
+				Anonymise: []repository.AnonymisedField{
+					{Key: "title"},
+				},
+			},
+		}, &repository.SetTypeExemplarSamplingResponse{})
+	test.Must(t, err, "configure exemplar sampling")
+
+	active, err := schemas.GetAllActive(ctx, &rpc.GetAllActiveSchemasRequest{})
+	test.Must(t, err, "get active schemas")
+
+	var collected *rpc.Exemplar
+
+	deadline := time.Now().Add(10 * time.Second)
+
+	for collected == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for exemplars to be collected")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		res, err := schemas.GetExemplars(ctx, &rpc.GetExemplarsRequest{
+			GenerationId: active.GenerationId,
+		})
+		test.Must(t, err, "get exemplars")
+
+		for _, ex := range res.Exemplars {
+			if strings.HasPrefix(ex.Name, "collected://core/article/") {
+				collected = ex
+			}
+		}
+	}
+
+	test.Equal(t, "X xxxx-xxxxx xxxxxxx", collected.Document.Title,
+		"anonymise the title")
+
+	if collected.Document.Uuid == docUUID ||
+		strings.Contains(collected.Document.Uri, docUUID) {
+		t.Fatal("expected the exemplar to not have the document UUID")
+	}
+
+	// Require a meta block that the collected exemplar doesn't have.
+	one := 1
+	articleType := "core/article"
+
+	spec := revisor.ConstraintSet{
+		Version: 1,
+		Name:    "test_strict",
+		Documents: []revisor.DocumentConstraint{
+			{
+				Match: revisor.MakeConstraintMap(
+					map[string]revisor.StringConstraint{
+						"type": {Const: &articleType},
+					}),
+				Meta: []*revisor.BlockConstraint{
+					{
+						Declares: &revisor.BlockSignature{
+							Type: "test/required",
+						},
+						Count: &one,
+					},
+				},
+			},
+		},
+	}
+
+	specPayload, err := json.Marshal(&spec)
+	test.Must(t, err, "marshal strict schema")
+
+	gen, err := schemas.RegisterGeneration(ctx, &rpc.RegisterGenerationRequest{
+		Activation: rpc.SchemaActivation_ACTIVATION_PENDING,
+		Schemas: append(active.Schemas, &rpc.Schema{
+			Name:    "test/strict",
+			Version: "v1.0.0",
+			Spec:    string(specPayload),
+		}),
+	})
+	test.Must(t, err, "register pending generation")
+
+	err = schemaExt.Call(ctx, "StartRevalidation",
+		repository.StartRevalidationRequest{
+			GenerationID: gen.GenerationId,
+		}, &repository.StartRevalidationResponse{})
+	test.Must(t, err, "start revalidation")
+
+	var status repository.GetRevalidationResponse
+
+	for status.Revalidation == nil ||
+		status.Revalidation.Status == repository.SchemaJobRunning {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the revalidation to finish")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		err = schemaExt.Call(ctx, "GetRevalidation",
+			repository.GetRevalidationRequest{
+				GenerationID: gen.GenerationId,
+			}, &status)
+		test.Must(t, err, "get revalidation status")
+	}
+
+	_, err = schemas.SetActive(ctx, &rpc.SetActiveSchemasRequest{
+		GenerationId: gen.GenerationId,
+		Activation:   rpc.SchemaActivation_ACTIVATION_ACTIVE,
+	})
+	itest.IsTwirpError(t, err, twirp.FailedPrecondition)
+
+	err = schemaExt.Call(ctx, "ActivateGeneration",
+		repository.ActivateGenerationRequest{
+			GenerationID: gen.GenerationId,
+		}, &repository.ActivateGenerationResponse{})
+	itest.IsTwirpError(t, err, twirp.FailedPrecondition)
+
+	err = schemaExt.Call(ctx, "ActivateGeneration",
+		repository.ActivateGenerationRequest{
+			GenerationID: gen.GenerationId,
+			Force:        true,
+		}, &repository.ActivateGenerationResponse{})
+	test.Must(t, err, "force activation of the generation")
+
+	res, err := schemas.GetExemplars(ctx, &rpc.GetExemplarsRequest{
+		GenerationId: gen.GenerationId,
+	})
+	test.Must(t, err, "get exemplars of the new generation")
+
+	test.Equal(t, 1, len(res.Exemplars),
+		"carry over the collected exemplars")
+	test.Equal(t, collected.Name, res.Exemplars[0].Name,
+		"carry over the collected exemplar")
+}
@@ -0,0 +1,145 @@
+package repository_test
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationExplainPermission(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+	ctx := t.Context()
+
+	tc := testingAPIServer(t, logger, testingServerOptions{})
+
+	const (
+		deskUnit = "unit://test/desk"
+		reader   = "user://test/reader"
+	)
+
+	editor := tc.DocumentsClient(t,
+		itest.Claims(t, "editor", "doc_read doc_write"))
+	admin := tc.ExtensionClient(t, rpc.DocumentsPathPrefix,
+		itest.Claims(t, "support", "doc_admin"))
+	readerExt := tc.ExtensionClient(t, rpc.DocumentsPathPrefix,
+		itest.Claims(t, "reader", "doc_read", deskUnit))
+
+	docUUID := uuid.NewString()
+
+	_, err := editor.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: baseDocument(docUUID, "article://test/explain"),
+		Acl: []*rpc.ACLEntry{
+			{
+				Uri:         deskUnit,
+				Permissions: []string{"r"},
+			},
+		},
+	})
+	test.Must(t, err, "create document")
+
+	explain := func(
+		t *testing.T, scopes []string, permission string,
+	) repository.ExplainPermissionResponse {
+		t.Helper()
+
+		var res repository.ExplainPermissionResponse
+
+		err := admin.Call(ctx, "ExplainPermission",
+			repository.ExplainPermissionRequest{
+				UUID:       docUUID,
+				Permission: permission,
+				Subject:    reader,
+				Units:      []string{deskUnit},
+				Scopes:     scopes,
+			}, &res)
+		test.Must(t, err, "explain permission")
+
+		return res
+	}
+
+	read := explain(t, []string{"doc_read"}, "r")
+
+	if !read.Allowed || read.Decision != repository.PermissionDecisionACL {
+		t.Fatalf("expected read to be granted through the ACL, got: %+v", read)
+	}
+
+	if len(read.ACL) != 1 || read.ACL[0].URI != deskUnit ||
+		read.ACL[0].Source != repository.ACLGrantDocument ||
+		!read.ACL[0].Grants {
+		t.Fatalf("expected the desk unit entry to be reported, got: %+v", read.ACL)
+	}
+
+	write := explain(t, []string{"doc_read"}, "w")
+
+	if write.Allowed || write.Decision != repository.PermissionDecisionMissingScope {
+		t.Fatalf("expected write to be denied for lack of scope, got: %+v", write)
+	}
+
+	write = explain(t, []string{"doc_read", "doc_write"}, "w")
+
+	if write.Allowed || write.Decision != repository.PermissionDecisionNoGrant {
+		t.Fatalf("expected write to be denied for lack of grant, got: %+v", write)
+	}
+
+	readAll := explain(t, []string{"doc_read_all"}, "r")
+
+	if !readAll.Allowed || readAll.Decision != repository.PermissionDecisionScope {
+		t.Fatalf("expected read to be granted by scope, got: %+v", readAll)
+	}
+
+	err = readerExt.Call(ctx, "ExplainPermission",
+		repository.ExplainPermissionRequest{
+			UUID:       docUUID,
+			Permission: "r",
+			Subject:    "user://test/editor",
+		}, &repository.ExplainPermissionResponse{})
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	err = tc.ExtensionClient(t, rpc.DocumentsPathPrefix,
+		itest.Claims(t, "outsider", "doc_read"),
+	).Call(ctx, "ExplainPermission",
+		repository.ExplainPermissionRequest{
+			UUID:       docUUID,
+			Permission: "r",
+		}, &repository.ExplainPermissionResponse{})
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	var missing repository.ExplainPermissionResponse
+
+	err = tc.ExtensionClient(t, rpc.DocumentsPathPrefix,
+		itest.Claims(t, "outsider", "doc_read"),
+	).Call(ctx, "ExplainPermission",
+		repository.ExplainPermissionRequest{
+			UUID:       uuid.New().String(),
+			Permission: "r",
+		}, &missing)
+	test.Must(t, err, "explain permission for a missing document")
+
+	if missing.Allowed || missing.Decision != repository.PermissionDecisionNoDocument {
+		t.Fatalf("expected missing document decision, got: %+v", missing)
+	}
+
+	var own repository.ExplainPermissionResponse
+
+	err = readerExt.Call(ctx, "ExplainPermission",
+		repository.ExplainPermissionRequest{
+			UUID:       docUUID,
+			Permission: "r",
+		}, &own)
+	test.Must(t, err, "explain own permission")
+
+	if !own.Allowed || own.Subject != reader {
+		t.Fatalf("expected own read permission to be granted, got: %+v", own)
+	}
+}
@@ -0,0 +1,179 @@
+package repository_test
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationMultipartUpload(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{})
+
+	claims := itest.Claims(t, "uploader",
+		"doc_read doc_write asset_upload")
+
+	client := tc.DocumentsClient(t, claims)
+	ext := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, claims)
+	other := tc.ExtensionClient(t, rpc.DocumentsPathPrefix,
+		itest.Claims(t, "other", "asset_upload"))
+
+	var created repository.CreateMultipartUploadResponse
+
+	err := ext.Call(ctx, "CreateMultipartUpload",
+		repository.CreateMultipartUploadRequest{
+			Name:        "large.bin",
+			ContentType: "application/octet-stream",
+		}, &created)
+	test.Must(t, err, "create multipart upload")
+
+	err = other.Call(ctx, "ListUploadParts",
+		repository.ListUploadPartsRequest{
+			ID: created.ID,
+		}, &repository.ListUploadPartsResponse{})
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	var urls repository.GetUploadPartURLsResponse
+
+	err = ext.Call(ctx, "GetUploadPartURLs",
+		repository.GetUploadPartURLsRequest{
+			ID:    created.ID,
+			Parts: []int32{1, 2},
+		}, &urls)
+	test.Must(t, err, "get upload part URLs")
+
+	test.Equal(t, 2, len(urls.Parts), "get one URL per part")
+
+	// All parts but the last must be at least 5MiB.
+	parts := [][]byte{
+		bytes.Repeat([]byte("a"), 5*1024*1024),
+		[]byte("the last part"),
+	}
+
+	uploadPart := func(url string, data []byte) {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(ctx,
+			http.MethodPut, url, bytes.NewReader(data))
+		test.Must(t, err, "create part upload request")
+
+		req.ContentLength = int64(len(data))
+
+		res, err := http.DefaultClient.Do(req)
+		test.Must(t, err, "upload part")
+
+		_ = res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("error response from part upload: %s",
+				res.Status)
+		}
+	}
+
+	uploadPart(urls.Parts[0].URL, parts[0])
+
+	var listed repository.ListUploadPartsResponse
+
+	err = ext.Call(ctx, "ListUploadParts",
+		repository.ListUploadPartsRequest{
+			ID: created.ID,
+		}, &listed)
+	test.Must(t, err, "list uploaded parts")
+
+	test.Equal(t, 1, len(listed.Parts), "list the uploaded part")
+	test.Equal(t, int64(len(parts[0])), listed.Parts[0].Size,
+		"get the size of the uploaded part")
+
+	docUUID := uuid.NewString()
+	doc := baseDocument(docUUID, "article://test/"+docUUID)
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: doc,
+		AttachObjects: map[string]string{
+			"binary": created.ID,
+		},
+	})
+	itest.IsTwirpError(t, err, twirp.InvalidArgument)
+
+	uploadPart(urls.Parts[1].URL, parts[1])
+
+	err = ext.Call(ctx, "CompleteMultipartUpload",
+		repository.CompleteMultipartUploadRequest{
+			ID: created.ID,
+		}, &repository.CompleteMultipartUploadResponse{})
+	test.Must(t, err, "complete multipart upload")
+
+	err = ext.Call(ctx, "AbortMultipartUpload",
+		repository.AbortMultipartUploadRequest{
+			ID: created.ID,
+		}, &repository.AbortMultipartUploadResponse{})
+	itest.IsTwirpError(t, err, twirp.FailedPrecondition)
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: doc,
+		AttachObjects: map[string]string{
+			"binary": created.ID,
+		},
+	})
+	test.Must(t, err, "attach the completed upload")
+
+	attachments, err := client.GetAttachments(ctx,
+		&rpc.GetAttachmentsRequest{
+			Documents:      []string{docUUID},
+			AttachmentName: "binary",
+			DownloadLink:   true,
+		})
+	test.Must(t, err, "get attachments")
+
+	test.Equal(t, 1, len(attachments.Attachments), "get the attachment")
+
+	res, err := http.Get(attachments.Attachments[0].DownloadLink)
+	test.Must(t, err, "download the attachment")
+
+	defer res.Body.Close()
+
+	downloaded, err := io.ReadAll(res.Body)
+	test.Must(t, err, "read the attachment")
+
+	test.Equal(t, len(parts[0])+len(parts[1]), len(downloaded),
+		"assemble the parts")
+
+	var aborted repository.CreateMultipartUploadResponse
+
+	err = ext.Call(ctx, "CreateMultipartUpload",
+		repository.CreateMultipartUploadRequest{
+			Name:        "aborted.bin",
+			ContentType: "application/octet-stream",
+		}, &aborted)
+	test.Must(t, err, "create a second multipart upload")
+
+	err = ext.Call(ctx, "AbortMultipartUpload",
+		repository.AbortMultipartUploadRequest{
+			ID: aborted.ID,
+		}, &repository.AbortMultipartUploadResponse{})
+	test.Must(t, err, "abort multipart upload")
+
+	err = ext.Call(ctx, "GetUploadPartURLs",
+		repository.GetUploadPartURLsRequest{
+			ID:    aborted.ID,
+			Parts: []int32{1},
+		}, &urls)
+	itest.IsTwirpError(t, err, twirp.FailedPrecondition)
+}
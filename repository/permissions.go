@@ -55,8 +55,10 @@ const (
 	ScopeDocumentWrite        = "doc_write"
 	ScopeMetaDocumentWriteAll = "meta_doc_write_all"
 	ScopeDocumentImport       = "doc_import"
+	ScopeDocumentExport       = "doc_export"
 	ScopeAssetUpload          = "asset_upload"
 	ScopeEventlogRead         = "eventlog_read"
+	ScopeReadAudit            = "read_audit"
 	ScopeMetricsAdmin         = "metrics_admin"
 	ScopeMetricsWrite         = "metrics_write"
 	ScopeMetricsRead          = "metrics_read"
@@ -51,6 +51,13 @@ type PGDocStoreOptions struct {
 	// consumers that still depend on the standalone event; expected to be
 	// removed in a future release.
 	EmitACLEvent bool
+	// AttachmentProcessors are run for objects that are attached to
+	// documents, see DefaultAttachmentProcessors().
+	AttachmentProcessors []AttachmentProcessor
+	// MaxProcessedAttachmentSize is the size limit for attachments that
+	// are passed to the attachment processors, larger objects are attached
+	// without processing. Defaults to DefaultMaxProcessedAttachmentSize.
+	MaxProcessedAttachmentSize int64
 }
 
 func NewPGDocStore(
@@ -63,6 +70,10 @@ func NewPGDocStore(
 		options.DeleteTimeout = 5 * time.Second
 	}
 
+	if options.MaxProcessedAttachmentSize == 0 {
+		options.MaxProcessedAttachmentSize = DefaultMaxProcessedAttachmentSize
+	}
+
 	if options.DefaultTZ == nil {
 		logger.Warn("running pgstore with fallback to UTC as default timezone")
 
@@ -519,6 +530,8 @@ func (s *PGDocStore) insertDeleteRecord(
 		aclEntries[i] = postgres.ACLEntry{
 			URI:         acls[i].URI,
 			Permissions: acls[i].Permissions,
+			NotBefore:   acls[i].NotBefore,
+			Expires:     acls[i].Expires,
 		}
 	}
 
@@ -1009,6 +1022,8 @@ func eventlogRowToEvent(r postgres.GetEventlogRow) (Event, error) {
 		e.Labels = extra.Labels
 		e.AttachedObjects = extra.AttachedObjects
 		e.DetachedObjects = extra.DetachedObjects
+		e.AttachedObjectMeta = assetMetadataMapFromPG(
+			extra.AttachedObjectMeta)
 		e.DeleteRecordID = extra.DeleteRecordID
 		e.SchemaGeneration = extra.SchemaGeneration
 	}
@@ -1073,6 +1088,8 @@ func (s *PGDocStore) GetCompactedEventlog(
 		if extra != nil {
 			e.AttachedObjects = extra.AttachedObjects
 			e.DetachedObjects = extra.DetachedObjects
+			e.AttachedObjectMeta = assetMetadataMapFromPG(
+				extra.AttachedObjectMeta)
 			e.DeleteRecordID = extra.DeleteRecordID
 			e.Timespans = extra.Timespans
 			e.Labels = extra.Labels
@@ -1343,9 +1360,9 @@ func (s *PGDocStore) BulkCheckPermissions(
 	}
 
 	uuids, err := s.reader.BulkCheckPermissions(ctx, postgres.BulkCheckPermissionsParams{
+		Uuids:       req.UUIDs,
 		URI:         req.GranteeURIs,
 		Permissions: perms,
-		Uuids:       req.UUIDs,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("check acls: %w", err)
@@ -1511,7 +1528,7 @@ func (s *PGDocStore) GetDocumentMeta(
 		}
 	}
 
-	acl, err := s.GetDocumentACL(ctx, docID)
+	acl, err := s.GetEffectiveDocumentACL(ctx, docID)
 	if err != nil {
 		return nil, err
 	}
@@ -1617,7 +1634,7 @@ func (s *PGDocStore) BulkGetDocumentMeta(
 			})
 	}
 
-	bulkACLs, err := s.BulkGetDocumentACL(ctx, foundDocs)
+	bulkACLs, err := bulkGetEffectiveACL(ctx, s.reader, foundDocs)
 	if err != nil {
 		return nil, err
 	}
@@ -1645,10 +1662,7 @@ func (s *PGDocStore) GetDocumentACL(
 	var acl []ACLEntry
 
 	for _, a := range aclResult {
-		acl = append(acl, ACLEntry{
-			URI:         a.URI,
-			Permissions: a.Permissions,
-		})
+		acl = append(acl, aclEntryFromRow(a))
 	}
 
 	return acl, nil
@@ -1667,10 +1681,7 @@ func (s *PGDocStore) BulkGetDocumentACL(
 	for _, a := range aclResult {
 		acl := result[a.UUID]
 
-		acl = append(acl, ACLEntry{
-			URI:         a.URI,
-			Permissions: a.Permissions,
-		})
+		acl = append(acl, aclEntryFromRow(a))
 
 		result[a.UUID] = acl
 	}
@@ -1678,6 +1689,90 @@ func (s *PGDocStore) BulkGetDocumentACL(
 	return result, nil
 }
 
+// GetPermissionSources implements DocStore.
+func (s *PGDocStore) GetPermissionSources(
+	ctx context.Context, docUUID uuid.UUID, granteeURIs []string,
+) (*PermissionSources, error) {
+	info, err := s.reader.GetDocumentInfo(ctx, postgres.GetDocumentInfoParams{
+		UUID: docUUID,
+		Now:  pg.Time(time.Now()),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, DocStoreErrorf(ErrCodeNotFound, "not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch document info: %w", err)
+	}
+
+	sources := PermissionSources{
+		Type:         info.Type,
+		MainDocument: pg.ToUUIDPointer(info.MainDoc),
+		SystemState:  SystemState(info.SystemState.String),
+		Lock: Lock{
+			Token:       info.LockToken.String,
+			URI:         info.LockUri.String,
+			Created:     info.LockCreated.Time,
+			Expires:     info.LockExpires.Time,
+			App:         info.LockApp.String,
+			Comment:     info.LockComment.String,
+			Exclusivity: LockExclusivity(info.LockExclusivity.String),
+		},
+	}
+
+	uuids := []uuid.UUID{docUUID}
+
+	if sources.MainDocument != nil {
+		uuids = append(uuids, *sources.MainDocument)
+	}
+
+	own, err := s.reader.BulkGetDocumentACL(ctx, uuids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch document ACLs: %w", err)
+	}
+
+	for _, a := range own {
+		if !slices.Contains(granteeURIs, a.URI) {
+			continue
+		}
+
+		source := ACLGrantDocument
+		if a.UUID != docUUID {
+			source = ACLGrantMainDocument
+		}
+
+		sources.Grants = append(sources.Grants, ACLGrant{
+			Source:   source,
+			Document: a.UUID,
+			Entry:    aclEntryFromRow(a),
+		})
+	}
+
+	inherited, err := s.reader.GetInheritedACLSources(ctx,
+		postgres.GetInheritedACLSourcesParams{
+			Uuids: uuids,
+			Uris:  granteeURIs,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch inherited ACLs: %w", err)
+	}
+
+	for _, a := range inherited {
+		sources.Grants = append(sources.Grants, ACLGrant{
+			Source:      ACLGrantInherited,
+			Document:    a.Parent,
+			Inheritor:   a.UUID,
+			Inheritable: a.Inheritable,
+			Entry: ACLEntry{
+				URI:         a.URI,
+				Permissions: a.Permissions,
+				NotBefore:   timestamptzPointer(a.NotBefore),
+				Expires:     timestamptzPointer(a.Expires),
+			},
+		})
+	}
+
+	return &sources, nil
+}
+
 func (s *PGDocStore) getFullDocumentHeads(
 	ctx context.Context, q *postgres.Queries, docUUID uuid.UUID,
 ) (map[string]StatusHead, error) {
@@ -1915,30 +2010,68 @@ func (s *PGDocStore) Update(
 				)
 			}
 
+			status := MultipartStatus(uploadInfo.MultipartStatus.String)
+			if uploadInfo.MultipartStatus.Valid &&
+				status != MultipartStatusCompleted {
+				return nil, elephantine.InvalidArgumentf(
+					"attach_objects",
+					"the multipart upload for %q (%s) is %s",
+					name, upload.ID, status,
+				)
+			}
+
 			// Transfer the metadata from the upload row.
 			upload.Meta = AssetMetadata{
 				Filename: uploadInfo.Meta.Filename,
 				Mimetype: uploadInfo.Meta.Mimetype,
 				Props:    uploadInfo.Meta.Props,
+				SHA256:   uploadInfo.Meta.SHA256,
+				Size:     uploadInfo.Meta.Size,
 			}
 
-			req.AttachObjects[name] = upload
-
-			exists, err := s.assets.UploadExists(ctx, upload.ID)
+			inspection, err := s.assets.InspectUpload(
+				ctx, upload.ID, upload.Meta.SHA256 != "")
 			if err != nil {
 				return nil, fmt.Errorf(
-					"check if %q (%s) has been uploaded: %w",
-					name, upload, err,
+					"inspect upload %q (%s): %w",
+					name, upload.ID, err,
 				)
 			}
 
-			if !exists {
+			if inspection == nil {
 				return nil, elephantine.InvalidArgumentf(
 					"attach_objects",
 					"no object uploaded for %q (%s)",
-					name, upload,
+					name, upload.ID,
+				)
+			}
+
+			err = VerifyUpload(upload.Meta, *inspection)
+			if err != nil {
+				return nil, elephantine.InvalidArgumentf(
+					"attach_objects",
+					"the object uploaded for %q (%s) doesn't match the upload: %v",
+					name, upload.ID, err,
+				)
+			}
+
+			// Record the verified size, checksum and content type,
+			// and the ETag so that we attach the object that was
+			// verified.
+			upload.Meta.Size = inspection.Size
+			upload.Meta.SHA256 = inspection.SHA256
+			upload.Meta.SniffedMimetype = inspection.Mimetype
+			upload.ETag = inspection.ETag
+
+			err = s.processUpload(ctx, req.UUID, name, &upload)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"process upload %q (%s): %w",
+					name, upload.ID, err,
 				)
 			}
+
+			req.AttachObjects[name] = upload
 		}
 	}
 
@@ -1966,13 +2099,36 @@ func (s *PGDocStore) Update(
 
 	q := postgres.New(tx)
 
+	// Types of the documents that are created by the batch, so that the
+	// meta document of a new document can be created in the same batch.
+	createdTypes := make(map[uuid.UUID]string)
+
 	for _, state := range updates {
+		mainDocID := state.MainDocID
+
+		createdMainType, mainCreated := "", false
+		if mainDocID != nil {
+			createdMainType, mainCreated = createdTypes[*mainDocID]
+		}
+
+		if mainCreated {
+			mainDocID = nil
+		}
+
 		info, err := s.UpdatePreflight(ctx, q,
-			state.Request.UUID, state.Request.IfMatch, state.MainDocID)
+			state.Request.UUID, state.Request.IfMatch, mainDocID)
 		if err != nil {
 			return nil, err
 		}
 
+		if mainCreated {
+			info.MainDocType = createdMainType
+		}
+
+		if !info.Exists && state.Doc != nil {
+			createdTypes[state.UUID] = state.Doc.Type
+		}
+
 		if !info.Exists && state.Doc == nil {
 			return nil, DocStoreErrorf(ErrCodeNotFound,
 				"non-document update for document that doesn't exist")
@@ -2066,9 +2222,15 @@ func (s *PGDocStore) Update(
 			// evts, or -1 if this update doesn't create a new version.
 			// Used to fold an accompanying ACL update onto it.
 			docEvtIdx = -1
+			// inheritanceChanged is set when the links of a new
+			// version changed the inherited permissions, together
+			// with the effective ACL from before the change.
+			inheritanceChanged   bool
+			aclBeforeInheritance []ACLEntry
 		)
 
 		workflow, hasWorkflow := workflows.GetDocumentWorkflow(state.Type)
+		hasWorkflow = hasWorkflow && !state.Request.NoWorkflow
 		trackWorkflow := hasWorkflow && !state.IsMetaDoc
 
 		if hasWorkflow {
@@ -2135,6 +2297,31 @@ func (s *PGDocStore) Update(
 
 			state.Version = version
 
+			err = updateDocumentLinks(ctx, q, state.UUID, *state.Doc)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"update document links: %w", err)
+			}
+
+			err = updateDeprecationUsage(ctx, q, state.UUID,
+				state.Version, state.Request.Deprecations)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"update deprecation usage: %w", err)
+			}
+
+			if !state.IsMetaDoc {
+				before, changed, err := updateACLInheritance(
+					ctx, q, state.UUID, *state.Doc)
+				if err != nil {
+					return nil, fmt.Errorf(
+						"update ACL inheritance: %w", err)
+				}
+
+				inheritanceChanged = changed
+				aclBeforeInheritance = before
+			}
+
 			evt := postgres.OutboxEvent{
 				Event:            string(TypeDocumentVersion),
 				UUID:             state.UUID,
@@ -2156,8 +2343,23 @@ func (s *PGDocStore) Update(
 			// Add attaches and detaches to the event, we'll act on
 			// these later, but they're recorded as part of the
 			// document update.
-			for name := range state.Request.AttachObjects {
+			for name, spec := range state.Request.AttachObjects {
 				evt.AttachedObjects = append(evt.AttachedObjects, name)
+
+				if evt.AttachedObjectMeta == nil {
+					evt.AttachedObjectMeta = make(map[string]postgres.AssetMetadata)
+				}
+
+				evt.AttachedObjectMeta[name] = postgres.AssetMetadata{
+					Filename:        spec.Meta.Filename,
+					Mimetype:        spec.Meta.Mimetype,
+					SniffedMimetype: spec.Meta.SniffedMimetype,
+					Props:           spec.Meta.Props,
+					SHA256:          spec.Meta.SHA256,
+					Size:            spec.Meta.Size,
+					Derived:         spec.Meta.Derived,
+					Renditions:      renditionsToPG(spec.Meta.Renditions),
+				}
 			}
 
 			evt.DetachedObjects = append(evt.DetachedObjects,
@@ -2379,26 +2581,45 @@ func (s *PGDocStore) Update(
 		}
 
 		if len(aclUpdate) > 0 {
-			err := updateACL(ctx, q, state.Request.UUID, aclUpdate)
+			err := updateACL(ctx, q, state.Request.UUID, aclUpdate,
+				state.Request.ACLTimeBounds)
 			if err != nil {
 				return nil, fmt.Errorf("update ACL: %w", err)
 			}
+		}
+
+		var aclEntries []postgres.ACLEntry
+
+		if len(aclUpdate) > 0 || inheritanceChanged {
+			effective, err := bulkGetEffectiveACL(ctx, q,
+				[]uuid.UUID{state.Request.UUID})
+			if err != nil {
+				return nil, err
+			}
 
-			entries := make([]postgres.ACLEntry, len(aclUpdate))
+			// ACL events are deltas, so they carry the effective
+			// permissions of the updated URIs and of the URIs
+			// whose inherited permissions changed with the links
+			// of the document.
+			changes := slices.Clone(aclUpdate)
 
-			for i := range aclUpdate {
-				entries[i] = postgres.ACLEntry{
-					URI:         aclUpdate[i].URI,
-					Permissions: aclUpdate[i].Permissions,
-				}
+			if inheritanceChanged {
+				changes = addChangedACLURIs(changes,
+					aclBeforeInheritance,
+					effective[state.Request.UUID])
 			}
 
+			aclEntries = aclTransitionEntries(
+				changes, effective[state.Request.UUID])
+		}
+
+		if len(aclEntries) > 0 {
 			// Fold the ACL onto the document version event when the ACL
 			// update accompanies a new version. This keeps the document
 			// and its permissions in a single event, so consumers never
 			// observe the new version before the ACL it was created with.
 			if docEvtIdx >= 0 {
-				evts[docEvtIdx].ACL = entries
+				evts[docEvtIdx].ACL = aclEntries
 			}
 
 			// Emit the standalone ACL event when the update isn't folded
@@ -2413,7 +2634,7 @@ func (s *PGDocStore) Update(
 					Timestamp:        state.Created,
 					Updater:          state.Creator,
 					Type:             state.Type,
-					ACL:              entries,
+					ACL:              aclEntries,
 					Language:         state.Language,
 					MainDocument:     state.Request.MainDocument,
 					MainDocumentType: state.MainDocType,
@@ -2424,6 +2645,21 @@ func (s *PGDocStore) Update(
 			}
 		}
 
+		// Documents that inherit permissions from this document get
+		// ACL events so that consumers can pick up their new effective
+		// permissions.
+		if len(aclUpdate) > 0 {
+			inheritorEvts, err := aclInheritorEvents(ctx, q,
+				state.Request.UUID, aclUpdate,
+				state.Created, state.Creator)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"create events for inheriting documents: %w", err)
+			}
+
+			evts = append(evts, inheritorEvts...)
+		}
+
 		// The workflow state is persisted once per update with the final
 		// state; the matching transition is folded onto the document or
 		// status event that caused it above. The separate workflow event
@@ -2479,7 +2715,8 @@ func (s *PGDocStore) Update(
 	// Process attachments last, as we want to reasonably certain that the
 	// transaction will be a success before we start manipulating the object
 	// store.
-	revertAttachments, err := s.processAttachments(ctx, q, updates)
+	revertAttachments, cleanupAttachments, err := s.processAttachments(
+		ctx, q, updates)
 	if err != nil {
 		return nil, fmt.Errorf("process attachments: %w", err)
 	}
@@ -2504,6 +2741,8 @@ func (s *PGDocStore) Update(
 		return nil, fmt.Errorf("commit: %w", err)
 	}
 
+	cleanupAttachments()
+
 	var res []DocumentUpdate
 
 	for _, up := range updates {
@@ -2520,18 +2759,123 @@ func (s *PGDocStore) Update(
 	return res, nil
 }
 
+// processUpload runs the attachment processors for an upload and records the
+// derived metadata and renditions on the upload.
+func (s *PGDocStore) processUpload(
+	ctx context.Context, document uuid.UUID, name string, upload *Upload,
+) error {
+	var accepted bool
+
+	for _, p := range s.opts.AttachmentProcessors {
+		if p.Accepts(upload.Meta.Mimetype) {
+			accepted = true
+
+			break
+		}
+	}
+
+	if !accepted || upload.Meta.Size > s.opts.MaxProcessedAttachmentSize {
+		return nil
+	}
+
+	// Read the version of the upload that was verified.
+	data, err := s.assets.ReadUpload(ctx, upload.ID, upload.ETag,
+		s.opts.MaxProcessedAttachmentSize)
+	if errors.Is(err, errUploadReplaced) {
+		return DocStoreErrorf(ErrCodeFailedPrecondition,
+			"the object uploaded for %q (%s) was replaced after it was verified",
+			name, upload.ID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("read upload: %w", err)
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	derived, renditions := processAttachment(ctx, s.logger,
+		s.opts.AttachmentProcessors, AttachmentData{
+			Document:    document,
+			Name:        name,
+			ContentType: upload.Meta.Mimetype,
+			Data:        data,
+		})
+
+	for i := range renditions {
+		renditions[i].Key = renditionKey(
+			document, name, renditions[i].Name, upload.ID)
+	}
+
+	upload.Meta.Derived = derived
+	upload.Meta.Renditions = assetRenditions(renditions)
+	upload.Renditions = renditions
+
+	return nil
+}
+
+// staleRendition is a rendition of a replaced or detached object version.
+type staleRendition struct {
+	Document uuid.UUID
+	Name     string
+	Key      string
+}
+
+// staleRenditions lists the renditions of an attached object version.
+func staleRenditions(
+	document uuid.UUID, name string, renditions []postgres.AssetRendition,
+) []staleRendition {
+	stale := make([]staleRendition, len(renditions))
+
+	for i, r := range renditions {
+		stale[i] = staleRendition{
+			Document: document,
+			Name:     name,
+			Key:      renditionObjectKey(document, name, r.Name, r.Key),
+		}
+	}
+
+	return stale
+}
+
+// deleteRenditions deletes renditions from the object store. Failures are
+// logged, as renditions that are left behind don't affect the attached
+// objects.
+func (s *PGDocStore) deleteRenditions(
+	ctx context.Context, renditions []staleRendition,
+) {
+	for _, r := range renditions {
+		err := s.assets.DeleteRendition(ctx, r.Key)
+		if err != nil {
+			s.logger.WarnContext(ctx,
+				"failed to delete rendition",
+				elephantine.LogKeyError, err,
+				elephantine.LogKeyDocumentUUID, r.Document,
+				elephantine.LogKeyObjectKey, r.Key,
+				"object_name", r.Name,
+			)
+		}
+	}
+}
+
 // processAttachments for the updates, on success it returns a function that can
-// be called to roll back the changes to the object store.
+// be called to roll back the changes to the object store, and a function that
+// should be called after commit to delete the renditions of the replaced and
+// detached object versions.
 func (s *PGDocStore) processAttachments(
 	ctx context.Context,
 	q *postgres.Queries,
 	updates []*docUpdateState,
-) (_ func(), outErr error) {
+) (_ func(), _ func(), outErr error) {
 	// If we fail we need to roll back the object store updates that have
 	// been made. This is tricky stuff as we never can guarantee any real
 	// consistency with the object store, but this will have to be on an
 	// best effort basis.
-	var attachRollback []attached
+	var (
+		attachRollback []attached
+		stale          []staleRendition
+	)
 
 	rollback := func() {
 		for _, r := range attachRollback {
@@ -2546,9 +2890,18 @@ func (s *PGDocStore) processAttachments(
 					"object_name", r.Name,
 				)
 			}
+
+			// The renditions were stored under keys of their own,
+			// so they can be removed without affecting the
+			// renditions of the current version.
+			s.deleteRenditions(ctx, r.Renditions)
 		}
 	}
 
+	cleanup := func() {
+		s.deleteRenditions(ctx, stale)
+	}
+
 	defer func() {
 		if outErr == nil || len(attachRollback) == 0 {
 			return
@@ -2565,16 +2918,23 @@ func (s *PGDocStore) processAttachments(
 					Name:     name,
 				})
 			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-				return nil, fmt.Errorf(
+				return nil, nil, fmt.Errorf(
 					"get current %q attachments for %s: %w",
 					name, state.UUID, err)
 			}
 
 			version := current.Version + 1
 
-			objectVersion, err := s.assets.AttachUpload(ctx, spec.ID, state.UUID, name)
+			objectVersion, err := s.assets.AttachUpload(
+				ctx, spec.ID, state.UUID, name, spec.ETag)
+			if errors.Is(err, errUploadReplaced) {
+				return nil, nil, DocStoreErrorf(ErrCodeFailedPrecondition,
+					"the object uploaded for %q (%s) was replaced after it was verified",
+					name, spec.ID)
+			}
+
 			if err != nil {
-				return nil, fmt.Errorf(
+				return nil, nil, fmt.Errorf(
 					"attach upload %q to document %s as %q: %w",
 					spec.ID, state.UUID, name, err,
 				)
@@ -2586,6 +2946,28 @@ func (s *PGDocStore) processAttachments(
 				CreatedVersion: objectVersion,
 			})
 
+			rb := &attachRollback[len(attachRollback)-1]
+
+			for _, r := range spec.Renditions {
+				err := s.assets.PutRendition(ctx, r)
+				if err != nil {
+					return nil, nil, fmt.Errorf(
+						"store %q rendition of the %q object for document %s: %w",
+						r.Name, name, state.UUID, err)
+				}
+
+				rb.Renditions = append(rb.Renditions, staleRendition{
+					Document: state.UUID,
+					Name:     name,
+					Key:      r.Key,
+				})
+			}
+
+			if !current.Deleted {
+				stale = append(stale, staleRenditions(
+					state.UUID, name, current.Meta.Renditions)...)
+			}
+
 			err = q.AddAttachedObject(ctx,
 				postgres.AddAttachedObjectParams{
 					Document:      state.UUID,
@@ -2596,13 +2978,18 @@ func (s *PGDocStore) processAttachments(
 					CreatedAt:     pg.Time(state.Created),
 					CreatedBy:     state.Creator,
 					Meta: postgres.AssetMetadata{
-						Filename: spec.Meta.Filename,
-						Mimetype: spec.Meta.Mimetype,
-						Props:    spec.Meta.Props,
+						Filename:        spec.Meta.Filename,
+						Mimetype:        spec.Meta.Mimetype,
+						SniffedMimetype: spec.Meta.SniffedMimetype,
+						Props:           spec.Meta.Props,
+						SHA256:          spec.Meta.SHA256,
+						Size:            spec.Meta.Size,
+						Derived:         spec.Meta.Derived,
+						Renditions:      renditionsToPG(spec.Meta.Renditions),
 					},
 				})
 			if err != nil {
-				return nil, fmt.Errorf(
+				return nil, nil, fmt.Errorf(
 					"add attached %q object for document %s: %w",
 					name, state.UUID, err)
 			}
@@ -2615,7 +3002,7 @@ func (s *PGDocStore) processAttachments(
 					Deleted:  false,
 				})
 			if err != nil {
-				return nil, fmt.Errorf(
+				return nil, nil, fmt.Errorf(
 					"add current attached %q object for document %s: %w",
 					name, state.UUID, err)
 			}
@@ -2630,16 +3017,19 @@ func (s *PGDocStore) processAttachments(
 			if errors.Is(err, pgx.ErrNoRows) || current.Deleted {
 				continue
 			} else if err != nil {
-				return nil, fmt.Errorf(
+				return nil, nil, fmt.Errorf(
 					"get current %q attachments for %s: %w",
 					name, state.UUID, err)
 			}
 
 			err = s.assets.DeleteObject(ctx, state.UUID, name)
 			if err != nil {
-				return nil, fmt.Errorf("delete attached object: %w", err)
+				return nil, nil, fmt.Errorf("delete attached object: %w", err)
 			}
 
+			stale = append(stale, staleRenditions(
+				state.UUID, name, current.Meta.Renditions)...)
+
 			attachRollback = append(attachRollback, attached{
 				Document: state.UUID,
 				Name:     name,
@@ -2653,14 +3043,14 @@ func (s *PGDocStore) processAttachments(
 					Deleted:  true,
 				})
 			if err != nil {
-				return nil, fmt.Errorf(
+				return nil, nil, fmt.Errorf(
 					"set current attached %q object for document %s as deleted: %w",
 					name, state.UUID, err)
 			}
 		}
 	}
 
-	return rollback, nil
+	return rollback, cleanup, nil
 }
 
 type DocumentExtracts struct {
@@ -2746,6 +3136,8 @@ type attached struct {
 	DocVersion     int64
 	Name           string
 	CreatedVersion string
+	// Renditions that were stored for the created version.
+	Renditions []staleRendition
 }
 
 func (s *PGDocStore) doAttachmentObjectRevert(
@@ -2851,7 +3243,11 @@ func (s *PGDocStore) CreateUpload(
 			Filename: upload.Meta.Filename,
 			Mimetype: upload.Meta.Mimetype,
 			Props:    upload.Meta.Props,
+			SHA256:   upload.Meta.SHA256,
+			Size:     upload.Meta.Size,
 		},
+		MultipartID:     pg.TextOrNull(upload.MultipartID),
+		MultipartStatus: pg.TextOrNull(string(upload.MultipartStatus)),
 	})
 	if err != nil {
 		return fmt.Errorf("insert upload row: %w", err)
@@ -2860,6 +3256,68 @@ func (s *PGDocStore) CreateUpload(
 	return nil
 }
 
+// DeleteUpload implements DocStore.
+func (s *PGDocStore) DeleteUpload(ctx context.Context, id uuid.UUID) error {
+	err := s.reader.DeleteUpload(ctx, id)
+	if err != nil {
+		return fmt.Errorf("delete upload row: %w", err)
+	}
+
+	return nil
+}
+
+// GetUpload implements DocStore.
+func (s *PGDocStore) GetUpload(
+	ctx context.Context, id uuid.UUID,
+) (*Upload, error) {
+	row, err := s.reader.GetUpload(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, DocStoreErrorf(ErrCodeNotFound, "no such upload")
+	} else if err != nil {
+		return nil, fmt.Errorf("read upload row: %w", err)
+	}
+
+	upload := Upload{
+		ID:        row.ID,
+		CreatedBy: row.CreatedBy,
+		CreatedAt: row.CreatedAt.Time,
+		Meta: AssetMetadata{
+			Filename: row.Meta.Filename,
+			Mimetype: row.Meta.Mimetype,
+			Props:    row.Meta.Props,
+			SHA256:   row.Meta.SHA256,
+			Size:     row.Meta.Size,
+		},
+		MultipartID:     row.MultipartID.String,
+		MultipartStatus: MultipartStatus(row.MultipartStatus.String),
+	}
+
+	return &upload, nil
+}
+
+// SetUploadMultipartStatus implements DocStore.
+func (s *PGDocStore) SetUploadMultipartStatus(
+	ctx context.Context, id uuid.UUID,
+	current MultipartStatus, status MultipartStatus,
+) error {
+	n, err := s.reader.SetUploadMultipartStatus(ctx,
+		postgres.SetUploadMultipartStatusParams{
+			MultipartStatus: string(status),
+			ID:              id,
+			CurrentStatus:   string(current),
+		})
+	if err != nil {
+		return fmt.Errorf("update upload row: %w", err)
+	}
+
+	if n == 0 {
+		return DocStoreErrorf(ErrCodeFailedPrecondition,
+			"the multipart upload is not %s", current)
+	}
+
+	return nil
+}
+
 // GetAttachments implements DocStore.
 func (s *PGDocStore) GetAttachments(
 	ctx context.Context,
@@ -2892,19 +3350,182 @@ func (s *PGDocStore) GetAttachments(
 			dlLink = l
 		}
 
+		renditions := make([]AttachmentRendition, len(rows[i].Meta.Renditions))
+
+		for j, r := range rows[i].Meta.Renditions {
+			renditions[j] = AttachmentRendition{
+				Name:        r.Name,
+				ContentType: r.Mimetype,
+				Size:        r.Size,
+			}
+
+			if !getDownloadLink {
+				continue
+			}
+
+			l, err := s.assets.CreateRenditionDownloadURL(ctx,
+				renditionObjectKey(rows[i].Document, rows[i].Name,
+					r.Name, r.Key))
+			if err != nil {
+				return nil, fmt.Errorf(
+					"create rendition download link: %w", err)
+			}
+
+			renditions[j].DownloadLink = l
+		}
+
 		res[i] = AttachmentDetails{
-			Document:     rows[i].Document,
-			Name:         rows[i].Name,
-			Version:      rows[i].Version,
-			DownloadLink: dlLink,
-			Filename:     rows[i].Meta.Filename,
-			ContentType:  rows[i].Meta.Mimetype,
+			Document:           rows[i].Document,
+			Name:               rows[i].Name,
+			Version:            rows[i].Version,
+			DownloadLink:       dlLink,
+			Filename:           rows[i].Meta.Filename,
+			ContentType:        rows[i].Meta.Mimetype,
+			SniffedContentType: rows[i].Meta.SniffedMimetype,
+			SHA256:             rows[i].Meta.SHA256,
+			Size:               rows[i].Meta.Size,
+			Derived:            rows[i].Meta.Derived,
+			Renditions:         renditions,
 		}
 	}
 
 	return res, nil
 }
 
+// GetAttachedObjects implements DocStore.
+func (s *PGDocStore) GetAttachedObjects(
+	ctx context.Context, document uuid.UUID,
+) ([]AttachedObject, error) {
+	rows, err := s.reader.GetDocumentAttachmentDetails(ctx, document)
+	if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	return attachedObjectsFromRows(rows), nil
+}
+
+// GetAttachedObjectsAtVersion implements DocStore.
+func (s *PGDocStore) GetAttachedObjectsAtVersion(
+	ctx context.Context, document uuid.UUID, version int64,
+) ([]AttachedObject, error) {
+	rows, err := s.reader.GetAttachedObjectsAtVersion(ctx,
+		postgres.GetAttachedObjectsAtVersionParams{
+			Document:   document,
+			DocVersion: version,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	return attachedObjectsFromRows(rows), nil
+}
+
+func attachedObjectsFromRows(rows []postgres.AttachedObject) []AttachedObject {
+	res := make([]AttachedObject, len(rows))
+
+	for i, row := range rows {
+		res[i] = AttachedObject{
+			Document:        row.Document,
+			Name:            row.Name,
+			Version:         row.Version,
+			ObjectVersion:   row.ObjectVersion,
+			AttachedAt:      row.AttachedAt,
+			CreatedBy:       row.CreatedBy,
+			CreatedAt:       row.CreatedAt.Time,
+			Filename:        row.Meta.Filename,
+			Mimetype:        row.Meta.Mimetype,
+			SniffedMimetype: row.Meta.SniffedMimetype,
+			Props:           row.Meta.Props,
+			SHA256:          row.Meta.SHA256,
+			Size:            row.Meta.Size,
+			Derived:         row.Meta.Derived,
+		}
+	}
+
+	return res
+}
+
+// GetAttachmentHistory implements DocStore.
+func (s *PGDocStore) GetAttachmentHistory(
+	ctx context.Context, document uuid.UUID, name string,
+) (*AttachmentHistory, error) {
+	current, err := s.reader.GetAttachedObject(ctx,
+		postgres.GetAttachedObjectParams{
+			Document: document,
+			Name:     name,
+		})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, DocStoreErrorf(ErrCodeNotFound,
+			"no such attachment")
+	} else if err != nil {
+		return nil, fmt.Errorf("read current attachment: %w", err)
+	}
+
+	rows, err := s.reader.GetAttachedObjectHistory(ctx,
+		postgres.GetAttachedObjectHistoryParams{
+			Document: document,
+			Name:     name,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("read attachment history: %w", err)
+	}
+
+	history := AttachmentHistory{
+		Versions: make([]AttachedObject, len(rows)),
+	}
+
+	if !current.Deleted {
+		history.CurrentVersion = current.Version
+	}
+
+	for i, row := range rows {
+		history.Versions[i] = attachedObjectFromRow(row)
+	}
+
+	return &history, nil
+}
+
+// GetAttachmentVersion implements DocStore.
+func (s *PGDocStore) GetAttachmentVersion(
+	ctx context.Context, document uuid.UUID, name string, version int64,
+) (*AttachedObject, error) {
+	row, err := s.reader.GetAttachedObjectVersion(ctx,
+		postgres.GetAttachedObjectVersionParams{
+			Document: document,
+			Name:     name,
+			Version:  version,
+		})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, DocStoreErrorf(ErrCodeNotFound,
+			"no such attachment version")
+	} else if err != nil {
+		return nil, fmt.Errorf("read attachment version: %w", err)
+	}
+
+	obj := attachedObjectFromRow(row)
+
+	return &obj, nil
+}
+
+func attachedObjectFromRow(row postgres.AttachedObject) AttachedObject {
+	return AttachedObject{
+		Document:        row.Document,
+		Name:            row.Name,
+		Version:         row.Version,
+		ObjectVersion:   row.ObjectVersion,
+		AttachedAt:      row.AttachedAt,
+		CreatedBy:       row.CreatedBy,
+		CreatedAt:       row.CreatedAt.Time,
+		Filename:        row.Meta.Filename,
+		Mimetype:        row.Meta.Mimetype,
+		SniffedMimetype: row.Meta.SniffedMimetype,
+		Props:           row.Meta.Props,
+		SHA256:          row.Meta.SHA256,
+		Size:            row.Meta.Size,
+		Derived:         row.Meta.Derived,
+	}
+}
+
 // GetDeliverableInfo implements DocStore.
 func (s *PGDocStore) GetDeliverableInfo(ctx context.Context, id uuid.UUID) (DeliverableInfo, error) {
 	info, err := s.reader.GetDeliverableInfo(ctx, id)
@@ -3558,6 +4179,21 @@ func (s *PGDocStore) GetMetaTypeForDocument(
 	}, nil
 }
 
+// GetMetaTypeForType implements DocStore.
+func (s *PGDocStore) GetMetaTypeForType(
+	ctx context.Context, docType string,
+) (DocumentMetaType, error) {
+	metaType, err := s.reader.GetMetaTypeForType(ctx, docType)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return DocumentMetaType{}, fmt.Errorf("query failed: %w", err)
+	}
+
+	return DocumentMetaType{
+		MetaType: metaType,
+		Exists:   true,
+	}, nil
+}
+
 // RegisterMetaType implements DocStore.
 func (s *PGDocStore) RegisterMetaType(
 	ctx context.Context, metaType string, exclusive bool,
@@ -4157,23 +4793,16 @@ func (s *PGDocStore) RegisterGeneration(
 	// Determine initial status.
 	status := postgres.SchemaGenerationStatusDeactivated
 
-	var activated pgtype.Timestamptz
-
-	switch req.Activation {
-	case GenerationStatusActive:
-		status = postgres.SchemaGenerationStatusActive
-		activated = pg.Time(now)
-	case GenerationStatusPending:
+	// Generations that should be active are stored as deactivated until
+	// they have passed the activation checks.
+	if req.Activation == GenerationStatusPending {
 		status = postgres.SchemaGenerationStatusPending
-	case GenerationStatusDeactivated:
-		// Already the default, nothing to do.
 	}
 
 	genID, err := q.InsertSchemaGeneration(ctx, postgres.InsertSchemaGenerationParams{
 		IdentityHash: identityHash,
 		Status:       status,
 		Created:      pg.Time(now),
-		Activated:    activated,
 	})
 	if err != nil {
 		return 0, fmt.Errorf("insert schema generation: %w", err)
@@ -4229,6 +4858,21 @@ func (s *PGDocStore) RegisterGeneration(
 	// Handle activation.
 	switch req.Activation {
 	case GenerationStatusActive:
+		// Registering an active generation is how new schemas are
+		// bootstrapped, so the revalidation requirement only applies
+		// when generations are activated later on.
+		if !req.Force {
+			gen, err := q.GetSchemaGeneration(ctx, genID)
+			if err != nil {
+				return 0, fmt.Errorf("get generation: %w", err)
+			}
+
+			err = s.checkGenerationExemplars(ctx, q, gen)
+			if err != nil {
+				return 0, err
+			}
+		}
+
 		err = s.activateGeneration(ctx, q, genID, now)
 		if err != nil {
 			return 0, err
@@ -4261,7 +4905,31 @@ func (s *PGDocStore) RegisterGeneration(
 func (s *PGDocStore) activateGeneration(
 	ctx context.Context, q *postgres.Queries, genID int64, now time.Time,
 ) error {
-	err := q.DeactivateCurrentActiveGeneration(ctx,
+	// Carry over the exemplars that have been collected for the
+	// previously active generation, they're replaced by the next
+	// collection run.
+	prev, err := q.GetActiveSchemaGeneration(ctx)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return fmt.Errorf("get previous active generation: %w", err)
+	case prev.ID != genID:
+		err = q.DropCollectedExemplars(ctx, genID)
+		if err != nil {
+			return fmt.Errorf("drop collected exemplars: %w", err)
+		}
+
+		err = q.CopyCollectedExemplars(ctx, postgres.CopyCollectedExemplarsParams{
+			ToGeneration:   genID,
+			FromGeneration: prev.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("copy collected exemplars: %w", err)
+		}
+	}
+
+	err = q.DeactivateCurrentActiveGeneration(ctx,
 		postgres.DeactivateCurrentActiveGenerationParams{
 			Deactivated: pg.Time(now),
 			ExcludeID:   genID,
@@ -4298,9 +4966,28 @@ func (s *PGDocStore) activateGeneration(
 		return fmt.Errorf("sync active schemas: %w", err)
 	}
 
+	// The deprecations that documents use can change with the schemas.
+	err = startDeprecationUsageScan(ctx, q, genID, now)
+	if err != nil {
+		return fmt.Errorf("start deprecation usage scan: %w", err)
+	}
+
 	return nil
 }
 
+// checkGenerationActivation runs the checks that a generation has to pass
+// before it can be activated without being forced.
+func (s *PGDocStore) checkGenerationActivation(
+	ctx context.Context, q *postgres.Queries, gen postgres.SchemaGeneration,
+) error {
+	err := checkGenerationRevalidated(ctx, q, gen)
+	if err != nil {
+		return err
+	}
+
+	return s.checkGenerationExemplars(ctx, q, gen)
+}
+
 func (s *PGDocStore) setPendingGeneration(
 	ctx context.Context, q *postgres.Queries, genID int64, now time.Time,
 ) error {
@@ -4333,6 +5020,7 @@ func (s *PGDocStore) setPendingGeneration(
 
 func (s *PGDocStore) SetGenerationStatus(
 	ctx context.Context, id int64, activation SchemaGenerationStatus,
+	force bool,
 ) error {
 	return pg.WithTX(ctx, s.pool, func(tx pgx.Tx) error {
 		q := postgres.New(tx)
@@ -4348,6 +5036,13 @@ func (s *PGDocStore) SetGenerationStatus(
 
 		switch activation {
 		case GenerationStatusActive:
+			if !force {
+				err = s.checkGenerationActivation(ctx, q, gen)
+				if err != nil {
+					return err
+				}
+			}
+
 			err = s.activateGeneration(ctx, q, id, now)
 			if err != nil {
 				return err
@@ -4726,7 +5421,7 @@ func (s *PGDocStore) RegisterOrIncrementMetric(ctx context.Context, metric Metri
 
 func updateACL(
 	ctx context.Context, q *postgres.Queries,
-	docUUID uuid.UUID, updateACL []ACLEntry,
+	docUUID uuid.UUID, updateACL []ACLEntry, setTimeBounds bool,
 ) error {
 	// Batch ACL updates, ACLs with empty permissions are dropped
 	// immediately.
@@ -4748,9 +5443,12 @@ func updateACL(
 		}
 
 		acls = append(acls, postgres.ACLUpdateParams{
-			UUID:        docUUID,
-			URI:         acl.URI,
-			Permissions: acl.Permissions,
+			UUID:          docUUID,
+			URI:           acl.URI,
+			Permissions:   acl.Permissions,
+			NotBefore:     pg.PTime(acl.NotBefore),
+			Expires:       pg.PTime(acl.Expires),
+			SetTimeBounds: setTimeBounds,
 		})
 	}
 
@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/pg"
+)
+
+type clientIPKey struct{}
+
+// withClientIP adds the client IP of the request to the context.
+func withClientIP(
+	ctx context.Context, r *http.Request, trustedProxies int,
+) context.Context {
+	return context.WithValue(ctx, clientIPKey{},
+		requestClientIP(r, trustedProxies))
+}
+
+// clientIPFromContext returns the client IP that was added to the context
+// by withClientIP.
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+
+	return ip
+}
+
+// requestClientIP returns the IP of the client that made the request. Each
+// trusted proxy in front of the repository appends the address it received
+// the request from to X-Forwarded-For, so the client IP is the address that
+// the outermost trusted proxy appended. Addresses to the left of it are set
+// by the client and can't be trusted. Without trusted proxies the remote
+// address of the connection is used.
+func requestClientIP(r *http.Request, trustedProxies int) string {
+	var forwarded []string
+
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		for addr := range strings.SplitSeq(v, ",") {
+			forwarded = append(forwarded, strings.TrimSpace(addr))
+		}
+	}
+
+	if trustedProxies > 0 && len(forwarded) > 0 {
+		idx := max(len(forwarded)-trustedProxies, 0)
+
+		return forwarded[idx]
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// newReadAuditRecord creates a read audit record for the authenticated
+// client.
+func newReadAuditRecord(
+	auth *elephantine.AuthInfo,
+	clientIP string,
+	operation ReadAuditOperation,
+	reads []DocumentRead,
+) ReadAuditRecord {
+	app := auth.Claims.AuthorizedParty
+	if app == "" {
+		app = auth.Claims.ClientID
+	}
+
+	return ReadAuditRecord{
+		Operation: operation,
+		Subject:   auth.Claims.Subject,
+		App:       app,
+		ClientIP:  clientIP,
+		Created:   time.Now(),
+		Reads:     reads,
+	}
+}
+
+// RecordReads implements DocStore.
+func (s *PGDocStore) RecordReads(
+	ctx context.Context, record ReadAuditRecord,
+) error {
+	if len(record.Reads) == 0 {
+		return nil
+	}
+
+	types, err := s.opts.TypeConfigurations.ReadAuditTypes(ctx)
+	if err != nil {
+		return fmt.Errorf("get read audit types: %w", err)
+	}
+
+	if len(types) == 0 {
+		return nil
+	}
+
+	params := postgres.InsertReadAuditParams{
+		Operation: string(record.Operation),
+		Subject:   record.Subject,
+		App:       record.App,
+		ClientIp:  record.ClientIP,
+		Created:   pg.Time(record.Created),
+		Types:     types,
+	}
+
+	for _, r := range record.Reads {
+		params.Uuids = append(params.Uuids, r.UUID)
+		params.Versions = append(params.Versions, r.Version)
+		params.Attachments = append(params.Attachments, r.Attachment)
+	}
+
+	err = s.reader.InsertReadAudit(ctx, params)
+	if err != nil {
+		return fmt.Errorf("insert read audit entries: %w", err)
+	}
+
+	return nil
+}
+
+// GetReadAudit implements DocStore.
+func (s *PGDocStore) GetReadAudit(
+	ctx context.Context, query ReadAuditQuery,
+) ([]ReadAuditEntry, error) {
+	rows, err := s.reader.GetReadAudit(ctx, postgres.GetReadAuditParams{
+		UUID:     pg.PUUID(query.UUID),
+		Subject:  pg.TextOrNull(query.Subject),
+		After:    query.After,
+		RowLimit: query.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	entries := make([]ReadAuditEntry, len(rows))
+
+	for i, row := range rows {
+		entries[i] = readAuditEntryFromRow(row)
+	}
+
+	return entries, nil
+}
+
+func readAuditEntryFromRow(row postgres.ReadAudit) ReadAuditEntry {
+	return ReadAuditEntry{
+		ID:         row.ID,
+		UUID:       row.UUID,
+		Type:       row.Type,
+		Version:    row.Version,
+		Operation:  ReadAuditOperation(row.Operation),
+		Attachment: row.Attachment.String,
+		Subject:    row.Subject,
+		App:        row.App.String,
+		ClientIP:   row.ClientIp.String,
+		Created:    row.Created.Time,
+	}
+}
+
+const (
+	readAuditArchiveBatchSize = 1000
+
+	// readAuditArchiveLag is how old read audit entries must be before
+	// they're archived. Entries get their IDs when they're inserted, but
+	// can be committed out of ID order, so only entries that are old
+	// enough to be known to be committed are archived, otherwise an
+	// entry that commits late could end up behind the archiver position.
+	readAuditArchiveLag = 2 * time.Minute
+)
+
+// ArchivedReadAuditBatch is the immutable archive object for a batch of read
+// audit log entries.
+type ArchivedReadAuditBatch struct {
+	FirstID         int64            `json:"first_id"`
+	LastID          int64            `json:"last_id"`
+	Entries         []ReadAuditEntry `json:"entries"`
+	Archived        time.Time        `json:"archived"`
+	ParentSignature string           `json:"parent_signature,omitempty"`
+}
+
+func (ab *ArchivedReadAuditBatch) GetArchivedTime() time.Time {
+	return ab.Archived
+}
+
+func (ab *ArchivedReadAuditBatch) GetParentSignature() string {
+	return ab.ParentSignature
+}
+
+func (a *Archiver) runReadAuditArchiver(ctx context.Context) error {
+	lock, err := pg.NewJobLock(a.pool, a.logger, "read-audit-archiver",
+		pg.JobLockOptions{})
+	if err != nil {
+		return fmt.Errorf("acquire job lock: %w", err)
+	}
+
+	return lock.RunWithContext(ctx, a.archiveReadAuditLog)
+}
+
+func (a *Archiver) archiveReadAuditLog(ctx context.Context) error {
+	a.logger.Info("starting read audit archiver")
+
+	q := postgres.New(a.pool)
+
+	state, err := q.GetReadAuditArchiver(ctx)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("get read audit archiver position: %w", err)
+	}
+
+	pollInterval := 1 * time.Minute
+
+	for {
+		rows, err := q.GetReadAudit(ctx, postgres.GetReadAuditParams{
+			After:    state.Position,
+			RowLimit: readAuditArchiveBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("read audit log entries: %w", err)
+		}
+
+		// Stop at the first entry that isn't known to be committed
+		// together with everything after it, so that no entry is
+		// skipped.
+		cutoff := time.Now().Add(-readAuditArchiveLag)
+
+		for i := range rows {
+			if !rows[i].Created.Time.Before(cutoff) {
+				rows = rows[:i]
+
+				break
+			}
+		}
+
+		if len(rows) > 0 {
+			newState, err := a.archiveReadAuditBatch(ctx, q, rows, state)
+			if err != nil {
+				return err
+			}
+
+			state = newState
+		}
+
+		// Keep going without waiting while we're working through a
+		// backlog.
+		if len(rows) == readAuditArchiveBatchSize {
+			continue
+		}
+
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (a *Archiver) archiveReadAuditBatch(
+	ctx context.Context,
+	q *postgres.Queries,
+	rows []postgres.ReadAudit,
+	state postgres.GetReadAuditArchiverRow,
+) (_ postgres.GetReadAuditArchiverRow, outErr error) {
+	batch := ArchivedReadAuditBatch{
+		FirstID:         rows[0].ID,
+		LastID:          rows[len(rows)-1].ID,
+		Entries:         make([]ReadAuditEntry, len(rows)),
+		Archived:        time.Now(),
+		ParentSignature: state.LastSignature,
+	}
+
+	for i, row := range rows {
+		batch.Entries[i] = readAuditEntryFromRow(row)
+	}
+
+	key := fmt.Sprintf("read-audit/%020d_%020d.json",
+		batch.FirstID, batch.LastID)
+
+	ref, err := a.storeArchiveObject(ctx, key, &batch)
+	if err != nil {
+		return state, fmt.Errorf(
+			"archive read audit batch %d-%d: %w",
+			batch.FirstID, batch.LastID, err)
+	}
+
+	defer func() {
+		if outErr != nil {
+			ref.Remove(ctx)
+		}
+	}()
+
+	err = q.SetReadAuditArchiver(ctx,
+		postgres.SetReadAuditArchiverParams{
+			Position:      batch.LastID,
+			LastSignature: ref.Signature,
+		})
+	if err != nil {
+		return state, fmt.Errorf(
+			"update read audit archiver state: %w", err)
+	}
+
+	return postgres.GetReadAuditArchiverRow{
+		Position:      batch.LastID,
+		LastSignature: ref.Signature,
+	}, nil
+}
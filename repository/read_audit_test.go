@@ -0,0 +1,141 @@
+package repository_test
+
+import (
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationReadAudit(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+	ctx := t.Context()
+
+	tc := testingAPIServer(t, logger, testingServerOptions{})
+
+	schemaExt := tc.ExtensionClient(t, rpc.SchemasPathPrefix,
+		itest.Claims(t, "admin", "schema_admin"))
+
+	editor := tc.DocumentsClient(t,
+		itest.Claims(t, "editor", "doc_read doc_write"))
+
+	readerClaims := itest.Claims(t, "reader", "doc_read_all")
+	readerClaims.AuthorizedParty = "test-app"
+
+	reader := tc.DocumentsClient(t, readerClaims)
+	readerExt := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, readerClaims)
+
+	auditor := tc.ExtensionClient(t, rpc.DocumentsPathPrefix,
+		itest.Claims(t, "auditor", "read_audit"))
+
+	docUUID := uuid.NewString()
+
+	_, err := editor.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: baseDocument(docUUID, "article://test/sensitive"),
+	})
+	test.Must(t, err, "create document")
+
+	_, err = reader.Get(ctx, &rpc.GetDocumentRequest{Uuid: docUUID})
+	test.Must(t, err, "read document before enabling read audit")
+
+	err = schemaExt.Call(ctx, "SetTypeReadAudit",
+		repository.SetTypeReadAuditRequest{
+			Type:    "core/article",
+			Enabled: true,
+		}, &repository.SetTypeReadAuditResponse{})
+	test.Must(t, err, "enable read audit for articles")
+
+	var audit repository.GetReadAuditResponse
+
+	// The test server has no trusted proxies, so a client provided
+	// X-Forwarded-For must not be recorded as the client IP.
+	spoofed := make(http.Header)
+
+	spoofed.Set("X-Forwarded-For", "203.0.113.7")
+
+	spoofCtx, err := twirp.WithHTTPRequestHeaders(ctx, spoofed)
+	test.Must(t, err, "set request headers")
+
+	// The type configuration is propagated asynchronously, so keep
+	// reading until the read shows up in the audit log.
+	deadline := time.Now().Add(5 * time.Second)
+
+	for len(audit.Entries) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the read to be audited")
+		}
+
+		_, err = reader.Get(spoofCtx, &rpc.GetDocumentRequest{Uuid: docUUID})
+		test.Must(t, err, "read document")
+
+		err = auditor.Call(ctx, "GetReadAudit",
+			repository.GetReadAuditRequest{UUID: docUUID}, &audit)
+		test.Must(t, err, "get read audit for document")
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	entry := audit.Entries[0]
+
+	test.Equal(t, repository.ReadAuditGet, entry.Operation,
+		"get the expected operation")
+	test.Equal(t, "user://test/reader", entry.Subject,
+		"get the expected subject")
+	test.Equal(t, "test-app", entry.App, "get the expected app")
+	test.Equal(t, "core/article", entry.Type, "get the expected type")
+	test.Equal(t, int64(1), entry.Version, "get the expected version")
+
+	if entry.ClientIP == "" {
+		t.Fatal("expected the client IP to be recorded")
+	}
+
+	if entry.ClientIP == "203.0.113.7" {
+		t.Fatal("expected the client provided X-Forwarded-For to be ignored")
+	}
+
+	lastID := audit.Entries[len(audit.Entries)-1].ID
+
+	_, err = reader.BulkGet(ctx, &rpc.BulkGetRequest{
+		Documents: []*rpc.BulkGetReference{{Uuid: docUUID}},
+	})
+	test.Must(t, err, "bulk get document")
+
+	err = auditor.Call(ctx, "GetReadAudit", repository.GetReadAuditRequest{
+		Subject: "user://test/reader",
+		After:   lastID,
+	}, &audit)
+	test.Must(t, err, "get read audit for subject")
+
+	test.Equal(t, 1, len(audit.Entries), "get one new audit entry")
+	test.Equal(t, repository.ReadAuditBulkGet, audit.Entries[0].Operation,
+		"get the expected operation")
+
+	err = readerExt.Call(ctx, "GetReadAudit",
+		repository.GetReadAuditRequest{UUID: docUUID}, &audit)
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	conn, err := pgx.Connect(ctx, tc.Env.PostgresURI)
+	test.Must(t, err, "connect to database")
+
+	t.Cleanup(func() {
+		_ = conn.Close(ctx)
+	})
+
+	_, err = conn.Exec(ctx, "DELETE FROM read_audit")
+	if err == nil {
+		t.Fatal("expected deletes from the read audit log to fail")
+	}
+}
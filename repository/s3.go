@@ -2,13 +2,18 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 )
 
 type S3Options struct {
@@ -76,3 +81,301 @@ func S3Client(
 
 	return client, nil
 }
+
+// maxCopyObjectSize is the largest object that can be copied with a single
+// CopyObject request, larger objects are copied in parts.
+const maxCopyObjectSize int64 = 5 << 30
+
+// Part size and part count limits for copying objects in parts.
+const (
+	minCopyPartSize int64 = 512 << 20
+	maxCopyParts    int64 = 10000
+)
+
+// s3CopyInput describes an object copy for copyS3Object.
+type s3CopyInput struct {
+	SourceBucket string
+	SourceKey    string
+	// SourceVersion is the version of the source object to copy, the
+	// current version is copied if it's empty.
+	SourceVersion string
+	// SourceETag makes the copy fail if the source object doesn't have
+	// the ETag.
+	SourceETag        string
+	Bucket            string
+	Key               string
+	ChecksumAlgorithm types.ChecksumAlgorithm
+}
+
+// s3CopyResult is the result of copyS3Object.
+type s3CopyResult struct {
+	VersionID string
+	// ChecksumSHA256 is the base64 encoded full object checksum. It's only
+	// set when the object was copied in a single request with the SHA-256
+	// checksum algorithm.
+	ChecksumSHA256 string
+}
+
+// copyS3Object copies an object. Objects that are too large for a single
+// CopyObject request are copied in parts with a multipart upload.
+func copyS3Object(
+	ctx context.Context, client *s3.Client, in s3CopyInput,
+) (*s3CopyResult, error) {
+	headIn := s3.HeadObjectInput{
+		Bucket: aws.String(in.SourceBucket),
+		Key:    aws.String(in.SourceKey),
+	}
+
+	source := in.SourceBucket + "/" + in.SourceKey
+
+	if in.SourceVersion != "" {
+		headIn.VersionId = aws.String(in.SourceVersion)
+		source += "?versionId=" + in.SourceVersion
+	}
+
+	if in.SourceETag != "" {
+		headIn.IfMatch = aws.String(in.SourceETag)
+	}
+
+	head, err := client.HeadObject(ctx, &headIn)
+	if err != nil {
+		return nil, fmt.Errorf("get source object information: %w", err)
+	}
+
+	if aws.ToInt64(head.ContentLength) > maxCopyObjectSize {
+		return copyS3ObjectInParts(ctx, client, in, source, head)
+	}
+
+	copyIn := s3.CopyObjectInput{
+		Bucket:            aws.String(in.Bucket),
+		Key:               aws.String(in.Key),
+		CopySource:        aws.String(source),
+		ChecksumAlgorithm: in.ChecksumAlgorithm,
+	}
+
+	if in.SourceETag != "" {
+		copyIn.CopySourceIfMatch = aws.String(in.SourceETag)
+	}
+
+	res, err := client.CopyObject(ctx, &copyIn)
+	if err != nil {
+		return nil, fmt.Errorf("copy object: %w", err)
+	}
+
+	result := s3CopyResult{
+		VersionID: aws.ToString(res.VersionId),
+	}
+
+	if res.CopyObjectResult != nil {
+		result.ChecksumSHA256 = aws.ToString(
+			res.CopyObjectResult.ChecksumSHA256)
+	}
+
+	return &result, nil
+}
+
+// copyS3ObjectInParts copies an object with a multipart upload. Every part is
+// copied on the condition that the source still has the ETag that it had
+// when the copy started, and the upload is aborted if the copy fails.
+func copyS3ObjectInParts(
+	ctx context.Context, client *s3.Client, in s3CopyInput,
+	source string, head *s3.HeadObjectOutput,
+) (_ *s3CopyResult, outErr error) {
+	created, err := client.CreateMultipartUpload(ctx,
+		&s3.CreateMultipartUploadInput{
+			Bucket:             aws.String(in.Bucket),
+			Key:                aws.String(in.Key),
+			ContentType:        head.ContentType,
+			ContentEncoding:    head.ContentEncoding,
+			ContentDisposition: head.ContentDisposition,
+			ContentLanguage:    head.ContentLanguage,
+			CacheControl:       head.CacheControl,
+			Metadata:           head.Metadata,
+			ChecksumAlgorithm:  in.ChecksumAlgorithm,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("create multipart upload: %w", err)
+	}
+
+	defer func() {
+		if outErr == nil {
+			return
+		}
+
+		_, err := client.AbortMultipartUpload(context.WithoutCancel(ctx),
+			&s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(in.Bucket),
+				Key:      aws.String(in.Key),
+				UploadId: created.UploadId,
+			})
+		if err != nil {
+			outErr = errors.Join(outErr,
+				fmt.Errorf("abort multipart upload: %w", err))
+		}
+	}()
+
+	size := aws.ToInt64(head.ContentLength)
+	partSize := max(minCopyPartSize, (size+maxCopyParts-1)/maxCopyParts)
+
+	var parts []types.CompletedPart
+
+	for start := int64(0); start < size; start += partSize {
+		partNumber := aws.Int32(int32(len(parts) + 1)) //nolint: gosec
+		end := min(start+partSize, size) - 1
+
+		res, err := client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:            aws.String(in.Bucket),
+			Key:               aws.String(in.Key),
+			UploadId:          created.UploadId,
+			PartNumber:        partNumber,
+			CopySource:        aws.String(source),
+			CopySourceRange:   aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+			CopySourceIfMatch: head.ETag,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("copy part %d: %w",
+				aws.ToInt32(partNumber), err)
+		}
+
+		if res.CopyPartResult == nil {
+			return nil, fmt.Errorf("no result for part %d",
+				aws.ToInt32(partNumber))
+		}
+
+		parts = append(parts, types.CompletedPart{
+			PartNumber:     partNumber,
+			ETag:           res.CopyPartResult.ETag,
+			ChecksumSHA256: res.CopyPartResult.ChecksumSHA256,
+		})
+	}
+
+	done, err := client.CompleteMultipartUpload(ctx,
+		&s3.CompleteMultipartUploadInput{
+			Bucket:   aws.String(in.Bucket),
+			Key:      aws.String(in.Key),
+			UploadId: created.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: parts,
+			},
+		})
+	if err != nil {
+		return nil, fmt.Errorf("complete multipart upload: %w", err)
+	}
+
+	return &s3CopyResult{
+		VersionID: aws.ToString(done.VersionId),
+	}, nil
+}
+
+// Part size and part count limits for uploading objects in parts.
+const (
+	minUploadPartSize int64 = 64 << 20
+	maxUploadParts    int64 = 10000
+)
+
+// s3UploadInput describes an object upload for uploadS3Object.
+type s3UploadInput struct {
+	Bucket      string
+	Key         string
+	ContentType string
+	Metadata    map[string]string
+	Body        io.ReaderAt
+	Size        int64
+}
+
+// uploadS3Object uploads an object with a multipart upload, so that the
+// object size isn't limited by what a single PutObject request accepts. The
+// parts are uploaded with SHA-256 checksums. Empty objects can't be uploaded.
+func uploadS3Object(
+	ctx context.Context, client *s3.Client, in s3UploadInput,
+) (outErr error) {
+	created, err := client.CreateMultipartUpload(ctx,
+		&s3.CreateMultipartUploadInput{
+			Bucket:            aws.String(in.Bucket),
+			Key:               aws.String(in.Key),
+			ContentType:       aws.String(in.ContentType),
+			Metadata:          in.Metadata,
+			ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+		})
+	if err != nil {
+		return fmt.Errorf("create multipart upload: %w", err)
+	}
+
+	defer func() {
+		if outErr == nil {
+			return
+		}
+
+		_, err := client.AbortMultipartUpload(context.WithoutCancel(ctx),
+			&s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(in.Bucket),
+				Key:      aws.String(in.Key),
+				UploadId: created.UploadId,
+			})
+		if err != nil {
+			outErr = errors.Join(outErr,
+				fmt.Errorf("abort multipart upload: %w", err))
+		}
+	}()
+
+	partSize := max(minUploadPartSize,
+		(in.Size+maxUploadParts-1)/maxUploadParts)
+
+	var parts []types.CompletedPart
+
+	for start := int64(0); start < in.Size; start += partSize {
+		partNumber := aws.Int32(int32(len(parts) + 1)) //nolint: gosec
+		length := min(partSize, in.Size-start)
+
+		res, err := client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:            aws.String(in.Bucket),
+			Key:               aws.String(in.Key),
+			UploadId:          created.UploadId,
+			PartNumber:        partNumber,
+			Body:              io.NewSectionReader(in.Body, start, length),
+			ContentLength:     aws.Int64(length),
+			ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+		})
+		if err != nil {
+			return fmt.Errorf("upload part %d: %w",
+				aws.ToInt32(partNumber), err)
+		}
+
+		parts = append(parts, types.CompletedPart{
+			PartNumber:     partNumber,
+			ETag:           res.ETag,
+			ChecksumSHA256: res.ChecksumSHA256,
+		})
+	}
+
+	_, err = client.CompleteMultipartUpload(ctx,
+		&s3.CompleteMultipartUploadInput{
+			Bucket:   aws.String(in.Bucket),
+			Key:      aws.String(in.Key),
+			UploadId: created.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: parts,
+			},
+		})
+	if err != nil {
+		return fmt.Errorf("complete multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+// isS3PreconditionFailed checks if a request failed because of a conditional
+// header, like If-Match or x-amz-copy-source-if-match.
+func isS3PreconditionFailed(err error) bool {
+	var (
+		ae smithy.APIError
+		re *smithyhttp.ResponseError
+	)
+
+	if errors.As(err, &ae) && ae.ErrorCode() == "PreconditionFailed" {
+		return true
+	}
+
+	return errors.As(err, &re) &&
+		re.HTTPStatusCode() == http.StatusPreconditionFailed
+}
@@ -91,6 +91,7 @@ func BootstrapGeneration(ctx context.Context, store SchemaStore) error {
 	_, err = store.RegisterGeneration(ctx, RegisterGenerationStoreRequest{
 		Schemas:    genSchemas,
 		Activation: GenerationStatusActive,
+		Force:      true,
 	})
 	if err != nil {
 		return fmt.Errorf("register bootstrap generation: %w", err)
@@ -2,14 +2,13 @@ package repository
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/google/uuid"
 	rpc_newsdoc "github.com/ttab/elephant-api/newsdoc"
 	"github.com/ttab/elephant-api/repository"
 	"github.com/ttab/newsdoc"
@@ -17,10 +16,15 @@ import (
 	"github.com/twitchtv/twirp"
 )
 
-func NewSchemasService(logger *slog.Logger, store SchemaStore) *SchemasService {
+// NewSchemasService creates a new schemas service. The archive is optional
+// and is used to compare generations that no longer are in the database.
+func NewSchemasService(
+	logger *slog.Logger, store SchemaStore, archive *GenerationArchive,
+) *SchemasService {
 	return &SchemasService{
-		logger: logger,
-		store:  store,
+		logger:  logger,
+		store:   store,
+		archive: archive,
 	}
 }
 
@@ -28,8 +32,9 @@ func NewSchemasService(logger *slog.Logger, store SchemaStore) *SchemasService {
 var _ repository.Schemas = &SchemasService{}
 
 type SchemasService struct {
-	logger *slog.Logger
-	store  SchemaStore
+	logger  *slog.Logger
+	store   SchemaStore
+	archive *GenerationArchive
 }
 
 // GetDocumentTypes implements repository.Schemas.
@@ -78,8 +83,25 @@ func (a *SchemasService) ConfigureType(
 		return nil, twirp.RequiredArgumentError("configuration")
 	}
 
-	err = a.store.ConfigureType(ctx, req.Type,
-		typeConfigurationFromRPC(req.Configuration))
+	conf := typeConfigurationFromRPC(req.Configuration)
+
+	// Read auditing, exemplar sampling and attachment archiving aren't
+	// part of the RPC type configuration, so we carry over the current
+	// settings.
+	current, err := a.store.GetTypeConfiguration(ctx, req.Type)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"read current type configuration: %v", err)
+	default:
+		conf.ReadAudit = current.ReadAudit
+		conf.ExemplarSampling = current.ExemplarSampling
+		conf.ArchiveAttachments = current.ArchiveAttachments
+	}
+
+	err = a.store.ConfigureType(ctx, req.Type, conf)
 	if err != nil {
 		return nil, twirp.InternalErrorf("store type configuration: %v", err)
 	}
@@ -415,9 +437,6 @@ func (a *SchemasService) RegisterGeneration(
 				"canonicalize exemplar: %v", cErr)
 		}
 
-		h := sha256.Sum256(canonical)
-		versionHash := "sha256:" + hex.EncodeToString(h[:])
-
 		name := doc.URI
 		if name == "" {
 			name = doc.UUID
@@ -427,7 +446,7 @@ func (a *SchemasService) RegisterGeneration(
 			Name:     name,
 			DocType:  doc.Type,
 			Document: canonical,
-			Version:  versionHash,
+			Version:  exemplarVersionHash(canonical),
 		})
 	}
 
@@ -473,7 +492,11 @@ func (a *SchemasService) RegisterGeneration(
 		Activation: activation,
 		Exemplars:  exemplars,
 	})
-	if err != nil {
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeFailedPrecondition):
+		return nil, twirp.FailedPrecondition.Error(err.Error())
+	case err != nil:
 		return nil, fmt.Errorf("register generation: %w", err)
 	}
 
@@ -509,18 +532,32 @@ func (a *SchemasService) SetActive(
 			"activation", "invalid activation value")
 	}
 
-	err = a.store.SetGenerationStatus(ctx, req.GenerationId, activation)
+	err = a.setGenerationStatus(ctx, req.GenerationId, activation, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repository.SetActiveSchemasResponse{}, nil
+}
+
+func (a *SchemasService) setGenerationStatus(
+	ctx context.Context, id int64, activation SchemaGenerationStatus,
+	force bool,
+) error {
+	err := a.store.SetGenerationStatus(ctx, id, activation, force)
 
 	switch {
 	case IsDocStoreErrorCode(err, ErrCodeBadRequest):
-		return nil, twirp.InvalidArgument.Error(err.Error())
+		return twirp.InvalidArgument.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeFailedPrecondition):
+		return twirp.FailedPrecondition.Error(err.Error())
 	case IsDocStoreErrorCode(err, ErrCodeNotFound):
-		return nil, twirp.NotFound.Error(err.Error())
+		return twirp.NotFound.Error(err.Error())
 	case err != nil:
-		return nil, fmt.Errorf("set generation status: %w", err)
+		return fmt.Errorf("set generation status: %w", err)
 	}
 
-	return &repository.SetActiveSchemasResponse{}, nil
+	return nil
 }
 
 // ListGenerations implements repository.Schemas.
@@ -673,3 +710,895 @@ func (a *SchemasService) UpdateDeprecation(
 
 	return &repository.UpdateDeprecationResponse{}, nil
 }
+
+// ExtensionMethods implements ExtensionProvider.
+func (a *SchemasService) ExtensionMethods() ExtensionMethods {
+	return ExtensionMethods{
+		"GetACLInheritance": JSONMethod(a.GetACLInheritance),
+		"SetACLInheritance": JSONMethod(a.SetACLInheritance),
+		"GetTypeReadAudit":  JSONMethod(a.GetTypeReadAudit),
+		"SetTypeReadAudit":  JSONMethod(a.SetTypeReadAudit),
+
+		"GetTypeAttachmentArchiving": JSONMethod(a.GetTypeAttachmentArchiving),
+		"SetTypeAttachmentArchiving": JSONMethod(a.SetTypeAttachmentArchiving),
+
+		"StartRevalidation":       JSONMethod(a.StartRevalidation),
+		"GetRevalidation":         JSONMethod(a.GetRevalidation),
+		"GetRevalidationFailures": JSONMethod(a.GetRevalidationFailures),
+
+		"SetGenerationTransforms": JSONMethod(a.SetGenerationTransforms),
+		"GetGenerationTransforms": JSONMethod(a.GetGenerationTransforms),
+		"StartUpgrade":            JSONMethod(a.StartUpgrade),
+		"GetUpgrade":              JSONMethod(a.GetUpgrade),
+
+		"GetDeprecationUsage":    JSONMethod(a.GetDeprecationUsage),
+		"GetDeprecatedDocuments": JSONMethod(a.GetDeprecatedDocuments),
+
+		"CompareGenerations": JSONMethod(a.CompareGenerations),
+
+		"GetTypeExemplarSampling": JSONMethod(a.GetTypeExemplarSampling),
+		"SetTypeExemplarSampling": JSONMethod(a.SetTypeExemplarSampling),
+		"ActivateGeneration":      JSONMethod(a.ActivateGeneration),
+	}
+}
+
+type GetACLInheritanceRequest struct {
+	Type string `json:"type"`
+}
+
+type GetACLInheritanceResponse struct {
+	Rules []ACLInheritanceRule `json:"rules"`
+}
+
+// GetACLInheritance returns the ACL inheritance rules for a document type.
+func (a *SchemasService) GetACLInheritance(
+	ctx context.Context, req *GetACLInheritanceRequest,
+) (*GetACLInheritanceResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin, ScopeSchemaRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type == "" {
+		return nil, twirp.RequiredArgumentError("type")
+	}
+
+	rules, err := a.store.GetACLInheritance(ctx, req.Type)
+	if err != nil {
+		return nil, twirp.InternalErrorf(
+			"get ACL inheritance rules: %v", err)
+	}
+
+	return &GetACLInheritanceResponse{
+		Rules: rules,
+	}, nil
+}
+
+type SetACLInheritanceRequest struct {
+	Type  string               `json:"type"`
+	Rules []ACLInheritanceRule `json:"rules"`
+}
+
+type SetACLInheritanceResponse struct{}
+
+// SetACLInheritance replaces the ACL inheritance rules for a document type.
+// The rules are applied to documents as new versions are written.
+func (a *SchemasService) SetACLInheritance(
+	ctx context.Context, req *SetACLInheritanceRequest,
+) (*SetACLInheritanceResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type == "" {
+		return nil, twirp.RequiredArgumentError("type")
+	}
+
+	for i, r := range req.Rules {
+		if r.Rel == "" {
+			return nil, twirp.RequiredArgumentError(
+				fmt.Sprintf("rules.%d.rel", i))
+		}
+
+		if len(r.Permissions) == 0 {
+			return nil, twirp.RequiredArgumentError(
+				fmt.Sprintf("rules.%d.permissions", i))
+		}
+
+		for _, p := range r.Permissions {
+			if !IsValidPermission(Permission(p)) {
+				return nil, twirp.InvalidArgumentError(
+					fmt.Sprintf("rules.%d.permissions", i),
+					fmt.Sprintf("invalid permission %q", p))
+			}
+		}
+	}
+
+	err = a.store.SetACLInheritance(ctx, req.Type, req.Rules)
+	if IsDocStoreErrorCode(err, ErrCodeBadRequest) {
+		return nil, twirp.InvalidArgumentError("rules", err.Error())
+	} else if err != nil {
+		return nil, twirp.InternalErrorf(
+			"store ACL inheritance rules: %v", err)
+	}
+
+	return &SetACLInheritanceResponse{}, nil
+}
+
+type GetTypeReadAuditRequest struct {
+	Type string `json:"type"`
+}
+
+type GetTypeReadAuditResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// GetTypeReadAudit returns whether read access to documents of a type is
+// audited.
+func (a *SchemasService) GetTypeReadAudit(
+	ctx context.Context, req *GetTypeReadAuditRequest,
+) (*GetTypeReadAuditResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin, ScopeSchemaRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type == "" {
+		return nil, twirp.RequiredArgumentError("type")
+	}
+
+	conf, err := a.store.GetTypeConfiguration(ctx, req.Type)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return &GetTypeReadAuditResponse{}, nil
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"read type configuration: %v", err)
+	}
+
+	return &GetTypeReadAuditResponse{
+		Enabled: conf.ReadAudit,
+	}, nil
+}
+
+type SetTypeReadAuditRequest struct {
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+}
+
+type SetTypeReadAuditResponse struct{}
+
+// SetTypeReadAudit enables or disables auditing of read access to documents
+// of a type.
+func (a *SchemasService) SetTypeReadAudit(
+	ctx context.Context, req *SetTypeReadAuditRequest,
+) (*SetTypeReadAuditResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type == "" {
+		return nil, twirp.RequiredArgumentError("type")
+	}
+
+	var conf TypeConfiguration
+
+	current, err := a.store.GetTypeConfiguration(ctx, req.Type)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"read current type configuration: %v", err)
+	default:
+		conf = *current
+	}
+
+	conf.ReadAudit = req.Enabled
+
+	err = a.store.ConfigureType(ctx, req.Type, conf)
+	if err != nil {
+		return nil, twirp.InternalErrorf("store type configuration: %v", err)
+	}
+
+	return &SetTypeReadAuditResponse{}, nil
+}
+
+type GetTypeAttachmentArchivingRequest struct {
+	Type string `json:"type"`
+}
+
+type GetTypeAttachmentArchivingResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// GetTypeAttachmentArchiving returns whether every version of the objects
+// attached to documents of a type is archived.
+func (a *SchemasService) GetTypeAttachmentArchiving(
+	ctx context.Context, req *GetTypeAttachmentArchivingRequest,
+) (*GetTypeAttachmentArchivingResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin, ScopeSchemaRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type == "" {
+		return nil, twirp.RequiredArgumentError("type")
+	}
+
+	conf, err := a.store.GetTypeConfiguration(ctx, req.Type)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return &GetTypeAttachmentArchivingResponse{}, nil
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"read type configuration: %v", err)
+	}
+
+	return &GetTypeAttachmentArchivingResponse{
+		Enabled: conf.ArchiveAttachments,
+	}, nil
+}
+
+type SetTypeAttachmentArchivingRequest struct {
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+}
+
+type SetTypeAttachmentArchivingResponse struct{}
+
+// SetTypeAttachmentArchiving enables or disables archiving of every version
+// of the objects attached to documents of a type. Only objects attached after
+// archiving has been enabled are archived.
+func (a *SchemasService) SetTypeAttachmentArchiving(
+	ctx context.Context, req *SetTypeAttachmentArchivingRequest,
+) (*SetTypeAttachmentArchivingResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type == "" {
+		return nil, twirp.RequiredArgumentError("type")
+	}
+
+	var conf TypeConfiguration
+
+	current, err := a.store.GetTypeConfiguration(ctx, req.Type)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"read current type configuration: %v", err)
+	default:
+		conf = *current
+	}
+
+	conf.ArchiveAttachments = req.Enabled
+
+	err = a.store.ConfigureType(ctx, req.Type, conf)
+	if err != nil {
+		return nil, twirp.InternalErrorf("store type configuration: %v", err)
+	}
+
+	return &SetTypeAttachmentArchivingResponse{}, nil
+}
+
+type StartRevalidationRequest struct {
+	GenerationID int64    `json:"generation_id"`
+	Types        []string `json:"types,omitempty"`
+}
+
+type StartRevalidationResponse struct {
+	Revalidation *SchemaRevalidation `json:"revalidation"`
+}
+
+// StartRevalidation starts a background revalidation of the current versions
+// of stored documents against a pending or deactivated schema generation.
+// Starting a revalidation of a generation that already has one restarts it.
+func (a *SchemasService) StartRevalidation(
+	ctx context.Context, req *StartRevalidationRequest,
+) (*StartRevalidationResponse, error) {
+	auth, err := RequireAnyScope(ctx, ScopeSchemaAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GenerationID == 0 {
+		return nil, twirp.RequiredArgumentError("generation_id")
+	}
+
+	for i, t := range req.Types {
+		if t == "" {
+			return nil, twirp.InvalidArgumentError(
+				fmt.Sprintf("types.%d", i), "cannot be empty")
+		}
+	}
+
+	rv, err := a.store.StartRevalidation(ctx, StartRevalidationStoreRequest{
+		GenerationID: req.GenerationID,
+		Types:        req.Types,
+		CreatedBy:    auth.Claims.Subject,
+	})
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.NotFound.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeFailedPrecondition):
+		return nil, twirp.FailedPrecondition.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeBadRequest):
+		return nil, twirp.InvalidArgumentError("types", err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf("start revalidation: %v", err)
+	}
+
+	return &StartRevalidationResponse{
+		Revalidation: rv,
+	}, nil
+}
+
+type GetRevalidationRequest struct {
+	GenerationID int64 `json:"generation_id"`
+}
+
+type GetRevalidationResponse struct {
+	Revalidation *SchemaRevalidation `json:"revalidation"`
+}
+
+// GetRevalidation returns the progress of the revalidation of a generation.
+func (a *SchemasService) GetRevalidation(
+	ctx context.Context, req *GetRevalidationRequest,
+) (*GetRevalidationResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin, ScopeSchemaRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GenerationID == 0 {
+		return nil, twirp.RequiredArgumentError("generation_id")
+	}
+
+	rv, err := a.store.GetRevalidation(ctx, req.GenerationID)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.NotFound.Error(err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf("read revalidation: %v", err)
+	}
+
+	return &GetRevalidationResponse{
+		Revalidation: rv,
+	}, nil
+}
+
+type GetRevalidationFailuresRequest struct {
+	GenerationID int64  `json:"generation_id"`
+	After        string `json:"after,omitempty"`
+	Limit        int64  `json:"limit,omitempty"`
+}
+
+type GetRevalidationFailuresResponse struct {
+	Failures []RevalidationFailure `json:"failures"`
+	Next     string                `json:"next,omitempty"`
+}
+
+// GetRevalidationFailures returns the documents that failed validation in the
+// revalidation of a generation, ordered by document UUID.
+func (a *SchemasService) GetRevalidationFailures(
+	ctx context.Context, req *GetRevalidationFailuresRequest,
+) (*GetRevalidationFailuresResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin, ScopeSchemaRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GenerationID == 0 {
+		return nil, twirp.RequiredArgumentError("generation_id")
+	}
+
+	var after uuid.UUID
+
+	if req.After != "" {
+		after, err = uuid.Parse(req.After)
+		if err != nil {
+			return nil, twirp.InvalidArgumentError("after", err.Error())
+		}
+	}
+
+	limit := req.Limit
+
+	switch {
+	case limit == 0:
+		limit = 100
+	case limit < 0 || limit > 1000:
+		return nil, twirp.InvalidArgumentError(
+			"limit", "must be between 1 and 1000")
+	}
+
+	failures, err := a.store.GetRevalidationFailures(ctx, RevalidationFailureQuery{
+		GenerationID: req.GenerationID,
+		After:        after,
+		Limit:        limit,
+	})
+	if err != nil {
+		return nil, twirp.InternalErrorf(
+			"read revalidation failures: %v", err)
+	}
+
+	res := GetRevalidationFailuresResponse{
+		Failures: failures,
+	}
+
+	if int64(len(failures)) == limit {
+		res.Next = failures[len(failures)-1].UUID.String()
+	}
+
+	return &res, nil
+}
+
+type SetGenerationTransformsRequest struct {
+	GenerationID int64               `json:"generation_id"`
+	Transforms   []DocumentTransform `json:"transforms"`
+}
+
+type SetGenerationTransformsResponse struct{}
+
+// SetGenerationTransforms sets the transforms that bring documents written in
+// the shape of earlier generations up to date with a schema generation. The
+// transforms replace any previously set transforms.
+func (a *SchemasService) SetGenerationTransforms(
+	ctx context.Context, req *SetGenerationTransformsRequest,
+) (*SetGenerationTransformsResponse, error) {
+	auth, err := RequireAnyScope(ctx, ScopeSchemaAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GenerationID == 0 {
+		return nil, twirp.RequiredArgumentError("generation_id")
+	}
+
+	err = ValidateTransforms(req.Transforms)
+	if err != nil {
+		return nil, twirp.InvalidArgumentError("transforms", err.Error())
+	}
+
+	transforms := req.Transforms
+	if transforms == nil {
+		transforms = []DocumentTransform{}
+	}
+
+	err = a.store.SetGenerationTransforms(ctx,
+		req.GenerationID, transforms, auth.Claims.Subject)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.NotFound.Error(err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf("store transforms: %v", err)
+	}
+
+	return &SetGenerationTransformsResponse{}, nil
+}
+
+type GetGenerationTransformsRequest struct {
+	GenerationID int64 `json:"generation_id"`
+}
+
+type GetGenerationTransformsResponse struct {
+	Transforms []DocumentTransform `json:"transforms"`
+}
+
+// GetGenerationTransforms returns the transforms of a schema generation.
+func (a *SchemasService) GetGenerationTransforms(
+	ctx context.Context, req *GetGenerationTransformsRequest,
+) (*GetGenerationTransformsResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin, ScopeSchemaRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GenerationID == 0 {
+		return nil, twirp.RequiredArgumentError("generation_id")
+	}
+
+	transforms, err := a.store.GetGenerationTransforms(ctx, req.GenerationID)
+	if err != nil {
+		return nil, twirp.InternalErrorf("read transforms: %v", err)
+	}
+
+	if transforms == nil {
+		transforms = []DocumentTransform{}
+	}
+
+	return &GetGenerationTransformsResponse{
+		Transforms: transforms,
+	}, nil
+}
+
+type StartUpgradeRequest struct {
+	Types []string `json:"types,omitempty"`
+}
+
+type StartUpgradeResponse struct {
+	Upgrade *SchemaUpgrade `json:"upgrade"`
+}
+
+// StartUpgrade starts a background job that writes new versions of the
+// documents that are changed by the transforms of the active schema
+// generation. Starting an upgrade when one already exists for the generation
+// restarts it.
+func (a *SchemasService) StartUpgrade(
+	ctx context.Context, req *StartUpgradeRequest,
+) (*StartUpgradeResponse, error) {
+	auth, err := RequireAnyScope(ctx, ScopeSchemaAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, t := range req.Types {
+		if t == "" {
+			return nil, twirp.InvalidArgumentError(
+				fmt.Sprintf("types.%d", i), "cannot be empty")
+		}
+	}
+
+	up, err := a.store.StartUpgrade(ctx, StartUpgradeStoreRequest{
+		Types:     req.Types,
+		CreatedBy: auth.Claims.Subject,
+	})
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeFailedPrecondition):
+		return nil, twirp.FailedPrecondition.Error(err.Error())
+	case IsDocStoreErrorCode(err, ErrCodeBadRequest):
+		return nil, twirp.InvalidArgumentError("types", err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf("start upgrade: %v", err)
+	}
+
+	return &StartUpgradeResponse{
+		Upgrade: up,
+	}, nil
+}
+
+type GetUpgradeRequest struct {
+	GenerationID int64 `json:"generation_id"`
+}
+
+type GetUpgradeResponse struct {
+	Upgrade *SchemaUpgrade `json:"upgrade"`
+}
+
+// GetUpgrade returns the progress of the document upgrade of a generation.
+func (a *SchemasService) GetUpgrade(
+	ctx context.Context, req *GetUpgradeRequest,
+) (*GetUpgradeResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin, ScopeSchemaRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GenerationID == 0 {
+		return nil, twirp.RequiredArgumentError("generation_id")
+	}
+
+	up, err := a.store.GetUpgrade(ctx, req.GenerationID)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.NotFound.Error(err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf("read upgrade: %v", err)
+	}
+
+	return &GetUpgradeResponse{
+		Upgrade: up,
+	}, nil
+}
+
+type GetDeprecationUsageRequest struct{}
+
+type GetDeprecationUsageResponse struct {
+	Deprecations []DeprecationUsage `json:"deprecations"`
+}
+
+// GetDeprecationUsage lists the deprecations together with the number of
+// documents whose current version uses them. Labels that have been
+// encountered but haven't been configured are included as not enforced.
+//
+// This is an extension method rather than a part of GetDeprecations as the
+// Deprecation message of the API has no field for the usage, and
+// GetDeprecations is called by clients that only need the labels, while
+// counting the usage requires a scan of the usage table.
+func (a *SchemasService) GetDeprecationUsage(
+	ctx context.Context, _ *GetDeprecationUsageRequest,
+) (*GetDeprecationUsageResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := a.store.GetDeprecationUsage(ctx)
+	if err != nil {
+		return nil, twirp.InternalErrorf(
+			"read deprecation usage: %v", err)
+	}
+
+	return &GetDeprecationUsageResponse{
+		Deprecations: usage,
+	}, nil
+}
+
+type GetDeprecatedDocumentsRequest struct {
+	Label string `json:"label"`
+	After string `json:"after,omitempty"`
+	Limit int64  `json:"limit,omitempty"`
+}
+
+type GetDeprecatedDocumentsResponse struct {
+	Documents []DeprecatedDocument `json:"documents"`
+	Next      string               `json:"next,omitempty"`
+}
+
+// GetDeprecatedDocuments lists the documents whose current version uses a
+// deprecation, ordered by document UUID.
+func (a *SchemasService) GetDeprecatedDocuments(
+	ctx context.Context, req *GetDeprecatedDocumentsRequest,
+) (*GetDeprecatedDocumentsResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Label == "" {
+		return nil, twirp.RequiredArgumentError("label")
+	}
+
+	var after uuid.UUID
+
+	if req.After != "" {
+		after, err = uuid.Parse(req.After)
+		if err != nil {
+			return nil, twirp.InvalidArgumentError("after", err.Error())
+		}
+	}
+
+	limit := req.Limit
+
+	switch {
+	case limit == 0:
+		limit = 100
+	case limit < 0 || limit > 1000:
+		return nil, twirp.InvalidArgumentError(
+			"limit", "must be between 1 and 1000")
+	}
+
+	docs, err := a.store.GetDeprecatedDocuments(ctx, DeprecatedDocumentQuery{
+		Label: req.Label,
+		After: after,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, twirp.InternalErrorf(
+			"read deprecated documents: %v", err)
+	}
+
+	res := GetDeprecatedDocumentsResponse{
+		Documents: docs,
+	}
+
+	if int64(len(docs)) == limit {
+		res.Next = docs[len(docs)-1].UUID.String()
+	}
+
+	return &res, nil
+}
+
+type CompareGenerationsRequest struct {
+	FromGenerationID int64 `json:"from_generation_id"`
+	// ToGenerationID defaults to the active generation.
+	ToGenerationID int64 `json:"to_generation_id,omitempty"`
+	RunExemplars   bool  `json:"run_exemplars,omitempty"`
+}
+
+type CompareGenerationsResponse struct {
+	Comparison *GenerationComparison `json:"comparison"`
+}
+
+// CompareGenerations lists the constraint changes between two schema
+// generations and classifies them as backward compatible or breaking.
+func (a *SchemasService) CompareGenerations(
+	ctx context.Context, req *CompareGenerationsRequest,
+) (*CompareGenerationsResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin, ScopeSchemaRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.FromGenerationID == 0 {
+		return nil, twirp.RequiredArgumentError("from_generation_id")
+	}
+
+	toID := req.ToGenerationID
+
+	if toID == 0 {
+		toID, err = a.store.GetActiveGenerationID(ctx)
+		if err != nil {
+			return nil, twirp.InternalErrorf(
+				"get active generation: %v", err)
+		}
+
+		if toID == 0 {
+			return nil, twirp.FailedPrecondition.Error(
+				"there is no active generation")
+		}
+	}
+
+	from, err := a.getGenerationSnapshot(ctx, req.FromGenerationID)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := a.getGenerationSnapshot(ctx, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison, err := CompareGenerations(ctx, from, to, req.RunExemplars)
+	if err != nil {
+		return nil, twirp.InternalErrorf("compare generations: %v", err)
+	}
+
+	return &CompareGenerationsResponse{
+		Comparison: comparison,
+	}, nil
+}
+
+// getGenerationSnapshot reads a generation from the database, falling back
+// to the archive for generations that have been removed.
+func (a *SchemasService) getGenerationSnapshot(
+	ctx context.Context, generationID int64,
+) (*GenerationSnapshot, error) {
+	snap, err := a.store.GetGenerationSnapshot(ctx, generationID)
+	if IsDocStoreErrorCode(err, ErrCodeNotFound) && a.archive != nil {
+		snap, err = a.archive.GetGenerationSnapshot(ctx, generationID)
+	}
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.NotFound.Error(err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"read generation %d: %v", generationID, err)
+	}
+
+	return snap, nil
+}
+
+type GetTypeExemplarSamplingRequest struct {
+	Type string `json:"type"`
+}
+
+type GetTypeExemplarSamplingResponse struct {
+	Sampling *ExemplarSampling `json:"sampling,omitempty"`
+}
+
+// GetTypeExemplarSampling returns the exemplar sampling configuration of a
+// document type.
+func (a *SchemasService) GetTypeExemplarSampling(
+	ctx context.Context, req *GetTypeExemplarSamplingRequest,
+) (*GetTypeExemplarSamplingResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin, ScopeSchemaRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type == "" {
+		return nil, twirp.RequiredArgumentError("type")
+	}
+
+	conf, err := a.store.GetTypeConfiguration(ctx, req.Type)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return &GetTypeExemplarSamplingResponse{}, nil
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"read type configuration: %v", err)
+	}
+
+	return &GetTypeExemplarSamplingResponse{
+		Sampling: conf.ExemplarSampling,
+	}, nil
+}
+
+type SetTypeExemplarSamplingRequest struct {
+	Type string `json:"type"`
+	// Sampling disables exemplar collection for the type when omitted.
+	Sampling *ExemplarSampling `json:"sampling,omitempty"`
+}
+
+type SetTypeExemplarSamplingResponse struct{}
+
+// SetTypeExemplarSampling configures the collection of exemplars from the
+// stored documents of a type.
+func (a *SchemasService) SetTypeExemplarSampling(
+	ctx context.Context, req *SetTypeExemplarSamplingRequest,
+) (*SetTypeExemplarSamplingResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type == "" {
+		return nil, twirp.RequiredArgumentError("type")
+	}
+
+	if req.Sampling != nil {
+		err := req.Sampling.Validate()
+		if err != nil {
+			return nil, twirp.InvalidArgumentError("sampling", err.Error())
+		}
+	}
+
+	var conf TypeConfiguration
+
+	current, err := a.store.GetTypeConfiguration(ctx, req.Type)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+	case err != nil:
+		return nil, twirp.InternalErrorf(
+			"read current type configuration: %v", err)
+	default:
+		conf = *current
+	}
+
+	conf.ExemplarSampling = req.Sampling
+
+	err = a.store.ConfigureType(ctx, req.Type, conf)
+	if err != nil {
+		return nil, twirp.InternalErrorf("store type configuration: %v", err)
+	}
+
+	return &SetTypeExemplarSamplingResponse{}, nil
+}
+
+type ActivateGenerationRequest struct {
+	GenerationID int64 `json:"generation_id"`
+	// Force activates the generation even if it hasn't been revalidated
+	// or exemplars fail validation against it.
+	Force bool `json:"force,omitempty"`
+}
+
+type ActivateGenerationResponse struct{}
+
+// ActivateGeneration activates a schema generation like SetActive, with the
+// option to override the revalidation and exemplar validation checks.
+func (a *SchemasService) ActivateGeneration(
+	ctx context.Context, req *ActivateGenerationRequest,
+) (*ActivateGenerationResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GenerationID == 0 {
+		return nil, twirp.RequiredArgumentError("generation_id")
+	}
+
+	err = a.setGenerationStatus(ctx, req.GenerationID,
+		GenerationStatusActive, req.Force)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ActivateGenerationResponse{}, nil
+}
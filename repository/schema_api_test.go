@@ -2,7 +2,10 @@ package repository_test
 
 import (
 	"log/slog"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -142,6 +145,222 @@ func TestDeprecations(t *testing.T) {
 	}
 }
 
+func TestDeprecationUsage(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	t.Parallel()
+
+	logger := slog.New(test.NewLogHandler(t, slog.LevelError))
+
+	dataDir := filepath.Join("..", "testdata", "TestDeprecations")
+
+	schemas, err := repository.LoadSchemasFromDir(
+		dataDir, "v1.0.0", "deprecation")
+	test.Must(t, err, "load deprecation schema")
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		Schemas:         schemas,
+		ConfigDirectory: dataDir,
+		NoCoreSchemas:   true,
+	})
+
+	schemaExt := tc.ExtensionClient(t, rpc_repository.SchemasPathPrefix,
+		itest.StandardClaims(t, "schema_admin"))
+
+	documentsClient := tc.DocumentsClient(t, itest.StandardClaims(t, "doc_write"))
+
+	ctx := t.Context()
+
+	doc := &newsdoc.Document{
+		Uuid: "5a7a4c4e-5d6b-4a47-8b0e-2b1d3e0a9c11",
+		Type: "test/deprecation",
+		Uri:  "test://usage/1",
+		Meta: []*newsdoc.Block{
+			{
+				Type: "test/meta",
+				Data: map[string]string{
+					"value": "2",
+				},
+			},
+		},
+		Language: "en",
+	}
+
+	_, err = documentsClient.Update(ctx, &rpc_repository.UpdateRequest{
+		Uuid:     doc.Uuid,
+		Document: doc,
+	})
+	test.Must(t, err, "create a document that uses a deprecation")
+
+	_, err = documentsClient.Update(ctx, &rpc_repository.UpdateRequest{
+		Uuid: "0c3f2b8e-8d3a-4f54-9d43-6c1f7a2e5b22",
+		Document: &newsdoc.Document{
+			Uuid:     "0c3f2b8e-8d3a-4f54-9d43-6c1f7a2e5b22",
+			Type:     "test/deprecation",
+			Uri:      "test://usage/2",
+			Language: "en",
+		},
+	})
+	test.Must(t, err, "create a document without deprecations")
+
+	var usage repository.GetDeprecationUsageResponse
+
+	err = schemaExt.Call(ctx, "GetDeprecationUsage",
+		repository.GetDeprecationUsageRequest{}, &usage)
+	test.Must(t, err, "get deprecation usage")
+
+	test.EqualDiff(t, []repository.DeprecationUsage{
+		{
+			Label:     "data-value",
+			Documents: 1,
+		},
+	}, usage.Deprecations, "count the documents using the deprecation")
+
+	var docs repository.GetDeprecatedDocumentsResponse
+
+	err = schemaExt.Call(ctx, "GetDeprecatedDocuments",
+		repository.GetDeprecatedDocumentsRequest{
+			Label: "data-value",
+		}, &docs)
+	test.Must(t, err, "get deprecated documents")
+
+	test.Equal(t, 1, len(docs.Documents), "get one document")
+	test.Equal(t, doc.Uuid, docs.Documents[0].UUID.String(),
+		"get the document that uses the deprecation")
+	test.Equal(t, int64(1), docs.Documents[0].Version,
+		"get the version that uses the deprecation")
+
+	doc.Meta[0].Data = nil
+	doc.Meta[0].Value = "2"
+
+	_, err = documentsClient.Update(ctx, &rpc_repository.UpdateRequest{
+		Uuid:     doc.Uuid,
+		Document: doc,
+	})
+	test.Must(t, err, "stop using the deprecation")
+
+	err = schemaExt.Call(ctx, "GetDeprecatedDocuments",
+		repository.GetDeprecatedDocumentsRequest{
+			Label: "data-value",
+		}, &docs)
+	test.Must(t, err, "get deprecated documents after update")
+
+	test.Equal(t, 0, len(docs.Documents),
+		"only list documents whose current version uses the deprecation")
+}
+
+func TestDeprecationUsageScan(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	t.Parallel()
+
+	logger := slog.New(test.NewLogHandler(t, slog.LevelError))
+
+	dataDir := filepath.Join("..", "testdata", "TestDeprecations")
+
+	schemas, err := repository.LoadSchemasFromDir(
+		dataDir, "v1.0.0", "deprecation")
+	test.Must(t, err, "load deprecation schema")
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		Schemas:            schemas,
+		ConfigDirectory:    dataDir,
+		NoCoreSchemas:      true,
+		RunDeprecationScan: true,
+	})
+
+	adminClaims := itest.StandardClaims(t, "schema_admin")
+
+	schemaClient := tc.SchemasClient(t, adminClaims)
+	schemaExt := tc.ExtensionClient(t, rpc_repository.SchemasPathPrefix,
+		adminClaims)
+
+	documentsClient := tc.DocumentsClient(t, itest.StandardClaims(t, "doc_write"))
+
+	ctx := t.Context()
+
+	docUUID := "7d0c5b1e-2f4a-4c8e-9b6d-3a5e1f2c4d33"
+
+	_, err = documentsClient.Update(ctx, &rpc_repository.UpdateRequest{
+		Uuid: docUUID,
+		Document: &newsdoc.Document{
+			Uuid: docUUID,
+			Type: "test/deprecation",
+			Uri:  "test://usage/scan",
+			Meta: []*newsdoc.Block{
+				{
+					Type: "test/meta",
+					Data: map[string]string{
+						"value": "2",
+					},
+				},
+			},
+			Language: "en",
+		},
+	})
+	test.Must(t, err, "create a document that uses a deprecation")
+
+	spec, err := os.ReadFile(filepath.Join(dataDir, "deprecation.json"))
+	test.Must(t, err, "read deprecation schema")
+
+	// Relabel the deprecation, documents that aren't written again only
+	// get the new label through the scan.
+	relabeled := strings.Replace(string(spec),
+		`"label": "data-value"`, `"label": "data-value-v2"`, 1)
+
+	gen, err := schemaClient.RegisterGeneration(ctx,
+		&rpc_repository.RegisterGenerationRequest{
+			Activation: rpc_repository.SchemaActivation_ACTIVATION_PENDING,
+			Schemas: []*rpc_repository.Schema{
+				{
+					Name:    "deprecation",
+					Version: "v1.1.0",
+					Spec:    relabeled,
+				},
+			},
+		})
+	test.Must(t, err, "register relabeled generation")
+
+	err = schemaExt.Call(ctx, "ActivateGeneration",
+		repository.ActivateGenerationRequest{
+			GenerationID: gen.GenerationId,
+			Force:        true,
+		}, &repository.ActivateGenerationResponse{})
+	test.Must(t, err, "activate relabeled generation")
+
+	want := []repository.DeprecationUsage{
+		{
+			Label:     "data-value-v2",
+			Documents: 1,
+		},
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+
+	for {
+		var usage repository.GetDeprecationUsageResponse
+
+		err = schemaExt.Call(ctx, "GetDeprecationUsage",
+			repository.GetDeprecationUsageRequest{}, &usage)
+		test.Must(t, err, "get deprecation usage")
+
+		if slices.Equal(want, usage.Deprecations) {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			test.EqualDiff(t, want, usage.Deprecations,
+				"record the usage of existing documents")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
 func TestVariantValidation(t *testing.T) {
 	if testing.Short() {
 		t.SkipNow()
@@ -0,0 +1,823 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/newsdoc"
+	"github.com/ttab/revisor"
+)
+
+// GenerationChangeKind describes how a constraint changed between two
+// schema generations.
+type GenerationChangeKind string
+
+const (
+	GenerationChangeAdded     GenerationChangeKind = "added"
+	GenerationChangeRemoved   GenerationChangeKind = "removed"
+	GenerationChangeTightened GenerationChangeKind = "tightened"
+	GenerationChangeLoosened  GenerationChangeKind = "loosened"
+	GenerationChangeChanged   GenerationChangeKind = "changed"
+)
+
+// GenerationChange is a difference in the constraints for a document type
+// between two schema generations. A breaking change can make documents that
+// were valid against the old generation invalid against the new one.
+type GenerationChange struct {
+	DocumentType string `json:"document_type"`
+	// Path to the changed constraint, f.ex. "meta[core/newsvalue].data.score".
+	// Empty for changes to the document type itself.
+	Path     string               `json:"path,omitempty"`
+	Kind     GenerationChangeKind `json:"kind"`
+	Breaking bool                 `json:"breaking"`
+	Details  []string             `json:"details,omitempty"`
+}
+
+// ExemplarResult is the outcome of validating an exemplar document against a
+// schema generation.
+type ExemplarResult struct {
+	Name    string   `json:"name"`
+	DocType string   `json:"doc_type"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// GenerationComparison describes the changes between two schema generations.
+type GenerationComparison struct {
+	From         int64              `json:"from"`
+	To           int64              `json:"to"`
+	FromArchived bool               `json:"from_archived,omitempty"`
+	ToArchived   bool               `json:"to_archived,omitempty"`
+	Compatible   bool               `json:"compatible"`
+	Changes      []GenerationChange `json:"changes"`
+	// Exemplars are the results of validating the exemplars of the
+	// "from" generation against the "to" generation.
+	Exemplars []ExemplarResult `json:"exemplars,omitempty"`
+}
+
+// GetGenerationSnapshot implements SchemaStore.
+func (s *PGDocStore) GetGenerationSnapshot(
+	ctx context.Context, generationID int64,
+) (*GenerationSnapshot, error) {
+	_, err := s.reader.GetSchemaGeneration(ctx, generationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, DocStoreErrorf(ErrCodeNotFound,
+			"generation %d not found", generationID)
+	} else if err != nil {
+		return nil, fmt.Errorf("get generation: %w", err)
+	}
+
+	rows, err := s.reader.GetSchemaGenerationSchemasWithSpec(ctx, generationID)
+	if err != nil {
+		return nil, fmt.Errorf("get generation schemas: %w", err)
+	}
+
+	snap := GenerationSnapshot{
+		ID:      generationID,
+		Schemas: make([]*Schema, len(rows)),
+	}
+
+	for i, row := range rows {
+		schema := Schema{
+			Name:    row.Name,
+			Version: row.Version,
+		}
+
+		err := json.Unmarshal(row.Spec, &schema.Specification)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal spec for %q: %w",
+				row.Name, err)
+		}
+
+		snap.Schemas[i] = &schema
+	}
+
+	snap.Exemplars, err = s.GetExemplars(ctx, generationID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snap, nil
+}
+
+// GenerationArchive reads schema generations from the archive bucket, for
+// generations that are no longer in the database.
+type GenerationArchive struct {
+	pool   *pgxpool.Pool
+	keys   SigningKeySet
+	reader *ArchiveReader
+}
+
+func NewGenerationArchive(
+	pool *pgxpool.Pool, client *s3.Client, bucket string,
+) *GenerationArchive {
+	ga := GenerationArchive{
+		pool: pool,
+	}
+
+	ga.reader = NewArchiveReader(ArchiveReaderOptions{
+		S3:          client,
+		Bucket:      bucket,
+		SigningKeys: &ga.keys,
+	})
+
+	return &ga
+}
+
+// GetGenerationSnapshot reads and verifies the schemas and exemplars of an
+// archived generation.
+func (ga *GenerationArchive) GetGenerationSnapshot(
+	ctx context.Context, generationID int64,
+) (*GenerationSnapshot, error) {
+	// Reload the keys so that we can verify objects signed with keys
+	// that have been added since the last read.
+	keys, err := loadSigningKeys(ctx, postgres.New(ga.pool))
+	if err != nil {
+		return nil, err
+	}
+
+	ga.keys.Replace(keys)
+
+	var ae smithy.APIError
+
+	gen, err := ga.reader.ReadGeneration(ctx, generationID)
+
+	switch {
+	case errors.As(err, &ae) && ae.ErrorCode() == "NoSuchKey":
+		return nil, DocStoreErrorf(ErrCodeNotFound,
+			"generation %d not found in the archive", generationID)
+	case err != nil:
+		return nil, fmt.Errorf("read archived generation: %w", err)
+	}
+
+	snap := GenerationSnapshot{
+		ID:       generationID,
+		Archived: true,
+		Schemas:  make([]*Schema, len(gen.Schemas)),
+	}
+
+	for i, ref := range gen.Schemas {
+		spec, err := ga.reader.ReadGenerationSchema(ctx, generationID, ref)
+		if err != nil {
+			return nil, fmt.Errorf("read archived schema %s@%s: %w",
+				ref.Name, ref.Version, err)
+		}
+
+		schema := Schema{
+			Name:    spec.Name,
+			Version: spec.Version,
+		}
+
+		err = json.Unmarshal(spec.Spec, &schema.Specification)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal spec for %q: %w",
+				spec.Name, err)
+		}
+
+		snap.Schemas[i] = &schema
+	}
+
+	for i, ref := range gen.Exemplars {
+		ex, err := ga.reader.ReadGenerationExemplar(
+			ctx, generationID, i, ref)
+		if err != nil {
+			return nil, fmt.Errorf("read archived exemplar %q: %w",
+				ref.Name, err)
+		}
+
+		snap.Exemplars = append(snap.Exemplars, ExemplarRecord{
+			Name:        ex.Name,
+			VersionHash: ex.Version,
+			DocType:     ex.DocType,
+			Document:    ex.Document,
+		})
+	}
+
+	return &snap, nil
+}
+
+// CompareGenerations lists the changes in the constraints for each document
+// type between two schema generations, and optionally validates the
+// exemplars of the "from" generation against the "to" generation.
+func CompareGenerations(
+	ctx context.Context, from *GenerationSnapshot, to *GenerationSnapshot,
+	runExemplars bool,
+) (*GenerationComparison, error) {
+	changes := diffGenerationModels(
+		buildGenerationModel(from.Schemas),
+		buildGenerationModel(to.Schemas))
+
+	res := GenerationComparison{
+		From:         from.ID,
+		To:           to.ID,
+		FromArchived: from.Archived,
+		ToArchived:   to.Archived,
+		Changes:      changes,
+	}
+
+	res.Compatible = !slices.ContainsFunc(res.Changes,
+		func(c GenerationChange) bool {
+			return c.Breaking
+		})
+
+	if !runExemplars || len(from.Exemplars) == 0 {
+		return &res, nil
+	}
+
+	constraints := make([]revisor.ConstraintSet, len(to.Schemas))
+
+	for i, s := range to.Schemas {
+		constraints[i] = s.Specification
+	}
+
+	val, err := revisor.NewValidator(constraints...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"create validator for generation %d: %w", to.ID, err)
+	}
+
+	for _, ex := range from.Exemplars {
+		var doc newsdoc.Document
+
+		err := json.Unmarshal(ex.Document, &doc)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unmarshal exemplar %q: %w", ex.Name, err)
+		}
+
+		results, err := val.ValidateDocument(ctx, &doc)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"validate exemplar %q: %w", ex.Name, err)
+		}
+
+		r := ExemplarResult{
+			Name:    ex.Name,
+			DocType: ex.DocType,
+		}
+
+		for _, vr := range results {
+			r.Errors = append(r.Errors, vr.String())
+		}
+
+		if len(r.Errors) > 0 {
+			res.Compatible = false
+		}
+
+		res.Exemplars = append(res.Exemplars, r)
+	}
+
+	return &res, nil
+}
+
+// blockCount is the allowed number of occurrences of a block, a max of -1
+// means that there is no upper bound.
+type blockCount struct {
+	Min int
+	Max int
+}
+
+// typeModel is a flattened view of the constraints for a document type,
+// keyed by constraint path.
+type typeModel struct {
+	Blocks map[string]blockCount
+	Values map[string]revisor.StringConstraint
+}
+
+type blockConstraintSource interface {
+	BlockConstraints(kind revisor.BlockKind) []*revisor.BlockConstraint
+}
+
+type generationModelBuilder struct {
+	definitions map[revisor.BlockKind]map[string]*revisor.BlockConstraint
+	types       map[string]*typeModel
+	declared    map[string]bool
+}
+
+// buildGenerationModel builds a model of the constraints for the document
+// types declared by a set of schemas. Documents and blocks that extend other
+// declarations through match statements are only included when they match on
+// the document type, and blocks without a declaration are ignored.
+func buildGenerationModel(schemas []*Schema) map[string]*typeModel {
+	b := generationModelBuilder{
+		definitions: make(map[revisor.BlockKind]map[string]*revisor.BlockConstraint),
+		types:       make(map[string]*typeModel),
+		declared:    make(map[string]bool),
+	}
+
+	for _, s := range schemas {
+		cs := s.Specification
+
+		for kind, defs := range map[revisor.BlockKind][]*revisor.BlockDefinition{
+			revisor.BlockKindLink:    cs.Links,
+			revisor.BlockKindMeta:    cs.Meta,
+			revisor.BlockKindContent: cs.Content,
+		} {
+			if b.definitions[kind] == nil {
+				b.definitions[kind] = make(map[string]*revisor.BlockConstraint)
+			}
+
+			for _, d := range defs {
+				b.definitions[kind][d.ID] = &d.Block
+			}
+		}
+	}
+
+	for _, s := range schemas {
+		for _, dc := range s.Specification.Documents {
+			docType := dc.Declares
+
+			if docType == "" {
+				c, ok := dc.Match.Constraints["type"]
+				if !ok || c.Const == nil {
+					continue
+				}
+
+				docType = *c.Const
+			} else {
+				b.declared[docType] = true
+			}
+
+			m, ok := b.types[docType]
+			if !ok {
+				m = &typeModel{
+					Blocks: make(map[string]blockCount),
+					Values: make(map[string]revisor.StringConstraint),
+				}
+
+				b.types[docType] = m
+			}
+
+			addValueConstraints(m, "attributes", dc.Attributes)
+			b.addBlocks(m, "", dc)
+		}
+	}
+
+	for docType := range b.types {
+		if !b.declared[docType] {
+			delete(b.types, docType)
+		}
+	}
+
+	return b.types
+}
+
+func (b *generationModelBuilder) addBlocks(
+	m *typeModel, prefix string, source blockConstraintSource,
+) {
+	for _, kind := range []revisor.BlockKind{
+		revisor.BlockKindLink, revisor.BlockKindMeta, revisor.BlockKindContent,
+	} {
+		for _, bc := range source.BlockConstraints(kind) {
+			bc = b.resolveRef(kind, bc)
+
+			if bc.Declares == nil {
+				continue
+			}
+
+			path := prefix + string(kind) + "[" + blockSignatureString(*bc.Declares) + "]"
+
+			if _, exists := m.Blocks[path]; exists {
+				continue
+			}
+
+			m.Blocks[path] = blockCountOf(bc)
+
+			addValueConstraints(m, path+".attributes", bc.Attributes)
+			addValueConstraints(m, path+".data", bc.Data)
+
+			b.addBlocks(m, path+".", bc)
+		}
+	}
+}
+
+// resolveRef resolves a reference to a block definition, constraints on the
+// referencing block are added to the definition.
+func (b *generationModelBuilder) resolveRef(
+	kind revisor.BlockKind, bc *revisor.BlockConstraint,
+) *revisor.BlockConstraint {
+	if bc.Ref == "" {
+		return bc
+	}
+
+	def, ok := b.definitions[kind][bc.Ref]
+	if !ok {
+		return bc
+	}
+
+	res := def.Copy()
+
+	if bc.Count != nil || bc.MinCount != nil || bc.MaxCount != nil {
+		res.Count = bc.Count
+		res.MinCount = bc.MinCount
+		res.MaxCount = bc.MaxCount
+	}
+
+	res.Attributes = mergeConstraintMaps(res.Attributes, bc.Attributes)
+	res.Data = mergeConstraintMaps(res.Data, bc.Data)
+
+	return res
+}
+
+func mergeConstraintMaps(a, b revisor.ConstraintMap) revisor.ConstraintMap {
+	if len(b.Constraints) == 0 {
+		return a
+	}
+
+	c := maps.Clone(a.Constraints)
+	if c == nil {
+		c = make(map[string]revisor.StringConstraint, len(b.Constraints))
+	}
+
+	maps.Copy(c, b.Constraints)
+
+	return revisor.MakeConstraintMap(c)
+}
+
+func blockSignatureString(sig revisor.BlockSignature) string {
+	s := sig.Type
+
+	if sig.Rel != "" {
+		s += " rel=" + sig.Rel
+	}
+
+	if sig.Role != "" {
+		s += " role=" + sig.Role
+	}
+
+	return s
+}
+
+func blockCountOf(bc *revisor.BlockConstraint) blockCount {
+	if bc.Count != nil {
+		return blockCount{Min: *bc.Count, Max: *bc.Count}
+	}
+
+	c := blockCount{Max: -1}
+
+	if bc.MinCount != nil {
+		c.Min = *bc.MinCount
+	}
+
+	if bc.MaxCount != nil {
+		c.Max = *bc.MaxCount
+	}
+
+	return c
+}
+
+func addValueConstraints(
+	m *typeModel, prefix string, cm revisor.ConstraintMap,
+) {
+	for k, c := range cm.Constraints {
+		path := prefix + "." + k
+
+		if _, exists := m.Values[path]; exists {
+			continue
+		}
+
+		m.Values[path] = c
+	}
+}
+
+func diffGenerationModels(from, to map[string]*typeModel) []GenerationChange {
+	changes := []GenerationChange{}
+
+	types := slices.Sorted(maps.Keys(from))
+
+	for docType := range to {
+		if _, ok := from[docType]; !ok {
+			types = append(types, docType)
+		}
+	}
+
+	slices.Sort(types)
+
+	for _, docType := range types {
+		a, inFrom := from[docType]
+		b, inTo := to[docType]
+
+		switch {
+		case !inTo:
+			changes = append(changes, GenerationChange{
+				DocumentType: docType,
+				Kind:         GenerationChangeRemoved,
+				Breaking:     true,
+			})
+		case !inFrom:
+			changes = append(changes, GenerationChange{
+				DocumentType: docType,
+				Kind:         GenerationChangeAdded,
+			})
+		default:
+			changes = append(changes, diffTypeModels(docType, a, b)...)
+		}
+	}
+
+	return changes
+}
+
+func diffTypeModels(docType string, a, b *typeModel) []GenerationChange {
+	var changes []GenerationChange
+
+	// Paths under added or removed blocks are covered by the block
+	// change.
+	var skip []string
+
+	skipped := func(path string) bool {
+		return slices.ContainsFunc(skip, func(prefix string) bool {
+			return strings.HasPrefix(path, prefix+".")
+		})
+	}
+
+	for _, path := range sortedUnion(a.Blocks, b.Blocks) {
+		if skipped(path) {
+			continue
+		}
+
+		oldCount, inA := a.Blocks[path]
+		newCount, inB := b.Blocks[path]
+
+		change := GenerationChange{
+			DocumentType: docType,
+			Path:         path,
+		}
+
+		switch {
+		case !inB:
+			change.Kind = GenerationChangeRemoved
+			change.Breaking = true
+
+			skip = append(skip, path)
+		case !inA:
+			change.Kind = GenerationChangeAdded
+			change.Breaking = newCount.Min > 0
+
+			if change.Breaking {
+				change.Details = append(change.Details,
+					"the block is required")
+			}
+
+			skip = append(skip, path)
+		default:
+			kind, details := compareBlockCounts(oldCount, newCount)
+			if kind == "" {
+				continue
+			}
+
+			change.Kind = kind
+			change.Details = details
+			change.Breaking = isBreakingKind(kind)
+		}
+
+		changes = append(changes, change)
+	}
+
+	for _, path := range sortedUnion(a.Values, b.Values) {
+		if skipped(path) {
+			continue
+		}
+
+		oldC, inA := a.Values[path]
+		newC, inB := b.Values[path]
+
+		change := GenerationChange{
+			DocumentType: docType,
+			Path:         path,
+		}
+
+		switch {
+		case !inB:
+			change.Kind = GenerationChangeRemoved
+			change.Breaking = true
+		case !inA:
+			change.Kind = GenerationChangeAdded
+			change.Breaking = !newC.Optional
+
+			if change.Breaking {
+				change.Details = append(change.Details,
+					"the value is required")
+			}
+		default:
+			kind, details := compareStringConstraints(oldC, newC)
+			if kind == "" {
+				continue
+			}
+
+			change.Kind = kind
+			change.Details = details
+			change.Breaking = isBreakingKind(kind)
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+func sortedUnion[T any](a, b map[string]T) []string {
+	keys := slices.Collect(maps.Keys(a))
+
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+func isBreakingKind(kind GenerationChangeKind) bool {
+	return kind == GenerationChangeTightened || kind == GenerationChangeChanged
+}
+
+// changeCollector keeps track of the direction of the individual changes
+// to a constraint.
+type changeCollector struct {
+	tightened bool
+	loosened  bool
+	changed   bool
+	details   []string
+}
+
+func (c *changeCollector) tighten(format string, a ...any) {
+	c.tightened = true
+	c.details = append(c.details, fmt.Sprintf(format, a...))
+}
+
+func (c *changeCollector) loosen(format string, a ...any) {
+	c.loosened = true
+	c.details = append(c.details, fmt.Sprintf(format, a...))
+}
+
+func (c *changeCollector) change(format string, a ...any) {
+	c.changed = true
+	c.details = append(c.details, fmt.Sprintf(format, a...))
+}
+
+func (c *changeCollector) note(format string, a ...any) {
+	c.details = append(c.details, fmt.Sprintf(format, a...))
+}
+
+func (c *changeCollector) result() (GenerationChangeKind, []string) {
+	switch {
+	case c.changed, c.tightened && c.loosened:
+		return GenerationChangeChanged, c.details
+	case c.tightened:
+		return GenerationChangeTightened, c.details
+	case c.loosened:
+		return GenerationChangeLoosened, c.details
+	case len(c.details) > 0:
+		// Only non-constraining changes, like deprecations.
+		return GenerationChangeLoosened, c.details
+	}
+
+	return "", nil
+}
+
+func compareBlockCounts(a, b blockCount) (GenerationChangeKind, []string) {
+	var c changeCollector
+
+	switch {
+	case b.Min > a.Min:
+		c.tighten("minimum count raised from %d to %d", a.Min, b.Min)
+	case b.Min < a.Min:
+		c.loosen("minimum count lowered from %d to %d", a.Min, b.Min)
+	}
+
+	switch {
+	case a.Max == b.Max:
+	case a.Max == -1 || (b.Max != -1 && b.Max < a.Max):
+		c.tighten("maximum count lowered from %s to %s",
+			countString(a.Max), countString(b.Max))
+	default:
+		c.loosen("maximum count raised from %s to %s",
+			countString(a.Max), countString(b.Max))
+	}
+
+	return c.result()
+}
+
+func countString(n int) string {
+	if n == -1 {
+		return "unbounded"
+	}
+
+	return strconv.Itoa(n)
+}
+
+func compareStringConstraints(
+	a, b revisor.StringConstraint,
+) (GenerationChangeKind, []string) {
+	var c changeCollector
+
+	switch {
+	case a.Optional && !b.Optional:
+		c.tighten("no longer optional")
+	case !a.Optional && b.Optional:
+		c.loosen("made optional")
+	}
+
+	switch {
+	case a.AllowEmpty && !b.AllowEmpty:
+		c.tighten("no longer allows empty values")
+	case !a.AllowEmpty && b.AllowEmpty:
+		c.loosen("allows empty values")
+	}
+
+	compareOptionalString(&c, "const",
+		ptrString(a.Const), ptrString(b.Const))
+
+	switch {
+	case len(a.Enum) == 0 && len(b.Enum) > 0:
+		c.tighten("restricted to the values: %s",
+			strings.Join(b.Enum, ", "))
+	case len(a.Enum) > 0 && len(b.Enum) == 0:
+		c.loosen("no longer restricted to an enum")
+	default:
+		var removed, added []string
+
+		for _, v := range a.Enum {
+			if !slices.Contains(b.Enum, v) {
+				removed = append(removed, v)
+			}
+		}
+
+		for _, v := range b.Enum {
+			if !slices.Contains(a.Enum, v) {
+				added = append(added, v)
+			}
+		}
+
+		if len(removed) > 0 {
+			c.tighten("enum values removed: %s",
+				strings.Join(removed, ", "))
+		}
+
+		if len(added) > 0 {
+			c.loosen("enum values added: %s",
+				strings.Join(added, ", "))
+		}
+	}
+
+	compareOptionalString(&c, "enum reference", a.EnumRef, b.EnumRef)
+
+	var aPattern, bPattern string
+
+	if a.Pattern != nil {
+		aPattern = a.Pattern.String()
+	}
+
+	if b.Pattern != nil {
+		bPattern = b.Pattern.String()
+	}
+
+	compareOptionalString(&c, "pattern", aPattern, bPattern)
+	compareOptionalString(&c, "glob", a.Glob.String(), b.Glob.String())
+	compareOptionalString(&c, "format", string(a.Format), string(b.Format))
+	compareOptionalString(&c, "time format", a.Time, b.Time)
+	compareOptionalString(&c, "geometry", a.Geometry, b.Geometry)
+	compareOptionalString(&c, "HTML policy", a.HTMLPolicy, b.HTMLPolicy)
+
+	switch {
+	case a.Deprecated == nil && b.Deprecated != nil:
+		c.note("deprecated with the label %q", b.Deprecated.Label)
+	case a.Deprecated != nil && b.Deprecated == nil:
+		c.note("no longer deprecated")
+	}
+
+	return c.result()
+}
+
+// compareOptionalString compares a constraint that is inactive when empty.
+func compareOptionalString(c *changeCollector, name string, a, b string) {
+	switch {
+	case a == b:
+	case a == "":
+		c.tighten("%s %q added", name, b)
+	case b == "":
+		c.loosen("%s %q removed", name, a)
+	default:
+		c.change("%s changed from %q to %q", name, a, b)
+	}
+}
+
+func ptrString(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
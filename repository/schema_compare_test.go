@@ -0,0 +1,163 @@
+package repository_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/ttab/revisor"
+)
+
+func compareTestSchema(t *testing.T, spec string) *repository.Schema {
+	t.Helper()
+
+	var cs revisor.ConstraintSet
+
+	err := json.Unmarshal([]byte(spec), &cs)
+	test.Must(t, err, "unmarshal constraint set")
+
+	return &repository.Schema{
+		Name:          "test",
+		Version:       "v1.0.0",
+		Specification: cs,
+	}
+}
+
+func TestCompareGenerations(t *testing.T) {
+	from := repository.GenerationSnapshot{
+		ID: 1,
+		Schemas: []*repository.Schema{compareTestSchema(t, `{
+  "version": 1,
+  "name": "test",
+  "documents": [
+    {
+      "declares": "core/article",
+      "meta": [
+        {
+          "declares": {"type": "core/newsvalue"},
+          "maxCount": 1,
+          "attributes": {
+            "value": {"enum": ["1", "2", "3"]}
+          }
+        },
+        {
+          "declares": {"type": "core/note"},
+          "data": {
+            "text": {}
+          }
+        }
+      ]
+    },
+    {
+      "declares": "core/event"
+    }
+  ]
+}`)},
+		Exemplars: []repository.ExemplarRecord{
+			{
+				Name:    "article",
+				DocType: "core/article",
+				Document: json.RawMessage(`{
+  "uuid": "8f6bb3fb-8b4a-4e05-9a4e-0a3cf7d4a1b2",
+  "type": "core/article",
+  "uri": "article://test/1",
+  "meta": [{"type": "core/newsvalue", "value": "3"}]
+}`),
+			},
+		},
+	}
+
+	to := repository.GenerationSnapshot{
+		ID: 2,
+		Schemas: []*repository.Schema{compareTestSchema(t, `{
+  "version": 1,
+  "name": "test",
+  "documents": [
+    {
+      "declares": "core/article",
+      "meta": [
+        {
+          "declares": {"type": "core/newsvalue"},
+          "count": 1,
+          "attributes": {
+            "value": {"enum": ["1", "2"]}
+          }
+        },
+        {
+          "declares": {"type": "core/note"},
+          "data": {
+            "text": {"optional": true}
+          }
+        },
+        {
+          "declares": {"type": "core/tag"},
+          "data": {
+            "label": {}
+          }
+        }
+      ]
+    },
+    {
+      "declares": "core/planning-item"
+    }
+  ]
+}`)},
+	}
+
+	res, err := repository.CompareGenerations(t.Context(), &from, &to, true)
+	test.Must(t, err, "compare generations")
+
+	test.EqualDiff(t, []repository.GenerationChange{
+		{
+			DocumentType: "core/article",
+			Path:         "meta[core/newsvalue]",
+			Kind:         repository.GenerationChangeTightened,
+			Breaking:     true,
+			Details:      []string{"minimum count raised from 0 to 1"},
+		},
+		{
+			DocumentType: "core/article",
+			Path:         "meta[core/tag]",
+			Kind:         repository.GenerationChangeAdded,
+		},
+		{
+			DocumentType: "core/article",
+			Path:         "meta[core/newsvalue].attributes.value",
+			Kind:         repository.GenerationChangeTightened,
+			Breaking:     true,
+			Details:      []string{"enum values removed: 3"},
+		},
+		{
+			DocumentType: "core/article",
+			Path:         "meta[core/note].data.text",
+			Kind:         repository.GenerationChangeLoosened,
+			Details:      []string{"made optional"},
+		},
+		{
+			DocumentType: "core/event",
+			Kind:         repository.GenerationChangeRemoved,
+			Breaking:     true,
+		},
+		{
+			DocumentType: "core/planning-item",
+			Kind:         repository.GenerationChangeAdded,
+		},
+	}, res.Changes, "get the expected changes")
+
+	test.Equal(t, false, res.Compatible, "report the generations as incompatible")
+
+	if len(res.Exemplars) != 1 || len(res.Exemplars[0].Errors) == 0 {
+		t.Fatalf("expected the exemplar to fail validation, got: %#v",
+			res.Exemplars)
+	}
+
+	same, err := repository.CompareGenerations(t.Context(), &from, &from, true)
+	test.Must(t, err, "compare a generation with itself")
+
+	test.Equal(t, 0, len(same.Changes), "get no changes")
+	test.Equal(t, true, same.Compatible, "report the generation as compatible")
+	test.EqualDiff(t, []repository.ExemplarResult{
+		{Name: "article", DocType: "core/article"},
+	}, same.Exemplars, "validate the exemplar")
+}
@@ -0,0 +1,491 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/pg"
+	"github.com/ttab/newsdoc"
+	"github.com/ttab/revisor"
+)
+
+const revalidationBatchSize = 200
+
+// errRevalidationReplaced is returned when a revalidation has been restarted
+// or stopped while a batch was being processed.
+var errRevalidationReplaced = errors.New("revalidation has been replaced")
+
+// StartRevalidation implements SchemaStore.
+func (s *PGDocStore) StartRevalidation(
+	ctx context.Context, req StartRevalidationStoreRequest,
+) (*SchemaRevalidation, error) {
+	err := pg.WithTX(ctx, s.pool, func(tx pgx.Tx) error {
+		q := postgres.New(tx)
+
+		gen, err := q.GetSchemaGeneration(ctx, req.GenerationID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DocStoreErrorf(ErrCodeNotFound,
+				"generation %d not found", req.GenerationID)
+		} else if err != nil {
+			return fmt.Errorf("get generation: %w", err)
+		}
+
+		if gen.Status == postgres.SchemaGenerationStatusActive {
+			return DocStoreErrorf(ErrCodeFailedPrecondition,
+				"generation %d is already active", req.GenerationID)
+		}
+
+		types := req.Types
+
+		if len(types) == 0 {
+			schemas, err := generationSchemas(ctx, q, req.GenerationID)
+			if err != nil {
+				return err
+			}
+
+			types = declaredDocumentTypes(schemas)
+		}
+
+		if len(types) == 0 {
+			return DocStoreErrorf(ErrCodeBadRequest,
+				"generation %d doesn't declare any document types",
+				req.GenerationID)
+		}
+
+		total, err := q.CountDocumentsOfTypes(ctx, types)
+		if err != nil {
+			return fmt.Errorf("count documents: %w", err)
+		}
+
+		err = q.DeleteSchemaRevalidationResults(ctx, req.GenerationID)
+		if err != nil {
+			return fmt.Errorf("clear previous results: %w", err)
+		}
+
+		err = q.StartSchemaRevalidation(ctx, postgres.StartSchemaRevalidationParams{
+			GenerationID: req.GenerationID,
+			Types:        types,
+			Created:      pg.Time(time.Now()),
+			CreatedBy:    req.CreatedBy,
+			Total:        total,
+		})
+		if err != nil {
+			return fmt.Errorf("store revalidation: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetRevalidation(ctx, req.GenerationID)
+}
+
+// GetRevalidation implements SchemaStore.
+func (s *PGDocStore) GetRevalidation(
+	ctx context.Context, generationID int64,
+) (*SchemaRevalidation, error) {
+	row, err := s.reader.GetSchemaRevalidation(ctx, generationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, DocStoreErrorf(ErrCodeNotFound,
+			"no revalidation of generation %d", generationID)
+	} else if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	rv := SchemaRevalidation{
+		GenerationID: row.GenerationID,
+		Types:        row.Types,
+		Status:       SchemaJobStatus(row.Status),
+		Created:      row.Created.Time,
+		CreatedBy:    row.CreatedBy,
+		Total:        row.Total,
+		Processed:    row.Processed,
+		Failed:       row.Failed,
+		Error:        row.Error.String,
+	}
+
+	if row.Finished.Valid {
+		t := row.Finished.Time
+		rv.Finished = &t
+	}
+
+	return &rv, nil
+}
+
+// GetRevalidationFailures implements SchemaStore.
+func (s *PGDocStore) GetRevalidationFailures(
+	ctx context.Context, query RevalidationFailureQuery,
+) ([]RevalidationFailure, error) {
+	rows, err := s.reader.GetSchemaRevalidationFailures(ctx,
+		postgres.GetSchemaRevalidationFailuresParams{
+			GenerationID: query.GenerationID,
+			After:        query.After,
+			RowLimit:     query.Limit,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	failures := make([]RevalidationFailure, len(rows))
+
+	for i, row := range rows {
+		failures[i] = RevalidationFailure{
+			UUID:    row.UUID,
+			Type:    row.Type,
+			Version: row.Version,
+		}
+
+		err := json.Unmarshal(row.Errors, &failures[i].Errors)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unmarshal errors for %s: %w", row.UUID, err)
+		}
+	}
+
+	return failures, nil
+}
+
+// RunRevalidation periodically checks for running revalidations and
+// validates the current versions of the selected documents against the
+// pending schema generation.
+func (s *PGDocStore) RunRevalidation(ctx context.Context, period time.Duration) {
+	for {
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return
+		}
+
+		jobLock, err := pg.NewJobLock(s.pool, s.logger, "schema-revalidation", pg.JobLockOptions{
+			PingInterval:  10 * time.Second,
+			StaleAfter:    1 * time.Minute,
+			CheckInterval: 20 * time.Second,
+			Timeout:       5 * time.Second,
+		})
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to create job lock",
+				elephantine.LogKeyError, err)
+
+			continue
+		}
+
+		err = jobLock.RunWithContext(ctx, s.processRevalidations)
+		if err != nil {
+			s.logger.ErrorContext(
+				ctx, "schema revalidation error",
+				elephantine.LogKeyError, err,
+			)
+		}
+	}
+}
+
+func (s *PGDocStore) processRevalidations(ctx context.Context) error {
+	for {
+		run, err := s.reader.GetRunningSchemaRevalidation(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("get running revalidation: %w", err)
+		}
+
+		err = s.revalidate(ctx, run)
+		if err != nil && !errors.Is(err, errRevalidationReplaced) {
+			return fmt.Errorf("revalidate generation %d: %w",
+				run.GenerationID, err)
+		}
+	}
+}
+
+func (s *PGDocStore) revalidate(
+	ctx context.Context, run postgres.SchemaRevalidation,
+) error {
+	gen, err := s.reader.GetSchemaGeneration(ctx, run.GenerationID)
+	if err != nil {
+		return fmt.Errorf("get generation: %w", err)
+	}
+
+	if gen.Status == postgres.SchemaGenerationStatusActive {
+		return s.finishRevalidation(ctx, run, SchemaJobCancelled,
+			"the generation has been activated")
+	}
+
+	val, err := s.generationValidator(ctx, run.GenerationID)
+	if err != nil {
+		return s.finishRevalidation(ctx, run, SchemaJobFailed,
+			err.Error())
+	}
+
+	// Documents are transformed on write once the generation is
+	// active, so validate them in their transformed shape.
+	transforms, err := s.GetGenerationTransforms(ctx, run.GenerationID)
+	if err != nil {
+		return fmt.Errorf("get generation transforms: %w", err)
+	}
+
+	position := run.Position
+
+	for {
+		docs, err := s.reader.GetCurrentDocumentsOfTypes(ctx,
+			postgres.GetCurrentDocumentsOfTypesParams{
+				Types:    run.Types,
+				After:    position,
+				RowLimit: revalidationBatchSize,
+			})
+		if err != nil {
+			return fmt.Errorf("get documents: %w", err)
+		}
+
+		if len(docs) == 0 {
+			return s.finishRevalidation(ctx, run, SchemaJobDone, "")
+		}
+
+		err = s.revalidateBatch(ctx, run, val, transforms, docs)
+		if err != nil {
+			return err
+		}
+
+		position = docs[len(docs)-1].UUID
+
+		// Stop if the generation was activated while we were working.
+		gen, err := s.reader.GetSchemaGeneration(ctx, run.GenerationID)
+		if err != nil {
+			return fmt.Errorf("get generation: %w", err)
+		}
+
+		if gen.Status == postgres.SchemaGenerationStatusActive {
+			return s.finishRevalidation(ctx, run, SchemaJobCancelled,
+				"the generation has been activated")
+		}
+	}
+}
+
+func (s *PGDocStore) revalidateBatch(
+	ctx context.Context, run postgres.SchemaRevalidation,
+	val *revisor.Validator, transforms []DocumentTransform,
+	docs []postgres.GetCurrentDocumentsOfTypesRow,
+) error {
+	var failures []postgres.InsertSchemaRevalidationResultParams
+
+	for _, d := range docs {
+		if d.DocumentData == nil {
+			continue
+		}
+
+		var doc newsdoc.Document
+
+		err := json.Unmarshal(d.DocumentData, &doc)
+		if err != nil {
+			return fmt.Errorf("unmarshal document %s: %w", d.UUID, err)
+		}
+
+		doc, _ = ApplyTransforms(doc, transforms)
+
+		results, err := val.ValidateDocument(ctx, &doc)
+		if err != nil {
+			return fmt.Errorf("validate document %s: %w", d.UUID, err)
+		}
+
+		if len(results) == 0 {
+			continue
+		}
+
+		messages := make([]string, len(results))
+
+		for i, r := range results {
+			messages[i] = r.String()
+		}
+
+		errorsJSON, err := json.Marshal(messages)
+		if err != nil {
+			return fmt.Errorf("marshal validation errors: %w", err)
+		}
+
+		failures = append(failures, postgres.InsertSchemaRevalidationResultParams{
+			GenerationID: run.GenerationID,
+			UUID:         d.UUID,
+			Type:         d.Type,
+			Version:      d.CurrentVersion,
+			Errors:       errorsJSON,
+		})
+	}
+
+	return pg.WithTX(ctx, s.pool, func(tx pgx.Tx) error {
+		q := postgres.New(tx)
+
+		for _, f := range failures {
+			err := q.InsertSchemaRevalidationResult(ctx, f)
+			if err != nil {
+				return fmt.Errorf("store result for %s: %w", f.UUID, err)
+			}
+		}
+
+		n, err := q.UpdateSchemaRevalidationProgress(ctx,
+			postgres.UpdateSchemaRevalidationProgressParams{
+				Position:     docs[len(docs)-1].UUID,
+				Processed:    int64(len(docs)),
+				Failed:       int64(len(failures)),
+				GenerationID: run.GenerationID,
+				Created:      run.Created,
+			})
+		if err != nil {
+			return fmt.Errorf("update progress: %w", err)
+		}
+
+		// Roll back if the revalidation was restarted while we
+		// processed the batch.
+		if n == 0 {
+			return errRevalidationReplaced
+		}
+
+		return nil
+	})
+}
+
+func (s *PGDocStore) finishRevalidation(
+	ctx context.Context, run postgres.SchemaRevalidation,
+	status SchemaJobStatus, message string,
+) error {
+	err := s.reader.FinishSchemaRevalidation(ctx,
+		postgres.FinishSchemaRevalidationParams{
+			Status:       string(status),
+			Finished:     pg.Time(time.Now()),
+			Error:        pg.TextOrNull(message),
+			GenerationID: run.GenerationID,
+			Created:      run.Created,
+		})
+	if err != nil {
+		return fmt.Errorf("finish revalidation: %w", err)
+	}
+
+	return nil
+}
+
+// generationValidator creates a validator for the schemas of a generation,
+// using the variants of the current type configuration.
+func (s *PGDocStore) generationValidator(
+	ctx context.Context, generationID int64,
+) (*revisor.Validator, error) {
+	schemas, err := generationSchemas(ctx, s.reader, generationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.schemaValidator(ctx, schemas)
+}
+
+// schemaValidator creates a validator for a set of schemas, with the variants
+// that have been configured for document types.
+func (s *PGDocStore) schemaValidator(
+	ctx context.Context, schemas []revisor.ConstraintSet,
+) (*revisor.Validator, error) {
+	val, err := revisor.NewValidator(schemas...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"create a validator from the constraints: %w", err)
+	}
+
+	variants, err := loadVariants(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("load variants: %w", err)
+	}
+
+	if len(variants) > 0 {
+		val = val.WithVariants(variants...)
+	}
+
+	return val, nil
+}
+
+func generationSchemas(
+	ctx context.Context, q *postgres.Queries, generationID int64,
+) ([]revisor.ConstraintSet, error) {
+	rows, err := q.GetSchemaGenerationSchemasWithSpec(ctx, generationID)
+	if err != nil {
+		return nil, fmt.Errorf("get generation schemas: %w", err)
+	}
+
+	schemas := make([]revisor.ConstraintSet, len(rows))
+
+	for i, row := range rows {
+		err := json.Unmarshal(row.Spec, &schemas[i])
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal spec for %q: %w",
+				row.Name, err)
+		}
+	}
+
+	return schemas, nil
+}
+
+// declaredDocumentTypes returns the document types declared by a set of
+// schemas.
+func declaredDocumentTypes(schemas []revisor.ConstraintSet) []string {
+	var types []string
+
+	for _, cs := range schemas {
+		for _, d := range cs.Documents {
+			if d.Declares == "" || slices.Contains(types, d.Declares) {
+				continue
+			}
+
+			types = append(types, d.Declares)
+		}
+	}
+
+	slices.Sort(types)
+
+	return types
+}
+
+// checkGenerationRevalidated verifies that stored documents have been
+// revalidated against a generation before it's activated. Generations that
+// are, or have been, active are exempt so that rollbacks aren't blocked, and
+// so are generations that don't declare any types that have been stored.
+func checkGenerationRevalidated(
+	ctx context.Context, q *postgres.Queries, gen postgres.SchemaGeneration,
+) error {
+	if gen.Status == postgres.SchemaGenerationStatusActive || gen.Activated.Valid {
+		return nil
+	}
+
+	rv, err := q.GetSchemaRevalidation(ctx, gen.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		schemas, err := generationSchemas(ctx, q, gen.ID)
+		if err != nil {
+			return err
+		}
+
+		stored, err := q.CountDocumentsOfTypes(ctx,
+			declaredDocumentTypes(schemas))
+		if err != nil {
+			return fmt.Errorf("count documents: %w", err)
+		}
+
+		if stored == 0 {
+			return nil
+		}
+
+		return DocStoreErrorf(ErrCodeFailedPrecondition,
+			"generation %d must be revalidated before it can be activated",
+			gen.ID)
+	} else if err != nil {
+		return fmt.Errorf("get revalidation: %w", err)
+	}
+
+	if SchemaJobStatus(rv.Status) != SchemaJobDone {
+		return DocStoreErrorf(ErrCodeFailedPrecondition,
+			"the revalidation of generation %d is %s, it must be done before the generation can be activated",
+			gen.ID, rv.Status)
+	}
+
+	return nil
+}
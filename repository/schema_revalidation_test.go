@@ -0,0 +1,191 @@
+package repository_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/ttab/revisor"
+	"github.com/twitchtv/twirp"
+)
+
+func TestIntegrationSchemaRevalidation(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+	ctx := t.Context()
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		RunRevalidation: true,
+	})
+
+	adminClaims := itest.Claims(t, "admin", "schema_admin")
+
+	schemas := tc.SchemasClient(t, adminClaims)
+	schemaExt := tc.ExtensionClient(t, rpc.SchemasPathPrefix, adminClaims)
+
+	editor := tc.DocumentsClient(t,
+		itest.Claims(t, "editor", "doc_read doc_write"))
+
+	okUUID := uuid.NewString()
+	strictUUID := uuid.NewString()
+
+	_, err := editor.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     okUUID,
+		Document: baseDocument(okUUID, "article://test/ok"),
+	})
+	test.Must(t, err, "create document")
+
+	_, err = editor.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     strictUUID,
+		Document: baseDocument(strictUUID, "article://test/strict/1"),
+	})
+	test.Must(t, err, "create document that will fail validation")
+
+	one := 1
+	articleType := "core/article"
+
+	// Require a meta block that the strict article doesn't have.
+	spec := revisor.ConstraintSet{
+		Version: 1,
+		Name:    "test_strict",
+		Documents: []revisor.DocumentConstraint{
+			{
+				Match: revisor.MakeConstraintMap(
+					map[string]revisor.StringConstraint{
+						"type": {Const: &articleType},
+						"uri": {Glob: revisor.GlobList{
+							mustGlob(t, "article://test/strict/*"),
+						}},
+					}),
+				Meta: []*revisor.BlockConstraint{
+					{
+						Declares: &revisor.BlockSignature{
+							Type: "test/required",
+						},
+						Count: &one,
+					},
+				},
+			},
+		},
+	}
+
+	specPayload, err := json.Marshal(&spec)
+	test.Must(t, err, "marshal strict schema")
+
+	active, err := schemas.GetAllActive(ctx, &rpc.GetAllActiveSchemasRequest{})
+	test.Must(t, err, "get active schemas")
+
+	genSchemas := append(active.Schemas, &rpc.Schema{
+		Name:    "test/strict",
+		Version: "v1.0.0",
+		Spec:    string(specPayload),
+	})
+
+	gen, err := schemas.RegisterGeneration(ctx, &rpc.RegisterGenerationRequest{
+		Activation: rpc.SchemaActivation_ACTIVATION_PENDING,
+		Schemas:    genSchemas,
+	})
+	test.Must(t, err, "register pending generation")
+
+	_, err = schemas.SetActive(ctx, &rpc.SetActiveSchemasRequest{
+		GenerationId: gen.GenerationId,
+		Activation:   rpc.SchemaActivation_ACTIVATION_ACTIVE,
+	})
+	itest.IsTwirpError(t, err, twirp.FailedPrecondition)
+
+	var started repository.StartRevalidationResponse
+
+	err = schemaExt.Call(ctx, "StartRevalidation",
+		repository.StartRevalidationRequest{
+			GenerationID: gen.GenerationId,
+			Types:        []string{"core/article"},
+		}, &started)
+	test.Must(t, err, "start revalidation")
+
+	test.Equal(t, int64(2), started.Revalidation.Total,
+		"get the number of documents to revalidate")
+	test.Equal(t, "user://test/admin", started.Revalidation.CreatedBy,
+		"get the subject that started the revalidation")
+
+	var status repository.GetRevalidationResponse
+
+	deadline := time.Now().Add(10 * time.Second)
+
+	for status.Revalidation == nil ||
+		status.Revalidation.Status == repository.SchemaJobRunning {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the revalidation to finish")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		err = schemaExt.Call(ctx, "GetRevalidation",
+			repository.GetRevalidationRequest{
+				GenerationID: gen.GenerationId,
+			}, &status)
+		test.Must(t, err, "get revalidation status")
+	}
+
+	test.Equal(t, repository.SchemaJobDone, status.Revalidation.Status,
+		"finish the revalidation")
+	test.Equal(t, int64(2), status.Revalidation.Processed,
+		"process all documents")
+	test.Equal(t, int64(1), status.Revalidation.Failed,
+		"get one failed document")
+
+	var failures repository.GetRevalidationFailuresResponse
+
+	err = schemaExt.Call(ctx, "GetRevalidationFailures",
+		repository.GetRevalidationFailuresRequest{
+			GenerationID: gen.GenerationId,
+			Limit:        1,
+		}, &failures)
+	test.Must(t, err, "get revalidation failures")
+
+	test.Equal(t, 1, len(failures.Failures), "get one failure")
+	test.Equal(t, strictUUID, failures.Failures[0].UUID.String(),
+		"get the strict document as a failure")
+
+	if len(failures.Failures[0].Errors) == 0 {
+		t.Fatal("expected the failure to list validation errors")
+	}
+
+	err = schemaExt.Call(ctx, "GetRevalidationFailures",
+		repository.GetRevalidationFailuresRequest{
+			GenerationID: gen.GenerationId,
+			After:        failures.Next,
+		}, &failures)
+	test.Must(t, err, "get next page of revalidation failures")
+
+	test.Equal(t, 0, len(failures.Failures), "get no more failures")
+
+	_, err = schemas.SetActive(ctx, &rpc.SetActiveSchemasRequest{
+		GenerationId: gen.GenerationId,
+		Activation:   rpc.SchemaActivation_ACTIVATION_ACTIVE,
+	})
+	test.Must(t, err, "activate the revalidated generation")
+
+	err = schemaExt.Call(ctx, "StartRevalidation",
+		repository.StartRevalidationRequest{
+			GenerationID: gen.GenerationId,
+		}, &started)
+	itest.IsTwirpError(t, err, twirp.FailedPrecondition)
+}
+
+func mustGlob(t *testing.T, pattern string) *revisor.Glob {
+	t.Helper()
+
+	g, err := revisor.CompileGlob(pattern)
+	test.Must(t, err, "compile glob %q", pattern)
+
+	return g
+}
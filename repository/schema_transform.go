@@ -0,0 +1,764 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/pg"
+	"github.com/ttab/newsdoc"
+	"github.com/ttab/revisor"
+)
+
+const (
+	// UpgradedByGenerationMeta is the version meta key that records the
+	// schema generation that a version was upgraded with.
+	UpgradedByGenerationMeta = "upgraded_by_generation"
+	// UpgradedByTransformsMeta is the version meta key that lists the
+	// names of the transforms that were applied in an upgrade.
+	UpgradedByTransformsMeta = "upgraded_by_transforms"
+
+	// schemaUpgradeUpdater is used as the updater of document versions
+	// written by the schema upgrade job.
+	schemaUpgradeUpdater = "internal://schema-upgrade"
+
+	upgradeBatchSize = 100
+)
+
+// TransformOperation is the kind of change that a document transform makes.
+type TransformOperation string
+
+const (
+	// TransformRenameDataKey renames the data key Key to To.
+	TransformRenameDataKey TransformOperation = "rename_data_key"
+	// TransformMoveBlock moves the matching blocks to the block kind To.
+	TransformMoveBlock TransformOperation = "move_block"
+	// TransformMapValue replaces the value From with To in the block
+	// attribute Key, data values are addressed as "data.[key]".
+	TransformMapValue TransformOperation = "map_value"
+)
+
+// DocumentTransform is a declarative change that a schema generation applies
+// to documents written in the shape of an earlier generation. Transforms only
+// apply to the top level blocks of a document and are idempotent, applying a
+// transform to a document that already has the new shape is a no-op.
+type DocumentTransform struct {
+	Name string `json:"name"`
+	// DocumentType restricts the transform to a document type.
+	DocumentType string             `json:"document_type,omitempty"`
+	Operation    TransformOperation `json:"operation"`
+	Block        BlockSelector      `json:"block"`
+	Key          string             `json:"key,omitempty"`
+	From         string             `json:"from,omitempty"`
+	To           string             `json:"to,omitempty"`
+}
+
+// BlockSelector selects the blocks that a transform applies to.
+type BlockSelector struct {
+	Kind revisor.BlockKind `json:"kind"`
+	Type string            `json:"type"`
+	Rel  string            `json:"rel,omitempty"`
+	Role string            `json:"role,omitempty"`
+}
+
+func (bs BlockSelector) matches(b newsdoc.Block) bool {
+	return b.Type == bs.Type &&
+		(bs.Rel == "" || b.Rel == bs.Rel) &&
+		(bs.Role == "" || b.Role == bs.Role)
+}
+
+func validBlockKind(kind revisor.BlockKind) bool {
+	switch kind {
+	case revisor.BlockKindLink, revisor.BlockKindMeta, revisor.BlockKindContent:
+		return true
+	}
+
+	return false
+}
+
+// ValidateTransforms checks that a set of transforms is well-formed.
+func ValidateTransforms(transforms []DocumentTransform) error {
+	names := make(map[string]bool, len(transforms))
+
+	for i, t := range transforms {
+		if t.Name == "" {
+			return fmt.Errorf("transform %d: missing name", i)
+		}
+
+		if names[t.Name] {
+			return fmt.Errorf("transform %q: duplicate name", t.Name)
+		}
+
+		names[t.Name] = true
+
+		err := t.validate()
+		if err != nil {
+			return fmt.Errorf("transform %q: %w", t.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (t DocumentTransform) validate() error {
+	if !validBlockKind(t.Block.Kind) {
+		return fmt.Errorf("invalid block kind %q", t.Block.Kind)
+	}
+
+	if t.Block.Type == "" {
+		return errors.New("missing block type")
+	}
+
+	switch t.Operation {
+	case TransformRenameDataKey:
+		if t.Key == "" || t.To == "" {
+			return errors.New("key and to are required")
+		}
+
+		if t.Key == t.To {
+			return errors.New("key and to must differ")
+		}
+	case TransformMoveBlock:
+		if !validBlockKind(revisor.BlockKind(t.To)) {
+			return fmt.Errorf("invalid target block kind %q", t.To)
+		}
+
+		if revisor.BlockKind(t.To) == t.Block.Kind {
+			return errors.New("the target block kind must differ")
+		}
+	case TransformMapValue:
+		_, ok := blockAttribute(&newsdoc.Block{}, t.Key)
+		if !ok && !strings.HasPrefix(t.Key, "data.") {
+			return fmt.Errorf("invalid attribute %q", t.Key)
+		}
+
+		if t.From == t.To {
+			return errors.New("from and to must differ")
+		}
+	default:
+		return fmt.Errorf("unknown operation %q", t.Operation)
+	}
+
+	return nil
+}
+
+// ApplyTransforms applies transforms to a document, returning the transformed
+// document and the names of the transforms that changed it. The blocks of
+// the given document are never modified.
+func ApplyTransforms(
+	doc newsdoc.Document, transforms []DocumentTransform,
+) (newsdoc.Document, []string) {
+	var applied []string
+
+	for _, t := range transforms {
+		if t.DocumentType != "" && t.DocumentType != doc.Type {
+			continue
+		}
+
+		var changed bool
+
+		doc, changed = t.apply(doc)
+		if changed {
+			applied = append(applied, t.Name)
+		}
+	}
+
+	return doc, applied
+}
+
+func (t DocumentTransform) apply(doc newsdoc.Document) (newsdoc.Document, bool) {
+	source := documentBlocks(doc, t.Block.Kind)
+
+	if t.Operation == TransformMoveBlock {
+		var keep, moved []newsdoc.Block
+
+		for _, b := range source {
+			if t.Block.matches(b) {
+				moved = append(moved, b)
+			} else {
+				keep = append(keep, b)
+			}
+		}
+
+		if len(moved) == 0 {
+			return doc, false
+		}
+
+		target := revisor.BlockKind(t.To)
+
+		doc = withDocumentBlocks(doc, t.Block.Kind, keep)
+		doc = withDocumentBlocks(doc, target, append(
+			slices.Clone(documentBlocks(doc, target)), moved...))
+
+		return doc, true
+	}
+
+	var out []newsdoc.Block
+
+	for i, b := range source {
+		if !t.Block.matches(b) {
+			continue
+		}
+
+		nb, changed := t.applyToBlock(b)
+		if !changed {
+			continue
+		}
+
+		if out == nil {
+			out = slices.Clone(source)
+		}
+
+		out[i] = nb
+	}
+
+	if out == nil {
+		return doc, false
+	}
+
+	return withDocumentBlocks(doc, t.Block.Kind, out), true
+}
+
+func (t DocumentTransform) applyToBlock(b newsdoc.Block) (newsdoc.Block, bool) {
+	switch t.Operation {
+	case TransformRenameDataKey:
+		v, ok := b.Data[t.Key]
+		if !ok {
+			return b, false
+		}
+
+		if _, exists := b.Data[t.To]; exists {
+			return b, false
+		}
+
+		data := maps.Clone(b.Data)
+
+		delete(data, t.Key)
+		data[t.To] = v
+
+		b.Data = data
+
+		return b, true
+	case TransformMapValue:
+		if key, ok := strings.CutPrefix(t.Key, "data."); ok {
+			v, ok := b.Data[key]
+			if !ok || v != t.From {
+				return b, false
+			}
+
+			data := maps.Clone(b.Data)
+
+			data[key] = t.To
+			b.Data = data
+
+			return b, true
+		}
+
+		attr, ok := blockAttribute(&b, t.Key)
+		if !ok || *attr != t.From {
+			return b, false
+		}
+
+		*attr = t.To
+
+		return b, true
+	case TransformMoveBlock:
+	}
+
+	return b, false
+}
+
+func blockAttribute(b *newsdoc.Block, name string) (*string, bool) {
+	switch name {
+	case "id":
+		return &b.ID, true
+	case "uuid":
+		return &b.UUID, true
+	case "uri":
+		return &b.URI, true
+	case "url":
+		return &b.URL, true
+	case "type":
+		return &b.Type, true
+	case "title":
+		return &b.Title, true
+	case "rel":
+		return &b.Rel, true
+	case "role":
+		return &b.Role, true
+	case "name":
+		return &b.Name, true
+	case "value":
+		return &b.Value, true
+	case "contenttype":
+		return &b.Contenttype, true
+	case "sensitivity":
+		return &b.Sensitivity, true
+	}
+
+	return nil, false
+}
+
+func documentBlocks(doc newsdoc.Document, kind revisor.BlockKind) []newsdoc.Block {
+	switch kind {
+	case revisor.BlockKindLink:
+		return doc.Links
+	case revisor.BlockKindMeta:
+		return doc.Meta
+	case revisor.BlockKindContent:
+		return doc.Content
+	}
+
+	return nil
+}
+
+func withDocumentBlocks(
+	doc newsdoc.Document, kind revisor.BlockKind, blocks []newsdoc.Block,
+) newsdoc.Document {
+	switch kind {
+	case revisor.BlockKindLink:
+		doc.Links = blocks
+	case revisor.BlockKindMeta:
+		doc.Meta = blocks
+	case revisor.BlockKindContent:
+		doc.Content = blocks
+	}
+
+	return doc
+}
+
+// SetGenerationTransforms implements SchemaStore.
+func (s *PGDocStore) SetGenerationTransforms(
+	ctx context.Context, generationID int64,
+	transforms []DocumentTransform, updatedBy string,
+) error {
+	data, err := json.Marshal(transforms)
+	if err != nil {
+		return fmt.Errorf("marshal transforms: %w", err)
+	}
+
+	return pg.WithTX(ctx, s.pool, func(tx pgx.Tx) error {
+		q := postgres.New(tx)
+
+		_, err := q.GetSchemaGeneration(ctx, generationID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DocStoreErrorf(ErrCodeNotFound,
+				"generation %d not found", generationID)
+		} else if err != nil {
+			return fmt.Errorf("get generation: %w", err)
+		}
+
+		err = q.SetSchemaGenerationTransforms(ctx,
+			postgres.SetSchemaGenerationTransformsParams{
+				GenerationID: generationID,
+				Transforms:   data,
+				Updated:      pg.Time(time.Now()),
+				UpdatedBy:    updatedBy,
+			})
+		if err != nil {
+			return fmt.Errorf("store transforms: %w", err)
+		}
+
+		// Let the validators pick up the new transforms.
+		err = s.schemas.Publish(ctx, tx, SchemaEvent{
+			Type: SchemaEventTypeActivation,
+		})
+		if err != nil {
+			return fmt.Errorf("publish schema event: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetGenerationTransforms implements SchemaStore.
+func (s *PGDocStore) GetGenerationTransforms(
+	ctx context.Context, generationID int64,
+) ([]DocumentTransform, error) {
+	data, err := s.reader.GetSchemaGenerationTransforms(ctx, generationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	var transforms []DocumentTransform
+
+	err = json.Unmarshal(data, &transforms)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal transforms: %w", err)
+	}
+
+	return transforms, nil
+}
+
+// GetActiveGenerationTransforms implements ValidatorStore.
+func (s *PGDocStore) GetActiveGenerationTransforms(
+	ctx context.Context,
+) ([]DocumentTransform, error) {
+	data, err := s.reader.GetActiveGenerationTransforms(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	var transforms []DocumentTransform
+
+	err = json.Unmarshal(data, &transforms)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal transforms: %w", err)
+	}
+
+	return transforms, nil
+}
+
+// StartUpgrade implements SchemaStore.
+func (s *PGDocStore) StartUpgrade(
+	ctx context.Context, req StartUpgradeStoreRequest,
+) (*SchemaUpgrade, error) {
+	var generationID int64
+
+	err := pg.WithTX(ctx, s.pool, func(tx pgx.Tx) error {
+		q := postgres.New(tx)
+
+		gen, err := q.GetActiveSchemaGeneration(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DocStoreErrorf(ErrCodeFailedPrecondition,
+				"there is no active generation")
+		} else if err != nil {
+			return fmt.Errorf("get active generation: %w", err)
+		}
+
+		generationID = gen.ID
+
+		data, err := q.GetSchemaGenerationTransforms(ctx, gen.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DocStoreErrorf(ErrCodeFailedPrecondition,
+				"the active generation %d has no transforms", gen.ID)
+		} else if err != nil {
+			return fmt.Errorf("get transforms: %w", err)
+		}
+
+		var transforms []DocumentTransform
+
+		err = json.Unmarshal(data, &transforms)
+		if err != nil {
+			return fmt.Errorf("unmarshal transforms: %w", err)
+		}
+
+		types := req.Types
+
+		if len(types) == 0 {
+			for _, t := range transforms {
+				if t.DocumentType == "" {
+					return DocStoreErrorf(ErrCodeBadRequest,
+						"transform %q applies to all document types, the types to upgrade must be specified",
+						t.Name)
+				}
+
+				if !slices.Contains(types, t.DocumentType) {
+					types = append(types, t.DocumentType)
+				}
+			}
+
+			slices.Sort(types)
+		}
+
+		if len(types) == 0 {
+			return DocStoreErrorf(ErrCodeFailedPrecondition,
+				"the active generation %d has no transforms", gen.ID)
+		}
+
+		err = q.StartSchemaUpgrade(ctx, postgres.StartSchemaUpgradeParams{
+			GenerationID: gen.ID,
+			Types:        types,
+			Created:      pg.Time(time.Now()),
+			CreatedBy:    req.CreatedBy,
+		})
+		if err != nil {
+			return fmt.Errorf("store upgrade: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetUpgrade(ctx, generationID)
+}
+
+// GetUpgrade implements SchemaStore.
+func (s *PGDocStore) GetUpgrade(
+	ctx context.Context, generationID int64,
+) (*SchemaUpgrade, error) {
+	row, err := s.reader.GetSchemaUpgrade(ctx, generationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, DocStoreErrorf(ErrCodeNotFound,
+			"no upgrade using generation %d", generationID)
+	} else if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	up := SchemaUpgrade{
+		GenerationID: row.GenerationID,
+		Types:        row.Types,
+		Status:       SchemaJobStatus(row.Status),
+		Created:      row.Created.Time,
+		CreatedBy:    row.CreatedBy,
+		Processed:    row.Processed,
+		Upgraded:     row.Upgraded,
+		Failed:       row.Failed,
+		Error:        row.Error.String,
+	}
+
+	if row.Finished.Valid {
+		t := row.Finished.Time
+		up.Finished = &t
+	}
+
+	return &up, nil
+}
+
+// RunSchemaUpgrades periodically checks for running upgrades and writes new
+// versions of the current documents of the selected types that are changed
+// by the transforms of the active schema generation.
+func (s *PGDocStore) RunSchemaUpgrades(
+	ctx context.Context, period time.Duration, validator DocumentValidator,
+) {
+	for {
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return
+		}
+
+		jobLock, err := pg.NewJobLock(s.pool, s.logger, "schema-upgrade", pg.JobLockOptions{
+			PingInterval:  10 * time.Second,
+			StaleAfter:    1 * time.Minute,
+			CheckInterval: 20 * time.Second,
+			Timeout:       5 * time.Second,
+		})
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to create job lock",
+				elephantine.LogKeyError, err)
+
+			continue
+		}
+
+		err = jobLock.RunWithContext(ctx, func(ctx context.Context) error {
+			return s.processUpgrades(ctx, validator)
+		})
+		if err != nil {
+			s.logger.ErrorContext(
+				ctx, "schema upgrade error",
+				elephantine.LogKeyError, err,
+			)
+		}
+	}
+}
+
+func (s *PGDocStore) processUpgrades(
+	ctx context.Context, validator DocumentValidator,
+) error {
+	for {
+		run, err := s.reader.GetRunningSchemaUpgrade(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("get running upgrade: %w", err)
+		}
+
+		err = s.upgrade(ctx, run, validator)
+		if err != nil && !errors.Is(err, errRevalidationReplaced) {
+			return fmt.Errorf("upgrade using generation %d: %w",
+				run.GenerationID, err)
+		}
+	}
+}
+
+func (s *PGDocStore) upgrade(
+	ctx context.Context, run postgres.SchemaUpgrade,
+	validator DocumentValidator,
+) error {
+	position := run.Position
+
+	for {
+		gen, err := s.reader.GetSchemaGeneration(ctx, run.GenerationID)
+		if err != nil {
+			return fmt.Errorf("get generation: %w", err)
+		}
+
+		if gen.Status != postgres.SchemaGenerationStatusActive {
+			return s.finishUpgrade(ctx, run, SchemaJobCancelled,
+				"the generation is no longer active")
+		}
+
+		// Wait for the validator to load the generation so that we
+		// use its transforms.
+		if validator.ActiveGenerationID() != run.GenerationID {
+			return fmt.Errorf(
+				"the validator hasn't loaded generation %d yet",
+				run.GenerationID)
+		}
+
+		docs, err := s.reader.GetCurrentDocumentsOfTypes(ctx,
+			postgres.GetCurrentDocumentsOfTypesParams{
+				Types:    run.Types,
+				After:    position,
+				RowLimit: upgradeBatchSize,
+			})
+		if err != nil {
+			return fmt.Errorf("get documents: %w", err)
+		}
+
+		if len(docs) == 0 {
+			return s.finishUpgrade(ctx, run, SchemaJobDone, "")
+		}
+
+		var upgraded, failed int64
+
+		for _, d := range docs {
+			ok, err := s.upgradeDocument(ctx, run.GenerationID, validator, d)
+			if err != nil {
+				s.logger.WarnContext(ctx, "failed to upgrade document",
+					elephantine.LogKeyError, err,
+					elephantine.LogKeyDocumentUUID, d.UUID)
+
+				failed++
+
+				continue
+			}
+
+			if ok {
+				upgraded++
+			}
+		}
+
+		position = docs[len(docs)-1].UUID
+
+		n, err := s.reader.UpdateSchemaUpgradeProgress(ctx,
+			postgres.UpdateSchemaUpgradeProgressParams{
+				Position:     position,
+				Processed:    int64(len(docs)),
+				Upgraded:     upgraded,
+				Failed:       failed,
+				GenerationID: run.GenerationID,
+				Created:      run.Created,
+			})
+		if err != nil {
+			return fmt.Errorf("update progress: %w", err)
+		}
+
+		if n == 0 {
+			return errRevalidationReplaced
+		}
+	}
+}
+
+// upgradeDocument writes a new version of a document if the transforms of
+// the active generation change it. Documents that have been updated since we
+// read them are skipped, as the update will have applied the transforms.
+func (s *PGDocStore) upgradeDocument(
+	ctx context.Context, generationID int64,
+	validator DocumentValidator, d postgres.GetCurrentDocumentsOfTypesRow,
+) (bool, error) {
+	if d.DocumentData == nil {
+		return false, nil
+	}
+
+	var doc newsdoc.Document
+
+	err := json.Unmarshal(d.DocumentData, &doc)
+	if err != nil {
+		return false, fmt.Errorf("unmarshal document: %w", err)
+	}
+
+	doc, applied := validator.TransformDocument(doc)
+	if len(applied) == 0 {
+		return false, nil
+	}
+
+	valCtx, deprecations := WithDeprecationCollector(ctx)
+
+	results, err := validator.ValidateDocument(valCtx, &doc)
+	if err != nil {
+		return false, fmt.Errorf("validate document: %w", err)
+	}
+
+	if len(results) > 0 {
+		return false, fmt.Errorf(
+			"the upgraded document had %d validation errors, the first one is: %v",
+			len(results), results[0].String())
+	}
+
+	_, err = s.Update(ctx, noWorkflows{}, []*UpdateRequest{{
+		UUID:     d.UUID,
+		Updated:  time.Now(),
+		Updater:  schemaUpgradeUpdater,
+		Document: &doc,
+		Meta: newsdoc.DataMap{
+			UpgradedByGenerationMeta: strconv.FormatInt(generationID, 10),
+			UpgradedByTransformsMeta: strings.Join(applied, ","),
+		},
+		IfMatch:          d.CurrentVersion,
+		SchemaGeneration: generationID,
+		Deprecations:     deprecations.Labels(),
+	}})
+	if IsDocStoreErrorCode(err, ErrCodeOptimisticLock) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("store upgraded version: %w", err)
+	}
+
+	return true, nil
+}
+
+func (s *PGDocStore) finishUpgrade(
+	ctx context.Context, run postgres.SchemaUpgrade,
+	status SchemaJobStatus, message string,
+) error {
+	err := s.reader.FinishSchemaUpgrade(ctx,
+		postgres.FinishSchemaUpgradeParams{
+			Status:       string(status),
+			Finished:     pg.Time(time.Now()),
+			Error:        pg.TextOrNull(message),
+			GenerationID: run.GenerationID,
+			Created:      run.Created,
+		})
+	if err != nil {
+		return fmt.Errorf("finish upgrade: %w", err)
+	}
+
+	return nil
+}
+
+// noWorkflows is used for schema upgrades, upgraded versions don't change
+// the content of a document and shouldn't progress its workflow state.
+type noWorkflows struct{}
+
+func (noWorkflows) HasStatus(_ string, _ string) bool {
+	return false
+}
+
+func (noWorkflows) EvaluateRules(_ StatusRuleInput) []StatusRuleViolation {
+	return nil
+}
+
+func (noWorkflows) GetDocumentWorkflow(_ string) (DocumentWorkflow, bool) {
+	return DocumentWorkflow{}, false
+}
+
+func (noWorkflows) GetAccessRules(_ string) []StatusRule {
+	return nil
+}
@@ -0,0 +1,249 @@
+package repository_test
+
+import (
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/ttab/newsdoc"
+	"github.com/ttab/revisor"
+	"github.com/twitchtv/twirp"
+)
+
+func TestApplyTransforms(t *testing.T) {
+	transforms := []repository.DocumentTransform{
+		{
+			Name:      "rename-duration",
+			Operation: repository.TransformRenameDataKey,
+			Block: repository.BlockSelector{
+				Kind: revisor.BlockKindMeta,
+				Type: "core/newsvalue",
+			},
+			Key: "halflife",
+			To:  "duration",
+		},
+		{
+			Name:         "section-as-link",
+			DocumentType: "core/article",
+			Operation:    repository.TransformMoveBlock,
+			Block: repository.BlockSelector{
+				Kind: revisor.BlockKindMeta,
+				Type: "core/section",
+			},
+			To: string(revisor.BlockKindLink),
+		},
+		{
+			Name:      "section-rel",
+			Operation: repository.TransformMapValue,
+			Block: repository.BlockSelector{
+				Kind: revisor.BlockKindLink,
+				Type: "core/section",
+			},
+			Key:  "rel",
+			From: "",
+			To:   "section",
+		},
+	}
+
+	err := repository.ValidateTransforms(transforms)
+	test.Must(t, err, "validate transforms")
+
+	doc := newsdoc.Document{
+		Type: "core/article",
+		Meta: []newsdoc.Block{
+			{
+				Type:  "core/newsvalue",
+				Value: "3",
+				Data:  newsdoc.DataMap{"halflife": "86400"},
+			},
+			{
+				Type:  "core/section",
+				Title: "Sports",
+			},
+		},
+	}
+
+	got, applied := repository.ApplyTransforms(doc, transforms)
+
+	test.EqualDiff(t, []string{
+		"rename-duration", "section-as-link", "section-rel",
+	}, applied, "apply all transforms")
+
+	test.EqualDiff(t, newsdoc.Document{
+		Type: "core/article",
+		Meta: []newsdoc.Block{
+			{
+				Type:  "core/newsvalue",
+				Value: "3",
+				Data:  newsdoc.DataMap{"duration": "86400"},
+			},
+		},
+		Links: []newsdoc.Block{
+			{
+				Type:  "core/section",
+				Title: "Sports",
+				Rel:   "section",
+			},
+		},
+	}, got, "get the transformed document")
+
+	test.EqualDiff(t, newsdoc.DataMap{"halflife": "86400"}, doc.Meta[0].Data,
+		"leave the original document unchanged")
+
+	again, applied := repository.ApplyTransforms(got, transforms)
+
+	test.Equal(t, 0, len(applied), "apply no transforms a second time")
+	test.EqualDiff(t, got, again, "leave the transformed document unchanged")
+
+	planning := doc
+	planning.Type = "core/planning-item"
+
+	_, applied = repository.ApplyTransforms(planning, transforms)
+
+	test.EqualDiff(t, []string{"rename-duration"}, applied,
+		"only apply transforms for the document type")
+
+	err = repository.ValidateTransforms([]repository.DocumentTransform{
+		transforms[0], transforms[0],
+	})
+	if err == nil {
+		t.Fatal("expected duplicate transform names to be rejected")
+	}
+}
+
+func TestIntegrationSchemaUpgrade(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+	ctx := t.Context()
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		RunSchemaUpgrade: true,
+		TransformOnRead:  true,
+	})
+
+	adminClaims := itest.Claims(t, "admin", "schema_admin")
+
+	schemas := tc.SchemasClient(t, adminClaims)
+	schemaExt := tc.ExtensionClient(t, rpc.SchemasPathPrefix, adminClaims)
+
+	editor := tc.DocumentsClient(t,
+		itest.Claims(t, "editor", "doc_read doc_write"))
+
+	docUUID := uuid.NewString()
+
+	_, err := editor.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     docUUID,
+		Document: baseDocument(docUUID, "article://test/upgrade"),
+	})
+	test.Must(t, err, "create document")
+
+	var started repository.StartUpgradeResponse
+
+	err = schemaExt.Call(ctx, "StartUpgrade",
+		repository.StartUpgradeRequest{}, &started)
+	itest.IsTwirpError(t, err, twirp.FailedPrecondition)
+
+	active, err := schemas.GetAllActive(ctx, &rpc.GetAllActiveSchemasRequest{})
+	test.Must(t, err, "get active schemas")
+
+	err = schemaExt.Call(ctx, "SetGenerationTransforms",
+		repository.SetGenerationTransformsRequest{
+			GenerationID: active.GenerationId,
+			Transforms: []repository.DocumentTransform{
+				{
+					Name:         "bump-newsvalue",
+					DocumentType: "core/article",
+					Operation:    repository.TransformMapValue,
+					Block: repository.BlockSelector{
+						Kind: revisor.BlockKindMeta,
+						Type: "core/newsvalue",
+					},
+					Key:  "value",
+					From: "3",
+					To:   "4",
+				},
+			},
+		}, &repository.SetGenerationTransformsResponse{})
+	test.Must(t, err, "set generation transforms")
+
+	err = tc.Validator.RefreshSchemas(ctx)
+	test.Must(t, err, "refresh validator")
+
+	read, err := editor.Get(ctx, &rpc.GetDocumentRequest{
+		Uuid: docUUID,
+	})
+	test.Must(t, err, "read document")
+
+	test.Equal(t, "4", read.Document.Meta[0].Value,
+		"transform the document on read")
+	test.Equal(t, int64(1), read.Version,
+		"read the stored version")
+
+	err = schemaExt.Call(ctx, "StartUpgrade",
+		repository.StartUpgradeRequest{}, &started)
+	test.Must(t, err, "start upgrade")
+
+	test.EqualDiff(t, []string{"core/article"}, started.Upgrade.Types,
+		"upgrade the document types of the transforms")
+
+	var status repository.GetUpgradeResponse
+
+	deadline := time.Now().Add(10 * time.Second)
+
+	for status.Upgrade == nil ||
+		status.Upgrade.Status == repository.SchemaJobRunning {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the upgrade to finish")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		err = schemaExt.Call(ctx, "GetUpgrade",
+			repository.GetUpgradeRequest{
+				GenerationID: active.GenerationId,
+			}, &status)
+		test.Must(t, err, "get upgrade status")
+	}
+
+	test.Equal(t, repository.SchemaJobDone, status.Upgrade.Status,
+		"finish the upgrade")
+	test.Equal(t, int64(1), status.Upgrade.Upgraded,
+		"upgrade the document")
+	test.Equal(t, int64(0), status.Upgrade.Failed,
+		"get no failed documents")
+
+	read, err = editor.Get(ctx, &rpc.GetDocumentRequest{
+		Uuid: docUUID,
+	})
+	test.Must(t, err, "read upgraded document")
+
+	test.Equal(t, int64(2), read.Version, "get an upgraded version")
+	test.Equal(t, "4", read.Document.Meta[0].Value,
+		"store the transformed document")
+
+	test.Equal(t, 1, len(read.Document.Meta),
+		"don't add blocks to the upgraded document")
+
+	history, err := editor.GetHistory(ctx, &rpc.GetHistoryRequest{
+		Uuid: docUUID,
+	})
+	test.Must(t, err, "read document history")
+
+	upgradeMeta := history.Versions[0].Meta
+
+	test.Equal(t, strconv.FormatInt(active.GenerationId, 10),
+		upgradeMeta[repository.UpgradedByGenerationMeta],
+		"record the generation in the version meta")
+	test.Equal(t, "bump-newsvalue",
+		upgradeMeta[repository.UpgradedByTransformsMeta],
+		"record the applied transforms in the version meta")
+}
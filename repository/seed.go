@@ -0,0 +1,529 @@
+package repository
+
+import (
+	"archive/zip"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ttab/eleconf"
+	rpc "github.com/ttab/elephant-api/repository"
+	"github.com/ttab/newsdoc"
+)
+
+// SeedOptions controls how documents are prepared for seeding by PrepareSeed.
+type SeedOptions struct {
+	// RemapUUIDs gives the documents new UUIDs derived from
+	// RemapNamespace. Links, URIs and meta documents that refer to the
+	// seeded documents are rewritten to use the new UUIDs.
+	RemapUUIDs     bool
+	RemapNamespace uuid.UUID
+	// ACLUnits replaces the ACLs of the documents with read and write
+	// grants for the units.
+	ACLUnits []string
+}
+
+// LoadSeedBundle reads the documents in an export bundle for import. The
+// bundle contents are checked against the checksums in the manifest.
+// Attachments aren't imported.
+func LoadSeedBundle(bundlePath string) ([]ImportDocument, error) {
+	zr, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("open bundle: %w", err)
+	}
+
+	defer zr.Close()
+
+	var manifest ExportManifest
+
+	err = readBundleJSON(&zr.Reader, ExportManifestName, "", &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	files := make(map[string][]string)
+
+	for _, f := range zr.File {
+		name, ok := strings.CutPrefix(f.Name, "documents/")
+		if !ok {
+			continue
+		}
+
+		docID, _, _ := strings.Cut(name, "/")
+
+		files[docID] = append(files[docID], f.Name)
+	}
+
+	docs := make([]ImportDocument, 0, len(manifest.Documents))
+
+	for _, docUUID := range manifest.Documents {
+		doc, err := loadBundleDocument(&zr.Reader, &manifest,
+			docUUID, files[docUUID.String()])
+		if err != nil {
+			return nil, fmt.Errorf("load document %s: %w", docUUID, err)
+		}
+
+		docs = append(docs, *doc)
+	}
+
+	return docs, nil
+}
+
+func loadBundleDocument(
+	zr *zip.Reader, manifest *ExportManifest,
+	docUUID uuid.UUID, names []string,
+) (*ImportDocument, error) {
+	prefix := fmt.Sprintf("documents/%s/", docUUID)
+
+	var info ExportedDocument
+
+	err := readBundleJSON(zr, prefix+"document.json",
+		manifest.Files[prefix+"document.json"], &info)
+	if err != nil {
+		return nil, fmt.Errorf("read document information: %w", err)
+	}
+
+	doc := ImportDocument{
+		UUID: docUUID.String(),
+	}
+
+	// Entries are named after zero padded version and status IDs, so
+	// sorting the names puts them in order.
+	slices.Sort(names)
+
+	for _, name := range names {
+		rest := strings.TrimPrefix(name, prefix)
+
+		switch {
+		case strings.HasPrefix(rest, "versions/"):
+			var v ArchivedDocumentVersion
+
+			err := readBundleJSON(zr, name, manifest.Files[name], &v)
+			if err != nil {
+				return nil, fmt.Errorf("read %s: %w", name, err)
+			}
+
+			var d newsdoc.Document
+
+			err = json.Unmarshal(v.DocumentData, &d)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"unmarshal document data of %s: %w", name, err)
+			}
+
+			meta, err := seedMetaFromJSON(v.Meta)
+			if err != nil {
+				return nil, fmt.Errorf("read meta of %s: %w", name, err)
+			}
+
+			doc.Versions = append(doc.Versions, ImportVersion{
+				Version:  v.Version,
+				Created:  v.Created,
+				Creator:  v.CreatorURI,
+				Meta:     meta,
+				Document: d,
+			})
+		case strings.HasPrefix(rest, "statuses/"):
+			var s ArchivedDocumentStatus
+
+			err := readBundleJSON(zr, name, manifest.Files[name], &s)
+			if err != nil {
+				return nil, fmt.Errorf("read %s: %w", name, err)
+			}
+
+			meta, err := seedMetaFromJSON(s.Meta)
+			if err != nil {
+				return nil, fmt.Errorf("read meta of %s: %w", name, err)
+			}
+
+			doc.Statuses = append(doc.Statuses, ImportStatus{
+				Name:    s.Name,
+				ID:      s.ID,
+				Version: s.Version,
+				Created: s.Created,
+				Creator: s.CreatorURI,
+				Meta:    meta,
+			})
+		}
+	}
+
+	if len(doc.Versions) == 0 {
+		return nil, errors.New("no versions in bundle")
+	}
+
+	// Statuses are imported in the order that they were set.
+	slices.SortStableFunc(doc.Statuses, func(a, b ImportStatus) int {
+		return a.Created.Compare(b.Created)
+	})
+
+	// The bundle only has the current ACL of the document, so set it
+	// with the latest version.
+	if info.MainDocument == nil {
+		doc.Versions[len(doc.Versions)-1].ACL = info.ACL
+	}
+
+	return &doc, nil
+}
+
+// readBundleJSON reads and unmarshals a JSON entry from a bundle, and checks
+// its hex encoded SHA-256 checksum if one is given.
+func readBundleJSON(
+	zr *zip.Reader, name string, checksum string, o any,
+) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		return fmt.Errorf("open entry: %w", err)
+	}
+
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("read entry: %w", err)
+	}
+
+	if checksum != "" {
+		sum := sha256.Sum256(data)
+
+		if hex.EncodeToString(sum[:]) != checksum {
+			return fmt.Errorf(
+				"%s doesn't match the manifest checksum", name)
+		}
+	}
+
+	err = json.Unmarshal(data, o)
+	if err != nil {
+		return fmt.Errorf("unmarshal entry: %w", err)
+	}
+
+	return nil
+}
+
+func seedMetaFromJSON(data json.RawMessage) (newsdoc.DataMap, error) {
+	var meta newsdoc.DataMap
+
+	if len(data) == 0 {
+		return meta, nil
+	}
+
+	err := json.Unmarshal(data, &meta)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal meta: %w", err)
+	}
+
+	return meta, nil
+}
+
+// LoadSeedDirectory reads the newsdoc JSON files in a directory, and its
+// subdirectories, for import as documents with a single version. The file
+// modification time is used as the creation time, so that unchanged files are
+// skipped when seeding is re-run. Documents without a UUID get one derived
+// from their URI.
+func LoadSeedDirectory(dir string, creator string) ([]ImportDocument, error) {
+	var docs []ImportDocument
+
+	err := filepath.WalkDir(dir, func(
+		name string, d fs.DirEntry, err error,
+	) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(d.Name()) != ".json" {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("stat %s: %w", name, err)
+		}
+
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+
+		var doc newsdoc.Document
+
+		err = json.Unmarshal(data, &doc)
+		if err != nil {
+			return fmt.Errorf("unmarshal %s: %w", name, err)
+		}
+
+		if doc.UUID == "" {
+			if doc.URI == "" {
+				return fmt.Errorf(
+					"%s has neither a UUID nor a URI", name)
+			}
+
+			doc.UUID = uuid.NewSHA1(
+				uuid.NameSpaceURL, []byte(doc.URI)).String()
+		}
+
+		docs = append(docs, ImportDocument{
+			UUID: doc.UUID,
+			Versions: []ImportVersion{{
+				Version:  1,
+				Created:  info.ModTime(),
+				Creator:  creator,
+				Document: doc,
+			}},
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load documents: %w", err)
+	}
+
+	return docs, nil
+}
+
+var seedUUIDExp = regexp.MustCompile(
+	`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// PrepareSeed remaps UUIDs and rewrites ACLs according to the options, and
+// orders the documents so that meta documents are imported after their main
+// documents.
+func PrepareSeed(
+	docs []ImportDocument, opts SeedOptions,
+) ([]ImportDocument, error) {
+	prepared := make([]ImportDocument, len(docs))
+
+	for i, doc := range docs {
+		if len(doc.Versions) == 0 {
+			return nil, fmt.Errorf("document %s has no versions", doc.UUID)
+		}
+
+		prepared[i] = doc
+		prepared[i].Versions = slices.Clone(doc.Versions)
+		prepared[i].Statuses = slices.Clone(doc.Statuses)
+	}
+
+	if opts.RemapUUIDs {
+		err := remapSeedUUIDs(prepared, opts.RemapNamespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(opts.ACLUnits) > 0 {
+		acl := make([]ACLEntry, len(opts.ACLUnits))
+
+		for i, unit := range opts.ACLUnits {
+			acl[i] = ACLEntry{
+				URI:         unit,
+				Permissions: []string{"r", "w"},
+			}
+		}
+
+		for _, doc := range prepared {
+			for i := range doc.Versions {
+				doc.Versions[i].ACL = nil
+			}
+
+			if !isSeedMetaDocument(doc) {
+				doc.Versions[0].ACL = acl
+			}
+		}
+	}
+
+	slices.SortStableFunc(prepared, func(a, b ImportDocument) int {
+		return cmp.Compare(
+			seedOrder(isSeedMetaDocument(a)),
+			seedOrder(isSeedMetaDocument(b)))
+	})
+
+	return prepared, nil
+}
+
+func seedOrder(isMeta bool) int {
+	if isMeta {
+		return 1
+	}
+
+	return 0
+}
+
+func isSeedMetaDocument(doc ImportDocument) bool {
+	return isMetaURI(doc.Versions[len(doc.Versions)-1].Document.URI)
+}
+
+// remapSeedUUIDs gives the documents new UUIDs derived from the namespace and
+// their old UUIDs. Meta documents get the UUID derived from the new UUID of
+// their main document. References to the old UUIDs in blocks, block data, and
+// version and status meta are rewritten.
+func remapSeedUUIDs(docs []ImportDocument, namespace uuid.UUID) error {
+	remap := make(map[string]string, len(docs))
+
+	for _, doc := range docs {
+		if isSeedMetaDocument(doc) {
+			continue
+		}
+
+		oldID, err := uuid.Parse(doc.UUID)
+		if err != nil {
+			return fmt.Errorf("invalid document UUID %q: %w",
+				doc.UUID, err)
+		}
+
+		remap[oldID.String()] = uuid.NewSHA1(
+			namespace, oldID[:]).String()
+	}
+
+	replace := func(s string) string {
+		return seedUUIDExp.ReplaceAllStringFunc(s, func(id string) string {
+			n, ok := remap[strings.ToLower(id)]
+			if !ok {
+				return id
+			}
+
+			return n
+		})
+	}
+
+	for i, doc := range docs {
+		if !isSeedMetaDocument(doc) {
+			docs[i].UUID = replace(strings.ToLower(doc.UUID))
+
+			continue
+		}
+
+		mainDoc, err := parseMetaURI(
+			replace(doc.Versions[len(doc.Versions)-1].Document.URI))
+		if err != nil {
+			return fmt.Errorf("meta document %s: %w", doc.UUID, err)
+		}
+
+		metaID, _ := metaIdentity(mainDoc)
+
+		docs[i].UUID = metaID.String()
+	}
+
+	for _, doc := range docs {
+		for i := range doc.Statuses {
+			doc.Statuses[i].Meta = remapSeedData(
+				doc.Statuses[i].Meta, replace)
+		}
+
+		for i := range doc.Versions {
+			doc.Versions[i].Meta = remapSeedData(
+				doc.Versions[i].Meta, replace)
+
+			d := doc.Versions[i].Document
+
+			d.UUID = doc.UUID
+			d.URI = replace(d.URI)
+			d.URL = replace(d.URL)
+			d.Links = remapSeedBlocks(d.Links, replace)
+			d.Meta = remapSeedBlocks(d.Meta, replace)
+			d.Content = remapSeedBlocks(d.Content, replace)
+
+			doc.Versions[i].Document = d
+		}
+	}
+
+	return nil
+}
+
+func remapSeedBlocks(
+	blocks []newsdoc.Block, replace func(string) string,
+) []newsdoc.Block {
+	if blocks == nil {
+		return nil
+	}
+
+	out := make([]newsdoc.Block, len(blocks))
+
+	for i, b := range blocks {
+		b.UUID = replace(b.UUID)
+		b.URI = replace(b.URI)
+		b.URL = replace(b.URL)
+		b.Data = remapSeedData(b.Data, replace)
+		b.Links = remapSeedBlocks(b.Links, replace)
+		b.Meta = remapSeedBlocks(b.Meta, replace)
+		b.Content = remapSeedBlocks(b.Content, replace)
+
+		out[i] = b
+	}
+
+	return out
+}
+
+func remapSeedData(
+	data newsdoc.DataMap, replace func(string) string,
+) newsdoc.DataMap {
+	if data == nil {
+		return nil
+	}
+
+	out := make(newsdoc.DataMap, len(data))
+
+	for k, v := range data {
+		out[k] = replace(v)
+	}
+
+	return out
+}
+
+// Seed imports documents through the Documents.Import extension method, in
+// batches of ImportMaxDocuments.
+func Seed(
+	ctx context.Context, client *ExtensionClient,
+	docs []ImportDocument, validateOnly bool,
+) ([]ImportResult, error) {
+	var results []ImportResult
+
+	for batch := range slices.Chunk(docs, ImportMaxDocuments) {
+		var res ImportResponse
+
+		err := client.Call(ctx, "Import", ImportRequest{
+			Documents:    batch,
+			ValidateOnly: validateOnly,
+		}, &res)
+		if err != nil {
+			return results, fmt.Errorf("import documents: %w", err)
+		}
+
+		results = append(results, res.Documents...)
+	}
+
+	return results, nil
+}
+
+// ApplySeedConfiguration activates the schemas and applies the repository
+// configuration, so that the statuses and document types that seeded
+// documents use are known.
+func ApplySeedConfiguration(
+	ctx context.Context, clients eleconf.Clients,
+	conf *eleconf.Config, schemas []eleconf.LoadedSchema,
+) error {
+	changes, err := eleconf.GetChanges(ctx, clients, conf, schemas,
+		nil, rpc.SchemaActivation_ACTIVATION_ACTIVE)
+	if err != nil {
+		return fmt.Errorf("get configuration changes: %w", err)
+	}
+
+	for _, change := range changes {
+		err := change.Execute(ctx, clients)
+		if err != nil {
+			_, desc := change.Describe()
+
+			return fmt.Errorf("apply %s: %w", desc, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,351 @@
+package repository_test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/ttab/eleconf"
+	rpc_newsdoc "github.com/ttab/elephant-api/newsdoc"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/ttab/newsdoc"
+)
+
+func TestPrepareSeedRemap(t *testing.T) {
+	namespace := uuid.New()
+	articleUUID := uuid.New()
+	sectionUUID := uuid.New()
+	otherUUID := uuid.NewString()
+
+	newArticle := uuid.NewSHA1(namespace, articleUUID[:]).String()
+	newSection := uuid.NewSHA1(namespace, sectionUUID[:]).String()
+
+	docs := []repository.ImportDocument{
+		{
+			UUID: articleUUID.String(),
+			Versions: []repository.ImportVersion{
+				{
+					Version: 1,
+					Meta: newsdoc.DataMap{
+						"duplicated_from": sectionUUID.String(),
+					},
+					Document: newsdoc.Document{
+						UUID: articleUUID.String(),
+						URI:  "article://test/" + articleUUID.String(),
+						Type: "core/article",
+						Meta: []newsdoc.Block{
+							{
+								Type: "core/note",
+								Data: newsdoc.DataMap{
+									"section": sectionUUID.String(),
+									"other":   otherUUID,
+								},
+							},
+						},
+					},
+				},
+			},
+			Statuses: []repository.ImportStatus{
+				{
+					Name:    "usable",
+					ID:      1,
+					Version: 1,
+					Meta: newsdoc.DataMap{
+						"source": "section://" + sectionUUID.String(),
+					},
+				},
+			},
+		},
+		{
+			UUID: sectionUUID.String(),
+			Versions: []repository.ImportVersion{
+				{
+					Version: 1,
+					Document: newsdoc.Document{
+						UUID: sectionUUID.String(),
+						URI:  "article://test/" + sectionUUID.String(),
+						Type: "core/article",
+					},
+				},
+			},
+		},
+	}
+
+	prepared, err := repository.PrepareSeed(docs, repository.SeedOptions{
+		RemapUUIDs:     true,
+		RemapNamespace: namespace,
+	})
+	test.Must(t, err, "prepare documents")
+
+	article := prepared[0]
+
+	test.Equal(t, newArticle, article.UUID, "remap the document UUID")
+	test.Equal(t, newSection, article.Versions[0].Meta["duplicated_from"],
+		"remap the version meta")
+	test.Equal(t, "section://"+newSection, article.Statuses[0].Meta["source"],
+		"remap the status meta")
+
+	data := article.Versions[0].Document.Meta[0].Data
+
+	test.Equal(t, newSection, data["section"], "remap the block data")
+	test.Equal(t, otherUUID, data["other"],
+		"leave UUIDs of other documents alone")
+
+	test.Equal(t, "section://"+sectionUUID.String(),
+		docs[0].Statuses[0].Meta["source"],
+		"leave the input statuses unchanged")
+	test.Equal(t, sectionUUID.String(),
+		docs[0].Versions[0].Document.Meta[0].Data["section"],
+		"leave the input blocks unchanged")
+}
+
+func TestIntegrationSeed(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelError))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		RunArchiver: true,
+	})
+
+	claims := itest.Claims(t, "seeder",
+		"doc_read doc_write doc_import doc_export")
+
+	client := tc.DocumentsClient(t, claims)
+	ext := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, claims)
+
+	config, err := eleconf.ReadConfigFromDirectory(
+		filepath.Join("..", "testdata", "config", "base"))
+	test.Must(t, err, "read repository configuration")
+
+	schemas, err := repository.LoadEmbeddedSchemaSet(
+		"se.ecms", "se.ecms.metadoc", "se.ecms.planning")
+	test.Must(t, err, "load core schemas")
+
+	err = repository.ApplySeedConfiguration(ctx, &eleconf.StaticClients{
+		Workflows: tc.WorkflowsClient(t,
+			itest.StandardClaims(t, repository.ScopeWorkflowAdmin)),
+		Schemas: tc.SchemasClient(t,
+			itest.StandardClaims(t, repository.ScopeSchemaAdmin)),
+		Metrics: tc.MetricsClient(t,
+			itest.StandardClaims(t, repository.ScopeMetricsAdmin)),
+	}, config, schemas)
+	test.Must(t, err, "apply an unchanged configuration")
+
+	sectionUUID := uuid.New()
+	articleUUID := uuid.New()
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid: sectionUUID.String(),
+		Document: baseDocument(sectionUUID.String(),
+			"article://test/"+sectionUUID.String()),
+	})
+	test.Must(t, err, "create the linked document")
+
+	article := baseDocument(articleUUID.String(),
+		"article://test/"+articleUUID.String())
+
+	article.Links = []*rpc_newsdoc.Block{
+		{
+			Uuid:  sectionUUID.String(),
+			Type:  "core/section",
+			Title: "A section",
+			Rel:   "section",
+		},
+	}
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     articleUUID.String(),
+		Document: article,
+	})
+	test.Must(t, err, "create the linking document")
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     articleUUID.String(),
+		Document: article,
+		Status: []*rpc.StatusUpdate{
+			{Name: "usable"},
+		},
+	})
+	test.Must(t, err, "update the linking document")
+
+	// Only archived versions and statuses are exported, so wait for the
+	// archiver to catch up.
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		var unarchived int64
+
+		err := tc.DB.QueryRow(ctx, `
+SELECT (SELECT COUNT(*) FROM document_version
+        WHERE uuid = ANY($1) AND archived = false)
+     + (SELECT COUNT(*) FROM document_status
+        WHERE uuid = ANY($1) AND archived = false)`,
+			[]uuid.UUID{sectionUUID, articleUUID}).Scan(&unarchived)
+		test.Must(t, err, "count unarchived versions and statuses")
+
+		if unarchived == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the documents to be archived")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	var started repository.StartExportResponse
+
+	err = ext.Call(ctx, "StartExport", repository.StartExportRequest{
+		UUIDs: []string{sectionUUID.String(), articleUUID.String()},
+	}, &started)
+	test.Must(t, err, "start export")
+
+	var exp repository.GetExportResponse
+
+	deadline = time.Now().Add(10 * time.Second)
+
+	for exp.Export == nil || exp.Export.Finished == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the export to finish")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		err := ext.Call(ctx, "GetExport", repository.GetExportRequest{
+			ID: started.Export.ID,
+		}, &exp)
+		test.Must(t, err, "get export")
+	}
+
+	test.Equal(t, repository.ExportDone, exp.Export.Status,
+		"complete the export")
+
+	obj, err := tc.Env.S3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(tc.Env.Bucket),
+		Key:    aws.String(exp.Export.ObjectKey),
+	})
+	test.Must(t, err, "get export bundle")
+
+	defer obj.Body.Close()
+
+	data, err := io.ReadAll(obj.Body)
+	test.Must(t, err, "read export bundle")
+
+	bundlePath := filepath.Join(t.TempDir(), "bundle.zip")
+
+	err = os.WriteFile(bundlePath, data, 0o600)
+	test.Must(t, err, "write export bundle")
+
+	bundleDocs, err := repository.LoadSeedBundle(bundlePath)
+	test.Must(t, err, "load export bundle")
+
+	namespace := uuid.New()
+
+	seedDocs, err := repository.PrepareSeed(bundleDocs, repository.SeedOptions{
+		RemapUUIDs:     true,
+		RemapNamespace: namespace,
+		ACLUnits:       []string{"core://unit/seeded"},
+	})
+	test.Must(t, err, "prepare documents")
+
+	results, err := repository.Seed(ctx, ext, seedDocs, false)
+	test.Must(t, err, "seed documents")
+
+	test.Equal(t, 2, len(results), "seed both documents")
+
+	for _, r := range results {
+		test.Equal(t, repository.ImportOutcomeImported, r.Outcome,
+			"import %s", r.UUID)
+	}
+
+	newSection := uuid.NewSHA1(namespace, sectionUUID[:]).String()
+	newArticle := uuid.NewSHA1(namespace, articleUUID[:]).String()
+
+	seeded, err := client.Get(ctx, &rpc.GetDocumentRequest{
+		Uuid: newArticle,
+	})
+	test.Must(t, err, "get the seeded document")
+
+	test.Equal(t, "article://test/"+newArticle, seeded.Document.Uri,
+		"remap the UUID in the URI")
+	test.Equal(t, newSection, seeded.Document.Links[0].Uuid,
+		"remap the link")
+
+	original, err := client.GetMeta(ctx, &rpc.GetMetaRequest{
+		Uuid: articleUUID.String(),
+	})
+	test.Must(t, err, "get the original metadata")
+
+	meta, err := client.GetMeta(ctx, &rpc.GetMetaRequest{
+		Uuid: newArticle,
+	})
+	test.Must(t, err, "get the seeded metadata")
+
+	test.Equal(t, original.Meta.Created, meta.Meta.Created,
+		"keep the creation time")
+	test.Equal(t, int64(2), meta.Meta.CurrentVersion,
+		"seed the version history")
+	test.Equal(t, int64(1), meta.Meta.Heads["usable"].Id,
+		"seed the status history")
+
+	var unitGrant bool
+
+	for _, e := range meta.Meta.Acl {
+		if e.Uri == "core://unit/seeded" {
+			unitGrant = true
+		}
+	}
+
+	test.Equal(t, true, unitGrant, "grant access to the test unit")
+
+	results, err = repository.Seed(ctx, ext, seedDocs, false)
+	test.Must(t, err, "re-run seeding")
+
+	for _, r := range results {
+		test.Equal(t, repository.ImportOutcomeUnchanged, r.Outcome,
+			"skip %s when re-seeding", r.UUID)
+	}
+
+	dir := t.TempDir()
+	fileUUID := uuid.NewString()
+
+	docData, err := json.Marshal(rpc_newsdoc.DocumentFromRPC(
+		baseDocument(fileUUID, "article://test/"+fileUUID)))
+	test.Must(t, err, "marshal document")
+
+	err = os.WriteFile(filepath.Join(dir, "article.json"), docData, 0o600)
+	test.Must(t, err, "write document file")
+
+	dirDocs, err := repository.LoadSeedDirectory(dir,
+		"core://application/test-seed")
+	test.Must(t, err, "load document directory")
+
+	results, err = repository.Seed(ctx, ext, dirDocs, false)
+	test.Must(t, err, "seed documents from directory")
+
+	test.Equal(t, repository.ImportOutcomeImported, results[0].Outcome,
+		"import the document file")
+
+	fileMeta, err := client.GetMeta(ctx, &rpc.GetMetaRequest{
+		Uuid: fileUUID,
+	})
+	test.Must(t, err, "get metadata of the document file")
+
+	test.Equal(t, "core://application/test-seed", fileMeta.Meta.CreatorUri,
+		"set the creator of the document file")
+}
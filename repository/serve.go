@@ -43,7 +43,7 @@ func ListenAndServe(
 		AllowInsecure:          false,
 		AllowInsecureLocalhost: true,
 		Hosts:                  corsHosts,
-		AllowedMethods:         []string{"GET", "POST"},
+		AllowedMethods:         []string{"GET", "POST", "PUT"},
 		AllowedHeaders:         []string{"Authorization", "Content-Type", "Last-Event-ID"},
 	}, handler)
 
@@ -84,6 +84,10 @@ type ServerOptions struct {
 	AuthMiddleware func(
 		w http.ResponseWriter, r *http.Request, next http.Handler,
 	) error
+	// TrustedProxies is the number of proxies in front of the
+	// repository that append to X-Forwarded-For, used to resolve the
+	// client IP that is recorded in the read audit log.
+	TrustedProxies int
 }
 
 func (so *ServerOptions) SetJWTValidation(parser elephantine.AuthInfoParser) {
@@ -128,7 +132,7 @@ func WithDocumentsAPI(
 			twirp.WithServerHooks(opts.Hooks),
 		)
 
-		registerAPI(router, opts, api)
+		registerAPI(router, opts, api, service)
 
 		return nil
 	}
@@ -145,7 +149,20 @@ func WithSchemasAPI(
 			twirp.WithServerHooks(opts.Hooks),
 		)
 
-		registerAPI(router, opts, api)
+		registerAPI(router, opts, api, service)
+
+		return nil
+	}
+}
+
+func WithTemplatesAPI(
+	service *TemplatesService,
+	opts ServerOptions,
+) RouterOption {
+	return func(router *httprouter.Router) error {
+		api := extensionOnlyServer{prefix: TemplatesPathPrefix}
+
+		registerAPI(router, opts, api, service)
 
 		return nil
 	}
@@ -162,7 +179,7 @@ func WithWorkflowsAPI(
 			twirp.WithServerHooks(opts.Hooks),
 		)
 
-		registerAPI(router, opts, api)
+		registerAPI(router, opts, api, service)
 
 		return nil
 	}
@@ -196,14 +213,71 @@ func WithSSE(
 
 func WithWebsocket(
 	handler http.Handler,
+	opt ServerOptions,
 ) RouterOption {
 	return func(router *httprouter.Router) error {
 		router.GET("/websocket/:token", internal.RHandleFunc(func(
 			w http.ResponseWriter, r *http.Request, _ httprouter.Params,
 		) error {
+			r = r.WithContext(withClientIP(r.Context(), r,
+				opt.TrustedProxies))
+
+			handler.ServeHTTP(w, r)
+
+			return nil
+		}))
+
+		return nil
+	}
+}
+
+// WithAssetProxy registers the endpoints that proxy uploads and downloads of
+// attached objects through the repository:
+//
+//   - PUT /uploads/{id}
+//   - GET /attachments/{document}/{name}?version={version}
+func WithAssetProxy(
+	proxy *AssetProxy,
+	opt ServerOptions,
+) RouterOption {
+	return func(router *httprouter.Router) error {
+		serve := func(
+			w http.ResponseWriter, r *http.Request,
+			fn func(http.ResponseWriter, *http.Request) error,
+		) error {
+			handler := internal.HandleFunc(fn)
+
+			r = r.WithContext(withClientIP(r.Context(), r,
+				opt.TrustedProxies))
+
+			if opt.AuthMiddleware != nil {
+				return opt.AuthMiddleware(w, r, handler)
+			}
+
 			handler.ServeHTTP(w, r)
 
 			return nil
+		}
+
+		router.PUT("/uploads/:id", internal.RHandleFunc(func(
+			w http.ResponseWriter, r *http.Request, p httprouter.Params,
+		) error {
+			return serve(w, r, func(
+				w http.ResponseWriter, r *http.Request,
+			) error {
+				return proxy.Upload(w, r, p.ByName("id"))
+			})
+		}))
+
+		router.GET("/attachments/:document/:name", internal.RHandleFunc(func(
+			w http.ResponseWriter, r *http.Request, p httprouter.Params,
+		) error {
+			return serve(w, r, func(
+				w http.ResponseWriter, r *http.Request,
+			) error {
+				return proxy.Download(w, r,
+					p.ByName("document"), p.ByName("name"))
+			})
 		}))
 
 		return nil
@@ -221,7 +295,7 @@ func WithMetricsAPI(
 			twirp.WithServerHooks(opts.Hooks),
 		)
 
-		registerAPI(router, opts, api)
+		registerAPI(router, opts, api, service)
 
 		return nil
 	}
@@ -344,16 +418,34 @@ type apiServerForRouter interface {
 
 func registerAPI(
 	router *httprouter.Router, opt ServerOptions,
-	api apiServerForRouter,
+	api apiServerForRouter, service any,
 ) {
+	var extensions ExtensionMethods
+
+	if p, ok := service.(ExtensionProvider); ok {
+		extensions = p.ExtensionMethods()
+	}
+
 	router.POST(api.PathPrefix()+":method", internal.RHandleFunc(func(
-		w http.ResponseWriter, r *http.Request, _ httprouter.Params,
+		w http.ResponseWriter, r *http.Request, p httprouter.Params,
 	) error {
+		var handler http.Handler = api
+
+		method := p.ByName("method")
+
+		if fn, ok := extensions[method]; ok {
+			handler = newExtensionHandler(
+				opt.Hooks, api.PathPrefix(), method, fn)
+		}
+
+		r = r.WithContext(withClientIP(r.Context(), r,
+			opt.TrustedProxies))
+
 		if opt.AuthMiddleware != nil {
-			return opt.AuthMiddleware(w, r, api)
+			return opt.AuthMiddleware(w, r, handler)
 		}
 
-		api.ServeHTTP(w, r)
+		handler.ServeHTTP(w, r)
 
 		return nil
 	}))
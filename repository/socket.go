@@ -80,6 +80,42 @@ func (c EventlogStreamConfig) withDefaults() EventlogStreamConfig {
 	return c
 }
 
+// DefaultDocumentSetBufferSize is the default number of document stream items
+// that can be queued for processing by a document set.
+const DefaultDocumentSetBufferSize = 128
+
+// DocumentSetConfig configures the backpressure handling of document sets.
+// When the processing buffer of a set overflows the set is resynchronised
+// against the current state of the documents.
+type DocumentSetConfig struct {
+	// BufferSize is the number of document stream items that can be
+	// queued for processing by a set. Defaults to
+	// DefaultDocumentSetBufferSize.
+	BufferSize int
+	// TypeBufferSizes overrides the buffer size for sets of specific
+	// document types.
+	TypeBufferSizes map[string]int
+}
+
+func (c DocumentSetConfig) withDefaults() DocumentSetConfig {
+	if c.BufferSize == 0 {
+		c.BufferSize = DefaultDocumentSetBufferSize
+	}
+
+	return c
+}
+
+// bufferSizeFor returns the processing buffer size for sets of the given
+// document type.
+func (c DocumentSetConfig) bufferSizeFor(docType string) int {
+	size, ok := c.TypeBufferSizes[docType]
+	if ok && size > 0 {
+		return size
+	}
+
+	return c.BufferSize
+}
+
 func NewSocketHandler(
 	ctx context.Context,
 	logger *slog.Logger,
@@ -90,8 +126,10 @@ func NewSocketHandler(
 	socketKey *ecdsa.PublicKey,
 	corsHosts []string,
 	eventlog EventlogStreamConfig,
+	docSets DocumentSetConfig,
 ) (*SocketHandler, error) {
 	eventlog = eventlog.withDefaults()
+	docSets = docSets.withDefaults()
 
 	// A zero BufferSize has been defaulted above; an explicitly negative value
 	// is a misconfiguration that NewDocumentStream rejects.
@@ -111,6 +149,7 @@ func NewSocketHandler(
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 10240,
+			Subprotocols:    []string{SocketProtocolV2},
 			CheckOrigin: func(r *http.Request) bool {
 				origin := r.Header.Get("Origin")
 
@@ -146,6 +185,7 @@ func NewSocketHandler(
 		socketKey: socketKey,
 		rate:      rateLimiterCache,
 		eventlog:  eventlog,
+		docSets:   docSets,
 	}
 
 	prom := elephantine.NewMetricsHelper(metricsRegisterer)
@@ -166,6 +206,11 @@ func NewSocketHandler(
 		Name: "repository_websocket_response_total",
 	}, []string{"method", "status", "response"})
 
+	prom.CounterVec(&h.docSetResyncs, prometheus.CounterOpts{
+		Name: "repository_document_set_resync_total",
+		Help: "Number of document set resynchronisations after processing buffer overflows.",
+	}, []string{"type", "result"})
+
 	if err := prom.Err(); err != nil {
 		return nil, fmt.Errorf("register metrics: %w", err)
 	}
@@ -184,11 +229,13 @@ type SocketHandler struct {
 	socketKey *ecdsa.PublicKey
 	rate      *sturdyc.Client[*rate.Limiter]
 	eventlog  EventlogStreamConfig
+	docSets   DocumentSetConfig
 
 	openSockets    prometheus.Gauge
 	socketRejected *prometheus.CounterVec
 	socketCall     *prometheus.CounterVec
 	socketResponse *prometheus.CounterVec
+	docSetResyncs  *prometheus.CounterVec
 }
 
 func (h *SocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -252,7 +299,9 @@ func (h *SocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	sess := NewSocketSession(
 		conn, h.log, h.store, h.cache, h.stream, h.auth,
 		h.socketCall, h.socketResponse, h.socketRejected,
-		h.eventlog,
+		h.docSetResyncs, h.eventlog, h.docSets,
+		conn.Subprotocol() == SocketProtocolV2,
+		clientIPFromContext(r.Context()),
 	)
 
 	h.openSockets.Inc()
@@ -270,7 +319,11 @@ func NewSocketSession(
 	socketCall *prometheus.CounterVec,
 	socketResponse *prometheus.CounterVec,
 	socketRejected *prometheus.CounterVec,
+	docSetResyncs *prometheus.CounterVec,
 	eventlog EventlogStreamConfig,
+	docSets DocumentSetConfig,
+	envelopes bool,
+	clientIP string,
 ) *SocketSession {
 	return &SocketSession{
 		conn:           conn,
@@ -284,9 +337,13 @@ func NewSocketSession(
 		sets:           make(map[string]*documentSetHandle),
 		eventlogs:      make(map[string]*eventlogHandle),
 		eventlog:       eventlog,
+		docSets:        docSets,
+		envelopes:      envelopes,
 		socketRejected: socketRejected,
+		docSetResyncs:  docSetResyncs,
 		socketCall:     socketCall,
 		socketResponse: socketResponse,
+		clientIP:       clientIP,
 	}
 }
 
@@ -300,8 +357,14 @@ type SocketSession struct {
 	socketCall     *prometheus.CounterVec
 	socketResponse *prometheus.CounterVec
 	socketRejected *prometheus.CounterVec
+	docSetResyncs  *prometheus.CounterVec
 
 	eventlog EventlogStreamConfig
+	docSets  DocumentSetConfig
+
+	// envelopes is true if the client uses the SocketProtocolV2
+	// subprotocol, where messages are wrapped in envelopes.
+	envelopes bool
 
 	authExpired *time.Ticker
 
@@ -316,6 +379,8 @@ type SocketSession struct {
 
 	sets      map[string]*documentSetHandle
 	eventlogs map[string]*eventlogHandle
+
+	clientIP string
 }
 
 func (s *SocketSession) setAuth(auth *elephantine.AuthInfo) {
@@ -342,6 +407,26 @@ func (s *SocketSession) getAuth() (*elephantine.AuthInfo, []string) {
 	return s.auth, s.identity
 }
 
+var _ ReadRecorder = &SocketSession{}
+
+// RecordReads implements ReadRecorder.
+func (s *SocketSession) RecordReads(
+	ctx context.Context, reads []DocumentRead,
+) error {
+	auth, _ := s.getAuth()
+	if auth == nil {
+		return errors.New("no authentication information")
+	}
+
+	err := s.store.RecordReads(ctx, newReadAuditRecord(
+		auth, s.clientIP, ReadAuditSocket, reads))
+	if err != nil {
+		return fmt.Errorf("record reads: %w", err)
+	}
+
+	return nil
+}
+
 func (s *SocketSession) Run(ctx context.Context) {
 	runCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -598,6 +683,18 @@ func (s *SocketSession) handleGetDocuments(
 		timespan = &ts
 	}
 
+	var filter *documentFilter
+
+	if req.Filter != nil {
+		f, err := documentFilterFromRPC(req.Filter)
+		if err != nil {
+			return nil, SockErrorf(string(twirp.InvalidArgument),
+				"invalid filter: %v", err)
+		}
+
+		filter = f
+	}
+
 	var includeExtractors []*newsdoc.ValueExtractor
 
 	for _, extr := range req.Include {
@@ -647,21 +744,41 @@ func (s *SocketSession) handleGetDocuments(
 		previous.Close()
 	}
 
-	handle := newDocumentSetHandle(ctx, callHandle, s)
+	var resume *setCursor
+
+	if callHandle.SetCursor != "" {
+		c, err := parseSetCursor(callHandle.SetCursor)
+		if err != nil {
+			return nil, SockErrorf(string(twirp.InvalidArgument),
+				"invalid set cursor: %v", err)
+		}
+
+		resume = &c
+	}
+
+	definition, err := documentSetDefinition(req)
+	if err != nil {
+		return nil, SockErrorf(string(twirp.Internal),
+			"create set definition: %v", err)
+	}
+
+	handle := newDocumentSetHandle(ctx, callHandle, s, s)
 
 	_, identity := s.getAuth()
 
 	docSet := newDocumentSet(
 		req.Type, req.IncludeAcls, req.SetName,
 		timespan, req.Labels,
+		filter,
 		includeExtractors,
 		subsetExtractors,
 		inclusionSubsets,
-		identity, s.cache, s.store, handle)
+		definition, identity, s.cache, s.store, handle,
+		s.docSets.bufferSizeFor(req.Type), s.docSetResyncs)
 
 	handle.Set = docSet
 
-	err := docSet.Initialise(handle.ctx, s.stream)
+	err = docSet.Initialise(handle.ctx, s.stream, resume)
 	if err != nil {
 		handle.Close()
 
@@ -1002,16 +1119,32 @@ func (h *eventlogHandle) hasReadPermission(docUUID uuid.UUID) bool {
 func (s *SocketSession) Respond(
 	ctx context.Context, handle *CallHandle, resp *rsock.Response, final bool,
 ) {
-	if resp == nil {
-		resp = &rsock.Response{}
-	}
-
-	rh := &responseHandle{
+	s.respond(ctx, &responseHandle{
 		CallHandle: handle,
 		Response:   resp,
 		Final:      final,
+	})
+}
+
+// RespondWithCursor implements SocketResponder.
+func (s *SocketSession) RespondWithCursor(
+	ctx context.Context, handle *CallHandle, resp *rsock.Response,
+	cursor string,
+) {
+	s.respond(ctx, &responseHandle{
+		CallHandle: handle,
+		Response:   resp,
+		SetCursor:  cursor,
+	})
+}
+
+func (s *SocketSession) respond(ctx context.Context, rh *responseHandle) {
+	if rh.Response == nil {
+		rh.Response = &rsock.Response{}
 	}
 
+	resp := rh.Response
+
 	status := "ok"
 
 	var response string
@@ -1036,8 +1169,8 @@ func (s *SocketSession) Respond(
 
 	var method string
 
-	if handle != nil {
-		method = handle.Method
+	if rh.CallHandle != nil {
+		method = rh.CallHandle.Method
 	}
 
 	s.socketResponse.WithLabelValues(method, status, response).Inc()
@@ -1115,6 +1248,12 @@ type CallHandle struct {
 	Protobuf bool
 	Method   string
 	Call     *rsock.Call
+	// SetCursors is true if the client uses the SocketProtocolV2
+	// subprotocol, which can carry set cursors.
+	SetCursors bool
+	// SetCursor is the cursor that the client wants to resume a document
+	// set from.
+	SetCursor string
 }
 
 func (s *SocketSession) readCall() (*CallHandle, error) {
@@ -1129,14 +1268,20 @@ func (s *SocketSession) readCall() (*CallHandle, error) {
 	}
 
 	var (
-		call     rsock.Call
-		useProto bool
+		call      rsock.Call
+		useProto  = msgType == websocket.BinaryMessage
+		setCursor string
 	)
 
+	if s.envelopes {
+		body, setCursor, err = unwrapEnvelope(body, useProto)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	switch msgType {
 	case websocket.BinaryMessage:
-		useProto = true
-
 		err := proto.Unmarshal(body, &call)
 		if err != nil {
 			return nil, fmt.Errorf("unmarshal protobuf message: %w", err)
@@ -1149,8 +1294,10 @@ func (s *SocketSession) readCall() (*CallHandle, error) {
 	}
 
 	return &CallHandle{
-		Protobuf: useProto,
-		Call:     &call,
+		Protobuf:   useProto,
+		Call:       &call,
+		SetCursors: s.envelopes,
+		SetCursor:  setCursor,
 	}, nil
 }
 
@@ -1158,6 +1305,7 @@ type responseHandle struct {
 	CallHandle *CallHandle
 	Response   *rsock.Response
 	Final      bool
+	SetCursor  string
 }
 
 func (s *SocketSession) writeResponse(
@@ -1194,6 +1342,15 @@ func (s *SocketSession) writeResponse(
 		data = d
 	}
 
+	if s.envelopes {
+		d, err := wrapEnvelope(data, r.SetCursor, useProtobuf)
+		if err != nil {
+			return err
+		}
+
+		data = d
+	}
+
 	err := s.writeMessage(msgType, data)
 	if err != nil {
 		return sockFatalf("write message to socket: %w", err)
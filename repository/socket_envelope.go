@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// SocketProtocolV2 is the websocket subprotocol that wraps calls and
+// responses in an envelope. The socket protocol messages don't have fields
+// for document set cursors, so the envelope carries them next to the message.
+// Clients that don't ask for the subprotocol get the unwrapped messages, and
+// no set cursors.
+//
+// Binary messages are encoded as the protobuf message:
+//
+//	message Envelope {
+//	  // Message is the encoded Call or Response.
+//	  bytes message = 1;
+//	  string set_cursor = 2;
+//	}
+//
+// Text messages are encoded as the JSON object:
+//
+//	{"message": {...}, "set_cursor": "..."}
+//
+// A call resumes a document set if it has a set cursor. Document set
+// responses carry the set cursor when the client has everything up to a new
+// position.
+const SocketProtocolV2 = "elephant-repository.v2"
+
+const (
+	envelopeMessageField   protowire.Number = 1
+	envelopeSetCursorField protowire.Number = 2
+)
+
+type jsonEnvelope struct {
+	Message   json.RawMessage `json:"message"`
+	SetCursor string          `json:"set_cursor,omitempty"`
+}
+
+// unwrapEnvelope returns the message and set cursor of an envelope.
+func unwrapEnvelope(body []byte, binary bool) ([]byte, string, error) {
+	if !binary {
+		var env jsonEnvelope
+
+		err := json.Unmarshal(body, &env)
+		if err != nil {
+			return nil, "", fmt.Errorf("unmarshal json envelope: %w", err)
+		}
+
+		if len(env.Message) == 0 {
+			return nil, "", errors.New("the envelope has no message")
+		}
+
+		return env.Message, env.SetCursor, nil
+	}
+
+	var (
+		message    []byte
+		hasMessage bool
+		cursor     string
+	)
+
+	for len(body) > 0 {
+		num, typ, n := protowire.ConsumeTag(body)
+		if n < 0 {
+			return nil, "", fmt.Errorf(
+				"invalid envelope tag: %w", protowire.ParseError(n))
+		}
+
+		body = body[n:]
+
+		switch {
+		case num == envelopeMessageField && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(body)
+			if n < 0 {
+				return nil, "", fmt.Errorf(
+					"invalid envelope message: %w",
+					protowire.ParseError(n))
+			}
+
+			message = v
+			hasMessage = true
+			body = body[n:]
+		case num == envelopeSetCursorField && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(body)
+			if n < 0 {
+				return nil, "", fmt.Errorf(
+					"invalid envelope set cursor: %w",
+					protowire.ParseError(n))
+			}
+
+			cursor = v
+			body = body[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, body)
+			if n < 0 {
+				return nil, "", fmt.Errorf(
+					"invalid envelope field %d: %w",
+					num, protowire.ParseError(n))
+			}
+
+			body = body[n:]
+		}
+	}
+
+	if !hasMessage {
+		return nil, "", errors.New("the envelope has no message")
+	}
+
+	return message, cursor, nil
+}
+
+// wrapEnvelope wraps an encoded message and an optional set cursor in an
+// envelope.
+func wrapEnvelope(message []byte, cursor string, binary bool) ([]byte, error) {
+	if !binary {
+		data, err := json.Marshal(jsonEnvelope{
+			Message:   message,
+			SetCursor: cursor,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("marshal json envelope: %w", err)
+		}
+
+		return data, nil
+	}
+
+	data := protowire.AppendTag(nil, envelopeMessageField, protowire.BytesType)
+	data = protowire.AppendBytes(data, message)
+
+	if cursor != "" {
+		data = protowire.AppendTag(data,
+			envelopeSetCursorField, protowire.BytesType)
+		data = protowire.AppendString(data, cursor)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,142 @@
+package repository_test
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	"github.com/ttab/elephant-api/repositorysocket"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+)
+
+func TestIntegrationSocketDocumentFilter(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		RunEventlogBuilder: true,
+	})
+
+	client, conn, rc := dialEventlogSocket(t, tc)
+
+	highUUID := uuid.NewString()
+	high := baseDocument(highUUID, "article://test/"+highUUID)
+	high.Meta[0].Value = "5"
+
+	highRes, err := client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     highUUID,
+		Document: high,
+	})
+	test.Must(t, err, "create high newsvalue article")
+
+	lowUUID := uuid.NewString()
+
+	lowRes, err := client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     lowUUID,
+		Document: baseDocument(lowUUID, "article://test/"+lowUUID),
+	})
+	test.Must(t, err, "create low newsvalue article")
+
+	badCall := makeCall(t, conn, &repositorysocket.Call{
+		GetDocuments: &repositorysocket.GetDocuments{
+			SetName: "bad-filter",
+			Type:    "core/article",
+			Filter: &rpc.DocumentFilter{
+				Expression: ".meta(type='broken",
+				Operator:   rpc.FilterOperator_FILTER_OP_ANY,
+			},
+		},
+	})
+
+	_, err = rc.AwaitResponse(badCall, nil, 2*time.Second)
+	if err == nil {
+		t.Fatal("expected a malformed filter expression to be rejected")
+	}
+
+	// Articles with a high newsvalue, or that have a current "usable"
+	// status.
+	const setCall = "4f0d1e8e-7d4b-4b8f-9a57-5d2f3c1f0a11"
+
+	makeCall(t, conn, &repositorysocket.Call{
+		CallId: setCall,
+		GetDocuments: &repositorysocket.GetDocuments{
+			SetName: "filtered",
+			Type:    "core/article",
+			Filter: &rpc.DocumentFilter{
+				Or: []*rpc.DocumentFilter{
+					{
+						Expression: ".meta(type='core/newsvalue')@{value}",
+						Operator:   rpc.FilterOperator_FILTER_OP_ANY,
+						Values: []*rpc.FilterValues{
+							{Values: map[string]string{"value": "5"}},
+							{Values: map[string]string{"value": "6"}},
+						},
+					},
+					{
+						Expression: repository.FilterExpressionHeads,
+						Operator:   rpc.FilterOperator_FILTER_OP_ANY,
+						Values: []*rpc.FilterValues{
+							{Values: map[string]string{
+								"name":    "usable",
+								"current": "true",
+							}},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	batch, err := rc.AwaitDocumentBatch(setCall, 2*time.Second)
+	test.Must(t, err, "get initial document batch")
+
+	test.Equal(t, 1, len(batch.DocumentBatch.Documents),
+		"only include matching documents in the initial batch")
+	test.Equal(t, highUUID, batch.DocumentBatch.Documents[0].Uuid,
+		"include the high newsvalue article")
+
+	_, err = rc.AwaitResponse(setCall, nil, 2*time.Second)
+	test.Must(t, err, "subscribe to document set")
+
+	// A status change makes the low newsvalue article enter the set.
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid: lowUUID,
+		Status: []*rpc.StatusUpdate{
+			{Name: "usable", Version: lowRes.Version},
+		},
+	})
+	test.Must(t, err, "publish the low newsvalue article")
+
+	added, err := rc.AwaitDocumentStatus(setCall, lowUUID, "usable", 1,
+		5*time.Second)
+	test.Must(t, err, "get the low newsvalue article added to the set")
+
+	if added.DocumentUpdate.Document == nil {
+		t.Fatal("expected the document to be sent when it enters the set")
+	}
+
+	// Lowering the newsvalue makes the high newsvalue article leave the
+	// set.
+	high.Meta[0].Value = "1"
+
+	_, err = client.Update(ctx, &rpc.UpdateRequest{
+		Uuid:     highUUID,
+		Document: high,
+		IfMatch:  highRes.Version,
+	})
+	test.Must(t, err, "lower the newsvalue")
+
+	_, err = rc.AwaitResponse(setCall,
+		func(resp *repositorysocket.Response) bool {
+			return resp.Removed != nil &&
+				resp.Removed.DocumentUuid == highUUID
+		}, 5*time.Second)
+	test.Must(t, err, "get the high newsvalue article removed from the set")
+}
@@ -0,0 +1,147 @@
+package repository_test
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	rpc "github.com/ttab/elephant-api/repository"
+	"github.com/ttab/elephant-api/repositorysocket"
+	"github.com/ttab/elephantine/test"
+)
+
+func TestIntegrationSocketDocumentSetResume(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		RunEventlogBuilder: true,
+	})
+
+	client, conn, rc := dialEnvelopeSocket(t, tc)
+
+	createArticle := func(docUUID string) {
+		t.Helper()
+
+		_, err := client.Update(ctx, &rpc.UpdateRequest{
+			Uuid: docUUID,
+			Document: baseDocument(
+				docUUID, "article://test/"+docUUID),
+		})
+		test.Must(t, err, "create article %s", docUUID)
+	}
+
+	var (
+		untouched = uuid.NewString()
+		updated   = uuid.NewString()
+		deleted   = uuid.NewString()
+		created   = uuid.NewString()
+	)
+
+	createArticle(untouched)
+	createArticle(updated)
+	createArticle(deleted)
+
+	getArticles := &repositorysocket.GetDocuments{
+		SetName: "articles",
+		Type:    "core/article",
+	}
+
+	setCall := makeEnvelopeCall(t, conn, &repositorysocket.Call{
+		GetDocuments: getArticles,
+	}, "", true)
+
+	_, err := rc.AwaitResponse(setCall, nil, 2*time.Second)
+	test.Must(t, err, "subscribe to document set")
+
+	// Touch a document so that the set gets a known position.
+	createArticle(updated)
+
+	_, err = rc.AwaitDocumentUpdate(setCall, updated, 2*time.Second)
+	test.Must(t, err, "get document update")
+
+	cursor := rc.SetCursor(setCall)
+	if cursor == "" {
+		t.Fatal("expected a set cursor with the document update")
+	}
+
+	err = conn.WriteMessage(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	test.Must(t, err, "close websocket")
+
+	createArticle(updated)
+	createArticle(created)
+
+	_, err = client.Delete(ctx, &rpc.DeleteDocumentRequest{
+		Uuid: deleted,
+	})
+	test.Must(t, err, "delete article")
+
+	_, conn, rc = dialEnvelopeSocket(t, tc)
+
+	resumeCall := makeEnvelopeCall(t, conn, &repositorysocket.Call{
+		GetDocuments: getArticles,
+	}, cursor, true)
+
+	pending := map[string]bool{
+		updated: true,
+		created: true,
+	}
+
+	var removed bool
+
+	_, err = rc.AwaitResponse(resumeCall,
+		func(resp *repositorysocket.Response) bool {
+			switch {
+			case resp.DocumentBatch != nil:
+				for _, state := range resp.DocumentBatch.Documents {
+					if state.Uuid == untouched {
+						t.Fatal("untouched document was re-sent on resume")
+					}
+
+					delete(pending, state.Uuid)
+				}
+			case resp.DocumentUpdate != nil &&
+				resp.DocumentUpdate.Document != nil:
+				delete(pending, resp.DocumentUpdate.Event.Uuid)
+			case resp.Removed != nil:
+				removed = removed ||
+					resp.Removed.DocumentUuid == deleted
+			}
+
+			return len(pending) == 0 && removed
+		}, 10*time.Second)
+	test.Must(t, err, "get the changes since the cursor")
+
+	// A cursor for a different set definition gives a full batch.
+	otherCall := makeEnvelopeCall(t, conn, &repositorysocket.Call{
+		GetDocuments: &repositorysocket.GetDocuments{
+			SetName: "other",
+			Type:    "core/article",
+			Subset:  []string{".title"},
+		},
+	}, cursor, false)
+
+	batch, err := rc.AwaitResponse(otherCall,
+		func(resp *repositorysocket.Response) bool {
+			return resp.DocumentBatch != nil &&
+				resp.DocumentBatch.FinalBatch
+		}, 2*time.Second)
+	test.Must(t, err, "get full batch for a mismatched cursor")
+
+	var gotUntouched bool
+
+	for _, state := range batch.DocumentBatch.Documents {
+		gotUntouched = gotUntouched || state.Uuid == untouched
+	}
+
+	if !gotUntouched {
+		t.Fatal("expected a full batch for a cursor with another fingerprint")
+	}
+}
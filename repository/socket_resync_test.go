@@ -0,0 +1,93 @@
+package repository_test
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	"github.com/ttab/elephant-api/repositorysocket"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestIntegrationSocketDocumentSetResync(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{
+		RunEventlogBuilder: true,
+		DocumentSets: repository.DocumentSetConfig{
+			TypeBufferSizes: map[string]int{
+				// A tiny buffer makes the set overflow on bursts
+				// of updates.
+				"core/article": 1,
+			},
+		},
+	})
+
+	client, conn, rc := dialEventlogSocket(t, tc)
+
+	const setCall = "0b6c5a1e-2f0e-4f43-a8c4-1c7b9d2e3f40"
+
+	makeCall(t, conn, &repositorysocket.Call{
+		CallId: setCall,
+		GetDocuments: &repositorysocket.GetDocuments{
+			SetName: "articles",
+			Type:    "core/article",
+		},
+	})
+
+	_, err := rc.AwaitDocumentBatch(setCall, 2*time.Second)
+	test.Must(t, err, "get initial document batch")
+
+	_, err = rc.AwaitResponse(setCall, nil, 2*time.Second)
+	test.Must(t, err, "subscribe to document set")
+
+	pending := make(map[string]bool)
+
+	var grp errgroup.Group
+
+	for range 30 {
+		docUUID := uuid.NewString()
+
+		pending[docUUID] = true
+
+		grp.Go(func() error {
+			_, err := client.Update(ctx, &rpc.UpdateRequest{
+				Uuid: docUUID,
+				Document: baseDocument(
+					docUUID, "article://test/"+docUUID),
+			})
+
+			return err //nolint: wrapcheck
+		})
+	}
+
+	test.Must(t, grp.Wait(), "create articles")
+
+	// Every article must reach the client, either as a document update or
+	// as part of a document batch after a resync. An "oos" error fails the
+	// await.
+	_, err = rc.AwaitResponse(setCall,
+		func(resp *repositorysocket.Response) bool {
+			switch {
+			case resp.DocumentUpdate != nil &&
+				resp.DocumentUpdate.Document != nil:
+				delete(pending, resp.DocumentUpdate.Event.Uuid)
+			case resp.DocumentBatch != nil:
+				for _, state := range resp.DocumentBatch.Documents {
+					delete(pending, state.Uuid)
+				}
+			}
+
+			return len(pending) == 0
+		}, 10*time.Second)
+	test.Must(t, err, "get all articles delivered to the set")
+}
@@ -1,6 +1,7 @@
 package repository_test
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -24,6 +25,7 @@ import (
 	"github.com/ttab/elephantine/test"
 	"golang.org/x/time/rate"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -735,6 +737,7 @@ type respNotification struct {
 type responseCollection struct {
 	m         sync.Mutex
 	responses []*repositorysocket.Response
+	cursors   map[string]string
 
 	lastAwait int
 
@@ -743,6 +746,7 @@ type responseCollection struct {
 
 func newResponseCollection() *responseCollection {
 	return &responseCollection{
+		cursors:    make(map[string]string),
 		notifyResp: make(chan respNotification, 64),
 	}
 }
@@ -751,7 +755,7 @@ func (rc *responseCollection) ReadResponses(t *testing.T, conn *websocket.Conn)
 	t.Helper()
 
 	for {
-		resp, ok := readResponse(t, conn)
+		resp, cursor, ok := readResponseWithCursor(t, conn)
 		if !ok {
 			return
 		}
@@ -759,6 +763,11 @@ func (rc *responseCollection) ReadResponses(t *testing.T, conn *websocket.Conn)
 		rc.m.Lock()
 		idx := len(rc.responses)
 		rc.responses = append(rc.responses, resp)
+
+		if cursor != "" {
+			rc.cursors[resp.CallId] = cursor
+		}
+
 		rc.m.Unlock()
 
 		select {
@@ -780,6 +789,14 @@ func (rc *responseCollection) Responses() []*repositorysocket.Response {
 	return res
 }
 
+// SetCursor returns the last set cursor that was received for a call.
+func (rc *responseCollection) SetCursor(callID string) string {
+	rc.m.Lock()
+	defer rc.m.Unlock()
+
+	return rc.cursors[callID]
+}
+
 func (rc *responseCollection) AwaitDocumentBatch(
 	callID string,
 	timeout time.Duration,
@@ -912,6 +929,10 @@ func makeCall(
 		call.CallId = uuid.NewString()
 	}
 
+	if conn.Subprotocol() == repository.SocketProtocolV2 {
+		return makeEnvelopeCall(t, conn, call, "", true)
+	}
+
 	data, err := proto.Marshal(call)
 	test.Must(t, err, "marshal message")
 
@@ -921,25 +942,105 @@ func makeCall(
 	return call.CallId
 }
 
+// makeEnvelopeCall makes a call wrapped in a SocketProtocolV2 envelope with
+// an optional set cursor, using protobuf if binary is true, and JSON
+// otherwise.
+func makeEnvelopeCall(
+	t *testing.T,
+	conn *websocket.Conn,
+	call *repositorysocket.Call,
+	cursor string,
+	binary bool,
+) string {
+	t.Helper()
+
+	if call.CallId == "" {
+		call.CallId = uuid.NewString()
+	}
+
+	if !binary {
+		msg, err := protojson.Marshal(call)
+		test.Must(t, err, "marshal message")
+
+		data, err := json.Marshal(map[string]any{
+			"message":    json.RawMessage(msg),
+			"set_cursor": cursor,
+		})
+		test.Must(t, err, "marshal envelope")
+
+		err = conn.WriteMessage(websocket.TextMessage, data)
+		test.Must(t, err, "write message")
+
+		return call.CallId
+	}
+
+	msg, err := proto.Marshal(call)
+	test.Must(t, err, "marshal message")
+
+	data := protowire.AppendTag(nil, 1, protowire.BytesType)
+	data = protowire.AppendBytes(data, msg)
+	data = protowire.AppendTag(data, 2, protowire.BytesType)
+	data = protowire.AppendString(data, cursor)
+
+	err = conn.WriteMessage(websocket.BinaryMessage, data)
+	test.Must(t, err, "write message")
+
+	return call.CallId
+}
+
 func readResponse(
 	t *testing.T, conn *websocket.Conn,
 ) (*repositorysocket.Response, bool) {
 	t.Helper()
 
+	resp, _, ok := readResponseWithCursor(t, conn)
+
+	return resp, ok
+}
+
+// readResponseWithCursor reads a response and the set cursor that was sent
+// with it in a SocketProtocolV2 envelope.
+func readResponseWithCursor(
+	t *testing.T, conn *websocket.Conn,
+) (*repositorysocket.Response, string, bool) {
+	t.Helper()
+
 	msgType, data, err := conn.ReadMessage()
-	if websocket.IsCloseError(err, websocket.CloseNoStatusReceived) {
-		return nil, false
+	if websocket.IsCloseError(err,
+		websocket.CloseNoStatusReceived, websocket.CloseNormalClosure) {
+		return nil, "", false
 	}
 
 	test.Must(t, err, "read message from websocket")
 
-	var resp repositorysocket.Response
+	var (
+		resp      repositorysocket.Response
+		cursor    string
+		envelopes = conn.Subprotocol() == repository.SocketProtocolV2
+	)
 
 	switch msgType {
 	case websocket.TextMessage:
-		err := protojson.Unmarshal(data, &resp)
+		if envelopes {
+			var env struct {
+				Message   json.RawMessage `json:"message"`
+				SetCursor string          `json:"set_cursor"`
+			}
+
+			err := json.Unmarshal(data, &env)
+			test.Must(t, err, "unmarshal json envelope")
+
+			data = env.Message
+			cursor = env.SetCursor
+		}
+
+		err = protojson.Unmarshal(data, &resp)
 		test.Must(t, err, "unmarshal json response")
 	case websocket.BinaryMessage:
+		if envelopes {
+			data, cursor = unwrapTestEnvelope(t, data)
+		}
+
 		err := proto.Unmarshal(data, &resp)
 		test.Must(t, err, "unmarshal protobuf response")
 	case websocket.CloseMessage:
@@ -948,7 +1049,45 @@ func readResponse(
 		t.Fatalf("unexpected message type %d", msgType)
 	}
 
-	return &resp, true
+	return &resp, cursor, true
+}
+
+// unwrapTestEnvelope returns the message and set cursor of a protobuf
+// SocketProtocolV2 envelope.
+func unwrapTestEnvelope(t *testing.T, data []byte) ([]byte, string) {
+	t.Helper()
+
+	var (
+		message []byte
+		cursor  string
+	)
+
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			t.Fatalf("invalid envelope tag: %v", protowire.ParseError(n))
+		}
+
+		data = data[n:]
+
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			message, n = protowire.ConsumeBytes(data)
+		case num == 2 && typ == protowire.BytesType:
+			cursor, n = protowire.ConsumeString(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+
+		if n < 0 {
+			t.Fatalf("invalid envelope field %d: %v",
+				num, protowire.ParseError(n))
+		}
+
+		data = data[n:]
+	}
+
+	return message, cursor
 }
 
 // dialEventlogSocket creates a documents client and an authenticated websocket
@@ -959,6 +1098,34 @@ func dialEventlogSocket(
 ) (rpc.Documents, *websocket.Conn, *responseCollection) {
 	t.Helper()
 
+	return dialSocket(t, tc, websocket.DefaultDialer)
+}
+
+// dialEnvelopeSocket is dialEventlogSocket for the SocketProtocolV2
+// subprotocol.
+func dialEnvelopeSocket(
+	t *testing.T, tc TestContext,
+) (rpc.Documents, *websocket.Conn, *responseCollection) {
+	t.Helper()
+
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = []string{repository.SocketProtocolV2}
+
+	client, conn, rc := dialSocket(t, tc, &dialer)
+
+	if conn.Subprotocol() != repository.SocketProtocolV2 {
+		t.Fatalf("expected the %s subprotocol, got %q",
+			repository.SocketProtocolV2, conn.Subprotocol())
+	}
+
+	return client, conn, rc
+}
+
+func dialSocket(
+	t *testing.T, tc TestContext, dialer *websocket.Dialer,
+) (rpc.Documents, *websocket.Conn, *responseCollection) {
+	t.Helper()
+
 	ctx := t.Context()
 
 	scopes := "doc_read doc_write eventlog_read"
@@ -982,7 +1149,7 @@ func dialEventlogSocket(
 		"Origin": []string{"https://example.ecms.se"},
 	}
 
-	conn, wsResp, err := websocket.DefaultDialer.Dial(wsURL.String(), header)
+	conn, wsResp, err := dialer.Dial(wsURL.String(), header)
 	test.Must(t, err, "dial websocket")
 
 	t.Cleanup(func() {
@@ -0,0 +1,369 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine/pg"
+	"github.com/ttab/newsdoc"
+)
+
+// Built-in template variables that are always available.
+const (
+	// TemplateVariableUUID is the UUID of the created document.
+	TemplateVariableUUID = "uuid"
+	// TemplateVariableNow is the time of creation in RFC3339 format.
+	TemplateVariableNow = "now"
+)
+
+var (
+	templatePlaceholderExp  = regexp.MustCompile(`\{\{\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*\}\}`)
+	templateVariableNameExp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// DocumentTemplate is a versioned skeleton for creating documents of a type.
+// String values in the document and the ACL can contain "{{name}}"
+// placeholders that are replaced with the values of the template variables
+// when the template is instantiated.
+type DocumentTemplate struct {
+	Name        string             `json:"name"`
+	Version     int64              `json:"version"`
+	Type        string             `json:"type"`
+	Description string             `json:"description,omitempty"`
+	Document    newsdoc.Document   `json:"document"`
+	Variables   []TemplateVariable `json:"variables,omitempty"`
+	// ACL is the default ACL for documents created from the template.
+	ACL       []TemplateACLEntry `json:"acl,omitempty"`
+	Created   time.Time          `json:"created"`
+	CreatedBy string             `json:"created_by"`
+}
+
+// TemplateVariable is a value that is supplied when a template is
+// instantiated.
+type TemplateVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Default     string `json:"default,omitempty"`
+	// Example is used instead of the default value when the template is
+	// validated.
+	Example string `json:"example,omitempty"`
+}
+
+// TemplateACLEntry is a default ACL entry for documents created from a
+// template.
+type TemplateACLEntry struct {
+	URI         string   `json:"uri"`
+	Permissions []string `json:"permissions"`
+}
+
+// Validate checks that the template is well-formed, and that the
+// placeholders in the document and ACL refer to declared or built-in
+// variables.
+func (t DocumentTemplate) Validate() error {
+	if t.Name == "" {
+		return errors.New("missing name")
+	}
+
+	if t.Type == "" {
+		return errors.New("missing type")
+	}
+
+	if t.Document.Type != "" && t.Document.Type != t.Type {
+		return fmt.Errorf("the document type %q doesn't match the template type %q",
+			t.Document.Type, t.Type)
+	}
+
+	declared := map[string]bool{
+		TemplateVariableUUID: true,
+		TemplateVariableNow:  true,
+	}
+
+	for _, v := range t.Variables {
+		if !templateVariableNameExp.MatchString(v.Name) {
+			return fmt.Errorf("invalid variable name %q", v.Name)
+		}
+
+		if declared[v.Name] {
+			return fmt.Errorf("variable %q is already declared or built-in",
+				v.Name)
+		}
+
+		declared[v.Name] = true
+	}
+
+	for _, e := range t.ACL {
+		if e.URI == "" {
+			return errors.New("missing ACL entry URI")
+		}
+
+		for _, p := range e.Permissions {
+			if !IsValidPermission(Permission(p)) {
+				return fmt.Errorf("invalid ACL permission %q", p)
+			}
+		}
+	}
+
+	payload, err := t.placeholderSource()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range templatePlaceholderExp.FindAllSubmatch(payload, -1) {
+		if !declared[string(m[1])] {
+			return fmt.Errorf("undeclared variable %q", string(m[1]))
+		}
+	}
+
+	return nil
+}
+
+func (t DocumentTemplate) placeholderSource() ([]byte, error) {
+	payload, err := json.Marshal(struct {
+		Document newsdoc.Document   `json:"document"`
+		ACL      []TemplateACLEntry `json:"acl"`
+	}{
+		Document: t.Document,
+		ACL:      t.ACL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal template: %w", err)
+	}
+
+	return payload, nil
+}
+
+// ExampleValues returns the values that are used to validate the template.
+func (t DocumentTemplate) ExampleValues() map[string]string {
+	values := make(map[string]string, len(t.Variables))
+
+	for _, v := range t.Variables {
+		if v.Example != "" {
+			values[v.Name] = v.Example
+		} else {
+			values[v.Name] = v.Default
+		}
+	}
+
+	return values
+}
+
+// Instantiate creates a document from the template, returning the document
+// and its default ACL. Variables that don't have a value use their default
+// value.
+func (t DocumentTemplate) Instantiate(
+	docUUID string, values map[string]string, now time.Time,
+) (newsdoc.Document, []TemplateACLEntry, error) {
+	resolved := map[string]string{
+		TemplateVariableUUID: docUUID,
+		TemplateVariableNow:  now.UTC().Format(time.RFC3339),
+	}
+
+	for _, v := range t.Variables {
+		value, ok := values[v.Name]
+
+		switch {
+		case ok:
+		case v.Required:
+			return newsdoc.Document{}, nil, fmt.Errorf(
+				"missing value for the required variable %q", v.Name)
+		default:
+			value = v.Default
+		}
+
+		resolved[v.Name] = value
+	}
+
+	for name := range values {
+		declared := slices.ContainsFunc(t.Variables,
+			func(v TemplateVariable) bool {
+				return v.Name == name
+			})
+		if !declared {
+			return newsdoc.Document{}, nil, fmt.Errorf(
+				"unknown variable %q", name)
+		}
+	}
+
+	payload, err := t.placeholderSource()
+	if err != nil {
+		return newsdoc.Document{}, nil, err
+	}
+
+	var replaceErr error
+
+	payload = templatePlaceholderExp.ReplaceAllFunc(payload,
+		func(m []byte) []byte {
+			name := templatePlaceholderExp.FindSubmatch(m)[1]
+
+			// Values are inserted into JSON strings, so they need
+			// to be escaped.
+			escaped, err := json.Marshal(resolved[string(name)])
+			if err != nil {
+				replaceErr = err
+			}
+
+			return escaped[1 : len(escaped)-1]
+		})
+	if replaceErr != nil {
+		return newsdoc.Document{}, nil, fmt.Errorf(
+			"escape value: %w", replaceErr)
+	}
+
+	var inst struct {
+		Document newsdoc.Document   `json:"document"`
+		ACL      []TemplateACLEntry `json:"acl"`
+	}
+
+	err = json.Unmarshal(payload, &inst)
+	if err != nil {
+		return newsdoc.Document{}, nil, fmt.Errorf(
+			"unmarshal instantiated template: %w", err)
+	}
+
+	inst.Document.UUID = docUUID
+	inst.Document.Type = t.Type
+
+	return inst.Document, inst.ACL, nil
+}
+
+// SaveTemplate implements TemplateStore.
+func (s *PGDocStore) SaveTemplate(
+	ctx context.Context, template DocumentTemplate,
+) (int64, error) {
+	document, err := json.Marshal(template.Document)
+	if err != nil {
+		return 0, fmt.Errorf("marshal document: %w", err)
+	}
+
+	variables, err := json.Marshal(template.Variables)
+	if err != nil {
+		return 0, fmt.Errorf("marshal variables: %w", err)
+	}
+
+	acl, err := json.Marshal(template.ACL)
+	if err != nil {
+		return 0, fmt.Errorf("marshal ACL: %w", err)
+	}
+
+	version, err := postgres.New(s.pool).InsertDocumentTemplate(ctx,
+		postgres.InsertDocumentTemplateParams{
+			Name:        template.Name,
+			Type:        template.Type,
+			Description: template.Description,
+			Document:    document,
+			Variables:   variables,
+			Acl:         acl,
+			Created:     pg.Time(template.Created),
+			CreatedBy:   template.CreatedBy,
+		})
+	if pg.IsConstraintError(err, "document_template_pkey") {
+		return 0, DocStoreErrorf(ErrCodeOptimisticLock,
+			"template %q was updated concurrently", template.Name)
+	} else if err != nil {
+		return 0, fmt.Errorf("insert template: %w", err)
+	}
+
+	return version, nil
+}
+
+// GetTemplate implements TemplateStore.
+func (s *PGDocStore) GetTemplate(
+	ctx context.Context, name string, version int64,
+) (*DocumentTemplate, error) {
+	row, err := s.reader.GetDocumentTemplate(ctx,
+		postgres.GetDocumentTemplateParams{
+			Name:    name,
+			Version: version,
+		})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, DocStoreErrorf(ErrCodeNotFound,
+			"template %q not found", name)
+	} else if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	return documentTemplateFromRow(row)
+}
+
+// ListTemplates implements TemplateStore.
+func (s *PGDocStore) ListTemplates(
+	ctx context.Context, docType string,
+) ([]DocumentTemplate, error) {
+	var typeFilter pgtype.Text
+
+	if docType != "" {
+		typeFilter = pg.Text(docType)
+	}
+
+	rows, err := s.reader.ListDocumentTemplates(ctx, typeFilter)
+	if err != nil {
+		return nil, fmt.Errorf("read from database: %w", err)
+	}
+
+	res := make([]DocumentTemplate, len(rows))
+
+	for i, row := range rows {
+		t, err := documentTemplateFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+
+		res[i] = *t
+	}
+
+	return res, nil
+}
+
+// DeleteTemplate implements TemplateStore.
+func (s *PGDocStore) DeleteTemplate(ctx context.Context, name string) error {
+	deleted, err := postgres.New(s.pool).DeleteDocumentTemplate(ctx, name)
+	if err != nil {
+		return fmt.Errorf("delete from database: %w", err)
+	}
+
+	if deleted == 0 {
+		return DocStoreErrorf(ErrCodeNotFound,
+			"template %q not found", name)
+	}
+
+	return nil
+}
+
+func documentTemplateFromRow(
+	row postgres.DocumentTemplate,
+) (*DocumentTemplate, error) {
+	t := DocumentTemplate{
+		Name:        row.Name,
+		Version:     row.Version,
+		Type:        row.Type,
+		Description: row.Description,
+		Created:     row.Created.Time,
+		CreatedBy:   row.CreatedBy,
+	}
+
+	err := json.Unmarshal(row.Document, &t.Document)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal document: %w", err)
+	}
+
+	err = json.Unmarshal(row.Variables, &t.Variables)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal variables: %w", err)
+	}
+
+	err = json.Unmarshal(row.Acl, &t.ACL)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal ACL: %w", err)
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/twitchtv/twirp"
+)
+
+// TemplatesPathPrefix is the twirp path prefix of the templates service. The
+// service doesn't have a published service definition, so all of its methods
+// are extension methods.
+const TemplatesPathPrefix = "/twirp/elephant.repository.Templates/"
+
+func NewTemplatesService(
+	store TemplateStore, validator DocumentValidator,
+) *TemplatesService {
+	return &TemplatesService{
+		store:     store,
+		validator: validator,
+	}
+}
+
+// TemplatesService manages versioned document templates.
+type TemplatesService struct {
+	store     TemplateStore
+	validator DocumentValidator
+}
+
+// ExtensionMethods implements ExtensionProvider.
+func (a *TemplatesService) ExtensionMethods() ExtensionMethods {
+	return ExtensionMethods{
+		"SaveTemplate":   JSONMethod(a.SaveTemplate),
+		"GetTemplate":    JSONMethod(a.GetTemplate),
+		"ListTemplates":  JSONMethod(a.ListTemplates),
+		"DeleteTemplate": JSONMethod(a.DeleteTemplate),
+	}
+}
+
+type SaveTemplateRequest struct {
+	Template DocumentTemplate `json:"template"`
+}
+
+type SaveTemplateResponse struct {
+	Version int64 `json:"version"`
+}
+
+// SaveTemplate stores a new version of a template. The template document is
+// instantiated with the example values of the variables and validated
+// against the active schema generation.
+func (a *TemplatesService) SaveTemplate(
+	ctx context.Context, req *SaveTemplateRequest,
+) (*SaveTemplateResponse, error) {
+	auth, err := RequireAnyScope(ctx, ScopeSchemaAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	t := req.Template
+
+	err = t.Validate()
+	if err != nil {
+		return nil, twirp.InvalidArgumentError("template", err.Error())
+	}
+
+	doc, _, err := t.Instantiate(
+		uuid.NewString(), t.ExampleValues(), time.Now())
+	if err != nil {
+		return nil, twirp.InvalidArgumentError("template", err.Error())
+	}
+
+	results, err := a.validator.ValidateDocument(ctx, &doc)
+	if err != nil {
+		return nil, twirp.InternalErrorf("validate template: %v", err)
+	}
+
+	if len(results) > 0 {
+		err := twirp.InvalidArgument.Errorf(
+			"the template document had %d validation errors, the first one is: %v",
+			len(results), results[0].String())
+
+		err = err.WithMeta("err_count", strconv.Itoa(len(results)))
+
+		for i := range results {
+			err = err.WithMeta(strconv.Itoa(i), results[i].String())
+		}
+
+		return nil, err
+	}
+
+	t.Created = time.Now()
+	t.CreatedBy = auth.Claims.Subject
+
+	version, err := a.store.SaveTemplate(ctx, t)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeOptimisticLock):
+		return nil, twirp.Aborted.Error(err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf("store template: %v", err)
+	}
+
+	return &SaveTemplateResponse{
+		Version: version,
+	}, nil
+}
+
+type GetTemplateRequest struct {
+	Name string `json:"name"`
+	// Version defaults to the latest version.
+	Version int64 `json:"version,omitempty"`
+}
+
+type GetTemplateResponse struct {
+	Template *DocumentTemplate `json:"template"`
+}
+
+// GetTemplate returns a version of a template.
+func (a *TemplatesService) GetTemplate(
+	ctx context.Context, req *GetTemplateRequest,
+) (*GetTemplateResponse, error) {
+	_, err := RequireAnyScope(ctx,
+		ScopeDocumentRead, ScopeSchemaAdmin, ScopeSchemaRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name == "" {
+		return nil, twirp.RequiredArgumentError("name")
+	}
+
+	t, err := a.store.GetTemplate(ctx, req.Name, req.Version)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.NotFound.Error(err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf("read template: %v", err)
+	}
+
+	return &GetTemplateResponse{
+		Template: t,
+	}, nil
+}
+
+type ListTemplatesRequest struct {
+	Type string `json:"type,omitempty"`
+}
+
+type ListTemplatesResponse struct {
+	Templates []DocumentTemplate `json:"templates"`
+}
+
+// ListTemplates returns the latest version of all templates, optionally
+// filtered by document type.
+func (a *TemplatesService) ListTemplates(
+	ctx context.Context, req *ListTemplatesRequest,
+) (*ListTemplatesResponse, error) {
+	_, err := RequireAnyScope(ctx,
+		ScopeDocumentRead, ScopeSchemaAdmin, ScopeSchemaRead)
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := a.store.ListTemplates(ctx, req.Type)
+	if err != nil {
+		return nil, twirp.InternalErrorf("list templates: %v", err)
+	}
+
+	return &ListTemplatesResponse{
+		Templates: templates,
+	}, nil
+}
+
+type DeleteTemplateRequest struct {
+	Name string `json:"name"`
+}
+
+type DeleteTemplateResponse struct{}
+
+// DeleteTemplate deletes all versions of a template.
+func (a *TemplatesService) DeleteTemplate(
+	ctx context.Context, req *DeleteTemplateRequest,
+) (*DeleteTemplateResponse, error) {
+	_, err := RequireAnyScope(ctx, ScopeSchemaAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name == "" {
+		return nil, twirp.RequiredArgumentError("name")
+	}
+
+	err = a.store.DeleteTemplate(ctx, req.Name)
+
+	switch {
+	case IsDocStoreErrorCode(err, ErrCodeNotFound):
+		return nil, twirp.NotFound.Error(err.Error())
+	case err != nil:
+		return nil, twirp.InternalErrorf("delete template: %v", err)
+	}
+
+	return &DeleteTemplateResponse{}, nil
+}
+
+// extensionOnlyServer is the API server of a service that only has
+// extension methods.
+type extensionOnlyServer struct {
+	prefix string
+}
+
+func (s extensionOnlyServer) PathPrefix() string {
+	return s.prefix
+}
+
+func (s extensionOnlyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = twirp.WriteError(w, twirp.NewErrorf(twirp.BadRoute,
+		"no handler for path %q", r.URL.Path))
+}
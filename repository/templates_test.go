@@ -0,0 +1,282 @@
+package repository_test
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/ttab/newsdoc"
+	"github.com/twitchtv/twirp"
+)
+
+func articleTemplate() repository.DocumentTemplate {
+	return repository.DocumentTemplate{
+		Name: "test-article",
+		Type: "core/article",
+		Document: newsdoc.Document{
+			URI:      "article://test/{{uuid}}",
+			Title:    "{{ headline }}",
+			Language: "en",
+			Meta: []newsdoc.Block{
+				{
+					Type:  "core/newsvalue",
+					Value: "{{newsvalue}}",
+				},
+			},
+		},
+		Variables: []repository.TemplateVariable{
+			{
+				Name:     "headline",
+				Required: true,
+				Example:  "An example headline",
+			},
+			{
+				Name:    "newsvalue",
+				Default: "3",
+			},
+		},
+		ACL: []repository.TemplateACLEntry{
+			{
+				URI:         "core://unit/{{unit}}",
+				Permissions: []string{"r", "w"},
+			},
+		},
+	}
+}
+
+func TestDocumentTemplateInstantiate(t *testing.T) {
+	tmpl := articleTemplate()
+
+	err := tmpl.Validate()
+	if err == nil {
+		t.Fatal("expected the undeclared ACL variable to be rejected")
+	}
+
+	tmpl.Variables = append(tmpl.Variables, repository.TemplateVariable{
+		Name:    "unit",
+		Default: "news",
+	})
+
+	test.Must(t, tmpl.Validate(), "validate template")
+
+	docUUID := uuid.NewString()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	_, _, err = tmpl.Instantiate(docUUID, nil, now)
+	if err == nil {
+		t.Fatal("expected a missing required variable to be rejected")
+	}
+
+	_, _, err = tmpl.Instantiate(docUUID, map[string]string{
+		"headline": "x",
+		"unknown":  "y",
+	}, now)
+	if err == nil {
+		t.Fatal("expected an unknown variable to be rejected")
+	}
+
+	doc, acl, err := tmpl.Instantiate(docUUID, map[string]string{
+		"headline": `A "quoted" headline`,
+	}, now)
+	test.Must(t, err, "instantiate template")
+
+	test.EqualDiff(t, newsdoc.Document{
+		UUID:     docUUID,
+		Type:     "core/article",
+		URI:      "article://test/" + docUUID,
+		Title:    `A "quoted" headline`,
+		Language: "en",
+		Meta: []newsdoc.Block{
+			{
+				Type:  "core/newsvalue",
+				Value: "3",
+			},
+		},
+	}, doc, "get the expected document")
+
+	test.EqualDiff(t, []repository.TemplateACLEntry{
+		{
+			URI:         "core://unit/news",
+			Permissions: []string{"r", "w"},
+		},
+	}, acl, "get the expected ACL")
+}
+
+func TestIntegrationDocumentTemplates(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+	ctx := t.Context()
+
+	tc := testingAPIServer(t, logger, testingServerOptions{})
+
+	admin := tc.ExtensionClient(t, repository.TemplatesPathPrefix,
+		itest.Claims(t, "admin", "schema_admin"))
+	reader := tc.ExtensionClient(t, repository.TemplatesPathPrefix,
+		itest.Claims(t, "reader", "doc_read"))
+
+	editorClaims := itest.Claims(t, "editor", "doc_read doc_write")
+
+	editor := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, editorClaims)
+	documents := tc.DocumentsClient(t, editorClaims)
+
+	tmpl := articleTemplate()
+
+	tmpl.Variables = append(tmpl.Variables, repository.TemplateVariable{
+		Name:    "unit",
+		Default: "news",
+	})
+
+	err := reader.Call(ctx, "SaveTemplate", repository.SaveTemplateRequest{
+		Template: tmpl,
+	}, &repository.SaveTemplateResponse{})
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	invalid := tmpl
+
+	invalid.Document.Meta = []newsdoc.Block{
+		{Type: "test/undeclared", Value: "{{newsvalue}}"},
+	}
+
+	err = admin.Call(ctx, "SaveTemplate", repository.SaveTemplateRequest{
+		Template: invalid,
+	}, &repository.SaveTemplateResponse{})
+	itest.IsTwirpError(t, err, twirp.InvalidArgument)
+
+	var saved repository.SaveTemplateResponse
+
+	err = admin.Call(ctx, "SaveTemplate", repository.SaveTemplateRequest{
+		Template: tmpl,
+	}, &saved)
+	test.Must(t, err, "save template")
+
+	test.Equal(t, 1, saved.Version, "get the first template version")
+
+	err = admin.Call(ctx, "SaveTemplate", repository.SaveTemplateRequest{
+		Template: tmpl,
+	}, &saved)
+	test.Must(t, err, "save a second template version")
+
+	test.Equal(t, 2, saved.Version, "get the second template version")
+
+	var list repository.ListTemplatesResponse
+
+	err = reader.Call(ctx, "ListTemplates", repository.ListTemplatesRequest{
+		Type: "core/article",
+	}, &list)
+	test.Must(t, err, "list templates")
+
+	test.Equal(t, 1, len(list.Templates), "list the latest version only")
+	test.Equal(t, 2, list.Templates[0].Version, "list the latest version")
+	test.Equal(t, "user://test/admin", list.Templates[0].CreatedBy,
+		"record the creator of the template")
+
+	var got repository.GetTemplateResponse
+
+	err = reader.Call(ctx, "GetTemplate", repository.GetTemplateRequest{
+		Name:    tmpl.Name,
+		Version: 1,
+	}, &got)
+	test.Must(t, err, "get the first template version")
+
+	test.Equal(t, 1, got.Template.Version, "get the requested version")
+
+	var created repository.CreateFromTemplateResponse
+
+	err = editor.Call(ctx, "CreateFromTemplate",
+		repository.CreateFromTemplateRequest{
+			Template: tmpl.Name,
+			Values: map[string]string{
+				"headline": "Created from a template",
+			},
+			ACL: []repository.TemplateACLEntry{
+				{
+					URI:         "user://test/editor",
+					Permissions: []string{"r", "w"},
+				},
+			},
+		}, &created)
+	test.Must(t, err, "create document from template")
+
+	test.Equal(t, 1, created.Version, "create a new document")
+	test.Equal(t, 2, created.TemplateVersion, "use the latest template")
+
+	doc, err := documents.Get(ctx, &rpc.GetDocumentRequest{
+		Uuid: created.UUID,
+	})
+	test.Must(t, err, "get the created document")
+
+	test.Equal(t, "Created from a template", doc.Document.Title,
+		"use the supplied values")
+	test.Equal(t, "article://test/"+created.UUID, doc.Document.Uri,
+		"use the document UUID in the URI")
+
+	var fromDefault repository.CreateFromTemplateResponse
+
+	err = editor.Call(ctx, "CreateFromTemplate",
+		repository.CreateFromTemplateRequest{
+			Template: tmpl.Name,
+			Values: map[string]string{
+				"headline": "Created with the template ACL",
+			},
+		}, &fromDefault)
+	test.Must(t, err, "create document with the template ACL")
+
+	meta, err := documents.GetMeta(ctx, &rpc.GetMetaRequest{
+		Uuid: fromDefault.UUID,
+	})
+	test.Must(t, err, "get the metadata of the created document")
+
+	acl := make(map[string][]string)
+
+	for _, e := range meta.Meta.Acl {
+		acl[e.Uri] = e.Permissions
+	}
+
+	test.EqualDiff(t, map[string][]string{
+		"core://unit/news":   {"r", "w"},
+		"user://test/editor": {"r", "w"},
+	}, acl, "merge the template ACL with the creator grant")
+
+	readerDocs := tc.ExtensionClient(t, rpc.DocumentsPathPrefix,
+		itest.Claims(t, "reader", "doc_read"))
+
+	err = readerDocs.Call(ctx, "CreateFromTemplate",
+		repository.CreateFromTemplateRequest{
+			Template: "does-not-exist",
+		}, &created)
+	itest.IsTwirpError(t, err, twirp.PermissionDenied)
+
+	err = editor.Call(ctx, "CreateFromTemplate",
+		repository.CreateFromTemplateRequest{
+			Template: tmpl.Name,
+			UUID:     created.UUID,
+			Values: map[string]string{
+				"headline": "Created again",
+			},
+		}, &created)
+	itest.IsTwirpError(t, err, twirp.FailedPrecondition)
+
+	err = editor.Call(ctx, "CreateFromTemplate",
+		repository.CreateFromTemplateRequest{
+			Template: tmpl.Name,
+		}, &created)
+	itest.IsTwirpError(t, err, twirp.InvalidArgument)
+
+	err = admin.Call(ctx, "DeleteTemplate", repository.DeleteTemplateRequest{
+		Name: tmpl.Name,
+	}, &repository.DeleteTemplateResponse{})
+	test.Must(t, err, "delete template")
+
+	err = reader.Call(ctx, "GetTemplate", repository.GetTemplateRequest{
+		Name: tmpl.Name,
+	}, &got)
+	itest.IsTwirpError(t, err, twirp.NotFound)
+}
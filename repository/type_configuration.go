@@ -78,7 +78,13 @@ func typeConfigurationToDB(conf TypeConfiguration) postgres.TypeConfiguration {
 			[]postgres.TypeLabelExpression,
 			len(conf.LabelExpressions),
 		),
-		Variants: conf.Variants,
+		Variants:           conf.Variants,
+		ReadAudit:          conf.ReadAudit,
+		ArchiveAttachments: conf.ArchiveAttachments,
+	}
+
+	if conf.ExemplarSampling != nil {
+		c.ExemplarSampling = exemplarSamplingToDB(*conf.ExemplarSampling)
 	}
 
 	for i, e := range conf.TimeExpressions {
@@ -110,7 +116,13 @@ func typeConfigurationFromDB(conf postgres.TypeConfiguration) TypeConfiguration
 			[]LabelConfiguration,
 			len(conf.LabelExpressions),
 		),
-		Variants: conf.Variants,
+		Variants:           conf.Variants,
+		ReadAudit:          conf.ReadAudit,
+		ArchiveAttachments: conf.ArchiveAttachments,
+	}
+
+	if conf.ExemplarSampling != nil {
+		c.ExemplarSampling = exemplarSamplingFromDB(*conf.ExemplarSampling)
 	}
 
 	for i, e := range conf.TimeExpressions {
@@ -194,3 +194,27 @@ func (th *TypeConfigurations) GetConfiguration(
 
 	return c, ok, nil
 }
+
+// ReadAuditTypes returns the types that have read auditing enabled.
+func (th *TypeConfigurations) ReadAuditTypes(
+	ctx context.Context,
+) ([]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-th.initWait:
+	}
+
+	th.m.RLock()
+	defer th.m.RUnlock()
+
+	var types []string
+
+	for t, c := range th.confs {
+		if c.ReadAudit {
+			types = append(types, t)
+		}
+	}
+
+	return types, nil
+}
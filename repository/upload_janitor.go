@@ -0,0 +1,584 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/ttab/elephant-repository/postgres"
+	"github.com/ttab/elephantine"
+	"github.com/ttab/elephantine/pg"
+)
+
+const (
+	// DefaultMaxUploadAge is the default age after which uploads are
+	// expired.
+	DefaultMaxUploadAge = 24 * time.Hour
+
+	// DefaultOrphanGracePeriod is the default minimum age of objects that
+	// are treated as orphans.
+	DefaultOrphanGracePeriod = 1 * time.Hour
+
+	uploadJanitorBatchSize = 200
+)
+
+// Kinds of inconsistencies between the attached_object table and the asset
+// bucket.
+const (
+	// InconsistencyMissingObject is reported for attached objects that
+	// don't exist in the asset bucket.
+	InconsistencyMissingObject = "missing_object"
+	// InconsistencyVersionMismatch is reported for attached objects where
+	// the latest version in the asset bucket isn't the attached version.
+	InconsistencyVersionMismatch = "version_mismatch"
+	// InconsistencyOrphanedObject is reported for objects in the asset
+	// bucket that aren't attached to a document.
+	InconsistencyOrphanedObject = "orphaned_object"
+)
+
+var inconsistencyKinds = []string{
+	InconsistencyMissingObject,
+	InconsistencyVersionMismatch,
+	InconsistencyOrphanedObject,
+}
+
+type UploadJanitorOptions struct {
+	Logger            *slog.Logger
+	DB                *pgxpool.Pool
+	Assets            *AssetBucket
+	MetricsRegisterer prometheus.Registerer
+	// MaxUploadAge is the age after which uploads are expired, defaults
+	// to DefaultMaxUploadAge.
+	MaxUploadAge time.Duration
+	// OrphanGracePeriod is the minimum age of an unreferenced object before
+	// it's treated as an orphan, this protects objects that are in the
+	// process of being attached. Defaults to DefaultOrphanGracePeriod.
+	OrphanGracePeriod time.Duration
+	// DryRun makes the janitor report what it would have done without
+	// deleting anything.
+	DryRun bool
+}
+
+// UploadJanitor expires old uploads and reconciles the attached_object table
+// against the asset bucket.
+type UploadJanitor struct {
+	logger       *slog.Logger
+	pool         *pgxpool.Pool
+	assets       *AssetBucket
+	maxUploadAge time.Duration
+	orphanGrace  time.Duration
+	dryRun       bool
+
+	deletes         *prometheus.CounterVec
+	inconsistencies *prometheus.GaugeVec
+}
+
+func NewUploadJanitor(opts UploadJanitorOptions) (*UploadJanitor, error) {
+	if opts.MetricsRegisterer == nil {
+		opts.MetricsRegisterer = prometheus.DefaultRegisterer
+	}
+
+	if opts.MaxUploadAge == 0 {
+		opts.MaxUploadAge = DefaultMaxUploadAge
+	}
+
+	if opts.OrphanGracePeriod == 0 {
+		opts.OrphanGracePeriod = DefaultOrphanGracePeriod
+	}
+
+	j := UploadJanitor{
+		logger:       opts.Logger,
+		pool:         opts.DB,
+		assets:       opts.Assets,
+		maxUploadAge: opts.MaxUploadAge,
+		orphanGrace:  opts.OrphanGracePeriod,
+		dryRun:       opts.DryRun,
+	}
+
+	m := elephantine.NewMetricsHelper(opts.MetricsRegisterer)
+
+	m.CounterVec(&j.deletes, prometheus.CounterOpts{
+		Name: "elephant_upload_janitor_deletes_total",
+		Help: "Number of expired uploads and orphaned objects deleted by the upload janitor.",
+	}, []string{"kind", "status"})
+
+	m.GaugeVec(&j.inconsistencies, prometheus.GaugeOpts{
+		Name: "elephant_attachment_inconsistencies",
+		Help: "Inconsistencies between attached objects and the asset bucket found in the last reconciliation.",
+	}, []string{"kind"})
+
+	if err := m.Err(); err != nil {
+		return nil, fmt.Errorf("register metrics: %w", err)
+	}
+
+	return &j, nil
+}
+
+// Run the janitor with the given period until the context is cancelled.
+func (j *UploadJanitor) Run(ctx context.Context, period time.Duration) {
+	for {
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return
+		}
+
+		jobLock, err := pg.NewJobLock(j.pool, j.logger, "upload-janitor", pg.JobLockOptions{
+			PingInterval:  10 * time.Second,
+			StaleAfter:    1 * time.Minute,
+			CheckInterval: 20 * time.Second,
+			Timeout:       5 * time.Second,
+		})
+		if err != nil {
+			j.logger.ErrorContext(ctx, "failed to create job lock",
+				elephantine.LogKeyError, err)
+
+			continue
+		}
+
+		err = jobLock.RunWithContext(ctx, func(ctx context.Context) error {
+			_, err := j.RunOnce(ctx)
+
+			return err
+		})
+		if err != nil {
+			j.logger.ErrorContext(
+				ctx, "upload janitor error",
+				elephantine.LogKeyError, err,
+			)
+		}
+	}
+}
+
+// JanitorReport describes the outcome of an upload janitor run. In dry-run
+// mode it describes what the janitor would have done.
+type JanitorReport struct {
+	ExpiredUploads  int
+	Inconsistencies []AttachmentInconsistency
+}
+
+// AttachmentInconsistency is a mismatch between the attached_object table and
+// the asset bucket.
+type AttachmentInconsistency struct {
+	Kind string
+	Key  string
+	// Document and Name are set for attached objects.
+	Document uuid.UUID
+	Name     string
+}
+
+// RunOnce expires old uploads and reconciles attached objects against the
+// asset bucket. Callers are responsible for making sure that only one janitor
+// runs at a time.
+func (j *UploadJanitor) RunOnce(ctx context.Context) (*JanitorReport, error) {
+	var report JanitorReport
+
+	expired, err := j.expireUploads(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("expire uploads: %w", err)
+	}
+
+	report.ExpiredUploads = expired
+
+	inconsistencies, err := j.reconcile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile attached objects: %w", err)
+	}
+
+	report.Inconsistencies = inconsistencies
+
+	return &report, nil
+}
+
+func (j *UploadJanitor) expireUploads(ctx context.Context) (int, error) {
+	q := postgres.New(j.pool)
+
+	params := postgres.GetExpiredUploadsParams{
+		Cutoff:       pg.Time(time.Now().Add(-j.maxUploadAge)),
+		AfterCreated: pg.Time(time.Time{}),
+		Count:        uploadJanitorBatchSize,
+	}
+
+	var count int
+
+	for {
+		uploads, err := q.GetExpiredUploads(ctx, params)
+		if err != nil {
+			return count, fmt.Errorf("list expired uploads: %w", err)
+		}
+
+		for _, u := range uploads {
+			count++
+
+			if j.dryRun {
+				j.logger.InfoContext(ctx, "would expire upload",
+					"upload_id", u.ID,
+					"dry_run", true)
+
+				continue
+			}
+
+			err := j.deleteUpload(ctx, q, u)
+
+			j.deletes.WithLabelValues("upload", statusLabel(err)).Inc()
+
+			if err != nil {
+				return count, fmt.Errorf(
+					"delete upload %s: %w", u.ID, err)
+			}
+		}
+
+		if len(uploads) < uploadJanitorBatchSize {
+			return count, nil
+		}
+
+		last := uploads[len(uploads)-1]
+
+		params.AfterCreated = last.CreatedAt
+		params.AfterID = last.ID
+	}
+}
+
+func (j *UploadJanitor) deleteUpload(
+	ctx context.Context, q *postgres.Queries,
+	upload postgres.GetExpiredUploadsRow,
+) error {
+	status := MultipartStatus(upload.MultipartStatus.String)
+
+	if upload.MultipartID.Valid && status == MultipartStatusUploading {
+		err := j.assets.AbortMultipartUpload(ctx,
+			upload.ID, upload.MultipartID.String)
+		if err != nil {
+			// The object store might already have discarded the
+			// multipart upload, and bucket lifecycle rules take
+			// care of incomplete multipart uploads.
+			j.logger.WarnContext(ctx,
+				"failed to abort expired multipart upload",
+				elephantine.LogKeyError, err,
+				"upload_id", upload.ID,
+			)
+		}
+	}
+
+	err := j.assets.DeleteUpload(ctx, upload.ID)
+	if err != nil {
+		return err
+	}
+
+	err = q.DeleteUpload(ctx, upload.ID)
+	if err != nil {
+		return fmt.Errorf("delete upload row: %w", err)
+	}
+
+	return nil
+}
+
+// reconcile compares the attached objects with the objects in the asset
+// bucket, one object name at a time. The attached objects of a name are
+// listed in document order, which matches the key order of the bucket
+// listing, so both can be read page by page.
+func (j *UploadJanitor) reconcile(
+	ctx context.Context,
+) ([]AttachmentInconsistency, error) {
+	r := reconciliation{
+		j:       j,
+		q:       postgres.New(j.pool),
+		started: time.Now(),
+	}
+
+	storedNames, err := j.assets.ListObjectNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list stored object names: %w", err)
+	}
+
+	attachedNames, err := r.q.ListAttachedObjectNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list attached object names: %w", err)
+	}
+
+	names := slices.Concat(storedNames, attachedNames)
+
+	slices.Sort(names)
+
+	for _, name := range slices.Compact(names) {
+		err := r.reconcileName(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("reconcile %q objects: %w", name, err)
+		}
+	}
+
+	counts := make(map[string]int)
+
+	for _, inc := range r.inconsistencies {
+		counts[inc.Kind]++
+
+		j.logger.WarnContext(ctx, "attachment inconsistency",
+			"kind", inc.Kind,
+			elephantine.LogKeyObjectKey, inc.Key,
+			"dry_run", j.dryRun,
+		)
+	}
+
+	for _, kind := range inconsistencyKinds {
+		j.inconsistencies.WithLabelValues(kind).Set(float64(counts[kind]))
+	}
+
+	return r.inconsistencies, nil
+}
+
+type reconciliation struct {
+	j               *UploadJanitor
+	q               *postgres.Queries
+	started         time.Time
+	inconsistencies []AttachmentInconsistency
+
+	// State for the name that is being reconciled.
+	params postgres.ListCurrentAttachedObjectsParams
+	rows   []postgres.ListCurrentAttachedObjectsRow
+	done   bool
+	group  []StoredObject
+}
+
+func (r *reconciliation) reconcileName(ctx context.Context, name string) error {
+	r.params = postgres.ListCurrentAttachedObjectsParams{
+		Name:  name,
+		Count: uploadJanitorBatchSize,
+	}
+	r.rows = nil
+	r.done = false
+	r.group = nil
+
+	// Objects are grouped by the attached object key, so that an
+	// attached object is checked together with its renditions.
+	err := r.j.assets.ListStoredObjects(ctx, name,
+		func(page []StoredObject) error {
+			for _, obj := range page {
+				if len(r.group) > 0 &&
+					renditionBaseKey(obj.Key) != renditionBaseKey(r.group[0].Key) {
+					err := r.checkGroup(ctx, name)
+					if err != nil {
+						return err
+					}
+				}
+
+				r.group = append(r.group, obj)
+			}
+
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("list stored objects: %w", err)
+	}
+
+	if len(r.group) > 0 {
+		err := r.checkGroup(ctx, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The remaining attached objects have no stored objects.
+	for {
+		row, err := r.peekRow(ctx)
+		if err != nil {
+			return err
+		}
+
+		if row == nil {
+			return nil
+		}
+
+		r.checkAttached(r.popRow(), nil)
+	}
+}
+
+// peekRow returns the next attached object without consuming it, or nil if
+// there are no more attached objects.
+func (r *reconciliation) peekRow(
+	ctx context.Context,
+) (*postgres.ListCurrentAttachedObjectsRow, error) {
+	if len(r.rows) == 0 && !r.done {
+		rows, err := r.q.ListCurrentAttachedObjects(ctx, r.params)
+		if err != nil {
+			return nil, fmt.Errorf("list attached objects: %w", err)
+		}
+
+		if len(rows) < uploadJanitorBatchSize {
+			r.done = true
+		}
+
+		if len(rows) > 0 {
+			r.params.AfterDocument = rows[len(rows)-1].Document
+		}
+
+		r.rows = rows
+	}
+
+	if len(r.rows) == 0 {
+		return nil, nil //nolint:nilnil // nil means no more attached objects
+	}
+
+	return &r.rows[0], nil
+}
+
+// popRow consumes the attached object returned by peekRow.
+func (r *reconciliation) popRow() *postgres.ListCurrentAttachedObjectsRow {
+	row := &r.rows[0]
+
+	r.rows = r.rows[1:]
+
+	return row
+}
+
+// checkGroup checks an attached object key and its renditions against the
+// attached objects.
+func (r *reconciliation) checkGroup(ctx context.Context, name string) error {
+	group := r.group
+	key := renditionBaseKey(group[0].Key)
+
+	r.group = nil
+
+	for {
+		row, err := r.peekRow(ctx)
+		if err != nil {
+			return err
+		}
+
+		if row == nil {
+			break
+		}
+
+		rowKey := r.j.assets.objKey(row.Document, name)
+
+		if rowKey > key {
+			break
+		}
+
+		r.popRow()
+
+		if rowKey < key {
+			// Attached objects that sort before the key have no
+			// stored objects.
+			r.checkAttached(row, nil)
+
+			continue
+		}
+
+		idx := slices.IndexFunc(group, func(o StoredObject) bool {
+			return o.Key == key
+		})
+
+		var obj *StoredObject
+
+		if idx != -1 {
+			obj = &group[idx]
+		}
+
+		r.checkAttached(row, obj)
+
+		return nil
+	}
+
+	// Nothing is attached under the key.
+	for _, obj := range group {
+		err := r.orphaned(ctx, obj)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAttached checks an attached object against the latest version of its
+// stored object, obj is nil if there is no stored object.
+func (r *reconciliation) checkAttached(
+	row *postgres.ListCurrentAttachedObjectsRow, obj *StoredObject,
+) {
+	// Skip objects that were attached after we started.
+	if row.CreatedAt.Time.After(r.started) {
+		return
+	}
+
+	var kind string
+
+	switch {
+	case obj == nil:
+		kind = InconsistencyMissingObject
+	case obj.LastModified.After(r.started):
+		// Changed after we started.
+	case obj.Deleted:
+		kind = InconsistencyMissingObject
+	case obj.VersionID != row.ObjectVersion:
+		kind = InconsistencyVersionMismatch
+	}
+
+	if kind == "" {
+		return
+	}
+
+	r.inconsistencies = append(r.inconsistencies,
+		AttachmentInconsistency{
+			Kind:     kind,
+			Key:      r.j.assets.objKey(row.Document, row.Name),
+			Document: row.Document,
+			Name:     row.Name,
+		})
+}
+
+// orphaned handles a stored object that isn't attached to a document.
+func (r *reconciliation) orphaned(ctx context.Context, obj StoredObject) error {
+	// Give attaches that are in flight a chance to finish.
+	if obj.Deleted || obj.LastModified.After(r.started.Add(-r.j.orphanGrace)) {
+		return nil
+	}
+
+	r.inconsistencies = append(r.inconsistencies,
+		AttachmentInconsistency{
+			Kind: InconsistencyOrphanedObject,
+			Key:  obj.Key,
+		})
+
+	if r.j.dryRun {
+		return nil
+	}
+
+	err := r.j.assets.DeleteKey(ctx, obj.Key)
+
+	r.j.deletes.WithLabelValues(
+		InconsistencyOrphanedObject, statusLabel(err)).Inc()
+
+	if err != nil {
+		return fmt.Errorf("delete orphaned object %q: %w", obj.Key, err)
+	}
+
+	return nil
+}
+
+// renditionBaseKey returns the key of the attached object that a rendition
+// belongs to, keys of attached objects are returned as-is.
+func renditionBaseKey(key string) string {
+	dir, file := path.Split(key)
+
+	base, _, found := strings.Cut(file, ".")
+	if !found {
+		return key
+	}
+
+	return dir + base
+}
+
+func statusLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "ok"
+}
@@ -0,0 +1,173 @@
+package repository_test
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+)
+
+func TestIntegrationUploadJanitor(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{})
+
+	client := tc.DocumentsClient(t, itest.Claims(t, "uploader",
+		"doc_read doc_write asset_upload"))
+
+	upload := func(data string) string {
+		t.Helper()
+
+		up, err := client.CreateUpload(ctx, &rpc.CreateUploadRequest{
+			Name:        "my.txt",
+			ContentType: "text/plain",
+		})
+		test.Must(t, err, "create upload")
+
+		req, err := http.NewRequestWithContext(ctx,
+			http.MethodPut, up.Url, strings.NewReader(data))
+		test.Must(t, err, "create upload request")
+
+		req.ContentLength = int64(len(data))
+
+		res, err := http.DefaultClient.Do(req)
+		test.Must(t, err, "make upload request")
+
+		_ = res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("error response from upload recipient: %s",
+				res.Status)
+		}
+
+		return up.Id
+	}
+
+	attach := func(data string) string {
+		t.Helper()
+
+		docUUID := uuid.NewString()
+
+		_, err := client.Update(ctx, &rpc.UpdateRequest{
+			Uuid:     docUUID,
+			Document: baseDocument(docUUID, "article://test/"+docUUID),
+			AttachObjects: map[string]string{
+				"plaintext": upload(data),
+			},
+		})
+		test.Must(t, err, "attach the object")
+
+		return "objects/plaintext/" + docUUID
+	}
+
+	unattached := upload("never attached")
+	missingKey := attach("will go missing")
+	replacedKey := attach("will be replaced")
+	orphanKey := "objects/plaintext/" + uuid.NewString()
+
+	_, err := tc.Env.S3.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(tc.Env.AssetBucket),
+		Key:    aws.String(missingKey),
+	})
+	test.Must(t, err, "delete an attached object")
+
+	for _, key := range []string{replacedKey, orphanKey} {
+		_, err = tc.Env.S3.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(tc.Env.AssetBucket),
+			Key:    aws.String(key),
+			Body:   strings.NewReader("unexpected"),
+		})
+		test.Must(t, err, "write object %q", key)
+	}
+
+	janitor := func(dryRun bool) *repository.UploadJanitor {
+		t.Helper()
+
+		j, err := repository.NewUploadJanitor(
+			repository.UploadJanitorOptions{
+				Logger:            logger,
+				DB:                tc.DB,
+				Assets:            tc.Assets,
+				MetricsRegisterer: prometheus.NewRegistry(),
+				MaxUploadAge:      time.Nanosecond,
+				OrphanGracePeriod: time.Nanosecond,
+				DryRun:            dryRun,
+			})
+		test.Must(t, err, "create upload janitor")
+
+		return j
+	}
+
+	kinds := func(report *repository.JanitorReport) map[string]string {
+		res := make(map[string]string)
+
+		for _, inc := range report.Inconsistencies {
+			res[inc.Key] = inc.Kind
+		}
+
+		return res
+	}
+
+	expected := map[string]string{
+		missingKey:  repository.InconsistencyMissingObject,
+		replacedKey: repository.InconsistencyVersionMismatch,
+		orphanKey:   repository.InconsistencyOrphanedObject,
+	}
+
+	report, err := janitor(true).RunOnce(ctx)
+	test.Must(t, err, "run janitor in dry-run mode")
+
+	test.Equal(t, 3, report.ExpiredUploads, "report the expired uploads")
+	test.EqualDiff(t, expected, kinds(report), "report inconsistencies")
+
+	uploadID, err := uuid.Parse(unattached)
+	test.Must(t, err, "parse upload ID")
+
+	inspection, err := tc.Assets.InspectUpload(ctx, uploadID, false)
+	test.Must(t, err, "inspect upload after dry run")
+
+	if inspection == nil {
+		t.Fatal("expected the dry run to keep the upload")
+	}
+
+	report, err = janitor(false).RunOnce(ctx)
+	test.Must(t, err, "run janitor")
+
+	test.Equal(t, 3, report.ExpiredUploads, "expire the uploads")
+	test.EqualDiff(t, expected, kinds(report), "report inconsistencies")
+
+	inspection, err = tc.Assets.InspectUpload(ctx, uploadID, false)
+	test.Must(t, err, "inspect expired upload")
+
+	if inspection != nil {
+		t.Fatal("expected the upload object to be deleted")
+	}
+
+	report, err = janitor(false).RunOnce(ctx)
+	test.Must(t, err, "run janitor again")
+
+	test.Equal(t, 0, report.ExpiredUploads, "have no uploads left")
+
+	if slices.ContainsFunc(report.Inconsistencies,
+		func(inc repository.AttachmentInconsistency) bool {
+			return inc.Kind == repository.InconsistencyOrphanedObject
+		}) {
+		t.Fatal("expected orphaned objects to be deleted")
+	}
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Reserved CreateUpload meta keys that are used to declare the expected
+// contents of an upload. They are not stored as props.
+const (
+	UploadMetaSHA256 = "elephant-sha256"
+	UploadMetaSize   = "elephant-size"
+)
+
+// sniffLen is the number of bytes that http.DetectContentType considers.
+const sniffLen = 512
+
+// UploadInspection is the actual size and content type of an upload.
+type UploadInspection struct {
+	Size int64
+	// SHA256 is the hex encoded checksum of the upload, only set if it was
+	// requested.
+	SHA256 string
+	// Mimetype is the sniffed content type of the upload.
+	Mimetype string
+	// ETag of the inspected upload object.
+	ETag string
+}
+
+// uploadDeclaration parses and validates the expected checksum and size of an
+// upload.
+func uploadDeclaration(checksum string, size int64) (string, error) {
+	if size < 0 {
+		return "", errors.New("size cannot be negative")
+	}
+
+	if checksum == "" {
+		return "", nil
+	}
+
+	checksum = strings.ToLower(checksum)
+
+	raw, err := hex.DecodeString(checksum)
+	if err != nil || len(raw) != 32 {
+		return "", errors.New("sha256 must be a hex encoded SHA-256 checksum")
+	}
+
+	return checksum, nil
+}
+
+// uploadDeclarationFromMeta extracts the reserved checksum and size keys from
+// CreateUpload meta.
+func uploadDeclarationFromMeta(
+	meta map[string]string,
+) (string, int64, error) {
+	var size int64
+
+	if v, ok := meta[UploadMetaSize]; ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid size: %w", err)
+		}
+
+		size = n
+	}
+
+	checksum, err := uploadDeclaration(meta[UploadMetaSHA256], size)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return checksum, size, nil
+}
+
+// VerifyUpload checks that the inspected upload matches the declared checksum,
+// size and content type.
+func VerifyUpload(declared AssetMetadata, actual UploadInspection) error {
+	if declared.Size != 0 && declared.Size != actual.Size {
+		return fmt.Errorf("expected a size of %d bytes, got %d bytes",
+			declared.Size, actual.Size)
+	}
+
+	if declared.SHA256 != "" && declared.SHA256 != actual.SHA256 {
+		return fmt.Errorf("expected the SHA-256 checksum %s, got %s",
+			declared.SHA256, actual.SHA256)
+	}
+
+	if !mimetypesCompatible(declared.Mimetype, actual.Mimetype) {
+		return fmt.Errorf("declared as %q, but the content is %q",
+			declared.Mimetype, actual.Mimetype)
+	}
+
+	return nil
+}
+
+// sniffedAliases lists the declared content types that are accepted for a
+// sniffed content type. Sniffing only recognises a limited set of formats, so
+// formats built on top of other formats (f.ex. office documents in zip files)
+// are detected as their container, and some formats are detected under
+// another name than the one that's commonly declared.
+var sniffedAliases = map[string][]string{
+	"application/zip": {
+		"application/x-zip-compressed",
+		"application/epub+zip",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		"application/vnd.oasis.opendocument.text",
+		"application/vnd.oasis.opendocument.spreadsheet",
+		"application/vnd.oasis.opendocument.presentation",
+		"application/vnd.google-earth.kmz",
+	},
+	"application/x-gzip": {"application/gzip"},
+	"application/ogg":    {"audio/ogg", "video/ogg", "audio/opus"},
+	"audio/wave":         {"audio/wav", "audio/x-wav", "audio/vnd.wave"},
+	"audio/aiff":         {"audio/x-aiff"},
+	"audio/mpeg":         {"audio/mp3"},
+	"video/avi":          {"video/x-msvideo"},
+	"video/mp4":          {"audio/mp4", "audio/x-m4a", "video/quicktime"},
+	"video/webm":         {"audio/webm"},
+	"image/jpeg":         {"image/jpg", "image/pjpeg"},
+	"image/x-icon":       {"image/vnd.microsoft.icon"},
+	"text/xml": {
+		"application/xml",
+		"application/atom+xml",
+		"application/rss+xml",
+		"application/xhtml+xml",
+		"image/svg+xml",
+	},
+	"text/html": {"application/xhtml+xml"},
+	"text/plain": {
+		"application/json",
+		"application/ld+json",
+		"application/geo+json",
+		"application/x-ndjson",
+		"application/javascript",
+		"application/xml",
+		"application/yaml",
+		"application/x-subrip",
+		"image/svg+xml",
+	},
+}
+
+// mimetypesCompatible checks if the sniffed content type could be the declared
+// type. The full media types are compared, and the declared type is accepted
+// if it's a known alias of the sniffed type. Sniffing can't tell text formats
+// apart, so sniffed plain text is accepted for all text types. Declaring the
+// upload as "application/octet-stream" doesn't make any claims about the
+// content, so any sniffed type is accepted.
+func mimetypesCompatible(declared string, sniffed string) bool {
+	dType, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		return false
+	}
+
+	if dType == "application/octet-stream" {
+		return true
+	}
+
+	sType, _, err := mime.ParseMediaType(sniffed)
+	if err != nil || sType == "application/octet-stream" {
+		return true
+	}
+
+	if dType == sType {
+		return true
+	}
+
+	if sType == "text/plain" && strings.HasPrefix(dType, "text/") {
+		return true
+	}
+
+	return slices.Contains(sniffedAliases[sType], dType)
+}
@@ -0,0 +1,292 @@
+package repository_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	rpc "github.com/ttab/elephant-api/repository"
+	itest "github.com/ttab/elephant-repository/internal/test"
+	"github.com/ttab/elephant-repository/repository"
+	"github.com/ttab/elephantine/test"
+	"github.com/twitchtv/twirp"
+)
+
+func TestVerifyUpload(t *testing.T) {
+	pngSum := sha256.Sum256([]byte("png"))
+	pngChecksum := hex.EncodeToString(pngSum[:])
+
+	cases := map[string]struct {
+		Declared repository.AssetMetadata
+		Actual   repository.UploadInspection
+		Valid    bool
+	}{
+		"matching": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "image/png",
+				SHA256:   pngChecksum,
+				Size:     3,
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "image/png",
+				SHA256:   pngChecksum,
+				Size:     3,
+			},
+			Valid: true,
+		},
+		"nothing declared": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "image/png",
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "image/png",
+				Size:     3,
+			},
+			Valid: true,
+		},
+		"wrong size": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "image/png",
+				Size:     4,
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "image/png",
+				Size:     3,
+			},
+		},
+		"wrong checksum": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "image/png",
+				SHA256:   pngChecksum,
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "image/png",
+				SHA256:   strings.Repeat("0", 64),
+			},
+		},
+		"wrong type": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "image/png",
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "application/pdf",
+			},
+		},
+		"other image type": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "image/jpeg",
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "image/png",
+			},
+		},
+		"jpeg alias": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "image/jpg",
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "image/jpeg",
+			},
+			Valid: true,
+		},
+		"sniffed text": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "image/svg+xml",
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "text/xml; charset=utf-8",
+			},
+			Valid: true,
+		},
+		"text as image": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "image/png",
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "text/plain; charset=utf-8",
+			},
+		},
+		"sniffed json": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "application/json",
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "text/plain; charset=utf-8",
+			},
+			Valid: true,
+		},
+		"zip container": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "application/zip",
+			},
+			Valid: true,
+		},
+		"declared as binary": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "application/octet-stream",
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "text/plain; charset=utf-8",
+			},
+			Valid: true,
+		},
+		"unknown zip format": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "application/vnd.android.package-archive",
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "application/zip",
+			},
+		},
+		"text as pdf": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "application/pdf",
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "text/plain; charset=utf-8",
+			},
+		},
+		"ogg audio": {
+			Declared: repository.AssetMetadata{
+				Mimetype: "audio/ogg",
+			},
+			Actual: repository.UploadInspection{
+				Mimetype: "application/ogg",
+			},
+			Valid: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := repository.VerifyUpload(c.Declared, c.Actual)
+
+			switch {
+			case c.Valid && err != nil:
+				t.Fatalf("expected upload to be accepted: %v", err)
+			case !c.Valid && err == nil:
+				t.Fatal("expected upload to be rejected")
+			}
+		})
+	}
+}
+
+func TestIntegrationUploadVerification(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := t.Context()
+	logger := slog.New(test.NewLogHandler(t, slog.LevelInfo))
+
+	tc := testingAPIServer(t, logger, testingServerOptions{})
+
+	claims := itest.Claims(t, "uploader",
+		"doc_read doc_write asset_upload")
+
+	client := tc.DocumentsClient(t, claims)
+	ext := tc.ExtensionClient(t, rpc.DocumentsPathPrefix, claims)
+
+	data := "Hello World\n"
+	sum := sha256.Sum256([]byte(data))
+	checksum := hex.EncodeToString(sum[:])
+
+	upload := func(contentType string, meta map[string]string) string {
+		t.Helper()
+
+		up, err := client.CreateUpload(ctx, &rpc.CreateUploadRequest{
+			Name:        "my.txt",
+			ContentType: contentType,
+			Meta:        meta,
+		})
+		test.Must(t, err, "create upload")
+
+		req, err := http.NewRequestWithContext(ctx,
+			http.MethodPut, up.Url, strings.NewReader(data))
+		test.Must(t, err, "create upload request")
+
+		req.ContentLength = int64(len(data))
+
+		res, err := http.DefaultClient.Do(req)
+		test.Must(t, err, "make upload request")
+
+		_ = res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("error response from upload recipient: %s",
+				res.Status)
+		}
+
+		return up.Id
+	}
+
+	_, err := client.CreateUpload(ctx, &rpc.CreateUploadRequest{
+		Name:        "my.txt",
+		ContentType: "text/plain",
+		Meta: map[string]string{
+			repository.UploadMetaSHA256: "not-a-checksum",
+		},
+	})
+	itest.IsTwirpError(t, err, twirp.InvalidArgument)
+
+	docUUID := uuid.NewString()
+	doc := baseDocument(docUUID, "article://test/"+docUUID)
+
+	attach := func(uploadID string) error {
+		_, err := client.Update(ctx, &rpc.UpdateRequest{
+			Uuid:     docUUID,
+			Document: doc,
+			AttachObjects: map[string]string{
+				"plaintext": uploadID,
+			},
+		})
+
+		return err //nolint: wrapcheck
+	}
+
+	err = attach(upload("text/plain", map[string]string{
+		repository.UploadMetaSize: strconv.Itoa(len(data) + 1),
+	}))
+	itest.IsTwirpError(t, err, twirp.InvalidArgument)
+
+	err = attach(upload("text/plain", map[string]string{
+		repository.UploadMetaSHA256: strings.Repeat("0", 64),
+	}))
+	itest.IsTwirpError(t, err, twirp.InvalidArgument)
+
+	err = attach(upload("image/png", nil))
+	itest.IsTwirpError(t, err, twirp.InvalidArgument)
+
+	err = attach(upload("text/plain", map[string]string{
+		repository.UploadMetaSHA256: checksum,
+		repository.UploadMetaSize:   strconv.Itoa(len(data)),
+		"some":                      "prop",
+	}))
+	test.Must(t, err, "attach a verified upload")
+
+	var details repository.GetAttachmentDetailsResponse
+
+	err = ext.Call(ctx, "GetAttachmentDetails",
+		repository.GetAttachmentDetailsRequest{
+			Documents:      []string{docUUID},
+			AttachmentName: "plaintext",
+		}, &details)
+	test.Must(t, err, "get attachment details")
+
+	test.Equal(t, 1, len(details.Attachments), "get the attachment")
+	test.Equal(t, checksum, details.Attachments[0].SHA256,
+		"record the verified checksum")
+	test.Equal(t, int64(len(data)), details.Attachments[0].Size,
+		"record the verified size")
+	test.Equal(t, "text/plain; charset=utf-8",
+		details.Attachments[0].SniffedContentType,
+		"record the sniffed content type")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 const (
 	LogKeyDeprecationLabel = "deprecation_label"
 	LogKeyEntityRef        = "entity_ref"
+	LogKeyGenerationID     = "generation_id"
 )
 
 type Validator struct {
@@ -24,6 +26,7 @@ type Validator struct {
 	activeGenerationID          int64
 	pendingVal                  *revisor.Validator
 	pendingGenerationID         int64
+	transforms                  []DocumentTransform
 	enforcedDeprecations        EnforcedDeprecations
 	logger                      *slog.Logger
 	deprecationsCounter         prometheus.CounterVec
@@ -39,6 +42,7 @@ type ValidatorStore interface {
 	GetActiveSchemas(ctx context.Context) ([]*Schema, error)
 	GetActiveGenerationID(ctx context.Context) (int64, error)
 	GetPendingGenerationSchemas(ctx context.Context) ([]*Schema, error)
+	GetActiveGenerationTransforms(ctx context.Context) ([]DocumentTransform, error)
 	OnSchemaUpdate(ctx context.Context, ch chan SchemaEvent)
 	GetEnforcedDeprecations(ctx context.Context) (EnforcedDeprecations, error)
 	OnDeprecationUpdate(ctx context.Context, ch chan DeprecationEvent)
@@ -206,6 +210,11 @@ func (v *Validator) loadSchemas(ctx context.Context, loader ValidatorStore) erro
 		genID = 0
 	}
 
+	transforms, err := loader.GetActiveGenerationTransforms(ctx)
+	if err != nil {
+		return fmt.Errorf("get active generation transforms: %w", err)
+	}
+
 	// Load pending generation validator if one exists.
 	var pendingVal *revisor.Validator
 
@@ -245,6 +254,7 @@ func (v *Validator) loadSchemas(ctx context.Context, loader ValidatorStore) erro
 	v.activeGenerationID = genID
 	v.pendingVal = pendingVal
 	v.pendingGenerationID = pendingGenID
+	v.transforms = transforms
 	v.m.Unlock()
 
 	return nil
@@ -343,6 +353,19 @@ func (v *Validator) ActiveGenerationID() int64 {
 	return v.activeGenerationID
 }
 
+// TransformDocument applies the transforms of the active schema generation to
+// a document, returning the transformed document and the names of the
+// transforms that changed it.
+func (v *Validator) TransformDocument(
+	doc newsdoc.Document,
+) (newsdoc.Document, []string) {
+	v.m.RLock()
+	transforms := v.transforms
+	v.m.RUnlock()
+
+	return ApplyTransforms(doc, transforms)
+}
+
 func (v *Validator) deprecationHandler(
 	ctx context.Context, doc *newsdoc.Document,
 	deprecation revisor.Deprecation, deprecationContext revisor.DeprecationContext,
@@ -351,6 +374,10 @@ func (v *Validator) deprecationHandler(
 	enforced := v.enforcedDeprecations[deprecation.Label]
 	v.m.RUnlock()
 
+	if c, ok := ctx.Value(deprecationCollectorKey{}).(*DeprecationCollector); ok {
+		c.add(deprecation.Label)
+	}
+
 	if !enforced {
 		var entityRef string
 
@@ -372,6 +399,48 @@ func (v *Validator) deprecationHandler(
 	}, nil
 }
 
+type deprecationCollectorKey struct{}
+
+// DeprecationCollector collects the labels of the deprecations that are
+// encountered when validating a document.
+type DeprecationCollector struct {
+	m      sync.Mutex
+	labels []string
+}
+
+// WithDeprecationCollector returns a context that collects the deprecation
+// labels that are encountered by ValidateDocument.
+func WithDeprecationCollector(
+	ctx context.Context,
+) (context.Context, *DeprecationCollector) {
+	var c DeprecationCollector
+
+	return context.WithValue(ctx, deprecationCollectorKey{}, &c), &c
+}
+
+func (c *DeprecationCollector) add(label string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if slices.Contains(c.labels, label) {
+		return
+	}
+
+	c.labels = append(c.labels, label)
+}
+
+// Labels returns the sorted labels of the encountered deprecations.
+func (c *DeprecationCollector) Labels() []string {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	labels := slices.Clone(c.labels)
+
+	slices.Sort(labels)
+
+	return labels
+}
+
 // PruneDocument prunes a document using the active schema generation,
 // removing non-conforming parts where possible.
 func (v *Validator) PruneDocument(
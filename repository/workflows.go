@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -186,6 +188,41 @@ func (w *Workflows) HasStatus(docType string, name string) bool {
 	return ok && !status.Disabled
 }
 
+// GetAccessRules returns the status rules for the document type that are
+// treated as access rules, a violation of an access rule is reported as a
+// permission error.
+func (w *Workflows) GetAccessRules(docType string) []StatusRule {
+	w.m.RLock()
+	defer w.m.RUnlock()
+
+	var rules []StatusRule
+
+	// The compiled rules are indexed by status, so the same rule can
+	// occur several times.
+	for _, compiled := range w.rules {
+		for _, r := range compiled {
+			if r.Type != docType || !r.AccessRule {
+				continue
+			}
+
+			seen := slices.ContainsFunc(rules, func(sr StatusRule) bool {
+				return sr.Name == r.Name
+			})
+			if seen {
+				continue
+			}
+
+			rules = append(rules, r.StatusRule)
+		}
+	}
+
+	slices.SortFunc(rules, func(a, b StatusRule) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return rules
+}
+
 // HasStatusRule reports whether a status rule with the given name is currently
 // loaded for the document type. It is primarily intended for tests that need
 // to wait for a rule to propagate from the database to the provider.
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS acl_inheritance_rule(
+       type text NOT NULL,
+       rel text NOT NULL,
+       link_type text NOT NULL DEFAULT '',
+       permissions text[] NOT NULL,
+       PRIMARY KEY(type, rel, link_type)
+);
+
+CREATE TABLE IF NOT EXISTS acl_inheritance(
+       uuid uuid NOT NULL REFERENCES document(uuid) ON DELETE CASCADE,
+       parent uuid NOT NULL,
+       permissions text[] NOT NULL,
+       PRIMARY KEY(uuid, parent)
+);
+
+CREATE INDEX IF NOT EXISTS acl_inheritance_parent_idx
+       ON acl_inheritance(parent);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS acl_inheritance;
+DROP TABLE IF EXISTS acl_inheritance_rule;
@@ -0,0 +1,18 @@
+ALTER TABLE acl
+      ADD COLUMN IF NOT EXISTS not_before timestamptz,
+      ADD COLUMN IF NOT EXISTS expires timestamptz;
+
+CREATE INDEX IF NOT EXISTS acl_not_before_idx
+       ON acl(not_before) WHERE not_before IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS acl_expires_idx
+       ON acl(expires) WHERE expires IS NOT NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS acl_expires_idx;
+DROP INDEX IF EXISTS acl_not_before_idx;
+
+ALTER TABLE acl
+      DROP COLUMN IF EXISTS not_before,
+      DROP COLUMN IF EXISTS expires;
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS read_audit(
+        id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+        uuid uuid NOT NULL,
+        type text NOT NULL,
+        version bigint NOT NULL,
+        operation text NOT NULL,
+        attachment text,
+        subject text NOT NULL,
+        app text,
+        client_ip text,
+        created timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS read_audit_uuid_idx
+       ON read_audit(uuid, id);
+
+CREATE INDEX IF NOT EXISTS read_audit_subject_idx
+       ON read_audit(subject, id);
+
+CREATE OR REPLACE FUNCTION read_audit_append_only()
+RETURNS TRIGGER AS $$
+BEGIN
+    raise exception 'the read audit log is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER read_audit_append_only
+       BEFORE UPDATE OR DELETE ON read_audit
+       FOR EACH ROW EXECUTE FUNCTION read_audit_append_only();
+
+CREATE TABLE IF NOT EXISTS read_audit_archiver(
+        id boolean PRIMARY KEY DEFAULT true,
+        position bigint NOT NULL DEFAULT 0,
+        last_signature text NOT NULL DEFAULT '',
+        CONSTRAINT single_row CHECK (id)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS read_audit_archiver;
+DROP TABLE IF EXISTS read_audit;
+DROP FUNCTION IF EXISTS read_audit_append_only();
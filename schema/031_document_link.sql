@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS document_link(
+       from_document uuid NOT NULL REFERENCES document(uuid) ON DELETE CASCADE,
+       to_document uuid NOT NULL,
+       rel text NOT NULL,
+       type text NOT NULL,
+       PRIMARY KEY(from_document, to_document, rel, type)
+);
+
+CREATE INDEX IF NOT EXISTS document_link_to_idx
+       ON document_link(to_document, from_document);
+
+-- Index the links of the current versions of existing documents.
+INSERT INTO document_link(from_document, to_document, rel, type)
+SELECT DISTINCT d.uuid, (l->>'uuid')::uuid,
+       COALESCE(l->>'rel', ''), COALESCE(l->>'type', '')
+FROM document AS d
+     INNER JOIN document_version AS v
+           ON v.uuid = d.uuid AND v.version = d.current_version,
+     jsonb_array_elements(
+       COALESCE(v.document_data->'links', '[]'::jsonb)) AS l
+WHERE d.system_state IS NULL
+      AND l->>'uuid' ~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+      AND (l->>'uuid')::uuid != d.uuid
+ON CONFLICT DO NOTHING;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS document_link;
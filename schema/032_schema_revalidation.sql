@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS schema_revalidation(
+       generation_id bigint PRIMARY KEY REFERENCES schema_generation(id),
+       types text[] NOT NULL,
+       status text NOT NULL,
+       created timestamptz NOT NULL,
+       created_by text NOT NULL,
+       finished timestamptz,
+       position uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+       total bigint NOT NULL DEFAULT 0,
+       processed bigint NOT NULL DEFAULT 0,
+       failed bigint NOT NULL DEFAULT 0,
+       error text
+);
+
+CREATE TABLE IF NOT EXISTS schema_revalidation_result(
+       generation_id bigint NOT NULL
+                     REFERENCES schema_revalidation(generation_id)
+                     ON DELETE CASCADE,
+       uuid uuid NOT NULL,
+       type text NOT NULL,
+       version bigint NOT NULL,
+       errors jsonb NOT NULL,
+       PRIMARY KEY(generation_id, uuid)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS schema_revalidation_result;
+DROP TABLE IF EXISTS schema_revalidation;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS schema_generation_transform(
+       generation_id bigint PRIMARY KEY REFERENCES schema_generation(id),
+       transforms jsonb NOT NULL,
+       updated timestamptz NOT NULL,
+       updated_by text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS schema_upgrade(
+       generation_id bigint PRIMARY KEY REFERENCES schema_generation(id),
+       types text[] NOT NULL,
+       status text NOT NULL,
+       created timestamptz NOT NULL,
+       created_by text NOT NULL,
+       finished timestamptz,
+       position uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+       processed bigint NOT NULL DEFAULT 0,
+       upgraded bigint NOT NULL DEFAULT 0,
+       failed bigint NOT NULL DEFAULT 0,
+       error text
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS schema_upgrade;
+DROP TABLE IF EXISTS schema_generation_transform;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS deprecation_usage(
+       label text NOT NULL,
+       uuid uuid NOT NULL REFERENCES document(uuid) ON DELETE CASCADE,
+       version bigint NOT NULL,
+       PRIMARY KEY(label, uuid)
+);
+
+CREATE INDEX IF NOT EXISTS deprecation_usage_uuid_idx
+       ON deprecation_usage(uuid);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS deprecation_usage;
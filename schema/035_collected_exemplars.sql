@@ -0,0 +1,7 @@
+ALTER TABLE schema_generation_exemplar
+      ADD COLUMN IF NOT EXISTS collected boolean NOT NULL DEFAULT false;
+
+---- create above / drop below ----
+
+ALTER TABLE schema_generation_exemplar
+      DROP COLUMN IF EXISTS collected;
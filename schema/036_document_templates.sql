@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS document_template(
+       name text NOT NULL,
+       version bigint NOT NULL,
+       type text NOT NULL,
+       description text NOT NULL,
+       document jsonb NOT NULL,
+       variables jsonb NOT NULL,
+       acl jsonb NOT NULL,
+       created timestamptz NOT NULL,
+       created_by text NOT NULL,
+       PRIMARY KEY(name, version)
+);
+
+CREATE INDEX IF NOT EXISTS document_template_type_idx
+       ON document_template(type);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS document_template;
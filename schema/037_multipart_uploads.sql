@@ -0,0 +1,9 @@
+ALTER TABLE upload
+      ADD COLUMN multipart_id text,
+      ADD COLUMN multipart_status text;
+
+---- create above / drop below ----
+
+ALTER TABLE upload
+      DROP COLUMN multipart_id,
+      DROP COLUMN multipart_status;
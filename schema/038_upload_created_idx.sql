@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS upload_created_at_idx
+       ON upload(created_at);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS upload_created_at_idx;
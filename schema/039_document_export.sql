@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS document_export(
+        id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+        uuids uuid[],
+        type text,
+        time_range tstzrange,
+        labels text[],
+        status text NOT NULL,
+        created timestamptz NOT NULL,
+        created_by text NOT NULL,
+        started timestamptz,
+        finished timestamptz,
+        total bigint NOT NULL DEFAULT 0,
+        processed bigint NOT NULL DEFAULT 0,
+        object_key text,
+        size bigint,
+        signature text,
+        error text
+);
+
+CREATE INDEX IF NOT EXISTS document_export_unfinished_idx
+       ON document_export(id) WHERE finished IS NULL;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS document_export;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS acl_inheritance_reapply(
+       type text PRIMARY KEY,
+       position uuid NOT NULL,
+       created timestamptz NOT NULL
+);
+
+-- Rules that were set before inheritance was re-applied on rule changes
+-- have only been applied to documents written after they were set.
+INSERT INTO acl_inheritance_reapply(type, position, created)
+SELECT DISTINCT type, '00000000-0000-0000-0000-000000000000'::uuid, now()
+FROM acl_inheritance_rule
+ON CONFLICT (type) DO NOTHING;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS acl_inheritance_reapply;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS deprecation_usage_scan(
+       generation_id bigint PRIMARY KEY
+              REFERENCES schema_generation(id) ON DELETE CASCADE,
+       position uuid NOT NULL,
+       created timestamptz NOT NULL
+);
+
+-- Usage has only been recorded for documents that have been written since
+-- it started being tracked.
+INSERT INTO deprecation_usage_scan(generation_id, position, created)
+SELECT id, '00000000-0000-0000-0000-000000000000'::uuid, now()
+FROM schema_generation
+WHERE status = 'active'
+ON CONFLICT (generation_id) DO NOTHING;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS deprecation_usage_scan;
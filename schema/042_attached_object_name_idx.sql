@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS attached_object_current_name_idx
+       ON attached_object_current(name, document);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS attached_object_current_name_idx;